@@ -0,0 +1,149 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/enum"
+	"github.com/aokimio/Amass/v3/format"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+)
+
+// daemonOutput is an enumeration finding tagged with the target that produced it.
+type daemonOutput struct {
+	Target string `json:"target"`
+	*requests.Output
+}
+
+// runEnumDaemon enumerates each target read from the input in turn, sharing the System, and its
+// resolvers, caches and data source rate limiters, across all the enumerations. The findings are
+// streamed to stdout as JSON lines tagged with the target.
+func runEnumDaemon(ctx context.Context, sys systems.System, args *enumArgs, input io.Reader) {
+	targets := make(chan string)
+	go readDaemonTargets(input, sys.Config().Domains(), targets)
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case target, ok := <-targets:
+			if !ok {
+				return
+			}
+
+			enumerateDaemonTarget(ctx, sys, args, target, func(out *requests.Output) {
+				_ = enc.Encode(&daemonOutput{Target: target, Output: out})
+			})
+		}
+	}
+}
+
+// readDaemonTargets sends the initial targets, followed by the newline-delimited targets read from the input.
+func readDaemonTargets(input io.Reader, initial []string, targets chan string) {
+	defer close(targets)
+
+	for _, target := range initial {
+		targets <- target
+	}
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		target := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		// Ignore blank lines and comments
+		if target == "" || strings.HasPrefix(target, "#") {
+			continue
+		}
+		targets <- target
+	}
+	if err := scanner.Err(); err != nil {
+		r.Fprintf(color.Error, "Failed to read the targets: %v\n", err)
+	}
+}
+
+func enumerateDaemonTarget(ctx context.Context, sys systems.System, args *enumArgs, target string, emit func(*requests.Output)) {
+	cfg := sys.Config()
+	// The scope is reset for each target
+	cfg.ClearDomains()
+	cfg.AddDomain(target)
+	if len(cfg.Domains()) == 0 {
+		r.Fprintf(color.Error, "The target %s is not a valid root domain name\n", target)
+		return
+	}
+
+	enumerateWithSystem(ctx, sys, args, target, emit)
+}
+
+// enumerateWithSystem performs an enumeration of the domains in scope using the System provided,
+// emitting the findings as they are extracted, and copies the findings into the graph databases
+// of the System once the enumeration completes. The target only labels the progress messages.
+func enumerateWithSystem(ctx context.Context, sys systems.System, args *enumArgs, target string, emit func(*requests.Output)) {
+	cfg := sys.Config()
+	cfg.UUID = uuid.New()
+	// Names and related domains reported during earlier enumerations are provided again for this one
+	sys.NameFilter().Reset()
+	sys.DomainFilter().Reset()
+
+	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer graph.Close()
+
+	e := enum.NewEnumeration(cfg, sys, graph)
+	if e == nil {
+		r.Fprintf(color.Error, "Failed to setup the enumeration of %s\n", target)
+		return
+	}
+
+	var tctx context.Context
+	var cancel context.CancelFunc
+	if args.Timeout == 0 {
+		tctx, cancel = context.WithCancel(ctx)
+	} else {
+		tctx, cancel = context.WithTimeout(ctx, time.Duration(args.Timeout))
+	}
+	defer cancel()
+	// The data source requests for this target end along with its enumeration
+	sys.SetRequestContext(tctx)
+	defer sys.SetRequestContext(nil)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	outChan := make(chan *requests.Output, 10)
+
+	wg.Add(2)
+	go processOutput(tctx, graph, e, &args.Matcher, format.NewAddressClassifier(e.Config.Sinkholes), []chan *requests.Output{outChan}, done, &wg)
+	go func() {
+		defer wg.Done()
+
+		for out := range outChan {
+			emit(out)
+		}
+	}()
+
+	fmt.Fprintf(color.Error, "%s%s\n", yellow("Enumerating "), yellow(target))
+	if err := e.Start(tctx); err != nil {
+		r.Fprintf(color.Error, "The enumeration of %s failed: %v\n", target, err)
+	}
+	close(done)
+	wg.Wait()
+	// Copy the findings for this target into the system graph databases
+	for _, g := range sys.GraphDatabases() {
+		if err := graph.Migrate(ctx, g); err != nil {
+			r.Fprintf(color.Error, "The database migration to %s failed: %v\n", g.String(), err)
+		}
+	}
+}
@@ -15,14 +15,17 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/datasrcs"
+	"github.com/aokimio/Amass/v3/enum"
 	"github.com/aokimio/Amass/v3/format"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/netmap"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
@@ -31,9 +34,15 @@ const (
 )
 
 type dbArgs struct {
-	Domains *stringset.Set
-	Enum    int
-	Options struct {
+	ASNs       format.ParseASNs
+	Domains    *stringset.Set
+	Enum       int
+	Path       string
+	JSONFormat format.ParseJSONFormat
+	Encoding   format.ParseOutputEncoding
+	Matcher    format.OutputMatcher
+	Options    struct {
+		Aggregate        bool
 		DemoMode         bool
 		IPs              bool
 		IPv4             bool
@@ -41,7 +50,11 @@ type dbArgs struct {
 		ListEnumerations bool
 		ASNTableSummary  bool
 		DiscoveredNames  bool
+		NameServers      bool
+		NetblockDomains  bool
 		NoColor          bool
+		Seeds            bool
+		Seen             bool
 		ShowAll          bool
 		Silent           bool
 		Sources          bool
@@ -51,6 +64,8 @@ type dbArgs struct {
 		Directory  string
 		Domains    string
 		JSONOutput string
+		RDFOutput  string
+		STIXOutput string
 		TermOut    string
 	}
 }
@@ -67,8 +82,10 @@ func RunDBCommand(clArgs []string) {
 
 	dbCommand.BoolVar(&help1, "h", false, "Show the program usage message")
 	dbCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	dbCommand.Var(&args.ASNs, "asn", "List the netblocks of the ASNs separated by commas and the names within them (can be used multiple times)")
 	dbCommand.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
 	dbCommand.IntVar(&args.Enum, "enum", 0, "Identify an enumeration via an index from the listing")
+	dbCommand.BoolVar(&args.Options.Aggregate, "aggregate", false, "Summarize ASN netblocks as the minimal set of covering CIDRs")
 	dbCommand.BoolVar(&args.Options.DemoMode, "demo", false, "Censor output to make it suitable for demonstrations")
 	dbCommand.BoolVar(&args.Options.IPs, "ip", false, "Show the IP addresses for discovered names")
 	dbCommand.BoolVar(&args.Options.IPv4, "ipv4", false, "Show the IPv4 addresses for discovered names")
@@ -77,13 +94,25 @@ func RunDBCommand(clArgs []string) {
 	dbCommand.BoolVar(&args.Options.Sources, "src", false, "Print data sources for the discovered names")
 	dbCommand.BoolVar(&args.Options.ASNTableSummary, "summary", false, "Print Just ASN Table Summary")
 	dbCommand.BoolVar(&args.Options.DiscoveredNames, "names", false, "Print Just Discovered Names")
+	dbCommand.Var(&args.Matcher.Match, "match", "Only output the names matching the regular expression (can be used multiple times)")
+	dbCommand.BoolVar(&args.Matcher.Addresses, "match-addrs", false, "Also test the addresses of the names against the -match and -filter-out patterns")
+	dbCommand.Var(&args.Matcher.FilterOut, "filter-out", "Leave the names matching the regular expression out of the output (can be used multiple times)")
+	dbCommand.BoolVar(&args.Options.NameServers, "nameservers", false, "Print the nameservers and the names that use them")
+	dbCommand.BoolVar(&args.Options.NetblockDomains, "netblock-domains", false, "Print the netblocks and the domains found hosted in them")
 	dbCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
+	dbCommand.StringVar(&args.Path, "path", "", "Print the sources, resolution chain and netblock attribution of the name")
+	dbCommand.BoolVar(&args.Options.Seeds, "seeds", false, "Print the root domains that led to the discovered names")
+	dbCommand.BoolVar(&args.Options.Seen, "seen", false, "Print the first and last times the discovered names were observed")
 	dbCommand.BoolVar(&args.Options.ShowAll, "show", false, "Print the results for the enumeration index + domains provided")
 	dbCommand.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
-	dbCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	dbCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
 	dbCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the graph database")
 	dbCommand.StringVar(&args.Filepaths.Domains, "df", "", "Path to a file providing root domain names")
 	dbCommand.StringVar(&args.Filepaths.JSONOutput, "json", "", "Path to the JSON output file")
+	dbCommand.Var(&args.JSONFormat, "json-format", "Format of the JSON output: native (default) or flat")
+	dbCommand.Var(&args.Encoding, "output-encoding", "Write the internationalized names as ascii (punycode, default) or unicode")
+	dbCommand.StringVar(&args.Filepaths.STIXOutput, "stix", "", "Path to the STIX 2.1 bundle output file")
+	dbCommand.StringVar(&args.Filepaths.RDFOutput, "rdf", "", "Path to the RDF output file written in the Turtle format")
 	dbCommand.StringVar(&args.Filepaths.TermOut, "o", "", "Path to the text file containing terminal stdout/stderr")
 
 	if len(clArgs) < 1 {
@@ -106,7 +135,7 @@ func RunDBCommand(clArgs []string) {
 		color.Error = ioutil.Discard
 	}
 	if args.Filepaths.Domains != "" {
-		list, err := config.GetListFromFile(args.Filepaths.Domains)
+		list, err := config.GetScopeListFromFile(args.Filepaths.Domains)
 		if err != nil {
 			r.Fprintf(color.Error, "Failed to parse the domain names file: %v\n", err)
 			return
@@ -123,6 +152,12 @@ func RunDBCommand(clArgs []string) {
 		if args.Domains.Len() == 0 {
 			args.Domains.InsertMany(cfg.Domains()...)
 		}
+		if args.Encoding == "" && cfg.OutputEncoding != "" {
+			if err := args.Encoding.Set(cfg.OutputEncoding); err != nil {
+				r.Fprintf(color.Error, "Failed to load the configuration file: %v\n", err)
+				os.Exit(1)
+			}
+		}
 	} else if args.Filepaths.ConfigFile != "" {
 		r.Fprintf(color.Error, "Failed to load the configuration file: %v\n", err)
 		os.Exit(1)
@@ -157,11 +192,12 @@ func RunDBCommand(clArgs []string) {
 		listEvents(uuids, memDB)
 		return
 	}
-	if args.Options.ShowAll || args.Filepaths.JSONOutput != "" {
+	if args.Options.ShowAll || args.Filepaths.JSONOutput != "" || args.Filepaths.STIXOutput != "" || args.Filepaths.RDFOutput != "" {
 		args.Options.DiscoveredNames = true
 		args.Options.ASNTableSummary = true
 	}
-	if !args.Options.DiscoveredNames && !args.Options.ASNTableSummary {
+	if !args.Options.DiscoveredNames && !args.Options.ASNTableSummary && args.Path == "" &&
+		!args.Options.NameServers && !args.Options.NetblockDomains && len(args.ASNs) == 0 {
 		CommandUsage(dbUsageMsg, dbCommand, dbBuf)
 		return
 	}
@@ -177,6 +213,22 @@ func RunDBCommand(clArgs []string) {
 
 		uuids = []string{uuids[idx]}
 	}
+	if args.Path != "" {
+		showNamePath(&args, uuids, memDB)
+		return
+	}
+	if args.Options.NameServers {
+		showNameServers(&args, uuids, memDB)
+		return
+	}
+	if args.Options.NetblockDomains {
+		showNetblockDomains(&args, uuids, memDB)
+		return
+	}
+	if len(args.ASNs) > 0 {
+		showASNNames(&args, uuids, memDB)
+		return
+	}
 
 	var asninfo bool
 	if args.Options.ASNTableSummary {
@@ -243,6 +295,10 @@ func showEventData(args *dbArgs, uuids []string, asninfo bool, db *netmap.Graph)
 		if len(domains) > 0 && !domainNameInScope(out.Name, domains) {
 			continue
 		}
+		if !args.Matcher.Allow(out) {
+			continue
+		}
+		format.EncodeOutput(out, args.Encoding.String())
 
 		out.Addresses = format.DesiredAddrTypes(out.Addresses, args.Options.IPv4, args.Options.IPv6)
 		if l := len(out.Addresses); (args.Options.IPs || args.Options.IPv4 || args.Options.IPv6) && l == 0 {
@@ -257,6 +313,13 @@ func showEventData(args *dbArgs, uuids []string, asninfo bool, db *netmap.Graph)
 		if ips != "" {
 			ips = " " + ips
 		}
+		if args.Options.Seen && !out.FirstSeen.IsZero() {
+			ips += fmt.Sprintf(" (first seen %s, last seen %s)",
+				out.FirstSeen.Local().Format(timeFormat), out.LastSeen.Local().Format(timeFormat))
+		}
+		if args.Options.Seeds && len(out.Seeds) > 0 {
+			ips += fmt.Sprintf(" (seeds %s)", strings.Join(out.Seeds, ", "))
+		}
 
 		if args.Options.DiscoveredNames {
 			var written bool
@@ -264,9 +327,9 @@ func showEventData(args *dbArgs, uuids []string, asninfo bool, db *netmap.Graph)
 				fmt.Fprintf(outfile, "%s%s%s\n", source, name, ips)
 				written = true
 			}
-			if args.Filepaths.JSONOutput != "" {
+			if args.Filepaths.JSONOutput != "" || args.Filepaths.STIXOutput != "" || args.Filepaths.RDFOutput != "" {
 				discovered = append(discovered, out)
-				written = true
+				written = args.Filepaths.JSONOutput != ""
 			}
 			if !written {
 				fmt.Fprintf(color.Output, "%s%s%s\n", blue(source), green(name), yellow(ips))
@@ -278,6 +341,12 @@ func showEventData(args *dbArgs, uuids []string, asninfo bool, db *netmap.Graph)
 		r.Println("No names were discovered")
 		return
 	}
+	if args.Filepaths.STIXOutput != "" {
+		writeSTIX(args, uuids, discovered, db)
+	}
+	if args.Filepaths.RDFOutput != "" {
+		writeRDF(args, discovered)
+	}
 	if args.Filepaths.JSONOutput != "" {
 		writeJSON(args, uuids, discovered, db)
 	} else if args.Options.ASNTableSummary {
@@ -293,11 +362,165 @@ func showEventData(args *dbArgs, uuids []string, asninfo bool, db *netmap.Graph)
 			out = color.Output
 		}
 
+		if args.Options.Aggregate {
+			format.AggregateSummaryData(asns)
+		}
 		format.FprintEnumerationSummary(out, total, tags, asns, args.Options.DemoMode)
 		color.NoColor = status
 	}
 }
 
+func showNamePath(args *dbArgs, uuids []string, db *netmap.Graph) {
+	path, err := enum.NamePath(context.Background(), db, strings.ToLower(strings.TrimSpace(args.Path)), uuids...)
+	if err != nil {
+		r.Fprintln(color.Error, err.Error())
+		os.Exit(1)
+	}
+
+	if args.Filepaths.JSONOutput == "" {
+		format.FprintNamePath(color.Output, path)
+		return
+	}
+
+	jsonptr := os.Stdout
+	// Write to STDOUT and not a file if named "-"
+	if args.Filepaths.JSONOutput != "-" {
+		jsonptr, err = os.OpenFile(args.Filepaths.JSONOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the JSON output file: %v\n", err)
+			return
+		}
+		defer func() {
+			_ = jsonptr.Sync()
+			_ = jsonptr.Close()
+		}()
+	}
+
+	enc := json.NewEncoder(jsonptr)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(path)
+}
+
+func showNameServers(args *dbArgs, uuids []string, db *netmap.Graph) {
+	nameservers, err := enum.NameServers(context.Background(), db, uuids...)
+	if err != nil || len(nameservers) == 0 {
+		r.Fprintln(color.Error, "No nameservers were discovered")
+		os.Exit(1)
+	}
+
+	domains := args.Domains.Slice()
+	var results []*requests.NameServer
+	for _, ns := range nameservers {
+		var names []string
+		for _, name := range ns.Domains {
+			if len(domains) == 0 || domainNameInScope(name, domains) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			ns.Domains = names
+			results = append(results, ns)
+		}
+	}
+
+	if args.Filepaths.JSONOutput == "" {
+		for _, ns := range results {
+			fmt.Fprintf(color.Output, "%s %s\n", green(ns.Name), yellow(fmt.Sprintf("(%d)", len(ns.Domains))))
+			for _, name := range ns.Domains {
+				fmt.Fprintf(color.Output, "\t%s\n", name)
+			}
+		}
+		return
+	}
+	writeJSONResults(args.Filepaths.JSONOutput, results)
+}
+
+func showNetblockDomains(args *dbArgs, uuids []string, db *netmap.Graph) {
+	netblocks, err := enum.NetblockDomains(context.Background(), db, uuids...)
+	if err != nil || len(netblocks) == 0 {
+		r.Fprintln(color.Error, "No domains were found hosted in the discovered netblocks")
+		os.Exit(1)
+	}
+
+	if args.Filepaths.JSONOutput == "" {
+		for _, nb := range netblocks {
+			fmt.Fprintf(color.Output, "%s %s\n", green(nb.Netblock), yellow(fmt.Sprintf("(%d)", len(nb.Domains))))
+			for _, d := range nb.Domains {
+				fmt.Fprintf(color.Output, "\t%s\n", d)
+			}
+		}
+		return
+	}
+	writeJSONResults(args.Filepaths.JSONOutput, netblocks)
+}
+
+func showASNNames(args *dbArgs, uuids []string, db *netmap.Graph) {
+	cache := requests.NewASNCache()
+	if err := fillCache(cache, db); err != nil {
+		r.Fprintln(color.Error, "Failed to populate the ASN cache")
+		os.Exit(1)
+	}
+
+	var asns []*requests.ASNRequest
+	for _, asn := range args.ASNs {
+		if req := cache.ASNSearch(asn); req != nil {
+			asns = append(asns, req)
+			continue
+		}
+		r.Fprintf(color.Error, "No netblocks were discovered for AS%d\n", asn)
+	}
+	if len(asns) == 0 {
+		os.Exit(1)
+	}
+
+	domains := args.Domains.Slice()
+	var outputs []*requests.Output
+	for _, out := range getEventOutput(context.Background(), uuids, true, db, cache) {
+		if (len(domains) == 0 || domainNameInScope(out.Name, domains)) && args.Matcher.Allow(out) {
+			outputs = append(outputs, out)
+		}
+	}
+
+	results := format.ASNNames(asns, outputs)
+	if args.Filepaths.JSONOutput != "" {
+		writeJSONResults(args.Filepaths.JSONOutput, results)
+		return
+	}
+
+	for _, res := range results {
+		fmt.Fprintf(color.Output, "%s %s %s\n", blue(strconv.Itoa(res.ASN)), green(res.Description),
+			yellow(fmt.Sprintf("(%d netblocks, %d names)", len(res.Netblocks), len(res.Names))))
+		for _, cidr := range res.Netblocks {
+			fmt.Fprintf(color.Output, "\t%s\n", yellow(cidr))
+		}
+		for _, n := range res.Names {
+			fmt.Fprintf(color.Output, "\t%s %s\n", green(n.Name), strings.Join(n.Addresses, ","))
+		}
+	}
+}
+
+// writeJSONResults encodes the results to the JSON output file, or to STDOUT when the path is "-".
+func writeJSONResults(path string, results interface{}) {
+	jsonptr := os.Stdout
+	if path != "-" {
+		var err error
+
+		jsonptr, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the JSON output file: %v\n", err)
+			return
+		}
+		defer func() {
+			_ = jsonptr.Sync()
+			_ = jsonptr.Close()
+		}()
+	}
+
+	enc := json.NewEncoder(jsonptr)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(results)
+}
+
 type jsonEvent struct {
 	UUID   string `json:"uuid"`
 	Start  string `json:"start"`
@@ -366,11 +589,68 @@ func writeJSON(args *dbArgs, uuids []string, assets []*requests.Output, db *netm
 	// Remove previously stored data and encode the JSON
 	_ = jsonptr.Truncate(0)
 	_, _ = jsonptr.Seek(0, 0)
-	_ = json.NewEncoder(jsonptr).Encode(output)
+	enc := json.NewEncoder(jsonptr)
+	if args.JSONFormat.Flat() {
+		// One object per name, in the same form as the enum flat output
+		for _, asset := range assets {
+			_ = enc.Encode(format.NewFlatOutput(asset))
+		}
+	} else {
+		_ = enc.Encode(output)
+	}
 	_ = jsonptr.Sync()
 	_ = jsonptr.Close()
 }
 
+func writeSTIX(args *dbArgs, uuids []string, assets []*requests.Output, db *netmap.Graph) {
+	now := time.Now()
+	first, last := now, now
+	// Use the time span of the selected events for the observations
+	if _, earliest, latest := orderedEvents(context.Background(), uuids, db); len(earliest) > 0 {
+		first, last = earliest[0], latest[len(latest)-1]
+	}
+
+	bundle := format.NewSTIXBundle(now)
+	for _, asset := range assets {
+		bundle.Add(asset, first, last)
+	}
+
+	stixptr, err := os.OpenFile(args.Filepaths.STIXOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to open the STIX output file: %v\n", err)
+		return
+	}
+	defer func() {
+		_ = stixptr.Sync()
+		_ = stixptr.Close()
+	}()
+
+	if err := bundle.Encode(stixptr); err != nil {
+		r.Fprintf(color.Error, "Failed to write the STIX output file: %v\n", err)
+	}
+}
+
+func writeRDF(args *dbArgs, assets []*requests.Output) {
+	graph := format.NewRDFGraph()
+	for _, asset := range assets {
+		graph.Add(asset)
+	}
+
+	rdfptr, err := os.OpenFile(args.Filepaths.RDFOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to open the RDF output file: %v\n", err)
+		return
+	}
+	defer func() {
+		_ = rdfptr.Sync()
+		_ = rdfptr.Close()
+	}()
+
+	if err := graph.Encode(rdfptr); err != nil {
+		r.Fprintf(color.Error, "Failed to write the RDF output file: %v\n", err)
+	}
+}
+
 func fillCache(cache *requests.ASNCache, db *netmap.Graph) error {
 	aslist, err := db.AllNodesOfType(context.Background(), netmap.TypeAS)
 	if err != nil {
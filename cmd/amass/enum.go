@@ -20,6 +20,8 @@ import (
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -28,16 +30,22 @@ import (
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/datasrcs"
 	"github.com/aokimio/Amass/v3/enum"
+	"github.com/aokimio/Amass/v3/eventlog"
 	"github.com/aokimio/Amass/v3/format"
+	"github.com/aokimio/Amass/v3/net/dns"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
+	"github.com/aokimio/Amass/v3/viz"
 	"github.com/caffix/netmap"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
 const enumUsageMsg = "enum [options] -d DOMAIN"
 
+// Graphviz layouts of larger graphs become difficult to read
+const maxReadableDOTNodes = 2000
+
 type enumArgs struct {
 	Addresses         format.ParseIPs
 	ASNs              format.ParseInts
@@ -48,24 +56,38 @@ type enumArgs struct {
 	BruteWordListMask *stringset.Set
 	Blacklist         *stringset.Set
 	Domains           *stringset.Set
+	DoH               format.ParseStrings
 	Excluded          *stringset.Set
 	Included          *stringset.Set
 	Interface         string
 	MaxDNSQueries     int
+	MetricsAddr       string
+	Exemplars         time.Duration
+	FlushInterval     int
+	FlushSize         int
 	ResolverQPS       int
 	TrustedQPS        int
 	MaxDepth          int
+	MaxAddrs          int
+	MaxRecursionDepth int
 	MinForRecursive   int
 	Names             *stringset.Set
 	Ports             format.ParseInts
 	Resolvers         *stringset.Set
 	Trusted           *stringset.Set
-	Timeout           int
+	Seed              int64
+	Timeout           format.ParseTimeout
+	ConfigDump        format.ParseDumpFormat
+	JSONFormat        format.ParseJSONFormat
+	OutputEncoding    format.ParseOutputEncoding
+	Matcher           format.OutputMatcher
 	Options           struct {
 		Active          bool
 		Alterations     bool
 		BruteForcing    bool
+		Daemon          bool
 		DemoMode        bool
+		FollowCNAMEs    bool
 		IPs             bool
 		IPv4            bool
 		IPv6            bool
@@ -74,27 +96,43 @@ type enumArgs struct {
 		NoColor         bool
 		NoLocalDatabase bool
 		NoRecursive     bool
+		OnlyResolved    bool
+		Sorted          bool
 		Passive         bool
+		Quiet           bool
+		Randomize       bool
+		RelatedTLDs     bool
 		Silent          bool
 		Sources         bool
+		SourceFiles     bool
+		StatusCodes     bool
+		SourceURLs      bool
 		Verbose         bool
 	}
 	Filepaths struct {
 		AllFilePrefix    string
 		AltWordlist      format.ParseStrings
+		AssetDir         string
 		Blacklist        string
 		BruteWordlist    format.ParseStrings
 		ConfigFile       string
 		Directory        string
+		DOTOutput        string
 		Domains          format.ParseStrings
+		EventLog         string
 		ExcludedSrcs     string
 		IncludedSrcs     string
 		JSONOutput       string
 		LogFile          string
 		Names            format.ParseStrings
+		PauseFile        string
+		Progress         string
+		RDFOutput        string
 		Resolvers        format.ParseStrings
 		Trusted          format.ParseStrings
 		ScriptsDirectory string
+		STIXOutput       string
+		StatsJSON        string
 		TermOut          string
 	}
 }
@@ -109,56 +147,88 @@ func defineEnumArgumentFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
 	enumFlags.Var(args.Excluded, "exclude", "Data source names separated by commas to be excluded")
 	enumFlags.Var(args.Included, "include", "Data source names separated by commas to be included")
+	enumFlags.IntVar(&args.FlushInterval, "flush-interval", 0, "Seconds between writes of the buffered findings to the output files")
+	enumFlags.IntVar(&args.FlushSize, "flush-size", 0, "Kilobytes of findings buffered before writing to the output files (default: write each finding)")
+	enumFlags.DurationVar(&args.Exemplars, "exemplars", 0, "Attach exemplars to the metrics of the requests taking at least the duration, such as 5s")
 	enumFlags.StringVar(&args.Interface, "iface", "", "Provide the network interface to send traffic through")
 	enumFlags.IntVar(&args.MaxDNSQueries, "max-dns-queries", 0, "Deprecated flag to be replaced by dns-qps in version 4.0")
 	enumFlags.IntVar(&args.MaxDNSQueries, "dns-qps", 0, "Maximum number of DNS queries per second across all resolvers")
 	enumFlags.IntVar(&args.ResolverQPS, "rqps", 0, "Maximum number of DNS queries per second for each untrusted resolver")
 	enumFlags.IntVar(&args.TrustedQPS, "trqps", 0, "Maximum number of DNS queries per second for each trusted resolver")
+	enumFlags.IntVar(&args.MaxAddrs, "max-addrs", 0, "Maximum addresses processed for each name, keeping the lowest (default: 0, unlimited)")
 	enumFlags.IntVar(&args.MaxDepth, "max-depth", 0, "Maximum number of subdomain labels for brute forcing")
+	enumFlags.StringVar(&args.MetricsAddr, "metrics", "", "Address the OpenMetrics endpoint listens on, such as 127.0.0.1:9090")
+	enumFlags.IntVar(&args.MaxRecursionDepth, "max-recursion-depth", 0, "Maximum labels beyond the root domain for subdomains that seed further queries")
 	enumFlags.IntVar(&args.MinForRecursive, "min-for-recursive", 1, "Subdomain labels seen before recursive brute forcing (Default: 1)")
 	enumFlags.Var(&args.Ports, "p", "Ports separated by commas (default: 80, 443)")
 	enumFlags.Var(args.Resolvers, "r", "IP addresses of untrusted DNS resolvers (can be used multiple times)")
 	enumFlags.Var(args.Resolvers, "tr", "IP addresses of trusted DNS resolvers (can be used multiple times)")
-	enumFlags.IntVar(&args.Timeout, "timeout", 0, "Number of minutes to let enumeration run before quitting")
+	enumFlags.Var(&args.DoH, "doh", "URLs of DNS-over-HTTPS resolvers (can be used multiple times)")
+	enumFlags.Int64Var(&args.Seed, "seed", 0, "Seed that makes the randomized data source timing repeatable")
+	enumFlags.Var(&args.Timeout, "timeout", "Maximum runtime (e.g. 90m, 2h), where a number alone is minutes")
+	enumFlags.Var(&args.Matcher.Match, "match", "Only output the names matching the regular expression (can be used multiple times)")
+	enumFlags.Var(&args.Matcher.FilterOut, "filter-out", "Leave the names matching the regular expression out of the output (can be used multiple times)")
+	enumFlags.Var(&args.ConfigDump, "config-dump", "Print the settings in effect and exit (-config-dump=json for JSON)")
 }
 
 func defineEnumOptionFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	var placeholder bool
 	enumFlags.BoolVar(&args.Options.Active, "active", false, "Attempt zone transfers and certificate name grabs")
 	enumFlags.BoolVar(&args.Options.BruteForcing, "brute", false, "Execute brute forcing after searches")
+	enumFlags.BoolVar(&args.Options.Daemon, "daemon", false, "Enumerate the newline-delimited root domain names read from stdin")
 	enumFlags.BoolVar(&args.Options.DemoMode, "demo", false, "Censor output to make it suitable for demonstrations")
+	enumFlags.BoolVar(&args.Options.FollowCNAMEs, "follow-cnames", false, "Follow CNAME chains through out-of-scope names back to in-scope names")
 	enumFlags.BoolVar(&args.Options.IPs, "ip", false, "Show the IP addresses for discovered names")
 	enumFlags.BoolVar(&args.Options.IPv4, "ipv4", false, "Show the IPv4 addresses for discovered names")
 	enumFlags.BoolVar(&args.Options.IPv6, "ipv6", false, "Show the IPv6 addresses for discovered names")
+	enumFlags.BoolVar(&args.Matcher.Addresses, "match-addrs", false, "Also test the addresses of the names against the -match and -filter-out patterns")
 	enumFlags.BoolVar(&args.Options.ListSources, "list", false, "Print the names of all available data sources")
 	enumFlags.BoolVar(&args.Options.Alterations, "alts", false, "Enable generation of altered names")
 	enumFlags.BoolVar(&args.Options.NoAlts, "noalts", true, "Deprecated flag to be removed in version 4.0")
 	enumFlags.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
 	enumFlags.BoolVar(&placeholder, "nolocaldb", false, "Deprecated feature to be removed in version 4.0")
 	enumFlags.BoolVar(&args.Options.NoRecursive, "norecursive", false, "Turn off recursive brute forcing")
+	enumFlags.BoolVar(&args.Options.OnlyResolved, "only-resolved", false, "Leave names that do not resolve out of the output, but keep them in the database")
 	enumFlags.BoolVar(&args.Options.Passive, "passive", false, "Disable DNS resolution of names and dependent features")
+	enumFlags.BoolVar(&args.Options.Randomize, "randomize", false, "Randomize the data source start order and first request timing")
+	enumFlags.BoolVar(&args.Options.RelatedTLDs, "related-tlds", false, "Add the registered variants of the root domains under related TLDs to the scope")
 	enumFlags.BoolVar(&placeholder, "share", false, "Deprecated feature to be removed in version 4.0")
+	enumFlags.BoolVar(&args.Options.Quiet, "quiet", false, "Summarize the routine data source errors instead of logging each of them")
 	enumFlags.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
+	enumFlags.BoolVar(&args.Options.Sorted, "sorted", false, "Hold the findings until the end and write them sorted by name for comparing runs")
 	enumFlags.BoolVar(&args.Options.Sources, "src", false, "Print data sources for the discovered names")
+	enumFlags.BoolVar(&args.Options.SourceFiles, "src-files", false, "Also write the findings of each data source to its own source_NAME.jsonl file")
+	enumFlags.BoolVar(&args.Options.StatusCodes, "status-codes", false, "Print the HTTP status codes each data source received once the run completes")
+	enumFlags.BoolVar(&args.Options.SourceURLs, "src-url", false, "Record the URL of the web page each name was extracted from")
 	enumFlags.BoolVar(&args.Options.Verbose, "v", false, "Output status / debug / troubleshooting info")
 }
 
 func defineEnumFilepathFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.StringVar(&args.Filepaths.AllFilePrefix, "oA", "", "Path prefix used for naming all output files")
 	enumFlags.Var(&args.Filepaths.AltWordlist, "aw", "Path to a different wordlist file for alterations")
+	enumFlags.StringVar(&args.Filepaths.AssetDir, "asset-dir", "", "Path to the directory keeping a JSON file for each discovered name")
 	enumFlags.StringVar(&args.Filepaths.Blacklist, "blf", "", "Path to a file providing blacklisted subdomains")
 	enumFlags.Var(&args.Filepaths.BruteWordlist, "w", "Path to a different wordlist file for brute forcing")
-	enumFlags.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	enumFlags.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
 	enumFlags.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the output files")
+	enumFlags.StringVar(&args.Filepaths.DOTOutput, "dot", "", "Path to the Graphviz DOT file rendered from the findings")
 	enumFlags.Var(&args.Filepaths.Domains, "df", "Path to a file providing root domain names")
 	enumFlags.StringVar(&args.Filepaths.ExcludedSrcs, "ef", "", "Path to a file providing data sources to exclude")
+	enumFlags.StringVar(&args.Filepaths.EventLog, "events", "", "Path to the JSONL file receiving the timeline of discovery events")
 	enumFlags.StringVar(&args.Filepaths.IncludedSrcs, "if", "", "Path to a file providing data sources to include")
 	enumFlags.StringVar(&args.Filepaths.JSONOutput, "json", "", "Path to the JSON output file")
+	enumFlags.Var(&args.JSONFormat, "json-format", "Format of the JSON output: native (default) or flat")
+	enumFlags.Var(&args.OutputEncoding, "output-encoding", "Write the internationalized names as ascii (punycode, default) or unicode")
 	enumFlags.StringVar(&args.Filepaths.LogFile, "log", "", "Path to the log file where errors will be written")
 	enumFlags.Var(&args.Filepaths.Names, "nf", "Path to a file providing already known subdomain names (from other tools/sources)")
+	enumFlags.StringVar(&args.Filepaths.PauseFile, "pause-file", "", "Path to a control file that pauses the enumeration while it exists")
+	enumFlags.StringVar(&args.Filepaths.Progress, "progress", "", "Path to a Unix domain socket or named pipe receiving JSON progress events")
 	enumFlags.Var(&args.Filepaths.Resolvers, "rf", "Path to a file providing untrusted DNS resolvers")
 	enumFlags.Var(&args.Filepaths.Trusted, "trf", "Path to a file providing trusted DNS resolvers")
 	enumFlags.StringVar(&args.Filepaths.ScriptsDirectory, "scripts", "", "Path to a directory containing ADS scripts")
+	enumFlags.StringVar(&args.Filepaths.STIXOutput, "stix", "", "Path to the STIX 2.1 bundle output file")
+	enumFlags.StringVar(&args.Filepaths.RDFOutput, "rdf", "", "Path to the RDF output file written in the Turtle format")
+	enumFlags.StringVar(&args.Filepaths.StatsJSON, "stats-json", "", "Path to the JSON file for per-source and per-phase run statistics")
 	enumFlags.StringVar(&args.Filepaths.TermOut, "o", "", "Path to the text file containing terminal stdout/stderr")
 }
 
@@ -170,32 +240,46 @@ func RunEnumCommand(clArgs []string) {
 	if cfg == nil {
 		return
 	}
-	createOutputDirectory(cfg)
-
-	rLog, wLog := io.Pipe()
-	// Setup logging so that messages can be written to the file and used by the program
-	cfg.Log = log.New(wLog, "", log.Lmicroseconds)
-	logfile := filepath.Join(config.OutputDirectory(cfg.Dir), "amass.log")
-	if args.Filepaths.LogFile != "" {
-		logfile = args.Filepaths.LogFile
-	}
-	// Start handling the log messages
-	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose)
-	// Create the System that will provide architecture to this enumeration
-	sys, err := systems.NewLocalSystem(cfg)
-	if err != nil {
-		r.Fprintf(color.Error, "%v\n", err)
-		os.Exit(1)
+	// In daemon mode, the timeout limits the enumeration of each target instead of the System
+	if args.Options.Daemon {
+		args.Timeout = format.ParseTimeout(cfg.Timeout)
+		cfg.Timeout = 0
 	}
+	sys, quiet := startEnumSystem(cfg, args)
 	defer func() { _ = sys.Shutdown() }()
+	defer closeEventLog(sys)
 
-	if err := sys.SetDataSources(datasrcs.GetAllSources(sys)); err != nil {
-		r.Fprintf(color.Error, "%v\n", err)
-		os.Exit(1)
+	// In daemon mode, the targets are read from stdin and enumerated one at a time
+	if args.Options.Daemon {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Monitor for cancellation by the user
+		go func(c context.CancelFunc) {
+			quit := make(chan os.Signal, 1)
+			signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(quit)
+
+			<-quit
+			c()
+		}(cancel)
+
+		runEnumDaemon(ctx, sys, args, os.Stdin)
+		printQuotaUsage(sys)
+		printBrokenSources(sys)
+		printSourceScores(sys)
+		if args.Options.StatusCodes {
+			printStatusCodes(sys)
+		}
+		printDepthCapped(sys)
+		printAddrsCapped(sys)
+		printUnresolved(sys)
+		printPassiveCounts(sys)
+		printQuietSummary(quiet)
+		if args.Filepaths.StatsJSON != "" {
+			saveStatsJSON(sys, args.Filepaths.StatsJSON)
+		}
+		return
 	}
-	// Expand data source category names into the associated source names
-	initializeSourceTags(sys.DataSources())
-	cfg.SourceFilter.Sources = expandCategoryNames(cfg.SourceFilter.Sources, generateCategoryMap(sys))
 	// Create the in-memory graph database used to store enumeration findings
 	graph := netmap.NewGraph(netmap.NewCayleyGraphMemory())
 	defer graph.Close()
@@ -210,38 +294,113 @@ func RunEnumCommand(clArgs []string) {
 	var outChans []chan *requests.Output
 	// This channel sends the signal for goroutines to terminate
 	done := make(chan struct{})
+	// The names resolving to the configured sinkhole and parking ranges are tagged
+	classifier := format.NewAddressClassifier(cfg.Sinkholes)
 	// Print output only if JSONOutput is not meant for STDOUT
 	if args.Filepaths.JSONOutput != "-" {
 		wg.Add(1)
 		// This goroutine will handle printing the output
 		printOutChan := make(chan *requests.Output, 10)
-		go printOutput(e, args, printOutChan, &wg)
+		go printOutput(e, args, classifier, printOutChan, &wg)
 		outChans = append(outChans, printOutChan)
 	}
 
+	// The writes to the output files are batched when the flush flags are provided
+	flusher := newOutputFlusher(args)
+	go flusher.handleSignals(done)
+	// The requests to the data sources can be paused by signal or by the control file
+	go handlePauseControl(e, args.Filepaths.PauseFile, done)
+
 	wg.Add(1)
 	// This goroutine will handle saving the output to the text file
 	txtOutChan := make(chan *requests.Output, 10)
-	go saveTextOutput(e, args, txtOutChan, &wg)
+	go saveTextOutput(e, args, flusher, txtOutChan, &wg)
 	outChans = append(outChans, txtOutChan)
 
 	wg.Add(1)
 	// This goroutine will handle saving the output to the JSON file
 	jsonOutChan := make(chan *requests.Output, 10)
-	go saveJSONOutput(e, args, jsonOutChan, &wg)
+	go saveJSONOutput(e, args, flusher, jsonOutChan, &wg)
 	outChans = append(outChans, jsonOutChan)
 
-	var ctx context.Context
-	var cancel context.CancelFunc
-	if args.Timeout == 0 {
-		ctx, cancel = context.WithCancel(context.Background())
-	} else {
-		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(args.Timeout)*time.Minute)
+	if args.Filepaths.STIXOutput != "" {
+		wg.Add(1)
+		// This goroutine will handle saving the output to the STIX bundle
+		stixOutChan := make(chan *requests.Output, 10)
+		go saveSTIXOutput(args, stixOutChan, &wg)
+		outChans = append(outChans, stixOutChan)
+	}
+
+	if args.Filepaths.RDFOutput != "" {
+		wg.Add(1)
+		// This goroutine will handle saving the output to the RDF document
+		rdfOutChan := make(chan *requests.Output, 10)
+		go saveRDFOutput(args, rdfOutChan, &wg)
+		outChans = append(outChans, rdfOutChan)
+	}
+
+	if args.Options.SourceFiles {
+		wg.Add(1)
+		// This goroutine will handle saving the findings of each data source to its own file
+		srcOutChan := make(chan *requests.Output, 10)
+		go saveSourceFiles(e, args, srcOutChan, &wg)
+		outChans = append(outChans, srcOutChan)
+	}
+
+	if args.Filepaths.AssetDir != "" {
+		wg.Add(1)
+		// This goroutine will handle keeping the file of each discovered name up to date
+		assetOutChan := make(chan *requests.Output, 10)
+		go saveAssetFiles(args, assetOutChan, &wg)
+		outChans = append(outChans, assetOutChan)
+	}
+
+	if cfg.RelatedTLDs.Enabled {
+		wg.Add(1)
+		// This goroutine will handle counting the findings under the domains added by the related TLD expansion
+		relatedOutChan := make(chan *requests.Output, 10)
+		go countRelatedTLDOutput(e, relatedOutChan, &wg)
+		outChans = append(outChans, relatedOutChan)
+	}
+
+	if cfg.Elasticsearch != nil {
+		wg.Add(1)
+		// This goroutine will handle indexing the output into the Elasticsearch cluster
+		esOutChan := make(chan *requests.Output, 10)
+		go saveElasticOutput(cfg, esOutChan, &wg)
+		outChans = append(outChans, esOutChan)
 	}
+
+	if cfg.Neo4j != nil {
+		wg.Add(1)
+		// This goroutine will handle writing the assets and relationships into the Neo4j database
+		neoOutChan := make(chan *requests.Output, 10)
+		go saveNeo4jOutput(cfg, neoOutChan, &wg)
+		outChans = append(outChans, neoOutChan)
+	}
+
+	if args.Filepaths.Progress != "" {
+		wg.Add(1)
+		// This goroutine will handle streaming the progress events to the frontend
+		progressOutChan := make(chan *requests.Output, 10)
+		go saveProgressOutput(sys, args.Filepaths.Progress, progressOutChan, &wg)
+		outChans = append(outChans, progressOutChan)
+	}
+
+	if cfg.Syslog != nil {
+		wg.Add(1)
+		// This goroutine will handle sending the output to the syslog server
+		syslogOutChan := make(chan *requests.Output, 10)
+		go saveSyslogOutput(cfg, syslogOutChan, &wg)
+		outChans = append(outChans, syslogOutChan)
+	}
+
+	// The System context carries the deadline of the timeout option
+	ctx, cancel := context.WithCancel(sys.Context())
 	defer cancel()
 
 	wg.Add(1)
-	go processOutput(ctx, graph, e, outChans, done, &wg)
+	go processOutput(ctx, graph, e, &args.Matcher, classifier, outChans, done, &wg)
 	// Monitor for cancellation by the user
 	go func(d chan struct{}, c context.Context, f context.CancelFunc) {
 		quit := make(chan os.Signal, 1)
@@ -255,6 +414,7 @@ func RunEnumCommand(clArgs []string) {
 		case <-c.Done():
 		}
 	}(done, ctx, cancel)
+	printQuotaEstimates(cfg, sys)
 	// Start the enumeration process
 	if err := e.Start(ctx); err != nil {
 		r.Println(err)
@@ -263,6 +423,21 @@ func RunEnumCommand(clArgs []string) {
 	// Let all the output goroutines know that the enumeration has finished
 	close(done)
 	wg.Wait()
+	printQuotaUsage(sys)
+	printBrokenSources(sys)
+	printSourceScores(sys)
+	if args.Options.StatusCodes {
+		printStatusCodes(sys)
+	}
+	printDepthCapped(sys)
+	printAddrsCapped(sys)
+	printUnresolved(sys)
+	printPassiveCounts(sys)
+	printQuietSummary(quiet)
+	printTimeLimited(sys)
+	if args.Filepaths.DOTOutput != "" {
+		saveDOTOutput(graph, cfg.UUID.String(), args.Filepaths.DOTOutput)
+	}
 	// If necessary, handle graph database migration
 	if len(e.Sys.GraphDatabases()) > 0 {
 		fmt.Fprintf(color.Error, "\n%s\n", green("The enumeration has finished"))
@@ -279,6 +454,7 @@ func RunEnumCommand(clArgs []string) {
 			c()
 		}(cancel)
 		// Copy the graph of findings into the system graph databases
+		start := time.Now()
 		for _, g := range e.Sys.GraphDatabases() {
 			fmt.Fprintf(color.Error, "%s%s%s\n",
 				yellow("Discoveries are being migrated into the "), yellow(g.String()), yellow(" database"))
@@ -288,10 +464,153 @@ func RunEnumCommand(clArgs []string) {
 					red("The database migration to "), red(g.String()), red(" failed: "), red(err.Error()))
 			}
 		}
+		sys.Stats().Phase("migration", time.Since(start))
+	}
+
+	if args.Filepaths.StatsJSON != "" {
+		saveStatsJSON(sys, args.Filepaths.StatsJSON)
+	}
+}
+
+// saveDOTOutput renders the findings of the enumeration using the same graph as the viz subcommand.
+func saveDOTOutput(graph *netmap.Graph, uuid, path string) {
+	nodes, edges := viz.VizData(context.Background(), graph, []string{uuid})
+	if len(nodes) > maxReadableDOTNodes {
+		fmt.Fprintf(color.Error, "%s\n", yellow(fmt.Sprintf(
+			"The DOT graph contains %d nodes and Graphviz may struggle to render it legibly", len(nodes))))
+	}
+
+	if err := writeGraphOutputFile("dot", path, nodes, edges); err != nil {
+		r.Fprintf(color.Error, "Failed to write the DOT output file: %v\n", err)
+	}
+}
+
+// printDepthCapped reports the subdomains that were not used as query seeds due to the maximum recursion depth.
+func printDepthCapped(sys systems.System) {
+	if n := sys.Stats().Counter(enum.DepthCappedCounter); n > 0 {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Recursion depth:"),
+			yellow(fmt.Sprintf("%d subdomains beyond the maximum were not used as query seeds", n)))
+	}
+}
+
+// printAddrsCapped reports the names that had some of their addresses left out due to the max_addrs_per_name option.
+func printAddrsCapped(sys systems.System) {
+	if n := sys.Stats().Counter(enum.AddrsCappedCounter); n > 0 {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Addresses per name:"),
+			yellow(fmt.Sprintf("%d names resolved to more than %d addresses and only the lowest were processed", n, sys.Config().MaxAddrsPerName)))
+	}
+}
+
+// printPassiveCounts reports how many of the names were resolved, and how many were only observed by the data sources.
+func printPassiveCounts(sys systems.System) {
+	resolved := sys.Stats().Counter(enum.ResolvedCounter)
+	passive := sys.Stats().Counter(enum.PassiveOnlyCounter)
+
+	if resolved > 0 || passive > 0 {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Names:"),
+			yellow(fmt.Sprintf("%d resolved, %d only observed passively by the data sources", resolved, passive)))
+	}
+}
+
+// printTimeLimited reports that the enumeration was stopped by the timeout option before it finished.
+func printTimeLimited(sys systems.System) {
+	if sys.Context().Err() == context.DeadlineExceeded {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Time limit:"),
+			yellow(fmt.Sprintf("The run was stopped after %v and the findings up to that point were written", sys.Config().Timeout)))
+	}
+}
+
+// printUnresolved reports the names from the data sources that were left out of the output by the only_resolved option.
+func printUnresolved(sys systems.System) {
+	if n := sys.Stats().Counter(enum.UnresolvedCounter); n > 0 {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Only resolved:"),
+			yellow(fmt.Sprintf("%d names that did not resolve were kept out of the output", n)))
+	}
+}
+
+func saveStatsJSON(sys systems.System, path string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to open the statistics file: %v\n", err)
+		return
+	}
+	defer func() {
+		_ = f.Sync()
+		_ = f.Close()
+	}()
+
+	if err := sys.Stats().WriteJSON(f); err != nil {
+		r.Fprintf(color.Error, "Failed to write the statistics file: %v\n", err)
+	}
+}
+
+// closeEventLog writes the remaining discovery events and closes the event log file.
+func closeEventLog(sys systems.System) {
+	if err := sys.EventLog().Close(); err != nil {
+		r.Fprintf(color.Error, "Failed to write the event log: %v\n", err)
+	}
+}
+
+// startEnumSystem creates the output directory, starts handling the log messages and returns
+// the System that provides architecture to the enumerations, with the data sources registered.
+func startEnumSystem(cfg *config.Config, args *enumArgs) (systems.System, *quietLog) {
+	createOutputDirectory(cfg)
+
+	rLog, wLog := io.Pipe()
+	// Setup logging so that messages can be written to the file and used by the program
+	cfg.Log = log.New(wLog, "", log.Lmicroseconds)
+	logfile := filepath.Join(config.OutputDirectory(cfg.Dir), "amass.log")
+	if args.Filepaths.LogFile != "" {
+		logfile = args.Filepaths.LogFile
+	}
+	var quiet *quietLog
+	if args.Options.Quiet {
+		quiet = newQuietLog()
+	}
+	// Start handling the log messages
+	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose, quiet)
+	// Create the System that will provide architecture to this enumeration
+	sys, err := systems.NewLocalSystem(cfg)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if args.Filepaths.EventLog != "" {
+		f, err := os.OpenFile(args.Filepaths.EventLog, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the event log: %v\n", err)
+			os.Exit(1)
+		}
+		sys.SetEventLog(eventlog.NewLog(f))
 	}
+
+	srcs := datasrcs.GetAllSources(sys)
+	quiet.setSources(srcs)
+	if err := sys.SetDataSources(srcs); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	sys.Stats().EnableExemplars(args.Exemplars)
+	if args.MetricsAddr != "" {
+		if err := startMetricsServer(args.MetricsAddr, sys.Stats()); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Expand data source category names into the associated source names
+	initializeSourceTags(sys.DataSources())
+	cfg.SourceFilter.Sources = expandCategoryNames(cfg.SourceFilter.Sources, generateCategoryMap(sys))
+	return sys, quiet
 }
 
 func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
+	return parseEnumCommand("enum", enumUsageMsg, clArgs, nil)
+}
+
+// parseEnumCommand parses the enumeration flags of the subcommand, along with the flags added by
+// define, and returns the configuration built from them, or nil when the subcommand has no work to do.
+func parseEnumCommand(name, usage string, clArgs []string, define func(*flag.FlagSet)) (*config.Config, *enumArgs) {
 	args := enumArgs{
 		AltWordList:       stringset.New(),
 		AltWordListMask:   stringset.New(),
@@ -306,7 +625,7 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		Trusted:           stringset.New(),
 	}
 	var help1, help2 bool
-	enumCommand := flag.NewFlagSet("enum", flag.ContinueOnError)
+	enumCommand := flag.NewFlagSet(name, flag.ContinueOnError)
 
 	enumBuf := new(bytes.Buffer)
 	enumCommand.SetOutput(enumBuf)
@@ -316,9 +635,12 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 	defineEnumArgumentFlags(enumCommand, &args)
 	defineEnumOptionFlags(enumCommand, &args)
 	defineEnumFilepathFlags(enumCommand, &args)
+	if define != nil {
+		define(enumCommand)
+	}
 
 	if len(clArgs) < 1 {
-		CommandUsage(enumUsageMsg, enumCommand, enumBuf)
+		CommandUsage(usage, enumCommand, enumBuf)
 		return nil, &args
 	}
 	if err := enumCommand.Parse(clArgs); err != nil {
@@ -326,7 +648,7 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		os.Exit(1)
 	}
 	if help1 || help2 {
-		CommandUsage(enumUsageMsg, enumCommand, enumBuf)
+		CommandUsage(usage, enumCommand, enumBuf)
 		return nil, &args
 	}
 
@@ -357,7 +679,7 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 	if (args.Excluded.Len() > 0 || args.Filepaths.ExcludedSrcs != "") &&
 		(args.Included.Len() > 0 || args.Filepaths.IncludedSrcs != "") {
 		r.Fprintln(color.Error, "Cannot provide both include and exclude arguments")
-		CommandUsage(enumUsageMsg, enumCommand, enumBuf)
+		CommandUsage(usage, enumCommand, enumBuf)
 		os.Exit(1)
 	}
 	if err := processEnumInputFiles(&args); err != nil {
@@ -381,6 +703,11 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		r.Fprintf(color.Error, "Configuration error: %v\n", err)
 		os.Exit(1)
 	}
+	// Check if the user has requested the settings in effect
+	if args.ConfigDump != "" {
+		printEffectiveConfig(cfg, string(args.ConfigDump))
+		return nil, &args
+	}
 	// Check if the user has requested the data source names
 	if args.Options.ListSources {
 		for _, line := range GetAllSourceInfo(cfg) {
@@ -397,14 +724,18 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		r.Fprintln(color.Error, "Ports can only be scanned in the active mode")
 		os.Exit(1)
 	}
-	if len(cfg.Domains()) == 0 {
+	if len(cfg.Domains()) == 0 && !args.Options.Daemon {
 		r.Fprintln(color.Error, "Configuration error: No root domain names were provided")
 		os.Exit(1)
 	}
+	// The blacklist wins when it contradicts the scope, so warn the user before the run
+	for _, conflict := range cfg.ScopeConflicts() {
+		fmt.Fprintf(color.Error, "%s%s\n", yellow("Scope warning: "), conflict)
+	}
 	return cfg, &args
 }
 
-func printOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+func printOutput(e *enum.Enumeration, args *enumArgs, classifier *format.AddressClassifier, output chan *requests.Output, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	var total int
@@ -436,9 +767,26 @@ func printOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.Outp
 	} else if !args.Options.Passive {
 		format.PrintEnumerationSummary(total, tags, asns, args.Options.DemoMode)
 	}
+	printClassCounts(classifier)
 }
 
-func saveTextOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+// printClassCounts reports the number of names that only resolve to sinkhole and parking addresses.
+func printClassCounts(classifier *format.AddressClassifier) {
+	if classifier == nil {
+		return
+	}
+
+	counts := classifier.Counts()
+	fmt.Fprintf(color.Error, "%s%s, %s%s",
+		yellow(strconv.Itoa(counts[config.SinkholeClass])), green(" names resolve to sinkholes"),
+		yellow(strconv.Itoa(counts[config.ParkedClass])), green(" to parking services"))
+	if n := classifier.Excluded(); n > 0 {
+		fmt.Fprintf(color.Error, "%s%s%s", green(" ("), yellow(strconv.Itoa(n)), green(" left out of the output)"))
+	}
+	fmt.Fprintln(color.Error)
+}
+
+func saveTextOutput(e *enum.Enumeration, args *enumArgs, flusher *outputFlusher, output chan *requests.Output, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	dir := config.OutputDirectory(e.Config.Dir)
@@ -465,6 +813,9 @@ func saveTextOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.O
 
 	_ = outptr.Truncate(0)
 	_, _ = outptr.Seek(0, 0)
+
+	w := flusher.wrap(outptr)
+	defer func() { _ = w.Close() }()
 	// Save all the output returned by the enumeration
 	for out := range output {
 		out.Addresses = format.DesiredAddrTypes(out.Addresses, args.Options.IPv4, args.Options.IPv6)
@@ -478,11 +829,11 @@ func saveTextOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.O
 			ips = " " + ips
 		}
 		// Write the line to the output file
-		fmt.Fprintf(outptr, "%s%s%s\n", source, name, ips)
+		fmt.Fprintf(w, "%s%s%s\n", source, name, ips)
 	}
 }
 
-func saveJSONOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+func saveJSONOutput(e *enum.Enumeration, args *enumArgs, flusher *outputFlusher, output chan *requests.Output, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	dir := config.OutputDirectory(e.Config.Dir)
@@ -519,15 +870,207 @@ func saveJSONOutput(e *enum.Enumeration, args *enumArgs, output chan *requests.O
 	_ = jsonptr.Truncate(0)
 	_, _ = jsonptr.Seek(0, 0)
 
-	enc := json.NewEncoder(jsonptr)
+	w := flusher.wrap(jsonptr)
+	defer func() { _ = w.Close() }()
+
+	enc := json.NewEncoder(w)
 	// Save all the output returned by the enumeration
 	for out := range output {
 		// Handle encoding the result as JSON
+		if args.JSONFormat.Flat() {
+			_ = enc.Encode(format.NewFlatOutput(out))
+			continue
+		}
 		_ = enc.Encode(out)
 	}
 }
 
-func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, outputs []chan *requests.Output, done chan struct{}, wg *sync.WaitGroup) {
+func saveSourceFiles(e *enum.Enumeration, args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	files := format.NewSourceFiles(config.OutputDirectory(e.Config.Dir),
+		args.Filepaths.AllFilePrefix, args.JSONFormat.Flat())
+	var failed bool
+	for out := range output {
+		if err := files.Write(out); err != nil && !failed {
+			r.Fprintf(color.Error, "Failed to write the data source output files: %v\n", err)
+			failed = true
+		}
+	}
+
+	if err := files.Close(); err != nil {
+		r.Fprintf(color.Error, "Failed to close the data source output files: %v\n", err)
+	}
+}
+
+func saveAssetFiles(args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	files := format.NewAssetFiles(args.Filepaths.AssetDir)
+	var failed bool
+	for out := range output {
+		if err := files.Write(out); err != nil && !failed {
+			r.Fprintf(color.Error, "Failed to write the asset files: %v\n", err)
+			failed = true
+		}
+	}
+
+	fmt.Fprintf(color.Error, "%s %s\n", blue("Assets:"), yellow(fmt.Sprintf("%d of %d files created or changed in %s",
+		len(files.Updated()), files.Names(), args.Filepaths.AssetDir)))
+}
+
+func countRelatedTLDOutput(e *enum.Enumeration, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	counts := make(map[string]int)
+	for out := range output {
+		// The domain is written in the output encoding, while the variants are kept in punycode
+		counts[dns.ToASCII(out.Domain)]++
+	}
+
+	related := e.RelatedTLDs()
+	if len(related) == 0 {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Related TLDs:"),
+			yellow("No registered variants of the root domains were found"))
+		return
+	}
+
+	var variants []string
+	for variant := range related {
+		variants = append(variants, variant)
+	}
+	sort.Strings(variants)
+
+	for _, variant := range variants {
+		fmt.Fprintf(color.Error, "%s %s\n", blue("Related TLDs:"), yellow(fmt.Sprintf(
+			"%d names found under %s, a variant of %s", counts[variant], variant, related[variant])))
+	}
+}
+
+func saveSTIXOutput(args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	start := time.Now()
+	bundle := format.NewSTIXBundle(start)
+	// Collect all the output returned by the enumeration
+	for out := range output {
+		bundle.Add(out, start, time.Now())
+	}
+
+	stixptr, err := os.OpenFile(args.Filepaths.STIXOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to open the STIX output file: %v\n", err)
+		return
+	}
+	defer func() {
+		_ = stixptr.Sync()
+		_ = stixptr.Close()
+	}()
+
+	if err := bundle.Encode(stixptr); err != nil {
+		r.Fprintf(color.Error, "Failed to write the STIX output file: %v\n", err)
+	}
+}
+
+func saveRDFOutput(args *enumArgs, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	graph := format.NewRDFGraph()
+	// Collect all the output returned by the enumeration
+	for out := range output {
+		graph.Add(out)
+	}
+
+	rdfptr, err := os.OpenFile(args.Filepaths.RDFOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to open the RDF output file: %v\n", err)
+		return
+	}
+	defer func() {
+		_ = rdfptr.Sync()
+		_ = rdfptr.Close()
+	}()
+
+	if err := graph.Encode(rdfptr); err != nil {
+		r.Fprintf(color.Error, "Failed to write the RDF output file: %v\n", err)
+	}
+}
+
+func saveElasticOutput(cfg *config.Config, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	sink := format.NewElasticSink(cfg.Elasticsearch, cfg.UUID.String(), cfg.Log)
+	// The documents are queued by the sink, so indexing never holds up the enumeration
+	for out := range output {
+		sink.Index(out)
+	}
+
+	if err := sink.Close(); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+	}
+	fmt.Fprintf(color.Error, "%s %s\n", blue("Elasticsearch:"),
+		yellow(fmt.Sprintf("%d documents indexed into %s", sink.Indexed(), cfg.Elasticsearch.Index)))
+}
+
+func saveNeo4jOutput(cfg *config.Config, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	sink := format.NewNeo4jSink(cfg.Neo4j, cfg.UUID.String(), cfg.Log)
+	// The findings are written in batches, each within a single transaction
+	for out := range output {
+		sink.Send(out)
+	}
+
+	if err := sink.Close(); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+	}
+	fmt.Fprintf(color.Error, "%s %s\n", blue("Neo4j:"),
+		yellow(fmt.Sprintf("%d names written to %s", sink.Written(), cfg.Neo4j)))
+}
+
+func saveSyslogOutput(cfg *config.Config, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	sink := format.NewSyslogSink(cfg.Syslog, cfg.UUID.String(), cfg.Log)
+	// Messages are dropped rather than queued without limit when the server falls behind
+	for out := range output {
+		sink.Send(out)
+	}
+
+	if err := sink.Close(); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+	}
+	fmt.Fprintf(color.Error, "%s %s\n", blue("Syslog:"),
+		yellow(fmt.Sprintf("%d messages sent to %s", sink.Sent(), cfg.Syslog)))
+}
+
+func saveProgressOutput(sys systems.System, path string, output chan *requests.Output, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	sink := format.NewProgressSink(path, sys.Config().Log)
+	// The totals of the data sources are sent as they change, between the discoveries
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+loop:
+	for {
+		select {
+		case out, ok := <-output:
+			if !ok {
+				break loop
+			}
+			sink.Discovery(out)
+		case <-t.C:
+			sink.SourceProgress(sys.Stats().Sources())
+		}
+	}
+
+	sink.SourceProgress(sys.Stats().Sources())
+	if err := sink.Close(); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+	}
+}
+
+func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, matcher *format.OutputMatcher, classifier *format.AddressClassifier, outputs []chan *requests.Output, done chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer func() {
 		// Signal all the other output goroutines to terminate
@@ -539,12 +1082,33 @@ func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, ou
 	// This filter ensures that we only get new names
 	known := stringset.New()
 	defer known.Close()
+	// With the sorted_output option, the findings are held until the enumeration is complete
+	var held []*requests.Output
+	send := func() {
+		format.SortOutput(held)
+		for _, o := range held {
+			for _, ch := range outputs {
+				ch <- o
+			}
+		}
+		held = nil
+	}
 	// The function that obtains output from the enum and puts it on the channel
-	extract := func(limit int) {
+	extract := func(ctx context.Context, limit int) {
 		for _, o := range ExtractOutput(ctx, g, e, known, true, limit) {
 			if !o.Complete(e.Config.Passive) || !e.Config.IsDomainInScope(o.Name) {
 				continue
 			}
+			// The patterns only select the output, since the findings are already in the graph
+			if !matcher.Allow(o) || !classifier.Allow(o) {
+				continue
+			}
+			// The names are only written in the selected encoding once the checks are complete
+			format.EncodeOutput(o, e.Config.OutputEncoding)
+			if e.Config.SortedOutput {
+				held = append(held, o)
+				continue
+			}
 			for _, ch := range outputs {
 				ch <- o
 			}
@@ -556,18 +1120,21 @@ func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, ou
 	for {
 		select {
 		case <-ctx.Done():
-			extract(0)
+			// The findings are still extracted after the deadline has passed
+			extract(context.Background(), 0)
+			send()
 			return
 		case <-done:
-			extract(0)
+			extract(context.Background(), 0)
+			send()
 			return
 		case <-t.C:
-			extract(100)
+			extract(ctx, 100)
 		}
 	}
 }
 
-func writeLogsAndMessages(logs *io.PipeReader, logfile string, verbose bool) {
+func writeLogsAndMessages(logs *io.PipeReader, logfile string, verbose bool, quiet *quietLog) {
 	wildcard := regexp.MustCompile("DNS wildcard")
 	queries := regexp.MustCompile("Querying")
 
@@ -596,12 +1163,22 @@ func writeLogsAndMessages(logs *io.PipeReader, logfile string, verbose bool) {
 			break
 		}
 
+		// Remove the timestamp
+		parts := strings.Split(line, " ")
+		msg := strings.Join(parts[1:], " ")
+		// The routine data source errors are only counted in quiet mode
+		suppressed, class := quiet.suppress(msg)
+		if suppressed {
+			continue
+		}
 		if filePtr != nil {
 			fmt.Fprintln(filePtr, line)
 		}
-		// Remove the timestamp
-		parts := strings.Split(line, " ")
-		line = strings.Join(parts[1:], " ")
+		line = msg
+		// Authentication errors are shown even though the other errors are suppressed
+		if class == "auth" {
+			fgR.Fprintln(color.Error, line)
+		}
 		// Check for Amass DNS wildcard messages
 		if verbose && wildcard.FindString(line) != "" {
 			fgR.Fprintln(color.Error, line)
@@ -634,7 +1211,7 @@ func processEnumInputFiles(args *enumArgs) error {
 		}
 	}
 	if args.Filepaths.Blacklist != "" {
-		list, err := config.GetListFromFile(args.Filepaths.Blacklist)
+		list, err := config.GetScopeListFromFile(args.Filepaths.Blacklist)
 		if err != nil {
 			return fmt.Errorf("failed to parse the blacklist file: %v", err)
 		}
@@ -665,7 +1242,7 @@ func processEnumInputFiles(args *enumArgs) error {
 	}
 	if len(args.Filepaths.Domains) > 0 {
 		for _, f := range args.Filepaths.Domains {
-			list, err := config.GetListFromFile(f)
+			list, err := config.GetScopeListFromFile(f)
 			if err != nil {
 				return fmt.Errorf("failed to parse the domain names file: %v", err)
 			}
@@ -728,6 +1305,12 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.MaxDepth != 0 {
 		conf.MaxDepth = e.MaxDepth
 	}
+	if e.MaxRecursionDepth != 0 {
+		conf.MaxRecursionDepth = e.MaxRecursionDepth
+	}
+	if e.MaxAddrs != 0 {
+		conf.MaxAddrsPerName = e.MaxAddrs
+	}
 	if e.Options.Active {
 		conf.Active = true
 		conf.Passive = false
@@ -741,9 +1324,36 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.Blacklist.Len() > 0 {
 		conf.Blacklist = e.Blacklist.Slice()
 	}
+	if e.Options.FollowCNAMEs {
+		conf.FollowCNAMEs = true
+	}
+	if e.Options.OnlyResolved {
+		conf.OnlyResolved = true
+	}
+	if e.Options.Sorted {
+		conf.SortedOutput = true
+	}
+	if e.Options.SourceURLs {
+		conf.SourceURLs = true
+	}
+	if e.OutputEncoding != "" {
+		conf.OutputEncoding = e.OutputEncoding.String()
+	}
+	if e.Options.Randomize {
+		conf.RandomizeSources = true
+	}
+	if e.Options.RelatedTLDs {
+		conf.RelatedTLDs.Enabled = true
+	}
+	if e.Seed != 0 {
+		conf.RandomSeed = e.Seed
+	}
 	if e.Options.Verbose {
 		conf.Verbose = true
 	}
+	if e.Timeout > 0 {
+		conf.Timeout = time.Duration(e.Timeout)
+	}
 	if e.ResolverQPS > 0 {
 		conf.ResolversQPS = e.ResolverQPS
 	}
@@ -756,6 +1366,11 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.Trusted.Len() > 0 {
 		conf.SetTrustedResolvers(e.Trusted.Slice()...)
 	}
+	if len(e.DoH) > 0 {
+		if err := conf.SetDoHResolvers(e.DoH...); err != nil {
+			return err
+		}
+	}
 	if e.MaxDNSQueries > 0 {
 		conf.MaxDNSQueries = e.MaxDNSQueries
 	}
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/format"
+)
+
+// The buffer size used when only the -flush-interval flag is provided
+const defaultFlushSize = 64 << 10
+
+// outputFlusher batches the writes to the output files as selected by the -flush-size and
+// -flush-interval flags, and writes out the buffered findings when a flush signal is received.
+type outputFlusher struct {
+	sync.Mutex
+	size     int
+	interval time.Duration
+	writers  []*format.BufferedWriter
+}
+
+func newOutputFlusher(args *enumArgs) *outputFlusher {
+	of := &outputFlusher{
+		size:     args.FlushSize * 1024,
+		interval: time.Duration(args.FlushInterval) * time.Second,
+	}
+
+	if of.size <= 0 && of.interval > 0 {
+		of.size = defaultFlushSize
+	}
+	return of
+}
+
+// wrap returns the writer used for the output file. Without the flags, each finding is written immediately.
+func (of *outputFlusher) wrap(w io.Writer) *format.BufferedWriter {
+	of.Lock()
+	defer of.Unlock()
+
+	bw := format.NewBufferedWriter(w, of.size, of.interval)
+	of.writers = append(of.writers, bw)
+	return bw
+}
+
+func (of *outputFlusher) flush() {
+	of.Lock()
+	defer of.Unlock()
+
+	for _, bw := range of.writers {
+		_ = bw.Flush()
+	}
+}
+
+// handleSignals flushes the output files each time one of the flush signals is received, until done is closed.
+func (of *outputFlusher) handleSignals(done chan struct{}) {
+	if len(flushSignals) == 0 {
+		return
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, flushSignals...)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-sig:
+			of.flush()
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import "os"
+
+// Windows has no signal for requesting a flush, so the buffered output is written as it fills,
+// at the flush interval, and when the enumeration completes.
+var flushSignals []os.Signal
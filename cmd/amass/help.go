@@ -25,6 +25,10 @@ func RunHelpCommand(clArgs []string) {
 		RunTrackCommand(help)
 	case "viz":
 		RunVizCommand(help)
+	case "report":
+		RunReportCommand(help)
+	case "scripts":
+		RunScriptsCommand(help)
 	default:
 		CommandUsage(mainUsageMsg, helpCommand, helpBuf)
 		return
@@ -12,6 +12,7 @@ import (
 	"io"
 	"log"
 	"math/rand"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -23,8 +24,9 @@ import (
 	"github.com/aokimio/Amass/v3/datasrcs"
 	"github.com/aokimio/Amass/v3/format"
 	"github.com/aokimio/Amass/v3/intel"
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
@@ -35,17 +37,21 @@ const (
 type intelArgs struct {
 	Addresses        format.ParseIPs
 	ASNs             format.ParseASNs
+	ASNWorkers       int
 	CIDRs            format.ParseCIDRs
 	OrganizationName string
 	Domains          *stringset.Set
 	Excluded         *stringset.Set
 	Included         *stringset.Set
+	MaxASNAddrs      int
 	MaxDNSQueries    int
 	Ports            format.ParseInts
 	Resolvers        *stringset.Set
-	Timeout          int
+	Timeout          format.ParseTimeout
+	ConfigDump       format.ParseDumpFormat
 	Options          struct {
 		Active       bool
+		Aggregate    bool
 		DemoMode     bool
 		IPs          bool
 		IPv4         bool
@@ -70,19 +76,23 @@ type intelArgs struct {
 func defineIntelArgumentFlags(intelFlags *flag.FlagSet, args *intelArgs) {
 	intelFlags.Var(&args.Addresses, "addr", "IPs and ranges (192.168.1.1-254) separated by commas")
 	intelFlags.Var(&args.ASNs, "asn", "ASNs separated by commas (can be used multiple times)")
+	intelFlags.IntVar(&args.ASNWorkers, "asn-workers", 0, "Number of ASNs expanded into netblocks at the same time (default: 4)")
 	intelFlags.Var(&args.CIDRs, "cidr", "CIDRs separated by commas (can be used multiple times)")
 	intelFlags.StringVar(&args.OrganizationName, "org", "", "Search string provided against AS description information")
 	intelFlags.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
 	intelFlags.Var(args.Excluded, "exclude", "Data source names separated by commas to be excluded")
 	intelFlags.Var(args.Included, "include", "Data source names separated by commas to be included")
+	intelFlags.IntVar(&args.MaxASNAddrs, "max-asn-addrs", 0, "Maximum addresses expanded from the netblocks of each ASN, taking the smallest first (default: 0, unlimited)")
 	intelFlags.IntVar(&args.MaxDNSQueries, "max-dns-queries", 0, "Maximum number of concurrent DNS queries")
 	intelFlags.Var(&args.Ports, "p", "Ports separated by commas (default: 80, 443)")
 	intelFlags.Var(args.Resolvers, "r", "IP addresses of preferred DNS resolvers (can be used multiple times)")
-	intelFlags.IntVar(&args.Timeout, "timeout", 0, "Number of minutes to let enumeration run before quitting")
+	intelFlags.Var(&args.Timeout, "timeout", "Maximum runtime (e.g. 90m, 2h), where a number alone is minutes")
+	intelFlags.Var(&args.ConfigDump, "config-dump", "Print the settings in effect and exit (-config-dump=json for JSON)")
 }
 
 func defineIntelOptionFlags(intelFlags *flag.FlagSet, args *intelArgs) {
 	intelFlags.BoolVar(&args.Options.Active, "active", false, "Attempt certificate name grabs")
+	intelFlags.BoolVar(&args.Options.Aggregate, "aggregate", false, "Print ASN netblocks as the minimal set of covering CIDRs")
 	intelFlags.BoolVar(&args.Options.DemoMode, "demo", false, "Censor output to make it suitable for demonstrations")
 	intelFlags.BoolVar(&args.Options.IPs, "ip", false, "Show the IP addresses for discovered names")
 	intelFlags.BoolVar(&args.Options.IPv4, "ipv4", false, "Show the IPv4 addresses for discovered names")
@@ -94,7 +104,7 @@ func defineIntelOptionFlags(intelFlags *flag.FlagSet, args *intelArgs) {
 }
 
 func defineIntelFilepathFlags(intelFlags *flag.FlagSet, args *intelArgs) {
-	intelFlags.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	intelFlags.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
 	intelFlags.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the output files")
 	intelFlags.Var(&args.Filepaths.Domains, "df", "Path to a file providing root domain names")
 	intelFlags.StringVar(&args.Filepaths.ExcludedSrcs, "ef", "", "Path to a file providing data sources to exclude")
@@ -166,6 +176,12 @@ func RunIntelCommand(clArgs []string) {
 		os.Exit(1)
 	}
 
+	// Check if the user has requested the settings in effect
+	if args.ConfigDump != "" {
+		printEffectiveConfig(cfg, string(args.ConfigDump))
+		return
+	}
+
 	// Some input validation
 	if !args.Options.ReverseWhois && args.OrganizationName == "" && !args.Options.ListSources &&
 		len(args.Addresses) == 0 && len(args.CIDRs) == 0 && len(args.ASNs) == 0 {
@@ -193,7 +209,7 @@ func RunIntelCommand(clArgs []string) {
 	}
 
 	createOutputDirectory(cfg)
-	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose)
+	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose, nil)
 
 	sys, err := systems.NewLocalSystem(cfg)
 	if err != nil {
@@ -206,17 +222,32 @@ func RunIntelCommand(clArgs []string) {
 
 	if args.OrganizationName != "" {
 		var asns []int
+		known := make(map[int]struct{})
+		// NetworksDB maps the organization name to the matching organizations and their ASNs
+		for _, src := range sys.DataSources() {
+			if n, ok := src.(*datasrcs.NetworksDB); ok {
+				for _, asn := range n.SearchOrganization(args.OrganizationName) {
+					if _, found := known[asn]; !found {
+						known[asn] = struct{}{}
+						asns = append(asns, asn)
+					}
+				}
+			}
+		}
 		for _, entry := range sys.Cache().DescriptionSearch(args.OrganizationName) {
-			asns = append(asns, entry.ASN)
+			if _, found := known[entry.ASN]; !found {
+				known[entry.ASN] = struct{}{}
+				asns = append(asns, entry.ASN)
+			}
 		}
 		if len(asns) > 0 {
-			printNetblocks(asns, cfg, sys)
+			printNetblocks(asns, args.Options.Aggregate, sys)
 		}
 		return
 	}
 	// Check if the user requested additional ASN & netblock information
 	if args.Options.ListSources && len(args.ASNs) > 0 {
-		printNetblocks(args.ASNs, cfg, sys)
+		printNetblocks(args.ASNs, args.Options.Aggregate, sys)
 		return
 	}
 
@@ -237,13 +268,8 @@ func RunIntelCommand(clArgs []string) {
 		args.Options.IPv6 = false
 		go func() { _ = ic.ReverseWhois() }()
 	} else {
-		var ctx context.Context
-		var cancel context.CancelFunc
-		if args.Timeout == 0 {
-			ctx, cancel = context.WithCancel(context.Background())
-		} else {
-			ctx, cancel = context.WithTimeout(context.Background(), time.Duration(args.Timeout)*time.Minute)
-		}
+		// The System context carries the deadline of the timeout option
+		ctx, cancel := context.WithCancel(sys.Context())
 		defer cancel()
 		// Monitor for cancellation by the user
 		go func() {
@@ -260,27 +286,67 @@ func RunIntelCommand(clArgs []string) {
 		go func() { _ = ic.HostedDomains(ctx) }()
 	}
 
-	if !processIntelOutput(ic, &args) {
+	found := processIntelOutput(ic, &args)
+	printSkippedNetblocks(ic)
+	printTimeLimited(sys)
+	if !found {
 		os.Exit(1)
 	}
 }
 
-func printNetblocks(asns []int, cfg *config.Config, sys systems.System) {
-	for _, asn := range asns {
-		systems.PopulateCache(context.Background(), asn, sys)
+// printSkippedNetblocks reports the netblocks left out due to the max_asn_addrs option.
+func printSkippedNetblocks(ic *intel.Collection) {
+	skipped := ic.SkippedNetblocks()
+	if len(skipped) == 0 {
+		return
+	}
+
+	var total int
+	for _, s := range skipped {
+		total += s.Addresses
+	}
+	fmt.Fprintf(color.Error, "%s\n", yellow(fmt.Sprintf("%d netblocks holding %d addresses exceeded the maximum of %d addresses per ASN and were skipped:",
+		len(skipped), total, ic.Config.MaxASNAddrs)))
+	for _, s := range skipped {
+		fmt.Fprintf(color.Error, "\t%s %s\n", yellow(fmt.Sprintf("AS%d", s.ASN)), s.Netblock)
+	}
+}
 
+func printNetblocks(asns []int, aggregate bool, sys systems.System) {
+	systems.PopulateCacheForASNs(sys.Context(), asns, sys)
+
+	for _, asn := range asns {
 		d := sys.Cache().ASNSearch(asn)
 		if d == nil {
 			continue
 		}
 
 		fmt.Printf("%s%s %s %s\n", blue("ASN: "), yellow(strconv.Itoa(asn)), green("-"), green(d.Description))
-		for _, cidr := range d.Netblocks {
+		netblocks := d.Netblocks
+		if aggregate {
+			netblocks = aggregateNetblocks(netblocks)
+		}
+		for _, cidr := range netblocks {
 			fmt.Printf("%s\n", yellow(fmt.Sprintf("\t%s", cidr)))
 		}
 	}
 }
 
+func aggregateNetblocks(netblocks []string) []string {
+	var cidrs []*net.IPNet
+	for _, cidr := range netblocks {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			cidrs = append(cidrs, ipnet)
+		}
+	}
+
+	var results []string
+	for _, ipnet := range amassnet.AggregateCIDRs(cidrs) {
+		results = append(results, ipnet.String())
+	}
+	return results
+}
+
 func processIntelOutput(ic *intel.Collection, args *intelArgs) bool {
 	var err error
 	dir := config.OutputDirectory(ic.Config.Dir)
@@ -343,7 +409,7 @@ func processIntelInputFiles(args *intelArgs) error {
 	}
 	if len(args.Filepaths.Domains) > 0 {
 		for _, f := range args.Filepaths.Domains {
-			list, err := config.GetListFromFile(f)
+			list, err := config.GetScopeListFromFile(f)
 			if err != nil {
 				return fmt.Errorf("failed to parse the domain names file: %v", err)
 			}
@@ -375,6 +441,12 @@ func (i intelArgs) OverrideConfig(conf *config.Config) error {
 	if len(i.ASNs) > 0 {
 		conf.ASNs = i.ASNs
 	}
+	if i.Timeout > 0 {
+		conf.Timeout = time.Duration(i.Timeout)
+	}
+	if i.ASNWorkers > 0 {
+		conf.ASNWorkers = i.ASNWorkers
+	}
 	if len(i.CIDRs) > 0 {
 		conf.CIDRs = i.CIDRs
 	}
@@ -393,6 +465,9 @@ func (i intelArgs) OverrideConfig(conf *config.Config) error {
 	if i.MaxDNSQueries > 0 {
 		conf.MaxDNSQueries = i.MaxDNSQueries
 	}
+	if i.MaxASNAddrs > 0 {
+		conf.MaxASNAddrs = i.MaxASNAddrs
+	}
 
 	if i.Included.Len() > 0 {
 		conf.SourceFilter.Include = true
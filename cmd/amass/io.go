@@ -11,10 +11,11 @@ import (
 	"time"
 
 	"github.com/aokimio/Amass/v3/enum"
+	"github.com/aokimio/Amass/v3/net/bgp"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 	"golang.org/x/net/publicsuffix"
 )
 
@@ -29,6 +30,10 @@ func ExtractOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, fi
 	if e.Config.Passive {
 		return EventNames(ctx, g, e.Config.UUID.String(), filter)
 	}
+	// The names that did not resolve are stored in the graph, but never become output
+	if e.Config.OnlyResolved && filter != nil {
+		filter.InsertMany(e.UnresolvedNames()...)
+	}
 	return EventOutput(ctx, g, e.Config.UUID.String(), filter, asinfo, e.Sys.Cache(), limit)
 }
 
@@ -62,15 +67,23 @@ func EventOutput(ctx context.Context, g *netmap.Graph, uuid string, f *stringset
 				continue
 			}
 			if o, found := lookup[p.Name]; found {
-				o.Addresses = append(o.Addresses, requests.AddressInfo{Address: net.ParseIP(p.Addr)})
+				o.Addresses = append(o.Addresses, requests.AddressInfo{
+					Address: net.ParseIP(p.Addr),
+					Geo:     enum.AddrGeolocation(ctx, g, p.Addr),
+				})
 			}
 		}
 	}
 
+	// Names without addresses were not confirmed through DNS resolution
+	for _, o := range lookup {
+		o.Passive = len(o.Addresses) == 0
+	}
+
 	if !asninfo || cache == nil {
 		return removeDuplicates(lookup, f)
 	}
-	return addInfrastructureInfo(lookup, f, cache)
+	return addInfrastructureInfo(ctx, g, lookup, f, cache)
 }
 
 func randomSelection(names []string, limit int) []string {
@@ -99,8 +112,12 @@ func removeDuplicates(lookup outLookup, filter *stringset.Set) []*requests.Outpu
 	return output
 }
 
-func addInfrastructureInfo(lookup outLookup, filter *stringset.Set, cache *requests.ASNCache) []*requests.Output {
+func addInfrastructureInfo(ctx context.Context, g *netmap.Graph, lookup outLookup, filter *stringset.Set, cache *requests.ASNCache) []*requests.Output {
 	output := make([]*requests.Output, 0, len(lookup))
+	// The netblocks that the BGP validation did not find in the global BGP table
+	stale := make(map[string]bool)
+	// The RPKI validation states of the netblocks and their origin
+	rpkiStates := make(map[string]string)
 
 	for _, o := range lookup {
 		var newaddrs []requests.AddressInfo
@@ -111,6 +128,11 @@ func addInfrastructureInfo(lookup outLookup, filter *stringset.Set, cache *reque
 				continue
 			}
 
+			if _, found := stale[i.Prefix]; !found {
+				stale[i.Prefix] = enum.NetblockBGPState(ctx, g, i.Prefix) == bgp.NotAnnounced
+				rpkiStates[i.Prefix] = enum.NetblockRPKIState(ctx, g, i.Prefix)
+			}
+
 			_, netblock, _ := net.ParseCIDR(i.Prefix)
 			newaddrs = append(newaddrs, requests.AddressInfo{
 				Address:     a.Address,
@@ -118,6 +140,9 @@ func addInfrastructureInfo(lookup outLookup, filter *stringset.Set, cache *reque
 				CIDRStr:     i.Prefix,
 				Netblock:    netblock,
 				Description: i.Description,
+				Geo:         a.Geo,
+				Stale:       stale[i.Prefix],
+				RPKI:        rpkiStates[i.Prefix],
 			})
 		}
 
@@ -149,6 +174,7 @@ func EventNames(ctx context.Context, g *netmap.Graph, uuid string, f *stringset.
 	var results []*requests.Output
 	for _, o := range buildNameInfo(ctx, g, uuid, names) {
 		if !f.Has(o.Name) {
+			o.Passive = true
 			results = append(results, o)
 			f.Insert(o.Name)
 		}
@@ -166,9 +192,16 @@ func buildNameInfo(ctx context.Context, g *netmap.Graph, uuid string, names []st
 
 		n := netmap.Node(name)
 		if srcs, err := g.NodeSources(ctx, n, uuid); err == nil && len(srcs) > 0 {
+			first, last := enum.AssetTimestamps(ctx, g, name, netmap.TypeFQDN)
 			results[name] = &requests.Output{
-				Name:    name,
-				Sources: srcs,
+				Name:        name,
+				Sources:     srcs,
+				FirstSeen:   first,
+				LastSeen:    last,
+				SourceURLs:  enum.SourceURLs(ctx, g, name),
+				Orgs:        enum.Orgs(ctx, g, name),
+				NameServers: enum.NameServersOf(ctx, g, name),
+				Seeds:       enum.Seeds(ctx, g, name, netmap.TypeFQDN),
 			}
 		}
 	}
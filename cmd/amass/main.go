@@ -24,6 +24,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -38,17 +39,18 @@ import (
 	"github.com/aokimio/Amass/v3/datasrcs"
 	"github.com/aokimio/Amass/v3/format"
 	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/net/dns"
 	"github.com/aokimio/Amass/v3/requests"
 	"github.com/aokimio/Amass/v3/resources"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
 const (
-	mainUsageMsg         = "intel|enum|viz|track|db [options]"
+	mainUsageMsg         = "intel|enum|viz|track|db|report [options]"
 	exampleConfigFileURL = "https://github.com/OWASP/Amass/blob/master/examples/config.ini"
 	userGuideURL         = "https://github.com/OWASP/Amass/blob/master/doc/user_guide.md"
 	tutorialURL          = "https://github.com/OWASP/Amass/blob/master/doc/tutorial.md"
@@ -79,7 +81,10 @@ func CommandUsage(msg string, cmdFlagSet *flag.FlagSet, errBuf *bytes.Buffer) {
 		g.Fprintf(color.Error, "\t%-11s - Perform enumerations and network mapping\n", "amass enum")
 		g.Fprintf(color.Error, "\t%-11s - Visualize enumeration results\n", "amass viz")
 		g.Fprintf(color.Error, "\t%-11s - Track differences between enumerations\n", "amass track")
+		g.Fprintf(color.Error, "\t%-11s - Enumerate on a schedule and report the changes\n", "amass watch")
 		g.Fprintf(color.Error, "\t%-11s - Manipulate the Amass graph database\n", "amass db")
+		g.Fprintf(color.Error, "\t%-11s - Write an HTML report of the enumeration results\n", "amass report")
+		g.Fprintf(color.Error, "\t%-11s - Validate the data source scripts\n", "amass scripts")
 	}
 
 	g.Fprintln(color.Error)
@@ -125,8 +130,14 @@ func main() {
 		RunIntelCommand(os.Args[2:])
 	case "track":
 		RunTrackCommand(os.Args[2:])
+	case "watch":
+		RunWatchCommand(os.Args[2:])
 	case "viz":
 		RunVizCommand(os.Args[2:])
+	case "report":
+		RunReportCommand(os.Args[2:])
+	case "scripts":
+		RunScriptsCommand(os.Args[2:])
 	case "help":
 		RunHelpCommand(os.Args[2:])
 	default:
@@ -154,6 +165,43 @@ func GetAllSourceInfo(cfg *config.Config) []string {
 	return DataSourceInfo(srcs, sys)
 }
 
+// printEffectiveConfig writes the settings in effect after the configuration file and command-line
+// arguments were applied, along with the data sources that would be used, in the selected format.
+func printEffectiveConfig(cfg *config.Config, dumpFormat string) {
+	sys, err := systems.NewLocalSystem(cfg)
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	all := datasrcs.GetAllSources(sys)
+	if err := sys.SetDataSources(datasrcs.SelectedDataSources(cfg, all)); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+	}
+
+	sources := make(map[string]bool, len(all))
+	for _, src := range all {
+		sources[src.String()] = false
+	}
+	for _, src := range sys.DataSources() {
+		sources[src.String()] = true
+	}
+
+	ec := cfg.Effective(sources)
+	if dumpFormat == "json" {
+		enc := json.NewEncoder(color.Output)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(ec)
+	} else {
+		err = ec.WriteText(color.Output)
+	}
+	if err != nil {
+		r.Fprintf(color.Error, "Failed to write the configuration: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // DataSourceInfo acquires the information for data sources used by the provided System.
 func DataSourceInfo(all []service.Service, sys systems.System) []string {
 	var names []string
@@ -316,9 +364,10 @@ func getEventOutput(ctx context.Context, uuids []string, asninfo bool, db *netma
 func domainNameInScope(name string, scope []string) bool {
 	var discovered bool
 
-	n := strings.ToLower(strings.TrimSpace(name))
+	// The names and domains are compared in their punycode form, however they were written
+	n := dns.ToASCII(name)
 	for _, d := range scope {
-		d = strings.ToLower(d)
+		d = dns.ToASCII(d)
 
 		if n == d || strings.HasSuffix(n, "."+d) {
 			discovered = true
@@ -0,0 +1,33 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aokimio/Amass/v3/stats"
+)
+
+// startMetricsServer serves the metrics of the collector in the OpenMetrics format at /metrics
+// on the address, until the program exits.
+func startMetricsServer(addr string, c *stats.Collector) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start the metrics endpoint: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() { _ = srv.Serve(ln) }()
+	return nil
+}
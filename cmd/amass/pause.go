@@ -0,0 +1,67 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aokimio/Amass/v3/enum"
+	"github.com/fatih/color"
+)
+
+// How often the existence of the pause control file is checked
+const pauseFileInterval = time.Second
+
+// handlePauseControl pauses the enumeration when one of the pause signals is received or the
+// control file is created, and resumes it on the next signal or once the file is removed,
+// until done is closed.
+func handlePauseControl(e *enum.Enumeration, file string, done chan struct{}) {
+	sig := make(chan os.Signal, 1)
+	if len(pauseSignals) > 0 {
+		signal.Notify(sig, pauseSignals...)
+		defer signal.Stop(sig)
+	}
+
+	var check <-chan time.Time
+	if file != "" {
+		t := time.NewTicker(pauseFileInterval)
+		defer t.Stop()
+		check = t.C
+	}
+
+	var filePaused bool
+	for {
+		select {
+		case <-done:
+			return
+		case <-sig:
+			if !e.Pause() {
+				e.Resume()
+				printPauseTransition(false)
+				continue
+			}
+			printPauseTransition(true)
+		case <-check:
+			_, err := os.Stat(file)
+			if exists := err == nil; exists != filePaused {
+				filePaused = exists
+				if (exists && e.Pause()) || (!exists && e.Resume()) {
+					printPauseTransition(exists)
+				}
+			}
+		}
+	}
+}
+
+func printPauseTransition(paused bool) {
+	if paused {
+		fmt.Fprintf(color.Error, "%s\n", yellow("The enumeration has been paused"))
+		return
+	}
+	fmt.Fprintf(color.Error, "%s\n", green("The enumeration has been resumed"))
+}
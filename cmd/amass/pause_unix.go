@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// The signals that pause the enumeration, and resume it when received again.
+var pauseSignals = []os.Signal{syscall.SIGUSR2}
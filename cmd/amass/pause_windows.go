@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import "os"
+
+// Windows has no signal for pausing the enumeration, so only the control file is watched.
+var pauseSignals []os.Signal
@@ -0,0 +1,143 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/caffix/service"
+	"github.com/fatih/color"
+)
+
+// The error classes used to summarize the suppressed log messages, checked in order
+var quietErrorClasses = []struct {
+	class string
+	re    *regexp.Regexp
+}{
+	{"auth", regexp.MustCompile(`(?i)\b(401|403)\b|unauthori[sz]ed|forbidden|invalid (api )?key|token does not`)},
+	{"rate limited", regexp.MustCompile(`(?i)\b429\b|rate limit`)},
+	{"http error", regexp.MustCompile(`\b[45]\d\d: `)},
+	{"timeout", regexp.MustCompile(`(?i)timeout|timed out|deadline exceeded|context expired`)},
+	{"extraction failed", regexp.MustCompile(`(?i)regular expression|failed to extract`)},
+	{"no results", regexp.MustCompile(`(?i)zero results|failed to discover`)},
+	{"response too large", regexp.MustCompile(`max_response_size`)},
+	{"budget exhausted", regexp.MustCompile(`(?i)query budget`)},
+	{"malformed response", regexp.MustCompile(`(?i)invalid character|unexpected end of json|cannot unmarshal`)},
+}
+
+// The data source messages that are notices about the run rather than errors of a request
+var quietNoticeRE = regexp.MustCompile(`WARNING|quota|No longer dispatching|Learned a rate limit|API key data was not provided|requires an API key`)
+
+// quietLog suppresses the routine errors logged by the data sources while handling
+// individual requests, and counts them by data source and error class for the summary.
+// Authentication errors and notices about the run are never suppressed.
+type quietLog struct {
+	sync.Mutex
+	sources map[string]struct{}
+	counts  map[string]map[string]int
+}
+
+func newQuietLog() *quietLog {
+	return &quietLog{
+		sources: make(map[string]struct{}),
+		counts:  make(map[string]map[string]int),
+	}
+}
+
+// setSources provides the names of the data sources whose messages can be suppressed.
+func (q *quietLog) setSources(srcs []service.Service) {
+	if q == nil {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	for _, src := range srcs {
+		q.sources[src.String()] = struct{}{}
+	}
+}
+
+// suppress returns true when the log line, without the timestamp, is a routine data source
+// error that has been counted. The class is returned for the messages that are not suppressed.
+func (q *quietLog) suppress(line string) (bool, string) {
+	if q == nil {
+		return false, ""
+	}
+
+	idx := strings.Index(line, ": ")
+	if idx <= 0 {
+		return false, ""
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	src, msg := line[:idx], line[idx+2:]
+	if _, found := q.sources[src]; !found || quietNoticeRE.MatchString(msg) {
+		return false, ""
+	}
+
+	class := "other"
+	for _, c := range quietErrorClasses {
+		if c.re.MatchString(msg) {
+			class = c.class
+			break
+		}
+	}
+	if class == "auth" {
+		return false, class
+	}
+
+	if q.counts[src] == nil {
+		q.counts[src] = make(map[string]int)
+	}
+	q.counts[src][class]++
+	return true, class
+}
+
+// fprintSummary writes the number of suppressed errors for each data source and error class.
+func (q *quietLog) fprintSummary(out io.Writer) {
+	if q == nil {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.counts) == 0 {
+		return
+	}
+
+	var srcs []string
+	for src := range q.counts {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+
+	fmt.Fprintf(out, "\n%s\n", yellow("Errors suppressed by the quiet mode:"))
+	for _, src := range srcs {
+		var classes []string
+		for class := range q.counts[src] {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		var parts []string
+		for _, class := range classes {
+			parts = append(parts, fmt.Sprintf("%s %d", class, q.counts[src][class]))
+		}
+		fmt.Fprintf(out, "%s: %s\n", blue(src), strings.Join(parts, ", "))
+	}
+}
+
+func printQuietSummary(q *quietLog) {
+	q.fprintSummary(color.Error)
+}
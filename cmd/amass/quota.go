@@ -0,0 +1,100 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/datasrcs"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/fatih/color"
+)
+
+// printQuotaEstimates lists the selected data sources that have a request quota, along with the
+// fewest requests the run is expected to make to each of them: one for each root domain and ASN.
+func printQuotaEstimates(cfg *config.Config, sys systems.System) {
+	min := len(cfg.Domains()) + len(cfg.ASNs)
+
+	for _, src := range datasrcs.SelectedDataSources(cfg, sys.DataSources()) {
+		quota := sys.Stats().Source(src.String()).Quota
+		if quota == 0 {
+			continue
+		}
+
+		fmt.Fprintf(color.Error, "%s%s%s\n", yellow(src.String()), yellow(": "),
+			yellow(fmt.Sprintf("quota of %d requests, at least %d expected for this run", quota, min)))
+		if quota < int64(min) {
+			r.Fprintf(color.Error, "%s: the quota is smaller than the requests expected and will stop the source early\n", src.String())
+		}
+	}
+}
+
+// printQuotaUsage reports the requests made to each data source that has a quota.
+func printQuotaUsage(sys systems.System) {
+	for _, src := range sys.DataSources() {
+		s := sys.Stats().Source(src.String())
+		if s.Quota == 0 {
+			continue
+		}
+
+		fmt.Fprintf(color.Error, "%s%s%s\n", blue(src.String()), blue(": "),
+			green(fmt.Sprintf("%d of %d requests in the quota were used", s.Requests, s.Quota)))
+	}
+}
+
+// printSourceScores reports the scores of the data sources that made requests during the run,
+// starting with the most productive.
+func printSourceScores(sys systems.System) {
+	scores := sys.Stats().Scores()
+
+	var names []string
+	for name := range scores {
+		if sys.Stats().Source(name).Requests > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if scores[names[i]] != scores[names[j]] {
+			return scores[names[i]] > scores[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, fmt.Sprintf("%s %.2f", name, scores[name]))
+	}
+	fmt.Fprintf(color.Error, "%s %s\n", blue("Source scores:"), yellow(strings.Join(list, ", ")))
+}
+
+// printStatusCodes reports the HTTP status codes received by each data source, which makes the
+// sources held back by rate limits or rejected credentials stand out.
+func printStatusCodes(sys systems.System) {
+	for _, name := range sys.Stats().StatusSources() {
+		fmt.Fprintf(color.Error, "%s %s\n", blue(name+":"),
+			yellow(stats.FormatStatusCodes(sys.Stats().StatusCodes(name))))
+	}
+}
+
+// printBrokenSources warns about the scrape data sources that stopped receiving requests
+// after failing to extract data from too many pages in a row.
+func printBrokenSources(sys systems.System) {
+	for _, src := range sys.DataSources() {
+		if !sys.Stats().Broken(src.String()) {
+			continue
+		}
+
+		r.Fprintf(color.Error, "%s: WARNING: the source was disabled after failing to extract data from %d pages in a row\n",
+			src.String(), sys.Config().ScrapeFailureLimit)
+		r.Fprintf(color.Error, "%s: check whether the layout of the site has changed\n", src.String())
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/format"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/fatih/color"
+)
+
+const (
+	reportUsageMsg    = "report [options] -d DOMAIN"
+	defaultReportFile = "amass_report.html"
+)
+
+type reportArgs struct {
+	Domains *stringset.Set
+	Enum    int
+	Options struct {
+		NoColor bool
+		Silent  bool
+	}
+	Filepaths struct {
+		ConfigFile string
+		Directory  string
+		Domains    string
+		Output     string
+	}
+}
+
+func RunReportCommand(clArgs []string) {
+	var args reportArgs
+	var help1, help2 bool
+	reportCommand := flag.NewFlagSet("report", flag.ContinueOnError)
+
+	args.Domains = stringset.New()
+	defer args.Domains.Close()
+
+	reportBuf := new(bytes.Buffer)
+	reportCommand.SetOutput(reportBuf)
+
+	reportCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	reportCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	reportCommand.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
+	reportCommand.IntVar(&args.Enum, "enum", 0, "Identify an enumeration via an index from the db listing")
+	reportCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
+	reportCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the graph database")
+	reportCommand.StringVar(&args.Filepaths.Domains, "df", "", "Path to a file providing root domain names")
+	reportCommand.StringVar(&args.Filepaths.Output, "o", "", "Path to the HTML report file (default: "+defaultReportFile+" in the output directory)")
+	reportCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
+	reportCommand.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
+
+	if len(clArgs) < 1 {
+		CommandUsage(reportUsageMsg, reportCommand, reportBuf)
+		return
+	}
+	if err := reportCommand.Parse(clArgs); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		CommandUsage(reportUsageMsg, reportCommand, reportBuf)
+		return
+	}
+	if args.Options.NoColor {
+		color.NoColor = true
+	}
+	if args.Options.Silent {
+		color.Output = ioutil.Discard
+		color.Error = ioutil.Discard
+	}
+	if args.Filepaths.Domains != "" {
+		list, err := config.GetScopeListFromFile(args.Filepaths.Domains)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to parse the domain names file: %v\n", err)
+			os.Exit(1)
+		}
+		args.Domains.InsertMany(list...)
+	}
+
+	cfg := new(config.Config)
+	// Check if a configuration file was provided, and if so, load the settings
+	if err := config.AcquireConfig(args.Filepaths.Directory, args.Filepaths.ConfigFile, cfg); err == nil {
+		if args.Filepaths.Directory == "" {
+			args.Filepaths.Directory = config.OutputDirectory(cfg.Dir)
+		}
+		if args.Domains.Len() == 0 {
+			args.Domains.InsertMany(cfg.Domains()...)
+		}
+	} else if args.Filepaths.ConfigFile != "" {
+		r.Fprintf(color.Error, "Failed to load the configuration file: %v\n", err)
+		os.Exit(1)
+	}
+	if args.Domains.Len() == 0 {
+		r.Fprintln(color.Error, "No root domain names were provided")
+		os.Exit(1)
+	}
+
+	db := openGraphDatabase(args.Filepaths.Directory, cfg)
+	if db == nil {
+		r.Fprintln(color.Error, "Failed to connect with the database")
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	// Create the in-memory graph database
+	memDB, err := memGraphForScope(ctx, args.Domains.Slice(), db)
+	if err != nil {
+		r.Fprintln(color.Error, err.Error())
+		os.Exit(1)
+	}
+	defer memDB.Close()
+	// Get all the UUIDs for events that have information in scope
+	uuids := memDB.EventsInScope(ctx, args.Domains.Slice()...)
+	if len(uuids) == 0 {
+		r.Fprintln(color.Error, "Failed to find the domains of interest in the database")
+		os.Exit(1)
+	}
+	// Put the events in chronological order
+	uuids, earliest, latest := orderedEvents(ctx, uuids, memDB)
+	if len(uuids) == 0 {
+		r.Fprintln(color.Error, "Failed to sort the events")
+		os.Exit(1)
+	}
+	// Select the enumeration that the user specified
+	if args.Enum > 0 && args.Enum <= len(uuids) {
+		idx := len(uuids) - args.Enum
+		uuids = []string{uuids[idx]}
+		earliest = []time.Time{earliest[idx]}
+		latest = []time.Time{latest[idx]}
+	}
+
+	cache := requests.NewASNCache()
+	if err := fillCache(cache, memDB); err != nil {
+		r.Fprintf(color.Error, "Failed to populate the ASN cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	rep := format.NewReport(time.Now(), earliest[0], latest[len(latest)-1])
+	for _, out := range getEventOutput(ctx, uuids, true, memDB, cache) {
+		if domainNameInScope(out.Name, args.Domains.Slice()) {
+			rep.Add(out)
+		}
+	}
+
+	path := args.Filepaths.Output
+	if path == "" {
+		path = filepath.Join(config.OutputDirectory(args.Filepaths.Directory), defaultReportFile)
+	}
+	if err := writeReport(path, rep); err != nil {
+		r.Fprintf(color.Error, "Failed to write the report: %v\n", err)
+		os.Exit(1)
+	}
+	g.Fprintf(color.Error, "The report was written to %s\n", path)
+}
+
+func writeReport(path string, rep *format.Report) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Sync()
+		_ = f.Close()
+	}()
+
+	return rep.WriteHTML(f)
+}
@@ -0,0 +1,127 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/datasrcs/scripting"
+	"github.com/aokimio/Amass/v3/resources"
+	"github.com/fatih/color"
+)
+
+const (
+	scriptsUsageMsg = "scripts validate [options] [FILE ...]"
+)
+
+type scriptsArgs struct {
+	Options struct {
+		NoColor bool
+		Verbose bool
+	}
+	Filepaths struct {
+		ConfigFile string
+		Directory  string
+	}
+}
+
+// RunScriptsCommand validates the data source scripts without performing any network activity.
+func RunScriptsCommand(clArgs []string) {
+	var args scriptsArgs
+	var help1, help2 bool
+	scriptsCommand := flag.NewFlagSet("scripts", flag.ContinueOnError)
+
+	scriptsBuf := new(bytes.Buffer)
+	scriptsCommand.SetOutput(scriptsBuf)
+
+	scriptsCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	scriptsCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	scriptsCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
+	scriptsCommand.BoolVar(&args.Options.Verbose, "v", false, "Also print the scripts that passed validation")
+	scriptsCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
+	scriptsCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the output directory holding the user provided scripts")
+
+	if len(clArgs) < 1 || clArgs[0] != "validate" {
+		CommandUsage(scriptsUsageMsg, scriptsCommand, scriptsBuf)
+		if len(clArgs) > 0 && clArgs[0] != "-h" && clArgs[0] != "-help" {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := scriptsCommand.Parse(clArgs[1:]); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		CommandUsage(scriptsUsageMsg, scriptsCommand, scriptsBuf)
+		return
+	}
+	if args.Options.NoColor {
+		color.NoColor = true
+	}
+
+	files, err := scriptFilesToValidate(&args, scriptsCommand.Args())
+	if err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var failed int
+	for _, f := range files {
+		name, err := scripting.ValidateScript(f.Path, f.Content)
+		if name == "" {
+			name = "unknown"
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(color.Output, "%s %s %s\n", red(f.Path), yellow("("+name+"):"), err)
+		} else if args.Options.Verbose {
+			fmt.Fprintf(color.Output, "%s %s %s\n", green(f.Path), yellow("("+name+"):"), green("OK"))
+		}
+	}
+
+	fmt.Fprintf(color.Error, "%s %d scripts validated, %d failed\n", blue("Scripts:"), len(files), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// scriptFilesToValidate reads the scripts named on the command-line, or acquires all the
+// default and user provided scripts when none were named.
+func scriptFilesToValidate(args *scriptsArgs, paths []string) ([]resources.ScriptFile, error) {
+	var files []resources.ScriptFile
+
+	if len(paths) > 0 {
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read the script: %v", err)
+			}
+			files = append(files, resources.ScriptFile{Path: path, Content: string(data)})
+		}
+		return files, nil
+	}
+
+	cfg := config.NewConfig()
+	// Check if a configuration file was provided, and if so, load the settings
+	if err := config.AcquireConfig(args.Filepaths.Directory, args.Filepaths.ConfigFile, cfg); err != nil && args.Filepaths.ConfigFile != "" {
+		return nil, fmt.Errorf("Failed to load the configuration file: %v", err)
+	}
+	if args.Filepaths.Directory != "" {
+		cfg.Dir = args.Filepaths.Directory
+	}
+	// A missing output directory only means that the user has not provided any scripts
+	files, _ = cfg.AcquireScriptFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("Failed to acquire the data source scripts")
+	}
+	return files, nil
+}
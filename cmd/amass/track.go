@@ -16,8 +16,8 @@ import (
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/netmap"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
@@ -61,7 +61,7 @@ func RunTrackCommand(clArgs []string) {
 	trackCommand.BoolVar(&args.Options.History, "history", false, "Show the difference between all enumeration pairs")
 	trackCommand.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
 	trackCommand.BoolVar(&args.Options.Silent, "silent", false, "Disable all output during execution")
-	trackCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	trackCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
 	trackCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the graph database")
 	trackCommand.StringVar(&args.Filepaths.Domains, "df", "", "Path to a file providing root domain names")
 
@@ -94,7 +94,7 @@ func RunTrackCommand(clArgs []string) {
 		os.Exit(1)
 	}
 	if args.Filepaths.Domains != "" {
-		list, err := config.GetListFromFile(args.Filepaths.Domains)
+		list, err := config.GetScopeListFromFile(args.Filepaths.Domains)
 		if err != nil {
 			r.Fprintf(color.Error, "Failed to parse the domain names file: %v\n", err)
 			os.Exit(1)
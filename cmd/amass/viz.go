@@ -15,8 +15,8 @@ import (
 	"time"
 
 	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/viz"
-	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 )
 
@@ -61,7 +61,7 @@ func RunVizCommand(clArgs []string) {
 	vizCommand.BoolVar(&help2, "help", false, "Show the program usage message")
 	vizCommand.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
 	vizCommand.IntVar(&args.Enum, "enum", 0, "Identify an enumeration via an index from the listing")
-	vizCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path to the INI configuration file. Additional details below")
+	vizCommand.StringVar(&args.Filepaths.ConfigFile, "config", "", "Path or http(s) URL of the INI configuration file. Additional details below")
 	vizCommand.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the graph database")
 	vizCommand.StringVar(&args.Filepaths.Domains, "df", "", "Path to a file providing root domain names")
 	vizCommand.StringVar(&args.Filepaths.Input, "i", "", "The Amass data operations JSON file")
@@ -101,7 +101,7 @@ func RunVizCommand(clArgs []string) {
 		os.Exit(1)
 	}
 	if args.Filepaths.Domains != "" {
-		list, err := config.GetListFromFile(args.Filepaths.Domains)
+		list, err := config.GetScopeListFromFile(args.Filepaths.Domains)
 		if err != nil {
 			r.Fprintf(color.Error, "Failed to parse the domain names file: %v\n", err)
 			return
@@ -0,0 +1,239 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aokimio/Amass/v3/format"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/fatih/color"
+)
+
+const watchUsageMsg = "watch [options] -d DOMAIN"
+
+// The time between the start of each enumeration when the -interval flag is not provided
+const defaultWatchInterval = 24 * time.Hour
+
+type watchArgs struct {
+	Interval time.Duration
+	Cycles   int
+	Webhook  string
+}
+
+// watchAlert is the JSON document posted to the webhook after each enumeration that found changes.
+type watchAlert struct {
+	Domains []string         `json:"domains"`
+	UUID    string           `json:"enum_uuid"`
+	Time    time.Time        `json:"time"`
+	Changes []*format.Change `json:"changes"`
+}
+
+// RunWatchCommand enumerates the target repeatedly, and reports the names, addresses and
+// autonomous systems that appear in each enumeration compared to the one before it.
+func RunWatchCommand(clArgs []string) {
+	// Seed the default pseudo-random number generator
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var wargs watchArgs
+	cfg, args := parseEnumCommand("watch", watchUsageMsg, clArgs, func(fs *flag.FlagSet) {
+		fs.DurationVar(&wargs.Interval, "interval", defaultWatchInterval, "Time between the start of each enumeration, such as 6h or 30m")
+		fs.IntVar(&wargs.Cycles, "cycles", 0, "Number of enumerations performed before exiting (default: run until stopped)")
+		fs.StringVar(&wargs.Webhook, "webhook", "", "URL that the changes found by each enumeration are posted to as JSON")
+	})
+	if cfg == nil {
+		return
+	}
+	if args.Options.Daemon {
+		r.Fprintln(color.Error, "The daemon flag cannot be used with the watch subcommand")
+		os.Exit(1)
+	}
+	if wargs.Interval <= 0 || wargs.Cycles < 0 {
+		r.Fprintln(color.Error, "The interval must be greater than zero, and the cycles must not be negative")
+		os.Exit(1)
+	}
+	if wargs.Webhook != "" {
+		if u, err := url.Parse(wargs.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			r.Fprintf(color.Error, "%s is not a valid http or https URL\n", wargs.Webhook)
+			os.Exit(1)
+		}
+	}
+	// The timeout limits each enumeration instead of the System
+	args.Timeout = format.ParseTimeout(cfg.Timeout)
+	cfg.Timeout = 0
+	// The System, and its resolvers, caches and data source rate limiters, is shared by all the enumerations
+	sys, quiet := startEnumSystem(cfg, args)
+	defer func() { _ = sys.Shutdown() }()
+	defer closeEventLog(sys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Monitor for cancellation by the user
+	go func(c context.CancelFunc) {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(quit)
+
+		<-quit
+		c()
+	}(cancel)
+
+	runWatch(ctx, sys, args, &wargs)
+	printQuotaUsage(sys)
+	printBrokenSources(sys)
+	printSourceScores(sys)
+	if args.Options.StatusCodes {
+		printStatusCodes(sys)
+	}
+	printQuietSummary(quiet)
+	if args.Filepaths.StatsJSON != "" {
+		saveStatsJSON(sys, args.Filepaths.StatsJSON)
+	}
+}
+
+func runWatch(ctx context.Context, sys systems.System, args *enumArgs, wargs *watchArgs) {
+	domains := sys.Config().Domains()
+	target := strings.Join(domains, ", ")
+	// The latest enumeration stored in the graph database is compared with the first one performed
+	prev := latestStoredOutput(ctx, sys, domains)
+
+	for cycle := 1; ; cycle++ {
+		start := time.Now()
+
+		var lock sync.Mutex
+		var current []*requests.Output
+		enumerateWithSystem(ctx, sys, args, target, func(out *requests.Output) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			current = append(current, out)
+		})
+		// An enumeration stopped by the user is incomplete, so it is not compared
+		if ctx.Err() != nil {
+			return
+		}
+
+		if prev == nil {
+			fmt.Fprintf(color.Error, "%s%s\n", yellow("No earlier enumeration to compare with, "),
+				yellow(fmt.Sprintf("%d names recorded", len(current))))
+		} else {
+			notifyChanges(ctx, sys, wargs, domains, format.Changes(prev, current), current)
+		}
+		prev = current
+
+		if wargs.Cycles > 0 && cycle >= wargs.Cycles {
+			return
+		}
+
+		next := start.Add(wargs.Interval)
+		fmt.Fprintf(color.Error, "%s%s\n", blue("Next enumeration at "), yellow(next.Format(timeFormat)))
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// latestStoredOutput returns the findings of the most recent enumeration of the domains found
+// in the graph databases of the System, or nil when the domains have not been enumerated.
+func latestStoredOutput(ctx context.Context, sys systems.System, domains []string) []*requests.Output {
+	for _, db := range sys.GraphDatabases() {
+		uuids := db.EventsInScope(ctx, domains...)
+		if len(uuids) == 0 {
+			continue
+		}
+
+		uuids, _, _ = orderedEvents(ctx, uuids, db)
+		output := []*requests.Output{}
+		for _, out := range getEventOutput(ctx, uuids[len(uuids)-1:], true, db, sys.Cache()) {
+			if domainNameInScope(out.Name, domains) {
+				output = append(output, out)
+			}
+		}
+		return output
+	}
+	return nil
+}
+
+// notifyChanges prints the changes, posts them to the webhook and sends the names involved to
+// the syslog server, when those have been configured.
+func notifyChanges(ctx context.Context, sys systems.System, wargs *watchArgs, domains []string, changes []*format.Change, current []*requests.Output) {
+	cfg := sys.Config()
+	if len(changes) == 0 {
+		g.Fprintln(color.Error, "No differences discovered")
+		return
+	}
+
+	labels := map[string]string{
+		format.NewName:    "New name: ",
+		format.NewAddress: "New address: ",
+		format.NewASN:     "New ASN: ",
+	}
+	for _, c := range changes {
+		line := fmt.Sprintf("%s%s", blue(labels[c.Type]), green(c.Value))
+		if c.Description != "" {
+			line += " " + yellow(c.Description)
+		}
+		if c.Type != format.NewName {
+			line += blue(" via ") + green(c.Name)
+		}
+		fmt.Fprintf(color.Output, "%s %s\n", line, yellow("["+strings.Join(c.Sources, ", ")+"]"))
+	}
+
+	if wargs.Webhook != "" {
+		postWatchAlert(ctx, wargs.Webhook, &watchAlert{
+			Domains: domains,
+			UUID:    cfg.UUID.String(),
+			Time:    time.Now(),
+			Changes: changes,
+		})
+	}
+
+	if cfg.Syslog != nil {
+		names := make(map[string]struct{})
+		for _, c := range changes {
+			names[c.Name] = struct{}{}
+		}
+
+		sink := format.NewSyslogSink(cfg.Syslog, cfg.UUID.String(), cfg.Log)
+		for _, out := range current {
+			if _, found := names[out.Name]; found {
+				sink.Send(out)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+		}
+	}
+}
+
+func postWatchAlert(ctx context.Context, webhook string, alert *watchAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if _, err := http.RequestWebPage(ctx, webhook, bytes.NewReader(body), headers, nil); err != nil {
+		r.Fprintf(color.Error, "Failed to post the changes to the webhook: %v\n", err)
+	}
+}
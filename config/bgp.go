@@ -0,0 +1,77 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+const (
+	// DefaultBGPValidator is the route collector API queried when none is configured.
+	DefaultBGPValidator = "ripestat"
+	// DefaultBGPMaxChecks is the number of prefixes checked during an enumeration when no limit is configured.
+	DefaultBGPMaxChecks = 100
+	// DefaultBGPChecksPerSecond is the rate of the checks when none is configured.
+	DefaultBGPChecksPerSecond = 2
+)
+
+// BGPValidationConfig contains the settings for checking that the netblocks discovered
+// are currently announced in the global BGP table.
+type BGPValidationConfig struct {
+	// The name of the route collector API queried for the prefixes
+	Validator string
+	// The base URL of the API, overriding the public address of the validator
+	URL string
+	// The most prefixes checked during an enumeration
+	MaxChecks int
+	// The most checks performed each second
+	Rate int
+}
+
+// String returns the validator and the limits placed on the checks.
+func (b *BGPValidationConfig) String() string {
+	s := b.Validator
+	if b.URL != "" {
+		s += " (" + b.URL + ")"
+	}
+	return fmt.Sprintf("%s max_checks=%d rate=%d", s, b.MaxChecks, b.Rate)
+}
+
+func (c *Config) loadBGPValidationSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("bgp_validation")
+	if err != nil {
+		return nil
+	}
+	if !sec.Key("enabled").MustBool(true) {
+		c.BGPValidation = nil
+		return nil
+	}
+
+	bc := &BGPValidationConfig{
+		Validator: strings.ToLower(strings.TrimSpace(sec.Key("validator").MustString(DefaultBGPValidator))),
+		URL:       strings.TrimRight(strings.TrimSpace(sec.Key("url").String()), "/"),
+		MaxChecks: sec.Key("max_checks").MustInt(DefaultBGPMaxChecks),
+		Rate:      sec.Key("rate").MustInt(DefaultBGPChecksPerSecond),
+	}
+
+	if bc.URL != "" {
+		if u, err := url.Parse(bc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("bgp_validation: %q is not a valid http or https URL", bc.URL)
+		}
+	}
+	if bc.MaxChecks <= 0 {
+		return fmt.Errorf("bgp_validation: max_checks must be greater than zero")
+	}
+	if bc.Rate <= 0 {
+		return fmt.Errorf("bgp_validation: rate must be greater than zero")
+	}
+
+	c.BGPValidation = bc
+	return nil
+}
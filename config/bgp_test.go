@@ -0,0 +1,60 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadBGPValidationSettings(t *testing.T) {
+	c := NewConfig()
+	if c.BGPValidation != nil {
+		t.Errorf("BGP validation was enabled by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[bgp_validation]\n"))
+	if err := c.loadBGPValidationSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if bc := c.BGPValidation; bc == nil || bc.Validator != DefaultBGPValidator || bc.URL != "" ||
+		bc.MaxChecks != DefaultBGPMaxChecks || bc.Rate != DefaultBGPChecksPerSecond {
+		t.Errorf("Failed to load the default BGP validation settings: %+v", bc)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[bgp_validation]
+		validator = RIPEstat
+		url = http://127.0.0.1:8080/ripestat/
+		max_checks = 20
+		rate = 1
+		`),
+	)
+	if err := c.loadBGPValidationSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if bc := c.BGPValidation; bc.Validator != "ripestat" || bc.URL != "http://127.0.0.1:8080/ripestat" ||
+		bc.MaxChecks != 20 || bc.Rate != 1 {
+		t.Errorf("Failed to load the BGP validation settings: %+v", bc)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[bgp_validation]\nenabled = false\n"))
+	if err := c.loadBGPValidationSettings(cfg); err != nil || c.BGPValidation != nil {
+		t.Errorf("BGP validation was not disabled: %v", err)
+	}
+
+	for _, bad := range []string{
+		"url = stat.ripe.net",
+		"url = ftp://stat.ripe.net",
+		"max_checks = 0",
+		"rate = -1",
+	} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[bgp_validation]\n"+bad+"\n"))
+		if err := NewConfig().loadBGPValidationSettings(cfg); err == nil {
+			t.Errorf("The invalid setting was accepted: %s", bad)
+		}
+	}
+}
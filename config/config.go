@@ -12,6 +12,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
@@ -21,9 +22,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aokimio/Amass/v3/resources"
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/go-ini/ini"
 	"github.com/google/uuid"
 )
@@ -35,6 +37,52 @@ const (
 	systemCfgDir   = "/etc"
 )
 
+// DefaultScrapeFailureLimit is the number of consecutive pages a scrape data source can fail
+// to extract data from before it is considered broken by a change to the site.
+const DefaultScrapeFailureLimit = 10
+
+// DefaultASNWorkers is the number of ASNs expanded into netblocks at the same time.
+const DefaultASNWorkers = 4
+
+// DefaultOutputBuffer is the number of results each data source can have waiting to be
+// processed before the backpressure strategy is applied.
+const DefaultOutputBuffer = 1000
+
+// The strategies applied when the results of a data source fill its output buffer.
+const (
+	// OutputBackpressureBlock holds the data source until there is room in the buffer.
+	OutputBackpressureBlock = "block"
+	// OutputBackpressureDrop discards the result and logs a warning.
+	OutputBackpressureDrop = "drop"
+)
+
+// The encodings of the internationalized names written to the output.
+const (
+	// OutputEncodingASCII writes the names as they appear in DNS, with the punycode labels.
+	OutputEncodingASCII = "ascii"
+	// OutputEncodingUnicode writes the names with the punycode labels decoded into Unicode.
+	OutputEncodingUnicode = "unicode"
+)
+
+// DefaultNameFilterSize is the number of names reported by the data sources that are
+// remembered, so names reported again by the same source are skipped.
+const DefaultNameFilterSize = 1000000
+
+// DefaultNameFilterFPRate is the expected false-positive rate of the name filter.
+const DefaultNameFilterFPRate = 0.01
+
+// DefaultMaxWhoisDomains is the most related domains a single reverse whois expansion of a
+// data source contributes.
+const DefaultMaxWhoisDomains = 1000
+
+// DefaultDNSRetries is the number of times a DNS query is sent again after the resolver
+// timed out or reported a server failure.
+const DefaultDNSRetries = 3
+
+// DefaultDNSRetryBackoff is the delay before the first DNS query retry, which doubles
+// with each retry that follows.
+const DefaultDNSRetryBackoff = 250 * time.Millisecond
+
 // Updater allows an object to implement a method that updates a configuration.
 type Updater interface {
 	OverrideConfig(*Config) error
@@ -59,6 +107,28 @@ type Config struct {
 	// The graph databases used by the system / enumerations
 	GraphDBs []*Database
 
+	// Number of graph writes and seconds between incremental flushes of findings to the graph databases
+	GraphFlushBatchSize int
+	GraphFlushInterval  int
+
+	// The Elasticsearch or OpenSearch cluster that the findings are indexed into, when configured
+	Elasticsearch *ElasticsearchConfig
+
+	// The Neo4j database that the findings and their relationships are written into, when configured
+	Neo4j *Neo4jConfig
+
+	// The syslog daemon or remote collector that the findings are sent to, when configured
+	Syslog *SyslogConfig
+
+	// The route collector API used to check that the discovered netblocks are still announced, when configured
+	BGPValidation *BGPValidationConfig
+
+	// The RPKI validator used to check the origin of the discovered netblocks, when configured
+	RPKI *RPKIConfig
+
+	// The sinkhole and parking address ranges that the discovered addresses are classified against, when configured
+	Sinkholes *SinkholeConfig
+
 	// The maximum number of concurrent DNS queries
 	MaxDNSQueries int `ini:"maximum_dns_queries"`
 
@@ -118,9 +188,110 @@ type Config struct {
 		Sources []string
 	}
 
+	// Public suffixes that discovered names must, or must not, end with
+	TLDFilter struct {
+		Include []string
+		Exclude []string
+	}
+
+	// Try the second-level label of each root domain under other public suffixes, and add the
+	// registered variants to the scope
+	RelatedTLDs struct {
+		Enabled bool
+		TLDs    []string
+	}
+
 	// The minimum number of minutes that data source responses will be reused
 	MinimumTTL int
 
+	// Randomize the data source start order and delay the first request sent to each source
+	RandomizeSources bool `ini:"randomize_sources"`
+
+	// Makes the random choices repeatable when set to a non-zero value
+	RandomSeed int64 `ini:"random_seed"`
+
+	// The most labels beyond the root domain a discovered subdomain can have and still seed further queries
+	MaxRecursionDepth int `ini:"max_recursion_depth"`
+
+	// The most addresses of a name that are processed, where zero processes all of them
+	MaxAddrsPerName int `ini:"max_addrs_per_name"`
+
+	// Follow the CNAME chains returned by the resolvers and submit the hops that are in scope
+	FollowCNAMEs bool `ini:"follow_cnames"`
+
+	// Leave the names that do not resolve out of the output, while still storing them in the graph
+	OnlyResolved bool `ini:"only_resolved"`
+
+	// Hold the output until the enumeration is complete, and write it sorted by name
+	SortedOutput bool `ini:"sorted_output"`
+
+	// Record the URL of the web page that each name was extracted from
+	SourceURLs bool `ini:"source_urls"`
+
+	// How the internationalized names are written to the output: ascii or unicode
+	OutputEncoding string `ini:"output_encoding"`
+
+	// The largest response body, in megabytes, read from the data sources, where zero selects the default
+	MaxResponseSize int `ini:"max_response_size"`
+
+	// The simultaneous connections opened to any single data source host, where zero selects the default
+	MaxConnsPerHost int `ini:"max_conns_per_host"`
+
+	// The results each data source can have waiting to be processed, where zero selects the
+	// default, and what happens once they fill the buffer: block or drop
+	OutputBuffer       int    `ini:"output_buffer"`
+	OutputBackpressure string `ini:"output_backpressure"`
+
+	// The local IP address that the HTTP connections and DNS queries are sent from
+	LocalAddress string `ini:"local_address"`
+
+	// Consecutive pages a scrape data source can fail to extract data from before it stops
+	// receiving requests, where zero disables the detection
+	ScrapeFailureLimit int `ini:"scrape_failure_limit"`
+
+	// The most requests in flight to the data sources at the same time, where zero means one for
+	// each source. Once the limit is reached, the sources with the best scores are served first
+	MaxSourceRequests int `ini:"max_source_requests"`
+
+	// The number of ASNs expanded into netblocks concurrently, while the data sources
+	// continue to respect their own rate limits
+	ASNWorkers int `ini:"asn_workers"`
+
+	// The most addresses expanded from the netblocks of a single ASN by the intel subcommand,
+	// taking the most specific netblocks first, where zero means unlimited
+	MaxASNAddrs int `ini:"max_asn_addrs"`
+
+	// The elements a string set, such as the netblocks collected for an ASN, can hold in memory
+	// before it is moved to disk, where zero keeps every set in memory
+	SpillThreshold int `ini:"spill_threshold"`
+
+	// The longest the run can take before the data source work is cancelled and the findings
+	// made so far are written, where zero means unlimited
+	Timeout time.Duration `ini:"timeout"`
+
+	// The retries of a DNS query that timed out or received SERVFAIL, and the delay before the
+	// first of them, where zero disables the retries or the delay
+	DNSRetries      int           `ini:"dns_retries"`
+	DNSRetryBackoff time.Duration `ini:"dns_retry_backoff"`
+
+	// The names from the data sources remembered to skip repeats, where zero disables the filter,
+	// and the false-positive rate of the Bloom filter that answers most of the lookups, where
+	// zero selects the default
+	NameFilterSize   int     `ini:"name_filter_size"`
+	NameFilterFPRate float64 `ini:"name_filter_fp_rate"`
+
+	// How long the related domains reported by the data sources through reverse whois are
+	// filtered after they were first reported, where zero filters them for the whole run
+	NewDomainWindow time.Duration `ini:"new_domain_window"`
+
+	// The most related domains a single reverse whois expansion of a data source contributes,
+	// keeping those most likely to be related, where zero removes the cap
+	MaxWhoisDomains int `ini:"max_whois_domains"`
+
+	// The MaxMind DB file and the online provider used to geolocate the discovered addresses
+	GeoDatabase string
+	GeoAPI      string
+
 	// Type of DNS records to query for
 	RecordTypes []string
 
@@ -129,6 +300,10 @@ type Config struct {
 	ResolversQPS     int
 	TrustedResolvers []string
 	TrustedQPS       int
+	// The URLs of DNS-over-HTTPS resolvers used in place of the trusted resolvers
+	DoHResolvers []string
+	// The URLs of remote resolver lists fetched at startup and merged with the resolvers
+	ResolverListURLs []string
 
 	// Option for verbose logging and output
 	Verbose bool
@@ -141,6 +316,9 @@ type Config struct {
 
 	// The data source configurations
 	datasrcConfigs map[string]*DataSourceConfig
+
+	// The configuration file the settings were loaded from
+	path string
 }
 
 // NewConfig returns a default configuration object.
@@ -161,9 +339,65 @@ func NewConfig() *Config {
 		MinimumTTL:     1440,
 		ResolversQPS:   DefaultQueriesPerPublicResolver,
 		TrustedQPS:     DefaultQueriesPerBaselineResolver,
+		// Findings are written to the graph databases during the enumeration
+		GraphFlushBatchSize: DefaultGraphFlushBatchSize,
+		GraphFlushInterval:  DefaultGraphFlushInterval,
+		ScrapeFailureLimit:  DefaultScrapeFailureLimit,
+		ASNWorkers:          DefaultASNWorkers,
+		OutputBuffer:        DefaultOutputBuffer,
+		OutputBackpressure:  OutputBackpressureBlock,
+		DNSRetries:          DefaultDNSRetries,
+		DNSRetryBackoff:     DefaultDNSRetryBackoff,
+		NameFilterSize:      DefaultNameFilterSize,
+		NameFilterFPRate:    DefaultNameFilterFPRate,
+		MaxWhoisDomains:     DefaultMaxWhoisDomains,
 	}
 }
 
+// NewRand returns a pseudo-random number generator seeded with RandomSeed, or with the current
+// time when no seed was provided.
+func (c *Config) NewRand() *rand.Rand {
+	seed := c.RandomSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// NumASNWorkers returns the number of ASNs that can be expanded into netblocks at the same time.
+func (c *Config) NumASNWorkers() int {
+	if c.ASNWorkers < 1 {
+		return DefaultASNWorkers
+	}
+	return c.ASNWorkers
+}
+
+// OutputBufferSize returns the number of results each data source can have waiting to be processed.
+func (c *Config) OutputBufferSize() int {
+	if c.OutputBuffer < 1 {
+		return DefaultOutputBuffer
+	}
+	return c.OutputBuffer
+}
+
+// DropOutput returns true when the results that do not fit in the output buffer of a data source are dropped.
+func (c *Config) DropOutput() bool {
+	return strings.EqualFold(c.OutputBackpressure, OutputBackpressureDrop)
+}
+
+// UnicodeOutput returns true when the internationalized names are written to the output in Unicode.
+func (c *Config) UnicodeOutput() bool {
+	return strings.EqualFold(c.OutputEncoding, OutputEncodingUnicode)
+}
+
+// NameFilterRate returns the false-positive rate of the name filter, where zero selects the default.
+func (c *Config) NameFilterRate() float64 {
+	if c.NameFilterFPRate <= 0 {
+		return DefaultNameFilterFPRate
+	}
+	return c.NameFilterFPRate
+}
+
 // UpdateConfig allows the provided Updater to update the current configuration.
 func (c *Config) UpdateConfig(update Updater) error {
 	return update.OverrideConfig(c)
@@ -191,6 +425,67 @@ func (c *Config) CheckSettings() error {
 	if c.Passive && c.Active {
 		return errors.New("active enumeration cannot be performed without DNS resolution")
 	}
+	if c.MaxRecursionDepth < 0 {
+		return errors.New("the maximum recursion depth must not be negative")
+	}
+	if c.MaxAddrsPerName < 0 {
+		return errors.New("the maximum addresses per name must not be negative")
+	}
+	if c.ScrapeFailureLimit < 0 {
+		return errors.New("the scrape failure limit must not be negative")
+	}
+	if c.Timeout < 0 {
+		return errors.New("the timeout must not be negative")
+	}
+	if c.MaxSourceRequests < 0 {
+		return errors.New("the maximum source requests must not be negative")
+	}
+	if c.ASNWorkers < 0 {
+		return errors.New("the number of ASN workers must not be negative")
+	}
+	if c.MaxASNAddrs < 0 {
+		return errors.New("the maximum addresses per ASN must not be negative")
+	}
+	if c.SpillThreshold < 0 {
+		return errors.New("the spill threshold must not be negative")
+	}
+	if c.MaxConnsPerHost < 0 {
+		return errors.New("the maximum connections per host must not be negative")
+	}
+	if c.OutputBuffer < 0 {
+		return errors.New("the output buffer must not be negative")
+	}
+	switch strings.ToLower(c.OutputBackpressure) {
+	case "", OutputBackpressureBlock, OutputBackpressureDrop:
+	default:
+		return fmt.Errorf("the output backpressure strategy %s is not block or drop", c.OutputBackpressure)
+	}
+	switch strings.ToLower(c.OutputEncoding) {
+	case "", OutputEncodingASCII, OutputEncodingUnicode:
+	default:
+		return fmt.Errorf("the output encoding %s is not ascii or unicode", c.OutputEncoding)
+	}
+	if c.LocalAddress != "" && net.ParseIP(c.LocalAddress) == nil {
+		return fmt.Errorf("the local address %s is not a valid IP address", c.LocalAddress)
+	}
+	if c.NameFilterSize < 0 {
+		return errors.New("the name filter size must not be negative")
+	}
+	if c.NameFilterFPRate < 0 || c.NameFilterFPRate >= 1 {
+		return errors.New("the name filter false-positive rate must be between zero and one")
+	}
+	if c.DNSRetries < 0 {
+		return errors.New("the number of DNS retries must not be negative")
+	}
+	if c.DNSRetryBackoff < 0 {
+		return errors.New("the DNS retry backoff must not be negative")
+	}
+	if c.NewDomainWindow < 0 {
+		return errors.New("the new domain window must not be negative")
+	}
+	if c.MaxWhoisDomains < 0 {
+		return errors.New("the maximum whois domains must not be negative")
+	}
 	if c.Alterations {
 		if len(c.AltWordlist) == 0 {
 			f, err := resources.GetResourceFile("alterations.txt")
@@ -217,12 +512,43 @@ func (c *Config) CheckSettings() error {
 	return err
 }
 
-// LoadSettings parses settings from an .ini file and assigns them to the Config.
+// LoadSettings parses settings from an .ini file and assigns them to the Config. The path can
+// also be an http or https URL, which is fetched and validated before the settings are applied.
 func (c *Config) LoadSettings(path string) error {
+	return c.loadSettingsFrom(path, c.Dir)
+}
+
+// loadSettingsFrom loads the settings at the path, keeping the copies of remote configurations
+// in the output directory provided.
+func (c *Config) loadSettingsFrom(path, dir string) error {
+	var source interface{} = path
+
+	if IsRemoteConfig(path) {
+		data, err := c.fetchRemoteConfig(path, dir)
+		if err != nil {
+			return err
+		}
+		source = data
+	}
+
+	if err := c.loadSettings(source); err != nil {
+		return err
+	}
+
+	c.path = path
+	if IsRemoteConfig(path) {
+		c.path = RedactConfigURL(path)
+	}
+	return nil
+}
+
+// loadSettings parses the settings from the source, which is either a file path or the
+// content of the file, and assigns them to the Config.
+func (c *Config) loadSettings(source interface{}) error {
 	cfg, err := ini.LoadSources(ini.LoadOptions{
 		Insensitive:  true,
 		AllowShadows: true,
-	}, path)
+	}, source)
 	if err != nil {
 		return fmt.Errorf("failed to load the configuration file: %v", err)
 	}
@@ -248,13 +574,19 @@ func (c *Config) LoadSettings(path string) error {
 		c.loadBruteForceSettings,
 		c.loadDatabaseSettings,
 		c.loadDataSourceSettings,
+		c.loadGeolocationSettings,
+		c.loadElasticsearchSettings,
+		c.loadNeo4jSettings,
+		c.loadSyslogSettings,
+		c.loadBGPValidationSettings,
+		c.loadRPKISettings,
+		c.loadSinkholeSettings,
 	}
 	for _, load := range loads {
 		if err := load(cfg); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -281,7 +613,7 @@ func AcquireConfig(dir, file string, cfg *Config) error {
 		path = syscfg
 	}
 
-	return cfg.LoadSettings(path)
+	return cfg.loadSettingsFrom(path, dir)
 }
 
 // OutputDirectory returns the file path of the Amass output directory. A suitable
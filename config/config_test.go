@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 )
 
 func TestCheckSettings(t *testing.T) {
@@ -111,6 +112,16 @@ func TestLoadSettings(t *testing.T) {
 	}
 }
 
+func TestNewRand(t *testing.T) {
+	c := NewConfig()
+	c.RandomSeed = 42
+
+	a, b := c.NewRand(), c.NewRand()
+	if !reflect.DeepEqual(a.Perm(20), b.Perm(20)) {
+		t.Errorf("The generators returned for the same seed produced different sequences")
+	}
+}
+
 func TestConfigCheckSettings(t *testing.T) {
 	type fields struct {
 		c *Config
@@ -148,6 +159,55 @@ func TestConfigCheckSettings(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "unknown output backpressure strategy",
+			fields: fields{
+				&Config{OutputBackpressure: "queue"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "drop output backpressure strategy",
+			fields: fields{
+				&Config{OutputBuffer: 10, OutputBackpressure: "Drop"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown output encoding",
+			fields: fields{
+				&Config{OutputEncoding: "utf8"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unicode output encoding",
+			fields: fields{
+				&Config{OutputEncoding: "Unicode"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative spill threshold",
+			fields: fields{
+				&Config{SpillThreshold: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative new domain window",
+			fields: fields{
+				&Config{NewDomainWindow: -time.Minute},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative maximum whois domains",
+			fields: fields{
+				&Config{MaxWhoisDomains: -1},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,119 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CredentialProvider resolves the secrets referenced by the data source credentials, which keeps
+// API keys and passwords out of the configuration file. A credential value of the form
+// scheme://reference is handed to the provider registered for the scheme, e.g. env://NAME.
+// Providers for external secret stores, such as Vault or AWS Secrets Manager, are added by
+// calling RegisterCredentialProvider from an init function before the configuration is loaded.
+type CredentialProvider interface {
+	// Scheme returns the prefix that selects the provider, such as "env" or "vault"
+	Scheme() string
+
+	// Resolve returns the secret identified by the reference, without the scheme prefix.
+	// The errors returned must not include the secret.
+	Resolve(ref string) (string, error)
+}
+
+var (
+	credProvidersLock sync.Mutex
+	credProviders     = make(map[string]CredentialProvider)
+	// The secrets already resolved during this run, keyed by the full reference
+	credCache = make(map[string]string)
+)
+
+func init() {
+	RegisterCredentialProvider(envCredentialProvider{})
+	RegisterCredentialProvider(fileCredentialProvider{})
+}
+
+// RegisterCredentialProvider makes the provider available for resolving credential values that
+// begin with its scheme. A provider registered later for the same scheme replaces the earlier one.
+func RegisterCredentialProvider(p CredentialProvider) {
+	credProvidersLock.Lock()
+	defer credProvidersLock.Unlock()
+
+	credProviders[strings.ToLower(p.Scheme())] = p
+}
+
+// resolveCredential returns the secret referenced by the value, or the value itself when it
+// does not begin with the scheme of a registered provider.
+func resolveCredential(val string) (string, error) {
+	parts := strings.SplitN(val, "://", 2)
+	if len(parts) != 2 {
+		return val, nil
+	}
+
+	credProvidersLock.Lock()
+	defer credProvidersLock.Unlock()
+
+	p, found := credProviders[strings.ToLower(parts[0])]
+	if !found {
+		return val, nil
+	}
+	if secret, cached := credCache[val]; cached {
+		return secret, nil
+	}
+
+	secret, err := p.Resolve(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the %s secret %s: %v", parts[0], parts[1], err)
+	}
+	credCache[val] = secret
+	return secret, nil
+}
+
+// resolveSecrets replaces the credential values that reference a secret store with the secrets.
+func (cr *Credentials) resolveSecrets() error {
+	for _, field := range []*string{&cr.Username, &cr.Password, &cr.Key, &cr.Secret} {
+		secret, err := resolveCredential(*field)
+		if err != nil {
+			return fmt.Errorf("the %s credentials: %v", cr.Name, err)
+		}
+		*field = secret
+	}
+	return nil
+}
+
+// envCredentialProvider resolves env://NAME references using the environment variables.
+type envCredentialProvider struct{}
+
+func (envCredentialProvider) Scheme() string { return "env" }
+
+func (envCredentialProvider) Resolve(ref string) (string, error) {
+	val, found := os.LookupEnv(ref)
+	if !found || val == "" {
+		return "", fmt.Errorf("the environment variable is not set")
+	}
+	return val, nil
+}
+
+// fileCredentialProvider resolves file:///path references by reading the secret from the file,
+// such as those mounted by container orchestrators.
+type fileCredentialProvider struct{}
+
+func (fileCredentialProvider) Scheme() string { return "file" }
+
+func (fileCredentialProvider) Resolve(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the file")
+	}
+
+	val := strings.TrimSpace(string(data))
+	if val == "" {
+		return "", fmt.Errorf("the file is empty")
+	}
+	return val, nil
+}
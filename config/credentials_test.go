@@ -0,0 +1,82 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Scheme() string { return "test" }
+
+func (p *countingProvider) Resolve(ref string) (string, error) {
+	p.calls++
+	if ref == "missing" {
+		return "", errors.New("not found")
+	}
+	return "secret-" + ref, nil
+}
+
+func TestCredentialProviders(t *testing.T) {
+	os.Setenv("AMASS_TEST_APIKEY", "envkey")
+	defer os.Unsetenv("AMASS_TEST_APIKEY")
+
+	path := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(path, []byte("filepass\n"), 0600); err != nil {
+		t.Fatalf("Failed to write the secret file: %v", err)
+	}
+
+	p := &countingProvider{}
+	RegisterCredentialProvider(p)
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[data_sources]
+		[data_sources.NetworksDB]
+		[data_sources.NetworksDB.Credentials]
+		apikey = env://AMASS_TEST_APIKEY
+		password = file://`+path+`
+		secret = test://shared
+		username = plain:user
+		[data_sources.Umbrella]
+		[data_sources.Umbrella.Credentials]
+		apikey = test://shared
+		`),
+	)
+
+	c := NewConfig()
+	if err := c.loadDataSourceSettings(cfg); err != nil {
+		t.Fatalf("Failed to parse the data source settings: %v", err)
+	}
+
+	creds := c.GetDataSourceConfig("NetworksDB").GetCredentials()
+	if creds.Key != "envkey" || creds.Password != "filepass" || creds.Secret != "secret-shared" || creds.Username != "plain:user" {
+		t.Errorf("The credentials were not resolved: %+v", *creds)
+	}
+	if creds := c.GetDataSourceConfig("Umbrella").GetCredentials(); creds.Key != "secret-shared" {
+		t.Errorf("The Umbrella API key was not resolved")
+	}
+	if p.calls != 1 {
+		t.Errorf("The secret was resolved %d times, expected it to be cached", p.calls)
+	}
+
+	err := NewConfig().GetDataSourceConfig("test").AddCredentials(&Credentials{Name: "account1", Key: "test://missing"})
+	if err == nil {
+		t.Errorf("The unresolved secret did not cause an error")
+	}
+	err = NewConfig().GetDataSourceConfig("test").AddCredentials(&Credentials{Name: "account1", Key: "env://AMASS_TEST_UNSET"})
+	if err == nil || strings.Contains(err.Error(), "envkey") {
+		t.Errorf("The missing environment variable was not reported properly: %v", err)
+	}
+}
@@ -5,19 +5,75 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/go-ini/ini"
 )
 
+// The modes selecting how a data source collects its data.
+const (
+	// ModeAuto uses the API when credentials with a key were provided, and scrapes the website otherwise
+	ModeAuto = "auto"
+	// ModeAPI always uses the API, which requires credentials with a key
+	ModeAPI = "api"
+	// ModeScrape always scrapes the website, which saves the API quota
+	ModeScrape = "scrape"
+)
+
+// The bounds on repeating the queries that returned zero results.
+const (
+	// MaxEmptyRetries is the most times a query is repeated after returning zero results
+	MaxEmptyRetries = 3
+	// DefaultEmptyRetryDelay is the seconds waited before each repeat of the query
+	DefaultEmptyRetryDelay = 5
+)
+
 // DataSourceConfig contains the configurations specific to a data source.
 type DataSourceConfig struct {
-	Name  string
-	TTL   int `ini:"ttl"`
-	creds map[string]*Credentials
+	Name string
+	TTL  int `ini:"ttl"`
+	// The maximum number of requests sent to the data source during a run, where zero is unlimited
+	Quota int `ini:"quota"`
+	// The maximum number of days since a passive DNS record was last observed, where zero keeps all records
+	MaxRecordAge int `ini:"max_record_age"`
+	// The largest response body, in megabytes, read from the data source, where zero uses the global setting
+	MaxResponseSize int `ini:"max_response_size"`
+	// The maximum number of related domains, such as co-occurrences, used for each domain, where zero uses the source default
+	MaxRelated int `ini:"max_related"`
+	// Raise the rate limit while the responses succeed, and settle below the rate that receives 429 responses
+	AdaptiveRate bool `ini:"adaptive_rate"`
+	// The most requests per second the adaptive rate limit can reach, where zero uses the default
+	MaxRate int `ini:"max_rate"`
+	// The milliseconds waited between the dependent requests sent while handling one request, where zero does not wait
+	RequestDelay int `ini:"request_delay"`
+	// The percentage of the rate limit interval that the gaps between requests randomly vary by, where zero keeps a fixed cadence
+	RateJitter int `ini:"rate_jitter"`
+	// The times a query expected to have data is repeated after the API returned zero results, where zero does not repeat it
+	EmptyRetries int `ini:"empty_retries"`
+	// The seconds waited before each repeat of the query, where zero uses the default
+	EmptyRetryDelay int `ini:"empty_retry_delay"`
+	// The scheme, host and optional path prefix that replace the public address of the data source
+	BaseURL string `ini:"base_url"`
+	// Extra query parameters merged into the requests sent to the data source
+	QueryParams map[string]string `ini:"-"`
+	// Whether the data source uses its API, scrapes its website, or chooses based on the credentials
+	Mode string `ini:"mode"`
+	// The PEM files of the TLS client certificate and key presented to the hosts of the data source
+	ClientCert string `ini:"client_cert"`
+	ClientKey  string `ini:"client_key"`
+	// The hosts the client certificate is presented to, which default to the host of the base URL
+	ClientCertHosts []string `ini:"-"`
+	clientCert      *tls.Certificate
+	creds           map[string]*Credentials
 }
 
 // Credentials contains values required for authenticating with web APIs.
@@ -27,6 +83,73 @@ type Credentials struct {
 	Password string `ini:"password"`
 	Key      string `ini:"apikey"`
 	Secret   string `ini:"secret"`
+	// Cookies and Headers are attached to the requests made by scrape data sources
+	Cookies []string `ini:"-"`
+	Headers []string `ini:"-"`
+}
+
+// The data sources that can scrape their website instead of using the API, keyed by the lowercase source name
+var scrapeSources = map[string]bool{
+	"networksdb": true,
+}
+
+var headerNameRE = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// The query parameters accepted by each data source, keyed by the lowercase source name
+var sourceQueryParams = map[string][]string{
+	"dnsdb":    {"limit", "time_first_after", "time_first_before", "time_last_after", "time_last_before"},
+	"umbrella": {"includecategory", "limit", "recordType", "start"},
+}
+
+// String implements the Stringer interface and keeps the secret values out of the logs.
+func (cr *Credentials) String() string {
+	redact := func(val string) string {
+		if val == "" {
+			return ""
+		}
+		return "<redacted>"
+	}
+
+	return fmt.Sprintf("%s: username=%s password=%s apikey=%s secret=%s cookies=%d headers=%d",
+		cr.Name, cr.Username, redact(cr.Password), redact(cr.Key), redact(cr.Secret), len(cr.Cookies), len(cr.Headers))
+}
+
+// HTTPHeaders returns the cookies and headers of the Credentials in a form that can be
+// provided to the HTTP request functions. Nil is returned when neither have been provided.
+func (cr *Credentials) HTTPHeaders() map[string]string {
+	if len(cr.Cookies) == 0 && len(cr.Headers) == 0 {
+		return nil
+	}
+
+	hdrs := make(map[string]string)
+	for _, h := range cr.Headers {
+		parts := strings.SplitN(h, ":", 2)
+		hdrs[http.CanonicalHeaderKey(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+	if len(cr.Cookies) > 0 {
+		hdrs["Cookie"] = strings.Join(cr.Cookies, "; ")
+	}
+	return hdrs
+}
+
+// checkHTTPSettings validates the cookie and header formats without revealing the values.
+func (cr *Credentials) checkHTTPSettings() error {
+	for i, c := range cr.Cookies {
+		req := &http.Request{Header: http.Header{"Cookie": {c}}}
+
+		if cookies := req.Cookies(); len(cookies) != 1 || cookies[0].Value == "" {
+			return fmt.Errorf("cookie %d of the %s credentials must have the form name=value", i+1, cr.Name)
+		}
+	}
+
+	for i, h := range cr.Headers {
+		parts := strings.SplitN(h, ":", 2)
+
+		if len(parts) != 2 || !headerNameRE.MatchString(strings.TrimSpace(parts[0])) || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("header %d of the %s credentials must have the form Name: value", i+1, cr.Name)
+		}
+	}
+	return nil
 }
 
 // GetDataSourceConfig returns the DataSourceConfig associated with the data source name argument.
@@ -47,12 +170,167 @@ func (c *Config) GetDataSourceConfig(source string) *DataSourceConfig {
 	return c.datasrcConfigs[key]
 }
 
-// AddCredentials adds the Credentials provided to the configuration.
+// ResponseSizeLimit returns the largest response body, in bytes, that should be read from the
+// data source, or zero when neither the data source nor the global setting selects a limit.
+func (c *Config) ResponseSizeLimit(source string) int64 {
+	size := c.MaxResponseSize
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.MaxResponseSize > 0 {
+		size = dsc.MaxResponseSize
+	}
+	return int64(size) << 20
+}
+
+// RequestDelay returns the time the data source waits between the chained requests it sends
+// while handling a single request, which smooths bursts without lowering the rate limit.
+func (c *Config) RequestDelay(source string) time.Duration {
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.RequestDelay > 0 {
+		return time.Duration(dsc.RequestDelay) * time.Millisecond
+	}
+	return 0
+}
+
+// RateJitter returns the fraction of the rate limit interval that the gaps between the requests
+// sent to the data source randomly vary by, in either direction.
+func (c *Config) RateJitter(source string) float64 {
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.RateJitter > 0 {
+		return float64(dsc.RateJitter) / 100
+	}
+	return 0
+}
+
+// EmptyRetries returns the number of times the data source repeats a query that is expected to
+// have data after the API returned zero results, and the time waited before each repeat. Some APIs
+// lag behind their backends, so a repeat after a short delay can return the missing data.
+func (c *Config) EmptyRetries(source string) (int, time.Duration) {
+	dsc := c.GetDataSourceConfig(source)
+	if dsc == nil || dsc.EmptyRetries <= 0 {
+		return 0, 0
+	}
+
+	delay := DefaultEmptyRetryDelay
+	if dsc.EmptyRetryDelay > 0 {
+		delay = dsc.EmptyRetryDelay
+	}
+	return dsc.EmptyRetries, time.Duration(delay) * time.Second
+}
+
+// BaseURL returns the address that the URLs of the data source are built from, which is the
+// def parameter unless the base_url option redirects the source to a mirror or mock server.
+func (c *Config) BaseURL(source, def string) string {
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.BaseURL != "" {
+		return dsc.BaseURL
+	}
+	return def
+}
+
+// SourceMode returns the mode selecting how the data source collects its data, which is
+// ModeAuto unless the mode option of the data source selects the API or scraping.
+func (c *Config) SourceMode(source string) string {
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.Mode != "" {
+		return dsc.Mode
+	}
+	return ModeAuto
+}
+
+// checkMode validates the mode option against the modes supported by the data source and the
+// credentials provided for it, and returns the mode in lowercase.
+func (dsc *DataSourceConfig) checkMode() (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(dsc.Mode))
+
+	switch mode {
+	case "", ModeAuto:
+		return ModeAuto, nil
+	case ModeScrape:
+		if !scrapeSources[strings.ToLower(dsc.Name)] {
+			return "", fmt.Errorf("the data source does not support the scrape mode")
+		}
+	case ModeAPI:
+		for _, creds := range dsc.creds {
+			if creds.Key != "" {
+				return mode, nil
+			}
+		}
+		return "", fmt.Errorf("the api mode requires credentials with an apikey")
+	default:
+		return "", fmt.Errorf("the mode %q must be auto, api or scrape", dsc.Mode)
+	}
+	return mode, nil
+}
+
+// ClientCertificate returns the TLS client certificate loaded for the data source, along with the
+// hosts it is presented to, or nil when the data source does not use a client certificate.
+func (c *Config) ClientCertificate(source string) (*tls.Certificate, []string) {
+	if dsc := c.GetDataSourceConfig(source); dsc != nil && dsc.clientCert != nil {
+		return dsc.clientCert, dsc.ClientCertHosts
+	}
+	return nil, nil
+}
+
+// loadClientCert loads the TLS client certificate and key of the data source, and selects the
+// hosts it is presented to, so a certificate that cannot be used is reported at startup.
+func (dsc *DataSourceConfig) loadClientCert(hosts []string) error {
+	if dsc.ClientCert == "" && dsc.ClientKey == "" {
+		if len(hosts) > 0 {
+			return fmt.Errorf("client_cert_host was provided without a client certificate")
+		}
+		return nil
+	}
+	if dsc.ClientCert == "" || dsc.ClientKey == "" {
+		return fmt.Errorf("both client_cert and client_key must be provided")
+	}
+
+	cert, err := tls.LoadX509KeyPair(dsc.ClientCert, dsc.ClientKey)
+	if err != nil {
+		return fmt.Errorf("failed to load the client certificate: %v", err)
+	}
+
+	dsc.ClientCertHosts = nil
+	for _, h := range hosts {
+		for _, host := range strings.Split(h, ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				dsc.ClientCertHosts = append(dsc.ClientCertHosts, host)
+			}
+		}
+	}
+	if len(dsc.ClientCertHosts) == 0 && dsc.BaseURL != "" {
+		if u, err := url.Parse(dsc.BaseURL); err == nil {
+			dsc.ClientCertHosts = []string{strings.ToLower(u.Hostname())}
+		}
+	}
+	if len(dsc.ClientCertHosts) == 0 {
+		return fmt.Errorf("client_cert_host or base_url must select the hosts the client certificate is presented to")
+	}
+
+	dsc.clientCert = &cert
+	return nil
+}
+
+// parseBaseURL checks that the base_url option is an absolute HTTP or HTTPS URL without a query,
+// and returns it without the trailing slash.
+func parseBaseURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("the base URL %q is not valid: %v", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("the base URL %q must be an absolute http or https URL", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("the base URL %q must not contain credentials, a query or a fragment", raw)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// AddCredentials adds the Credentials provided to the configuration, after resolving the
+// values that reference secrets held by a CredentialProvider.
 func (dsc *DataSourceConfig) AddCredentials(cred *Credentials) error {
 	if cred == nil || cred.Name == "" {
 		return fmt.Errorf("AddCredentials: The Credentials argument is invalid")
 	}
 
+	if err := cred.resolveSecrets(); err != nil {
+		return err
+	}
 	if dsc.creds == nil {
 		dsc.creds = make(map[string]*Credentials)
 	}
@@ -73,7 +351,54 @@ func (dsc *DataSourceConfig) GetCredentials() *Credentials {
 	return nil
 }
 
+// AllCredentials returns every set of Credentials associated with the receiver configuration,
+// ordered by the name of the set.
+func (dsc *DataSourceConfig) AllCredentials() []*Credentials {
+	var creds []*Credentials
+	for _, c := range dsc.creds {
+		creds = append(creds, c)
+	}
+
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Name < creds[j].Name })
+	return creds
+}
+
+// parseQueryParams validates the name=value query parameters provided for the data source
+// against the parameters it accepts, and returns them keyed by their accepted spelling.
+func parseQueryParams(source string, params []string) (map[string]string, error) {
+	accepted := sourceQueryParams[strings.ToLower(source)]
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("the data source does not accept query parameters")
+	}
+
+	qp := make(map[string]string)
+	for _, p := range params {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("query parameter %q must have the form name=value", p)
+		}
+
+		var name string
+		key := strings.TrimSpace(parts[0])
+		for _, a := range accepted {
+			if strings.EqualFold(a, key) {
+				name = a
+				break
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("query parameter %s is not accepted, use one of: %s", key, strings.Join(accepted, ", "))
+		}
+		qp[name] = strings.TrimSpace(parts[1])
+	}
+	return qp, nil
+}
+
 func (c *Config) loadDataSourceSettings(cfg *ini.File) error {
+	if c.MaxResponseSize < 0 {
+		return fmt.Errorf("the maximum response size must not be negative")
+	}
+
 	sec, err := cfg.GetSection("data_sources")
 	if err != nil {
 		return err
@@ -104,6 +429,47 @@ func (c *Config) loadDataSourceSettings(cfg *ini.File) error {
 		if c.MinimumTTL > dsc.TTL {
 			dsc.TTL = c.MinimumTTL
 		}
+		if dsc.Quota < 0 {
+			return fmt.Errorf("data source %s: the quota must not be negative", name)
+		}
+		if dsc.MaxRecordAge < 0 {
+			return fmt.Errorf("data source %s: the maximum record age must not be negative", name)
+		}
+		if dsc.MaxResponseSize < 0 {
+			return fmt.Errorf("data source %s: the maximum response size must not be negative", name)
+		}
+		if dsc.MaxRelated < 0 {
+			return fmt.Errorf("data source %s: the maximum number of related domains must not be negative", name)
+		}
+		if dsc.MaxRate < 0 {
+			return fmt.Errorf("data source %s: the maximum rate must not be negative", name)
+		}
+		if dsc.RequestDelay < 0 {
+			return fmt.Errorf("data source %s: the request delay must not be negative", name)
+		}
+		if dsc.RateJitter < 0 || dsc.RateJitter >= 100 {
+			return fmt.Errorf("data source %s: the rate jitter must be a percentage from 0 to 99", name)
+		}
+		if dsc.EmptyRetries < 0 || dsc.EmptyRetries > MaxEmptyRetries {
+			return fmt.Errorf("data source %s: the empty result retries must be from 0 to %d", name, MaxEmptyRetries)
+		}
+		if dsc.EmptyRetryDelay < 0 {
+			return fmt.Errorf("data source %s: the empty result retry delay must not be negative", name)
+		}
+		if dsc.BaseURL != "" {
+			base, err := parseBaseURL(dsc.BaseURL)
+			if err != nil {
+				return fmt.Errorf("data source %s: %v", name, err)
+			}
+			dsc.BaseURL = base
+		}
+		if child.HasKey("query_param") {
+			qp, err := parseQueryParams(name, child.Key("query_param").ValueWithShadows())
+			if err != nil {
+				return fmt.Errorf("data source %s: %v", name, err)
+			}
+			dsc.QueryParams = qp
+		}
 		// Check for data source credentials
 		for _, cr := range child.ChildSections() {
 			setName := strings.Split(cr.Name(), ".")[2]
@@ -112,10 +478,32 @@ func (c *Config) loadDataSourceSettings(cfg *ini.File) error {
 			if err := cr.MapTo(creds); err != nil {
 				return err
 			}
+			if cr.HasKey("cookie") {
+				creds.Cookies = cr.Key("cookie").ValueWithShadows()
+			}
+			if cr.HasKey("header") {
+				creds.Headers = cr.Key("header").ValueWithShadows()
+			}
+			if err := creds.checkHTTPSettings(); err != nil {
+				return fmt.Errorf("data source %s: %v", name, err)
+			}
 			if err := dsc.AddCredentials(creds); err != nil {
-				return err
+				return fmt.Errorf("data source %s: %v", name, err)
 			}
 		}
+		var hosts []string
+		if child.HasKey("client_cert_host") {
+			hosts = child.Key("client_cert_host").ValueWithShadows()
+		}
+		if err := dsc.loadClientCert(hosts); err != nil {
+			return fmt.Errorf("data source %s: %v", name, err)
+		}
+		// The mode is checked once the credentials have been loaded
+		mode, err := dsc.checkMode()
+		if err != nil {
+			return fmt.Errorf("data source %s: %v", name, err)
+		}
+		dsc.Mode = mode
 	}
 	return nil
 }
@@ -0,0 +1,433 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+// EffectiveConfig is the snapshot of the settings in effect once the defaults, the configuration
+// file and the command-line arguments have been merged. The values of credentials and passwords
+// are left out, so the snapshot can be shared when asking for support.
+type EffectiveConfig struct {
+	ConfigFile         string                 `json:"config_file"`
+	OutputDirectory    string                 `json:"output_directory"`
+	ScriptsDirectory   string                 `json:"scripts_directory"`
+	Scripts            []string               `json:"scripts"`
+	Mode               string                 `json:"mode"`
+	Domains            []string               `json:"domains"`
+	Addresses          []string               `json:"addresses"`
+	CIDRs              []string               `json:"cidrs"`
+	ASNs               []int                  `json:"asns"`
+	Ports              []int                  `json:"ports"`
+	Blacklist          []string               `json:"blacklist"`
+	IncludedTLDs       []string               `json:"included_tlds"`
+	ExcludedTLDs       []string               `json:"excluded_tlds"`
+	RelatedTLDs        []string               `json:"related_tlds"`
+	BruteForcing       bool                   `json:"brute_forcing"`
+	Recursive          bool                   `json:"recursive"`
+	MinForRecursive    int                    `json:"min_for_recursive"`
+	MaxDepth           int                    `json:"max_depth"`
+	Alterations        bool                   `json:"alterations"`
+	Resolvers          []string               `json:"resolvers"`
+	ResolversQPS       int                    `json:"resolvers_qps"`
+	TrustedResolvers   []string               `json:"trusted_resolvers"`
+	TrustedQPS         int                    `json:"trusted_qps"`
+	DoHResolvers       []string               `json:"doh_resolvers"`
+	ResolverListURLs   []string               `json:"resolver_list_urls"`
+	MaxDNSQueries      int                    `json:"maximum_dns_queries"`
+	MinimumTTL         int                    `json:"minimum_ttl"`
+	MaxRecursionDepth  int                    `json:"max_recursion_depth"`
+	MaxAddrsPerName    int                    `json:"max_addrs_per_name"`
+	MaxResponseSize    int                    `json:"max_response_size"`
+	MaxConnsPerHost    int                    `json:"max_conns_per_host"`
+	LocalAddress       string                 `json:"local_address,omitempty"`
+	OutputBuffer       int                    `json:"output_buffer"`
+	OutputBackpressure string                 `json:"output_backpressure"`
+	ScrapeFailureLimit int                    `json:"scrape_failure_limit"`
+	MaxSourceRequests  int                    `json:"max_source_requests"`
+	ASNWorkers         int                    `json:"asn_workers"`
+	MaxASNAddrs        int                    `json:"max_asn_addrs"`
+	SpillThreshold     int                    `json:"spill_threshold"`
+	Timeout            string                 `json:"timeout"`
+	DNSRetries         int                    `json:"dns_retries"`
+	DNSRetryBackoff    string                 `json:"dns_retry_backoff"`
+	NameFilterSize     int                    `json:"name_filter_size"`
+	NameFilterFPRate   float64                `json:"name_filter_fp_rate"`
+	NewDomainWindow    string                 `json:"new_domain_window"`
+	MaxWhoisDomains    int                    `json:"max_whois_domains"`
+	FollowCNAMEs       bool                   `json:"follow_cnames"`
+	OnlyResolved       bool                   `json:"only_resolved"`
+	SortedOutput       bool                   `json:"sorted_output"`
+	SourceURLs         bool                   `json:"source_urls"`
+	OutputEncoding     string                 `json:"output_encoding"`
+	RandomizeSources   bool                   `json:"randomize_sources"`
+	RandomSeed         int64                  `json:"random_seed"`
+	GraphDBs           []string               `json:"graph_databases"`
+	Elasticsearch      string                 `json:"elasticsearch"`
+	Neo4j              string                 `json:"neo4j"`
+	Syslog             string                 `json:"syslog"`
+	BGPValidation      string                 `json:"bgp_validation"`
+	RPKI               string                 `json:"rpki"`
+	Sinkholes          string                 `json:"sinkholes"`
+	DataSources        []*EffectiveDataSource `json:"data_sources"`
+	Errors             map[string]string      `json:"errors,omitempty"`
+}
+
+// EffectiveDataSource holds the settings in effect for a single data source.
+type EffectiveDataSource struct {
+	Name            string `json:"name"`
+	Enabled         bool   `json:"enabled"`
+	Mode            string `json:"mode"`
+	TTL             int    `json:"ttl"`
+	Quota           int    `json:"quota"`
+	MaxRecordAge    int    `json:"max_record_age"`
+	MaxResponseSize int    `json:"max_response_size"`
+	MaxRelated      int    `json:"max_related"`
+	AdaptiveRate    bool   `json:"adaptive_rate"`
+	MaxRate         int    `json:"max_rate"`
+	RequestDelay    int    `json:"request_delay"`
+	RateJitter      int    `json:"rate_jitter"`
+	// The repeats of the queries that returned zero results, and the seconds waited before each
+	EmptyRetries    int    `json:"empty_retries"`
+	EmptyRetryDelay int    `json:"empty_retry_delay"`
+	BaseURL         string `json:"base_url,omitempty"`
+	// The client certificate file and the hosts it is presented to
+	ClientCert      string   `json:"client_cert,omitempty"`
+	ClientCertHosts []string `json:"client_cert_hosts,omitempty"`
+	// The extra query parameters merged into the requests
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	// The names of the credential sets, each with the fields that were provided
+	Credentials map[string][]string `json:"credentials,omitempty"`
+}
+
+// Effective returns the settings in effect for the Config. The sources argument maps the name of
+// each data source known to the caller to whether it will be used.
+func (c *Config) Effective(sources map[string]bool) *EffectiveConfig {
+	ec := &EffectiveConfig{
+		ConfigFile:         c.path,
+		OutputDirectory:    OutputDirectory(c.Dir),
+		ScriptsDirectory:   c.ScriptsDirectory,
+		Mode:               "normal",
+		Domains:            c.Domains(),
+		ASNs:               c.ASNs,
+		Ports:              c.Ports,
+		Blacklist:          c.Blacklist,
+		IncludedTLDs:       c.TLDFilter.Include,
+		ExcludedTLDs:       c.TLDFilter.Exclude,
+		BruteForcing:       c.BruteForcing,
+		Recursive:          c.Recursive,
+		MinForRecursive:    c.MinForRecursive,
+		MaxDepth:           c.MaxDepth,
+		Alterations:        c.Alterations,
+		Resolvers:          c.Resolvers,
+		ResolversQPS:       c.ResolversQPS,
+		TrustedResolvers:   c.TrustedResolvers,
+		TrustedQPS:         c.TrustedQPS,
+		DoHResolvers:       c.DoHResolvers,
+		ResolverListURLs:   c.ResolverListURLs,
+		MaxDNSQueries:      c.MaxDNSQueries,
+		MinimumTTL:         c.MinimumTTL,
+		MaxRecursionDepth:  c.MaxRecursionDepth,
+		MaxAddrsPerName:    c.MaxAddrsPerName,
+		MaxResponseSize:    c.MaxResponseSize,
+		MaxConnsPerHost:    c.MaxConnsPerHost,
+		LocalAddress:       c.LocalAddress,
+		OutputBuffer:       c.OutputBufferSize(),
+		OutputBackpressure: OutputBackpressureBlock,
+		OutputEncoding:     OutputEncodingASCII,
+		ScrapeFailureLimit: c.ScrapeFailureLimit,
+		MaxSourceRequests:  c.MaxSourceRequests,
+		ASNWorkers:         c.NumASNWorkers(),
+		MaxASNAddrs:        c.MaxASNAddrs,
+		SpillThreshold:     c.SpillThreshold,
+		Timeout:            "unlimited",
+		DNSRetries:         c.DNSRetries,
+		DNSRetryBackoff:    c.DNSRetryBackoff.String(),
+		NameFilterSize:     c.NameFilterSize,
+		NameFilterFPRate:   c.NameFilterRate(),
+		NewDomainWindow:    "whole run",
+		MaxWhoisDomains:    c.MaxWhoisDomains,
+		FollowCNAMEs:       c.FollowCNAMEs,
+		OnlyResolved:       c.OnlyResolved,
+		SortedOutput:       c.SortedOutput,
+		SourceURLs:         c.SourceURLs,
+		RandomizeSources:   c.RandomizeSources,
+		RandomSeed:         c.RandomSeed,
+	}
+
+	if c.Passive {
+		ec.Mode = "passive"
+	} else if c.Active {
+		ec.Mode = "active"
+	}
+	if c.Timeout > 0 {
+		ec.Timeout = c.Timeout.String()
+	}
+	if c.NewDomainWindow > 0 {
+		ec.NewDomainWindow = c.NewDomainWindow.String()
+	}
+	if c.DropOutput() {
+		ec.OutputBackpressure = OutputBackpressureDrop
+	}
+	if c.UnicodeOutput() {
+		ec.OutputEncoding = OutputEncodingUnicode
+	}
+	if c.RelatedTLDs.Enabled {
+		ec.RelatedTLDs = c.RelatedTLDs.TLDs
+		if len(ec.RelatedTLDs) == 0 {
+			ec.RelatedTLDs = DefaultRelatedTLDs
+		}
+	}
+	for _, addr := range c.Addresses {
+		ec.Addresses = append(ec.Addresses, addr.String())
+	}
+	for _, cidr := range c.CIDRs {
+		ec.CIDRs = append(ec.CIDRs, cidr.String())
+	}
+	for _, db := range c.GraphDBs {
+		ec.GraphDBs = append(ec.GraphDBs, db.System+" "+redactURL(db.URL))
+	}
+	if c.Elasticsearch != nil {
+		ec.Elasticsearch = redactURL(c.Elasticsearch.URL) + " index=" + c.Elasticsearch.Index
+	}
+	if c.Neo4j != nil {
+		ec.Neo4j = c.Neo4j.String()
+	}
+	if c.Syslog != nil {
+		ec.Syslog = c.Syslog.String()
+	}
+	if c.BGPValidation != nil {
+		ec.BGPValidation = c.BGPValidation.String()
+	}
+	if c.RPKI != nil {
+		ec.RPKI = c.RPKI.String()
+	}
+	if c.Sinkholes != nil {
+		ec.Sinkholes = c.Sinkholes.String()
+	}
+
+	files, err := c.AcquireScriptFiles()
+	for _, f := range files {
+		if f.Path != "" {
+			ec.Scripts = append(ec.Scripts, f.Path)
+		}
+	}
+	if err != nil {
+		ec.Errors = map[string]string{"scripts": err.Error()}
+	}
+
+	names := make(map[string]string)
+	for name := range sources {
+		names[strings.ToLower(name)] = name
+	}
+	c.Lock()
+	for key := range c.datasrcConfigs {
+		if _, found := names[key]; !found {
+			names[key] = key
+		}
+	}
+	c.Unlock()
+
+	for _, name := range names {
+		ec.DataSources = append(ec.DataSources, c.effectiveDataSource(name, sources[name]))
+	}
+	sort.Slice(ec.DataSources, func(i, j int) bool {
+		return strings.ToLower(ec.DataSources[i].Name) < strings.ToLower(ec.DataSources[j].Name)
+	})
+	return ec
+}
+
+func (c *Config) effectiveDataSource(name string, enabled bool) *EffectiveDataSource {
+	dsc := c.GetDataSourceConfig(name)
+
+	eds := &EffectiveDataSource{
+		Name:            name,
+		Enabled:         enabled,
+		Mode:            c.SourceMode(name),
+		TTL:             dsc.TTL,
+		Quota:           dsc.Quota,
+		MaxRecordAge:    dsc.MaxRecordAge,
+		MaxResponseSize: dsc.MaxResponseSize,
+		MaxRelated:      dsc.MaxRelated,
+		AdaptiveRate:    dsc.AdaptiveRate,
+		MaxRate:         dsc.MaxRate,
+		RequestDelay:    dsc.RequestDelay,
+		RateJitter:      dsc.RateJitter,
+		BaseURL:         dsc.BaseURL,
+		QueryParams:     dsc.QueryParams,
+	}
+	if retries, delay := c.EmptyRetries(name); retries > 0 {
+		eds.EmptyRetries = retries
+		eds.EmptyRetryDelay = int(delay / time.Second)
+	}
+	if dsc.clientCert != nil {
+		eds.ClientCert = dsc.ClientCert
+		eds.ClientCertHosts = dsc.ClientCertHosts
+	}
+	if eds.TTL < c.MinimumTTL {
+		eds.TTL = c.MinimumTTL
+	}
+
+	for setName, cr := range dsc.creds {
+		if eds.Credentials == nil {
+			eds.Credentials = make(map[string][]string)
+		}
+		eds.Credentials[setName] = cr.providedFields()
+	}
+	return eds
+}
+
+// providedFields returns the names of the fields that hold a value, without revealing the values.
+func (cr *Credentials) providedFields() []string {
+	var fields []string
+
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"username", cr.Username != ""},
+		{"password", cr.Password != ""},
+		{"apikey", cr.Key != ""},
+		{"secret", cr.Secret != ""},
+		{"cookie", len(cr.Cookies) > 0},
+		{"header", len(cr.Headers) > 0},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// redactURL removes the user information from the URL, including the forms used by the
+// database drivers that are not valid URLs.
+func redactURL(u string) string {
+	start := 0
+	if i := strings.Index(u, "://"); i >= 0 {
+		start = i + 3
+	}
+
+	if at := strings.LastIndex(u, "@"); at > start {
+		return u[:start] + redacted + u[at:]
+	}
+	return u
+}
+
+// WriteText writes the settings in a form that is easy to read.
+func (ec *EffectiveConfig) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	setting := func(name string, val interface{}) {
+		switch v := val.(type) {
+		case []string:
+			val = strings.Join(v, ", ")
+		case []int:
+			val = strings.Trim(fmt.Sprint(v), "[]")
+		}
+		fmt.Fprintf(tw, "%s\t%v\n", name, val)
+	}
+
+	setting("Configuration file", ec.ConfigFile)
+	setting("Output directory", ec.OutputDirectory)
+	setting("Scripts directory", ec.ScriptsDirectory)
+	setting("User scripts", ec.Scripts)
+	setting("Mode", ec.Mode)
+	setting("Domains", ec.Domains)
+	setting("Addresses", ec.Addresses)
+	setting("CIDRs", ec.CIDRs)
+	setting("ASNs", ec.ASNs)
+	setting("Ports", ec.Ports)
+	setting("Blacklist", ec.Blacklist)
+	setting("Included TLDs", ec.IncludedTLDs)
+	setting("Excluded TLDs", ec.ExcludedTLDs)
+	setting("Related TLDs", ec.RelatedTLDs)
+	setting("Brute forcing", ec.BruteForcing)
+	setting("Recursive", ec.Recursive)
+	setting("Minimum for recursive", ec.MinForRecursive)
+	setting("Maximum depth", ec.MaxDepth)
+	setting("Alterations", ec.Alterations)
+	setting("Resolvers", ec.Resolvers)
+	setting("Resolvers QPS", ec.ResolversQPS)
+	setting("Trusted resolvers", ec.TrustedResolvers)
+	setting("Trusted QPS", ec.TrustedQPS)
+	setting("DoH resolvers", ec.DoHResolvers)
+	setting("Resolver lists", ec.ResolverListURLs)
+	setting("Maximum DNS queries", ec.MaxDNSQueries)
+	setting("Minimum TTL", ec.MinimumTTL)
+	setting("Maximum recursion depth", ec.MaxRecursionDepth)
+	setting("Maximum addresses per name", ec.MaxAddrsPerName)
+	setting("Maximum response size", ec.MaxResponseSize)
+	setting("Maximum connections per host", ec.MaxConnsPerHost)
+	setting("Local address", ec.LocalAddress)
+	setting("Output buffer", ec.OutputBuffer)
+	setting("Output backpressure", ec.OutputBackpressure)
+	setting("Scrape failure limit", ec.ScrapeFailureLimit)
+	setting("Maximum source requests", ec.MaxSourceRequests)
+	setting("ASN workers", ec.ASNWorkers)
+	setting("Maximum addresses per ASN", ec.MaxASNAddrs)
+	setting("Spill threshold", ec.SpillThreshold)
+	setting("Timeout", ec.Timeout)
+	setting("DNS retries", ec.DNSRetries)
+	setting("DNS retry backoff", ec.DNSRetryBackoff)
+	setting("Name filter size", ec.NameFilterSize)
+	setting("Name filter false-positive rate", ec.NameFilterFPRate)
+	setting("New domain window", ec.NewDomainWindow)
+	setting("Maximum whois domains", ec.MaxWhoisDomains)
+	setting("Follow CNAMEs", ec.FollowCNAMEs)
+	setting("Only resolved", ec.OnlyResolved)
+	setting("Sorted output", ec.SortedOutput)
+	setting("Source URLs", ec.SourceURLs)
+	setting("Output encoding", ec.OutputEncoding)
+	setting("Randomize sources", ec.RandomizeSources)
+	setting("Random seed", ec.RandomSeed)
+	setting("Graph databases", ec.GraphDBs)
+	setting("Elasticsearch", ec.Elasticsearch)
+	setting("Neo4j", ec.Neo4j)
+	setting("Syslog", ec.Syslog)
+	setting("BGP validation", ec.BGPValidation)
+	setting("RPKI validation", ec.RPKI)
+	setting("Sinkholes", ec.Sinkholes)
+	for name, err := range ec.Errors {
+		setting("Error ("+name+")", err)
+	}
+
+	fmt.Fprintf(tw, "\nData Source\tEnabled\tMode\tTTL\tQuota\tMax Record Age\tMax Response Size\tMax Related\tAdaptive Rate\tRequest Delay\tRate Jitter\tEmpty Retries\tBase URL\tQuery Parameters\tCredentials\n")
+	for _, src := range ec.DataSources {
+		var sets []string
+		for name, fields := range src.Credentials {
+			sets = append(sets, name+" ("+strings.Join(fields, ", ")+")")
+		}
+		sort.Strings(sets)
+
+		var params []string
+		for name, val := range src.QueryParams {
+			params = append(params, name+"="+val)
+		}
+		sort.Strings(params)
+
+		adaptive := "off"
+		if src.AdaptiveRate {
+			adaptive = "on"
+			if src.MaxRate > 0 {
+				adaptive = fmt.Sprintf("max %d/s", src.MaxRate)
+			}
+		}
+		retries := "off"
+		if src.EmptyRetries > 0 {
+			retries = fmt.Sprintf("%d after %ds", src.EmptyRetries, src.EmptyRetryDelay)
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%dms\t%d%%\t%s\t%s\t%s\t%s\n", src.Name, src.Enabled, src.Mode, src.TTL, src.Quota, src.MaxRecordAge,
+			src.MaxResponseSize, src.MaxRelated, adaptive, src.RequestDelay, src.RateJitter, retries, src.BaseURL, strings.Join(params, "&"), strings.Join(sets, "; "))
+	}
+	return tw.Flush()
+}
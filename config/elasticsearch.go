@@ -0,0 +1,67 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+const (
+	// DefaultElasticsearchIndex is the index that receives the findings when none is configured.
+	DefaultElasticsearchIndex = "amass"
+
+	// DefaultElasticsearchBatchSize is the number of documents sent in each bulk request.
+	DefaultElasticsearchBatchSize = 500
+)
+
+// ElasticsearchConfig contains the values required for indexing the findings into an
+// Elasticsearch or OpenSearch cluster.
+type ElasticsearchConfig struct {
+	URL       string
+	Index     string
+	Username  string
+	Password  string
+	APIKey    string
+	BatchSize int
+}
+
+func (c *Config) loadElasticsearchSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("elasticsearch")
+	if err != nil {
+		return nil
+	}
+
+	es := &ElasticsearchConfig{
+		URL:       strings.TrimRight(strings.TrimSpace(sec.Key("url").String()), "/"),
+		Index:     strings.TrimSpace(sec.Key("index").MustString(DefaultElasticsearchIndex)),
+		Username:  sec.Key("username").String(),
+		Password:  sec.Key("password").String(),
+		APIKey:    sec.Key("api_key").String(),
+		BatchSize: DefaultElasticsearchBatchSize,
+	}
+
+	u, err := url.Parse(es.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("elasticsearch: url must be an http or https URL of the cluster")
+	}
+	// Index names are required to be lowercase and cannot contain path separators
+	if es.Index == "" || es.Index != strings.ToLower(es.Index) || strings.ContainsAny(es.Index, "/\\ ") {
+		return fmt.Errorf("elasticsearch: %q is not a valid index name", es.Index)
+	}
+	if sec.HasKey("batch_size") {
+		size, err := sec.Key("batch_size").Int()
+		if err != nil || size <= 0 {
+			return fmt.Errorf("elasticsearch: batch_size must be a positive integer")
+		}
+		es.BatchSize = size
+	}
+
+	c.Elasticsearch = es
+	return nil
+}
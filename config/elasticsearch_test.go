@@ -0,0 +1,47 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadElasticsearchSettings(t *testing.T) {
+	c := NewConfig()
+	if c.Elasticsearch != nil {
+		t.Errorf("Elasticsearch was configured by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[elasticsearch]
+		url = https://localhost:9200/
+		username = elastic
+		password = changeme
+		batch_size = 100
+		`),
+	)
+	if err := c.loadElasticsearchSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	es := c.Elasticsearch
+	if es == nil || es.URL != "https://localhost:9200" || es.Index != DefaultElasticsearchIndex ||
+		es.Username != "elastic" || es.Password != "changeme" || es.BatchSize != 100 {
+		t.Errorf("Failed to load the Elasticsearch settings: %+v", es)
+	}
+
+	for _, bad := range []string{
+		"url = localhost:9200",
+		"url = http://localhost:9200\nindex = Amass",
+		"url = http://localhost:9200\nbatch_size = 0",
+	} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[elasticsearch]\n"+bad))
+		if err := NewConfig().loadElasticsearchSettings(cfg); err == nil {
+			t.Errorf("The invalid setting was accepted: %s", bad)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+func (c *Config) loadGeolocationSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("geolocation")
+	if err != nil {
+		return nil
+	}
+
+	if sec.HasKey("database") {
+		path := strings.TrimSpace(sec.Key("database").String())
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("geolocation: unable to access the database file %s: %v", path, err)
+		}
+		c.GeoDatabase = path
+	}
+	if sec.HasKey("api") {
+		c.GeoAPI = strings.ToLower(strings.TrimSpace(sec.Key("api").String()))
+	}
+	return nil
+}
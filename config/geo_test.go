@@ -0,0 +1,37 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadGeolocationSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	if err := ioutil.WriteFile(path, []byte{}, 0600); err != nil {
+		t.Fatalf("Failed to create the database file: %v", err)
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true},
+		[]byte("[geolocation]\ndatabase = "+path+"\napi = IP-API\n"))
+
+	c := NewConfig()
+	if err := c.loadGeolocationSettings(cfg); err != nil {
+		t.Fatalf("Failed to parse the geolocation settings: %v", err)
+	}
+	if c.GeoDatabase != path || c.GeoAPI != "ip-api" {
+		t.Errorf("Unexpected geolocation settings: %s, %s", c.GeoDatabase, c.GeoAPI)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true},
+		[]byte("[geolocation]\ndatabase = "+filepath.Join(t.TempDir(), "missing.mmdb")+"\n"))
+	if err := NewConfig().loadGeolocationSettings(cfg); err == nil {
+		t.Errorf("The missing database file was accepted")
+	}
+}
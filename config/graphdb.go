@@ -5,11 +5,20 @@
 package config
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/go-ini/ini"
 )
 
+const (
+	// DefaultGraphFlushBatchSize is the number of graph writes that trigger a flush to the graph databases.
+	DefaultGraphFlushBatchSize = 500
+
+	// DefaultGraphFlushInterval is the maximum number of seconds between flushes to the graph databases.
+	DefaultGraphFlushInterval = 30
+)
+
 // Database contains values required for connecting with graph databases.
 type Database struct {
 	System   string
@@ -27,6 +36,21 @@ func (c *Config) loadDatabaseSettings(cfg *ini.File) error {
 		return nil
 	}
 
+	if sec.HasKey("flush_batch_size") {
+		size, err := sec.Key("flush_batch_size").Int()
+		if err != nil || size <= 0 {
+			return fmt.Errorf("graphdbs: flush_batch_size must be a positive integer")
+		}
+		c.GraphFlushBatchSize = size
+	}
+	if sec.HasKey("flush_interval") {
+		secs, err := sec.Key("flush_interval").Int()
+		if err != nil || secs <= 0 {
+			return fmt.Errorf("graphdbs: flush_interval must be a positive number of seconds")
+		}
+		c.GraphFlushInterval = secs
+	}
+
 	for _, child := range sec.ChildSections() {
 		db := new(Database)
 		name := strings.Split(child.Name(), ".")[1]
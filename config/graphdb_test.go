@@ -57,6 +57,33 @@ func TestLoadDatabaseSettings(t *testing.T) {
 	}
 }
 
+func TestLoadGraphFlushSettings(t *testing.T) {
+	c := NewConfig()
+	if c.GraphFlushBatchSize != DefaultGraphFlushBatchSize || c.GraphFlushInterval != DefaultGraphFlushInterval {
+		t.Errorf("The graph flush settings were not set to the default values")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[graphdbs]
+		flush_batch_size = 100
+		flush_interval = 5
+		`),
+	)
+	if err := c.loadDatabaseSettings(cfg); err != nil {
+		t.Errorf("Load failed: %v", err)
+	}
+	if c.GraphFlushBatchSize != 100 || c.GraphFlushInterval != 5 {
+		t.Errorf("Failed to load the graph flush settings")
+	}
+
+	for _, bad := range []string{"flush_batch_size = 0", "flush_interval = -1", "flush_interval = soon"} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[graphdbs]\n"+bad))
+		if err := NewConfig().loadDatabaseSettings(cfg); err == nil {
+			t.Errorf("The invalid setting '%s' was accepted", bad)
+		}
+	}
+}
+
 func TestLocalDatabaseSettings(t *testing.T) {
 	c := NewConfig()
 	db := new(Database)
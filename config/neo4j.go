@@ -0,0 +1,69 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// DefaultNeo4jBatchSize is the number of findings written to Neo4j in each transaction.
+const DefaultNeo4jBatchSize = 500
+
+// Neo4jConfig contains the values required for writing the findings into a Neo4j database.
+type Neo4jConfig struct {
+	URI       string
+	Username  string
+	Password  string
+	Database  string
+	BatchSize int
+}
+
+// String returns the URI of the server, without the user information, and the database.
+func (n *Neo4jConfig) String() string {
+	s := redactURL(n.URI)
+	if n.Database != "" {
+		s += " database=" + n.Database
+	}
+	return s
+}
+
+func (c *Config) loadNeo4jSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("neo4j")
+	if err != nil {
+		return nil
+	}
+
+	n := &Neo4jConfig{
+		URI:       strings.TrimSpace(sec.Key("uri").String()),
+		Username:  sec.Key("username").String(),
+		Password:  sec.Key("password").String(),
+		Database:  strings.TrimSpace(sec.Key("database").String()),
+		BatchSize: DefaultNeo4jBatchSize,
+	}
+
+	u, err := url.Parse(n.URI)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("neo4j: uri must be a Bolt URI of the server, such as bolt://localhost:7687")
+	}
+	switch u.Scheme {
+	case "bolt", "bolt+s", "bolt+ssc", "neo4j", "neo4j+s", "neo4j+ssc":
+	default:
+		return fmt.Errorf("neo4j: the %q scheme is not supported, use bolt, neo4j or their +s and +ssc variants", u.Scheme)
+	}
+	if sec.HasKey("batch_size") {
+		size, err := sec.Key("batch_size").Int()
+		if err != nil || size <= 0 {
+			return fmt.Errorf("neo4j: batch_size must be a positive integer")
+		}
+		n.BatchSize = size
+	}
+
+	c.Neo4j = n
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadNeo4jSettings(t *testing.T) {
+	c := NewConfig()
+	if c.Neo4j != nil {
+		t.Errorf("Neo4j was configured by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[neo4j]
+		uri = bolt+s://graph.example.com
+		username = neo4j
+		password = changeme
+		database = amass
+		batch_size = 100
+		`),
+	)
+	if err := c.loadNeo4jSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	n := c.Neo4j
+	if n == nil || n.URI != "bolt+s://graph.example.com" || n.Username != "neo4j" ||
+		n.Password != "changeme" || n.Database != "amass" || n.BatchSize != 100 {
+		t.Errorf("Failed to load the Neo4j settings: %+v", n)
+	}
+
+	for _, bad := range []string{
+		"uri = localhost:7687",
+		"uri = http://localhost:7474",
+		"uri = bolt://localhost:7687\nbatch_size = 0",
+	} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[neo4j]\n"+bad))
+		if err := NewConfig().loadNeo4jSettings(cfg); err == nil {
+			t.Errorf("The invalid setting was accepted: %s", bad)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/go-ini/ini"
+)
+
+const (
+	// The file in the output directory that keeps the last copy of each remote configuration fetched
+	remoteConfigCacheFile = "remote_configs.json"
+	// The longest wait for the remote configuration to be fetched
+	remoteConfigTimeout = 30 * time.Second
+)
+
+// remoteConfigCopy is a remote configuration as it was last fetched and validated.
+type remoteConfigCopy struct {
+	Config  string    `json:"config"`
+	Fetched time.Time `json:"fetched"`
+}
+
+var remoteConfigCacheLock sync.Mutex
+
+// IsRemoteConfig returns true when the configuration path is an http or https URL.
+func IsRemoteConfig(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// RedactConfigURL removes the credentials from the URL of a remote configuration, including
+// the user information and the values of the query parameters, which often hold tokens.
+func RedactConfigURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return redactURL(u)
+	}
+
+	if parsed.User != nil {
+		parsed.User = url.User(redacted)
+	}
+	if parsed.RawQuery != "" {
+		q := parsed.Query()
+		for k := range q {
+			q[k] = []string{redacted}
+		}
+		parsed.RawQuery = q.Encode()
+	}
+	// The redacted values are easier to read without the escaping
+	s, _ := url.PathUnescape(parsed.String())
+	if s == "" {
+		s = parsed.String()
+	}
+	return s
+}
+
+// fetchRemoteConfig returns the configuration document at the URL once it has been validated. A
+// copy of the document is kept in the output directory, and replaces the document when the URL
+// cannot be fetched or the document is not valid.
+func (c *Config) fetchRemoteConfig(u, dir string) ([]byte, error) {
+	redactedURL := RedactConfigURL(u)
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteConfigTimeout)
+	defer cancel()
+
+	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	if err != nil {
+		// The URL within the error could expose the credentials
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+	} else {
+		err = validateRemoteConfig([]byte(page))
+	}
+
+	if err == nil {
+		c.saveRemoteConfig(dir, u, page)
+		return []byte(page), nil
+	}
+	if saved := savedRemoteConfig(dir, u); saved != nil {
+		c.Log.Printf("Configuration %s: %v, using the copy fetched on %s",
+			redactedURL, err, saved.Fetched.Format("2006-01-02 15:04:05"))
+		return []byte(saved.Config), nil
+	}
+	return nil, fmt.Errorf("failed to obtain the configuration %s: %v", redactedURL, err)
+}
+
+// validateRemoteConfig checks that the document parses and holds settings that can be applied,
+// without changing the configuration in use.
+func validateRemoteConfig(data []byte) error {
+	f, err := ini.LoadSources(ini.LoadOptions{
+		Insensitive:  true,
+		AllowShadows: true,
+	}, data)
+	if err != nil {
+		return fmt.Errorf("the document is not a valid INI configuration: %v", err)
+	}
+
+	var keys int
+	for _, sec := range f.Sections() {
+		keys += len(sec.Keys())
+		if sec.Name() != ini.DefaultSection {
+			keys++
+		}
+	}
+	if keys == 0 {
+		return errors.New("the document does not hold any settings")
+	}
+
+	if err := NewConfig().loadSettings(data); err != nil {
+		return fmt.Errorf("the document holds invalid settings: %v", err)
+	}
+	return nil
+}
+
+func remoteConfigCachePath(dir string) string {
+	return filepath.Join(OutputDirectory(dir), remoteConfigCacheFile)
+}
+
+func readRemoteConfigs(path string) map[string]*remoteConfigCopy {
+	configs := make(map[string]*remoteConfigCopy)
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &configs)
+	}
+	return configs
+}
+
+func savedRemoteConfig(dir, u string) *remoteConfigCopy {
+	remoteConfigCacheLock.Lock()
+	defer remoteConfigCacheLock.Unlock()
+
+	if saved, found := readRemoteConfigs(remoteConfigCachePath(dir))[u]; found && saved != nil && saved.Config != "" {
+		return saved
+	}
+	return nil
+}
+
+func (c *Config) saveRemoteConfig(dir, u, config string) {
+	remoteConfigCacheLock.Lock()
+	defer remoteConfigCacheLock.Unlock()
+
+	path := remoteConfigCachePath(dir)
+	configs := readRemoteConfigs(path)
+	configs[u] = &remoteConfigCopy{
+		Config:  config,
+		Fetched: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(configs, "", "  ")
+	if err != nil {
+		return
+	}
+	// The configuration can hold the credentials of the data sources
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = ioutil.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		c.Log.Printf("Failed to save the copy of the configuration %s: %v", RedactConfigURL(u), err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/net/http"
+)
+
+// The file in the output directory that keeps the last copy of each resolver list fetched.
+const resolverListCacheFile = "resolver_lists.json"
+
+// resolverListCopy is a resolver list as it was last fetched.
+type resolverListCopy struct {
+	Resolvers []string  `json:"resolvers"`
+	Fetched   time.Time `json:"fetched"`
+}
+
+var resolverListCacheLock sync.Mutex
+
+// SetResolverListURLs assigns the URLs of the remote resolver lists provided in the parameter to the configuration.
+func (c *Config) SetResolverListURLs(urls ...string) error {
+	var lists []string
+	seen := make(map[string]struct{})
+
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if _, found := seen[u]; found || u == "" {
+			continue
+		}
+		seen[u] = struct{}{}
+
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("the resolver list %s is not an http or https URL", u)
+		}
+		lists = append(lists, u)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.ResolverListURLs = lists
+	return nil
+}
+
+// LoadResolverLists fetches the remote resolver lists and adds the resolvers to the configuration.
+// A list that cannot be fetched is replaced by the copy saved in the output directory the last
+// time it was fetched, and an error is returned when no copy has been saved.
+func (c *Config) LoadResolverLists(ctx context.Context) error {
+	for _, u := range c.ResolverListURLs {
+		var resolvers []string
+
+		page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+		if err == nil {
+			var invalid []string
+			if resolvers, invalid = ParseResolverList(page); len(invalid) > 0 {
+				c.Log.Printf("Resolver list %s: ignored %d entries that are not usable resolvers, such as %q",
+					u, len(invalid), invalid[0])
+			}
+			if len(resolvers) == 0 {
+				err = fmt.Errorf("the list does not contain any usable resolvers")
+			}
+		}
+
+		if err == nil {
+			c.saveResolverList(u, resolvers)
+		} else if saved := c.savedResolverList(u); saved != nil {
+			c.Log.Printf("Resolver list %s: %v, using the copy fetched on %s",
+				u, err, saved.Fetched.Format("2006-01-02 15:04:05"))
+			resolvers = saved.Resolvers
+		} else {
+			return fmt.Errorf("failed to obtain the resolver list %s: %v", u, err)
+		}
+
+		c.AddResolvers(resolvers...)
+	}
+	return nil
+}
+
+// ParseResolverList returns the usable resolvers in the list and the entries that were rejected.
+// The entries are IP addresses, optionally with a port, separated by whitespace or commas, and
+// the text following a '#' or ';' on a line is ignored.
+func ParseResolverList(list string) ([]string, []string) {
+	var resolvers, invalid []string
+	seen := make(map[string]struct{})
+
+	for _, line := range strings.Split(list, "\n") {
+		if idx := strings.IndexAny(line, "#;"); idx != -1 {
+			line = line[:idx]
+		}
+
+		for _, entry := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		}) {
+			r, ok := usableResolver(entry)
+			if !ok {
+				invalid = append(invalid, entry)
+				continue
+			}
+			if _, found := seen[r]; !found {
+				seen[r] = struct{}{}
+				resolvers = append(resolvers, r)
+			}
+		}
+	}
+	return resolvers, invalid
+}
+
+// usableResolver returns the resolver in the form used by the configuration. Addresses that
+// cannot receive queries from the network, such as loopback and multicast addresses, are rejected.
+func usableResolver(entry string) (string, bool) {
+	host, port := entry, ""
+	if h, p, err := net.SplitHostPort(entry); err == nil {
+		host, port = h, p
+	}
+
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsInterfaceLocalMulticast() {
+		return "", false
+	}
+	if port == "" {
+		return ip.String(), true
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", false
+	}
+	return net.JoinHostPort(ip.String(), port), true
+}
+
+func (c *Config) resolverListCachePath() string {
+	return filepath.Join(OutputDirectory(c.Dir), resolverListCacheFile)
+}
+
+func readResolverLists(path string) map[string]*resolverListCopy {
+	lists := make(map[string]*resolverListCopy)
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &lists)
+	}
+	return lists
+}
+
+func (c *Config) savedResolverList(u string) *resolverListCopy {
+	resolverListCacheLock.Lock()
+	defer resolverListCacheLock.Unlock()
+
+	if saved, found := readResolverLists(c.resolverListCachePath())[u]; found && saved != nil && len(saved.Resolvers) > 0 {
+		return saved
+	}
+	return nil
+}
+
+func (c *Config) saveResolverList(u string, resolvers []string) {
+	resolverListCacheLock.Lock()
+	defer resolverListCacheLock.Unlock()
+
+	path := c.resolverListCachePath()
+	lists := readResolverLists(path)
+	lists[u] = &resolverListCopy{
+		Resolvers: resolvers,
+		Fetched:   time.Now(),
+	}
+
+	data, err := json.MarshalIndent(lists, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		c.Log.Printf("Failed to save the copy of the resolver list %s: %v", u, err)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestParseResolverList(t *testing.T) {
+	list := "# curated resolvers\n1.1.1.1\n8.8.8.8:53, 9.9.9.9 ; Quad9\r\n[2606:4700::1111]:5353\n" +
+		"1.1.1.1\nresolver.example.com\n127.0.0.1\n0.0.0.0\n224.0.0.1\n8.8.4.4:0\n"
+
+	resolvers, invalid := ParseResolverList(list)
+	if want := []string{"1.1.1.1", "8.8.8.8:53", "9.9.9.9", "[2606:4700::1111]:5353"}; !reflect.DeepEqual(resolvers, want) {
+		t.Errorf("ParseResolverList() = %v, want %v", resolvers, want)
+	}
+	if want := []string{"resolver.example.com", "127.0.0.1", "0.0.0.0", "224.0.0.1", "8.8.4.4:0"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("The rejected entries = %v, want %v", invalid, want)
+	}
+}
+
+func TestLoadResolverLists(t *testing.T) {
+	var fail int32
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(nethttp.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "1.1.1.1\n9.9.9.9\n")
+	}))
+	defer srv.Close()
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true, AllowShadows: true},
+		[]byte("[resolvers]\nresolver = 8.8.8.8\nresolvers_url = "+srv.URL+"/resolvers.txt\n"))
+
+	c := NewConfig()
+	c.Dir = t.TempDir()
+	if err := c.loadResolverSettings(cfg); err != nil {
+		t.Fatalf("Failed to load the resolver settings: %v", err)
+	}
+	if err := c.LoadResolverLists(context.Background()); err != nil {
+		t.Fatalf("Failed to load the resolver list: %v", err)
+	}
+	sort.Strings(c.Resolvers)
+	if want := []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}; !reflect.DeepEqual(c.Resolvers, want) {
+		t.Errorf("The resolver list was not merged: %v", c.Resolvers)
+	}
+
+	// The saved copy is used once the list cannot be fetched
+	atomic.StoreInt32(&fail, 1)
+	c.SetResolvers("8.8.8.8")
+	if err := c.LoadResolverLists(context.Background()); err != nil {
+		t.Fatalf("The saved copy of the resolver list was not used: %v", err)
+	}
+	if len(c.Resolvers) != 3 {
+		t.Errorf("The saved copy of the resolver list was not merged: %v", c.Resolvers)
+	}
+
+	c = NewConfig()
+	c.Dir = t.TempDir()
+	if err := c.SetResolverListURLs(srv.URL + "/resolvers.txt"); err != nil {
+		t.Fatalf("The resolver list URL was rejected: %v", err)
+	}
+	if err := c.LoadResolverLists(context.Background()); err == nil {
+		t.Errorf("No error was returned without a saved copy of the resolver list")
+	}
+	if err := c.SetResolverListURLs("ftp://example.com/resolvers.txt"); err == nil {
+		t.Errorf("The ftp URL was accepted as a resolver list")
+	}
+}
@@ -10,11 +10,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/aokimio/Amass/v3/net/http"
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/go-ini/ini"
 )
 
@@ -163,9 +164,46 @@ func (c *Config) loadResolverSettings(cfg *ini.File) error {
 	}
 
 	c.Resolvers = stringset.Deduplicate(sec.Key("resolver").ValueWithShadows())
-	if len(c.Resolvers) == 0 {
-		return errors.New("no resolver keys were found in the resolvers section")
+	if sec.HasKey("doh") {
+		if err := c.SetDoHResolvers(sec.Key("doh").ValueWithShadows()...); err != nil {
+			return err
+		}
+	}
+	if sec.HasKey("resolvers_url") {
+		if err := c.SetResolverListURLs(sec.Key("resolvers_url").ValueWithShadows()...); err != nil {
+			return err
+		}
+	}
+	if len(c.Resolvers) == 0 && len(c.DoHResolvers) == 0 && len(c.ResolverListURLs) == 0 {
+		return errors.New("no resolver, resolvers_url or doh keys were found in the resolvers section")
+	}
+
+	return nil
+}
+
+// SetDoHResolvers assigns the URLs of the DNS-over-HTTPS resolvers provided in the parameter to the
+// configuration. The resolvers are consulted in the order provided.
+func (c *Config) SetDoHResolvers(urls ...string) error {
+	var resolvers []string
+	seen := make(map[string]struct{})
+
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if _, found := seen[u]; found || u == "" {
+			continue
+		}
+		seen[u] = struct{}{}
+
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("the DNS-over-HTTPS resolver %s is not an https URL", u)
+		}
+		resolvers = append(resolvers, u)
 	}
 
+	c.Lock()
+	defer c.Unlock()
+
+	c.DoHResolvers = resolvers
 	return nil
 }
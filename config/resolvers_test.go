@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/go-ini/ini"
 )
 
 func TestConfigSetResolvers(t *testing.T) {
@@ -42,3 +44,23 @@ func TestConfigSetResolvers(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadDoHResolverSettings(t *testing.T) {
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true, AllowShadows: true},
+		[]byte("[resolvers]\ndoh = https://cloudflare-dns.com/dns-query\ndoh = https://dns.google/dns-query\n"))
+
+	c := NewConfig()
+	if err := c.loadResolverSettings(cfg); err != nil {
+		t.Fatalf("Failed to load the DoH resolvers: %v", err)
+	}
+	want := []string{"https://cloudflare-dns.com/dns-query", "https://dns.google/dns-query"}
+	if !reflect.DeepEqual(c.DoHResolvers, want) {
+		t.Errorf("DoHResolvers = %v, want %v", c.DoHResolvers, want)
+	}
+
+	for _, u := range []string{"http://dns.google/dns-query", "8.8.8.8"} {
+		if err := NewConfig().SetDoHResolvers(u); err == nil {
+			t.Errorf("%s was accepted as a DoH resolver", u)
+		}
+	}
+}
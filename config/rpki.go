@@ -0,0 +1,94 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+const (
+	// DefaultRPKIValidator is the RPKI validator API queried when none is configured.
+	DefaultRPKIValidator = "ripestat"
+	// RPKIFileValidator is the name of the validator that reads the VRPs from a local file.
+	RPKIFileValidator = "vrp_file"
+	// DefaultRPKIMaxChecks is the number of prefixes checked during an enumeration when no limit is configured.
+	DefaultRPKIMaxChecks = 100
+	// DefaultRPKIChecksPerSecond is the rate of the checks sent to an API when none is configured.
+	DefaultRPKIChecksPerSecond = 2
+)
+
+// RPKIConfig contains the settings for checking the origin of the netblocks discovered
+// against the validated ROA payloads (VRPs) of the RPKI.
+type RPKIConfig struct {
+	// The name of the validator API, or RPKIFileValidator for a local file
+	Validator string
+	// The base URL of the API, overriding the public address of the validator
+	URL string
+	// The JSON or CSV file of VRPs exported by a relying party, such as Routinator or rpki-client
+	VRPFile string
+	// The most prefixes checked during an enumeration
+	MaxChecks int
+	// The most checks sent to the API each second
+	Rate int
+}
+
+// String returns the validator and the limits placed on the checks.
+func (r *RPKIConfig) String() string {
+	if r.Validator == RPKIFileValidator {
+		return fmt.Sprintf("%s (%s) max_checks=%d", r.Validator, r.VRPFile, r.MaxChecks)
+	}
+
+	s := r.Validator
+	if r.URL != "" {
+		s += " (" + r.URL + ")"
+	}
+	return fmt.Sprintf("%s max_checks=%d rate=%d", s, r.MaxChecks, r.Rate)
+}
+
+func (c *Config) loadRPKISettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("rpki")
+	if err != nil {
+		return nil
+	}
+	if !sec.Key("enabled").MustBool(true) {
+		c.RPKI = nil
+		return nil
+	}
+
+	rc := &RPKIConfig{
+		URL:       strings.TrimRight(strings.TrimSpace(sec.Key("url").String()), "/"),
+		VRPFile:   strings.TrimSpace(sec.Key("vrp_file").String()),
+		MaxChecks: sec.Key("max_checks").MustInt(DefaultRPKIMaxChecks),
+		Rate:      sec.Key("rate").MustInt(DefaultRPKIChecksPerSecond),
+	}
+	// Providing a file of VRPs selects the local validator, unless another has been named
+	validator := DefaultRPKIValidator
+	if rc.VRPFile != "" {
+		validator = RPKIFileValidator
+	}
+	rc.Validator = strings.ToLower(strings.TrimSpace(sec.Key("validator").MustString(validator)))
+
+	if rc.Validator == RPKIFileValidator && rc.VRPFile == "" {
+		return fmt.Errorf("rpki: the %s validator requires the vrp_file option", RPKIFileValidator)
+	}
+	if rc.URL != "" {
+		if u, err := url.Parse(rc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("rpki: %q is not a valid http or https URL", rc.URL)
+		}
+	}
+	if rc.MaxChecks <= 0 {
+		return fmt.Errorf("rpki: max_checks must be greater than zero")
+	}
+	if rc.Rate <= 0 {
+		return fmt.Errorf("rpki: rate must be greater than zero")
+	}
+
+	c.RPKI = rc
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadRPKISettings(t *testing.T) {
+	c := NewConfig()
+	if c.RPKI != nil {
+		t.Errorf("RPKI validation was enabled by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[rpki]\n"))
+	if err := c.loadRPKISettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if rc := c.RPKI; rc == nil || rc.Validator != DefaultRPKIValidator || rc.URL != "" ||
+		rc.MaxChecks != DefaultRPKIMaxChecks || rc.Rate != DefaultRPKIChecksPerSecond {
+		t.Errorf("Failed to load the default RPKI settings: %+v", rc)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[rpki]
+		vrp_file = /var/lib/routinator/vrps.json
+		max_checks = 500
+		`),
+	)
+	if err := c.loadRPKISettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if rc := c.RPKI; rc.Validator != RPKIFileValidator || rc.VRPFile != "/var/lib/routinator/vrps.json" || rc.MaxChecks != 500 {
+		t.Errorf("The VRP file did not select the local validator: %+v", rc)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[rpki]\nenabled = false\n"))
+	if err := c.loadRPKISettings(cfg); err != nil || c.RPKI != nil {
+		t.Errorf("RPKI validation was not disabled: %v", err)
+	}
+
+	for _, bad := range []string{
+		"validator = vrp_file",
+		"url = stat.ripe.net",
+		"max_checks = 0",
+		"rate = -1",
+	} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[rpki]\n"+bad+"\n"))
+		if err := NewConfig().loadRPKISettings(cfg); err == nil {
+			t.Errorf("The invalid setting was accepted: %s", bad)
+		}
+	}
+}
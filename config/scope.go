@@ -7,13 +7,15 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/dns"
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/go-ini/ini"
+	"golang.org/x/net/publicsuffix"
 )
 
 // DomainRegex returns the Regexp object for the domain name identified by the parameter.
@@ -39,8 +41,8 @@ func (c *Config) AddDomain(domain string) {
 	c.Lock()
 	defer c.Unlock()
 
-	// Check that the domain string is not empty
-	d := strings.TrimSpace(domain)
+	// Check that the domain string is not empty, and keep internationalized names in punycode
+	d := dns.ToASCII(domain)
 	if d == "" {
 		return
 	}
@@ -80,6 +82,15 @@ func (c *Config) Domains() []string {
 	return c.domains
 }
 
+// ClearDomains removes all the root domain names from the configuration, so a new scope can be provided.
+func (c *Config) ClearDomains() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.domains = nil
+	c.regexps = nil
+}
+
 // IsDomainInScope returns true if the DNS name in the parameter ends with a domain in the config list.
 func (c *Config) IsDomainInScope(name string) bool {
 	var discovered bool
@@ -93,7 +104,8 @@ func (c *Config) IsDomainInScope(name string) bool {
 
 // WhichDomain returns the domain in the config list that the DNS name in the parameter ends with.
 func (c *Config) WhichDomain(name string) string {
-	n := strings.ToLower(strings.TrimSpace(name))
+	// Names written in Unicode match the root domains like their punycode form
+	n := dns.ToASCII(name)
 
 	for _, d := range c.Domains() {
 		if hasPathSuffix(n, d) {
@@ -103,6 +115,22 @@ func (c *Config) WhichDomain(name string) string {
 	return ""
 }
 
+// SeedDomains returns all the domains in the config list that the DNS name in the parameter ends
+// with, since a name can fall within more than one of the root domains provided. The domains are
+// returned in alphabetical order.
+func (c *Config) SeedDomains(name string) []string {
+	n := dns.ToASCII(name)
+
+	var seeds []string
+	for _, d := range c.Domains() {
+		if hasPathSuffix(n, d) {
+			seeds = append(seeds, d)
+		}
+	}
+	sort.Strings(seeds)
+	return seeds
+}
+
 func hasPathSuffix(path, suffix string) bool {
 	if strings.HasSuffix(path, suffix) {
 		plen := len(path)
@@ -170,6 +198,46 @@ func (c *Config) Blacklisted(name string) bool {
 	return false
 }
 
+// ScopeConflicts returns a description of each blacklist entry that contradicts the root domain
+// names in scope. The blacklist takes precedence, so an entry matching a root domain, or one of
+// its parents, removes that entire domain from the enumeration. Entries outside all the root
+// domains are also reported, since they have no effect.
+func (c *Config) ScopeConflicts() []string {
+	domains := c.Domains()
+	if len(domains) == 0 {
+		return nil
+	}
+
+	c.blacklistLock.Lock()
+	defer c.blacklistLock.Unlock()
+
+	var conflicts []string
+	for _, entry := range c.Blacklist {
+		bl := strings.Trim(strings.ToLower(strings.TrimSpace(entry)), ".")
+		if bl == "" {
+			continue
+		}
+
+		var inscope bool
+		for _, d := range domains {
+			d = strings.ToLower(d)
+
+			if hasPathSuffix(d, bl) {
+				inscope = true
+				conflicts = append(conflicts, fmt.Sprintf(
+					"the blacklist entry %s excludes the entire root domain %s", entry, d))
+			} else if hasPathSuffix(bl, d) {
+				inscope = true
+			}
+		}
+		if !inscope {
+			conflicts = append(conflicts, fmt.Sprintf(
+				"the blacklist entry %s is outside the root domains in scope and has no effect", entry))
+		}
+	}
+	return conflicts
+}
+
 func (c *Config) loadScopeSettings(cfg *ini.File) error {
 	scope, err := cfg.GetSection("scope")
 	if err != nil {
@@ -222,9 +290,119 @@ func (c *Config) loadScopeSettings(cfg *ini.File) error {
 		c.Blacklist = stringset.Deduplicate(blacklisted.Key("subdomain").ValueWithShadows())
 	}
 
+	// Load up the top-level domains that discovered names are filtered by
+	if tlds, err := cfg.GetSection("scope.tlds"); err == nil {
+		if tlds.HasKey("include") {
+			c.TLDFilter.Include = normalizeTLDs(tlds.Key("include").ValueWithShadows())
+		}
+		if tlds.HasKey("exclude") {
+			c.TLDFilter.Exclude = normalizeTLDs(tlds.Key("exclude").ValueWithShadows())
+		}
+	}
+
+	// Load up the public suffixes that the root domains are expanded across
+	if related, err := cfg.GetSection("scope.related_tlds"); err == nil {
+		c.RelatedTLDs.Enabled = true
+		if related.HasKey("enabled") {
+			if c.RelatedTLDs.Enabled, err = related.Key("enabled").Bool(); err != nil {
+				return fmt.Errorf("scope.related_tlds: the enabled option must be true or false")
+			}
+		}
+		if related.HasKey("tld") {
+			c.RelatedTLDs.TLDs = normalizeTLDs(related.Key("tld").ValueWithShadows())
+		}
+	}
+
 	return nil
 }
 
+// AllowedTLD returns true if the public suffix of the name in the parameter passes the TLD filter.
+// A name is dropped when the suffix matches an excluded TLD, even if it also matches an included TLD.
+func (c *Config) AllowedTLD(name string) bool {
+	if len(c.TLDFilter.Include) == 0 && len(c.TLDFilter.Exclude) == 0 {
+		return true
+	}
+
+	n := strings.Trim(strings.ToLower(strings.TrimSpace(name)), ".")
+	if n == "" {
+		return false
+	}
+	// Multi-label suffixes, such as co.uk, are matched by the complete public suffix or any parent of it
+	suffix, _ := publicsuffix.PublicSuffix(n)
+
+	for _, tld := range c.TLDFilter.Exclude {
+		if hasPathSuffix(suffix, tld) {
+			return false
+		}
+	}
+	if len(c.TLDFilter.Include) == 0 {
+		return true
+	}
+	for _, tld := range c.TLDFilter.Include {
+		if hasPathSuffix(suffix, tld) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRelatedTLDs are the public suffixes that the root domains are expanded across when the
+// related TLD expansion is enabled without a list.
+var DefaultRelatedTLDs = []string{
+	"com", "net", "org", "info", "biz", "io", "co", "us", "ca", "co.uk",
+	"de", "fr", "eu", "nl", "es", "it", "ch", "com.au", "jp", "in", "com.br",
+}
+
+// RelatedTLDVariants returns the domains formed by the second-level label of the root domain under
+// each of the related TLDs, such as example.net and example.org for example.com. The root domain
+// itself, the domains already in scope and the blacklisted domains are left out.
+func (c *Config) RelatedTLDVariants(domain string) []string {
+	d := strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	etld1, err := publicsuffix.EffectiveTLDPlusOne(d)
+	if err != nil {
+		return nil
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(etld1)
+	label := strings.TrimSuffix(etld1, "."+suffix)
+	if label == "" || strings.Contains(label, ".") {
+		return nil
+	}
+
+	tlds := c.RelatedTLDs.TLDs
+	if len(tlds) == 0 {
+		tlds = DefaultRelatedTLDs
+	}
+
+	var variants []string
+	for _, tld := range tlds {
+		if tld == suffix {
+			continue
+		}
+
+		v := label + "." + tld
+		if c.IsDomainInScope(v) || c.Blacklisted(v) || !c.AllowedTLD(v) {
+			continue
+		}
+		variants = append(variants, v)
+	}
+
+	variants = stringset.Deduplicate(variants)
+	sort.Strings(variants)
+	return variants
+}
+
+func normalizeTLDs(tlds []string) []string {
+	var results []string
+
+	for _, tld := range tlds {
+		if t := strings.Trim(strings.ToLower(strings.TrimSpace(tld)), "*."); t != "" {
+			results = append(results, t)
+		}
+	}
+	return stringset.Deduplicate(results)
+}
+
 type parseIPs []net.IP
 
 func (p *parseIPs) String() string {
@@ -4,7 +4,10 @@
 package config
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -61,6 +64,61 @@ func TestConfigAddDomains(t *testing.T) {
 	}
 }
 
+func TestConfigClearDomains(t *testing.T) {
+	c := new(Config)
+	c.AddDomains("owasp.org", "utica.edu")
+	c.ClearDomains()
+
+	if len(c.Domains()) != 0 || c.IsDomainInScope("www.owasp.org") || c.DomainRegex("owasp.org") != nil {
+		t.Errorf("Config.ClearDomains() failed to remove the root domain names")
+	}
+
+	c.AddDomain("appsecusa.org")
+	if !c.IsDomainInScope("www.appsecusa.org") || c.IsDomainInScope("www.utica.edu") {
+		t.Errorf("Config.ClearDomains() failed to allow a new scope")
+	}
+}
+
+func TestConfigSeedDomains(t *testing.T) {
+	c := new(Config)
+	c.AddDomains("owasp.org", "dev.owasp.org", "utica.edu")
+
+	tests := []struct {
+		name  string
+		seeds []string
+	}{
+		{"www.owasp.org", []string{"owasp.org"}},
+		{"API.Dev.OWASP.org", []string{"dev.owasp.org", "owasp.org"}},
+		{"utica.edu", []string{"utica.edu"}},
+		{"notowasp.org", nil},
+	}
+	for _, test := range tests {
+		if seeds := c.SeedDomains(test.name); !reflect.DeepEqual(seeds, test.seeds) {
+			t.Errorf("Config.SeedDomains(%s) returned %v, expected %v", test.name, seeds, test.seeds)
+		}
+	}
+}
+
+func TestConfigInternationalizedScope(t *testing.T) {
+	for _, domain := range []string{"münchen.de", "xn--mnchen-3ya.de"} {
+		c := new(Config)
+		c.AddDomain(domain)
+
+		if domains := c.Domains(); len(domains) != 1 || domains[0] != "xn--mnchen-3ya.de" {
+			t.Errorf("The domain %s was added as %v", domain, domains)
+		}
+		// The scope is matched whichever form the name is written in
+		for _, name := range []string{"www.xn--mnchen-3ya.de", "www.münchen.de", "WWW.MÜNCHEN.DE"} {
+			if d := c.WhichDomain(name); d != "xn--mnchen-3ya.de" {
+				t.Errorf("With the domain %s, %s was matched to %q", domain, name, d)
+			}
+		}
+		if c.IsDomainInScope("www.munchen.de") {
+			t.Errorf("With the domain %s, the name without the umlaut was in scope", domain)
+		}
+	}
+}
+
 func TestConfigParseIPsParseRange(t *testing.T) {
 	type args struct {
 		s string
@@ -200,6 +258,72 @@ func TestConfigBlacklistSubdomain(t *testing.T) {
 	}
 }
 
+func TestConfigScopeConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		domains   []string
+		blacklist []string
+		expected  int
+	}{
+		{"no blacklist", []string{"owasp.org"}, nil, 0},
+		{"no domains", nil, []string{"owasp.org"}, 0},
+		{"subdomain of the scope", []string{"owasp.org"}, []string{"www.owasp.org", "dev.api.owasp.org"}, 0},
+		{"same as the scope", []string{"owasp.org"}, []string{"OWASP.org"}, 1},
+		{"parent of the scope", []string{"api.owasp.org"}, []string{"owasp.org"}, 1},
+		{"label suffix is not a subdomain", []string{"owasp.org"}, []string{"myowasp.org"}, 1},
+		{"outside the scope", []string{"owasp.org"}, []string{"www.example.com"}, 1},
+		{"overlaps several domains", []string{"owasp.org", "api.owasp.org"}, []string{"owasp.org"}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewConfig()
+			c.AddDomains(test.domains...)
+			c.Blacklist = test.blacklist
+
+			if got := c.ScopeConflicts(); len(got) != test.expected {
+				t.Errorf("Expected %d conflicts, got %d: %v", test.expected, len(got), got)
+			}
+		})
+	}
+}
+
+func TestConfigAllowedTLD(t *testing.T) {
+	c := NewConfig()
+	if !c.AllowedTLD("www.example.cn") {
+		t.Errorf("Names were filtered without a TLD filter being configured")
+	}
+
+	c.TLDFilter.Include = normalizeTLDs([]string{"COM", "uk", "cn"})
+	c.TLDFilter.Exclude = normalizeTLDs([]string{"com.cn", ".co.uk"})
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"www.example.com", true},
+		{"WWW.EXAMPLE.COM.", true},
+		{"www.example.cn", true},
+		{"www.example.com.cn", false},
+		{"www.example.gov.uk", true},
+		{"www.example.co.uk", false},
+		{"www.example.org", false},
+		{"www.example.com.au", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := c.AllowedTLD(test.name); got != test.expected {
+			t.Errorf("AllowedTLD(%s) returned %t, expected %t", test.name, got, test.expected)
+		}
+	}
+
+	c.TLDFilter.Include = nil
+	if c.AllowedTLD("www.example.com.cn") || !c.AllowedTLD("www.example.org") {
+		t.Errorf("The exclude list did not work without an include list")
+	}
+}
+
 func TestLoadScopeSettings(t *testing.T) {
 	type args struct {
 		cfg []byte
@@ -364,6 +488,24 @@ func TestLoadScopeSettings(t *testing.T) {
 			assertionFunc: func(t *testing.T, c *Config) {
 			},
 		},
+		{
+			name: "success - valid TLDs in section scope.tlds",
+			args: args{cfg: []byte(`
+			[scope]
+			[scope.tlds]
+			include = .COM
+			exclude = cn
+			`)},
+			wantErr: false,
+			assertionFunc: func(t *testing.T, c *Config) {
+				if len(c.TLDFilter.Include) != 1 || c.TLDFilter.Include[0] != "com" {
+					t.Errorf("Config.loadScopeSettings() - failed to load the included TLDs")
+				}
+				if len(c.TLDFilter.Exclude) != 1 || c.TLDFilter.Exclude[0] != "cn" {
+					t.Errorf("Config.loadScopeSettings() - failed to load the excluded TLDs")
+				}
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -381,3 +523,93 @@ func TestLoadScopeSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestGetScopeListFromFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create the directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	write("shared/cloud.txt", "# Cloud hosted properties\ncloud.owasp.org\ninclude ../apps.txt\n")
+	write("apps.txt", "apps.owasp.org # owned by the web team\n\n")
+	root := write("scope.txt", "# Root domains\nowasp.org\ninclude shared/cloud.txt\n  # indented comment\napps.owasp.org\n")
+
+	list, err := GetScopeListFromFile(root)
+	if err != nil {
+		t.Fatalf("Failed to read the scope file: %v", err)
+	}
+	sort.Strings(list)
+	if expected := []string{"apps.owasp.org", "cloud.owasp.org", "owasp.org"}; !reflect.DeepEqual(list, expected) {
+		t.Errorf("Got: %v; Expected: %v", list, expected)
+	}
+
+	// Including a file from itself, directly or through another file, is an error
+	write("a.txt", "a.owasp.org\ninclude b.txt\n")
+	write("b.txt", "b.owasp.org\ninclude a.txt\n")
+	if _, err := GetScopeListFromFile(filepath.Join(dir, "a.txt")); err == nil {
+		t.Errorf("The include cycle was not reported")
+	}
+	if _, err := GetScopeListFromFile(write("self.txt", "include self.txt\n")); err == nil {
+		t.Errorf("The file that includes itself was not reported")
+	}
+	if _, err := GetScopeListFromFile(write("missing.txt", "include nothere.txt\n")); err == nil {
+		t.Errorf("The missing included file was not reported")
+	}
+}
+
+func TestConfigRelatedTLDVariants(t *testing.T) {
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true, AllowShadows: true}, []byte(`
+		[scope]
+		[scope.domains]
+		domain = example.com
+		domain = example.org
+		[scope.blacklisted]
+		subdomain = example.io
+		[scope.related_tlds]
+		tld = com
+		tld = .NET
+		tld = org
+		tld = io
+		tld = co.uk
+		`),
+	)
+
+	c := NewConfig()
+	if err := c.loadScopeSettings(cfg); err != nil {
+		t.Fatalf("Failed to load the scope settings: %v", err)
+	}
+	if !c.RelatedTLDs.Enabled {
+		t.Errorf("The related TLD expansion was not enabled by the section")
+	}
+
+	expected := []string{"example.co.uk", "example.net"}
+	for _, domain := range []string{"example.com", "www.example.com"} {
+		if got := c.RelatedTLDVariants(domain); !reflect.DeepEqual(got, expected) {
+			t.Errorf("RelatedTLDVariants(%s) = %v, expected %v", domain, got, expected)
+		}
+	}
+	if got := c.RelatedTLDVariants("com"); len(got) != 0 {
+		t.Errorf("Variants were returned for a public suffix: %v", got)
+	}
+
+	c = NewConfig()
+	if got := c.RelatedTLDVariants("example.co.uk"); len(got) != len(DefaultRelatedTLDs)-1 {
+		t.Errorf("Expected the variants across the default TLDs, got %v", got)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[scope]\n[scope.related_tlds]\nenabled = false\n"))
+	if err := c.loadScopeSettings(cfg); err != nil || c.RelatedTLDs.Enabled {
+		t.Errorf("The related TLD expansion was not disabled: %v", err)
+	}
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[scope]\n[scope.related_tlds]\nenabled = sometimes\n"))
+	if err := NewConfig().loadScopeSettings(cfg); err == nil {
+		t.Errorf("The invalid enabled option was accepted")
+	}
+}
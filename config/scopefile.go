@@ -0,0 +1,70 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/stringset"
+)
+
+// GetScopeListFromFile reads a file of root domain names or blacklisted subdomain names. Text
+// following a '#' is a comment, and a line of the form "include <path>" adds the names from
+// another file, where a relative path is resolved against the directory of the including file.
+func GetScopeListFromFile(path string) ([]string, error) {
+	list, err := readScopeFile(path, make(map[string]struct{}))
+	if err != nil {
+		return nil, err
+	}
+	return stringset.Deduplicate(list), nil
+}
+
+// readScopeFile returns the names in the file and those it includes. The including files
+// are tracked so a file that includes itself, directly or through others, is reported.
+func readScopeFile(path string, including map[string]struct{}) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("error locating the file %s: %v", path, err)
+	}
+	if _, found := including[abs]; found {
+		return nil, fmt.Errorf("the file %s includes itself", path)
+	}
+	including[abs] = struct{}{}
+	defer delete(including, abs)
+
+	lines, err := GetListFromFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range lines {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "include" {
+			inc := fields[1]
+			if !filepath.IsAbs(inc) {
+				inc = filepath.Join(filepath.Dir(abs), inc)
+			}
+
+			list, err := readScopeFile(inc, including)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			names = append(names, list...)
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
@@ -14,7 +14,19 @@ import (
 
 // AcquireScripts returns all the default and user provided scripts for data sources.
 func (c *Config) AcquireScripts() ([]string, error) {
-	scripts, err := resources.GetDefaultScripts()
+	files, err := c.AcquireScriptFiles()
+
+	var scripts []string
+	for _, f := range files {
+		scripts = append(scripts, f.Content)
+	}
+	return scripts, err
+}
+
+// AcquireScriptFiles returns all the default and user provided scripts for data sources,
+// along with the paths they were read from.
+func (c *Config) AcquireScriptFiles() ([]resources.ScriptFile, error) {
+	scripts, err := resources.GetDefaultScriptFiles()
 	if err != nil {
 		return scripts, err
 	}
@@ -49,7 +61,7 @@ func (c *Config) AcquireScripts() ([]string, error) {
 				return err
 			}
 
-			scripts = append(scripts, string(data))
+			scripts = append(scripts, resources.ScriptFile{Path: path, Content: string(data)})
 			return nil
 		})
 	}
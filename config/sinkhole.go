@@ -0,0 +1,117 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/go-ini/ini"
+)
+
+const (
+	// SinkholeClass marks the addresses operated by a sinkhole, which take over malicious or seized domains.
+	SinkholeClass = "sinkhole"
+	// ParkedClass marks the addresses of domain parking services, which serve placeholder pages.
+	ParkedClass = "parked"
+)
+
+// SinkholeConfig contains the address ranges of the sinkholes and parking services that
+// the discovered addresses are classified against.
+type SinkholeConfig struct {
+	Sinkholes []string
+	Parked    []string
+	// Leave the names that only resolve to addresses within the ranges out of the output
+	Exclude bool
+}
+
+// String returns the number of ranges of each class and whether the names are excluded.
+func (s *SinkholeConfig) String() string {
+	return fmt.Sprintf("sinkholes=%d parked=%d exclude=%t", len(s.Sinkholes), len(s.Parked), s.Exclude)
+}
+
+// Classify returns the class of the range containing the address, or an empty string when the
+// address is not within any of them. When ranges of both classes contain the address, the
+// most specific range determines the class.
+func (s *SinkholeConfig) Classify(addr string) string {
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if s == nil || ip == nil {
+		return ""
+	}
+
+	sinkhole := amassnet.LongestPrefixMatch(ip, s.Sinkholes)
+	parked := amassnet.LongestPrefixMatch(ip, s.Parked)
+	if sinkhole == "" && parked == "" {
+		return ""
+	}
+	if sinkhole == "" || (parked != "" && prefixLength(parked) > prefixLength(sinkhole)) {
+		return ParkedClass
+	}
+	return SinkholeClass
+}
+
+func prefixLength(cidr string) int {
+	if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+		ones, _ := ipnet.Mask.Size()
+		return ones
+	}
+	return -1
+}
+
+func (c *Config) loadSinkholeSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("sinkholes")
+	if err != nil {
+		return nil
+	}
+	if !sec.Key("enabled").MustBool(true) {
+		c.Sinkholes = nil
+		return nil
+	}
+
+	sc := &SinkholeConfig{Exclude: sec.Key("exclude").MustBool(false)}
+	if sc.Sinkholes, err = parseAddressRanges(sec.Key("sinkhole").ValueWithShadows()); err != nil {
+		return fmt.Errorf("sinkholes: %v", err)
+	}
+	if sc.Parked, err = parseAddressRanges(sec.Key("parked").ValueWithShadows()); err != nil {
+		return fmt.Errorf("sinkholes: %v", err)
+	}
+	if len(sc.Sinkholes) == 0 && len(sc.Parked) == 0 {
+		return fmt.Errorf("sinkholes: no sinkhole or parked address ranges were provided")
+	}
+
+	c.Sinkholes = sc
+	return nil
+}
+
+// parseAddressRanges returns the canonical form of the CIDRs and addresses provided, separated
+// by commas, where a single address is treated as a range holding only that address.
+func parseAddressRanges(values []string) ([]string, error) {
+	var ranges []string
+
+	for _, val := range values {
+		for _, r := range strings.Split(val, ",") {
+			if r = strings.TrimSpace(r); r == "" {
+				continue
+			}
+
+			if ip := net.ParseIP(r); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					bits = 32
+				}
+				r = fmt.Sprintf("%s/%d", ip.String(), bits)
+			}
+
+			cidr := amassnet.CanonicalCIDR(r)
+			if cidr == "" {
+				return nil, fmt.Errorf("%q is not a valid address or CIDR", r)
+			}
+			ranges = append(ranges, cidr)
+		}
+	}
+	return ranges, nil
+}
@@ -0,0 +1,86 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadSinkholeSettings(t *testing.T) {
+	c := NewConfig()
+	if c.Sinkholes != nil {
+		t.Errorf("The sinkhole classification was enabled by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true, AllowShadows: true}, []byte(`
+		[sinkholes]
+		exclude = true
+		sinkhole = 192.0.2.0/24, 2001:DB8::/32
+		sinkhole = 198.51.100.7
+		parked = 203.0.113.0/24
+		`),
+	)
+	if err := c.loadSinkholeSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	sc := c.Sinkholes
+	if sc == nil || !sc.Exclude {
+		t.Fatalf("Failed to load the sinkhole settings: %+v", sc)
+	}
+	if fmt.Sprint(sc.Sinkholes) != "[192.0.2.0/24 2001:db8::/32 198.51.100.7/32]" {
+		t.Errorf("Unexpected sinkhole ranges: %v", sc.Sinkholes)
+	}
+	if fmt.Sprint(sc.Parked) != "[203.0.113.0/24]" {
+		t.Errorf("Unexpected parked ranges: %v", sc.Parked)
+	}
+
+	for _, bad := range []string{
+		"[sinkholes]\n",
+		"[sinkholes]\nsinkhole = 192.0.2.0/33\n",
+		"[sinkholes]\nparked = parking.example.com\n",
+	} {
+		cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true, AllowShadows: true}, []byte(bad))
+		if err := NewConfig().loadSinkholeSettings(cfg); err == nil {
+			t.Errorf("The invalid settings were accepted: %q", bad)
+		}
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[sinkholes]\nenabled = false\nsinkhole = 192.0.2.0/24\n"))
+	if err := c.loadSinkholeSettings(cfg); err != nil || c.Sinkholes != nil {
+		t.Errorf("The disabled section was not honored: %v", err)
+	}
+}
+
+func TestSinkholeClassify(t *testing.T) {
+	sc := &SinkholeConfig{
+		Sinkholes: []string{"192.0.2.0/24", "2001:db8::/32"},
+		Parked:    []string{"192.0.2.128/25", "203.0.113.0/24"},
+	}
+
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"192.0.2.1", SinkholeClass},
+		{"192.0.2.200", ParkedClass},
+		{"203.0.113.9", ParkedClass},
+		{"2001:db8::1", SinkholeClass},
+		{"198.51.100.1", ""},
+		{"invalid", ""},
+	}
+	for _, test := range tests {
+		if class := sc.Classify(test.addr); class != test.expected {
+			t.Errorf("%s: got class %q, expected %q", test.addr, class, test.expected)
+		}
+	}
+
+	var none *SinkholeConfig
+	if class := none.Classify("192.0.2.1"); class != "" {
+		t.Errorf("A nil configuration classified the address as %q", class)
+	}
+}
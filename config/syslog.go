@@ -0,0 +1,133 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// DefaultSyslogAppName is the APP-NAME of the syslog messages when none is configured.
+const DefaultSyslogAppName = "amass"
+
+// The syslog facility codes selected by name with the 'facility' option.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// The syslog severity codes selected by name with the 'severity' option.
+var syslogSeverities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// SyslogConfig contains the values required for sending the findings as RFC 5424 messages
+// to the local syslog daemon or to a remote collector.
+type SyslogConfig struct {
+	// One of unix, udp, tcp or tls
+	Network string
+	// The path of the local socket, or the host:port of the remote collector. An
+	// empty address selects the socket of the local syslog daemon
+	Address  string
+	Facility int
+	Severity int
+	AppName  string
+	// The PEM file of the certificate authorities trusted for the tls network
+	CAFile string
+}
+
+// String returns the location that the messages are sent to.
+func (s *SyslogConfig) String() string {
+	if s.Network == "unix" && s.Address == "" {
+		return "the local syslog daemon"
+	}
+	return s.Network + "://" + s.Address
+}
+
+func (c *Config) loadSyslogSettings(cfg *ini.File) error {
+	sec, err := cfg.GetSection("syslog")
+	if err != nil {
+		return nil
+	}
+
+	sc := &SyslogConfig{
+		Network: "unix",
+		AppName: strings.TrimSpace(sec.Key("app_name").MustString(DefaultSyslogAppName)),
+		CAFile:  strings.TrimSpace(sec.Key("ca_file").String()),
+	}
+
+	if addr := strings.TrimSpace(sec.Key("address").String()); addr != "" {
+		parts := strings.SplitN(addr, "://", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("syslog: address must be in the form udp://host:port, tcp://host:port or tls://host:port")
+		}
+
+		sc.Network, sc.Address = strings.ToLower(parts[0]), parts[1]
+		switch sc.Network {
+		case "udp", "tcp", "tls":
+			if _, _, err := net.SplitHostPort(sc.Address); err != nil {
+				return fmt.Errorf("syslog: %q is not a valid host:port", sc.Address)
+			}
+		case "unix":
+			if sc.Address == "" {
+				return fmt.Errorf("syslog: the unix address requires the path of the socket")
+			}
+		default:
+			return fmt.Errorf("syslog: %q is not a supported network", sc.Network)
+		}
+	}
+
+	facility := strings.ToLower(strings.TrimSpace(sec.Key("facility").MustString("user")))
+	code, found := syslogFacilities[facility]
+	if !found {
+		return fmt.Errorf("syslog: %q is not a valid facility", facility)
+	}
+	sc.Facility = code
+
+	severity := strings.ToLower(strings.TrimSpace(sec.Key("severity").MustString("info")))
+	if code, found = syslogSeverities[severity]; !found {
+		return fmt.Errorf("syslog: %q is not a valid severity", severity)
+	}
+	sc.Severity = code
+
+	// The APP-NAME field is limited to 48 printable characters without spaces
+	if sc.AppName == "" || len(sc.AppName) > 48 || strings.ContainsAny(sc.AppName, " \t") {
+		return fmt.Errorf("syslog: %q is not a valid app_name", sc.AppName)
+	}
+	if sc.CAFile != "" && sc.Network != "tls" {
+		return fmt.Errorf("syslog: ca_file is only used with the tls network")
+	}
+
+	c.Syslog = sc
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestLoadSyslogSettings(t *testing.T) {
+	c := NewConfig()
+	if c.Syslog != nil {
+		t.Errorf("Syslog was configured by default")
+	}
+
+	cfg, _ := ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[syslog]\n"))
+	if err := c.loadSyslogSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if sc := c.Syslog; sc == nil || sc.Network != "unix" || sc.Address != "" ||
+		sc.Facility != 1 || sc.Severity != 6 || sc.AppName != DefaultSyslogAppName {
+		t.Errorf("Failed to load the default syslog settings: %+v", sc)
+	}
+
+	cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte(`
+		[syslog]
+		address = tls://logs.example.com:6514
+		facility = local3
+		severity = notice
+		app_name = recon
+		ca_file = /etc/ssl/collector.pem
+		`),
+	)
+	if err := c.loadSyslogSettings(cfg); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if sc := c.Syslog; sc.Network != "tls" || sc.Address != "logs.example.com:6514" || sc.Facility != 19 ||
+		sc.Severity != 5 || sc.AppName != "recon" || sc.CAFile != "/etc/ssl/collector.pem" {
+		t.Errorf("Failed to load the syslog settings: %+v", sc)
+	}
+
+	for _, bad := range []string{
+		"address = logs.example.com:514",
+		"address = udp://logs.example.com",
+		"address = http://logs.example.com:514",
+		"facility = local9",
+		"severity = verbose",
+		"app_name = amass scan",
+		"address = udp://logs.example.com:514\nca_file = ca.pem",
+	} {
+		cfg, _ = ini.LoadSources(ini.LoadOptions{Insensitive: true}, []byte("[syslog]\n"+bad))
+		if err := NewConfig().loadSyslogSettings(cfg); err == nil {
+			t.Errorf("The invalid setting was accepted: %s", bad)
+		}
+	}
+}
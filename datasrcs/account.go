@@ -0,0 +1,80 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+type accountKey struct{}
+
+// sourceAccount is one of the sets of credentials configured for a data source that queries
+// each of them, such as the Umbrella organizations managed by the same user. Each account
+// is labeled with the name of its credentials section and has a rate limit of its own.
+type sourceAccount struct {
+	label   string
+	creds   *config.Credentials
+	limiter *rateJitter
+}
+
+// sourceAccounts returns the accounts of the data source, each limited to persec requests per
+// second. Nil is returned when fewer than two sets of credentials have a key, since a single
+// account is queried through the rate limit of the data source and its results are not labeled.
+func sourceAccounts(sys systems.System, srv service.Service, persec int) []*sourceAccount {
+	dsc := sys.Config().GetDataSourceConfig(srv.String())
+	if dsc == nil || persec <= 0 {
+		return nil
+	}
+
+	var accounts []*sourceAccount
+	for _, creds := range dsc.AllCredentials() {
+		if creds.Key == "" {
+			continue
+		}
+
+		accounts = append(accounts, &sourceAccount{
+			label: creds.Name,
+			creds: creds,
+			limiter: &rateJitter{
+				interval: time.Second / time.Duration(persec),
+				band:     sys.Config().RateJitter(srv.String()),
+				rng:      sys.Config().NewRand(),
+			},
+		})
+	}
+	if len(accounts) < 2 {
+		return nil
+	}
+	return accounts
+}
+
+// withAccount returns a copy of the parent context that performs the queries through the account,
+// which labels the names reported and replaces the rate limit of the data source with its own.
+// The parent is returned when the account is nil.
+func withAccount(parent context.Context, a *sourceAccount) context.Context {
+	if a == nil {
+		return parent
+	}
+	return context.WithValue(parent, accountKey{}, a)
+}
+
+// accountFrom returns the account carried by the context, or nil when there is none.
+func accountFrom(ctx context.Context) *sourceAccount {
+	a, _ := ctx.Value(accountKey{}).(*sourceAccount)
+	return a
+}
+
+// accountLabel returns the label of the account carried by the context, or an empty string.
+func accountLabel(ctx context.Context) string {
+	if a := accountFrom(ctx); a != nil {
+		return a.label
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+const (
+	// The file in the output directory that keeps the learned rates between runs
+	learnedRatesFile = "rate_limits.json"
+	// The most requests per second reached when the data source does not set max_rate
+	adaptiveDefaultMaxRate = 10
+	// Successful responses received at the current rate before the rate is raised
+	adaptiveRateWindow = 25
+)
+
+// adaptiveRate raises the rate limit of a data source while its responses succeed, and
+// settles just below the rate at which the source responded with 429 Too Many Requests.
+type adaptiveRate struct {
+	sync.Mutex
+	sys       systems.System
+	srv       service.Service
+	rate      int
+	max       int
+	ceiling   int
+	successes int
+	// Responses still expected from the requests sent before the rate was lowered
+	settling int
+}
+
+var adaptiveRates = struct {
+	sync.Mutex
+	m map[service.Service]*adaptiveRate
+}{m: make(map[service.Service]*adaptiveRate)}
+
+var learnedRatesLock sync.Mutex
+
+// setRateLimit sets the number of requests per second sent to the data source. When the
+// adaptive rate limit is enabled for the source, the rate learned during previous runs is
+// used in place of the rate provided, and the responses continue to adjust it.
+func setRateLimit(sys systems.System, srv service.Service, persec int) {
+	dsc := sys.Config().GetDataSourceConfig(srv.String())
+	if dsc == nil || !dsc.AdaptiveRate {
+		applyRateLimit(sys, srv, persec)
+		return
+	}
+
+	max := dsc.MaxRate
+	if max == 0 {
+		max = adaptiveDefaultMaxRate
+	}
+	rate := persec
+	if learned := loadLearnedRates(sys.Config())[srv.String()]; learned > 0 {
+		rate = learned
+	}
+	if rate > max {
+		rate = max
+	}
+	if rate < 1 {
+		rate = 1
+	}
+
+	ar := &adaptiveRate{
+		sys:  sys,
+		srv:  srv,
+		rate: rate,
+		max:  max,
+	}
+	applyRateLimit(sys, srv, rate)
+
+	adaptiveRates.Lock()
+	adaptiveRates.m[srv] = ar
+	adaptiveRates.Unlock()
+}
+
+// adaptiveRateFor returns the adaptive rate limit of the data source, or nil when it is not enabled.
+func adaptiveRateFor(srv service.Service) *adaptiveRate {
+	adaptiveRates.Lock()
+	defer adaptiveRates.Unlock()
+
+	return adaptiveRates.m[srv]
+}
+
+// observe adjusts the rate using the status code of a response from the data source.
+func (ar *adaptiveRate) observe(status int) {
+	ar.Lock()
+	defer ar.Unlock()
+
+	// The requests in flight when the rate was lowered were sent at the old rate, so their
+	// responses do not lower it again
+	settling := ar.settling > 0
+	if settling {
+		ar.settling--
+	}
+
+	if status == 429 {
+		ar.successes = 0
+		if settling || ar.ceiling == ar.rate {
+			return
+		}
+
+		ar.ceiling = ar.rate
+		ar.settling = adaptiveRateWindow
+		if ar.rate > 1 {
+			ar.rate--
+		}
+		ar.update()
+		ar.sys.Config().Log.Printf("%s: Learned a rate limit ceiling of %d requests per second, settling at %d",
+			ar.srv.String(), ar.ceiling, ar.rate)
+		return
+	}
+	if status < 200 || status >= 300 {
+		return
+	}
+
+	ar.successes++
+	if ar.successes < adaptiveRateWindow {
+		return
+	}
+	ar.successes = 0
+	// The rate is not raised again once the ceiling is known for this run
+	if next := ar.rate + 1; next <= ar.max && (ar.ceiling == 0 || next < ar.ceiling) {
+		ar.rate = next
+		ar.update()
+	}
+}
+
+func (ar *adaptiveRate) update() {
+	applyRateLimit(ar.sys, ar.srv, ar.rate)
+	saveLearnedRate(ar.sys.Config(), ar.srv.String(), ar.rate)
+}
+
+func learnedRatesPath(cfg *config.Config) string {
+	return filepath.Join(config.OutputDirectory(cfg.Dir), learnedRatesFile)
+}
+
+// loadLearnedRates returns the requests per second learned for each data source during previous runs.
+func loadLearnedRates(cfg *config.Config) map[string]int {
+	learnedRatesLock.Lock()
+	defer learnedRatesLock.Unlock()
+
+	return readLearnedRates(learnedRatesPath(cfg))
+}
+
+func readLearnedRates(path string) map[string]int {
+	rates := make(map[string]int)
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &rates)
+	}
+	return rates
+}
+
+func saveLearnedRate(cfg *config.Config, name string, rate int) {
+	learnedRatesLock.Lock()
+	defer learnedRatesLock.Unlock()
+
+	path := learnedRatesPath(cfg)
+	rates := readLearnedRates(path)
+	rates[name] = rate
+
+	data, err := json.MarshalIndent(rates, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		cfg.Log.Printf("Failed to save the learned rate limits: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"io/ioutil"
+	"log"
+	nethttp "net/http"
+	"strings"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/net/http"
+)
+
+func TestAdaptiveRate(t *testing.T) {
+	sys := testSystem()
+	sys.Config().Dir = t.TempDir()
+	dsc := sys.Config().GetDataSourceConfig("Umbrella")
+	dsc.AdaptiveRate = true
+	dsc.MaxRate = 4
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+
+	setRateLimit(sys, u, 2)
+	ar := adaptiveRateFor(u)
+	if ar == nil || ar.rate != 2 {
+		t.Fatalf("The adaptive rate limit was not set up: %+v", ar)
+	}
+
+	successes := func(num int) {
+		for i := 0; i < num; i++ {
+			ar.observe(200)
+		}
+	}
+	successes(adaptiveRateWindow)
+	if ar.rate != 3 {
+		t.Errorf("The rate was not raised after %d successful responses: %d", adaptiveRateWindow, ar.rate)
+	}
+	successes(3 * adaptiveRateWindow)
+	if ar.rate != 4 {
+		t.Errorf("The rate was raised beyond the maximum: %d", ar.rate)
+	}
+
+	ar.observe(429)
+	if ar.ceiling != 4 || ar.rate != 3 {
+		t.Errorf("The rate did not settle below the ceiling: rate %d, ceiling %d", ar.rate, ar.ceiling)
+	}
+	successes(3 * adaptiveRateWindow)
+	if ar.rate != 3 {
+		t.Errorf("The rate reached the ceiling again: %d", ar.rate)
+	}
+
+	// The next run starts at the learned rate
+	if rates := loadLearnedRates(sys.Config()); rates["Umbrella"] != 3 {
+		t.Errorf("The learned rate was not saved: %v", rates)
+	}
+	setRateLimit(sys, u, 2)
+	if ar := adaptiveRateFor(u); ar.rate != 3 || ar.ceiling != 0 {
+		t.Errorf("The learned rate was not used: %+v", ar)
+	}
+}
+
+func TestAdaptiveRateBurst(t *testing.T) {
+	sys := testSystem()
+	sys.Config().Dir = t.TempDir()
+	logs := new(strings.Builder)
+	sys.Config().Log = log.New(logs, "", 0)
+	dsc := sys.Config().GetDataSourceConfig("Umbrella")
+	dsc.AdaptiveRate = true
+	dsc.MaxRate = 8
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+
+	setRateLimit(sys, u, 5)
+	ar := adaptiveRateFor(u)
+	// The requests in flight at once are all refused
+	for i := 0; i < 10; i++ {
+		ar.observe(429)
+	}
+	if ar.ceiling != 5 || ar.rate != 4 {
+		t.Errorf("The burst lowered the rate more than once: rate %d, ceiling %d", ar.rate, ar.ceiling)
+	}
+	if n := strings.Count(logs.String(), "Learned a rate limit ceiling"); n != 1 {
+		t.Errorf("The ceiling was logged %d times for a single burst", n)
+	}
+
+	// Once the responses to the earlier requests have arrived, a refusal lowers the rate again
+	for i := 0; i < adaptiveRateWindow; i++ {
+		ar.observe(200)
+	}
+	ar.observe(429)
+	if ar.ceiling != 4 || ar.rate != 3 {
+		t.Errorf("The rate was not lowered by the refusal after the burst: rate %d, ceiling %d", ar.rate, ar.ceiling)
+	}
+	if rates := loadLearnedRates(sys.Config()); rates["Umbrella"] != 3 {
+		t.Errorf("The lowered rate was not saved: %v", rates)
+	}
+}
+
+func TestAdaptiveRateObservesResponses(t *testing.T) {
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		return &nethttp.Response{
+			StatusCode: 429,
+			Status:     "429 Too Many Requests",
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}
+	})
+	defer func() { http.DefaultClient.Transport = orig }()
+
+	sys := testSystem()
+	sys.Config().Dir = t.TempDir()
+	sys.Config().GetDataSourceConfig("Umbrella").AdaptiveRate = true
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+
+	setRateLimit(sys, u, 2)
+	if _, err := http.RequestWebPage(sourceContext(sys, u), "https://investigate.api.umbrella.com/", nil, nil, nil); err == nil {
+		t.Errorf("The 429 response did not return an error")
+	}
+	if ar := adaptiveRateFor(u); ar.ceiling != 2 || ar.rate != 1 {
+		t.Errorf("The 429 response was not provided to the adaptive rate limit: %+v", ar)
+	}
+
+	// Sources without the option keep the rate provided
+	c := NewCloudflare(sys)
+	defer func() { _ = c.Stop() }()
+	setRateLimit(sys, c, 2)
+	if adaptiveRateFor(c) != nil {
+		t.Errorf("The adaptive rate limit was enabled without the option")
+	}
+}
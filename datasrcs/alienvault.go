@@ -17,11 +17,15 @@ import (
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 )
 
+// The address of the OTX API, unless base_url is set for the source
+const alienvaultBaseURL = "https://otx.alienvault.com"
+
 // AlienVault is the Service that handles access to the AlienVault data source.
 type AlienVault struct {
 	service.BaseService
@@ -56,7 +60,7 @@ func (a *AlienVault) OnStart() error {
 		a.sys.Config().Log.Printf("%s: API key data was not provided", a.String())
 	}
 
-	a.SetRateLimit(1)
+	setRateLimit(a.sys, a, 1)
 	return nil
 }
 
@@ -66,13 +70,14 @@ func (a *AlienVault) requests() {
 		case <-a.Done():
 			return
 		case in := <-a.Input():
+			ctx := sourceContext(a.sys, a)
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				a.CheckRateLimit()
-				a.dnsRequest(context.TODO(), req)
+				checkRateLimit(ctx, a)
+				a.dnsRequest(ctx, req)
 			case *requests.WhoisRequest:
-				a.CheckRateLimit()
-				a.whoisRequest(context.TODO(), req)
+				checkRateLimit(ctx, a)
+				a.whoisRequest(ctx, req)
 			}
 		}
 	}
@@ -86,7 +91,7 @@ func (a *AlienVault) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 	a.sys.Config().Log.Printf("Querying %s for %s subdomains", a.String(), req.Domain)
 	a.executeDNSQuery(ctx, req)
 
-	a.CheckRateLimit()
+	checkRateLimit(ctx, a)
 	a.executeURLQuery(ctx, req)
 }
 
@@ -146,12 +151,13 @@ func (a *AlienVault) executeDNSQuery(ctx context.Context, req *requests.DNSReque
 	}
 
 	for _, ip := range ips.Slice() {
-		a.Output() <- &requests.AddrRequest{
+		stats.RecordResult(ctx)
+		sendOutput(ctx, a.sys, a, &requests.AddrRequest{
 			Address: ip,
 			Domain:  req.Domain,
 			Tag:     a.SourceType,
 			Source:  a.String(),
-		}
+		})
 	}
 }
 
@@ -206,7 +212,7 @@ func (a *AlienVault) executeURLQuery(ctx context.Context, req *requests.DNSReque
 		pages := int(math.Ceil(float64(m.FullSize) / float64(m.Limit)))
 
 		for cur := m.PageNum + 1; cur <= pages; cur++ {
-			a.CheckRateLimit()
+			checkRateLimit(ctx, a)
 			pageURL := u + "?page=" + strconv.Itoa(cur)
 			page, err = http.RequestWebPage(ctx, pageURL, nil, headers, nil)
 			if err != nil {
@@ -231,12 +237,13 @@ func (a *AlienVault) executeURLQuery(ctx context.Context, req *requests.DNSReque
 	}
 
 	for _, ip := range ips.Slice() {
-		a.Output() <- &requests.AddrRequest{
+		stats.RecordResult(ctx)
+		sendOutput(ctx, a.sys, a, &requests.AddrRequest{
 			Address: ip,
 			Domain:  req.Domain,
 			Tag:     a.SourceType,
 			Source:  a.String(),
-		}
+		})
 	}
 }
 
@@ -255,7 +262,7 @@ func extractNamesIPs(urls []avURL, names *stringset.Set, ips *stringset.Set, re
 
 func (a *AlienVault) executeWhoisQuery(ctx context.Context, req *requests.WhoisRequest) {
 	emails := a.queryWhoisForEmails(ctx, req)
-	a.CheckRateLimit()
+	checkRateLimit(ctx, a)
 
 	newDomains := stringset.New()
 	defer newDomains.Close()
@@ -282,7 +289,7 @@ func (a *AlienVault) executeWhoisQuery(ctx context.Context, req *requests.WhoisR
 				newDomains.Insert(d.Domain)
 			}
 		}
-		a.CheckRateLimit()
+		checkRateLimit(ctx, a)
 	}
 
 	if newDomains.Len() == 0 {
@@ -290,12 +297,13 @@ func (a *AlienVault) executeWhoisQuery(ctx context.Context, req *requests.WhoisR
 		return
 	}
 
-	a.Output() <- &requests.WhoisRequest{
+	stats.RecordResult(ctx)
+	sendOutput(ctx, a.sys, a, &requests.WhoisRequest{
 		Domain:     req.Domain,
 		NewDomains: newDomains.Slice(),
 		Tag:        a.SourceType,
 		Source:     a.String(),
-	}
+	})
 }
 
 func (a *AlienVault) queryWhoisForEmails(ctx context.Context, req *requests.WhoisRequest) []string {
@@ -353,17 +361,22 @@ func (a *AlienVault) getHeaders() map[string]string {
 	return headers
 }
 
+// baseURL returns the address of the OTX API, or the mirror selected by the base_url option.
+func (a *AlienVault) baseURL() string {
+	return a.sys.Config().BaseURL(a.String(), alienvaultBaseURL)
+}
+
 func (a *AlienVault) getWhoisURL(domain string) string {
 	// https://otx.alienvault.com/otxapi/indicator/domain/whois/google.com
-	return "https://otx.alienvault.com/otxapi/indicator/domain/whois/" + domain
+	return a.baseURL() + "/otxapi/indicator/domain/whois/" + domain
 }
 
 func (a *AlienVault) getReverseWhoisURL(email string) string {
-	return "https://otx.alienvault.com/otxapi/indicator/email/whois/" + email
+	return a.baseURL() + "/otxapi/indicator/email/whois/" + email
 }
 
 func (a *AlienVault) getURL(domain string) string {
-	format := "https://otx.alienvault.com/api/v1/indicators/domain/%s/"
+	format := a.baseURL() + "/api/v1/indicators/domain/%s/"
 
 	return fmt.Sprintf(format, domain)
 }
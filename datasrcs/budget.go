@@ -0,0 +1,54 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// The maximum number of chained queries a data source can perform while handling a single request
+const defaultQueryBudget = 50
+
+// errBudgetExhausted is returned once a request has used all the queries in its budget.
+var errBudgetExhausted = errors.New("the query budget for the request has been exhausted")
+
+type budgetKey struct{}
+
+type queryBudget struct {
+	remaining int64
+}
+
+// withQueryBudget returns a copy of the parent context that limits the number of chained
+// queries performed for the request, which protects against malformed API responses
+// driving the recursive and chained fetches of a data source.
+func withQueryBudget(parent context.Context, num int) context.Context {
+	return context.WithValue(parent, budgetKey{}, &queryBudget{remaining: int64(num)})
+}
+
+// spendBudget consumes one query from the budget carried by the context. Contexts
+// without a budget are not limited, and no queries remain once the context is done.
+// The query is then held back until the request delay of the data source has passed.
+func spendBudget(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b, ok := ctx.Value(budgetKey{}).(*queryBudget); ok && atomic.AddInt64(&b.remaining, -1) < 0 {
+		return errBudgetExhausted
+	}
+	return waitRequestDelay(ctx)
+}
+
+// budgetExhausted returns true when the budget carried by the context has no queries remaining,
+// or the context is done, so recursive and chained fetches stop at their next check.
+func budgetExhausted(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+
+	b, ok := ctx.Value(budgetKey{}).(*queryBudget)
+	return ok && atomic.LoadInt64(&b.remaining) <= 0
+}
@@ -0,0 +1,162 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+type roundTripFunc func(*nethttp.Request) *nethttp.Response
+
+func (f roundTripFunc) RoundTrip(req *nethttp.Request) (*nethttp.Response, error) {
+	return f(req), nil
+}
+
+// serveResponses replaces the HTTP transport with one that answers every request using the
+// handler, and returns a pointer to the number of requests made.
+func serveResponses(t *testing.T, handler func(path string) string) *int64 {
+	var count int64
+
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		atomic.AddInt64(&count, 1)
+		return &nethttp.Response{
+			StatusCode: 200,
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(handler(req.URL.Path))),
+			Request:    req,
+		}
+	})
+	t.Cleanup(func() { http.DefaultClient.Transport = orig })
+	return &count
+}
+
+func testSystem() systems.System {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	return &systems.SimpleSystem{
+		Cfg:      cfg,
+		ASNCache: requests.NewASNCache(),
+	}
+}
+
+func TestQueryBudget(t *testing.T) {
+	ctx := withQueryBudget(context.Background(), 2)
+
+	for i := 0; i < 2; i++ {
+		if err := spendBudget(ctx); err != nil {
+			t.Errorf("Query %d was rejected before the budget was exhausted", i+1)
+		}
+	}
+	if !budgetExhausted(ctx) {
+		t.Errorf("The budget was not reported as exhausted")
+	}
+	if err := spendBudget(ctx); err != errBudgetExhausted {
+		t.Errorf("The query was allowed after the budget was exhausted")
+	}
+	if err := spendBudget(context.Background()); err != nil || budgetExhausted(context.Background()) {
+		t.Errorf("A context without a budget was limited")
+	}
+}
+
+func TestBudgetAfterDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(withQueryBudget(context.Background(), 10))
+	cancel()
+
+	if err := spendBudget(ctx); err == nil {
+		t.Errorf("A query was allowed after the context was done")
+	}
+	if !budgetExhausted(ctx) {
+		t.Errorf("The budget was not reported as exhausted after the context was done")
+	}
+}
+
+func TestNetworksDBChainedFetchBudget(t *testing.T) {
+	// The domain-to-ips page links to far more IP addresses than the budget allows fetching
+	var links strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&links, "<a class=\"link_sm\" href=\"/ip/10.0.%d.1\">\n", i)
+	}
+
+	count := serveResponses(t, func(path string) string {
+		switch {
+		case strings.HasPrefix(path, "/domain-to-ips/"):
+			return links.String()
+		case strings.HasPrefix(path, "/ip/"):
+			return `<b>Network:</b> <a href="/org/x">x</a> <a href="/net/x">10.0.0.0/24</a>`
+		}
+		return ""
+	})
+
+	n := NewNetworksDB(testSystem())
+	defer func() { _ = n.Stop() }()
+	n.hasAPIKey = false
+
+	n.whoisRequest(withQueryBudget(context.Background(), defaultQueryBudget), &requests.WhoisRequest{Domain: "owasp.org"})
+	if got := atomic.LoadInt64(count); got != defaultQueryBudget {
+		t.Errorf("NetworksDB made %d requests, expected the budget of %d", got, defaultQueryBudget)
+	}
+}
+
+func TestNetworksDBQuota(t *testing.T) {
+	var links strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&links, "<a class=\"link_sm\" href=\"/ip/10.0.%d.1\">\n", i)
+	}
+
+	count := serveResponses(t, func(path string) string {
+		if strings.HasPrefix(path, "/domain-to-ips/") {
+			return links.String()
+		}
+		return `<b>Network:</b> <a href="/org/x">x</a> <a href="/net/x">10.0.0.0/24</a>`
+	})
+
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Collector = stats.NewCollector()
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+	n.hasAPIKey = false
+
+	// Each web page requested by the chained fetches counts against the quota
+	sys.Collector.SetQuota(n.String(), 5)
+	n.whoisRequest(stats.NewContext(context.Background(), sys.Collector, n.String()), &requests.WhoisRequest{Domain: "owasp.org"})
+	if got := atomic.LoadInt64(count); got != 5 {
+		t.Errorf("NetworksDB made %d requests, expected the quota of 5", got)
+	}
+	if s := sys.Collector.Source(n.String()); s.Requests != 5 || !sys.Collector.QuotaReached(n.String()) {
+		t.Errorf("The requests were not counted against the quota: %+v", s)
+	}
+}
+
+func TestUmbrellaRecursionBudget(t *testing.T) {
+	// The AS lookups never provide a prefix, which sends the queries back and forth
+	count := serveResponses(t, func(path string) string {
+		if strings.HasSuffix(path, "/as_for_ip.json") {
+			return `[{"creation_date":"2020-01-01","ir":3,"description":"TEST","asn":26808,"cidr":""}]`
+		}
+		return `[{"cidr":"10.0.0.0/24","geo":{"country_name":"US","country_code":"US"}}]`
+	})
+
+	u := NewUmbrella(testSystem())
+	defer func() { _ = u.Stop() }()
+
+	u.executeASNQuery(withQueryBudget(context.Background(), 1), &requests.ASNRequest{ASN: 26808})
+	if got := atomic.LoadInt64(count); got != 1 {
+		t.Errorf("Umbrella made %d requests, expected the budget of 1", got)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// cachedResponse returns the response to the query that the data source stored in the graph
+// databases within the number of minutes set by its 'ttl' option, the same way as the scripted
+// data sources. False is returned when the option is not set or no such response was stored.
+func cachedResponse(ctx context.Context, sys systems.System, srv service.Service, query string) (string, bool) {
+	ttl := responseTTL(sys, srv)
+	if ttl <= 0 {
+		return "", false
+	}
+
+	for _, db := range sys.GraphDatabases() {
+		if db == nil {
+			continue
+		}
+
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		resp, err := db.GetSourceData(tctx, srv.String(), query, ttl)
+		cancel()
+		if err == nil {
+			return resp, true
+		}
+	}
+	return "", false
+}
+
+// cacheResponse stores the response to the query in the graph databases, when the 'ttl' option
+// of the data source is set.
+func cacheResponse(ctx context.Context, sys systems.System, srv service.Service, query, resp string) {
+	if responseTTL(sys, srv) <= 0 {
+		return
+	}
+
+	for _, db := range sys.GraphDatabases() {
+		if db == nil {
+			continue
+		}
+
+		tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		_ = db.CacheSourceData(tctx, srv.String(), query, resp)
+		cancel()
+	}
+}
+
+func responseTTL(sys systems.System, srv service.Service) int {
+	if dsc := sys.Config().GetDataSourceConfig(srv.String()); dsc != nil {
+		return dsc.TTL
+	}
+	return 0
+}
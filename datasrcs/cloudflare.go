@@ -9,6 +9,7 @@ import (
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/service"
 	"github.com/cloudflare/cloudflare-go"
@@ -48,7 +49,7 @@ func (c *Cloudflare) OnStart() error {
 		c.sys.Config().Log.Printf("%s: API key data was not provided", c.String())
 	}
 
-	c.SetRateLimit(2)
+	setRateLimit(c.sys, c, 2)
 	return nil
 }
 
@@ -58,10 +59,11 @@ func (c *Cloudflare) requests() {
 		case <-c.Done():
 			return
 		case in := <-c.Input():
+			ctx := sourceContext(c.sys, c)
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				c.CheckRateLimit()
-				c.dnsRequest(context.TODO(), req)
+				checkRateLimit(ctx, c)
+				c.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -96,21 +98,25 @@ func (c *Cloudflare) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 
 		for _, record := range records {
 			if d := c.sys.Config().WhichDomain(record.Name); d != "" {
-				c.Output() <- &requests.DNSRequest{
-					Name:   record.Name,
-					Domain: req.Domain,
-					Tag:    c.SourceType,
-					Source: c.String(),
-				}
+				stats.RecordResult(ctx)
+				sendOutput(ctx, c.sys, c, &requests.DNSRequest{
+					Name:    record.Name,
+					Domain:  req.Domain,
+					Tag:     c.SourceType,
+					Source:  c.String(),
+					Passive: true,
+				})
 			}
 			if record.Type == "CNAME" {
 				if d := c.sys.Config().WhichDomain(record.Content); d != "" {
-					c.Output() <- &requests.DNSRequest{
-						Name:   record.Content,
-						Domain: req.Domain,
-						Tag:    c.SourceType,
-						Source: c.String(),
-					}
+					stats.RecordResult(ctx)
+					sendOutput(ctx, c.sys, c, &requests.DNSRequest{
+						Name:    record.Content,
+						Domain:  req.Domain,
+						Tag:     c.SourceType,
+						Source:  c.String(),
+						Passive: true,
+					})
 				}
 			}
 		}
@@ -0,0 +1,61 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type delayKey struct{}
+
+type requestDelay struct {
+	sync.Mutex
+	delay time.Duration
+	next  time.Time
+}
+
+// withRequestDelay returns a copy of the parent context that spaces the chained queries performed
+// for the request by the delay, so the dependent requests of a data source do not arrive in bursts.
+// The parent is returned when no delay is selected.
+func withRequestDelay(parent context.Context, delay time.Duration) context.Context {
+	if delay <= 0 {
+		return parent
+	}
+	return context.WithValue(parent, delayKey{}, &requestDelay{delay: delay})
+}
+
+// waitRequestDelay blocks until the delay carried by the context has passed since the previous
+// query of the request, or the context is done. The first query of the request does not wait.
+func waitRequestDelay(ctx context.Context) error {
+	d, ok := ctx.Value(delayKey{}).(*requestDelay)
+	if !ok {
+		return nil
+	}
+
+	d.Lock()
+	now := time.Now()
+	wait := d.next.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	d.next = now.Add(wait + d.delay)
+	d.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestRequestDelay(t *testing.T) {
+	delay := 50 * time.Millisecond
+	ctx := withRequestDelay(context.Background(), delay)
+
+	start := time.Now()
+	if err := waitRequestDelay(ctx); err != nil || time.Since(start) >= delay {
+		t.Errorf("The first query of the request waited for the delay")
+	}
+	for i := 0; i < 2; i++ {
+		if err := waitRequestDelay(ctx); err != nil {
+			t.Errorf("The query failed to wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("Three queries took %v, expected at least %v", elapsed, 2*delay)
+	}
+
+	if withRequestDelay(context.Background(), 0).Value(delayKey{}) != nil {
+		t.Errorf("A zero delay was added to the context")
+	}
+
+	cctx, cancel := context.WithCancel(withRequestDelay(context.Background(), time.Minute))
+	_ = waitRequestDelay(cctx)
+	cancel()
+	if err := waitRequestDelay(cctx); err == nil {
+		t.Errorf("The delay did not end once the context was done")
+	}
+}
+
+func TestNetworksDBRequestDelay(t *testing.T) {
+	var links strings.Builder
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(&links, "<a class=\"link_sm\" href=\"/ip/10.0.%d.1\">\n", i)
+	}
+
+	var mu sync.Mutex
+	var times []time.Time
+	serveResponses(t, func(path string) string {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		if strings.HasPrefix(path, "/domain-to-ips/") {
+			return links.String()
+		}
+		return ""
+	})
+
+	sys := testSystem()
+	sys.Config().GetDataSourceConfig("NetworksDB").RequestDelay = 40
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+	n.hasAPIKey = false
+
+	n.whoisRequest(sourceContext(sys, n), &requests.WhoisRequest{Domain: "owasp.org"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 4 {
+		t.Fatalf("NetworksDB made %d requests, expected 4", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		// Allow for the resolution of the timer
+		if gap := times[i].Sub(times[i-1]); gap < 35*time.Millisecond {
+			t.Errorf("Request %d was sent %v after the previous one", i+1, gap)
+		}
+	}
+}
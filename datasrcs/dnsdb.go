@@ -15,11 +15,14 @@ import (
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 )
 
+// The address of the DNSDB API, unless base_url is set for the source
+const dnsdbBaseURL = "https://api.dnsdb.info"
+
 // DNSDB is the Service that handles access to the DNSDB data source.
 type DNSDB struct {
 	service.BaseService
@@ -54,7 +57,7 @@ func (d *DNSDB) OnStart() error {
 		d.sys.Config().Log.Printf("%s: API key data was not provided", d.String())
 	}
 
-	d.SetRateLimit(1)
+	setRateLimit(d.sys, d, 1)
 	return d.checkConfig()
 }
 
@@ -76,10 +79,11 @@ func (d *DNSDB) requests() {
 		case <-d.Done():
 			return
 		case in := <-d.Input():
+			ctx := sourceContext(d.sys, d)
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				d.CheckRateLimit()
-				d.dnsRequest(context.TODO(), req)
+				checkRateLimit(ctx, d)
+				d.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -94,7 +98,7 @@ func (d *DNSDB) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		return
 	}
 
-	numRateLimitChecks(d, 120)
+	numRateLimitChecks(ctx, d, 120)
 	d.sys.Config().Log.Printf("Querying %s for %s subdomains", d.String(), req.Domain)
 
 	headers := map[string]string{
@@ -116,7 +120,9 @@ func (d *DNSDB) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 }
 
 func (d *DNSDB) getURL(domain string) string {
-	return fmt.Sprintf("https://api.dnsdb.info/lookup/rrset/name/*.%s?limit=10000000", domain)
+	base := d.sys.Config().BaseURL(d.String(), dnsdbBaseURL)
+
+	return withQueryParams(d.sys, d, fmt.Sprintf("%s/lookup/rrset/name/*.%s?limit=10000000", base, domain))
 }
 
 func (d *DNSDB) parse(ctx context.Context, page, domain string) []string {
@@ -0,0 +1,75 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"net"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// retryEmpty runs the query, which returns true when the API responded with zero results, and
+// repeats it after the configured delay while the results are still missing. Only the queries that
+// are expected to have data are repeated, and only for the data sources that enable the option,
+// since every repeat spends the quota of the source.
+func retryEmpty(ctx context.Context, sys systems.System, srv service.Service, expected bool, desc string, query func() bool) {
+	if !query() {
+		return
+	}
+
+	retries, delay := sys.Config().EmptyRetries(srv.String())
+	if !expected || retries == 0 {
+		return
+	}
+
+	for i := 1; i <= retries; i++ {
+		if budgetExhausted(ctx) || sys.Stats().QuotaReached(srv.String()) {
+			break
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		checkRateLimit(ctx, srv)
+		if !query() {
+			sys.Config().Log.Printf("%s: %s: The query returned results after %d of %d repeats", srv.String(), desc, i, retries)
+			return
+		}
+	}
+}
+
+// routedAddress returns true when the address is outside of the reserved ranges, so the
+// registration and routing data for it is expected to exist.
+func routedAddress(addr string) bool {
+	if net.ParseIP(addr) == nil {
+		return false
+	}
+
+	reserved, _ := amassnet.IsReservedAddress(addr)
+	return !reserved
+}
+
+// publicASN returns true when the ASN can be assigned to an organization, rather than being
+// reserved for private use, documentation or the transition to four-byte numbers.
+func publicASN(asn int) bool {
+	switch {
+	case asn <= 0, asn == 23456:
+		return false
+	case asn >= 64496 && asn <= 131071:
+		return false
+	case asn >= 4200000000:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// recordExtraction tracks whether the regular expressions of a scrape data source matched the
+// page. Once too many pages in a row have failed, the source stops receiving requests, and the
+// warning makes it clear that the site has likely changed instead of the results silently drying up.
+func recordExtraction(ctx context.Context, sys systems.System, srv service.Service, ok bool) {
+	if stats.RecordExtraction(ctx, ok) {
+		sys.Config().Log.Printf("%s: WARNING: Failed to extract data from %d pages in a row, so the source "+
+			"will not be used for the rest of the run. The layout of the site has likely changed",
+			srv.String(), sys.Config().ScrapeFailureLimit)
+	}
+}
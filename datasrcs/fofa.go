@@ -53,7 +53,7 @@ func (f *FOFA) OnStart() error {
 		return errors.New(estr)
 	}
 
-	f.SetRateLimit(1)
+	setRateLimit(f.sys, f, 1)
 	return nil
 }
 
@@ -63,10 +63,11 @@ func (f *FOFA) requests() {
 		case <-f.Done():
 			return
 		case in := <-f.Input():
+			ctx := sourceContext(f.sys, f)
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				f.CheckRateLimit()
-				f.dnsRequest(context.TODO(), req)
+				checkRateLimit(ctx, f)
+				f.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -102,6 +103,6 @@ func (f *FOFA) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		for _, res := range results {
 			genNewNameEvent(ctx, f.sys, f, res.Domain)
 		}
-		f.CheckRateLimit()
+		checkRateLimit(ctx, f)
 	}
 }
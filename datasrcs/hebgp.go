@@ -0,0 +1,260 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"html"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+const heBaseURL = "https://bgp.he.net"
+
+var (
+	heAnnouncedRE = regexp.MustCompile(`href="/AS([0-9]+)"[^>]*>AS[0-9]+</a>\s*</td>\s*<td[^>]*>\s*<a href="/net/([^"]+)"`)
+	heASNameRE    = regexp.MustCompile(`<title>AS[0-9]+ (.*?) - bgp\.he\.net</title>`)
+	heCCRE        = regexp.MustCompile(`href="/country/([A-Za-z]{2})"`)
+	heNetRE       = regexp.MustCompile(`href="/net/([^"]+)"`)
+	heCellRE      = regexp.MustCompile(`<td[^>]*>([^<]*)</td>`)
+)
+
+// HurricaneElectric is the Service that handles access to the Hurricane Electric BGP Toolkit data source.
+type HurricaneElectric struct {
+	service.BaseService
+
+	SourceType string
+	sys        systems.System
+}
+
+// NewHurricaneElectric returns he object initialized, but not yet started.
+func NewHurricaneElectric(sys systems.System) *HurricaneElectric {
+	h := &HurricaneElectric{
+		SourceType: requests.SCRAPE,
+		sys:        sys,
+	}
+
+	go h.requests()
+	h.BaseService = *service.NewBaseService(h, "HurricaneElectric")
+	return h
+}
+
+// Description implements the Service interface.
+func (h *HurricaneElectric) Description() string {
+	return h.SourceType
+}
+
+// OnStart implements the Service interface.
+func (h *HurricaneElectric) OnStart() error {
+	setRateLimit(h.sys, h, 1)
+	return nil
+}
+
+func (h *HurricaneElectric) requests() {
+	for {
+		select {
+		case <-h.Done():
+			return
+		case in := <-h.Input():
+			ctx := sourceContext(h.sys, h)
+			switch req := in.(type) {
+			case *requests.ASNRequest:
+				h.asnRequest(ctx, req)
+			}
+		}
+	}
+}
+
+func (h *HurricaneElectric) asnRequest(ctx context.Context, req *requests.ASNRequest) {
+	if req.Address == "" && req.ASN == 0 {
+		return
+	}
+
+	asn, prefix := req.ASN, ""
+	if req.Address != "" {
+		if asn, prefix = h.addrAnnouncement(ctx, req.Address); asn == 0 {
+			return
+		}
+	}
+	h.executeASNQuery(ctx, asn, req.Address, prefix)
+}
+
+// addrAnnouncement returns the ASN announcing the most specific prefix that contains the address.
+func (h *HurricaneElectric) addrAnnouncement(ctx context.Context, addr string) (int, string) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return 0, ""
+	}
+
+	u := h.baseURL() + "/ip/" + ip.String()
+	page, err := h.scrapeWebPage(ctx, u)
+	if err != nil {
+		h.sys.Config().Log.Printf("%s: %s: %v", h.String(), u, err)
+		return 0, ""
+	}
+
+	asn, prefix := heAnnouncement(ip, page)
+	if asn == 0 {
+		h.sys.Config().Log.Printf("%s: %s: Failed to extract the announcing ASN", h.String(), u)
+		// Addresses that are not announced still have a page
+		recordExtraction(ctx, h.sys, h, strings.Contains(page, "bgp.he.net"))
+	}
+	return asn, prefix
+}
+
+// heAnnouncement returns the ASN and prefix of the announcement on the IP page with the most
+// specific prefix containing the address.
+func heAnnouncement(ip net.IP, page string) (int, string) {
+	var asn, best int
+	var prefix string
+
+	for _, match := range heAnnouncedRE.FindAllStringSubmatch(page, -1) {
+		cidr := amassnet.CanonicalCIDR(match[2])
+		if cidr == "" {
+			continue
+		}
+
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		if !ipnet.Contains(ip) {
+			continue
+		}
+
+		ones, _ := ipnet.Mask.Size()
+		if a, err := strconv.Atoi(match[1]); err == nil && (prefix == "" || ones > best) {
+			asn, best, prefix = a, ones, cidr
+		}
+	}
+	return asn, prefix
+}
+
+func (h *HurricaneElectric) executeASNQuery(ctx context.Context, asn int, addr, prefix string) {
+	u := h.baseURL() + "/AS" + strconv.Itoa(asn)
+	page, err := h.scrapeWebPage(ctx, u)
+	if err != nil {
+		h.sys.Config().Log.Printf("%s: %s: %v", h.String(), u, err)
+		return
+	}
+
+	matches := heASNameRE.FindStringSubmatch(page)
+	if len(matches) < 2 {
+		h.sys.Config().Log.Printf("%s: %s: The regular expression failed to extract the AS name", h.String(), u)
+		recordExtraction(ctx, h.sys, h, false)
+		return
+	}
+	name := strings.TrimSpace(html.UnescapeString(matches[1]))
+
+	var cc string
+	if matches = heCCRE.FindStringSubmatch(page); len(matches) > 1 {
+		cc = strings.ToUpper(matches[1])
+	}
+
+	netblocks := stringset.New()
+	defer netblocks.Close()
+
+	names := make(map[string]string)
+	for _, table := range []string{"table_prefixes4", "table_prefixes6"} {
+		for cidr, nm := range hePrefixTable(page, table) {
+			netblocks.Insert(cidr)
+			if nm != "" {
+				names[cidr] = nm
+			}
+		}
+	}
+	recordExtraction(ctx, h.sys, h, true)
+
+	if prefix != "" {
+		netblocks.Insert(prefix)
+	}
+	if netblocks.Len() == 0 {
+		h.sys.Config().Log.Printf("%s: %s: No prefixes are announced by the ASN", h.String(), u)
+		return
+	}
+	if addr != "" && prefix == "" {
+		prefix = amassnet.LongestPrefixMatch(net.ParseIP(addr), netblocks.Slice())
+	}
+	if prefix == "" {
+		prefix = firstNetblock(netblocks)
+	}
+
+	desc := name
+	if cc != "" {
+		desc = name + ", " + cc
+	}
+	req := &requests.ASNRequest{
+		Address:       addr,
+		ASN:           asn,
+		Prefix:        prefix,
+		CC:            cc,
+		Description:   desc,
+		Netblocks:     netblocks.Slice(),
+		NetblockNames: names,
+		Tag:           h.SourceType,
+		Source:        h.String(),
+	}
+	req.SplitNetblocks()
+
+	stats.RecordResult(ctx)
+	h.sys.Cache().Update(req)
+}
+
+// hePrefixTable returns the prefixes in the table of the ASN page with the id provided, keyed
+// by the canonical CIDR, along with the description of each.
+func hePrefixTable(page, id string) map[string]string {
+	start := strings.Index(page, `id="`+id+`"`)
+	if start == -1 {
+		return nil
+	}
+
+	table := page[start:]
+	if end := strings.Index(table, "</table>"); end != -1 {
+		table = table[:end]
+	}
+
+	prefixes := make(map[string]string)
+	for _, row := range strings.Split(table, "<tr")[1:] {
+		match := heNetRE.FindStringSubmatch(row)
+		if len(match) < 2 {
+			continue
+		}
+
+		cidr := amassnet.CanonicalCIDR(match[1])
+		if cidr == "" {
+			continue
+		}
+
+		var desc string
+		if cells := heCellRE.FindAllStringSubmatch(row, -1); len(cells) > 0 {
+			desc = strings.TrimSpace(html.UnescapeString(cells[len(cells)-1][1]))
+		}
+		prefixes[cidr] = desc
+	}
+	return prefixes
+}
+
+func (h *HurricaneElectric) scrapeWebPage(ctx context.Context, u string) (string, error) {
+	// The toolkit blocks clients that request pages too quickly, so one page is
+	// requested every two seconds at the default rate limit
+	numRateLimitChecks(ctx, h, 2)
+
+	if err := spendBudget(ctx); err != nil {
+		return "", err
+	}
+	return http.RequestWebPage(ctx, u, nil, nil, nil)
+}
+
+// baseURL returns the address of the BGP Toolkit, or the mirror selected by the base_url option.
+func (h *HurricaneElectric) baseURL() string {
+	return h.sys.Config().BaseURL(h.String(), heBaseURL)
+}
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+const heTestIPPage = `<table id="ipinfo"><tbody>
+<tr><td><a href="/AS3356" title="AS3356">AS3356</a></td>
+<td><a href="/net/8.0.0.0/12">8.0.0.0/12</a></td><td>Level 3 Parent, LLC</td></tr>
+<tr><td><a href="/AS15169" title="AS15169">AS15169</a></td>
+<td><a href="/net/8.8.8.0/24">8.8.8.0/24</a></td><td>Google LLC</td></tr>
+</tbody></table>`
+
+const heTestASNPage = `<html><head><title>AS15169 Google LLC - bgp.he.net</title></head><body>
+<div>Country of Origin: <a href="/country/US">United States</a></div>
+<table id="table_prefixes4"><thead><tr><th>Prefix</th><th>Description</th></tr></thead><tbody>
+<tr><td class="nowrap"><a href="/net/8.8.8.0/24">8.8.8.0/24</a></td><td>Google LLC</td></tr>
+<tr><td class="nowrap"><a href="/net/8.8.4.0/24">8.8.4.0/24</a></td><td>GOOGLE &amp; CO</td></tr>
+</tbody></table>
+<table id="table_prefixes6"><thead><tr><th>Prefix</th><th>Description</th></tr></thead><tbody>
+<tr><td class="nowrap"><a href="/net/2001:4860::/32">2001:4860::/32</a></td><td>Google IPv6</td></tr>
+</tbody></table>
+<table id="table_peers4"><tbody>
+<tr><td><a href="/net/192.0.2.0/24">192.0.2.0/24</a></td><td>Not a prefix</td></tr>
+</tbody></table></body></html>`
+
+func TestHurricaneElectricAnnouncement(t *testing.T) {
+	asn, prefix := heAnnouncement(net.ParseIP("8.8.8.8"), heTestIPPage)
+	if asn != 15169 || prefix != "8.8.8.0/24" {
+		t.Errorf("Failed to select the most specific announcement: AS%d %s", asn, prefix)
+	}
+	if asn, _ := heAnnouncement(net.ParseIP("192.0.2.1"), heTestIPPage); asn != 0 {
+		t.Errorf("Returned AS%d for an address that is not announced", asn)
+	}
+}
+
+func TestHurricaneElectricASNRequest(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		switch path {
+		case "/ip/8.8.8.8":
+			return heTestIPPage
+		case "/AS15169":
+			return heTestASNPage
+		}
+		return ""
+	})
+
+	sys := testSystem()
+	h := NewHurricaneElectric(sys)
+	defer func() { _ = h.Stop() }()
+
+	h.asnRequest(context.Background(), &requests.ASNRequest{Address: "8.8.8.8"})
+
+	entry := sys.Cache().ASNSearch(15169)
+	if entry == nil {
+		t.Fatal("The ASN was not added to the cache")
+	}
+	if entry.Prefix != "8.8.8.0/24" || entry.CC != "US" || entry.Description != "Google LLC, US" {
+		t.Errorf("Unexpected ASN information: %s %s %s", entry.Prefix, entry.CC, entry.Description)
+	}
+	if len(entry.Netblocks) != 3 {
+		t.Errorf("Expected the IPv4 and IPv6 prefixes, got %v", entry.Netblocks)
+	}
+	if entry.NetblockNames["8.8.4.0/24"] != "GOOGLE & CO" || entry.NetblockNames["2001:4860::/32"] != "Google IPv6" {
+		t.Errorf("Failed to extract the prefix descriptions: %v", entry.NetblockNames)
+	}
+	if r := sys.Cache().AddrSearch("2001:4860::8888"); r == nil || r.ASN != 15169 {
+		t.Errorf("The IPv6 prefix was not cached for the ASN")
+	}
+}
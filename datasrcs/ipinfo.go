@@ -0,0 +1,345 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/resolve"
+	"github.com/caffix/service"
+)
+
+const (
+	ipinfoBaseURL = "https://ipinfo.io"
+	// The free plan allows 50,000 requests per month, so requests are paused for this long
+	// after the service reports that the limit was reached without providing a Retry-After
+	ipinfoDefaultBackoff = 10 * time.Minute
+)
+
+// IPinfo is the Service that handles access to the IPinfo data source.
+type IPinfo struct {
+	service.BaseService
+
+	SourceType string
+	sys        systems.System
+	creds      *config.Credentials
+	// Requests are not sent before this time, after the rate limit was reached
+	limitedUntil time.Time
+	// Set once the token is found to lack access to the ASN details
+	asnDenied bool
+}
+
+// NewIPinfo returns he object initialized, but not yet started.
+func NewIPinfo(sys systems.System) *IPinfo {
+	i := &IPinfo{
+		SourceType: requests.API,
+		sys:        sys,
+	}
+
+	go i.requests()
+	i.BaseService = *service.NewBaseService(i, "IPinfo")
+	return i
+}
+
+// Description implements the Service interface.
+func (i *IPinfo) Description() string {
+	return i.SourceType
+}
+
+// OnStart implements the Service interface.
+func (i *IPinfo) OnStart() error {
+	i.creds = i.sys.Config().GetDataSourceConfig(i.String()).GetCredentials()
+
+	if i.creds == nil || i.creds.Key == "" {
+		i.sys.Config().Log.Printf("%s: API key data was not provided", i.String())
+	}
+
+	setRateLimit(i.sys, i, 1)
+	return i.checkConfig()
+}
+
+func (i *IPinfo) checkConfig() error {
+	creds := i.sys.Config().GetDataSourceConfig(i.String()).GetCredentials()
+
+	if creds == nil || creds.Key == "" {
+		estr := fmt.Sprintf("%s: check callback failed for the configuration", i.String())
+		i.sys.Config().Log.Print(estr)
+		return errors.New(estr)
+	}
+
+	return nil
+}
+
+func (i *IPinfo) requests() {
+	for {
+		select {
+		case <-i.Done():
+			return
+		case in := <-i.Input():
+			ctx := sourceContext(i.sys, i)
+			switch req := in.(type) {
+			case *requests.AddrRequest:
+				checkRateLimit(ctx, i)
+				i.addrRequest(ctx, req)
+			case *requests.ASNRequest:
+				checkRateLimit(ctx, i)
+				i.asnRequest(ctx, req)
+			}
+		}
+	}
+}
+
+func (i *IPinfo) addrRequest(ctx context.Context, req *requests.AddrRequest) {
+	if i.creds == nil || i.creds.Key == "" || req.Address == "" {
+		return
+	}
+
+	info := i.queryAddr(ctx, req.Address)
+	if info == nil {
+		return
+	}
+
+	if name := resolve.RemoveLastDot(info.Hostname); name != "" {
+		genNewNameEvent(ctx, i.sys, i, name)
+	}
+	if as := info.asnRequest(req.Address, i.SourceType, i.String()); as != nil && as.Prefix != "" {
+		stats.RecordResult(ctx)
+		i.sys.Cache().Update(as)
+	}
+}
+
+func (i *IPinfo) asnRequest(ctx context.Context, req *requests.ASNRequest) {
+	if i.creds == nil || i.creds.Key == "" {
+		return
+	}
+	if req.Address == "" && req.ASN == 0 {
+		return
+	}
+
+	as := &requests.ASNRequest{ASN: req.ASN}
+	if req.Address != "" {
+		info := i.queryAddr(ctx, req.Address)
+		if info == nil {
+			return
+		}
+		if as = info.asnRequest(req.Address, i.SourceType, i.String()); as == nil {
+			return
+		}
+	}
+
+	// The ASN details and netblocks are only provided to paid plans
+	if !i.asnDenied {
+		checkRateLimit(ctx, i)
+		i.queryASN(ctx, as)
+	}
+	if as.Prefix == "" {
+		return
+	}
+
+	as.Tag = i.SourceType
+	as.Source = i.String()
+	stats.RecordResult(ctx)
+	i.sys.Cache().Update(as)
+}
+
+type ipinfoAddrResponse struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Country  string `json:"country"`
+	// The free plan provides the AS in the form "AS15169 Google LLC"
+	Org string `json:"org"`
+	// The paid plans provide the AS details along with the announced route
+	ASN *struct {
+		ASN    string `json:"asn"`
+		Name   string `json:"name"`
+		Domain string `json:"domain"`
+		Route  string `json:"route"`
+	} `json:"asn"`
+}
+
+// asnRequest returns the AS information provided for the address, or nil when the
+// response does not identify the AS.
+func (r *ipinfoAddrResponse) asnRequest(addr, tag, source string) *requests.ASNRequest {
+	var asn int
+	var desc, prefix string
+
+	if r.ASN != nil {
+		asn = ipinfoParseASN(r.ASN.ASN)
+		desc = r.ASN.Name
+		prefix = strings.TrimSpace(r.ASN.Route)
+	} else if parts := strings.SplitN(r.Org, " ", 2); len(parts) > 0 {
+		asn = ipinfoParseASN(parts[0])
+		if len(parts) == 2 {
+			desc = parts[1]
+		}
+	}
+	if asn == 0 {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(prefix); err != nil {
+		prefix = ""
+	}
+
+	var netblocks []string
+	if prefix != "" {
+		netblocks = []string{prefix}
+	}
+	return &requests.ASNRequest{
+		Address:     addr,
+		ASN:         asn,
+		Prefix:      prefix,
+		CC:          r.Country,
+		Description: desc,
+		Netblocks:   netblocks,
+		Tag:         tag,
+		Source:      source,
+	}
+}
+
+func (i *IPinfo) queryAddr(ctx context.Context, addr string) *ipinfoAddrResponse {
+	page, _, err := i.request(ctx, i.addrURL(addr))
+	if err != nil {
+		return nil
+	}
+
+	var info ipinfoAddrResponse
+	if err := json.Unmarshal([]byte(page), &info); err != nil || info.IP == "" {
+		return nil
+	}
+	return &info
+}
+
+// queryASN adds the description, registry and netblocks of the AS to the request.
+func (i *IPinfo) queryASN(ctx context.Context, req *requests.ASNRequest) {
+	page, status, err := i.request(ctx, i.asnURL(req.ASN))
+	if status == 401 || status == 403 {
+		i.asnDenied = true
+		i.sys.Config().Log.Printf("%s: The token does not provide access to the ASN details", i.String())
+	}
+	if err != nil {
+		return
+	}
+
+	var as struct {
+		ASN       string `json:"asn"`
+		Name      string `json:"name"`
+		Country   string `json:"country"`
+		Allocated string `json:"allocated"`
+		Registry  string `json:"registry"`
+		Prefixes  []struct {
+			Netblock string `json:"netblock"`
+		} `json:"prefixes"`
+		Prefixes6 []struct {
+			Netblock string `json:"netblock"`
+		} `json:"prefixes6"`
+	}
+	if err := json.Unmarshal([]byte(page), &as); err != nil || ipinfoParseASN(as.ASN) != req.ASN {
+		return
+	}
+
+	if req.Description == "" {
+		req.Description = as.Name
+	}
+	if req.CC == "" {
+		req.CC = as.Country
+	}
+	req.Registry = strings.ToUpper(as.Registry)
+	if created, err := time.Parse("2006-01-02", as.Allocated); err == nil {
+		req.AllocationDate = created
+	}
+	for _, p := range append(as.Prefixes, as.Prefixes6...) {
+		if _, _, err := net.ParseCIDR(p.Netblock); err == nil && p.Netblock != req.Prefix {
+			req.Netblocks = append(req.Netblocks, p.Netblock)
+		}
+	}
+	if req.Prefix == "" && len(req.Netblocks) > 0 {
+		req.Prefix = req.Netblocks[0]
+	}
+}
+
+// request sends the query unless the rate limit of the plan was recently reached, in which
+// case no requests are sent until the time requested by the service has passed. The status
+// code is zero when no response was received. Responses cached within the 'ttl' option are
+// returned without sending the query.
+func (i *IPinfo) request(ctx context.Context, u string) (string, int, error) {
+	if page, found := cachedResponse(ctx, i.sys, i, u); found {
+		http.RecordSourceURL(ctx, u)
+		return page, 200, nil
+	}
+	if time.Now().Before(i.limitedUntil) {
+		return "", 0, errors.New("the rate limit was reached")
+	}
+	if err := spendBudget(ctx); err != nil {
+		i.sys.Config().Log.Printf("%s: %s: %v", i.String(), u, err)
+		return "", 0, err
+	}
+
+	resp, err := http.RequestWebPageWithHeaders(ctx, u, nil, i.headers(), nil)
+	if resp == nil {
+		i.sys.Config().Log.Printf("%s: %s: %v", i.String(), u, err)
+		return "", 0, err
+	}
+	if err != nil {
+		i.sys.Config().Log.Printf("%s: %s: %v", i.String(), u, err)
+		if resp.StatusCode == 429 {
+			delay, ok := http.RetryAfter(resp.Header)
+			if !ok || delay == 0 {
+				delay = ipinfoDefaultBackoff
+			}
+			i.limitedUntil = time.Now().Add(delay)
+			i.sys.Config().Log.Printf("%s: The rate limit was reached, pausing requests for %v", i.String(), delay)
+		}
+	} else {
+		cacheResponse(ctx, i.sys, i, u, resp.Body)
+	}
+	return resp.Body, resp.StatusCode, err
+}
+
+func (i *IPinfo) headers() map[string]string {
+	headers := map[string]string{"Accept": "application/json"}
+
+	if i.creds != nil && i.creds.Key != "" {
+		headers["Authorization"] = "Bearer " + i.creds.Key
+	}
+	return headers
+}
+
+// baseURL returns the address of the IPinfo API, or the mirror selected by the base_url option.
+func (i *IPinfo) baseURL() string {
+	return i.sys.Config().BaseURL(i.String(), ipinfoBaseURL)
+}
+
+func (i *IPinfo) addrURL(addr string) string {
+	return i.baseURL() + "/" + addr + "/json"
+}
+
+func (i *IPinfo) asnURL(asn int) string {
+	return i.baseURL() + "/AS" + strconv.Itoa(asn) + "/json"
+}
+
+func ipinfoParseASN(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) < 3 || !strings.EqualFold(s[:2], "AS") {
+		return 0
+	}
+
+	asn, err := strconv.Atoi(s[2:])
+	if err != nil {
+		return 0
+	}
+	return asn
+}
@@ -0,0 +1,111 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+)
+
+func TestIPinfoAddrRequest(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasPrefix(path, "/AS") {
+			return `{"asn":"AS15169","name":"Google LLC","country":"US","registry":"arin",` +
+				`"prefixes":[{"netblock":"8.8.8.0/24"},{"netblock":"8.8.4.0/24"}],` +
+				`"prefixes6":[{"netblock":"2001:4860::/32"}]}`
+		}
+		return `{"ip":"8.8.8.8","hostname":"dns.owasp.org","country":"US",` +
+			`"asn":{"asn":"AS15169","name":"Google LLC","route":"8.8.8.0/24"}}`
+	})
+
+	sys := testSystem()
+	i := NewIPinfo(sys)
+	defer func() { _ = i.Stop() }()
+	i.creds = &config.Credentials{Key: "fake"}
+
+	i.addrRequest(context.Background(), &requests.AddrRequest{Address: "8.8.8.8"})
+	select {
+	case out := <-i.Output():
+		if req, ok := out.(*requests.DNSRequest); !ok || req.Name != "dns.owasp.org" {
+			t.Errorf("Unexpected output for the hostname: %v", out)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("The hostname was not sent as a name")
+	}
+	if entry := sys.Cache().AddrSearch("8.8.8.8"); entry == nil || entry.ASN != 15169 || entry.Prefix != "8.8.8.0/24" {
+		t.Errorf("The address was not added to the cache: %+v", entry)
+	}
+
+	i.asnRequest(context.Background(), &requests.ASNRequest{ASN: 15169})
+	entry := sys.Cache().ASNSearch(15169)
+	if entry == nil || len(entry.Netblocks) != 3 || len(entry.IPv6Netblocks) != 1 || entry.Registry != "ARIN" {
+		t.Errorf("The ASN netblocks were not added to the cache: %+v", entry)
+	}
+}
+
+func TestIPinfoResponseTTL(t *testing.T) {
+	count := serveResponses(t, func(path string) string {
+		return `{"ip":"8.8.8.8","country":"US","asn":{"asn":"AS15169","name":"Google LLC","route":"8.8.8.0/24"}}`
+	})
+
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Graph = netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer sys.Graph.Close()
+	sys.Config().GetDataSourceConfig("IPinfo").TTL = 60
+	i := NewIPinfo(sys)
+	defer func() { _ = i.Stop() }()
+	i.creds = &config.Credentials{Key: "fake"}
+
+	for n := 0; n < 2; n++ {
+		i.addrRequest(context.Background(), &requests.AddrRequest{Address: "8.8.8.8"})
+	}
+	if *count != 1 {
+		t.Errorf("The address was queried %d times within the TTL", *count)
+	}
+	if entry := sys.Cache().AddrSearch("8.8.8.8"); entry == nil || entry.ASN != 15169 {
+		t.Errorf("The cached response was not used: %+v", entry)
+	}
+}
+
+func TestIPinfoFreePlan(t *testing.T) {
+	var asnQueries int
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasPrefix(path, "/AS") {
+			asnQueries++
+			return `{"error":{"title":"Unknown token"}}`
+		}
+		return `{"ip":"8.8.8.8","country":"US","org":"AS15169 Google LLC"}`
+	})
+
+	sys := testSystem()
+	i := NewIPinfo(sys)
+	defer func() { _ = i.Stop() }()
+	i.creds = &config.Credentials{Key: "fake"}
+
+	info := i.queryAddr(context.Background(), "8.8.8.8")
+	if info == nil {
+		t.Fatalf("The address information was not parsed")
+	}
+	as := info.asnRequest("8.8.8.8", i.SourceType, i.String())
+	if as == nil || as.ASN != 15169 || as.Description != "Google LLC" || as.CC != "US" || as.Prefix != "" {
+		t.Errorf("Unexpected AS information from the org field: %+v", as)
+	}
+
+	// Without the route or the ASN netblocks, nothing can be added to the cache
+	i.asnRequest(context.Background(), &requests.ASNRequest{Address: "8.8.8.8"})
+	if entry := sys.Cache().ASNSearch(15169); entry != nil {
+		t.Errorf("An entry without netblocks was added to the cache: %+v", entry)
+	}
+	if asnQueries != 1 {
+		t.Errorf("The ASN details were requested %d times", asnQueries)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// rateJitter replaces the fixed cadence of the rate limiter for a data source. Each gap between
+// requests is drawn uniformly from a band around the rate limit interval, so the average rate
+// remains the configured rate while the timing of the requests varies.
+type rateJitter struct {
+	sync.Mutex
+	interval time.Duration
+	band     float64
+	rng      *rand.Rand
+	next     time.Time
+}
+
+var rateJitters = struct {
+	sync.Mutex
+	m map[service.Service]*rateJitter
+}{m: make(map[service.Service]*rateJitter)}
+
+// applyRateLimit sets the number of requests per second sent to the data source. When jitter is
+// configured for the source, the rate limiter of the service is disabled and the jittered gaps
+// are enforced by checkRateLimit instead.
+func applyRateLimit(sys systems.System, srv service.Service, persec int) {
+	band := sys.Config().RateJitter(srv.String())
+	if band <= 0 || persec <= 0 {
+		srv.SetRateLimit(persec)
+		return
+	}
+
+	rateJitters.Lock()
+	defer rateJitters.Unlock()
+
+	interval := time.Second / time.Duration(persec)
+	if rj, found := rateJitters.m[srv]; found {
+		rj.Lock()
+		rj.interval = interval
+		rj.Unlock()
+		return
+	}
+
+	rateJitters.m[srv] = &rateJitter{
+		interval: interval,
+		band:     band,
+		// The random_seed option makes the gaps repeatable
+		rng: sys.Config().NewRand(),
+	}
+	srv.SetRateLimit(0)
+}
+
+// rateJitterFor returns the jittered rate limit of the data source, or nil when it is not configured.
+func rateJitterFor(srv service.Service) *rateJitter {
+	rateJitters.Lock()
+	defer rateJitters.Unlock()
+
+	return rateJitters.m[srv]
+}
+
+// wait blocks until the time selected for the next request to the data source.
+func (rj *rateJitter) wait() {
+	rj.Lock()
+	now := time.Now()
+	at := rj.next
+	if at.Before(now) {
+		at = now
+	}
+	rj.next = at.Add(rj.gap())
+	rj.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// gap returns a random duration within the band around the interval, with the interval as its mean.
+func (rj *rateJitter) gap() time.Duration {
+	offset := (rj.rng.Float64()*2 - 1) * rj.band
+	return time.Duration(float64(rj.interval) * (1 + offset))
+}
@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateJitter(t *testing.T) {
+	sys := testSystem()
+	sys.Config().RandomSeed = 42
+	sys.Config().GetDataSourceConfig("NetworksDB").RateJitter = 50
+
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+
+	setRateLimit(sys, n, 20)
+	rj := rateJitterFor(n)
+	if rj == nil || rj.interval != 50*time.Millisecond {
+		t.Fatalf("The jittered rate limit was not set up: %+v", rj)
+	}
+
+	num := 40
+	gaps := make([]time.Duration, 0, num)
+	last := time.Now()
+	start := last
+	for i := 0; i <= num; i++ {
+		checkRateLimit(context.Background(), n)
+
+		now := time.Now()
+		if i > 0 {
+			gaps = append(gaps, now.Sub(last))
+		}
+		last = now
+	}
+
+	var min, max time.Duration
+	for i, gap := range gaps {
+		if i == 0 || gap < min {
+			min = gap
+		}
+		if gap > max {
+			max = gap
+		}
+	}
+	if max-min < 10*time.Millisecond {
+		t.Errorf("The gaps between requests did not vary: from %v to %v", min, max)
+	}
+	// The average rate remains the configured rate
+	if avg := time.Since(start) / time.Duration(num); avg < 40*time.Millisecond || avg > 60*time.Millisecond {
+		t.Errorf("The average gap of %v did not match the rate limit", avg)
+	}
+}
+
+func TestRateJitterSeed(t *testing.T) {
+	sys := testSystem()
+	sys.Config().RandomSeed = 7
+
+	a := &rateJitter{interval: time.Second, band: 0.3, rng: sys.Config().NewRand()}
+	b := &rateJitter{interval: time.Second, band: 0.3, rng: sys.Config().NewRand()}
+	for i := 0; i < 10; i++ {
+		ga, gb := a.gap(), b.gap()
+		if ga != gb {
+			t.Fatalf("The seeded gaps differed: %v and %v", ga, gb)
+		}
+		if ga < 700*time.Millisecond || ga > 1300*time.Millisecond {
+			t.Errorf("The gap %v was outside of the band", ga)
+		}
+	}
+}
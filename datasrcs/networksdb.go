@@ -7,25 +7,40 @@ package datasrcs
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aokimio/Amass/v3/config"
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/dns"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 )
 
 const (
 	networksdbBaseURL = "https://networksdb.io"
 	networksdbAPIPATH = "/api/v1"
+	// Limits applied when the scrape path is rate limited by the server
+	networksdbMaxRetries     = 5
+	networksdbDefaultBackoff = 30 * time.Second
+	networksdbMaxBackoff     = 5 * time.Minute
+	// The number of organizations matching a search that are expanded into ASNs and netblocks
+	networksdbMaxOrgMatches = 5
+	// The score a hosted domain receives for each netblock listing it, which exceeds any prefix
+	// length added to it, so the number of netblocks ranks the domains before their size
+	networksdbNetblockWeight = 256
 )
 
 var (
@@ -34,10 +49,13 @@ var (
 	networksdbASNRE        = regexp.MustCompile(`AS Number:<\/b> ([0-9]*)<br>`)
 	networksdbCIDRRE       = regexp.MustCompile(`CIDR:<\/b>(.*)<br>`)
 	networksdbIPPageCIDRRE = regexp.MustCompile(`<b>Network:.* href=".*".*href=".*">(.*)<\/a>`)
+	networksdbIPPageNameRE = regexp.MustCompile(`<b>Network:.*?<a [^>]*href="[^"]*"[^>]*>([^<]+)<\/a>`)
 	networksdbASNameRE     = regexp.MustCompile(`AS Name:<\/b>(.*)<br>`)
 	networksdbCCRE         = regexp.MustCompile(`Location:<\/b>.*href="/country/(.*)">`)
 	networksdbDomainsRE    = regexp.MustCompile(`Domains in network`)
 	networksdbTableRE      = regexp.MustCompile(`<table class`)
+	networksdbTextRE       = regexp.MustCompile(`>([^<>]+)<`)
+	networksdbDomainRE     = regexp.MustCompile(`^` + dns.AnySubdomainRegexString() + `$`)
 )
 
 // NetworksDB is the Service that handles access to the NetworksDB.io data source.
@@ -48,6 +66,8 @@ type NetworksDB struct {
 	sys        systems.System
 	creds      *config.Credentials
 	hasAPIKey  bool
+	// Bounds the ASN expansions performed at the same time
+	workers chan struct{}
 }
 
 // NewNetworksDB returns he object initialized, but not yet started.
@@ -56,6 +76,7 @@ func NewNetworksDB(sys systems.System) *NetworksDB {
 		SourceType: requests.API,
 		sys:        sys,
 		hasAPIKey:  true,
+		workers:    make(chan struct{}, sys.Config().NumASNWorkers()),
 	}
 
 	go n.requests()
@@ -71,14 +92,29 @@ func (n *NetworksDB) Description() string {
 // OnStart implements the Service interface.
 func (n *NetworksDB) OnStart() error {
 	n.creds = n.sys.Config().GetDataSourceConfig(n.String()).GetCredentials()
-
-	if n.creds == nil || n.creds.Key == "" {
-		n.sys.Config().Log.Printf("%s: API key data was not provided", n.String())
+	hasKey := n.creds != nil && n.creds.Key != ""
+
+	// The mode option overrides the choice based on the credentials
+	switch n.sys.Config().SourceMode(n.String()) {
+	case config.ModeAPI:
+		if !hasKey {
+			estr := fmt.Sprintf("%s: the api mode requires an API key", n.String())
+			n.sys.Config().Log.Print(estr)
+			return errors.New(estr)
+		}
+	case config.ModeScrape:
+		n.sys.Config().Log.Printf("%s: Scraping the website as selected by the mode", n.String())
 		n.SourceType = requests.SCRAPE
 		n.hasAPIKey = false
+	default:
+		if !hasKey {
+			n.sys.Config().Log.Printf("%s: API key data was not provided", n.String())
+			n.SourceType = requests.SCRAPE
+			n.hasAPIKey = false
+		}
 	}
 
-	n.SetRateLimit(1)
+	setRateLimit(n.sys, n, 1)
 	return nil
 }
 
@@ -88,13 +124,28 @@ func (n *NetworksDB) requests() {
 		case <-n.Done():
 			return
 		case in := <-n.Input():
+			ctx := sourceContext(n.sys, n)
 			switch req := in.(type) {
 			case *requests.ASNRequest:
-				n.CheckRateLimit()
-				n.asnRequest(context.TODO(), req)
+				// The ASN requests are independent, so several are handled at once
+				select {
+				case <-n.Done():
+					return
+				case n.workers <- struct{}{}:
+				}
+				go func(req *requests.ASNRequest) {
+					defer func() { <-n.workers }()
+
+					checkRateLimit(ctx, n)
+					n.asnRequest(ctx, req)
+				}(req)
 			case *requests.WhoisRequest:
-				n.CheckRateLimit()
-				n.whoisRequest(context.TODO(), req)
+				checkRateLimit(ctx, n)
+				if req.Domain == "" && req.Company != "" {
+					n.orgSearch(ctx, req.Company)
+					continue
+				}
+				n.whoisRequest(ctx, req)
 			}
 		}
 	}
@@ -105,12 +156,12 @@ func (n *NetworksDB) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 		return
 	}
 
-	numRateLimitChecks(n, 2)
+	numRateLimitChecks(ctx, n, 2)
 	if n.hasAPIKey {
 		if req.Address != "" {
 			n.executeAPIASNAddrQuery(ctx, req.Address)
 		} else {
-			n.executeAPIASNQuery(ctx, req.ASN, "", nil)
+			n.executeAPIASNQuery(ctx, req.ASN, "", nil, nil)
 		}
 		return
 	}
@@ -121,13 +172,13 @@ func (n *NetworksDB) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 		set := stringset.New()
 		defer set.Close()
 
-		n.executeASNQuery(ctx, req.ASN, "", set)
+		n.executeASNQuery(ctx, req.ASN, "", set, nil)
 	}
 }
 
 func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 	u := n.getIPURL(addr)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := n.scrapeWebPage(ctx, u)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
 		return
@@ -136,12 +187,16 @@ func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 	matches := networksdbASNLinkRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
 		n.sys.Config().Log.Printf("%s: %s: Failed to extract the autonomous system href", n.String(), u)
+		// Addresses that are not announced still have a page listing the network
+		recordExtraction(ctx, n.sys, n, networksdbIPPageCIDRRE.MatchString(page))
 		return
 	}
 
-	numRateLimitChecks(n, 3)
-	u = networksdbBaseURL + matches[1]
-	page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+	names := networksdbIPPageNetworkName(page)
+
+	numRateLimitChecks(ctx, n, 3)
+	u = n.baseURL() + matches[1]
+	page, err = n.scrapeWebPage(ctx, u)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
 		return
@@ -151,14 +206,18 @@ func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 	defer netblocks.Close()
 
 	for _, match := range networksdbCIDRRE.FindAllStringSubmatch(page, -1) {
-		if len(match) >= 2 {
-			netblocks.Insert(strings.TrimSpace(match[1]))
+		if len(match) < 2 {
+			continue
+		}
+		if cidr := amassnet.CanonicalCIDR(match[1]); cidr != "" {
+			netblocks.Insert(cidr)
 		}
 	}
 
 	matches = networksdbASNRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
 		n.sys.Config().Log.Printf("%s: %s: The regular expression failed to extract the ASN", n.String(), u)
+		recordExtraction(ctx, n.sys, n, false)
 		return
 	}
 
@@ -168,17 +227,80 @@ func (n *NetworksDB) executeASNAddrQuery(ctx context.Context, addr string) {
 		return
 	}
 
-	n.executeASNQuery(ctx, asn, addr, netblocks)
+	n.executeASNQuery(ctx, asn, addr, netblocks, names)
+}
+
+// networksdbIPPageNetworkName returns the name of the network listed on the IP page keyed by the CIDR.
+func networksdbIPPageNetworkName(page string) map[string]string {
+	cidr := networksdbIPPageCIDRRE.FindStringSubmatch(page)
+	name := networksdbIPPageNameRE.FindStringSubmatch(page)
+	if len(cidr) < 2 || len(name) < 2 {
+		return nil
+	}
+
+	c := strings.TrimSpace(cidr[1])
+	nm := strings.TrimSpace(name[1])
+	if _, _, err := net.ParseCIDR(c); err != nil || nm == "" || nm == c {
+		return nil
+	}
+	return map[string]string{c: nm}
+}
+
+// scrapeWebPage requests the page at the provided URL and waits out any 429 responses from the
+// server, so the in-progress ASN or whois expansion can resume once the limit has been lifted.
+func (n *NetworksDB) scrapeWebPage(ctx context.Context, u string) (string, error) {
+	if err := spendBudget(ctx); err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := http.RequestWebPageWithHeaders(ctx, u, nil, n.scrapeHeaders(), nil)
+		if err == nil || resp == nil || resp.StatusCode != 429 || attempt > networksdbMaxRetries {
+			if resp == nil {
+				return "", err
+			}
+			return resp.Body, err
+		}
+
+		delay, ok := http.RetryAfter(resp.Header)
+		if !ok || delay == 0 {
+			delay = networksdbDefaultBackoff
+		}
+		if delay > networksdbMaxBackoff {
+			delay = networksdbMaxBackoff
+		}
+		// A single line is logged when the rate limiting starts, rather than one for each retry
+		if attempt == 1 {
+			n.sys.Config().Log.Printf("%s: %s: Rate limited by the server, resuming in %v and retrying up to %d times",
+				n.String(), u, delay, networksdbMaxRetries)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		case <-n.Done():
+			t.Stop()
+			return "", errors.New("the service was stopped")
+		case <-t.C:
+		}
+	}
+}
+
+// baseURL returns the address of the NetworksDB site, or the mirror selected by the base_url option.
+func (n *NetworksDB) baseURL() string {
+	return n.sys.Config().BaseURL(n.String(), networksdbBaseURL)
 }
 
 func (n *NetworksDB) getIPURL(addr string) string {
-	return networksdbBaseURL + "/ip/" + addr
+	return n.baseURL() + "/ip/" + addr
 }
 
-func (n *NetworksDB) executeASNQuery(ctx context.Context, asn int, addr string, netblocks *stringset.Set) {
-	numRateLimitChecks(n, 3)
+func (n *NetworksDB) executeASNQuery(ctx context.Context, asn int, addr string, netblocks *stringset.Set, names map[string]string) {
+	numRateLimitChecks(ctx, n, 3)
 	u := n.getASNURL(asn)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := n.scrapeWebPage(ctx, u)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
 		return
@@ -187,6 +309,7 @@ func (n *NetworksDB) executeASNQuery(ctx context.Context, asn int, addr string,
 	matches := networksdbASNameRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
 		n.sys.Config().Log.Printf("%s: The regular expression failed to extract the AS name", n.String())
+		recordExtraction(ctx, n.sys, n, false)
 		return
 	}
 	name := strings.TrimSpace(matches[1])
@@ -194,45 +317,48 @@ func (n *NetworksDB) executeASNQuery(ctx context.Context, asn int, addr string,
 	matches = networksdbCCRE.FindStringSubmatch(page)
 	if matches == nil || len(matches) < 2 {
 		n.sys.Config().Log.Printf("%s: The regular expression failed to extract the country code", n.String())
+		recordExtraction(ctx, n.sys, n, false)
 		return
 	}
 	cc := strings.TrimSpace(matches[1])
+	recordExtraction(ctx, n.sys, n, true)
 
 	for _, match := range networksdbCIDRRE.FindAllStringSubmatch(page, -1) {
-		if len(match) >= 2 {
-			netblocks.Insert(strings.TrimSpace(match[1]))
+		if len(match) < 2 {
+			continue
+		}
+		if cidr := amassnet.CanonicalCIDR(match[1]); cidr != "" {
+			netblocks.Insert(cidr)
 		}
 	}
 
 	var prefix string
 	if addr != "" {
-		ip := net.ParseIP(addr)
-
-		for _, cidr := range netblocks.Slice() {
-			if _, ipnet, err := net.ParseCIDR(cidr); err == nil && ipnet.Contains(ip) {
-				prefix = cidr
-				break
-			}
-		}
+		prefix = amassnet.LongestPrefixMatch(net.ParseIP(addr), netblocks.Slice())
 	}
 	if prefix == "" && netblocks.Len() > 0 {
-		prefix = netblocks.Slice()[0] // TODO order may matter here :shrug:
+		prefix = firstNetblock(netblocks)
 	}
 
-	n.sys.Cache().Update(&requests.ASNRequest{
-		Address:     addr,
-		ASN:         asn,
-		Prefix:      prefix,
-		CC:          cc,
-		Description: name + ", " + cc,
-		Netblocks:   netblocks.Slice(),
-		Tag:         n.SourceType,
-		Source:      n.String(),
-	})
+	req := &requests.ASNRequest{
+		Address:       addr,
+		ASN:           asn,
+		Prefix:        prefix,
+		CC:            cc,
+		Description:   name + ", " + cc,
+		Netblocks:     netblocks.Slice(),
+		NetblockNames: names,
+		Tag:           n.SourceType,
+		Source:        n.String(),
+	}
+	req.SplitNetblocks()
+
+	stats.RecordResult(ctx)
+	n.sys.Cache().Update(req)
 }
 
 func (n *NetworksDB) getASNURL(asn int) string {
-	return networksdbBaseURL + "/autonomous-system/AS" + strconv.Itoa(asn)
+	return n.baseURL() + "/autonomous-system/AS" + strconv.Itoa(asn)
 }
 
 func (n *NetworksDB) executeAPIASNAddrQuery(ctx context.Context, addr string) {
@@ -242,7 +368,7 @@ func (n *NetworksDB) executeAPIASNAddrQuery(ctx context.Context, addr string) {
 		return
 	}
 
-	numRateLimitChecks(n, 3)
+	numRateLimitChecks(ctx, n, 3)
 	asns := n.apiOrgInfoQuery(ctx, id)
 	if len(asns) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: Failed to obtain ASNs associated with the organization", n.String(), id)
@@ -250,26 +376,26 @@ func (n *NetworksDB) executeAPIASNAddrQuery(ctx context.Context, addr string) {
 	}
 
 	var asn int
+	var names map[string]string
 	cidrs := stringset.New()
 	defer cidrs.Close()
 	ip := net.ParseIP(addr)
-loop:
 	for _, a := range asns {
-		numRateLimitChecks(n, 3)
-		cidrs = n.apiNetblocksQuery(ctx, a)
+		if budgetExhausted(ctx) || n.sys.Stats().QuotaReached(n.String()) {
+			break
+		}
+
+		numRateLimitChecks(ctx, n, 3)
+		cidrs, names = n.apiNetblocksQuery(ctx, a)
 		defer cidrs.Close()
 
 		if cidrs.Len() == 0 {
 			n.sys.Config().Log.Printf("%s: %d: Failed to obtain netblocks associated with the ASN", n.String(), a)
 		}
 
-		for _, cidr := range cidrs.Slice() {
-			if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
-				if ipnet.Contains(ip) {
-					asn = a
-					break loop
-				}
-			}
+		if amassnet.LongestPrefixMatch(ip, cidrs.Slice()) != "" {
+			asn = a
+			break
 		}
 	}
 
@@ -277,17 +403,18 @@ loop:
 		n.sys.Config().Log.Printf("%s: %s: Failed to obtain the ASN associated with the IP address", n.String(), addr)
 		return
 	}
-	n.executeAPIASNQuery(ctx, asn, addr, cidrs)
+	n.executeAPIASNQuery(ctx, asn, addr, cidrs, names)
 }
 
-func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr string, netblocks *stringset.Set) {
+func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr string, netblocks *stringset.Set, names map[string]string) {
 	if netblocks == nil {
 		netblocks = stringset.New()
 		defer netblocks.Close()
 	}
 
 	if netblocks.Len() == 0 {
-		set := n.apiNetblocksQuery(ctx, asn)
+		var set *stringset.Set
+		set, names = n.apiNetblocksQuery(ctx, asn)
 		defer set.Close()
 
 		netblocks.Union(set)
@@ -299,19 +426,13 @@ func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr strin
 
 	var prefix string
 	if addr != "" {
-		ip := net.ParseIP(addr)
-		for _, cidr := range netblocks.Slice() {
-			if _, ipnet, err := net.ParseCIDR(cidr); err == nil && ipnet.Contains(ip) {
-				prefix = cidr
-				break
-			}
-		}
+		prefix = amassnet.LongestPrefixMatch(net.ParseIP(addr), netblocks.Slice())
 	}
 	if prefix == "" {
-		prefix = netblocks.Slice()[0]
+		prefix = firstNetblock(netblocks)
 	}
 
-	numRateLimitChecks(n, 3)
+	numRateLimitChecks(ctx, n, 3)
 	req := n.apiASNInfoQuery(ctx, asn)
 	if req == nil {
 		n.sys.Config().Log.Printf("%s: %d: Failed to obtain ASN information", n.String(), asn)
@@ -323,18 +444,37 @@ func (n *NetworksDB) executeAPIASNQuery(ctx context.Context, asn int, addr strin
 	}
 	req.Prefix = prefix
 	req.Netblocks = netblocks.Slice()
+	req.SplitNetblocks()
+	req.NetblockNames = names
+	stats.RecordResult(ctx)
 	n.sys.Cache().Update(req)
 }
 
 func (n *NetworksDB) apiIPQuery(ctx context.Context, addr string) (string, string) {
-	numRateLimitChecks(n, 3)
+	var cidr, id string
+
+	retryEmpty(ctx, n.sys, n, routedAddress(addr), addr, func() bool {
+		var empty bool
+		cidr, id, empty = n.apiIPRequest(ctx, addr)
+		return empty
+	})
+	return cidr, id
+}
+
+func (n *NetworksDB) apiIPRequest(ctx context.Context, addr string) (string, string, bool) {
+	if err := spendBudget(ctx); err != nil {
+		n.sys.Config().Log.Printf("%s: %v", n.String(), err)
+		return "", "", false
+	}
+
+	numRateLimitChecks(ctx, n, 3)
 	u := n.getAPIIPURL()
 	params := url.Values{"ip": {addr}}
 	body := strings.NewReader(params.Encode())
 	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return "", ""
+		return "", "", false
 	}
 
 	var m struct {
@@ -351,24 +491,28 @@ func (n *NetworksDB) apiIPQuery(ctx context.Context, addr string) (string, strin
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return "", ""
+		return "", "", false
 	} else if m.Error != "" {
 		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
-		return "", ""
-	} else if m.Total == 0 || len(m.Results) == 0 {
-		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
-		return "", ""
+		return "", "", false
+	} else if !n.hasResults(u, m.Total, len(m.Results)) {
+		return "", "", m.Total == 0
 	}
 
-	return m.Results[0].Network.CIDR, m.Results[0].Org.ID
+	return m.Results[0].Network.CIDR, m.Results[0].Org.ID, false
 }
 
 func (n *NetworksDB) getAPIIPURL() string {
-	return networksdbBaseURL + networksdbAPIPATH + "/ip/info"
+	return n.baseURL() + networksdbAPIPATH + "/ip/info"
 }
 
 func (n *NetworksDB) apiOrgInfoQuery(ctx context.Context, id string) []int {
-	numRateLimitChecks(n, 3)
+	if err := spendBudget(ctx); err != nil {
+		n.sys.Config().Log.Printf("%s: %v", n.String(), err)
+		return []int{}
+	}
+
+	numRateLimitChecks(ctx, n, 3)
 	u := n.getAPIOrgInfoURL()
 	params := url.Values{"id": {id}}
 	body := strings.NewReader(params.Encode())
@@ -391,7 +535,9 @@ func (n *NetworksDB) apiOrgInfoQuery(ctx context.Context, id string) []int {
 	} else if m.Error != "" {
 		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
 		return []int{}
-	} else if m.Total == 0 || len(m.Results[0].ASNs) == 0 {
+	} else if !n.hasResults(u, m.Total, len(m.Results)) {
+		return []int{}
+	} else if len(m.Results[0].ASNs) == 0 {
 		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
 		return []int{}
 	}
@@ -400,18 +546,202 @@ func (n *NetworksDB) apiOrgInfoQuery(ctx context.Context, id string) []int {
 }
 
 func (n *NetworksDB) getAPIOrgInfoURL() string {
-	return networksdbBaseURL + networksdbAPIPATH + "/org/info"
+	return n.baseURL() + networksdbAPIPATH + "/org/info"
+}
+
+// SearchOrganization finds the organizations matching the provided name, and expands the top
+// matches into the ASNs and netblocks saved in the System cache. The ASNs are returned.
+func (n *NetworksDB) SearchOrganization(org string) []int {
+	ctx := sourceContext(n.sys, n)
+
+	checkRateLimit(ctx, n)
+	return n.orgSearch(ctx, org)
+}
+
+func (n *NetworksDB) orgSearch(ctx context.Context, org string) []int {
+	if !n.hasAPIKey {
+		n.sys.Config().Log.Printf("%s: %s: The organization search requires an API key", n.String(), org)
+		return nil
+	}
+
+	orgs := n.apiOrgSearchQuery(ctx, org)
+	if len(orgs) > networksdbMaxOrgMatches {
+		orgs = orgs[:networksdbMaxOrgMatches]
+	}
+
+	type expansion struct {
+		org networksdbOrg
+		asn int
+	}
+
+	var jobs []expansion
+	for _, o := range orgs {
+		if budgetExhausted(ctx) || n.sys.Stats().QuotaReached(n.String()) {
+			break
+		}
+
+		numRateLimitChecks(ctx, n, 3)
+		for _, asn := range n.apiOrgInfoQuery(ctx, o.ID) {
+			jobs = append(jobs, expansion{org: o, asn: asn})
+		}
+	}
+
+	// Each ASN is expanded into netblocks independently, while the rate limiter is shared
+	var wg sync.WaitGroup
+	var results []int
+	sem := make(chan struct{}, n.sys.Config().NumASNWorkers())
+	for _, job := range jobs {
+		if budgetExhausted(ctx) || n.sys.Stats().QuotaReached(n.String()) {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job expansion) {
+			defer func() { <-sem; wg.Done() }()
+
+			netblocks := stringset.New()
+			defer netblocks.Close()
+
+			n.executeASNQuery(ctx, job.asn, "", netblocks, nil)
+			if entry := networksdbOrgEntry(job.org, job.asn, netblocks.Slice(), n.SourceType, n.String()); entry != nil {
+				n.sys.Cache().Update(entry)
+			}
+		}(job)
+		results = append(results, job.asn)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// firstNetblock returns the lowest of the netblocks, since the order of the set is not stable.
+func firstNetblock(netblocks *stringset.Set) string {
+	cidrs := netblocks.Slice()
+
+	sort.Strings(cidrs)
+	return cidrs[0]
+}
+
+// networksdbOrgEntry returns the cache entry holding the organization metadata and the aggregated netblocks of the ASN.
+func networksdbOrgEntry(o networksdbOrg, asn int, netblocks []string, tag, source string) *requests.ASNRequest {
+	var cidrs []*net.IPNet
+	for _, cidr := range netblocks {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			cidrs = append(cidrs, ipnet)
+		}
+	}
+
+	var aggregated []string
+	for _, ipnet := range amassnet.AggregateCIDRs(cidrs) {
+		aggregated = append(aggregated, ipnet.String())
+	}
+	if len(aggregated) == 0 {
+		return nil
+	}
+
+	desc := o.Name
+	if o.CC != "" {
+		desc += ", " + o.CC
+	}
+	req := &requests.ASNRequest{
+		ASN:         asn,
+		Prefix:      aggregated[0],
+		CC:          o.CC,
+		Description: desc,
+		Netblocks:   aggregated,
+		Tag:         tag,
+		Source:      source,
+	}
+	req.SplitNetblocks()
+	return req
+}
+
+type networksdbOrg struct {
+	ID   string
+	Name string
+	CC   string
+}
+
+// apiOrgSearchQuery returns the organizations matching the search string, ordered by relevance.
+func (n *NetworksDB) apiOrgSearchQuery(ctx context.Context, org string) []networksdbOrg {
+	if err := spendBudget(ctx); err != nil {
+		n.sys.Config().Log.Printf("%s: %v", n.String(), err)
+		return nil
+	}
+
+	numRateLimitChecks(ctx, n, 3)
+	u := n.getAPIOrgSearchURL()
+	params := url.Values{"search": {org}}
+	body := strings.NewReader(params.Encode())
+	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
+	if err != nil {
+		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		return nil
+	}
+
+	var m struct {
+		Error   string `json:"error"`
+		Total   int    `json:"total"`
+		Results []struct {
+			ID          string `json:"id"`
+			Name        string `json:"organisation"`
+			CountryCode string `json:"countrycode"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(page), &m); err != nil {
+		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+		return nil
+	} else if m.Error != "" {
+		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
+		return nil
+	} else if !n.hasResults(u, m.Total, len(m.Results)) {
+		return nil
+	}
+
+	var orgs []networksdbOrg
+	for _, r := range m.Results {
+		if r.ID == "" {
+			continue
+		}
+		orgs = append(orgs, networksdbOrg{
+			ID:   r.ID,
+			Name: strings.TrimSpace(r.Name),
+			CC:   strings.TrimSpace(r.CountryCode),
+		})
+	}
+	return orgs
+}
+
+func (n *NetworksDB) getAPIOrgSearchURL() string {
+	return n.baseURL() + networksdbAPIPATH + "/org/search"
 }
 
 func (n *NetworksDB) apiASNInfoQuery(ctx context.Context, asn int) *requests.ASNRequest {
-	numRateLimitChecks(n, 3)
+	var req *requests.ASNRequest
+
+	retryEmpty(ctx, n.sys, n, publicASN(asn), "AS"+strconv.Itoa(asn), func() bool {
+		var empty bool
+		req, empty = n.apiASNInfoRequest(ctx, asn)
+		return empty
+	})
+	return req
+}
+
+func (n *NetworksDB) apiASNInfoRequest(ctx context.Context, asn int) (*requests.ASNRequest, bool) {
+	if err := spendBudget(ctx); err != nil {
+		n.sys.Config().Log.Printf("%s: %v", n.String(), err)
+		return nil, false
+	}
+
+	numRateLimitChecks(ctx, n, 3)
 	u := n.getAPIASNInfoURL()
 	params := url.Values{"asn": {strconv.Itoa(asn)}}
 	body := strings.NewReader(params.Encode())
 	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return nil
+		return nil, false
 	}
 
 	var m struct {
@@ -427,13 +757,12 @@ func (n *NetworksDB) apiASNInfoQuery(ctx context.Context, asn int) *requests.ASN
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return nil
+		return nil, false
 	} else if m.Error != "" {
 		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
-		return nil
-	} else if m.Total == 0 || len(m.Results) == 0 {
-		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
-		return nil
+		return nil, false
+	} else if !n.hasResults(u, m.Total, len(m.Results)) {
+		return nil, m.Total == 0
 	}
 
 	return &requests.ASNRequest{
@@ -442,52 +771,102 @@ func (n *NetworksDB) apiASNInfoQuery(ctx context.Context, asn int) *requests.ASN
 		Description: m.Results[0].Description + ", " + m.Results[0].CountryCode,
 		Tag:         n.SourceType,
 		Source:      n.String(),
-	}
+	}, false
 }
 
 func (n *NetworksDB) getAPIASNInfoURL() string {
-	return networksdbBaseURL + networksdbAPIPATH + "/as/info"
+	return n.baseURL() + networksdbAPIPATH + "/as/info"
+}
+
+// apiNetblocksQuery returns the netblocks announced by the ASN, along with the network names keyed by CIDR.
+func (n *NetworksDB) apiNetblocksQuery(ctx context.Context, asn int) (*stringset.Set, map[string]string) {
+	var netblocks *stringset.Set
+	var names map[string]string
+
+	retryEmpty(ctx, n.sys, n, publicASN(asn), "AS"+strconv.Itoa(asn), func() bool {
+		if netblocks != nil {
+			netblocks.Close()
+		}
+
+		var empty bool
+		netblocks, names, empty = n.apiNetblocksRequest(ctx, asn)
+		return empty
+	})
+	return netblocks, names
 }
 
-func (n *NetworksDB) apiNetblocksQuery(ctx context.Context, asn int) *stringset.Set {
+func (n *NetworksDB) apiNetblocksRequest(ctx context.Context, asn int) (*stringset.Set, map[string]string, bool) {
 	netblocks := stringset.New()
+	if err := spendBudget(ctx); err != nil {
+		n.sys.Config().Log.Printf("%s: %v", n.String(), err)
+		return netblocks, nil, false
+	}
 
-	numRateLimitChecks(n, 3)
+	numRateLimitChecks(ctx, n, 3)
 	u := n.getAPINetblocksURL()
 	params := url.Values{"asn": {strconv.Itoa(asn)}}
 	body := strings.NewReader(params.Encode())
 	page, err := http.RequestWebPage(ctx, u, body, n.getHeaders(), nil)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return netblocks
+		return netblocks, nil, false
 	}
 
 	var m struct {
 		Error   string `json:"error"`
 		Total   int    `json:"total"`
 		Results []struct {
-			CIDR string `json:"cidr"`
+			CIDR        string `json:"cidr"`
+			Name        string `json:"netname"`
+			Description string `json:"description"`
 		} `json:"results"`
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
-		return netblocks
+		return netblocks, nil, false
 	} else if m.Error != "" {
 		n.sys.Config().Log.Printf("%s: %s: %s", n.String(), u, m.Error)
-		return netblocks
-	} else if m.Total == 0 || len(m.Results) == 0 {
-		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
-		return netblocks
+		return netblocks, nil, false
+	} else if !n.hasResults(u, m.Total, len(m.Results)) {
+		return netblocks, nil, m.Total == 0
 	}
 
+	names := make(map[string]string)
 	for _, block := range m.Results {
-		netblocks.Insert(block.CIDR)
+		cidr := amassnet.CanonicalCIDR(block.CIDR)
+		if cidr == "" {
+			continue
+		}
+		netblocks.Insert(cidr)
+
+		name := strings.TrimSpace(block.Name)
+		if name == "" {
+			name = strings.TrimSpace(block.Description)
+		}
+		if name != "" {
+			names[cidr] = name
+		}
 	}
-	return netblocks
+	return netblocks, names, false
 }
 
 func (n *NetworksDB) getAPINetblocksURL() string {
-	return networksdbBaseURL + networksdbAPIPATH + "/as/networks"
+	return n.baseURL() + networksdbAPIPATH + "/as/networks"
+}
+
+// hasResults checks the result count of an API response envelope, and logs the responses without
+// usable results, including partial responses that claim results missing from the envelope.
+func (n *NetworksDB) hasResults(u string, total, num int) bool {
+	if num > 0 {
+		return true
+	}
+
+	if total > 0 {
+		n.sys.Config().Log.Printf("%s: %s: The response claimed %d results, but provided none", n.String(), u, total)
+	} else {
+		n.sys.Config().Log.Printf("%s: %s: The request returned zero results", n.String(), u)
+	}
+	return false
 }
 
 func (n *NetworksDB) getHeaders() map[string]string {
@@ -501,14 +880,22 @@ func (n *NetworksDB) getHeaders() map[string]string {
 	}
 }
 
+// scrapeHeaders returns the session cookies and headers provided with the credentials, if any.
+func (n *NetworksDB) scrapeHeaders() map[string]string {
+	if n.creds == nil {
+		return nil
+	}
+	return n.creds.HTTPHeaders()
+}
+
 func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisRequest) {
 	if !n.sys.Config().IsDomainInScope(req.Domain) {
 		return
 	}
 
-	numRateLimitChecks(n, 2)
+	numRateLimitChecks(ctx, n, 2)
 	u := n.getDomainToIPURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	page, err := n.scrapeWebPage(ctx, u)
 	if err != nil {
 		n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
 		return
@@ -520,26 +907,30 @@ func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisReques
 		return
 	}
 
-	newdomains := stringset.New()
-	defer newdomains.Close()
-
-	re := dns.AnySubdomainRegex()
+	// The domains hosted in more of the netblocks of the domain, and in the smaller ones, are the
+	// most likely to be related, rather than the other tenants of a large hosting provider
+	newdomains := make(relatedDomains)
+	netblocks := make(map[string][]string)
 	for _, match := range matches {
 		if len(match) < 2 {
 			continue
 		}
 
-		numRateLimitChecks(n, 3)
-		u = networksdbBaseURL + match[1]
-		page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+		numRateLimitChecks(ctx, n, 3)
+		u = n.baseURL() + match[1]
+		page, err = n.scrapeWebPage(ctx, u)
 		if err != nil {
 			n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+			if errors.Is(err, errBudgetExhausted) || errors.Is(err, stats.ErrQuotaReached) {
+				break
+			}
 			continue
 		}
 
 		cidrMatch := networksdbIPPageCIDRRE.FindStringSubmatch(page)
 		if cidrMatch == nil || len(cidrMatch) < 2 {
 			n.sys.Config().Log.Printf("%s: %s: Failed to extract the CIDR", n.String(), u)
+			recordExtraction(ctx, n.sys, n, false)
 			continue
 		}
 
@@ -548,44 +939,83 @@ func (n *NetworksDB) whoisRequest(ctx context.Context, req *requests.WhoisReques
 			continue
 		}
 
-		numRateLimitChecks(n, 3)
+		numRateLimitChecks(ctx, n, 3)
 		first, last := amassnet.FirstLast(cidr)
 		u := n.getDomainsInNetworkURL(first.String(), last.String())
 
-		page, err = http.RequestWebPage(ctx, u, nil, nil, nil)
+		page, err = n.scrapeWebPage(ctx, u)
 		if err != nil {
 			n.sys.Config().Log.Printf("%s: %s: %v", n.String(), u, err)
+			if errors.Is(err, errBudgetExhausted) || errors.Is(err, stats.ErrQuotaReached) {
+				break
+			}
 			continue
 		}
 
 		domainsPos := networksdbDomainsRE.FindStringIndex(page)
-		tablePos := networksdbTableRE.FindStringIndex(page)
+		var tablePos []int
+		if domainsPos != nil {
+			// The section ends at the first table following the heading
+			if pos := networksdbTableRE.FindStringIndex(page[domainsPos[1]:]); pos != nil {
+				tablePos = []int{domainsPos[1] + pos[0], domainsPos[1] + pos[1]}
+			}
+		}
 		if domainsPos == nil || tablePos == nil || len(domainsPos) < 2 || len(tablePos) < 2 {
 			n.sys.Config().Log.Printf("%s: %s: Failed to extract the domain section of the page", n.String(), u)
+			recordExtraction(ctx, n.sys, n, false)
 			continue
 		}
+		recordExtraction(ctx, n.sys, n, true)
 
 		start := domainsPos[1]
 		end := tablePos[1]
-		for _, d := range re.FindAllString(page[start:end], -1) {
-			newdomains.Insert(strings.TrimSpace(d))
+		ones, _ := cidr.Mask.Size()
+		for _, d := range n.hostedDomains(page[start:end]) {
+			newdomains.add(d, networksdbNetblockWeight+ones)
+			netblocks[cidr.String()] = append(netblocks[cidr.String()], d)
 		}
 	}
 
-	if len(newdomains.Slice()) > 0 {
-		n.Output() <- &requests.WhoisRequest{
+	if len(newdomains) > 0 {
+		stats.RecordResult(ctx)
+		sendOutput(ctx, n.sys, n, &requests.WhoisRequest{
 			Domain:     req.Domain,
-			NewDomains: newdomains.Slice(),
+			NewDomains: capWhoisDomains(n.sys, n, req.Domain, newdomains),
+			Netblocks:  netblocks,
 			Tag:        n.SourceType,
 			Source:     n.String(),
+		})
+	}
+}
+
+// hostedDomains returns the domains listed in the section of the domains-in-network page.
+// A name is only accepted when it makes up the whole text of an element, such as a table
+// cell or the link to the domain, which leaves out the names found in the URLs, scripts and
+// prose around the listing, and the names of NetworksDB itself are never reported.
+func (n *NetworksDB) hostedDomains(section string) []string {
+	site := "networksdb.io"
+	if u, err := url.Parse(n.baseURL()); err == nil && u.Hostname() != "" {
+		site = u.Hostname()
+	}
+
+	var domains []string
+	for _, match := range networksdbTextRE.FindAllStringSubmatch(section, -1) {
+		d := strings.ToLower(strings.TrimSpace(match[1]))
+		if !networksdbDomainRE.MatchString(d) {
+			continue
+		}
+		if d == site || strings.HasSuffix(d, "."+site) || d == "networksdb.io" || strings.HasSuffix(d, ".networksdb.io") {
+			continue
 		}
+		domains = append(domains, d)
 	}
+	return domains
 }
 
 func (n *NetworksDB) getDomainToIPURL(domain string) string {
-	return networksdbBaseURL + "/domain-to-ips/" + domain
+	return n.baseURL() + "/domain-to-ips/" + domain
 }
 
 func (n *NetworksDB) getDomainsInNetworkURL(first, last string) string {
-	return networksdbBaseURL + "/domains-in-network/" + first + "/" + last
+	return n.baseURL() + "/domains-in-network/" + first + "/" + last
 }
@@ -0,0 +1,453 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	nethttp "net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+func TestNetworksDBNetblockNames(t *testing.T) {
+	page := `<b>Network:</b> <a class="link_sm" href="/networks/org/google">GOOGLE</a> ` +
+		`<a class="link_sm" href="/networks/8.8.8.0-8.8.8.255">8.8.8.0/24</a>`
+	if names := networksdbIPPageNetworkName(page); len(names) != 1 || names["8.8.8.0/24"] != "GOOGLE" {
+		t.Errorf("Failed to extract the network name from the IP page: %v", names)
+	}
+
+	_ = serveResponses(t, func(path string) string {
+		return `{"total":2,"results":[{"cidr":"8.8.8.0/24","netname":"LVLT-GOGL-8-8-8"},` +
+			`{"cidr":"8.8.4.0/24","netname":"","description":"Google LLC"}]}`
+	})
+
+	n := NewNetworksDB(testSystem())
+	defer func() { _ = n.Stop() }()
+	n.creds = &config.Credentials{Key: "fake"}
+
+	netblocks, names := n.apiNetblocksQuery(context.Background(), 15169)
+	defer netblocks.Close()
+
+	if netblocks.Len() != 2 || names["8.8.8.0/24"] != "LVLT-GOGL-8-8-8" || names["8.8.4.0/24"] != "Google LLC" {
+		t.Errorf("Failed to extract the network names from the API response: %v", names)
+	}
+}
+
+func TestNetworksDBCanonicalNetblocks(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		return `{"total":4,"results":[{"cidr":"2001:DB8::/32","netname":"EXAMPLE-V6"},` +
+			`{"cidr":" 2001:db8:0:0::/32 ","netname":"EXAMPLE-V6"},` +
+			`{"cidr":"8.8.8.0/24","netname":"LVLT-GOGL-8-8-8"},{"cidr":"8.8.8.1/24"}]}`
+	})
+
+	n := NewNetworksDB(testSystem())
+	defer func() { _ = n.Stop() }()
+	n.creds = &config.Credentials{Key: "fake"}
+
+	netblocks, names := n.apiNetblocksQuery(context.Background(), 15169)
+	defer netblocks.Close()
+
+	if netblocks.Len() != 2 || !netblocks.Has("2001:db8::/32") || !netblocks.Has("8.8.8.0/24") {
+		t.Errorf("The equivalent netblocks were not deduplicated: %v", netblocks.Slice())
+	}
+	if names["2001:db8::/32"] != "EXAMPLE-V6" || names["8.8.8.0/24"] != "LVLT-GOGL-8-8-8" {
+		t.Errorf("The network names were not keyed by the canonical netblocks: %v", names)
+	}
+}
+
+func TestNetworksDBOrgSearch(t *testing.T) {
+	var orgs []string
+	for i := 0; i < 2*networksdbMaxOrgMatches; i++ {
+		orgs = append(orgs, fmt.Sprintf(`{"id":"org-%d","organisation":"Google LLC","countrycode":"US"}`, i))
+	}
+
+	var infos int64
+	_ = serveResponses(t, func(path string) string {
+		switch {
+		case strings.HasSuffix(path, "/org/search"):
+			return fmt.Sprintf(`{"total":%d,"results":[%s]}`, len(orgs), strings.Join(orgs, ","))
+		case strings.HasSuffix(path, "/org/info"):
+			atomic.AddInt64(&infos, 1)
+			return `{"total":1,"results":[{"asns":[15169]}]}`
+		}
+		return "AS Name:</b> GOOGLE<br>\nLocation:</b> <a href=\"/country/US\">\n" +
+			"CIDR:</b> 8.8.8.0/24<br>\nCIDR:</b> 8.8.9.0/24<br>\n"
+	})
+
+	sys := testSystem()
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+	n.creds = &config.Credentials{Key: "fake"}
+
+	asns := n.orgSearch(withQueryBudget(context.Background(), defaultQueryBudget), "Google")
+	if got := atomic.LoadInt64(&infos); got != networksdbMaxOrgMatches {
+		t.Errorf("Expanded %d organizations, expected the cap of %d", got, networksdbMaxOrgMatches)
+	}
+	if len(asns) != networksdbMaxOrgMatches || asns[0] != 15169 {
+		t.Errorf("Unexpected ASNs returned by the organization search: %v", asns)
+	}
+
+	entry := sys.Cache().ASNSearch(15169)
+	if entry == nil {
+		t.Fatalf("The ASN was not saved in the cache")
+	}
+	var aggregated bool
+	for _, cidr := range entry.Netblocks {
+		if cidr == "8.8.8.0/23" {
+			aggregated = true
+		}
+	}
+	if !aggregated || !strings.Contains(entry.Description, "Google LLC") {
+		t.Errorf("The cache entry is missing the aggregated netblocks or organization: %+v", entry)
+	}
+	if len(entry.IPv4Netblocks) != len(entry.Netblocks) || len(entry.IPv6Netblocks) != 0 {
+		t.Errorf("The netblocks were not separated by address family: %+v", entry)
+	}
+}
+
+func TestNetworksDBPartialEnvelopes(t *testing.T) {
+	bodies := []string{
+		`{"total":3,"results":[]}`,
+		`{"total":3}`,
+		`{"total":1,"results":[{}]}`,
+		`{"total":1,"results":[{"asns":null}]`,
+		``,
+	}
+
+	for _, body := range bodies {
+		b := body
+		_ = serveResponses(t, func(path string) string { return b })
+
+		n := NewNetworksDB(testSystem())
+		n.creds = &config.Credentials{Key: "fake"}
+		ctx := context.Background()
+
+		if cidr, id := n.apiIPQuery(ctx, "8.8.8.8"); cidr != "" || id != "" {
+			t.Errorf("%s: apiIPQuery returned %s, %s", b, cidr, id)
+		}
+		if asns := n.apiOrgInfoQuery(ctx, "google"); len(asns) != 0 {
+			t.Errorf("%s: apiOrgInfoQuery returned %v", b, asns)
+		}
+		if orgs := n.apiOrgSearchQuery(ctx, "Google"); len(orgs) != 0 {
+			t.Errorf("%s: apiOrgSearchQuery returned %v", b, orgs)
+		}
+		if req := n.apiASNInfoQuery(ctx, 15169); req != nil && req.ASN != 0 {
+			t.Errorf("%s: apiASNInfoQuery returned %v", b, req)
+		}
+		netblocks, names := n.apiNetblocksQuery(ctx, 15169)
+		if len(names) != 0 {
+			t.Errorf("%s: apiNetblocksQuery returned the names %v", b, names)
+		}
+		netblocks.Close()
+		_ = n.Stop()
+	}
+}
+
+func TestNetworksDBEmptyRetries(t *testing.T) {
+	var empty int64 = 1
+	count := serveResponses(t, func(path string) string {
+		if atomic.AddInt64(&empty, -1) >= 0 {
+			return `{"total":0,"results":[]}`
+		}
+		return `{"total":1,"results":[{"organisation":{"id":"google"},"network":{"cidr":"8.8.8.0/24"}}]}`
+	})
+
+	sys := testSystem()
+	dsc := sys.Config().GetDataSourceConfig("NetworksDB")
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+	n.creds = &config.Credentials{Key: "fake"}
+
+	// The query is not repeated unless the option was enabled for the data source
+	if cidr, _ := n.apiIPQuery(context.Background(), "8.8.8.8"); cidr != "" || atomic.LoadInt64(count) != 1 {
+		t.Errorf("The query was repeated without the option: %d requests", atomic.LoadInt64(count))
+	}
+
+	dsc.EmptyRetries = 2
+	dsc.EmptyRetryDelay = 1
+	atomic.StoreInt64(&empty, 1)
+	atomic.StoreInt64(count, 0)
+	start := time.Now()
+	if cidr, id := n.apiIPQuery(context.Background(), "8.8.8.8"); cidr != "8.8.8.0/24" || id != "google" {
+		t.Errorf("The repeated query did not return the results: %s, %s", cidr, id)
+	}
+	if c := atomic.LoadInt64(count); c != 2 || time.Since(start) < time.Second {
+		t.Errorf("Expected a single repeat after the delay, got %d requests in %v", c, time.Since(start))
+	}
+
+	// Addresses that cannot be routed are not expected to have data
+	atomic.StoreInt64(&empty, 5)
+	atomic.StoreInt64(count, 0)
+	if cidr, _ := n.apiIPQuery(context.Background(), "192.168.1.1"); cidr != "" || atomic.LoadInt64(count) != 1 {
+		t.Errorf("The query for the reserved address was repeated: %d requests", atomic.LoadInt64(count))
+	}
+	atomic.StoreInt64(count, 0)
+	if req := n.apiASNInfoQuery(context.Background(), 64512); req != nil || atomic.LoadInt64(count) != 1 {
+		t.Errorf("The query for the private ASN was repeated: %d requests", atomic.LoadInt64(count))
+	}
+}
+
+func TestNetworksDBBrokenScrape(t *testing.T) {
+	var layout atomic.Value
+	layout.Store("<html>The site has been redesigned</html>")
+	_ = serveResponses(t, func(path string) string { return layout.Load().(string) })
+
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Collector = stats.NewCollector()
+	sys.Collector.SetExtractionLimit(3)
+	sys.Cfg.Log = log.New(ioutil.Discard, "", 0)
+
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+
+	ctx := stats.NewContext(context.Background(), sys.Collector, n.String())
+	for i := 0; i < 2; i++ {
+		n.executeASNQuery(ctx, 15169, "", stringset.New(), nil)
+	}
+	if sys.Collector.Broken(n.String()) {
+		t.Errorf("The source was marked broken before reaching the limit")
+	}
+
+	// A page that still matches resets the consecutive failures
+	layout.Store("AS Name:</b> GOOGLE<br>\nLocation:</b> <a href=\"/country/US\">\nCIDR:</b> 8.8.8.0/24<br>\n")
+	n.executeASNQuery(ctx, 15169, "", stringset.New(), nil)
+
+	layout.Store("<html>The site has been redesigned</html>")
+	for i := 0; i < 3; i++ {
+		n.executeASNQuery(ctx, 15169, "", stringset.New(), nil)
+	}
+	if !sys.Collector.Broken(n.String()) {
+		t.Errorf("The source was not marked broken after the consecutive extraction failures")
+	}
+}
+
+func TestNetworksDBScrapeRateLimited(t *testing.T) {
+	var requests int32
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		resp := &nethttp.Response{
+			StatusCode: 200,
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("AS Name:</b> GOOGLE<br>")),
+			Request:    req,
+		}
+		// The first requests are rejected until the limit is lifted
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			resp.StatusCode = 429
+			resp.Status = "429 Too Many Requests"
+			resp.Header.Set("Retry-After", "1")
+			resp.Body = ioutil.NopCloser(strings.NewReader(""))
+		}
+		return resp
+	})
+	t.Cleanup(func() { http.DefaultClient.Transport = orig })
+
+	sys := testSystem()
+	logs := new(bytes.Buffer)
+	sys.Config().Log = log.New(logs, "", 0)
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+
+	page, err := n.scrapeWebPage(context.Background(), n.getASNURL(15169))
+	if err != nil || !strings.Contains(page, "GOOGLE") {
+		t.Errorf("The page was not obtained once the limit was lifted: %v", err)
+	}
+	if c := strings.Count(logs.String(), "Rate limited"); c != 1 {
+		t.Errorf("The rate limiting was logged %d times instead of once: %s", c, logs.String())
+	}
+}
+
+func TestNetworksDBWhoisNetblocks(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		switch {
+		case strings.HasPrefix(path, "/domain-to-ips/"):
+			return `<a class="link_sm" href="/ip/192.0.2.10">192.0.2.10</a>`
+		case strings.HasPrefix(path, "/ip/"):
+			return `<b>Network:</b> <a class="link_sm" href="/networks/org/owasp">OWASP</a> ` +
+				`<a class="link_sm" href="/networks/192.0.2.0-192.0.2.255">192.0.2.0/24</a>`
+		case strings.HasPrefix(path, "/domains-in-network/"):
+			return `Domains in network <td>example.com</td><td>example.net</td><table class="x">`
+		}
+		return ""
+	})
+
+	n := NewNetworksDB(testSystem())
+	defer func() { _ = n.Stop() }()
+
+	done := make(chan *requests.WhoisRequest, 1)
+	go func() {
+		select {
+		case out := <-n.Output():
+			done <- out.(*requests.WhoisRequest)
+		case <-time.After(time.Second):
+			done <- nil
+		}
+	}()
+
+	n.whoisRequest(context.Background(), &requests.WhoisRequest{Domain: "owasp.org"})
+	req := <-done
+	if req == nil {
+		t.Fatal("The whois request did not provide the domains found")
+	}
+	if domains := req.Netblocks["192.0.2.0/24"]; fmt.Sprint(domains) != "[example.com example.net]" {
+		t.Errorf("The domains were not provided with the netblock hosting them: %v", req.Netblocks)
+	}
+}
+
+func TestNetworksDBWhoisExtraneousDomains(t *testing.T) {
+	page := `<html><head><script src="https://cdn.jsdelivr.net/npm/jquery.min.js"></script></head>` +
+		`<nav><a href="/">networksdb.io</a> <a href="https://twitter.com/networksdb">Twitter</a></nav>` +
+		`<table class="nav"><tr><td>stats.networksdb.io</td></tr></table>` +
+		`<h1>Domains in network 192.0.2.0 - 192.0.2.255</h1>` +
+		`<p>Sponsored by hosting.example.org, the listing below is updated daily.</p>` +
+		`<div><a class="link_sm" href="/domain/example.com">example.com</a></div>` +
+		`<div><a class="link_sm" href="/domain/www.example.net">WWW.Example.NET</a></div>` +
+		`<script>var tracker = "metrics.example.io";</script>` +
+		`<table class="results"><tr><td>footer.example.info</td></tr></table>` +
+		`<footer>Contact abuse@networksdb.io or visit status.example.biz</footer></html>`
+
+	_ = serveResponses(t, func(path string) string {
+		switch {
+		case strings.HasPrefix(path, "/domain-to-ips/"):
+			return `<a class="link_sm" href="/ip/192.0.2.10">192.0.2.10</a>`
+		case strings.HasPrefix(path, "/ip/"):
+			return `<b>Network:</b> <a class="link_sm" href="/networks/org/owasp">OWASP</a> ` +
+				`<a class="link_sm" href="/networks/192.0.2.0-192.0.2.255">192.0.2.0/24</a>`
+		case strings.HasPrefix(path, "/domains-in-network/"):
+			return page
+		}
+		return ""
+	})
+
+	n := NewNetworksDB(testSystem())
+	defer func() { _ = n.Stop() }()
+
+	done := make(chan *requests.WhoisRequest, 1)
+	go func() {
+		select {
+		case out := <-n.Output():
+			done <- out.(*requests.WhoisRequest)
+		case <-time.After(time.Second):
+			done <- nil
+		}
+	}()
+
+	n.whoisRequest(context.Background(), &requests.WhoisRequest{Domain: "owasp.org"})
+	req := <-done
+	if req == nil {
+		t.Fatal("The whois request did not provide the domains found")
+	}
+	if domains := req.Netblocks["192.0.2.0/24"]; fmt.Sprint(domains) != "[example.com www.example.net]" {
+		t.Errorf("The domains outside of the listing were not left out: %v", domains)
+	}
+}
+
+func TestNetworksDBWhoisDomainCap(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		switch {
+		case strings.HasPrefix(path, "/domain-to-ips/"):
+			return `<a class="link_sm" href="/ip/192.0.2.10">192.0.2.10</a>` +
+				`<a class="link_sm" href="/ip/198.51.100.10">198.51.100.10</a>`
+		case strings.HasPrefix(path, "/ip/192.0.2.10"):
+			return `<b>Network:</b> <a class="link_sm" href="/networks/org/host">Host</a> ` +
+				`<a class="link_sm" href="/networks/192.0.2.0-192.0.2.255">192.0.2.0/24</a>`
+		case strings.HasPrefix(path, "/ip/"):
+			return `<b>Network:</b> <a class="link_sm" href="/networks/org/owasp">OWASP</a> ` +
+				`<a class="link_sm" href="/networks/198.51.100.0-198.51.100.15">198.51.100.0/28</a>`
+		case strings.HasPrefix(path, "/domains-in-network/192.0.2.0/"):
+			return `Domains in network <td>a-tenant.com</td><td>owasp.net</td><td>b-tenant.com</td><table class="x">`
+		case strings.HasPrefix(path, "/domains-in-network/"):
+			return `Domains in network <td>owasp.net</td><td>owaspfoundation.com</td><table class="x">`
+		}
+		return ""
+	})
+
+	sys := testSystem()
+	sys.Config().MaxWhoisDomains = 2
+	logs := new(strings.Builder)
+	sys.Config().Log = log.New(logs, "", 0)
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+
+	done := make(chan *requests.WhoisRequest, 1)
+	go func() {
+		select {
+		case out := <-n.Output():
+			done <- out.(*requests.WhoisRequest)
+		case <-time.After(time.Second):
+			done <- nil
+		}
+	}()
+
+	n.whoisRequest(context.Background(), &requests.WhoisRequest{Domain: "owasp.org"})
+	req := <-done
+	if req == nil {
+		t.Fatal("The whois request did not provide the domains found")
+	}
+	// The domain in both netblocks comes first, followed by the one in the smaller netblock
+	if fmt.Sprint(req.NewDomains) != "[owasp.net owaspfoundation.com]" {
+		t.Errorf("The cap kept the domains %v", req.NewDomains)
+	}
+	if len(req.Netblocks["192.0.2.0/24"]) != 3 {
+		t.Errorf("The domains hosted in the netblocks were capped: %v", req.Netblocks)
+	}
+	if !strings.Contains(logs.String(), "Kept 2 of the 4 related domains") {
+		t.Errorf("The cap was reached without a notice: %s", logs.String())
+	}
+}
+
+func TestNetworksDBMode(t *testing.T) {
+	tests := []struct {
+		mode   string
+		key    string
+		scrape bool
+		fails  bool
+	}{
+		{mode: config.ModeAuto, key: "fake", scrape: false},
+		{mode: config.ModeAuto, scrape: true},
+		{mode: config.ModeScrape, key: "fake", scrape: true},
+		{mode: config.ModeAPI, key: "fake", scrape: false},
+		{mode: config.ModeAPI, fails: true},
+	}
+
+	for _, test := range tests {
+		sys := testSystem()
+		dsc := sys.Config().GetDataSourceConfig("NetworksDB")
+		dsc.Mode = test.mode
+		if test.key != "" {
+			_ = dsc.AddCredentials(&config.Credentials{Name: "Credentials", Key: test.key})
+		}
+
+		n := NewNetworksDB(sys)
+		err := n.OnStart()
+		_ = n.Stop()
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("The %s mode without an API key was accepted", test.mode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("The %s mode failed to start: %v", test.mode, err)
+		}
+		if scrape := !n.hasAPIKey; scrape != test.scrape || (n.SourceType == requests.SCRAPE) != test.scrape {
+			t.Errorf("The %s mode with key %q selected scraping %t, expected %t", test.mode, test.key, scrape, test.scrape)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// outputBuffer holds the results of a data source until the enumeration is ready for them,
+// so a slow consumer does not stall the source while there is room in the buffer.
+type outputBuffer struct {
+	srv    service.Service
+	ch     chan interface{}
+	drop   bool
+	warned int32
+}
+
+var outputBuffers = struct {
+	sync.Mutex
+	m map[service.Service]*outputBuffer
+}{m: make(map[service.Service]*outputBuffer)}
+
+// sendOutput provides a result of the data source to the enumeration. Once the output buffer of
+// the source is full, the result is dropped when the configuration selects the drop strategy, and
+// otherwise the source waits for room, until the context expires or the source is stopped.
+func sendOutput(ctx context.Context, sys systems.System, srv service.Service, req interface{}) {
+	// The discovery is logged when the source emits it, even if the result is dropped below
+	sys.EventLog().RecordOutput(srv.String(), req)
+	filterNewDomains(sys, srv.String(), req)
+	b := outputBufferFor(sys, srv)
+
+	select {
+	case b.ch <- req:
+		sys.Stats().OutputDepth(srv.String(), len(b.ch))
+		return
+	default:
+	}
+
+	sys.Stats().OutputDepth(srv.String(), cap(b.ch))
+	if b.drop {
+		sys.Stats().OutputDropped(srv.String())
+		if atomic.CompareAndSwapInt32(&b.warned, 0, 1) {
+			sys.Config().Log.Printf("%s: WARNING: The output buffer is full, so results are being dropped", srv.String())
+		}
+		return
+	}
+
+	start := time.Now()
+	select {
+	case b.ch <- req:
+	case <-ctx.Done():
+	case <-srv.Done():
+	}
+	sys.Stats().OutputBlocked(srv.String(), time.Since(start))
+}
+
+// filterNewDomains removes the related domains of a WhoisRequest that another data source, or
+// the same one, already reported, so they are not expanded through reverse whois again.
+func filterNewDomains(sys systems.System, source string, req interface{}) {
+	if w, ok := req.(*requests.WhoisRequest); ok {
+		w.NewDomains = sys.DomainFilter().Filter(source, w.NewDomains)
+	}
+}
+
+// outputBufferFor returns the output buffer of the data source, creating it on first use.
+func outputBufferFor(sys systems.System, srv service.Service) *outputBuffer {
+	outputBuffers.Lock()
+	defer outputBuffers.Unlock()
+
+	if b, found := outputBuffers.m[srv]; found {
+		return b
+	}
+
+	b := &outputBuffer{
+		srv:  srv,
+		ch:   make(chan interface{}, sys.Config().OutputBufferSize()),
+		drop: sys.Config().DropOutput(),
+	}
+	outputBuffers.m[srv] = b
+	go b.forward()
+	return b
+}
+
+// forward moves the buffered results to the output channel of the data source, in the order
+// they were provided, until the source is stopped.
+func (b *outputBuffer) forward() {
+	defer func() {
+		outputBuffers.Lock()
+		delete(outputBuffers.m, b.srv)
+		outputBuffers.Unlock()
+	}()
+
+	for {
+		select {
+		case <-b.srv.Done():
+			return
+		case req := <-b.ch:
+			select {
+			case <-b.srv.Done():
+				return
+			case b.srv.Output() <- req:
+			}
+		}
+	}
+}
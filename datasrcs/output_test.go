@@ -0,0 +1,112 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+func TestSendOutputDrop(t *testing.T) {
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Collector = stats.NewCollector()
+	sys.Cfg.OutputBuffer = 1
+	sys.Cfg.OutputBackpressure = config.OutputBackpressureDrop
+	logs := new(bytes.Buffer)
+	sys.Cfg.Log = log.New(logs, "", 0)
+
+	c := NewCloudflare(sys)
+	defer func() { _ = c.Stop() }()
+
+	// Nothing reads the output channel, so the results beyond it and the buffer are dropped
+	num := 30
+	for i := 0; i < num; i++ {
+		sendOutput(context.Background(), sys, c, &requests.DNSRequest{Name: "www.owasp.org"})
+	}
+
+	st := sys.Collector.Source(c.String())
+	if accepted := int64(num) - st.OutputDropped; accepted > int64(cap(c.Output())+2) {
+		t.Errorf("%d results were accepted beyond the output buffer", accepted)
+	}
+	if st.OutputDepthMax != 1 {
+		t.Errorf("The output buffer depth was not recorded: %d", st.OutputDepthMax)
+	}
+	if n := strings.Count(logs.String(), "WARNING"); n != 1 {
+		t.Errorf("Expected a single warning about the dropped results, got %d", n)
+	}
+}
+
+func TestSendOutputBlock(t *testing.T) {
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Collector = stats.NewCollector()
+	sys.Cfg.OutputBuffer = 1
+
+	c := NewCloudflare(sys)
+	defer func() { _ = c.Stop() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < cap(c.Output())+3; i++ {
+		sendOutput(ctx, sys, c, &requests.DNSRequest{Name: "www.owasp.org"})
+	}
+	if ctx.Err() == nil {
+		t.Fatal("The data source was not held back once the output buffer was full")
+	}
+
+	st := sys.Collector.Source(c.String())
+	if st.OutputDropped != 0 || st.OutputBlockedMS == 0 {
+		t.Errorf("Unexpected output metrics for the block strategy: %+v", st)
+	}
+
+	// Once the enumeration reads the results, the data source can continue
+	<-c.Output()
+	done := make(chan struct{})
+	go func() {
+		sendOutput(context.Background(), sys, c, &requests.DNSRequest{Name: "www.owasp.org"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("The data source remained blocked after results were read")
+	}
+}
+
+func TestSendOutputNewDomains(t *testing.T) {
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Domains = requests.NewDomainFilter(0)
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+
+	ctx := context.Background()
+	sendOutput(ctx, sys, u, &requests.WhoisRequest{Domain: "owasp.org", NewDomains: []string{"owasp.net", "owaspfoundation.com"}})
+	// The domain only NetworksDB reports is passed on, while the repeat is removed
+	sendOutput(ctx, sys, n, &requests.WhoisRequest{Domain: "owasp.org", NewDomains: []string{"owasp.net", "owasp.info"}})
+
+	first := (<-u.Output()).(*requests.WhoisRequest)
+	second := (<-n.Output()).(*requests.WhoisRequest)
+	if got := strings.Join(first.NewDomains, ","); got != "owasp.net,owaspfoundation.com" {
+		t.Errorf("The first report passed on %s", got)
+	}
+	if got := strings.Join(second.NewDomains, ","); got != "owasp.info" {
+		t.Errorf("The second report passed on %s", got)
+	}
+	if got := strings.Join(sys.Domains.Sources("owasp.net"), ","); got != n.String()+","+u.String() {
+		t.Errorf("The sources reporting owasp.net were %s", got)
+	}
+}
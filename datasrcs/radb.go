@@ -9,6 +9,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -16,16 +18,20 @@ import (
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 	"github.com/miekg/dns"
 )
 
 const (
 	// radbWhoisURL is the URL for the RADb whois server.
 	radbWhoisURL = "whois.radb.net"
+	// The port of the whois protocol, and the time allowed for an entire exchange with the server
+	radbWhoisPort    = "43"
+	radbWhoisTimeout = 10 * time.Second
 )
 
 // RADb is the Service that handles access to the RADb data source.
@@ -57,7 +63,7 @@ func (r *RADb) Description() string {
 // OnStart implements the Service interface.
 func (r *RADb) OnStart() error {
 	msg := resolve.QueryMsg(radbWhoisURL, dns.TypeA)
-	if resp, err := r.sys.TrustedResolvers().QueryBlocking(context.TODO(), msg); err == nil {
+	if resp, err := r.sys.TrustedResolvers().QueryBlocking(r.sys.Context(), msg); err == nil {
 		if ans := resolve.ExtractAnswers(resp); len(ans) > 0 {
 			ip := ans[0].Data
 			if ip != "" {
@@ -65,7 +71,7 @@ func (r *RADb) OnStart() error {
 			}
 		}
 	}
-	r.SetRateLimit(1)
+	setRateLimit(r.sys, r, 1)
 	return nil
 }
 
@@ -93,10 +99,11 @@ func (r *RADb) requests() {
 		case <-r.Done():
 			return
 		case in := <-r.Input():
+			ctx := sourceContext(r.sys, r)
 			switch req := in.(type) {
 			case *requests.ASNRequest:
-				r.CheckRateLimit()
-				r.asnRequest(context.TODO(), req)
+				checkRateLimit(ctx, r)
+				r.asnRequest(ctx, req)
 			}
 		}
 	}
@@ -107,7 +114,7 @@ func (r *RADb) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 		return
 	}
 
-	r.CheckRateLimit()
+	checkRateLimit(ctx, r)
 	if req.Address != "" {
 		r.executeASNAddrQuery(ctx, req.Address)
 		return
@@ -169,13 +176,63 @@ func (r *RADb) executeASNQuery(ctx context.Context, asn int, addr, prefix string
 		return
 	}
 
-	numRateLimitChecks(r, 2)
+	blocks := stringset.New()
+	defer blocks.Close()
+
+	if prefix != "" {
+		blocks.Insert(prefix)
+	}
+
+	// The registration data is only available for the ASNs managed by ARIN, while the
+	// routing registries hold the routes of every ASN
+	desc, at, found := r.autnum(ctx, asn)
+	if found {
+		numRateLimitChecks(ctx, r, 2)
+		nb := r.netblocks(ctx, asn)
+		blocks.Union(nb)
+		nb.Close()
+	}
+
+	routes, err := r.irrRoutes(ctx, asn)
+	if err != nil {
+		r.sys.Config().Log.Printf("%s: AS%d: %v", r.String(), asn, err)
+	}
+	var known []string
+	if as := r.sys.Cache().ASNSearch(asn); as != nil {
+		known = as.Netblocks
+	}
+	blocks.InsertMany(newRoutes(append(known, blocks.Slice()...), routes)...)
+
+	if blocks.Len() == 0 {
+		r.sys.Config().Log.Printf("%s: AS%d: The query returned zero netblocks", r.String(), asn)
+		return
+	}
+
+	stats.RecordResult(ctx)
+	r.sys.Cache().Update(&requests.ASNRequest{
+		Address:        addr,
+		ASN:            asn,
+		Prefix:         prefix,
+		AllocationDate: at,
+		Description:    desc,
+		Netblocks:      blocks.Slice(),
+		Tag:            r.SourceType,
+		Source:         r.String(),
+	})
+}
+
+// autnum returns the description and registration date of the ASN, and false when the
+// registration data could not be obtained.
+func (r *RADb) autnum(ctx context.Context, asn int) (string, time.Time, bool) {
+	var at time.Time
+
+	numRateLimitChecks(ctx, r, 2)
 	url := r.getASNURL("arin", strconv.Itoa(asn))
 	headers := map[string]string{"Content-Type": "application/json"}
 	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
 	if err != nil {
 		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
-		return
+		return "", at, false
 	}
 
 	var m struct {
@@ -188,55 +245,22 @@ func (r *RADb) executeASNQuery(ctx context.Context, asn int, addr, prefix string
 	}
 	if err := json.Unmarshal([]byte(page), &m); err != nil {
 		r.sys.Config().Log.Printf("%s: %s: %v", r.String(), url, err)
-		return
+		return "", at, false
 	} else if m.ClassName != "autnum" {
 		r.sys.Config().Log.Printf("%s: %s: The query returned incorrect results", r.String(), url)
-		return
+		return "", at, false
 	}
 
-	var registration string
 	for _, event := range m.Dates {
-		if event.Action == "registration" {
-			registration = event.Date
-			break
+		if event.Action != "registration" {
+			continue
 		}
-	}
-
-	var at time.Time
-	if registration != "" {
-		d, err := time.Parse(time.RFC3339, registration)
-		if err == nil {
+		if d, err := time.Parse(time.RFC3339, event.Date); err == nil {
 			at = d
 		}
+		break
 	}
-
-	numRateLimitChecks(r, 2)
-	blocks := stringset.New()
-	defer blocks.Close()
-
-	if prefix != "" {
-		blocks.Insert(prefix)
-	}
-
-	nb := r.netblocks(ctx, asn)
-	defer nb.Close()
-
-	blocks.Union(nb)
-	if blocks.Len() == 0 {
-		r.sys.Config().Log.Printf("%s: %s: The query returned zero netblocks", r.String(), url)
-		return
-	}
-
-	r.sys.Cache().Update(&requests.ASNRequest{
-		Address:        addr,
-		ASN:            asn,
-		Prefix:         prefix,
-		AllocationDate: at,
-		Description:    m.Description,
-		Netblocks:      blocks.Slice(),
-		Tag:            r.SourceType,
-		Source:         r.String(),
-	})
+	return m.Description, at, true
 }
 
 func (r *RADb) getASNURL(registry, asn string) string {
@@ -248,7 +272,7 @@ func (r *RADb) getASNURL(registry, asn string) string {
 func (r *RADb) netblocks(ctx context.Context, asn int) *stringset.Set {
 	netblocks := stringset.New()
 
-	numRateLimitChecks(r, 2)
+	numRateLimitChecks(ctx, r, 2)
 	url := r.getNetblocksURL(strconv.Itoa(asn))
 	headers := map[string]string{"Content-Type": "application/json"}
 	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
@@ -309,32 +333,8 @@ func (r *RADb) getNetblocksURL(asn string) string {
 }
 
 func (r *RADb) ipToASN(ctx context.Context, cidr string) int {
-	numRateLimitChecks(r, 2)
-	if r.addr == "" {
-		msg := resolve.QueryMsg(radbWhoisURL, dns.TypeA)
-		resp, err := r.sys.TrustedResolvers().QueryBlocking(ctx, msg)
-		if err != nil {
-			r.sys.Config().Log.Printf("%s: %s: %v", r.String(), radbWhoisURL, err)
-			return 0
-		}
-
-		ans := resolve.ExtractAnswers(resp)
-		if len(ans) == 0 {
-			return 0
-		}
-
-		ip := ans[0].Data
-		if ip == "" {
-			r.sys.Config().Log.Printf("%s: Failed to resolve %s", r.String(), radbWhoisURL)
-			return 0
-		}
-		r.addr = ip
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	conn, err := amassnet.DialContext(ctx, "tcp", r.addr+":43")
+	numRateLimitChecks(ctx, r, 2)
+	conn, err := r.whoisConn(ctx)
 	if err != nil {
 		r.sys.Config().Log.Printf("%s: %v", r.String(), err)
 		return 0
@@ -368,3 +368,138 @@ func (r *RADb) ipToASN(ctx context.Context, cidr string) int {
 	}
 	return asn
 }
+
+// whoisConn connects to the RADb whois server, which mirrors the routing registries, and
+// bounds the entire exchange on the connection by the whois timeout.
+func (r *RADb) whoisConn(ctx context.Context) (net.Conn, error) {
+	if r.addr == "" {
+		msg := resolve.QueryMsg(radbWhoisURL, dns.TypeA)
+		resp, err := r.sys.TrustedResolvers().QueryBlocking(ctx, msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", radbWhoisURL, err)
+		}
+
+		ans := resolve.ExtractAnswers(resp)
+		if len(ans) == 0 || ans[0].Data == "" {
+			return nil, fmt.Errorf("failed to resolve %s", radbWhoisURL)
+		}
+		r.addr = ans[0].Data
+	}
+
+	dctx, cancel := context.WithTimeout(ctx, radbWhoisTimeout)
+	defer cancel()
+
+	conn, err := amassnet.DialContext(dctx, "tcp", net.JoinHostPort(r.addr, radbWhoisPort))
+	if err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(radbWhoisTimeout))
+	return conn, nil
+}
+
+// irrRoutes returns the prefixes of the route and route6 objects registered in the routing
+// registries with the ASN as their origin.
+func (r *RADb) irrRoutes(ctx context.Context, asn int) ([]string, error) {
+	if err := spendBudget(ctx); err != nil {
+		return nil, err
+	}
+
+	numRateLimitChecks(ctx, r, 2)
+	conn, err := r.whoisConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return queryIRRRoutes(conn, asn)
+}
+
+// queryIRRRoutes sends the IRRd queries for the IPv4 and IPv6 routes originated by the ASN over
+// the whois connection, and returns the prefixes in their canonical form, each listed once.
+func queryIRRRoutes(conn io.ReadWriter, asn int) ([]string, error) {
+	// Multiple-command mode keeps the connection open for both queries
+	if _, err := fmt.Fprintf(conn, "!!\n!gAS%d\n!6AS%d\n!q\n", asn, asn); err != nil {
+		return nil, err
+	}
+
+	var routes []string
+	seen := make(map[string]struct{})
+	rd := bufio.NewReader(conn)
+	for answers := 0; answers < 2; {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return routes, err
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case line == "C" || line == "D":
+			// The ASN has no routes of the address family
+		case strings.HasPrefix(line, "F"):
+			return routes, fmt.Errorf("the IRR query failed: %s", strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "A"):
+			n, err := strconv.Atoi(line[1:])
+			if err != nil || n < 0 {
+				return routes, fmt.Errorf("malformed IRR response: %q", line)
+			}
+
+			data := make([]byte, n)
+			if _, err := io.ReadFull(rd, data); err != nil {
+				return routes, err
+			}
+			for _, field := range strings.Fields(string(data)) {
+				if _, ipnet, err := net.ParseCIDR(field); err == nil {
+					if cidr := ipnet.String(); !hasRoute(seen, cidr) {
+						routes = append(routes, cidr)
+					}
+				}
+			}
+			// The data is followed by the line completing the answer
+			for line = ""; line == ""; {
+				if line, err = rd.ReadString('\n'); err != nil {
+					return routes, err
+				}
+				line = strings.TrimSpace(line)
+			}
+			if line != "C" {
+				return routes, fmt.Errorf("unexpected IRR response: %q", line)
+			}
+		default:
+			return routes, fmt.Errorf("unexpected IRR response: %q", line)
+		}
+		answers++
+	}
+	return routes, nil
+}
+
+// newRoutes returns the routes that are not among the netblocks already known for the ASN, such
+// as the prefixes derived from BGP announcements by the other data sources.
+func newRoutes(known, routes []string) []string {
+	seen := make(map[string]struct{}, len(known))
+	for _, cidr := range known {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			seen[ipnet.String()] = struct{}{}
+		}
+	}
+
+	var results []string
+	for _, cidr := range routes {
+		if !hasRoute(seen, cidr) {
+			results = append(results, cidr)
+		}
+	}
+	return results
+}
+
+// hasRoute returns true when the prefix was already seen, and otherwise records it.
+func hasRoute(seen map[string]struct{}, cidr string) bool {
+	if _, found := seen[cidr]; found {
+		return true
+	}
+
+	seen[cidr] = struct{}{}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serveIRR answers the IRRd queries read from the connection with the provided responses.
+func serveIRR(t *testing.T, conn net.Conn, responses map[string]string) {
+	t.Helper()
+
+	go func() {
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			query := strings.TrimSpace(scanner.Text())
+			if query == "!q" {
+				return
+			}
+			if resp, found := responses[query]; found {
+				fmt.Fprint(conn, resp)
+			}
+		}
+	}()
+}
+
+func TestQueryIRRRoutes(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	v4 := "192.0.2.0/24 198.51.100.0/24 192.0.2.1/24\n"
+	v6 := "2001:db8::/32\n"
+	serveIRR(t, server, map[string]string{
+		"!gAS64496": fmt.Sprintf("A%d\n%sC\n", len(v4), v4),
+		"!6AS64496": fmt.Sprintf("A%d\n%sC\n", len(v6), v6),
+	})
+
+	routes, err := queryIRRRoutes(client, 64496)
+	if err != nil {
+		t.Fatalf("The IRR query failed: %v", err)
+	}
+	// The host address is written as the prefix and removed as a duplicate
+	expected := []string{"192.0.2.0/24", "198.51.100.0/24", "2001:db8::/32"}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Got the routes %v, expected %v", routes, expected)
+	}
+}
+
+func TestQueryIRRRoutesMissing(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	serveIRR(t, server, map[string]string{
+		"!gAS64497": "D\n",
+		"!6AS64497": "C\n",
+	})
+
+	if routes, err := queryIRRRoutes(client, 64497); err != nil || len(routes) != 0 {
+		t.Errorf("Expected no routes without an error, got %v and %v", routes, err)
+	}
+
+	client, server = net.Pipe()
+	defer client.Close()
+
+	serveIRR(t, server, map[string]string{"!gAS64498": "F Invalid origin\n"})
+	if _, err := queryIRRRoutes(client, 64498); err == nil {
+		t.Errorf("The failed IRR query did not return an error")
+	}
+}
+
+func TestNewRoutes(t *testing.T) {
+	known := []string{"192.0.2.0/24", "2001:0db8::/32", "bad"}
+	routes := []string{"192.0.2.0/24", "198.51.100.0/24", "2001:db8::/32", "198.51.100.0/24"}
+
+	expected := []string{"198.51.100.0/24"}
+	if got := newRoutes(known, routes); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got the routes %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"sync"
+
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// DataSourceFactory returns a data source Service initialized for the provided System.
+//
+// The returned Service must satisfy the same contract as the built-in data sources:
+//
+//   - String returns a unique name that is used for source filtering and configuration lookups
+//   - Description returns one of the tag constants from the requests package (e.g. requests.API)
+//   - OnStart acquires credentials from sys.Config().GetDataSourceConfig(name) and sets the rate limit
+//   - Requests received on the Input channel are type switched (*requests.DNSRequest,
+//     *requests.AddrRequest, *requests.ASNRequest, *requests.WhoisRequest) and the
+//     Service calls CheckRateLimit before each external query
+//   - Findings are sent on the Output channel, or added to sys.Cache() for ASN information
+//   - The request handling goroutine returns once the Done channel is closed
+//
+// A factory may return nil to indicate that the data source is not available.
+type DataSourceFactory func(sys systems.System) service.Service
+
+var (
+	registryLock sync.Mutex
+	registry     []DataSourceFactory
+)
+
+// RegisterDataSource adds a data source factory that GetAllSources will call alongside the
+// built-in data sources and scripts. It is intended to be called from an init function in
+// the package implementing the external data source. Data sources sharing a name with an
+// already provided data source are discarded.
+func RegisterDataSource(f DataSourceFactory) {
+	if f == nil {
+		return
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	registry = append(registry, f)
+}
+
+func registeredSources(sys systems.System, existing []service.Service) []service.Service {
+	registryLock.Lock()
+	factories := make([]DataSourceFactory, len(registry))
+	copy(factories, registry)
+	registryLock.Unlock()
+
+	names := make(map[string]struct{}, len(existing))
+	for _, srv := range existing {
+		names[srv.String()] = struct{}{}
+	}
+
+	var srvs []service.Service
+	for _, f := range factories {
+		srv := f(sys)
+		if srv == nil {
+			continue
+		}
+		if _, found := names[srv.String()]; found {
+			sys.Config().Log.Printf("Data source %s was already provided and will be ignored", srv.String())
+			continue
+		}
+
+		names[srv.String()] = struct{}{}
+		srvs = append(srvs, srv)
+	}
+	return srvs
+}
@@ -5,7 +5,11 @@
 package scripting
 
 import (
+	"context"
+	"time"
+
 	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/caffix/service"
 	lua "github.com/yuin/gopher-lua"
 )
@@ -193,15 +197,18 @@ func (s *Script) setRateLimit(L *lua.LState) int {
 	return 0
 }
 
-func numRateLimitChecks(srv service.Service, num int) {
+func numRateLimitChecks(ctx context.Context, srv service.Service, num int) {
 	for i := 0; i < num; i++ {
+		start := time.Now()
+
 		srv.CheckRateLimit()
+		stats.RecordRateLimitWait(ctx, time.Since(start))
 	}
 }
 
 // Wrapper so scripts can block until past the data source rate limit.
 func (s *Script) checkRateLimit(L *lua.LState) int {
-	numRateLimitChecks(s, s.seconds)
+	numRateLimitChecks(s.ctx, s, s.seconds)
 	return 0
 }
 
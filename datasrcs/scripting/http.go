@@ -127,6 +127,7 @@ func (s *Script) req(ctx context.Context, url, data string, headers map[string]s
 	dsc := cfg.GetDataSourceConfig(s.String())
 	if dsc != nil && dsc.TTL > 0 {
 		if r, err := s.getCachedResponse(ctx, url+data, dsc.TTL); err == nil {
+			http.RecordSourceURL(ctx, url)
 			return r, err
 		}
 	}
@@ -136,7 +137,7 @@ func (s *Script) req(ctx context.Context, url, data string, headers map[string]s
 		body = strings.NewReader(data)
 	}
 
-	numRateLimitChecks(s, s.seconds)
+	numRateLimitChecks(s.ctx, s, s.seconds)
 	resp, err := http.RequestWebPage(ctx, url, body, headers, auth)
 	if err != nil {
 		if cfg.Verbose {
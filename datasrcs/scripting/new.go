@@ -12,22 +12,38 @@ import (
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/aokimio/Amass/v3/systems"
 	bf "github.com/tylertreat/BoomFilters"
 	lua "github.com/yuin/gopher-lua"
 )
 
 func genNewName(ctx context.Context, sys systems.System, script *Script, name string) {
+	if sys.NameFilter().Has(script.String(), name) {
+		return
+	}
+	if sys.Wildcards().Detected(name) || !sys.Config().AllowedTLD(name) {
+		return
+	}
 	if domain := sys.Config().WhichDomain(name); domain != "" {
 		select {
 		case <-ctx.Done():
 		case <-script.Done():
 		default:
-			script.queue.Append(&requests.DNSRequest{
-				Name:   name,
-				Domain: domain,
-				Tag:    script.Description(),
-				Source: script.String(),
+			var u string
+			if sys.Config().SourceURLs {
+				u = http.SourceURL(ctx)
+			}
+
+			sys.NameFilter().Insert(script.String(), name)
+			stats.RecordResult(ctx)
+			script.appendOutput(ctx, &requests.DNSRequest{
+				Name:      name,
+				Domain:    domain,
+				Tag:       script.Description(),
+				Source:    script.String(),
+				SourceURL: u,
+				Passive:   true,
 			})
 		}
 	}
@@ -90,7 +106,8 @@ func (s *Script) newAddr(L *lua.LState) int {
 				case <-ctx.Done():
 				case <-s.Done():
 				default:
-					s.queue.Append(&requests.AddrRequest{
+					stats.RecordResult(ctx)
+					s.appendOutput(ctx, &requests.AddrRequest{
 						Address: ip.String(),
 						Domain:  domain,
 						Tag:     s.SourceType,
@@ -142,6 +159,7 @@ func (s *Script) newASN(L *lua.LState) int {
 
 			cc, _ := getStringField(L, params, "cc")
 			registry, _ := getStringField(L, params, "registry")
+			stats.RecordResult(ctx)
 			s.sys.Cache().Update(&requests.ASNRequest{
 				Address:        addr,
 				ASN:            int(asn),
@@ -167,7 +185,8 @@ func (s *Script) associated(L *lua.LState) int {
 			case <-ctx.Done():
 			case <-s.Done():
 			default:
-				s.queue.Append(&requests.WhoisRequest{
+				stats.RecordResult(ctx)
+				s.appendOutput(ctx, &requests.WhoisRequest{
 					Domain:     domain,
 					NewDomains: []string{assoc},
 					Tag:        s.SourceType,
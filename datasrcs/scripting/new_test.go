@@ -8,7 +8,7 @@ import (
 	"testing"
 
 	"github.com/aokimio/Amass/v3/requests"
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stringset"
 )
 
 func TestNewNames(t *testing.T) {
@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/net/dns"
+	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/queue"
 	"github.com/caffix/service"
@@ -49,6 +52,7 @@ type Script struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	queue      queue.Queue
+	warned     int32
 }
 
 // NewScript returns he object initialized, but not yet started.
@@ -63,7 +67,8 @@ func NewScript(script string, sys systems.System) *Script {
 		subre: re,
 		queue: queue.NewQueue(),
 	}
-	s.ctx, s.cancel = context.WithCancel(context.Background())
+	// The script work ends with the runtime of the System
+	s.ctx, s.cancel = context.WithCancel(sys.Context())
 
 	L := s.newLuaState(sys.Config())
 	s.luaState = L
@@ -86,6 +91,9 @@ func NewScript(script string, sys systems.System) *Script {
 		return nil
 	}
 	s.BaseService = *service.NewBaseService(s, name)
+	// Attribute the metrics collected during script execution to this data source
+	s.ctx = http.WithSourceURL(stats.NewContext(s.ctx, sys.Stats(), name))
+	s.ctx = http.WithMaxResponseSize(s.ctx, sys.Config().ResponseSizeLimit(name))
 	// Save references to the callbacks defined within the script
 	s.assignCallbacks()
 	go s.manageOutput()
@@ -257,6 +265,49 @@ func (s *Script) checkConfig() error {
 	return errors.New(estr)
 }
 
+// The interval between the checks for room in the output queue while a script is held back
+const outputWaitInterval = 10 * time.Millisecond
+
+// appendOutput queues a result of the script, applying the backpressure strategy selected by
+// the configuration once the results waiting in the queue reach the output buffer size.
+func (s *Script) appendOutput(ctx context.Context, req interface{}) {
+	s.sys.EventLog().RecordOutput(s.String(), req)
+	// The related domains already reported by a data source are not expanded again
+	if w, ok := req.(*requests.WhoisRequest); ok {
+		w.NewDomains = s.sys.DomainFilter().Filter(s.String(), w.NewDomains)
+	}
+	size := s.sys.Config().OutputBufferSize()
+
+	if s.queue.Len() >= size {
+		s.sys.Stats().OutputDepth(s.String(), s.queue.Len())
+		if s.sys.Config().DropOutput() {
+			s.sys.Stats().OutputDropped(s.String())
+			if atomic.CompareAndSwapInt32(&s.warned, 0, 1) {
+				s.sys.Config().Log.Printf("%s: WARNING: The output buffer is full, so results are being dropped", s.String())
+			}
+			return
+		}
+
+		start := time.Now()
+		t := time.NewTicker(outputWaitInterval)
+		defer t.Stop()
+
+		for s.queue.Len() >= size {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.Done():
+				return
+			case <-t.C:
+			}
+		}
+		s.sys.Stats().OutputBlocked(s.String(), time.Since(start))
+	}
+
+	s.queue.Append(req)
+	s.sys.Stats().OutputDepth(s.String(), s.queue.Len())
+}
+
 func (s *Script) manageOutput() {
 loop:
 	for {
@@ -338,40 +389,49 @@ func (s *Script) dispatch(in interface{}) {
 	s.active.Lock()
 	defer s.active.Unlock()
 
+	ctx := s.requestContext()
+
 	switch req := in.(type) {
 	case *requests.DNSRequest:
 		if s.cbs.Vertical.Type() != lua.LTNil && req != nil && req.Domain != "" {
-			s.CheckRateLimit()
-			s.dnsRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.dnsRequest(ctx, req)
 		}
 	case *requests.ResolvedRequest:
 		if s.cbs.Resolved.Type() != lua.LTNil && req != nil && req.Name != "" && len(req.Records) > 0 {
-			s.CheckRateLimit()
-			s.resolvedRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.resolvedRequest(ctx, req)
 		}
 	case *requests.SubdomainRequest:
 		if s.cbs.Subdomain.Type() != lua.LTNil && req != nil && req.Name != "" {
-			s.CheckRateLimit()
-			s.subdomainRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.subdomainRequest(ctx, req)
 		}
 	case *requests.AddrRequest:
 		if s.cbs.Address.Type() != lua.LTNil && req != nil && req.Address != "" {
-			s.CheckRateLimit()
-			s.addrRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.addrRequest(ctx, req)
 		}
 	case *requests.ASNRequest:
 		if s.cbs.Asn.Type() != lua.LTNil && req != nil && (req.Address != "" || req.ASN != 0) {
-			s.CheckRateLimit()
-			s.asnRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.asnRequest(ctx, req)
 		}
 	case *requests.WhoisRequest:
 		if s.cbs.Horizontal.Type() != lua.LTNil {
-			s.CheckRateLimit()
-			s.whoisRequest(s.ctx, req)
+			numRateLimitChecks(ctx, s, 1)
+			s.whoisRequest(ctx, req)
 		}
 	}
 }
 
+// requestContext returns the context that a request is handled within, which is done once the
+// work on the current target ends or the System reaches the end of its runtime.
+func (s *Script) requestContext() context.Context {
+	ctx := http.WithSourceURL(stats.NewContext(s.sys.RequestContext(), s.sys.Stats(), s.String()))
+	return http.WithMaxResponseSize(ctx, s.sys.Config().ResponseSizeLimit(s.String()))
+}
+
 func (s *Script) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 	L := s.luaState
 
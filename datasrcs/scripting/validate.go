@@ -0,0 +1,122 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// The longest the top level of a script is allowed to execute during validation
+const validationTimeout = 5 * time.Second
+
+// The global functions that Amass calls during the execution of a script
+var callbackNames = []string{"start", "stop", "check", "vertical", "horizontal", "address", "asn", "resolved", "subdomain"}
+
+// ScriptError describes a problem found in a data source script. The Line is zero when the
+// problem is not associated with a specific line of the script.
+type ScriptError struct {
+	Line    int
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ScriptError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// ValidateScript parses and compiles the script identified by path, executes the top level of the
+// script to define the globals, and checks the globals required by Amass. None of the callbacks are
+// executed, so the validation does not perform network activity. The name of the data source
+// implemented by the script is returned.
+func ValidateScript(path, script string) (string, error) {
+	chunk, err := parse.Parse(strings.NewReader(script), path)
+	if err != nil {
+		var perr *parse.Error
+		if errors.As(err, &perr) && perr.Pos.Line == parse.EOF {
+			return "", &ScriptError{Message: perr.Message + " at the end of the script"}
+		} else if errors.As(err, &perr) {
+			return "", &ScriptError{Line: perr.Pos.Line, Message: fmt.Sprintf("%s near '%s'", perr.Message, perr.Token)}
+		}
+		return "", &ScriptError{Message: err.Error()}
+	}
+
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return "", &ScriptError{Message: err.Error()}
+	}
+
+	s := &Script{}
+	L := s.newLuaState(config.NewConfig())
+	defer L.Close()
+	s.luaState = L
+
+	ctx, cancel := context.WithTimeout(context.Background(), validationTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		return "", runtimeScriptError(path, err)
+	}
+
+	name, err := s.scriptName()
+	if err != nil {
+		return "", &ScriptError{Message: err.Error()}
+	} else if name == "" {
+		return "", &ScriptError{Message: "the script global 'name' is empty"}
+	}
+
+	if t, err := s.scriptType(); err != nil {
+		return name, &ScriptError{Message: err.Error()}
+	} else if t == "" {
+		return name, &ScriptError{Message: "the script global 'type' is empty"}
+	}
+
+	var defined bool
+	for _, cb := range callbackNames {
+		switch L.GetGlobal(cb).Type() {
+		case lua.LTNil:
+		case lua.LTFunction:
+			defined = true
+		default:
+			return name, &ScriptError{Message: fmt.Sprintf("the script global '%s' is not a function", cb)}
+		}
+	}
+	if !defined {
+		return name, &ScriptError{Message: "the script does not define any callback functions"}
+	}
+	return name, nil
+}
+
+// runtimeScriptError extracts the line number from the error raised while executing the script.
+func runtimeScriptError(path string, err error) error {
+	msg := err.Error()
+
+	var aerr *lua.ApiError
+	if errors.As(err, &aerr) && aerr.Object != nil {
+		msg = aerr.Object.String()
+	}
+
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(path) + `:(\d+):\s*(.*)`)
+	if m := re.FindStringSubmatch(msg); m != nil {
+		if line, err := strconv.Atoi(m[1]); err == nil {
+			return &ScriptError{Line: line, Message: m[2]}
+		}
+	}
+	return &ScriptError{Message: msg}
+}
@@ -0,0 +1,60 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/resources"
+)
+
+func TestValidateDefaultScripts(t *testing.T) {
+	files, err := resources.GetDefaultScriptFiles()
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Failed to obtain the default scripts: %v", err)
+	}
+
+	for _, f := range files {
+		if _, err := ValidateScript(f.Path, f.Content); err != nil {
+			t.Errorf("%s: %v", f.Path, err)
+		}
+	}
+}
+
+func TestValidateScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		line   int
+		msg    string
+	}{
+		{"syntax", "name = \"Test\"\ntype = \"api\"\nfunction vertical(ctx, domain\nend\n", 4, "near"},
+		{"runtime", "name = \"Test\"\ntype = \"api\"\nlocal x = nil + 1\n", 3, "cannot perform"},
+		{"no name", "type = \"api\"\nfunction vertical(ctx, domain) end\n", 0, "'name'"},
+		{"no type", "name = \"Test\"\nfunction vertical(ctx, domain) end\n", 0, "'type'"},
+		{"bad callback", "name = \"Test\"\ntype = \"api\"\nvertical = 5\n", 0, "not a function"},
+		{"no callbacks", "name = \"Test\"\ntype = \"api\"\n", 0, "callback"},
+	}
+
+	for _, test := range tests {
+		_, err := ValidateScript("test.ads", test.script)
+
+		var serr *ScriptError
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: expected a ScriptError, got %v", test.name, err)
+			continue
+		}
+		if serr.Line != test.line || !strings.Contains(serr.Message, test.msg) {
+			t.Errorf("%s: unexpected error at line %d: %s", test.name, serr.Line, serr.Message)
+		}
+	}
+
+	name, err := ValidateScript("test.ads", "name = \"Test\"\ntype = \"api\"\nfunction vertical(ctx, domain) end\n")
+	if err != nil || name != "Test" {
+		t.Errorf("The valid script failed validation: %s, %v", name, err)
+	}
+}
@@ -6,14 +6,19 @@ package datasrcs
 
 import (
 	"context"
+	"net/url"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/datasrcs/scripting"
+	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 )
 
 // GetAllSources returns a slice of all data source services, initialized and ready.
@@ -23,10 +28,13 @@ func GetAllSources(sys systems.System) []service.Service {
 		NewCloudflare(sys),
 		NewDNSDB(sys),
 		NewFOFA(sys),
+		NewHurricaneElectric(sys),
+		NewIPinfo(sys),
 		NewNetworksDB(sys),
 		NewRADb(sys),
 		NewTwitter(sys),
 		NewUmbrella(sys),
+		NewWhoisXMLAPI(sys),
 	}
 
 	if scripts, err := sys.Config().AcquireScripts(); err == nil {
@@ -36,6 +44,7 @@ func GetAllSources(sys systems.System) []service.Service {
 			}
 		}
 	}
+	srvs = append(srvs, registeredSources(sys, srvs)...)
 
 	sort.Slice(srvs, func(i, j int) bool {
 		return srvs[i].String() < srvs[j].String()
@@ -75,18 +84,102 @@ func SelectedDataSources(cfg *config.Config, avail []service.Service) []service.
 }
 
 func genNewNameEvent(ctx context.Context, sys systems.System, srv service.Service, name string) {
+	// Skip the names this data source has already reported through the same account
+	org := accountLabel(ctx)
+	key := srv.String()
+	if org != "" {
+		key += "/" + org
+	}
+	if sys.NameFilter().Has(key, name) {
+		return
+	}
+	// Drop names that fall within subdomains already known to be DNS wildcards
+	if sys.Wildcards().Detected(name) {
+		return
+	}
+	// Drop names outside the top-level domains selected by the user
+	if !sys.Config().AllowedTLD(name) {
+		return
+	}
+
 	if domain := sys.Config().WhichDomain(name); domain != "" {
-		srv.Output() <- &requests.DNSRequest{
-			Name:   name,
-			Domain: domain,
-			Tag:    srv.Description(),
-			Source: srv.String(),
+		var u string
+		if sys.Config().SourceURLs {
+			u = http.SourceURL(ctx)
 		}
+
+		sys.NameFilter().Insert(key, name)
+		stats.RecordResult(ctx)
+		sendOutput(ctx, sys, srv, &requests.DNSRequest{
+			Name:      name,
+			Domain:    domain,
+			Tag:       srv.Description(),
+			Source:    srv.String(),
+			SourceURL: u,
+			Org:       org,
+			Passive:   true,
+		})
 	}
 }
 
-func numRateLimitChecks(srv service.Service, num int) {
-	for i := 0; i < num; i++ {
+// sourceContext returns a context that attributes the metrics collected while handling a request
+// to the data source, carries the query budget for the request, records the pages requested,
+// presents the client certificate of the data source, and provides the response status codes to
+// the adaptive rate limit when it is enabled.
+// The context is done once the work on the current target ends, or the System reaches the end of
+// its runtime or is shut down.
+func sourceContext(sys systems.System, srv service.Service) context.Context {
+	ctx := stats.NewContext(sys.RequestContext(), sys.Stats(), srv.String())
+	ctx = http.WithMaxResponseSize(ctx, sys.Config().ResponseSizeLimit(srv.String()))
+	if ar := adaptiveRateFor(srv); ar != nil {
+		ctx = http.WithStatusObserver(ctx, ar.observe)
+	}
+	ctx = withRequestDelay(ctx, sys.Config().RequestDelay(srv.String()))
+	if cert, hosts := sys.Config().ClientCertificate(srv.String()); cert != nil {
+		ctx = http.WithClientCert(ctx, cert, hosts)
+	}
+	return withQueryBudget(http.WithSourceURL(ctx), defaultQueryBudget)
+}
+
+// withQueryParams merges the query parameters configured for the data source into the URL,
+// replacing the values of the parameters already present. The URL is returned unchanged
+// when no parameters have been configured.
+func withQueryParams(sys systems.System, srv service.Service, u string) string {
+	dsc := sys.Config().GetDataSourceConfig(srv.String())
+	if dsc == nil || len(dsc.QueryParams) == 0 {
+		return u
+	}
+
+	base, query := u, ""
+	if idx := strings.Index(u, "?"); idx != -1 {
+		base, query = u[:idx], u[idx+1:]
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return u
+	}
+	for name, val := range dsc.QueryParams {
+		values.Set(name, val)
+	}
+	return base + "?" + values.Encode()
+}
+
+func checkRateLimit(ctx context.Context, srv service.Service) {
+	start := time.Now()
+
+	if a := accountFrom(ctx); a != nil {
+		a.limiter.wait()
+	} else if rj := rateJitterFor(srv); rj != nil {
+		rj.wait()
+	} else {
 		srv.CheckRateLimit()
 	}
+	stats.RecordRateLimitWait(ctx, time.Since(start))
+}
+
+func numRateLimitChecks(ctx context.Context, srv service.Service, num int) {
+	for i := 0; i < num; i++ {
+		checkRateLimit(ctx, srv)
+	}
 }
@@ -0,0 +1,144 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+func TestGenNewNameEventRepeats(t *testing.T) {
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Filter = requests.NewNameFilter(100, 0.01)
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+
+	for i := 0; i < 3; i++ {
+		go genNewNameEvent(context.Background(), sys, u, "www.owasp.org")
+		select {
+		case <-u.Output():
+			if i > 0 {
+				t.Errorf("The repeated name was reported again")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if i == 0 {
+				t.Errorf("The name was not reported")
+			}
+		}
+	}
+	// Names from other data sources are still reported
+	c := NewCloudflare(sys)
+	defer func() { _ = c.Stop() }()
+	go genNewNameEvent(context.Background(), sys, c, "www.owasp.org")
+	select {
+	case <-c.Output():
+	case <-time.After(time.Second):
+		t.Errorf("The name was not reported for another data source")
+	}
+}
+
+// The names are reported repeatedly, as happens when a source is asked about the subdomains
+// of names it already reported.
+func benchmarkRepeatedNames(b *testing.B, filter *requests.NameFilter) {
+	cfg := config.NewConfig()
+	for i := 0; i < 100; i++ {
+		cfg.AddDomain("owasp" + strconv.Itoa(i) + ".org")
+	}
+	sys := &systems.SimpleSystem{
+		Cfg:           cfg,
+		WildcardCache: requests.NewWildcardCache(),
+		Filter:        filter,
+	}
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	go func() {
+		for range u.Output() {
+		}
+	}()
+
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = "www" + strconv.Itoa(i) + ".owasp99.org"
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		genNewNameEvent(ctx, sys, u, names[i%len(names)])
+	}
+}
+
+func BenchmarkRepeatedNames(b *testing.B) {
+	benchmarkRepeatedNames(b, nil)
+}
+
+func BenchmarkRepeatedNamesFiltered(b *testing.B) {
+	benchmarkRepeatedNames(b, requests.NewNameFilter(config.DefaultNameFilterSize, config.DefaultNameFilterFPRate))
+}
+
+func TestBaseURLOverride(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer srv.Close()
+
+	sys := testSystem()
+	for _, name := range []string{"NetworksDB", "Umbrella", "AlienVault", "DNSDB", "IPinfo"} {
+		sys.Config().GetDataSourceConfig(name).BaseURL = srv.URL + "/mirror"
+	}
+
+	n := NewNetworksDB(sys)
+	defer func() { _ = n.Stop() }()
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	a := NewAlienVault(sys)
+	defer func() { _ = a.Stop() }()
+	d := NewDNSDB(sys)
+	defer func() { _ = d.Stop() }()
+	i := NewIPinfo(sys)
+	defer func() { _ = i.Stop() }()
+
+	for _, got := range []string{
+		n.getIPURL("10.0.0.1"),
+		n.getAPIIPURL(),
+		u.restAddrToASNURL("10.0.0.1"),
+		u.whoisRecordURL("owasp.org"),
+		a.getURL("owasp.org"),
+		d.getURL("owasp.org"),
+		i.asnURL(13335),
+	} {
+		if !strings.HasPrefix(got, srv.URL+"/mirror/") {
+			t.Errorf("The URL did not use the base URL override: %s", got)
+		}
+	}
+
+	if _, err := n.scrapeWebPage(sourceContext(sys, n), n.getIPURL("10.0.0.1")); err != nil {
+		t.Fatalf("The request to the mirror failed: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/mirror/ip/10.0.0.1" {
+		t.Errorf("The mirror received the wrong requests: %v", paths)
+	}
+
+	// The public addresses are used by the sources without an override
+	sys = testSystem()
+	u = NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	if got := u.restAddrToASNURL("10.0.0.1"); got != "https://investigate.api.umbrella.com/bgp_routes/ip/10.0.0.1/as_for_ip.json" {
+		t.Errorf("The default base URL was not used: %s", got)
+	}
+}
@@ -58,13 +58,13 @@ func (t *Twitter) OnStart() error {
 			config := &oauth2.Config{}
 			token := &oauth2.Token{AccessToken: bearer}
 			// OAuth2 http.Client will automatically authorize Requests
-			httpClient := config.Client(context.Background(), token)
+			httpClient := config.Client(t.sys.Context(), token)
 			// Twitter client
 			t.client = twitter.NewClient(httpClient)
 		}
 	}
 
-	t.SetRateLimit(1)
+	setRateLimit(t.sys, t, 1)
 	return t.checkConfig()
 }
 
@@ -87,10 +87,11 @@ func (t *Twitter) requests() {
 		case <-t.Done():
 			return
 		case in := <-t.Input():
+			ctx := sourceContext(t.sys, t)
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				t.CheckRateLimit()
-				t.dnsRequest(context.TODO(), req)
+				checkRateLimit(ctx, t)
+				t.dnsRequest(ctx, req)
 			}
 		}
 	}
@@ -102,7 +103,7 @@ func (t *Twitter) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		return
 	}
 
-	numRateLimitChecks(t, 2)
+	numRateLimitChecks(ctx, t, 2)
 	t.sys.Config().Log.Printf("Querying %s for %s subdomains", t.String(), req.Domain)
 
 	searchParams := &twitter.SearchTweetParams{
@@ -131,7 +132,7 @@ func (t *Twitter) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 
 func (t *Twitter) getBearerToken() (string, error) {
 	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded;charset=UTF-8"}
-	page, err := http.RequestWebPage(context.Background(), "https://api.twitter.com/oauth2/token",
+	page, err := http.RequestWebPage(t.sys.Context(), "https://api.twitter.com/oauth2/token",
 		strings.NewReader("grant_type=client_credentials"), headers,
 		&http.BasicAuth{
 			Username: t.creds.Key,
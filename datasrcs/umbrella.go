@@ -10,16 +10,53 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aokimio/Amass/v3/config"
+	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
+)
+
+// The email addresses from the whois records that can be used in a reverse whois query
+var umbrellaEmailRE = regexp.MustCompile(`^[a-zA-Z0-9._+-]+@[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.[a-zA-Z]{2,}$`)
+
+// The address of the Investigate API, unless base_url is set for the source
+const umbrellaBaseURL = "https://investigate.api.umbrella.com"
+
+// The reverse whois chunks returned for large organizations exceed the default response size limit
+const umbrellaReverseWhoisMaxResponseSize int64 = 64 << 20
+
+// The number of co-occurring domains used for each domain, unless max_related is set for the source
+const umbrellaDefaultMaxRelated = 25
+
+// The weights of the searches relating a domain found through reverse whois to the domain queried,
+// as powers of two, so each search outweighs the searches below it combined
+const (
+	umbrellaCooccurrenceWeight = 1
+	umbrellaEmailWeight        = 2
+	umbrellaNameServerWeight   = 4
+)
+
+const (
+	// The most domains searched for subdomains in a single request
+	umbrellaSearchBatchSize = 10
+	// The longest time spent gathering the DNS requests that are searched together
+	umbrellaBatchWait = 250 * time.Millisecond
+	// The most results returned for a search
+	umbrellaSearchLimit = 1000
 )
 
 // Umbrella is the Service that handles access to the Umbrella data source.
@@ -29,6 +66,11 @@ type Umbrella struct {
 	SourceType string
 	sys        systems.System
 	creds      *config.Credentials
+	// The organizations queried when several keys have been configured
+	accounts []*sourceAccount
+	next     uint32
+	// The response fields already reported as missing
+	missing sync.Map
 }
 
 // NewUmbrella returns he object initialized, but not yet started.
@@ -56,7 +98,11 @@ func (u *Umbrella) OnStart() error {
 		u.sys.Config().Log.Printf("%s: API key data was not provided", u.String())
 	}
 
-	u.SetRateLimit(2)
+	setRateLimit(u.sys, u, 2)
+	if u.accounts = sourceAccounts(u.sys, u, 2); len(u.accounts) > 0 {
+		u.creds = u.accounts[0].creds
+		u.sys.Config().Log.Printf("%s: Querying %d organizations", u.String(), len(u.accounts))
+	}
 	return u.checkConfig()
 }
 
@@ -79,21 +125,135 @@ func (u *Umbrella) requests() {
 		case <-u.Done():
 			return
 		case in := <-u.Input():
-			switch req := in.(type) {
-			case *requests.DNSRequest:
-				u.CheckRateLimit()
-				u.dnsRequest(context.TODO(), req)
-			case *requests.AddrRequest:
-				u.CheckRateLimit()
-				u.addrRequest(context.TODO(), req)
-			case *requests.ASNRequest:
-				u.CheckRateLimit()
-				u.asnRequest(context.TODO(), req)
-			case *requests.WhoisRequest:
-				u.CheckRateLimit()
-				u.whoisRequest(context.TODO(), req)
+			u.dispatch(in, true)
+		}
+	}
+}
+
+// dispatch handles a request received by the data source. When batch is true, the DNS requests
+// that arrive shortly after a DNS request are gathered, so their domains are searched together.
+// The names are searched through each of the organizations, while the AS and whois information
+// is obtained through one of them, taking turns.
+func (u *Umbrella) dispatch(in interface{}, batch bool) {
+	switch req := in.(type) {
+	case *requests.DNSRequest:
+		if !batch {
+			for _, ctx := range u.accountContexts() {
+				checkRateLimit(ctx, u)
+				u.dnsRequest(ctx, req)
 			}
+			return
+		}
+
+		reqs, held := u.gatherDNSRequests(req)
+		for _, ctx := range u.accountContexts() {
+			checkRateLimit(ctx, u)
+			u.dnsRequests(ctx, reqs)
+		}
+		for _, h := range held {
+			u.dispatch(h, false)
+		}
+	case *requests.AddrRequest:
+		for _, ctx := range u.accountContexts() {
+			checkRateLimit(ctx, u)
+			u.addrRequest(ctx, req)
 		}
+	case *requests.ASNRequest:
+		ctx := withAccount(sourceContext(u.sys, u), u.nextAccount())
+		checkRateLimit(ctx, u)
+		u.asnRequest(ctx, req)
+	case *requests.WhoisRequest:
+		ctx := withAccount(sourceContext(u.sys, u), u.nextAccount())
+		checkRateLimit(ctx, u)
+		u.whoisRequest(ctx, req)
+	}
+}
+
+// accountContexts returns a context for each organization queried by the data source.
+func (u *Umbrella) accountContexts() []context.Context {
+	if len(u.accounts) == 0 {
+		return []context.Context{sourceContext(u.sys, u)}
+	}
+
+	var ctxs []context.Context
+	for _, a := range u.accounts {
+		ctxs = append(ctxs, withAccount(sourceContext(u.sys, u), a))
+	}
+	return ctxs
+}
+
+// nextAccount returns the organization whose turn it is to be queried, or nil when a single key is used.
+func (u *Umbrella) nextAccount() *sourceAccount {
+	if len(u.accounts) == 0 {
+		return nil
+	}
+
+	n := atomic.AddUint32(&u.next, 1) - 1
+	return u.accounts[int(n)%len(u.accounts)]
+}
+
+// gatherDNSRequests receives the DNS requests that arrive within a short time of the first one,
+// until enough domains have been gathered for a search. Other requests are returned as held.
+func (u *Umbrella) gatherDNSRequests(first *requests.DNSRequest) ([]*requests.DNSRequest, []interface{}) {
+	reqs := []*requests.DNSRequest{first}
+	domains := stringset.New(first.Domain)
+	defer domains.Close()
+
+	var held []interface{}
+	t := time.NewTimer(umbrellaBatchWait)
+	defer t.Stop()
+
+	for domains.Len() < umbrellaSearchBatchSize {
+		select {
+		case <-u.Done():
+			return reqs, held
+		case <-t.C:
+			return reqs, held
+		case in := <-u.Input():
+			if req, ok := in.(*requests.DNSRequest); ok {
+				reqs = append(reqs, req)
+				domains.Insert(req.Domain)
+			} else {
+				held = append(held, in)
+			}
+		}
+	}
+	return reqs, held
+}
+
+// dnsRequests searches the domains of the DNS requests for subdomains in batches, and then
+// handles the rest of each request on its own.
+func (u *Umbrella) dnsRequests(ctx context.Context, reqs []*requests.DNSRequest) {
+	if u.creds == nil || u.creds.Key == "" {
+		return
+	}
+
+	domains := stringset.New()
+	defer domains.Close()
+
+	var inscope []*requests.DNSRequest
+	for _, req := range reqs {
+		if u.sys.Config().IsDomainInScope(req.Domain) {
+			inscope = append(inscope, req)
+			domains.Insert(req.Domain)
+		}
+	}
+
+	batch := domains.Slice()
+	sort.Strings(batch)
+	for i := 0; i < len(batch); i += umbrellaSearchBatchSize {
+		end := i + umbrellaSearchBatchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		if i > 0 {
+			checkRateLimit(ctx, u)
+		}
+		u.searchSubdomains(ctx, batch[i:end]...)
+	}
+
+	for _, req := range inscope {
+		u.relatedRequest(withAccount(sourceContext(u.sys, u), accountFrom(ctx)), req)
 	}
 }
 
@@ -105,29 +265,135 @@ func (u *Umbrella) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
 		return
 	}
 
-	u.sys.Config().Log.Printf("Querying %s for %s subdomains", u.String(), req.Domain)
+	u.searchSubdomains(ctx, req.Domain)
+	u.relatedRequest(ctx, req)
+}
 
-	headers := u.restHeaders()
-	url := u.restDNSURL(req.Domain)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+// searchSubdomains requests the names within the domains seen in the DNS traffic. When several
+// domains are searched together and the results do not all fit in the response, each domain
+// is searched again on its own.
+func (u *Umbrella) searchSubdomains(ctx context.Context, domains ...string) {
+	for _, d := range domains {
+		u.sys.Config().Log.Printf("Querying %s for %s subdomains", u.String(), d)
+	}
+
+	url := u.restDNSURL(domains[0])
+	if len(domains) > 1 {
+		url = u.restDNSBatchURL(domains...)
+	}
+
+	page, err := http.RequestWebPage(ctx, url, nil, u.restHeaders(ctx), nil)
 	if err != nil {
 		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
 		return
 	}
 	// Extract the subdomain names from the REST API results
 	var subs struct {
-		Matches []struct {
+		TotalResults      int  `json:"totalResults"`
+		MoreDataAvailable bool `json:"moreDataAvailable"`
+		Matches           []struct {
 			Name string `json:"name"`
 		} `json:"matches"`
 	}
 	if err := json.Unmarshal([]byte(page), &subs); err != nil {
 		return
 	}
+	if len(domains) > 1 && (subs.MoreDataAvailable || subs.TotalResults > len(subs.Matches)) {
+		for _, d := range domains {
+			if budgetExhausted(ctx) {
+				return
+			}
+			checkRateLimit(ctx, u)
+			u.searchSubdomains(ctx, d)
+		}
+		return
+	}
 	for _, m := range subs.Matches {
 		genNewNameEvent(ctx, u.sys, u, m.Name)
 	}
 }
 
+// relatedRequest obtains the domains related to the domain of the request and its nameservers.
+func (u *Umbrella) relatedRequest(ctx context.Context, req *requests.DNSRequest) {
+	if budgetExhausted(ctx) {
+		return
+	}
+	checkRateLimit(ctx, u)
+	// Names that co-occur with the domain in the DNS traffic are in scope or related domains
+	related := stringset.New()
+	defer related.Close()
+
+	for _, name := range u.queryCooccurrences(ctx, req.Domain) {
+		if u.sys.Config().IsDomainInScope(name) {
+			genNewNameEvent(ctx, u.sys, u, name)
+		} else {
+			related.Insert(name)
+		}
+	}
+	// The nameservers in the whois record of the root domain are recorded as assets
+	var nameservers []string
+	if req.Name == req.Domain && spendBudget(ctx) == nil {
+		if record := u.queryWhois(ctx, req.Domain); record != nil {
+			nameservers = requests.NormalizeNameServers(record.NameServers...)
+		}
+	}
+	if related.Len() > 0 || len(nameservers) > 0 {
+		stats.RecordResult(ctx)
+		sendOutput(ctx, u.sys, u, &requests.WhoisRequest{
+			Domain:      req.Domain,
+			NewDomains:  related.Slice(),
+			NameServers: nameservers,
+			Tag:         u.SourceType,
+			Source:      u.String(),
+		})
+	}
+}
+
+// queryCooccurrences returns the names that co-occur with the domain, in order of decreasing
+// score and bounded by the max_related setting of the data source.
+func (u *Umbrella) queryCooccurrences(ctx context.Context, domain string) []string {
+	if err := spendBudget(ctx); err != nil {
+		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), domain, err)
+		return nil
+	}
+
+	url := u.cooccurrencesURL(domain)
+	page, err := http.RequestWebPage(ctx, url, nil, u.restHeaders(ctx), nil)
+	if err != nil {
+		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+		return nil
+	}
+	// Each entry holds the name and the score of the co-occurrence
+	var co struct {
+		Found bool            `json:"found"`
+		PFS2  [][]interface{} `json:"pfs2"`
+	}
+	if err := json.Unmarshal([]byte(page), &co); err != nil || !co.Found {
+		return nil
+	}
+
+	limit := umbrellaDefaultMaxRelated
+	if max := u.sys.Config().GetDataSourceConfig(u.String()).MaxRelated; max > 0 {
+		limit = max
+	}
+
+	var names []string
+	for _, entry := range co.PFS2 {
+		if len(names) >= limit {
+			break
+		}
+		if len(entry) == 0 {
+			continue
+		}
+		if name, ok := entry[0].(string); ok {
+			if name = resolve.RemoveLastDot(strings.ToLower(strings.TrimSpace(name))); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 func (u *Umbrella) addrRequest(ctx context.Context, req *requests.AddrRequest) {
 	if u.creds == nil || u.creds.Key == "" {
 		return
@@ -136,7 +402,7 @@ func (u *Umbrella) addrRequest(ctx context.Context, req *requests.AddrRequest) {
 		return
 	}
 
-	headers := u.restHeaders()
+	headers := u.restHeaders(ctx)
 	url := u.restAddrURL(req.Address)
 	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
 	if err != nil {
@@ -146,20 +412,36 @@ func (u *Umbrella) addrRequest(ctx context.Context, req *requests.AddrRequest) {
 	// Extract the subdomain names from the REST API results
 	var ip struct {
 		Records []struct {
-			Data string `json:"rr"`
+			Data     string `json:"rr"`
+			LastSeen int64  `json:"lastSeen"`
 		} `json:"records"`
 	}
 	if err := json.Unmarshal([]byte(page), &ip); err != nil {
 		return
 	}
 
+	oldest := u.oldestRecordTime()
 	for _, record := range ip.Records {
+		// Records without an observation time are kept, since their age is unknown
+		if !oldest.IsZero() && record.LastSeen > 0 && time.Unix(record.LastSeen, 0).Before(oldest) {
+			continue
+		}
 		if name := resolve.RemoveLastDot(record.Data); name != "" {
 			genNewNameEvent(ctx, u.sys, u, name)
 		}
 	}
 }
 
+// oldestRecordTime returns the earliest time a passive DNS record can have last been observed
+// and still be used, or the zero time when the records are not filtered by age.
+func (u *Umbrella) oldestRecordTime() time.Time {
+	days := u.sys.Config().GetDataSourceConfig(u.String()).MaxRecordAge
+	if days <= 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -days)
+}
+
 func (u *Umbrella) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 	if u.creds == nil || u.creds.Key == "" {
 		return
@@ -175,87 +457,105 @@ func (u *Umbrella) asnRequest(ctx context.Context, req *requests.ASNRequest) {
 }
 
 func (u *Umbrella) executeASNAddrQuery(ctx context.Context, req *requests.ASNRequest) {
-	headers := u.restHeaders()
+	var as []umbrellaASEntry
+	headers := u.restHeaders(ctx)
 	url := u.restAddrToASNURL(req.Address)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
-	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
-		return
-	}
-	// Extract the AS information from the REST API results
-	var as []struct {
-		Date        string `json:"creation_date"`
-		Registry    int    `json:"ir"`
-		Description string `json:"description"`
-		ASN         int    `json:"asn"`
-		CIDR        string `json:"cidr"`
-	}
-	if err := json.Unmarshal([]byte(page), &as); err != nil || len(as) == 0 {
-		return
-	}
+	retryEmpty(ctx, u.sys, u, routedAddress(req.Address), req.Address, func() bool {
+		if err := spendBudget(ctx); err != nil {
+			u.sys.Config().Log.Printf("%s: %s: %v", u.String(), req.Address, err)
+			return false
+		}
 
-	created, err := time.Parse("2006-01-02", as[0].Date)
-	if err != nil {
+		page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+		if err != nil {
+			u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+			return false
+		}
+		// Extract the AS information from the REST API results
+		as = u.asEntries(url, page)
+		return len(as) == 0 && umbrellaEmpty(page)
+	})
+	if len(as) == 0 {
 		return
 	}
+	// Use the entry with the most specific netblock containing the address
+	var cidrs []string
+	for _, a := range as {
+		cidrs = append(cidrs, a.CIDR)
+	}
+	entry := as[0]
+	if match := amassnet.LongestPrefixMatch(net.ParseIP(req.Address), cidrs); match != "" {
+		for _, a := range as {
+			if a.CIDR == match {
+				entry = a
+				break
+			}
+		}
+	}
 
-	var registry string
-	switch as[0].Registry {
-	case 1:
-		registry = "AfriNIC"
-	case 2:
-		registry = "APNIC"
-	case 3:
-		registry = "ARIN"
-	case 4:
-		registry = "LACNIC"
-	case 5:
-		registry = "RIPE NCC"
-	default:
-		registry = "N/A"
-	}
-
-	req.ASN = as[0].ASN
-	req.Prefix = as[0].CIDR
-	req.Registry = registry
-	req.AllocationDate = created
-	req.Description = as[0].Description
+	req.ASN = entry.ASN
+	req.Prefix = entry.CIDR
+	req.Registry = entry.Registry
+	req.AllocationDate = entry.Created
+	req.Description = entry.Description
 	req.Tag = u.SourceType
 	req.Source = u.String()
 	if len(req.Netblocks) == 0 {
-		req.Netblocks = []string{strings.TrimSpace(req.Prefix)}
+		req.Netblocks = []string{req.Prefix}
 
-		u.CheckRateLimit()
+		checkRateLimit(ctx, u)
 		u.executeASNQuery(ctx, req)
 	}
 
+	stats.RecordResult(ctx)
 	u.sys.Cache().Update(req)
 }
 
 func (u *Umbrella) executeASNQuery(ctx context.Context, req *requests.ASNRequest) {
-	headers := u.restHeaders()
+	var netblock []umbrellaNetblock
+	headers := u.restHeaders(ctx)
 	url := u.restASNToCIDRsURL(req.ASN)
-	page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
-	if err != nil {
-		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+	retryEmpty(ctx, u.sys, u, publicASN(req.ASN), "AS"+strconv.Itoa(req.ASN), func() bool {
+		if err := spendBudget(ctx); err != nil {
+			u.sys.Config().Log.Printf("%s: AS%d: %v", u.String(), req.ASN, err)
+			return false
+		}
+
+		page, err := http.RequestWebPage(ctx, url, nil, headers, nil)
+		if err != nil {
+			u.sys.Config().Log.Printf("%s: %s: %v", u.String(), url, err)
+			return false
+		}
+		// Extract the netblock information from the REST API results
+		netblock = u.netblockEntries(url, page)
+		return len(netblock) == 0 && umbrellaEmpty(page)
+	})
+	if len(netblock) == 0 {
 		return
 	}
-	// Extract the netblock information from the REST API results
-	var netblock []struct {
-		CIDR string `json:"cidr"`
-		Geo  struct {
-			CountryName string `json:"country_name"`
-			CountryCode string `json:"country_code"`
-		} `json:"geo"`
+
+	// Equivalent netblocks are only kept once, in their canonical form
+	netblocks := stringset.New()
+	defer netblocks.Close()
+
+	var cidrs []string
+	add := func(cidr string) {
+		if cidr = amassnet.CanonicalCIDR(cidr); cidr != "" && !netblocks.Has(cidr) {
+			netblocks.Insert(cidr)
+			cidrs = append(cidrs, cidr)
+		}
 	}
-	if err := json.Unmarshal([]byte(page), &netblock); err != nil || len(netblock) == 0 {
-		return
+	for _, cidr := range req.Netblocks {
+		add(cidr)
 	}
-
 	for _, nb := range netblock {
-		req.Netblocks = append(req.Netblocks, strings.TrimSpace(nb.CIDR))
-		if nb.CIDR == req.Prefix {
-			req.CC = nb.Geo.CountryCode
+		add(nb.CIDR)
+	}
+	req.Netblocks = cidrs
+	// A netblock of the AS can be more specific than the prefix reported for the address
+	if req.Address != "" {
+		if match := amassnet.LongestPrefixMatch(net.ParseIP(req.Address), req.Netblocks); match != "" {
+			req.Prefix = match
 		}
 	}
 	// If no basic AS info exists, then obtain an IP and query
@@ -264,9 +564,9 @@ func (u *Umbrella) executeASNQuery(ctx context.Context, req *requests.ASNRequest
 
 		if err == nil {
 			req.Address = addr.String()
-			req.CC = netblock[0].Geo.CountryCode
+			req.CC = netblock[0].CC
 
-			u.CheckRateLimit()
+			checkRateLimit(ctx, u)
 			u.executeASNAddrQuery(ctx, req)
 			return
 		}
@@ -274,7 +574,7 @@ func (u *Umbrella) executeASNQuery(ctx context.Context, req *requests.ASNRequest
 	// Finish populating the AS info in the request
 	for _, nb := range netblock {
 		if nb.CIDR == req.Prefix {
-			req.CC = nb.Geo.CountryCode
+			req.CC = nb.CC
 			break
 		}
 	}
@@ -283,12 +583,41 @@ func (u *Umbrella) executeASNQuery(ctx context.Context, req *requests.ASNRequest
 // Umbrella provides much more than this, but we're only interested in these
 // fields
 type whoisRecord struct {
-	NameServers         []string `json:"nameServers"`
-	AdminContactEmail   string   `json:"administrativeContactEmail"`
-	BillingContactEmail string   `json:"billingContactEmail"`
-	RegistrantEmail     string   `json:"registrantEmail"`
-	TechContactEmail    string   `json:"technicalContactEmail"`
-	ZoneContactEmail    string   `json:"zoneContactEmail"`
+	NameServers         whoisValues `json:"nameServers"`
+	AdminContactEmail   whoisValues `json:"administrativeContactEmail"`
+	BillingContactEmail whoisValues `json:"billingContactEmail"`
+	RegistrantEmail     whoisValues `json:"registrantEmail"`
+	TechContactEmail    whoisValues `json:"technicalContactEmail"`
+	ZoneContactEmail    whoisValues `json:"zoneContactEmail"`
+}
+
+// whoisValues holds a whois field that Umbrella provides as a string, an array of strings or null.
+type whoisValues []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Values of other types are ignored,
+// so an unexpected field does not cause the rest of the record to be lost.
+func (w *whoisValues) UnmarshalJSON(data []byte) error {
+	var val interface{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+
+	*w = nil
+	switch v := val.(type) {
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			*w = append(*w, s)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				if s := strings.TrimSpace(str); s != "" {
+					*w = append(*w, s)
+				}
+			}
+		}
+	}
+	return nil
 }
 
 // Umbrella provides the same response for email and ns reverse records. Makes
@@ -309,30 +638,33 @@ func (u *Umbrella) collateEmails(ctx context.Context, record *whoisRecord) []str
 	emails := stringset.New()
 	defer emails.Close()
 
-	if u.validateScope(ctx, record.AdminContactEmail) {
-		emails.InsertMany(record.AdminContactEmail)
-	}
-	if u.validateScope(ctx, record.BillingContactEmail) {
-		emails.InsertMany(record.BillingContactEmail)
-	}
-	if u.validateScope(ctx, record.RegistrantEmail) {
-		emails.InsertMany(record.RegistrantEmail)
-	}
-	if u.validateScope(ctx, record.TechContactEmail) {
-		emails.InsertMany(record.TechContactEmail)
-	}
-	if u.validateScope(ctx, record.ZoneContactEmail) {
-		emails.InsertMany(record.ZoneContactEmail)
+	for _, field := range []whoisValues{
+		record.AdminContactEmail,
+		record.BillingContactEmail,
+		record.RegistrantEmail,
+		record.TechContactEmail,
+		record.ZoneContactEmail,
+	} {
+		for _, email := range field {
+			if !umbrellaEmailRE.MatchString(email) {
+				u.sys.Config().Log.Printf("%s: Skipping the malformed whois email address %q", u.String(), email)
+				continue
+			}
+			// The scope is checked using the domain name of the address
+			if u.validateScope(ctx, email[strings.LastIndex(email, "@")+1:]) {
+				emails.Insert(strings.ToLower(email))
+			}
+		}
 	}
 	return emails.Slice()
 }
 
 func (u *Umbrella) queryWhois(ctx context.Context, domain string) *whoisRecord {
 	var whois whoisRecord
-	headers := u.restHeaders()
+	headers := u.restHeaders(ctx)
 	whoisURL := u.whoisRecordURL(domain)
 
-	u.CheckRateLimit()
+	checkRateLimit(ctx, u)
 	record, err := http.RequestWebPage(ctx, whoisURL, nil, headers, nil)
 	if err != nil {
 		u.sys.Config().Log.Printf("%s: %s: %v", u.String(), whoisURL, err)
@@ -351,11 +683,33 @@ func (u *Umbrella) queryReverseWhois(ctx context.Context, apiURL string) []strin
 	domains := stringset.New()
 	defer domains.Close()
 
-	headers := u.restHeaders()
+	// The limit is only raised when the user has not selected one for the data source
+	if dsc := u.sys.Config().GetDataSourceConfig(u.String()); dsc == nil || dsc.MaxResponseSize == 0 {
+		if http.MaxResponseSize(ctx) < umbrellaReverseWhoisMaxResponseSize {
+			ctx = http.WithMaxResponseSize(ctx, umbrellaReverseWhoisMaxResponseSize)
+		}
+	}
+
+	// Continue a query that failed part of the way through from the last chunk received,
+	// rather than spending the quota on the earlier chunks again
+	var start int
+	cfg := u.sys.Config()
+	if p := loadReverseWhoisProgress(cfg, apiURL); p != nil {
+		start = p.Offset
+		domains.InsertMany(p.Domains...)
+		cfg.Log.Printf("%s: %s: resuming from offset %d", u.String(), apiURL, start)
+	}
+
+	headers := u.restHeaders(ctx)
 	var whois map[string]rWhoisResponse
 	// Umbrella provides data in 500 piece chunks
-	for count, more := 0, true; more; count = count + 500 {
-		u.CheckRateLimit()
+	for count, more := start, true; more; count = count + 500 {
+		// Keep the chunks collected when the run reaches its time limit
+		if ctx.Err() != nil {
+			break
+		}
+
+		checkRateLimit(ctx, u)
 		fullAPIURL := fmt.Sprintf("%s&offset=%d", apiURL, count)
 		record, err := http.RequestWebPage(ctx, fullAPIURL, nil, headers, nil)
 		if err != nil {
@@ -382,6 +736,15 @@ func (u *Umbrella) queryReverseWhois(ctx context.Context, apiURL string) []strin
 				more = true
 			}
 		}
+
+		if more {
+			saveReverseWhoisProgress(cfg, apiURL, &reverseWhoisProgress{
+				Offset:  count + 500,
+				Domains: domains.Slice(),
+			})
+		} else {
+			saveReverseWhoisProgress(cfg, apiURL, nil)
+		}
 	}
 	return domains.Slice()
 }
@@ -406,15 +769,15 @@ func (u *Umbrella) whoisRequest(ctx context.Context, req *requests.WhoisRequest)
 		return
 	}
 
-	domains := stringset.New()
-	defer domains.Close()
-
+	// The domains sharing the nameservers of the domain are the most likely to be related, followed
+	// by those registered with the same email addresses and then the co-occurring domains
+	domains := make(relatedDomains)
 	emails := u.collateEmails(ctx, whoisRecord)
 	if len(emails) > 0 {
 		emailURL := u.reverseWhoisByEmailURL(emails...)
 		for _, d := range u.queryReverseWhois(ctx, emailURL) {
 			if !u.sys.Config().IsDomainInScope(d) {
-				domains.Insert(d)
+				domains.add(d, umbrellaEmailWeight)
 			}
 		}
 	}
@@ -429,33 +792,55 @@ func (u *Umbrella) whoisRequest(ctx context.Context, req *requests.WhoisRequest)
 		nsURL := u.reverseWhoisByNSURL(nameservers...)
 		for _, d := range u.queryReverseWhois(ctx, nsURL) {
 			if !u.sys.Config().IsDomainInScope(d) {
-				domains.Insert(d)
+				domains.add(d, umbrellaNameServerWeight)
 			}
 		}
 	}
 
-	if domains.Len() > 0 {
-		u.Output() <- &requests.WhoisRequest{
-			Domain:     req.Domain,
-			NewDomains: domains.Slice(),
-			Tag:        u.SourceType,
-			Source:     u.String(),
+	if !budgetExhausted(ctx) {
+		checkRateLimit(ctx, u)
+		for _, d := range u.queryCooccurrences(ctx, req.Domain) {
+			if !u.sys.Config().IsDomainInScope(d) {
+				domains.add(d, umbrellaCooccurrenceWeight)
+			}
 		}
 	}
+
+	if nameservers := requests.NormalizeNameServers(whoisRecord.NameServers...); len(domains) > 0 || len(nameservers) > 0 {
+		stats.RecordResult(ctx)
+		sendOutput(ctx, u.sys, u, &requests.WhoisRequest{
+			Domain:      req.Domain,
+			NewDomains:  capWhoisDomains(u.sys, u, req.Domain, domains),
+			NameServers: nameservers,
+			Tag:         u.SourceType,
+			Source:      u.String(),
+		})
+	}
 }
 
-func (u *Umbrella) restHeaders() map[string]string {
+// restHeaders returns the headers of a request sent with the key of the organization
+// carried by the context, or with the key of the data source.
+func (u *Umbrella) restHeaders(ctx context.Context) map[string]string {
 	headers := map[string]string{"Content-Type": "application/json"}
 
-	if u.creds != nil && u.creds.Key != "" {
-		headers["Authorization"] = "Bearer " + u.creds.Key
+	creds := u.creds
+	if a := accountFrom(ctx); a != nil {
+		creds = a.creds
+	}
+	if creds != nil && creds.Key != "" {
+		headers["Authorization"] = "Bearer " + creds.Key
 	}
 
 	return headers
 }
 
+// baseURL returns the address of the Investigate API, or the mirror selected by the base_url option.
+func (u *Umbrella) baseURL() string {
+	return u.sys.Config().BaseURL(u.String(), umbrellaBaseURL)
+}
+
 func (u *Umbrella) whoisBaseURL() string {
-	return `https://investigate.api.umbrella.com/whois/`
+	return u.baseURL() + "/whois/"
 }
 
 func (u *Umbrella) whoisRecordURL(domain string) string {
@@ -469,23 +854,44 @@ func (u *Umbrella) reverseWhoisByNSURL(ns ...string) string {
 }
 
 func (u *Umbrella) reverseWhoisByEmailURL(emails ...string) string {
-	emailQuery := strings.Join(emails, ",")
+	var escaped []string
+	for _, email := range emails {
+		escaped = append(escaped, url.QueryEscape(email))
+	}
+	emailQuery := strings.Join(escaped, ",")
 
 	return u.whoisBaseURL() + `emails?emailList=` + emailQuery
 }
 
+func (u *Umbrella) cooccurrencesURL(domain string) string {
+	return u.baseURL() + "/recommendations/name/" + domain + ".json"
+}
+
 func (u *Umbrella) restDNSURL(domain string) string {
-	return `https://investigate.api.umbrella.com/search/.*[.]` + domain + "?start=-30days&limit=1000"
+	return withQueryParams(u.sys, u, u.baseURL()+`/search/.*[.]`+domain+
+		"?start=-30days&limit="+strconv.Itoa(umbrellaSearchLimit))
+}
+
+// restDNSBatchURL returns the URL of a search for the names within any of the domains.
+func (u *Umbrella) restDNSBatchURL(domains ...string) string {
+	var escaped []string
+	for _, d := range domains {
+		escaped = append(escaped, strings.ReplaceAll(regexp.QuoteMeta(d), `\.`, "[.]"))
+	}
+	expr := `.*[.](` + strings.Join(escaped, "|") + `)`
+
+	return withQueryParams(u.sys, u, u.baseURL()+"/search/"+url.PathEscape(expr)+
+		"?start=-30days&limit="+strconv.Itoa(umbrellaSearchLimit))
 }
 
 func (u *Umbrella) restAddrURL(addr string) string {
-	return "https://investigate.api.umbrella.com/pdns/ip/" + addr + "?recordType=A,AAAA"
+	return withQueryParams(u.sys, u, u.baseURL()+"/pdns/ip/"+addr+"?recordType=A,AAAA")
 }
 
 func (u *Umbrella) restAddrToASNURL(addr string) string {
-	return fmt.Sprintf("https://investigate.api.umbrella.com/bgp_routes/ip/%s/as_for_ip.json", addr)
+	return u.baseURL() + "/bgp_routes/ip/" + addr + "/as_for_ip.json"
 }
 
 func (u *Umbrella) restASNToCIDRsURL(asn int) string {
-	return fmt.Sprintf("https://investigate.api.umbrella.com/bgp_routes/asn/%d/prefixes_for_asn.json", asn)
+	return u.baseURL() + "/bgp_routes/asn/" + strconv.Itoa(asn) + "/prefixes_for_asn.json"
 }
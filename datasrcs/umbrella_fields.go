@@ -0,0 +1,256 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+)
+
+// The names each Investigate API field has been known by, tried in order, so the BGP route
+// responses can still be read after a field is renamed.
+var (
+	umbrellaASNFields         = []string{"asn", "as_number", "asNumber", "as"}
+	umbrellaCIDRFields        = []string{"cidr", "prefix", "network", "netblock"}
+	umbrellaDescriptionFields = []string{"description", "as_name", "asName", "name"}
+	umbrellaRegistryFields    = []string{"ir", "registry", "rir"}
+	umbrellaDateFields        = []string{"creation_date", "creationDate", "created", "date"}
+	umbrellaGeoFields         = []string{"geo", "location"}
+	umbrellaCCFields          = []string{"country_code", "countryCode", "cc"}
+	// The members that can wrap the array of results in place of a bare array
+	umbrellaEnvelopeFields = []string{"data", "results", "items", "records", "prefixes"}
+)
+
+// umbrellaObject is a JSON object from an Investigate API response, read without a fixed struct.
+type umbrellaObject map[string]interface{}
+
+// umbrellaASEntry is an autonomous system announcing a prefix, from the as_for_ip endpoint.
+type umbrellaASEntry struct {
+	ASN         int
+	CIDR        string
+	Description string
+	Registry    string
+	Created     time.Time
+}
+
+// umbrellaNetblock is a prefix announced by an autonomous system, from the prefixes_for_asn endpoint.
+type umbrellaNetblock struct {
+	CIDR string
+	CC   string
+}
+
+// umbrellaEmpty returns true when the response was understood and held no results.
+func umbrellaEmpty(page string) bool {
+	entries, err := umbrellaEntries(page)
+	return err == nil && len(entries) == 0
+}
+
+// umbrellaEntries decodes a response holding an array of objects, an object wrapping the
+// array in one of the envelope members, or a single object.
+func umbrellaEntries(page string) ([]umbrellaObject, error) {
+	var val interface{}
+
+	d := json.NewDecoder(strings.NewReader(page))
+	d.UseNumber()
+	if err := d.Decode(&val); err != nil {
+		return nil, err
+	}
+
+	if obj, ok := val.(map[string]interface{}); ok {
+		for _, key := range umbrellaEnvelopeFields {
+			if arr, found := umbrellaObject(obj).value([]string{key}); found {
+				if _, ok := arr.([]interface{}); ok {
+					val = arr
+					break
+				}
+			}
+		}
+	}
+
+	switch v := val.(type) {
+	case []interface{}:
+		var entries []umbrellaObject
+		for _, e := range v {
+			if obj, ok := e.(map[string]interface{}); ok {
+				entries = append(entries, obj)
+			}
+		}
+		return entries, nil
+	case map[string]interface{}:
+		return []umbrellaObject{v}, nil
+	}
+	return nil, errors.New("the response is not a JSON object or array")
+}
+
+// value returns the first of the named members present in the object, ignoring the case of the names.
+func (o umbrellaObject) value(keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		if v, found := o[key]; found && v != nil {
+			return v, true
+		}
+		for k, v := range o {
+			if strings.EqualFold(k, key) && v != nil {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// str returns the named member as a string, accepting strings and numbers.
+func (o umbrellaObject) str(keys []string) (string, bool) {
+	v, found := o.value(keys)
+	if !found {
+		return "", false
+	}
+
+	switch s := v.(type) {
+	case string:
+		s = strings.TrimSpace(s)
+		return s, s != ""
+	case json.Number:
+		return s.String(), true
+	}
+	return "", false
+}
+
+// num returns the named member as an integer, accepting numbers and strings such as "13335" or "AS13335".
+func (o umbrellaObject) num(keys []string) (int, bool) {
+	s, found := o.str(keys)
+	if !found {
+		return 0, false
+	}
+
+	s = strings.TrimPrefix(strings.ToUpper(s), "AS")
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// object returns the named member when it is a JSON object.
+func (o umbrellaObject) object(keys []string) (umbrellaObject, bool) {
+	if v, found := o.value(keys); found {
+		if obj, ok := v.(map[string]interface{}); ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// asEntries reads the autonomous systems from the as_for_ip response. The entries missing the
+// ASN or a valid prefix are skipped, each missing field is reported once in the log, and the
+// prefixes are returned in their canonical form.
+func (u *Umbrella) asEntries(url, page string) []umbrellaASEntry {
+	objs, err := umbrellaEntries(page)
+	if err != nil {
+		u.sys.Config().Log.Printf("%s: %s: failed to parse the response: %v", u.String(), url, err)
+		return nil
+	}
+
+	var entries []umbrellaASEntry
+	for _, obj := range objs {
+		asn, found := obj.num(umbrellaASNFields)
+		if !found {
+			u.missingField(url, "asn")
+			continue
+		}
+		cidr, found := obj.str(umbrellaCIDRFields)
+		if !found {
+			u.missingField(url, "cidr")
+			continue
+		}
+		if cidr = amassnet.CanonicalCIDR(cidr); cidr == "" {
+			continue
+		}
+
+		entry := umbrellaASEntry{
+			ASN:      asn,
+			CIDR:     cidr,
+			Registry: umbrellaRegistry(obj),
+		}
+		if entry.Description, found = obj.str(umbrellaDescriptionFields); !found {
+			u.missingField(url, "description")
+		}
+		if date, found := obj.str(umbrellaDateFields); !found {
+			u.missingField(url, "creation_date")
+		} else if created, err := time.Parse("2006-01-02", date); err == nil {
+			entry.Created = created
+		} else if created, err := time.Parse(time.RFC3339, date); err == nil {
+			entry.Created = created
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// netblockEntries reads the prefixes from the prefixes_for_asn response in their canonical
+// form, reporting a missing prefix field once in the log.
+func (u *Umbrella) netblockEntries(url, page string) []umbrellaNetblock {
+	objs, err := umbrellaEntries(page)
+	if err != nil {
+		u.sys.Config().Log.Printf("%s: %s: failed to parse the response: %v", u.String(), url, err)
+		return nil
+	}
+
+	var netblocks []umbrellaNetblock
+	for _, obj := range objs {
+		cidr, found := obj.str(umbrellaCIDRFields)
+		if !found {
+			u.missingField(url, "cidr")
+			continue
+		}
+		if cidr = amassnet.CanonicalCIDR(cidr); cidr == "" {
+			continue
+		}
+
+		nb := umbrellaNetblock{CIDR: cidr}
+		if geo, found := obj.object(umbrellaGeoFields); found {
+			nb.CC, _ = geo.str(umbrellaCCFields)
+		} else {
+			nb.CC, _ = obj.str(umbrellaCCFields)
+		}
+		netblocks = append(netblocks, nb)
+	}
+	return netblocks
+}
+
+// missingField warns, once per field, that the API response lacked a field Amass depends on,
+// so a change to the API is noticed rather than resulting in silently empty findings.
+func (u *Umbrella) missingField(url, field string) {
+	if _, warned := u.missing.LoadOrStore(field, true); !warned {
+		u.sys.Config().Log.Printf("%s: %s: the response is missing the expected %s field, "+
+			"the Investigate API may have changed", u.String(), url, field)
+	}
+}
+
+// umbrellaRegistry returns the name of the regional internet registry, which the API provides
+// as a number, or as the name itself.
+func umbrellaRegistry(obj umbrellaObject) string {
+	s, found := obj.str(umbrellaRegistryFields)
+	if !found {
+		return "N/A"
+	}
+
+	switch s {
+	case "1":
+		return "AfriNIC"
+	case "2":
+		return "APNIC"
+	case "3":
+		return "ARIN"
+	case "4":
+		return "LACNIC"
+	case "5":
+		return "RIPE NCC"
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return "N/A"
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+const (
+	// The file in the output directory that keeps the progress of the reverse whois queries between runs
+	reverseWhoisProgressFile = "umbrella_reverse_whois.json"
+	// Saved progress older than this is discarded, since the results may have shifted between the chunks
+	reverseWhoisProgressMaxAge = 24 * time.Hour
+)
+
+// reverseWhoisProgress is the state of a chunked reverse whois query that did not complete:
+// the offset of the next chunk and the domains collected from the chunks already received.
+type reverseWhoisProgress struct {
+	Offset  int       `json:"offset"`
+	Domains []string  `json:"domains"`
+	Updated time.Time `json:"updated"`
+}
+
+var reverseWhoisProgressLock sync.Mutex
+
+func reverseWhoisProgressPath(cfg *config.Config) string {
+	return filepath.Join(config.OutputDirectory(cfg.Dir), reverseWhoisProgressFile)
+}
+
+// loadReverseWhoisProgress returns the saved progress of the query, or nil when the
+// query has no recent progress to continue from.
+func loadReverseWhoisProgress(cfg *config.Config, apiURL string) *reverseWhoisProgress {
+	reverseWhoisProgressLock.Lock()
+	defer reverseWhoisProgressLock.Unlock()
+
+	p, found := readReverseWhoisProgress(reverseWhoisProgressPath(cfg))[apiURL]
+	if !found || p == nil || time.Since(p.Updated) > reverseWhoisProgressMaxAge {
+		return nil
+	}
+	return p
+}
+
+func readReverseWhoisProgress(path string) map[string]*reverseWhoisProgress {
+	progress := make(map[string]*reverseWhoisProgress)
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &progress)
+	}
+	return progress
+}
+
+// saveReverseWhoisProgress records the progress of the query, or removes the entry when p is nil.
+// Entries left behind by queries that never completed are removed once they are too old to be used.
+func saveReverseWhoisProgress(cfg *config.Config, apiURL string, p *reverseWhoisProgress) {
+	reverseWhoisProgressLock.Lock()
+	defer reverseWhoisProgressLock.Unlock()
+
+	path := reverseWhoisProgressPath(cfg)
+	progress := readReverseWhoisProgress(path)
+	_, changed := progress[apiURL]
+	for key, entry := range progress {
+		if entry == nil || time.Since(entry.Updated) > reverseWhoisProgressMaxAge {
+			delete(progress, key)
+			changed = true
+		}
+	}
+
+	if p == nil && !changed {
+		return
+	} else if p == nil {
+		delete(progress, apiURL)
+	} else {
+		p.Updated = time.Now()
+		progress[apiURL] = p
+	}
+
+	data, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		cfg.Log.Printf("Failed to save the progress of the Umbrella reverse whois queries: %v", err)
+	}
+}
@@ -0,0 +1,498 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	nethttp "net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+func TestUmbrellaRecordAge(t *testing.T) {
+	recent := time.Now().AddDate(0, 0, -5).Unix()
+	stale := time.Now().AddDate(0, 0, -400).Unix()
+	_ = serveResponses(t, func(path string) string {
+		return fmt.Sprintf(`{"records":[{"rr":"www.owasp.org.","lastSeen":%d},`+
+			`{"rr":"old.owasp.org.","lastSeen":%d},{"rr":"unknown.owasp.org."}]}`, recent, stale)
+	})
+
+	tests := []struct {
+		age      int
+		expected []string
+	}{
+		{0, []string{"www.owasp.org", "old.owasp.org", "unknown.owasp.org"}},
+		{90, []string{"www.owasp.org", "unknown.owasp.org"}},
+	}
+
+	for _, test := range tests {
+		sys := testSystem()
+		sys.Config().GetDataSourceConfig("Umbrella").MaxRecordAge = test.age
+
+		u := NewUmbrella(sys)
+		u.creds = &config.Credentials{Key: "fake"}
+
+		var names []string
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < len(test.expected); i++ {
+				select {
+				case out := <-u.Output():
+					names = append(names, out.(*requests.DNSRequest).Name)
+				case <-time.After(time.Second):
+					return
+				}
+			}
+		}()
+
+		u.addrRequest(context.Background(), &requests.AddrRequest{Address: "10.0.0.1"})
+		<-done
+		if fmt.Sprint(names) != fmt.Sprint(test.expected) {
+			t.Errorf("Maximum age of %d days: got %v, expected %v", test.age, names, test.expected)
+		}
+		// No other names should have been sent
+		select {
+		case out := <-u.Output():
+			t.Errorf("Maximum age of %d days: the stale name %v was sent", test.age, out)
+		default:
+		}
+		_ = u.Stop()
+	}
+}
+
+func TestUmbrellaMostSpecificPrefix(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasSuffix(path, "as_for_ip.json") {
+			return `[{"creation_date":"2010-01-01","ir":3,"description":"Broad","asn":64500,"cidr":"10.0.0.0/8"},` +
+				`{"creation_date":"2012-01-01","ir":3,"description":"Specific","asn":64501,"cidr":"10.1.0.0/16"}]`
+		}
+		return `[{"cidr":"10.1.0.0/16","geo":{"country_code":"US"}},{"cidr":"10.1.2.0/24","geo":{"country_code":"CA"}}]`
+	})
+
+	sys := testSystem()
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	req := &requests.ASNRequest{Address: "10.1.2.3"}
+	u.executeASNAddrQuery(context.Background(), req)
+	if req.ASN != 64501 || req.Description != "Specific" {
+		t.Errorf("The AS of the most specific route was not selected: %+v", req)
+	}
+	if req.Prefix != "10.1.2.0/24" || req.CC != "CA" {
+		t.Errorf("The most specific netblock was not selected as the prefix: %s, %s", req.Prefix, req.CC)
+	}
+}
+
+func TestUmbrellaAlteredResponseShape(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasSuffix(path, "as_for_ip.json") {
+			return `{"data":[{"creationDate":"2012-01-01T00:00:00Z","registry":"ARIN","asName":"Specific",` +
+				`"as_number":"AS64501","prefix":"10.1.0.0/16","extra":{"new":true}}]}`
+		}
+		return `{"results":[{"prefix":"10.1.0.0/16","location":{"countryCode":"US"}},{"network":"10.2.0.0/16","cc":"CA"}]}`
+	})
+
+	sys := testSystem()
+	var logs bytes.Buffer
+	sys.Config().Log = log.New(&logs, "", 0)
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	req := &requests.ASNRequest{Address: "10.1.2.3"}
+	u.executeASNAddrQuery(context.Background(), req)
+	if req.ASN != 64501 || req.Description != "Specific" || req.Registry != "ARIN" || req.AllocationDate.Year() != 2012 {
+		t.Errorf("The AS information was not read from the renamed fields: %+v", req)
+	}
+	if req.Prefix != "10.1.0.0/16" || req.CC != "US" || len(req.Netblocks) != 2 {
+		t.Errorf("The netblocks were not read from the renamed fields: %s, %s, %v", req.Prefix, req.CC, req.Netblocks)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Warnings were logged for a complete response: %s", logs.String())
+	}
+}
+
+func TestUmbrellaCanonicalNetblocks(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasSuffix(path, "as_for_ip.json") {
+			return `[{"asn":64501,"cidr":"2001:DB8:0::/32","description":"Example","ir":3}]`
+		}
+		return `[{"cidr":"2001:db8::/32"},{"cidr":" 2001:0DB8:0000::/32"},{"cidr":"10.1.0.0 / 16"},` +
+			`{"cidr":"10.1.2.0/16"},{"cidr":"not a netblock"}]`
+	})
+
+	u := NewUmbrella(testSystem())
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	req := &requests.ASNRequest{Address: "2001:db8::1"}
+	u.executeASNAddrQuery(context.Background(), req)
+	if req.Prefix != "2001:db8::/32" {
+		t.Errorf("The prefix was not canonicalized: %s", req.Prefix)
+	}
+	if fmt.Sprint(req.Netblocks) != "[2001:db8::/32 10.1.0.0/16]" {
+		t.Errorf("The equivalent netblocks were not deduplicated: %v", req.Netblocks)
+	}
+}
+
+func TestUmbrellaMissingFields(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasSuffix(path, "as_for_ip.json") {
+			return `[{"autonomous_system":64501,"route":"10.1.0.0/16"},{"autonomous_system":64502,"route":"10.2.0.0/16"}]`
+		}
+		return `[]`
+	})
+
+	sys := testSystem()
+	var logs bytes.Buffer
+	sys.Config().Log = log.New(&logs, "", 0)
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	for i := 0; i < 2; i++ {
+		req := &requests.ASNRequest{Address: "10.1.2.3"}
+		u.executeASNAddrQuery(context.Background(), req)
+		if req.ASN != 0 {
+			t.Errorf("An AS was returned from the unrecognized response: %+v", req)
+		}
+	}
+	// The missing field is only reported once
+	if n := strings.Count(logs.String(), "missing the expected asn field"); n != 1 {
+		t.Errorf("Expected one warning about the missing field, got %d: %s", n, logs.String())
+	}
+}
+
+func TestUmbrellaCooccurrences(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		if strings.HasPrefix(path, "/recommendations/name/") {
+			return `{"found":true,"pfs2":[["www.owasp.org.",0.9],["owasp-cdn.net.",0.8],` +
+				`["api.owasp.org.",0.7],["unrelated.com.",0.1]]}`
+		}
+		if strings.HasPrefix(path, "/whois/") {
+			return `{"nameServers":["NS1.OWASP.org.","ns2.cloudflare.com","ns1.owasp.org","not a name"]}`
+		}
+		return `{"matches":[]}`
+	})
+
+	sys := testSystem()
+	sys.Config().GetDataSourceConfig("Umbrella").MaxRelated = 3
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	var names, related, nameservers []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			select {
+			case out := <-u.Output():
+				switch req := out.(type) {
+				case *requests.DNSRequest:
+					names = append(names, req.Name)
+				case *requests.WhoisRequest:
+					related = append(related, req.NewDomains...)
+					nameservers = append(nameservers, req.NameServers...)
+				}
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	u.dnsRequest(context.Background(), &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"})
+	<-done
+	if fmt.Sprint(names) != "[www.owasp.org api.owasp.org]" {
+		t.Errorf("Unexpected in-scope names: %v", names)
+	}
+	// The lowest scoring domain is beyond the limit
+	if fmt.Sprint(related) != "[owasp-cdn.net]" {
+		t.Errorf("Unexpected related domains: %v", related)
+	}
+	if fmt.Sprint(nameservers) != "[ns1.owasp.org ns2.cloudflare.com]" {
+		t.Errorf("The nameservers were not normalized: %v", nameservers)
+	}
+}
+
+func TestUmbrellaWhoisNullFields(t *testing.T) {
+	_ = serveResponses(t, func(path string) string {
+		return `{"nameServers":null,"administrativeContactEmail":null,` +
+			`"billingContactEmail":["billing@owasp.org",null,"not an email"],` +
+			`"registrantEmail":"Registrant+Web@OWASP.org","technicalContactEmail":{"email":"tech@owasp.org"},` +
+			`"zoneContactEmail":"owasp.org?emailList=x@owasp.org"}`
+	})
+
+	sys := testSystem()
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	record := u.queryWhois(context.Background(), "owasp.org")
+	if record == nil {
+		t.Fatalf("The whois record with null fields was not parsed")
+	}
+	if len(record.NameServers) != 0 || len(record.AdminContactEmail) != 0 || len(record.TechContactEmail) != 0 {
+		t.Errorf("Values were parsed from null or unexpected fields: %+v", record)
+	}
+
+	emails := u.collateEmails(context.Background(), record)
+	sort.Strings(emails)
+	if fmt.Sprint(emails) != "[billing@owasp.org registrant+web@owasp.org]" {
+		t.Errorf("Unexpected email addresses: %v", emails)
+	}
+	if u := u.reverseWhoisByEmailURL(emails...); !strings.HasSuffix(u, "emailList=billing%40owasp.org,registrant%2Bweb%40owasp.org") {
+		t.Errorf("The email addresses were not escaped in the query: %s", u)
+	}
+}
+
+func TestUmbrellaQueryParams(t *testing.T) {
+	sys := testSystem()
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+
+	if url := u.restAddrURL("10.0.0.1"); url != "https://investigate.api.umbrella.com/pdns/ip/10.0.0.1?recordType=A,AAAA" {
+		t.Errorf("The URL was changed without query parameters: %s", url)
+	}
+
+	sys.Config().GetDataSourceConfig("Umbrella").QueryParams = map[string]string{"recordType": "A", "limit": "500"}
+	if url := u.restAddrURL("10.0.0.1"); url != "https://investigate.api.umbrella.com/pdns/ip/10.0.0.1?limit=500&recordType=A" {
+		t.Errorf("The query parameters were not merged: %s", url)
+	}
+	if url := u.restDNSURL("owasp.org"); url != "https://investigate.api.umbrella.com/search/.*[.]owasp.org?limit=500&recordType=A&start=-30days" {
+		t.Errorf("The query parameters were not merged: %s", url)
+	}
+}
+
+func TestUmbrellaBatchSearch(t *testing.T) {
+	var truncated int32
+	var searches []string
+	var lock sync.Mutex
+	_ = serveResponses(t, func(path string) string {
+		if !strings.HasPrefix(path, "/search/") {
+			return ""
+		}
+
+		lock.Lock()
+		searches = append(searches, path)
+		lock.Unlock()
+		if strings.Contains(path, "|") && atomic.LoadInt32(&truncated) == 1 {
+			return `{"totalResults":3,"moreDataAvailable":true,"matches":[{"name":"www.owasp.org"}]}`
+		}
+		return `{"totalResults":2,"matches":[{"name":"www.owasp.org"},{"name":"api.owasp.net"}]}`
+	})
+
+	sys := testSystem()
+	sys.Config().AddDomain("owasp.net")
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	names := make(chan string, 10)
+	go func() {
+		for out := range u.Output() {
+			if req, ok := out.(*requests.DNSRequest); ok {
+				names <- req.Name
+			}
+		}
+	}()
+
+	reqs := []*requests.DNSRequest{
+		{Name: "owasp.org", Domain: "owasp.org"},
+		{Name: "owasp.net", Domain: "owasp.net"},
+		{Name: "example.com", Domain: "example.com"},
+	}
+	u.dnsRequests(context.Background(), reqs)
+	if len(searches) != 1 || searches[0] != "/search/.*[.](owasp[.]net|owasp[.]org)" {
+		t.Errorf("The in-scope domains were not searched together: %v", searches)
+	}
+
+	var found []string
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-names:
+			found = append(found, name)
+		case <-time.After(time.Second):
+		}
+	}
+	sort.Strings(found)
+	if fmt.Sprint(found) != "[api.owasp.net www.owasp.org]" {
+		t.Errorf("The names were not provided for each domain: %v", found)
+	}
+
+	// Results that do not fit in the response are requested for each domain on its own
+	atomic.StoreInt32(&truncated, 1)
+	searches = nil
+	u.dnsRequests(context.Background(), reqs[:2])
+	if len(searches) != 3 || strings.Contains(searches[1], "|") || strings.Contains(searches[2], "|") {
+		t.Errorf("The truncated search was not split by domain: %v", searches)
+	}
+}
+
+func TestUmbrellaReverseWhoisResume(t *testing.T) {
+	var offsets []string
+	var fail int32 = 1
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		offset := req.URL.Query().Get("offset")
+		offsets = append(offsets, offset)
+
+		status, body := 200, ""
+		switch offset {
+		case "0":
+			body = `{"owasp.org":{"totalResults":2,"moreDataAvailable":true,"domains":[{"domain":"www.owasp.org","current":true}]}}`
+		case "500":
+			if atomic.LoadInt32(&fail) == 1 {
+				status = 503
+				break
+			}
+			body = `{"owasp.org":{"totalResults":2,"moreDataAvailable":false,"domains":[{"domain":"api.owasp.org","current":true}]}}`
+		}
+		return &nethttp.Response{
+			StatusCode: status,
+			Status:     fmt.Sprintf("%d", status),
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+	})
+	defer func() { http.DefaultClient.Transport = orig }()
+
+	sys := testSystem()
+	sys.Config().Dir = t.TempDir()
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	u.creds = &config.Credentials{Key: "fake"}
+
+	apiURL := u.reverseWhoisByEmailURL("admin@owasp.org")
+	if domains := u.queryReverseWhois(context.Background(), apiURL); fmt.Sprint(domains) != "[www.owasp.org]" {
+		t.Errorf("The domains of the first chunk were not returned: %v", domains)
+	}
+	if p := loadReverseWhoisProgress(sys.Config(), apiURL); p == nil || p.Offset != 500 {
+		t.Fatalf("The offset of the failed chunk was not saved: %+v", p)
+	}
+
+	offsets = nil
+	atomic.StoreInt32(&fail, 0)
+	domains := u.queryReverseWhois(context.Background(), apiURL)
+	sort.Strings(domains)
+	if fmt.Sprint(offsets) != "[500]" {
+		t.Errorf("The query did not resume from the saved offset: %v", offsets)
+	}
+	if fmt.Sprint(domains) != "[api.owasp.org www.owasp.org]" {
+		t.Errorf("The domains of the earlier chunks were not returned: %v", domains)
+	}
+	if p := loadReverseWhoisProgress(sys.Config(), apiURL); p != nil {
+		t.Errorf("The progress was kept after the query completed: %+v", p)
+	}
+
+	// A completed query starts again from the first chunk
+	offsets = nil
+	_ = u.queryReverseWhois(context.Background(), apiURL)
+	if fmt.Sprint(offsets) != "[0 500]" {
+		t.Errorf("The completed query did not start from the first chunk: %v", offsets)
+	}
+}
+
+func TestUmbrellaOrganizations(t *testing.T) {
+	var lock sync.Mutex
+	keys := make(map[string]int)
+
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		lock.Lock()
+		keys[req.Header.Get("Authorization")]++
+		lock.Unlock()
+
+		body := `{"records":[{"rr":"www.owasp.org."}]}`
+		if strings.HasSuffix(req.URL.Path, "as_for_ip.json") {
+			body = `[{"asn":64500,"cidr":"10.0.0.0/8","description":"Example"}]`
+		} else if strings.HasSuffix(req.URL.Path, "prefixes_for_asn.json") {
+			body = `[{"cidr":"10.0.0.0/8","geo":{"country_code":"US"}}]`
+		}
+		return &nethttp.Response{
+			StatusCode: 200,
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+	})
+	defer func() { http.DefaultClient.Transport = orig }()
+
+	sys := testSystem()
+	sys.(*systems.SimpleSystem).Filter = requests.NewNameFilter(1000, 0.001)
+	dsc := sys.Config().GetDataSourceConfig("Umbrella")
+	for _, org := range []string{"tenant-b", "tenant-a", "nokey"} {
+		creds := &config.Credentials{Name: org}
+		if org != "nokey" {
+			creds.Key = org + "-key"
+		}
+		if err := dsc.AddCredentials(creds); err != nil {
+			t.Fatalf("Failed to add the credentials: %v", err)
+		}
+	}
+
+	u := NewUmbrella(sys)
+	defer func() { _ = u.Stop() }()
+	if u.accounts = sourceAccounts(sys, u, 100); len(u.accounts) != 2 {
+		t.Fatalf("Expected the 2 organizations with a key, got %d", len(u.accounts))
+	}
+	u.creds = u.accounts[0].creds
+
+	// The name is reported once through each organization, labeled with it
+	u.dispatch(&requests.AddrRequest{Address: "10.0.0.1"}, false)
+	var orgs []string
+	for i := 0; i < 2; i++ {
+		select {
+		case out := <-u.Output():
+			req := out.(*requests.DNSRequest)
+			if req.Name != "www.owasp.org" {
+				t.Errorf("Unexpected name reported: %s", req.Name)
+			}
+			orgs = append(orgs, req.Org)
+		case <-time.After(time.Second):
+			t.Fatal("The name was not reported through each organization")
+		}
+	}
+	if fmt.Sprint(orgs) != "[tenant-a tenant-b]" {
+		t.Errorf("Unexpected organization labels: %v", orgs)
+	}
+	// Already reported through both organizations
+	u.dispatch(&requests.AddrRequest{Address: "10.0.0.1"}, false)
+	select {
+	case out := <-u.Output():
+		t.Errorf("The name was reported again: %v", out)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The AS information is obtained through one organization at a time
+	lock.Lock()
+	keys = make(map[string]int)
+	lock.Unlock()
+	for _, asn := range []int{64500, 64501} {
+		u.dispatch(&requests.ASNRequest{ASN: asn}, false)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(keys) != 2 || keys["Bearer tenant-a-key"] == 0 || keys["Bearer tenant-b-key"] == 0 {
+		t.Errorf("The AS requests did not take turns between the organizations: %v", keys)
+	}
+}
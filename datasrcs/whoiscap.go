@@ -0,0 +1,48 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// relatedDomains holds the domains found by the reverse whois searches of a domain, each with a
+// score that grows with the evidence relating it to the domain.
+type relatedDomains map[string]int
+
+// add raises the score of the domain by the weight of the evidence provided.
+func (r relatedDomains) add(domain string, weight int) {
+	if d := strings.ToLower(strings.TrimSpace(domain)); d != "" {
+		r[d] += weight
+	}
+}
+
+// capWhoisDomains returns the related domains with the highest scores first, in alphabetical order
+// when the scores are equal, keeping no more than the max_whois_domains setting allows. A notice is
+// logged when domains are left out, since a search on a shared email address, such as the one of a
+// privacy service, can return thousands of domains that have nothing to do with the target.
+func capWhoisDomains(sys systems.System, srv service.Service, domain string, related relatedDomains) []string {
+	domains := make([]string, 0, len(related))
+	for d := range related {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if related[domains[i]] != related[domains[j]] {
+			return related[domains[i]] > related[domains[j]]
+		}
+		return domains[i] < domains[j]
+	})
+
+	if max := sys.Config().MaxWhoisDomains; max > 0 && len(domains) > max {
+		sys.Config().Log.Printf("%s: %s: Kept %d of the %d related domains, the rest exceed max_whois_domains",
+			srv.String(), domain, max, len(domains))
+		domains = domains[:max]
+	}
+	return domains
+}
@@ -0,0 +1,524 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/config"
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+// The most pages requested for each reverse whois search, which return up to 10,000 domains each
+const whoisxmlMaxReverseWhoisPages = 5
+
+// The registrant contact fields searched by the reverse whois queries
+const (
+	whoisxmlEmailField = "RegistrantContact.Email"
+	whoisxmlNameField  = "RegistrantContact.Name"
+	whoisxmlOrgField   = "RegistrantContact.Organization"
+)
+
+// Registrant values containing these words belong to privacy services and redacted records,
+// which are shared by far too many unrelated domains to be searched
+var whoisxmlRedactedWords = []string{
+	"redacted", "privacy", "private", "protected", "proxy", "withheld", "not disclosed", "whoisguard", "gdpr",
+}
+
+// WhoisXMLAPI is the Service that handles access to the WhoisXML API data source.
+type WhoisXMLAPI struct {
+	service.BaseService
+
+	SourceType string
+	sys        systems.System
+	creds      *config.Credentials
+}
+
+// NewWhoisXMLAPI returns he object initialized, but not yet started.
+func NewWhoisXMLAPI(sys systems.System) *WhoisXMLAPI {
+	w := &WhoisXMLAPI{
+		SourceType: requests.API,
+		sys:        sys,
+	}
+
+	go w.requests()
+	w.BaseService = *service.NewBaseService(w, "WhoisXMLAPI")
+	return w
+}
+
+// Description implements the Service interface.
+func (w *WhoisXMLAPI) Description() string {
+	return w.SourceType
+}
+
+// OnStart implements the Service interface.
+func (w *WhoisXMLAPI) OnStart() error {
+	w.creds = w.sys.Config().GetDataSourceConfig(w.String()).GetCredentials()
+
+	if w.creds == nil || w.creds.Key == "" {
+		estr := fmt.Sprintf("%s: API key data was not provided", w.String())
+		w.sys.Config().Log.Print(estr)
+		return errors.New(estr)
+	}
+
+	setRateLimit(w.sys, w, 2)
+	return nil
+}
+
+func (w *WhoisXMLAPI) requests() {
+	for {
+		select {
+		case <-w.Done():
+			return
+		case in := <-w.Input():
+			ctx := sourceContext(w.sys, w)
+			switch req := in.(type) {
+			case *requests.DNSRequest:
+				checkRateLimit(ctx, w)
+				w.dnsRequest(ctx, req)
+			case *requests.ASNRequest:
+				checkRateLimit(ctx, w)
+				w.asnRequest(ctx, req)
+			case *requests.WhoisRequest:
+				checkRateLimit(ctx, w)
+				w.whoisRequest(ctx, req)
+			}
+		}
+	}
+}
+
+func (w *WhoisXMLAPI) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
+	if w.creds == nil || w.creds.Key == "" {
+		return
+	}
+	if !w.sys.Config().IsDomainInScope(req.Domain) {
+		return
+	}
+
+	w.sys.Config().Log.Printf("Querying %s for %s subdomains", w.String(), req.Domain)
+	page, err := w.request(ctx, w.subdomainsURL(req.Domain), nil)
+	if err != nil {
+		w.sys.Config().Log.Printf("%s: %s subdomains: %v", w.String(), req.Domain, err)
+		return
+	}
+	// Extract the subdomain names from the REST API results
+	var subs struct {
+		Result struct {
+			Count   int `json:"count"`
+			Records []struct {
+				Domain string `json:"domain"`
+			} `json:"records"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(page), &subs); err != nil {
+		return
+	}
+	for _, r := range subs.Result.Records {
+		genNewNameEvent(ctx, w.sys, w, http.CleanName(r.Domain))
+	}
+}
+
+// whoisxmlContact holds the fields of a whois contact that can be used in a reverse whois search.
+type whoisxmlContact struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization"`
+	Email        string `json:"email"`
+}
+
+type whoisxmlRecord struct {
+	Registrant  whoisxmlContact `json:"registrant"`
+	NameServers struct {
+		HostNames []string `json:"hostNames"`
+	} `json:"nameServers"`
+	// The registry provides the contact when the registrar record is thin
+	RegistryData struct {
+		Registrant  whoisxmlContact `json:"registrant"`
+		NameServers struct {
+			HostNames []string `json:"hostNames"`
+		} `json:"nameServers"`
+	} `json:"registryData"`
+}
+
+// whoisxmlSearchTerm is a registrant contact field and the value searched for in it. A term
+// without a field is searched for in every field of the whois records.
+type whoisxmlSearchTerm struct {
+	Field string `json:"field"`
+	Term  string `json:"term"`
+}
+
+func (w *WhoisXMLAPI) whoisRequest(ctx context.Context, req *requests.WhoisRequest) {
+	if w.creds == nil || w.creds.Key == "" {
+		return
+	}
+	if !w.sys.Config().IsDomainInScope(req.Domain) {
+		return
+	}
+
+	// The records mentioning the root domain are searched even when its whois record is unavailable
+	terms := []whoisxmlSearchTerm{{Term: req.Domain}}
+	record := w.queryWhois(ctx, req.Domain)
+	if record != nil {
+		terms = append(terms, w.searchTerms(record)...)
+	}
+
+	// The domains matching more of the registrant details are the most likely to be related
+	domains := make(relatedDomains)
+	for _, term := range terms {
+		if budgetExhausted(ctx) {
+			break
+		}
+
+		checkRateLimit(ctx, w)
+		for _, d := range w.queryReverseWhois(ctx, term) {
+			// The domains already in scope are enumerated anyway, so only the new ones are reported
+			if d != "" && !w.sys.Config().IsDomainInScope(d) {
+				domains.add(d, 1)
+			}
+		}
+	}
+
+	var hostnames []string
+	if record != nil {
+		hostnames = record.NameServers.HostNames
+		if len(hostnames) == 0 {
+			hostnames = record.RegistryData.NameServers.HostNames
+		}
+	}
+	if nameservers := requests.NormalizeNameServers(hostnames...); len(domains) > 0 || len(nameservers) > 0 {
+		stats.RecordResult(ctx)
+		sendOutput(ctx, w.sys, w, &requests.WhoisRequest{
+			Domain:      req.Domain,
+			NewDomains:  capWhoisDomains(w.sys, w, req.Domain, domains),
+			NameServers: nameservers,
+			Tag:         w.SourceType,
+			Source:      w.String(),
+		})
+	}
+}
+
+func (w *WhoisXMLAPI) queryWhois(ctx context.Context, domain string) *whoisxmlRecord {
+	if err := spendBudget(ctx); err != nil {
+		w.sys.Config().Log.Printf("%s: %s: %v", w.String(), domain, err)
+		return nil
+	}
+
+	page, err := w.request(ctx, w.whoisURL(domain), nil)
+	if err != nil {
+		w.sys.Config().Log.Printf("%s: %s whois: %v", w.String(), domain, err)
+		return nil
+	}
+
+	var resp struct {
+		Record *whoisxmlRecord `json:"WhoisRecord"`
+	}
+	if err := json.Unmarshal([]byte(page), &resp); err != nil {
+		w.sys.Config().Log.Printf("%s: %s whois: %v", w.String(), domain, err)
+		return nil
+	}
+	return resp.Record
+}
+
+// searchTerms returns the registrant email address, name and organization of the whois record
+// that can be searched for related domains. The email address is only used when its domain is
+// in scope, and the values hidden by privacy services are skipped.
+func (w *WhoisXMLAPI) searchTerms(record *whoisxmlRecord) []whoisxmlSearchTerm {
+	var terms []whoisxmlSearchTerm
+
+	seen := stringset.New()
+	defer seen.Close()
+
+	add := func(field, value string) {
+		value = strings.TrimSpace(value)
+		key := field + "/" + strings.ToLower(value)
+		if value == "" || seen.Has(key) || whoisxmlRedacted(value) {
+			return
+		}
+
+		seen.Insert(key)
+		terms = append(terms, whoisxmlSearchTerm{Field: field, Term: value})
+	}
+
+	for _, c := range []whoisxmlContact{record.Registrant, record.RegistryData.Registrant} {
+		if email := strings.ToLower(strings.TrimSpace(c.Email)); umbrellaEmailRE.MatchString(email) {
+			if w.sys.Config().IsDomainInScope(email[strings.LastIndex(email, "@")+1:]) {
+				add(whoisxmlEmailField, email)
+			}
+		}
+		add(whoisxmlNameField, c.Name)
+		add(whoisxmlOrgField, c.Organization)
+	}
+	return terms
+}
+
+// whoisxmlRedacted returns true when the whois value was provided by a privacy service.
+func whoisxmlRedacted(value string) bool {
+	value = strings.ToLower(value)
+
+	for _, word := range whoisxmlRedactedWords {
+		if strings.Contains(value, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryReverseWhois returns the domains currently registered with the search term, following
+// the pages of the results until they are complete or the page limit has been reached.
+func (w *WhoisXMLAPI) queryReverseWhois(ctx context.Context, term whoisxmlSearchTerm) []string {
+	var domains []string
+	var searchAfter string
+
+	desc := fmt.Sprintf("reverse whois for %q", term.Term)
+	if term.Field != "" {
+		desc = fmt.Sprintf("reverse whois for %s %q", term.Field, term.Term)
+	}
+	for page := 0; page < whoisxmlMaxReverseWhoisPages; page++ {
+		// Keep the pages collected when the run reaches its time limit
+		if ctx.Err() != nil {
+			break
+		}
+		if err := spendBudget(ctx); err != nil {
+			w.sys.Config().Log.Printf("%s: %s: %v", w.String(), desc, err)
+			break
+		}
+		if page > 0 {
+			checkRateLimit(ctx, w)
+		}
+
+		resp, err := w.request(ctx, w.reverseWhoisURL(), w.reverseWhoisBody(term, searchAfter))
+		if err != nil {
+			w.sys.Config().Log.Printf("%s: %s: %v", w.String(), desc, err)
+			break
+		}
+
+		var result struct {
+			Count       int      `json:"domainsCount"`
+			Domains     []string `json:"domainsList"`
+			SearchAfter string   `json:"nextPageSearchAfter"`
+		}
+		if err := json.Unmarshal([]byte(resp), &result); err != nil {
+			w.sys.Config().Log.Printf("%s: %s: %v", w.String(), desc, err)
+			break
+		}
+
+		for _, d := range result.Domains {
+			domains = append(domains, http.CleanName(d))
+		}
+		if result.SearchAfter == "" || len(result.Domains) == 0 {
+			return domains
+		}
+		searchAfter = result.SearchAfter
+	}
+
+	if searchAfter != "" {
+		w.sys.Config().Log.Printf("%s: %s: Stopped after %d pages of results", w.String(), desc, whoisxmlMaxReverseWhoisPages)
+	}
+	return domains
+}
+
+func (w *WhoisXMLAPI) reverseWhoisBody(term whoisxmlSearchTerm, searchAfter string) map[string]interface{} {
+	body := map[string]interface{}{
+		"searchType": "current",
+		"mode":       "purchase",
+		"punycode":   true,
+	}
+	if term.Field == "" {
+		body["basicSearchTerms"] = map[string][]string{"include": {term.Term}}
+	} else {
+		body["advancedSearchTerms"] = []whoisxmlSearchTerm{term}
+	}
+	if searchAfter != "" {
+		body["searchAfter"] = searchAfter
+	}
+	return body
+}
+
+// whoisxmlNetblocks is the part of the IP Netblocks API response used for the AS information.
+type whoisxmlNetblocks struct {
+	Result struct {
+		Count    int `json:"count"`
+		Inetnums []struct {
+			Source string `json:"source"`
+			Org    *struct {
+				Country string `json:"country"`
+				Name    string `json:"name"`
+			} `json:"org"`
+			AS *struct {
+				ASN   int    `json:"asn"`
+				Route string `json:"route"`
+			} `json:"as"`
+		} `json:"inetnums"`
+	} `json:"result"`
+}
+
+func (w *WhoisXMLAPI) asnRequest(ctx context.Context, req *requests.ASNRequest) {
+	if w.creds == nil || w.creds.Key == "" {
+		return
+	}
+
+	asn := req.ASN
+	if asn == 0 {
+		if req.Address == "" {
+			return
+		}
+
+		nb := w.queryNetblocks(ctx, "ip", req.Address)
+		if nb == nil {
+			return
+		}
+		for _, r := range nb.Result.Inetnums {
+			if r.AS != nil && r.AS.ASN > 0 {
+				asn = r.AS.ASN
+				break
+			}
+		}
+		if asn == 0 {
+			return
+		}
+		checkRateLimit(ctx, w)
+	}
+
+	nb := w.queryNetblocks(ctx, "asn", strconv.Itoa(asn))
+	if nb == nil {
+		return
+	}
+
+	var cc, desc, registry string
+	var netblocks []string
+	for i, r := range nb.Result.Inetnums {
+		if i == 0 {
+			registry = r.Source
+			if r.Org != nil {
+				cc = r.Org.Country
+				desc = r.Org.Name
+			}
+		}
+		if r.AS != nil && r.AS.ASN == asn {
+			if cidr := amassnet.CanonicalCIDR(r.AS.Route); cidr != "" {
+				netblocks = append(netblocks, cidr)
+			}
+		}
+	}
+	if len(netblocks) == 0 {
+		return
+	}
+
+	req.ASN = asn
+	req.Prefix = netblocks[0]
+	req.CC = cc
+	req.Registry = registry
+	req.Description = desc
+	req.Netblocks = netblocks
+	req.Tag = w.SourceType
+	req.Source = w.String()
+
+	stats.RecordResult(ctx)
+	w.sys.Cache().Update(req)
+}
+
+// queryNetblocks returns the netblocks that the IP Netblocks API provides for the address or ASN.
+func (w *WhoisXMLAPI) queryNetblocks(ctx context.Context, param, value string) *whoisxmlNetblocks {
+	if err := spendBudget(ctx); err != nil {
+		w.sys.Config().Log.Printf("%s: %s: %v", w.String(), value, err)
+		return nil
+	}
+
+	page, err := w.request(ctx, w.netblocksURL(param, value), nil)
+	if err != nil {
+		w.sys.Config().Log.Printf("%s: %s netblocks: %v", w.String(), value, err)
+		return nil
+	}
+
+	var nb whoisxmlNetblocks
+	if err := json.Unmarshal([]byte(page), &nb); err != nil || nb.Result.Count == 0 || len(nb.Result.Inetnums) == 0 {
+		return nil
+	}
+	return &nb
+}
+
+// request sends the query to the WhoisXML API, as a POST of the JSON body when one is provided.
+// The responses are cached under the 'ttl' option without the API key, since each request picks
+// one of the API keys provided, as the script replaced by this data source did.
+func (w *WhoisXMLAPI) request(ctx context.Context, u string, body map[string]interface{}) (string, error) {
+	query := u
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+		query += string(data)
+	}
+	if page, ok := cachedResponse(ctx, w.sys, w, query); ok {
+		http.RecordSourceURL(ctx, u)
+		return page, nil
+	}
+
+	key := w.apiKey()
+	var page string
+	var err error
+	if body == nil {
+		page, err = http.RequestWebPage(ctx, u+"&apiKey="+url.QueryEscape(key), nil, nil, nil)
+	} else {
+		body["apiKey"] = key
+
+		data, merr := json.Marshal(body)
+		if merr != nil {
+			return "", merr
+		}
+
+		headers := map[string]string{"Content-Type": "application/json"}
+		page, err = http.RequestWebPage(ctx, u, bytes.NewReader(data), headers, nil)
+	}
+	if err == nil {
+		cacheResponse(ctx, w.sys, w, query, page)
+	}
+	return page, err
+}
+
+// apiKey returns the key of one of the credentials sets configured for the data source.
+func (w *WhoisXMLAPI) apiKey() string {
+	if creds := w.sys.Config().GetDataSourceConfig(w.String()).GetCredentials(); creds != nil && creds.Key != "" {
+		return creds.Key
+	}
+	return w.creds.Key
+}
+
+// baseURL returns the address of the WhoisXML API service. Each service has its own host, so
+// the name of the service is placed after the address selected by the base_url option.
+func (w *WhoisXMLAPI) baseURL(service string) string {
+	if base := w.sys.Config().BaseURL(w.String(), ""); base != "" {
+		return strings.TrimSuffix(base, "/") + "/" + service
+	}
+	return "https://" + service + ".whoisxmlapi.com"
+}
+
+func (w *WhoisXMLAPI) subdomainsURL(domain string) string {
+	return w.baseURL("subdomains") + "/api/v1?domainName=" + domain
+}
+
+func (w *WhoisXMLAPI) whoisURL(domain string) string {
+	return w.baseURL("www") + "/whoisserver/WhoisService?domainName=" + domain + "&outputFormat=JSON"
+}
+
+func (w *WhoisXMLAPI) reverseWhoisURL() string {
+	return w.baseURL("reverse-whois") + "/api/v2"
+}
+
+func (w *WhoisXMLAPI) netblocksURL(param, value string) string {
+	return w.baseURL("ip-netblocks") + "/api/v2?" + param + "=" + value
+}
@@ -0,0 +1,146 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+)
+
+func TestWhoisXMLAPIReverseWhois(t *testing.T) {
+	var lock sync.Mutex
+	var searches []string
+	orig := http.DefaultClient.Transport
+	http.DefaultClient.Transport = roundTripFunc(func(req *nethttp.Request) *nethttp.Response {
+		var body string
+		if req.URL.Path != "/reverse-whois/api/v2" && req.URL.Query().Get("apiKey") != "fake" {
+			t.Errorf("The API key was not provided in %s", req.URL)
+		}
+		switch req.URL.Path {
+		case "/www/whoisserver/WhoisService":
+			body = `{"WhoisRecord":{"registrant":{"name":"REDACTED FOR PRIVACY","organization":"OWASP Foundation",` +
+				`"email":"Admin@OWASP.org"},"nameServers":{"hostNames":["NS1.OWASP.ORG"]},` +
+				`"registryData":{"registrant":{"organization":"owasp foundation","email":"contact@privacy.example"}}}}`
+		case "/reverse-whois/api/v2":
+			var search struct {
+				Key   string               `json:"apiKey"`
+				Terms []whoisxmlSearchTerm `json:"advancedSearchTerms"`
+				Basic struct {
+					Include []string `json:"include"`
+				} `json:"basicSearchTerms"`
+				SearchAfter string `json:"searchAfter"`
+			}
+			data, _ := ioutil.ReadAll(req.Body)
+			_ = json.Unmarshal(data, &search)
+			if search.Key != "fake" {
+				t.Errorf("The API key was not provided in the reverse whois search")
+			}
+
+			if len(search.Terms) == 0 {
+				lock.Lock()
+				searches = append(searches, "include="+strings.Join(search.Basic.Include, ",")+"&"+search.SearchAfter)
+				lock.Unlock()
+
+				body = `{"domainsCount":2,"domainsList":["owasp.org","owasp-chapter.org"]}`
+				break
+			}
+
+			term := search.Terms[0].Field + "=" + search.Terms[0].Term
+			lock.Lock()
+			searches = append(searches, term+"&"+search.SearchAfter)
+			lock.Unlock()
+
+			switch {
+			case search.Terms[0].Field == whoisxmlEmailField && search.SearchAfter == "":
+				body = `{"nextPageSearchAfter":"page2","domainsCount":3,"domainsList":["owasp.org","OWASP.net"]}`
+			case search.Terms[0].Field == whoisxmlEmailField:
+				body = `{"nextPageSearchAfter":null,"domainsCount":3,"domainsList":["owaspfoundation.com"]}`
+			default:
+				body = `{"domainsCount":2,"domainsList":["owasp.net","www.owasp.org"]}`
+			}
+		}
+		return &nethttp.Response{
+			StatusCode: 200,
+			Header:     make(nethttp.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+	})
+	t.Cleanup(func() { http.DefaultClient.Transport = orig })
+
+	sys := testSystem()
+	sys.Config().GetDataSourceConfig("WhoisXMLAPI").BaseURL = "http://whoisxml.test"
+	w := NewWhoisXMLAPI(sys)
+	defer func() { _ = w.Stop() }()
+	w.creds = &config.Credentials{Key: "fake"}
+
+	w.whoisRequest(context.Background(), &requests.WhoisRequest{Domain: "owasp.org"})
+
+	var out *requests.WhoisRequest
+	select {
+	case o := <-w.Output():
+		out = o.(*requests.WhoisRequest)
+	case <-time.After(time.Second):
+		t.Fatalf("The related domains were not reported")
+	}
+
+	sort.Strings(out.NewDomains)
+	if fmt.Sprint(out.NewDomains) != "[owasp-chapter.org owasp.net owaspfoundation.com]" {
+		t.Errorf("Unexpected related domains: %v", out.NewDomains)
+	}
+	if fmt.Sprint(out.NameServers) != "[ns1.owasp.org]" {
+		t.Errorf("Unexpected nameservers: %v", out.NameServers)
+	}
+	// The root domain is searched like the removed script did, and the redacted name and the
+	// email address outside of scope are not searched
+	expected := []string{
+		"include=owasp.org&",
+		whoisxmlEmailField + "=admin@owasp.org&",
+		whoisxmlEmailField + "=admin@owasp.org&page2",
+		whoisxmlOrgField + "=OWASP Foundation&",
+	}
+	if fmt.Sprint(searches) != fmt.Sprint(expected) {
+		t.Errorf("Unexpected reverse whois searches: got %v, expected %v", searches, expected)
+	}
+}
+
+func TestWhoisXMLAPIResponseTTL(t *testing.T) {
+	count := serveResponses(t, func(path string) string {
+		return `{"result":{"count":1,"inetnums":[{"source":"ARIN","org":{"country":"US","name":"Google LLC"},` +
+			`"as":{"asn":15169,"route":"8.8.8.0/24"}}]}}`
+	})
+
+	sys := testSystem().(*systems.SimpleSystem)
+	sys.Graph = netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer sys.Graph.Close()
+	sys.Config().GetDataSourceConfig("WhoisXMLAPI").TTL = 60
+	w := NewWhoisXMLAPI(sys)
+	defer func() { _ = w.Stop() }()
+	w.creds = &config.Credentials{Key: "fake"}
+
+	for n := 0; n < 2; n++ {
+		w.asnRequest(context.Background(), &requests.ASNRequest{ASN: 15169})
+	}
+	if *count != 1 {
+		t.Errorf("The ASN was queried %d times within the TTL", *count)
+	}
+	if entry := sys.Cache().ASNSearch(15169); entry == nil || entry.Prefix != "8.8.8.0/24" {
+		t.Errorf("The cached response was not used: %+v", entry)
+	}
+}
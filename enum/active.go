@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	amassnet "github.com/aokimio/Amass/v3/net"
 	amassdns "github.com/aokimio/Amass/v3/net/dns"
 	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
@@ -245,6 +246,17 @@ func (a *activeTask) zoneWalk(ctx context.Context, req *requests.ZoneXFRRequest,
 		return
 	}
 
+	// The walk is sent from the local address selected for the network connections
+	if local := amassnet.LocalIP(); local != nil {
+		p, err := amassdns.NewBoundProxy(local, addr)
+		if err != nil {
+			a.enum.Config.Log.Printf("DNS: Zone Walk failed: %v", err)
+			return
+		}
+		defer p.Stop()
+		addr = p.Addr()
+	}
+
 	r := resolve.NewResolvers()
 	r.SetLogger(a.enum.Config.Log)
 	_ = r.AddResolvers(5, addr)
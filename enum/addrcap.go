@@ -0,0 +1,123 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+	"github.com/miekg/dns"
+)
+
+// AddrsCappedCounter is the statistics counter of the names that resolved to more addresses than
+// the max_addrs_per_name option allows, and had the remaining addresses left out.
+const AddrsCappedCounter = "addrs_capped"
+
+// AddrsCappedPredicate is the FQDN property holding the number of addresses the name resolved
+// to, when only some of them were processed.
+const AddrsCappedPredicate = "addrs_capped"
+
+// capAddresses keeps the first max addresses among the A and AAAA records, with the IPv4 addresses
+// in numerical order before the IPv6 addresses, so the same addresses are kept on each run. The
+// other records are returned unchanged, along with the number of distinct addresses in the records.
+func capAddresses(records []requests.DNSAnswer, max int) ([]requests.DNSAnswer, int) {
+	var kept, addrs []requests.DNSAnswer
+
+	seen := make(map[string]struct{})
+	for _, r := range records {
+		if t := uint16(r.Type); t != dns.TypeA && t != dns.TypeAAAA {
+			kept = append(kept, r)
+			continue
+		}
+
+		ip := net.ParseIP(r.Data)
+		if ip == nil {
+			kept = append(kept, r)
+			continue
+		}
+		if _, found := seen[ip.String()]; !found {
+			seen[ip.String()] = struct{}{}
+			addrs = append(addrs, r)
+		}
+	}
+
+	total := len(addrs)
+	if max <= 0 || total <= max {
+		return records, total
+	}
+
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return compareAddrs(net.ParseIP(addrs[i].Data), net.ParseIP(addrs[j].Data)) < 0
+	})
+	return append(kept, addrs[:max]...), total
+}
+
+func compareAddrs(a, b net.IP) int {
+	a4, b4 := a.To4(), b.To4()
+
+	switch {
+	case a4 != nil && b4 != nil:
+		return bytes.Compare(a4, b4)
+	case a4 != nil:
+		return -1
+	case b4 != nil:
+		return 1
+	}
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// limitAddresses applies the max_addrs_per_name option to the records of the request, and returns
+// the number of addresses the name resolved to when some of them were left out, or zero.
+func (e *Enumeration) limitAddresses(req *requests.DNSRequest) int {
+	records, total := capAddresses(req.Records, e.Config.MaxAddrsPerName)
+	if len(records) == len(req.Records) {
+		return 0
+	}
+
+	req.Records = records
+	e.Sys.Stats().Count(AddrsCappedCounter, 1)
+	e.Config.Log.Printf("%s: Processing %d of the %d addresses", req.Name, e.Config.MaxAddrsPerName, total)
+	return total
+}
+
+// markAddrsCapped records on the name the number of addresses it resolved to.
+func (e *Enumeration) markAddrsCapped(ctx context.Context, name string, total int) {
+	node, err := e.graph.ReadNode(ctx, name, netmap.TypeFQDN)
+	if err != nil {
+		return
+	}
+	if AddrsCapped(ctx, e.graph, name) < total {
+		_ = e.graph.UpsertProperty(ctx, node, AddrsCappedPredicate, strconv.Itoa(total))
+	}
+}
+
+// AddrsCapped returns the most addresses the name was found to resolve to when the enumerations
+// only processed some of them, or zero when all of its addresses were processed.
+func AddrsCapped(ctx context.Context, g *netmap.Graph, name string) int {
+	node, err := g.ReadNode(ctx, name, netmap.TypeFQDN)
+	if err != nil {
+		return 0
+	}
+
+	props, err := g.ReadProperties(ctx, node, AddrsCappedPredicate)
+	if err != nil {
+		return 0
+	}
+
+	var most int
+	for _, p := range props {
+		if s, ok := p.Value.Native().(string); ok {
+			if n, err := strconv.Atoi(s); err == nil && n > most {
+				most = n
+			}
+		}
+	}
+	return most
+}
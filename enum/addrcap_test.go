@@ -0,0 +1,91 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+	"github.com/miekg/dns"
+)
+
+func TestCapAddresses(t *testing.T) {
+	records := []requests.DNSAnswer{
+		{Type: int(dns.TypeAAAA), Data: "2001:db8::1"},
+		{Type: int(dns.TypeA), Data: "192.0.2.20"},
+		{Type: int(dns.TypeTXT), Data: "v=spf1 -all"},
+		{Type: int(dns.TypeA), Data: "192.0.2.3"},
+		{Type: int(dns.TypeA), Data: "192.0.2.20"},
+		{Type: int(dns.TypeA), Data: "10.0.0.1"},
+	}
+
+	kept, total := capAddresses(records, 2)
+	if total != 4 {
+		t.Errorf("Expected 4 distinct addresses, got %d", total)
+	}
+
+	var got []string
+	for _, r := range kept {
+		got = append(got, r.Data)
+	}
+	if fmt.Sprint(got) != "[v=spf1 -all 10.0.0.1 192.0.2.3]" {
+		t.Errorf("Unexpected records kept: %v", got)
+	}
+
+	if kept, _ := capAddresses(records, 0); len(kept) != len(records) {
+		t.Errorf("The records were changed without a maximum")
+	}
+	if kept, _ := capAddresses(records, 4); len(kept) != len(records) {
+		t.Errorf("The records were changed when the addresses were within the maximum")
+	}
+}
+
+func TestLimitAddresses(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	cfg := config.NewConfig()
+	cfg.MaxAddrsPerName = 1
+	c := stats.NewCollector()
+	e := &Enumeration{
+		Config: cfg,
+		Sys:    &systems.SimpleSystem{Cfg: cfg, Collector: c},
+		graph:  g,
+	}
+
+	req := &requests.DNSRequest{
+		Name: "cdn.owasp.org",
+		Records: []requests.DNSAnswer{
+			{Type: int(dns.TypeA), Data: "192.0.2.2"},
+			{Type: int(dns.TypeA), Data: "192.0.2.1"},
+		},
+	}
+	total := e.limitAddresses(req)
+	if total != 2 || len(req.Records) != 1 || req.Records[0].Data != "192.0.2.1" {
+		t.Fatalf("Unexpected records kept from %d addresses: %v", total, req.Records)
+	}
+	if n := c.Counter(AddrsCappedCounter); n != 1 {
+		t.Errorf("Expected 1 capped name, got %d", n)
+	}
+
+	if err := g.UpsertA(ctx, req.Name, "192.0.2.1", "DNS", "event"); err != nil {
+		t.Fatalf("Failed to insert the A record: %v", err)
+	}
+	e.markAddrsCapped(ctx, req.Name, total)
+	e.markAddrsCapped(ctx, req.Name, 1)
+	if n := AddrsCapped(ctx, g, req.Name); n != 2 {
+		t.Errorf("Expected the name to record 2 addresses, got %d", n)
+	}
+	if n := AddrsCapped(ctx, g, "www.owasp.org"); n != 0 {
+		t.Errorf("Expected zero for an unknown name, got %d", n)
+	}
+}
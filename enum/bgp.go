@@ -0,0 +1,133 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+
+	"github.com/aokimio/Amass/v3/net/bgp"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+)
+
+// BGPPredicate is the node property holding the state of a netblock in the global BGP table.
+const BGPPredicate = "bgp"
+
+// bgpValidator checks the netblocks discovered against the global BGP table in the background,
+// since the checks are rate limited and would otherwise stall the pipeline.
+type bgpValidator struct {
+	enum     *Enumeration
+	checker  *bgp.Checker
+	queue    queue.Queue
+	queued   *stringset.Set
+	done     chan struct{}
+	finished chan struct{}
+}
+
+func newBGPValidator(e *Enumeration, checker *bgp.Checker) *bgpValidator {
+	v := &bgpValidator{
+		enum:     e,
+		checker:  checker,
+		queue:    queue.NewQueue(),
+		queued:   stringset.New(),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go v.processRequests()
+	return v
+}
+
+// Stop waits for the netblocks already queued to be checked, so their state is stored before
+// the findings are written, unless the enumeration is cancelled.
+func (v *bgpValidator) Stop() {
+	close(v.done)
+	<-v.finished
+	v.queued.Close()
+}
+
+// validate queues the netblock for a check, once per enumeration.
+func (v *bgpValidator) validate(cidr string) {
+	if cidr == "" || v.queued.Has(cidr) {
+		return
+	}
+
+	v.queued.Insert(cidr)
+	v.queue.Append(cidr)
+}
+
+func (v *bgpValidator) processRequests() {
+	defer close(v.finished)
+
+	for {
+		select {
+		case <-v.enum.ctx.Done():
+			return
+		case <-v.done:
+			v.nextRequest()
+			return
+		case <-v.queue.Signal():
+			v.nextRequest()
+		}
+	}
+}
+
+func (v *bgpValidator) nextRequest() {
+	for {
+		if v.enum.ctx.Err() != nil {
+			return
+		}
+
+		e, ok := v.queue.Next()
+		if !ok {
+			return
+		}
+		if cidr, ok := e.(string); ok {
+			v.check(v.enum.ctx, cidr)
+		}
+	}
+}
+
+func (v *bgpValidator) check(ctx context.Context, cidr string) {
+	state, first := v.checker.Check(ctx, cidr)
+	if state == "" || !first {
+		return
+	}
+	if state == bgp.NotAnnounced {
+		v.enum.Config.Log.Printf("BGP validation: %s is not announced in the global BGP table", cidr)
+	}
+
+	if node, err := v.enum.graph.UpsertNode(ctx, cidr, netmap.TypeNetblock); err == nil {
+		_ = v.enum.graph.UpsertProperty(ctx, node, BGPPredicate, state)
+		v.enum.flusher.written(cidr)
+	}
+}
+
+// NetblockBGPState returns the state of the netblock in the global BGP table, either bgp.Announced
+// or bgp.NotAnnounced, or an empty string when the netblock was not checked. A netblock found
+// announced by any of the enumerations is reported as announced.
+func NetblockBGPState(ctx context.Context, g *netmap.Graph, cidr string) string {
+	node, err := g.ReadNode(ctx, cidr, netmap.TypeNetblock)
+	if err != nil {
+		return ""
+	}
+
+	props, err := g.ReadProperties(ctx, node, BGPPredicate)
+	if err != nil {
+		return ""
+	}
+
+	var state string
+	for _, p := range props {
+		if s, ok := p.Value.Native().(string); ok && (s == bgp.Announced || s == bgp.NotAnnounced) {
+			state = s
+			if s == bgp.Announced {
+				break
+			}
+		}
+	}
+	return state
+}
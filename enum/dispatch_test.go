@@ -0,0 +1,61 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/queue"
+	"github.com/caffix/service"
+)
+
+func TestDispatchByScore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.NewConfig()
+	cfg.MaxSourceRequests = 1
+	collector := stats.NewCollector()
+	// The failing source was listed first, yet the productive one must be served first
+	for i := 0; i < 5; i++ {
+		collector.Request("failing", errors.New("timeout"))
+		collector.Request("productive", nil)
+		collector.Result("productive")
+	}
+
+	failing := service.NewBaseService(nil, "failing")
+	productive := service.NewBaseService(nil, "productive")
+	e := &Enumeration{
+		Config:   cfg,
+		Sys:      &systems.SimpleSystem{Cfg: cfg, Collector: collector},
+		ctx:      ctx,
+		srcs:     []service.Service{failing, productive},
+		done:     make(chan struct{}),
+		requests: queue.NewQueue(),
+	}
+	go e.manageDataSrcRequests()
+	e.sendRequests(&requests.ASNRequest{ASN: 13335})
+
+	select {
+	case <-productive.Input():
+	case <-failing.Input():
+		t.Fatalf("The source with the lower score was served first")
+	case <-time.After(2 * time.Second):
+		t.Fatalf("The request was not dispatched")
+	}
+	// The failing source is served once the request in flight was accepted
+	select {
+	case <-failing.Input():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("The request was not dispatched to the source with the lower score")
+	}
+}
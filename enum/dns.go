@@ -9,6 +9,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	amassnet "github.com/aokimio/Amass/v3/net"
 	amassdns "github.com/aokimio/Amass/v3/net/dns"
@@ -21,6 +22,8 @@ import (
 
 const maxDNSQueryAttempts int = 10
 
+var errWildcardDetected = errors.New("wildcard detected")
+
 // InitialQueryTypes include the DNS record types that are queried for a discovered name.
 var InitialQueryTypes = []uint16{
 	dns.TypeCNAME,
@@ -89,12 +92,17 @@ func (dt *dnsTask) Process(ctx context.Context, data pipeline.Data, tp pipeline.
 
 	switch v := data.(type) {
 	case *requests.DNSRequest:
-		if req, err := dt.processFwdRequest(ctx, v); err == nil {
+		req, err := dt.processFwdRequest(ctx, v)
+		if err == nil {
 			v.Records = append(v.Records, req.Records...)
 		}
 		if len(v.Records) == 0 {
+			if err != errWildcardDetected {
+				dt.enum.recordUnresolved(ctx, v)
+			}
 			return nil, nil
 		}
+		dt.enum.recordResolved(v)
 	case *requests.AddrRequest:
 		if dt.processRevRequest(ctx, v.Address, tp) || v.InScope {
 			return data, nil
@@ -136,7 +144,7 @@ loop:
 			return nil, errors.New("failed to resolve name")
 		}
 		if dt.enum.wildcardDetected(ctx, req, resp) {
-			return nil, errors.New("wildcard detected")
+			return nil, errWildcardDetected
 		}
 
 		ans := resolve.ExtractAnswers(resp)
@@ -144,6 +152,11 @@ loop:
 			continue
 		}
 
+		// Keep the CNAME chain that led to the answers, so hops past the first can be followed
+		if dt.enum.Config.FollowCNAMEs && qtype != dns.TypeCNAME {
+			req.Records = append(req.Records, convertAnswers(resolve.AnswersByType(ans, dns.TypeCNAME))...)
+		}
+
 		rr := resolve.AnswersByType(ans, qtype)
 		if len(rr) == 0 {
 			continue
@@ -174,7 +187,17 @@ func (e *Enumeration) fwdQuery(ctx context.Context, name string, qtype uint16) (
 	return resp, err
 }
 
-func (e *Enumeration) dnsQuery(ctx context.Context, msg *dns.Msg, r *resolve.Resolvers, attempts int) (*dns.Msg, error) {
+// The longest delay between the retries of a DNS query
+const maxDNSRetryBackoff = 5 * time.Second
+
+// dnsQuerier is implemented by the resolver pools used for the active resolution.
+type dnsQuerier interface {
+	QueryBlocking(ctx context.Context, msg *dns.Msg) (*dns.Msg, error)
+}
+
+func (e *Enumeration) dnsQuery(ctx context.Context, msg *dns.Msg, r dnsQuerier, attempts int) (*dns.Msg, error) {
+	var retries int
+
 	for num := 0; num < attempts; num++ {
 		select {
 		case <-ctx.Done():
@@ -183,7 +206,14 @@ func (e *Enumeration) dnsQuery(ctx context.Context, msg *dns.Msg, r *resolve.Res
 		}
 
 		resp, err := r.QueryBlocking(ctx, msg)
-		if err != nil {
+		if dnsRetryable(resp, err) {
+			if retries >= e.Config.DNSRetries {
+				break
+			}
+			if !dnsRetryWait(ctx, dnsRetryDelay(e.Config.DNSRetryBackoff, retries)) {
+				return nil, errors.New("context expired")
+			}
+			retries++
 			continue
 		}
 		if resp.Rcode == dns.RcodeNameError {
@@ -199,8 +229,51 @@ func (e *Enumeration) dnsQuery(ctx context.Context, msg *dns.Msg, r *resolve.Res
 	return nil, nil
 }
 
+// dnsRetryable returns true when the query failed in a way that can be transient: the
+// resolver did not respond in time or reported a server failure.
+func dnsRetryable(resp *dns.Msg, err error) bool {
+	if err != nil || resp == nil {
+		return true
+	}
+	return resp.Rcode == resolve.RcodeNoResponse || resp.Rcode == dns.RcodeServerFailure
+}
+
+// dnsRetryDelay returns the delay before the retry, doubling the backoff for each retry
+// already performed.
+func dnsRetryDelay(backoff time.Duration, retry int) time.Duration {
+	delay := backoff
+	for i := 0; i < retry && delay < maxDNSRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxDNSRetryBackoff {
+		delay = maxDNSRetryBackoff
+	}
+	return delay
+}
+
+// dnsRetryWait returns false when the context expires before the delay has passed.
+func dnsRetryWait(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+	}
+	return true
+}
+
 func (e *Enumeration) wildcardDetected(ctx context.Context, req *requests.DNSRequest, resp *dns.Msg) bool {
 	if !requests.TrustedTag(req.Tag) && e.Sys.TrustedResolvers().WildcardDetected(ctx, resp, req.Domain) {
+		// Share the finding so data sources can drop other names within the wildcard
+		if parts := strings.SplitN(req.Name, ".", 2); len(parts) == 2 {
+			e.Sys.Wildcards().Insert(parts[1], req.Domain)
+		}
 		return true
 	}
 	return false
@@ -0,0 +1,108 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/caffix/resolve"
+	"github.com/miekg/dns"
+)
+
+// testQuerier answers the queries with the provided rcodes in order, repeating the last one.
+type testQuerier struct {
+	rcodes  []int
+	queries int
+}
+
+func (q *testQuerier) QueryBlocking(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	idx := q.queries
+	if idx >= len(q.rcodes) {
+		idx = len(q.rcodes) - 1
+	}
+	q.queries++
+
+	rcode := q.rcodes[idx]
+	if rcode < 0 {
+		return nil, errors.New("query failed")
+	}
+
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	resp.Rcode = rcode
+	if rcode == dns.RcodeSuccess {
+		rr, _ := dns.NewRR("www.owasp.org. 300 IN A 104.16.1.1")
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resp, nil
+}
+
+func TestDNSQueryRetries(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.DNSRetries = 2
+	cfg.DNSRetryBackoff = time.Millisecond
+	e := &Enumeration{Config: cfg}
+	msg := resolve.QueryMsg("www.owasp.org", dns.TypeA)
+
+	tests := []struct {
+		name    string
+		rcodes  []int
+		queries int
+		found   bool
+	}{
+		{"NXDOMAIN", []int{dns.RcodeNameError}, 1, false},
+		{"Timeout", []int{resolve.RcodeNoResponse}, 3, false},
+		{"SERVFAIL", []int{dns.RcodeServerFailure}, 3, false},
+		{"Query error", []int{-1}, 3, false},
+		{"Timeout then answer", []int{resolve.RcodeNoResponse, dns.RcodeServerFailure, dns.RcodeSuccess}, 3, true},
+		{"Timeout then NXDOMAIN", []int{resolve.RcodeNoResponse, dns.RcodeNameError}, 2, false},
+	}
+
+	for _, test := range tests {
+		q := &testQuerier{rcodes: test.rcodes}
+
+		resp, err := e.dnsQuery(context.Background(), msg, q, maxDNSQueryAttempts)
+		if q.queries != test.queries {
+			t.Errorf("%s: %d queries were sent, expected %d", test.name, q.queries, test.queries)
+		}
+		if found := err == nil && resp != nil; found != test.found {
+			t.Errorf("%s: unexpected result: %v, %v", test.name, resp, err)
+		}
+	}
+
+	// Retries can be disabled
+	cfg.DNSRetries = 0
+	q := &testQuerier{rcodes: []int{resolve.RcodeNoResponse, dns.RcodeSuccess}}
+	if resp, _ := e.dnsQuery(context.Background(), msg, q, maxDNSQueryAttempts); resp != nil || q.queries != 1 {
+		t.Errorf("The query was retried %d times with the retries disabled", q.queries-1)
+	}
+}
+
+func TestDNSRetryDelay(t *testing.T) {
+	backoff := 250 * time.Millisecond
+
+	if d := dnsRetryDelay(backoff, 0); d != backoff {
+		t.Errorf("The first retry was delayed by %v", d)
+	}
+	if d := dnsRetryDelay(backoff, 2); d != time.Second {
+		t.Errorf("The third retry was delayed by %v", d)
+	}
+	if d := dnsRetryDelay(backoff, 20); d != maxDNSRetryBackoff {
+		t.Errorf("The delay was not capped: %v", d)
+	}
+}
+
+func TestDNSRetryWaitExpired(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if dnsRetryWait(ctx, time.Minute) {
+		t.Errorf("The wait continued after the context expired")
+	}
+}
@@ -6,10 +6,17 @@ package enum
 
 import (
 	"context"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/aokimio/Amass/v3/config"
 	"github.com/aokimio/Amass/v3/datasrcs"
+	"github.com/aokimio/Amass/v3/net/bgp"
+	"github.com/aokimio/Amass/v3/net/geo"
+	"github.com/aokimio/Amass/v3/net/rpki"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/netmap"
 	"github.com/caffix/pipeline"
@@ -19,29 +26,48 @@ import (
 
 const maxActivePipelineTasks int = 25
 
+// The longest random delay before the first request is sent to a data source
+const maxFirstRequestDelay = 5 * time.Second
+
 // Enumeration is the object type used to execute a DNS enumeration.
 type Enumeration struct {
-	Config   *config.Config
-	Sys      systems.System
-	ctx      context.Context
-	graph    *netmap.Graph
-	srcs     []service.Service
-	done     chan struct{}
-	nameSrc  *enumSource
-	subTask  *subdomainTask
-	dnsTask  *dnsTask
-	store    *dataManager
-	requests queue.Queue
+	Config     *config.Config
+	Sys        systems.System
+	ctx        context.Context
+	graph      *netmap.Graph
+	srcs       []service.Service
+	done       chan struct{}
+	nameSrc    *enumSource
+	subTask    *subdomainTask
+	dnsTask    *dnsTask
+	store      *dataManager
+	flusher    *graphFlusher
+	requests   queue.Queue
+	geo        *geolocator
+	bgp        *bgpValidator
+	rpki       *rpkiValidator
+	unresolved *stringset.Set
+	passive    *stringset.Set
+	resolved   *stringset.Set
+	// Closed by Resume when the requests to the data sources were paused
+	pauseLock sync.Mutex
+	resumed   chan struct{}
+	// The domains added by the related TLD expansion, mapped to the root domains they vary
+	relatedLock sync.Mutex
+	related     map[string]string
 }
 
 // NewEnumeration returns an initialized Enumeration that has not been started yet.
 func NewEnumeration(cfg *config.Config, sys systems.System, graph *netmap.Graph) *Enumeration {
 	return &Enumeration{
-		Config:   cfg,
-		Sys:      sys,
-		graph:    graph,
-		srcs:     datasrcs.SelectedDataSources(cfg, sys.DataSources()),
-		requests: queue.NewQueue(),
+		Config:     cfg,
+		Sys:        sys,
+		graph:      graph,
+		srcs:       datasrcs.SelectedDataSources(cfg, sys.DataSources()),
+		requests:   queue.NewQueue(),
+		unresolved: stringset.New(),
+		passive:    stringset.New(),
+		resolved:   stringset.New(),
 	}
 }
 
@@ -59,6 +85,28 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	e.ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 	go e.manageDataSrcRequests()
+	// Findings are copied into the graph databases as the enumeration progresses
+	e.flusher = newGraphFlusher(ctx, e)
+	defer e.flusher.Stop(ctx)
+
+	if enricher, err := geo.NewEnricher(e.Config); err != nil {
+		return err
+	} else if enricher != nil {
+		e.geo = newGeolocator(e, enricher)
+		defer e.geo.Stop()
+	}
+	if checker, err := bgp.NewChecker(e.Config); err != nil {
+		return err
+	} else if checker != nil {
+		e.bgp = newBGPValidator(e, checker)
+		defer e.bgp.Stop()
+	}
+	if checker, err := rpki.NewChecker(e.Config); err != nil {
+		return err
+	} else if checker != nil {
+		e.rpki = newRPKIValidator(e, checker)
+		defer e.rpki.Stop()
+	}
 
 	if !e.Config.Passive {
 		e.dnsTask = newDNSTask(e)
@@ -72,17 +120,18 @@ func (e *Enumeration) Start(ctx context.Context) error {
 
 	var stages []pipeline.Stage
 	if !e.Config.Passive {
-		stages = append(stages, pipeline.FIFO("root", e.dnsTask.rootTaskFunc()))
-		stages = append(stages, pipeline.DynamicPool("dns", e.dnsTask, e.Sys.Resolvers().QPS()))
-		stages = append(stages, pipeline.FIFO("store", e.store))
-		stages = append(stages, pipeline.FIFO("", e.subTask))
+		stages = append(stages, pipeline.FIFO("root", e.timedTask("root", e.dnsTask.rootTaskFunc())))
+		stages = append(stages, pipeline.DynamicPool("dns", e.timedTask("dns", e.dnsTask), e.Sys.Resolvers().QPS()))
+		stages = append(stages, pipeline.FIFO("store", e.timedTask("store", e.store)))
+		stages = append(stages, pipeline.FIFO("", e.timedTask("subdomain", e.subTask)))
 	}
 	if e.Config.Active {
 		activetask := newActiveTask(e, maxActivePipelineTasks)
 		defer activetask.Stop()
-		stages = append(stages, pipeline.FIFO("active", activetask))
+		stages = append(stages, pipeline.FIFO("active", e.timedTask("active", activetask)))
 	}
 
+	e.expandRelatedTLDs()
 	e.submitASNs()
 	e.submitDomainNames()
 	/*
@@ -93,6 +142,10 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	go e.submitKnownNames()
 	go e.submitProvidedNames()
 
+	start := time.Now()
+	defer func() { e.Sys.Stats().Phase("enumeration", time.Since(start)) }()
+	defer e.countPassiveNames()
+
 	var err error
 	if p := pipeline.NewPipeline(stages...); e.Config.Passive {
 		err = p.Execute(e.ctx, e.nameSrc, e.makeOutputSink())
@@ -104,6 +157,16 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	return err
 }
 
+// timedTask wraps the pipeline task so the time spent in the named phase is added to the run statistics.
+func (e *Enumeration) timedTask(phase string, task pipeline.Task) pipeline.Task {
+	return pipeline.TaskFunc(func(ctx context.Context, data pipeline.Data, tp pipeline.TaskParams) (pipeline.Data, error) {
+		start := time.Now()
+		defer func() { e.Sys.Stats().Phase(phase, time.Since(start)) }()
+
+		return task.Process(ctx, data, tp)
+	})
+}
+
 // Release the root domain names to the input source and each data source.
 func (e *Enumeration) submitDomainNames() {
 	for _, domain := range e.Config.Domains() {
@@ -142,8 +205,56 @@ func (e *Enumeration) manageDataSrcRequests() {
 		pending[src.String()] = false
 	}
 
+	// The first request sent to each data source is delayed by a random amount of time
+	delays := make(map[string]time.Duration)
+	if e.Config.RandomizeSources {
+		rng := e.Config.NewRand()
+		for _, src := range e.srcs {
+			delays[src.String()] = time.Duration(rng.Int63n(int64(maxFirstRequestDelay)))
+		}
+	}
+
 	finished := make(chan string, len(e.srcs))
+	exhausted := make(map[string]bool)
+	broken := make(map[string]bool)
 	requestsMap := make(map[string][]interface{})
+	// The requests in flight across the data sources, and the most allowed when limited
+	var active int
+	limit := e.Config.MaxSourceRequests
+	// dispatch sends the next request to each idle data source with requests waiting. When the requests
+	// in flight are limited, the sources with the best scores are served first
+	dispatch := func() {
+		var ready []string
+		for name, reqs := range requestsMap {
+			if len(reqs) > 0 && !pending[name] {
+				ready = append(ready, name)
+			}
+		}
+		if limit > 0 && len(ready) > limit-active {
+			scores := make(map[string]float64, len(ready))
+			for _, name := range ready {
+				scores[name] = e.Sys.Stats().Score(name)
+			}
+			sort.Slice(ready, func(i, j int) bool {
+				if scores[ready[i]] != scores[ready[j]] {
+					return scores[ready[i]] > scores[ready[j]]
+				}
+				return ready[i] < ready[j]
+			})
+		}
+
+		for _, name := range ready {
+			if limit > 0 && active >= limit {
+				break
+			}
+
+			go e.fireRequest(nameToSrc[name], requestsMap[name][0], delays[name], finished)
+			delete(delays, name)
+			requestsMap[name] = requestsMap[name][1:]
+			pending[name] = true
+			active++
+		}
+	}
 loop:
 	for {
 		select {
@@ -157,27 +268,58 @@ loop:
 				continue loop
 			}
 			for name := range nameToSrc {
-				if len(requestsMap[name]) == 0 && !pending[name] {
-					go e.fireRequest(nameToSrc[name], element, finished)
-					pending[name] = true
-				} else {
-					requestsMap[name] = append(requestsMap[name], element)
+				// Stop dispatching to data sources that have used up their request quota
+				if e.Sys.Stats().QuotaReached(name) {
+					if !exhausted[name] {
+						exhausted[name] = true
+						e.Config.Log.Printf("%s: The request quota has been reached for this run", name)
+					}
+					continue
 				}
+				// Scrape data sources that keep failing to extract data are likely broken by a site change
+				if e.Sys.Stats().Broken(name) {
+					if !broken[name] {
+						broken[name] = true
+						e.Config.Log.Printf("%s: No longer dispatching requests to the broken data source", name)
+					}
+					continue
+				}
+				requestsMap[name] = append(requestsMap[name], element)
 			}
+			dispatch()
 		case name := <-finished:
-			if len(requestsMap[name]) == 0 {
-				pending[name] = false
-				continue loop
+			active--
+			pending[name] = false
+			if e.Sys.Stats().QuotaReached(name) || e.Sys.Stats().Broken(name) {
+				requestsMap[name] = nil
 			}
-
-			go e.fireRequest(nameToSrc[name], requestsMap[name][0], finished)
-			requestsMap[name] = requestsMap[name][1:]
+			dispatch()
 		}
 	}
 	e.requests.Process(func(e interface{}) {})
 }
 
-func (e *Enumeration) fireRequest(srv service.Service, req interface{}, finished chan string) {
+func (e *Enumeration) fireRequest(srv service.Service, req interface{}, delay time.Duration, finished chan string) {
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+
+		select {
+		case <-e.done:
+			finished <- srv.String()
+			return
+		case <-e.ctx.Done():
+			finished <- srv.String()
+			return
+		case <-t.C:
+		}
+	}
+
+	if !e.waitWhilePaused() {
+		finished <- srv.String()
+		return
+	}
+
 	select {
 	case <-e.done:
 	case <-e.ctx.Done():
@@ -197,6 +339,12 @@ func (e *Enumeration) makeOutputSink() pipeline.SinkFunc {
 		if ok && req != nil && req.Name != "" && e.Config.IsDomainInScope(req.Name) {
 			if _, err := e.graph.UpsertFQDN(e.ctx, req.Name, req.Source, e.Config.UUID.String()); err != nil {
 				e.Config.Log.Print(err.Error())
+			} else {
+				e.markSeen(e.ctx, req.Name, netmap.TypeFQDN, time.Time{}, time.Time{})
+				e.markSourceURL(e.ctx, req.Name, req.SourceURL)
+				e.markOrg(e.ctx, req.Name, req.Org)
+				e.markSeeds(e.ctx, req.Name, netmap.TypeFQDN, e.requestSeeds(e.ctx, req))
+				e.flusher.written(req.Name)
 			}
 		}
 		return nil
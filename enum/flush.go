@@ -0,0 +1,241 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/caffix/netmap"
+)
+
+// graphFlusher copies the findings of the enumeration from the in-memory graph into
+// the system graph databases in batches, so discoveries are persisted while the
+// enumeration is still running. Each flush only copies the nodes written since the
+// previous one, along with the neighbors that have not been copied yet. The complete
+// event is migrated by the caller of the enumeration once it has finished.
+type graphFlusher struct {
+	sync.Mutex
+	enum     *Enumeration
+	batch    int
+	interval time.Duration
+	writes   int
+	changed  map[string]struct{}
+	// The nodes and data sources already copied into the graph databases
+	copied   *stringset.Set
+	signal   chan struct{}
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// newGraphFlusher returns a running graphFlusher, or nil when the system has no graph databases.
+// The flushes stop being sent once the context is cancelled.
+func newGraphFlusher(ctx context.Context, e *Enumeration) *graphFlusher {
+	if len(e.Sys.GraphDatabases()) == 0 {
+		return nil
+	}
+
+	batch := e.Config.GraphFlushBatchSize
+	if batch <= 0 {
+		batch = config.DefaultGraphFlushBatchSize
+	}
+	secs := e.Config.GraphFlushInterval
+	if secs <= 0 {
+		secs = config.DefaultGraphFlushInterval
+	}
+
+	gf := &graphFlusher{
+		enum:     e,
+		batch:    batch,
+		interval: time.Duration(secs) * time.Second,
+		changed:  make(map[string]struct{}),
+		copied:   stringset.New(),
+		signal:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go gf.processFlushes(ctx)
+	return gf
+}
+
+// Stop terminates the graphFlusher after any flush in progress has completed, and then
+// flushes the nodes written since, unless the context has been cancelled.
+func (gf *graphFlusher) Stop(ctx context.Context) {
+	if gf == nil {
+		return
+	}
+
+	close(gf.done)
+	<-gf.finished
+
+	gf.flush(ctx)
+	gf.copied.Close()
+}
+
+// touched records the nodes of the in-memory graph changed by a write, so they are
+// copied by the next flush.
+func (gf *graphFlusher) touched(ids ...string) {
+	if gf == nil {
+		return
+	}
+
+	gf.Lock()
+	defer gf.Unlock()
+
+	for _, id := range ids {
+		if id != "" {
+			gf.changed[id] = struct{}{}
+		}
+	}
+}
+
+// written records a write to the in-memory graph that changed the nodes identified by ids,
+// and requests a flush once the batch size is reached.
+func (gf *graphFlusher) written(ids ...string) {
+	if gf == nil {
+		return
+	}
+
+	gf.touched(ids...)
+
+	gf.Lock()
+	gf.writes++
+	full := gf.writes >= gf.batch
+	gf.Unlock()
+
+	if full {
+		select {
+		case gf.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (gf *graphFlusher) processFlushes(ctx context.Context) {
+	defer close(gf.finished)
+
+	t := time.NewTicker(gf.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-gf.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-gf.signal:
+		case <-t.C:
+		}
+
+		gf.flush(ctx)
+	}
+}
+
+func (gf *graphFlusher) flush(ctx context.Context) {
+	// The nodes left pending are copied by the migration of the complete event
+	if ctx.Err() != nil {
+		return
+	}
+
+	gf.Lock()
+	writes := gf.writes
+	gf.writes = 0
+	changed := gf.changed
+	gf.changed = make(map[string]struct{})
+	gf.Unlock()
+
+	if writes == 0 && len(changed) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gf.interval)
+	defer cancel()
+
+	uuid := gf.enum.Config.UUID.String()
+	// The event node is copied by the first flush, while it only references a single batch
+	first := !gf.copied.Has(uuid)
+	gf.copied.Insert(uuid)
+
+	nodes, edges, sources := gf.collect(ctx, changed, uuid)
+	for _, g := range gf.enum.Sys.GraphDatabases() {
+		if err := gf.copy(ctx, g, first, uuid, nodes, edges, sources); err != nil {
+			gf.enum.Config.Log.Printf("Failed to flush the findings to the %s database: %v", g.String(), err)
+		}
+	}
+}
+
+// collect returns the changed nodes, followed by the neighbors that have not been copied yet,
+// the edges coming into all of them, and the data sources named by those edges for the first time.
+func (gf *graphFlusher) collect(ctx context.Context,
+	changed map[string]struct{}, uuid string) ([]netmap.Node, []*netmap.Edge, []string) {
+	var queue []string
+	for id := range changed {
+		queue = append(queue, id)
+		gf.copied.Insert(id)
+	}
+
+	var sources []string
+	var nodes []netmap.Node
+	var edges []*netmap.Edge
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		nodes = append(nodes, id)
+
+		neighbors, _ := gf.enum.graph.AllOutNodes(ctx, id)
+		in, _ := gf.enum.graph.ReadInEdges(ctx, id)
+		for _, edge := range in {
+			neighbors = append(neighbors, edge.From)
+			// The edges from the event are named after the data source that found the node
+			if key := "source:" + edge.Predicate; edge.From == uuid &&
+				edge.Predicate != "domain" && !gf.copied.Has(key) {
+				gf.copied.Insert(key)
+				sources = append(sources, edge.Predicate)
+			}
+		}
+		edges = append(edges, in...)
+
+		for _, n := range neighbors {
+			if nid := gf.enum.graph.NodeToID(n); !gf.copied.Has(nid) {
+				gf.copied.Insert(nid)
+				queue = append(queue, nid)
+			}
+		}
+	}
+	return nodes, edges, sources
+}
+
+func (gf *graphFlusher) copy(ctx context.Context, g *netmap.Graph, first bool,
+	uuid string, nodes []netmap.Node, edges []*netmap.Edge, sources []string) error {
+	if first {
+		if err := g.WriteNodeQuads(ctx, gf.enum.graph, []netmap.Node{uuid}); err != nil {
+			return err
+		}
+	}
+	if len(nodes) > 0 {
+		if err := g.WriteNodeQuads(ctx, gf.enum.graph, nodes); err != nil {
+			return err
+		}
+	}
+
+	for _, src := range sources {
+		if _, err := g.UpsertSource(ctx, src); err != nil {
+			return err
+		}
+		if err := g.UpsertEdge(ctx, &netmap.Edge{Predicate: "used", From: uuid, To: src}); err != nil {
+			return err
+		}
+	}
+	for _, edge := range edges {
+		if err := g.UpsertEdge(ctx, edge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
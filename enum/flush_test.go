@@ -0,0 +1,121 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+)
+
+const flushCrashDirEnv = "AMASS_FLUSH_CRASH_DIR"
+
+var flushTestNames = []string{"www.owasp.org", "api.owasp.org", "dev.owasp.org"}
+
+// TestGraphFlusherCrash runs the flusher in a child process that is killed before the enumeration
+// completes, and then checks that the findings were persisted to the local graph database.
+func TestGraphFlusherCrash(t *testing.T) {
+	if dir := os.Getenv(flushCrashDirEnv); dir != "" {
+		runFlushCrashChild(dir)
+		return
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGraphFlusherCrash$")
+	cmd.Env = append(os.Environ(), flushCrashDirEnv+"="+dir)
+	if err := cmd.Run(); err == nil {
+		t.Fatalf("The child process was expected to be killed mid-run")
+	}
+
+	cayley := netmap.NewCayleyGraph("local", dir, "")
+	if cayley == nil {
+		t.Fatalf("Failed to reopen the local graph database")
+	}
+	g := netmap.NewGraph(cayley)
+	defer g.Close()
+
+	for _, name := range flushTestNames {
+		if _, err := g.ReadNode(context.Background(), name, netmap.TypeFQDN); err != nil {
+			t.Errorf("The name %s was not persisted before the crash: %v", name, err)
+		}
+	}
+}
+
+func TestGraphFlusherIncremental(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.GraphFlushBatchSize = 1000
+	cfg.GraphFlushInterval = 60
+	uuid := cfg.UUID.String()
+
+	db := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer db.Close()
+	e := &Enumeration{
+		Config: cfg,
+		Sys:    &systems.SimpleSystem{Cfg: cfg, Graph: db},
+		graph:  netmap.NewGraph(netmap.NewCayleyGraphMemory()),
+	}
+	gf := newGraphFlusher(ctx, e)
+	defer gf.Stop(ctx)
+
+	for _, name := range flushTestNames[:2] {
+		if _, err := e.graph.UpsertFQDN(ctx, name, "DNS", uuid); err == nil {
+			gf.written(name)
+		}
+	}
+	gf.flush(ctx)
+	// Only the names written after the first flush are provided to the second one
+	if err := e.graph.UpsertA(ctx, flushTestNames[2], "192.0.2.1", "Crtsh", uuid); err == nil {
+		gf.written(flushTestNames[2])
+	}
+	gf.flush(ctx)
+
+	if names := db.EventFQDNs(ctx, uuid); len(names) != len(flushTestNames)+1 {
+		t.Errorf("The event in the database references the names %v", names)
+	}
+	if _, err := db.ReadNode(ctx, "192.0.2.1", netmap.TypeAddr); err != nil {
+		t.Errorf("The address of %s was not flushed: %v", flushTestNames[2], err)
+	}
+	if srcs, err := db.NodeSources(ctx, flushTestNames[2], uuid); err != nil || len(srcs) != 1 || srcs[0] != "Crtsh" {
+		t.Errorf("The source of %s was not flushed: %v", flushTestNames[2], srcs)
+	}
+	if pairs, err := db.NamesToAddrs(ctx, uuid, flushTestNames[2]); err != nil || len(pairs) != 1 {
+		t.Errorf("The A record of %s was not flushed: %v", flushTestNames[2], err)
+	}
+}
+
+func runFlushCrashChild(dir string) {
+	cfg := config.NewConfig()
+	cfg.GraphFlushBatchSize = len(flushTestNames)
+	cfg.GraphFlushInterval = 60
+
+	db := netmap.NewGraph(netmap.NewCayleyGraph("local", dir, ""))
+	e := &Enumeration{
+		Config: cfg,
+		Sys:    &systems.SimpleSystem{Cfg: cfg, Graph: db},
+		graph:  netmap.NewGraph(netmap.NewCayleyGraphMemory()),
+	}
+	e.flusher = newGraphFlusher(context.Background(), e)
+
+	for _, name := range flushTestNames {
+		if _, err := e.graph.UpsertFQDN(context.Background(), name, "DNS", cfg.UUID.String()); err == nil {
+			e.flusher.written(name)
+		}
+	}
+	// Wait for the batch to be flushed, then die without stopping the flusher or closing the database
+	for i := 0; i < 100; i++ {
+		if _, err := db.ReadNode(context.Background(), flushTestNames[len(flushTestNames)-1], netmap.TypeFQDN); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	os.Exit(3)
+}
@@ -0,0 +1,132 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aokimio/Amass/v3/net/geo"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+)
+
+// GeoPredicate is the node property holding the geographic location of an address.
+const GeoPredicate = "geolocation"
+
+// geolocator attaches the locations of the discovered addresses. The lookups performed by
+// online providers are handled in the background to keep them from stalling the pipeline.
+type geolocator struct {
+	enum     *Enumeration
+	enricher *geo.Enricher
+	queue    queue.Queue
+	done     chan struct{}
+}
+
+func newGeolocator(e *Enumeration, enricher *geo.Enricher) *geolocator {
+	g := &geolocator{
+		enum:     e,
+		enricher: enricher,
+		queue:    queue.NewQueue(),
+		done:     make(chan struct{}),
+	}
+
+	if !enricher.Local() {
+		go g.processRequests()
+	}
+	return g
+}
+
+func (g *geolocator) Stop() {
+	close(g.done)
+}
+
+// locate attaches the location of the address to the request when it can be obtained
+// without network activity, and otherwise queues the address for a background lookup.
+func (g *geolocator) locate(ctx context.Context, req *requests.AddrRequest) {
+	if req.Geo != nil {
+		return
+	}
+	if !g.enricher.Local() {
+		g.queue.Append(req.Address)
+		return
+	}
+
+	req.Geo = g.lookup(ctx, req.Address)
+}
+
+func (g *geolocator) processRequests() {
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-g.enum.ctx.Done():
+			return
+		case <-g.queue.Signal():
+			g.nextRequest()
+		}
+	}
+}
+
+func (g *geolocator) nextRequest() {
+	for {
+		select {
+		case <-g.done:
+			return
+		default:
+		}
+
+		e, ok := g.queue.Next()
+		if !ok {
+			return
+		}
+		if addr, ok := e.(string); ok {
+			_ = g.lookup(g.enum.ctx, addr)
+		}
+	}
+}
+
+func (g *geolocator) lookup(ctx context.Context, addr string) *requests.GeoInfo {
+	info, first := g.enricher.Lookup(ctx, addr)
+	if info == nil || !first {
+		return info
+	}
+
+	if val, err := json.Marshal(info); err == nil {
+		if node, err := g.enum.graph.UpsertNode(ctx, addr, netmap.TypeAddr); err == nil {
+			_ = g.enum.graph.UpsertProperty(ctx, node, GeoPredicate, string(val))
+			g.enum.flusher.written(addr)
+		}
+	}
+	return info
+}
+
+// AddrGeolocation returns the geographic location stored for the address, or nil when
+// the address was not geolocated.
+func AddrGeolocation(ctx context.Context, g *netmap.Graph, addr string) *requests.GeoInfo {
+	node, err := g.ReadNode(ctx, addr, netmap.TypeAddr)
+	if err != nil {
+		return nil
+	}
+
+	props, err := g.ReadProperties(ctx, node, GeoPredicate)
+	if err != nil {
+		return nil
+	}
+
+	for _, p := range props {
+		s, ok := p.Value.Native().(string)
+		if !ok {
+			continue
+		}
+
+		var info requests.GeoInfo
+		if err := json.Unmarshal([]byte(s), &info); err == nil {
+			return &info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+// HostedDomainPredicate is the netblock property holding the domains found hosted in it.
+const HostedDomainPredicate = "hosted_domain"
+
+// storeNetblockDomains records the domains a data source found hosted in each netblock, so the
+// other tenants of the infrastructure used by the target remain available after the enumeration.
+func (e *Enumeration) storeNetblockDomains(ctx context.Context, req *requests.WhoisRequest) {
+	for cidr, domains := range req.Netblocks {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		node, err := e.graph.UpsertNetblock(ctx, ipnet.String(), req.Source, e.Config.UUID.String())
+		if err != nil {
+			e.Config.Log.Printf("%s failed to insert the netblock: %v", e.graph, err)
+			continue
+		}
+
+		for _, d := range domains {
+			if d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); d != "" {
+				_ = e.graph.UpsertProperty(ctx, node, HostedDomainPredicate, d)
+			}
+		}
+		e.flusher.written(ipnet.String())
+	}
+}
+
+// HostedDomains returns the domains found hosted in the netblock identified by the CIDR.
+func HostedDomains(ctx context.Context, g *netmap.Graph, cidr string) []string {
+	node, err := g.ReadNode(ctx, cidr, netmap.TypeNetblock)
+	if err != nil {
+		return nil
+	}
+
+	props, err := g.ReadProperties(ctx, node, HostedDomainPredicate)
+	if err != nil {
+		return nil
+	}
+
+	var domains []string
+	seen := make(map[string]struct{})
+	for _, p := range props {
+		d, ok := p.Value.Native().(string)
+		if !ok || d == "" {
+			continue
+		}
+		if _, found := seen[d]; !found {
+			seen[d] = struct{}{}
+			domains = append(domains, d)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// NetblockDomains returns the netblocks in the events identified by the uuids, or in all the
+// events when none are provided, that domains were found hosted in. The netblocks hosting the
+// most domains are first.
+func NetblockDomains(ctx context.Context, g *netmap.Graph, uuids ...string) ([]*requests.NetblockDomains, error) {
+	nodes, err := g.AllNodesOfType(ctx, netmap.TypeNetblock, uuids...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*requests.NetblockDomains
+	seen := make(map[string]struct{})
+	for _, node := range nodes {
+		cidr := g.NodeToID(node)
+		if _, found := seen[cidr]; found {
+			continue
+		}
+		seen[cidr] = struct{}{}
+
+		if domains := HostedDomains(ctx, g, cidr); len(domains) > 0 {
+			results = append(results, &requests.NetblockDomains{
+				Netblock: cidr,
+				Domains:  domains,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if len(results[i].Domains) != len(results[j].Domains) {
+			return len(results[i].Domains) > len(results[j].Domains)
+		}
+		return results[i].Netblock < results[j].Netblock
+	})
+	return results, nil
+}
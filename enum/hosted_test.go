@@ -0,0 +1,53 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/caffix/netmap"
+)
+
+func TestNetblockDomains(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	uuid := "event"
+	for cidr, domains := range map[string][]string{
+		"192.0.2.0/24":    {"owasp.org", "example.com", "owasp.org"},
+		"198.51.100.0/24": {"example.net", "example.com", "owasp.net"},
+		"203.0.113.0/24":  nil,
+	} {
+		node, err := g.UpsertNetblock(ctx, cidr, "NetworksDB", uuid)
+		if err != nil {
+			t.Fatalf("Failed to insert the netblock: %v", err)
+		}
+		for _, d := range domains {
+			_ = g.UpsertProperty(ctx, node, HostedDomainPredicate, d)
+		}
+	}
+
+	if d := HostedDomains(ctx, g, "192.0.2.0/24"); fmt.Sprint(d) != "[example.com owasp.org]" {
+		t.Errorf("Unexpected domains hosted in the netblock: %v", d)
+	}
+
+	netblocks, err := NetblockDomains(ctx, g, uuid)
+	if err != nil {
+		t.Fatalf("Failed to obtain the netblocks: %v", err)
+	}
+	// The netblock without hosted domains is left out
+	if len(netblocks) != 2 {
+		t.Fatalf("Expected 2 netblocks, got %d", len(netblocks))
+	}
+	if nb := netblocks[0]; nb.Netblock != "198.51.100.0/24" || fmt.Sprint(nb.Domains) != "[example.com example.net owasp.net]" {
+		t.Errorf("The netblock hosting the most domains was not first: %+v", nb)
+	}
+	if nb := netblocks[1]; nb.Netblock != "192.0.2.0/24" {
+		t.Errorf("Unexpected netblock: %+v", nb)
+	}
+}
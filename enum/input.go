@@ -119,7 +119,7 @@ func (r *enumSource) newName(req *requests.DNSRequest) {
 	if r.subre.FindString(req.Name) != req.Name {
 		return
 	}
-	if r.enum.Config.Blacklisted(req.Name) {
+	if r.enum.Config.Blacklisted(req.Name) || !r.enum.Config.AllowedTLD(req.Name) {
 		return
 	}
 	// Do not further evaluate service subdomains
@@ -130,6 +130,7 @@ func (r *enumSource) newName(req *requests.DNSRequest) {
 			return
 		}
 	}
+	r.enum.recordPassive(req)
 	if r.accept(req.Name, req.Tag, req.Source, true) {
 		r.queue.Append(req)
 	}
@@ -206,6 +207,11 @@ func (r *enumSource) Next(ctx context.Context) bool {
 			r.markDone()
 			return false
 		case <-t.C:
+			// A paused enumeration is not considered finished for lack of new names
+			if r.enum.Paused() {
+				t.Reset(waitForDuration)
+				continue
+			}
 			r.markDone()
 			return false
 		case <-r.queue.Signal():
@@ -291,9 +297,15 @@ func (r *enumSource) monitorDataSrcOutput(srv service.Service) {
 
 			switch req := in.(type) {
 			case *requests.DNSRequest:
-				r.newName(req)
+				// Names emitted before the scope of the configuration changed are dropped
+				if r.enum.Config.IsDomainInScope(req.Name) {
+					r.newName(req)
+				}
 			case *requests.AddrRequest:
 				r.newAddr(req)
+			case *requests.WhoisRequest:
+				r.enum.storeNameServers(r.enum.ctx, req)
+				r.enum.storeNetblockDomains(r.enum.ctx, req)
 			}
 		}
 	}
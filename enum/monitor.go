@@ -42,6 +42,7 @@ func (e *Enumeration) checkForMissedWildcards(ip string) {
 		}
 
 		e.Config.BlacklistSubdomain(sub)
+		e.Sys.Wildcards().Insert(sub, e.Config.WhichDomain(sub))
 		for _, node := range nodes {
 			_ = e.graph.DeleteNode(e.ctx, node)
 		}
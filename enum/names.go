@@ -9,15 +9,20 @@ import (
 	"strings"
 
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/pipeline"
-	"github.com/caffix/stringset"
 )
 
+// DepthCappedCounter is the statistics counter of the subdomains that were not used as query
+// seeds, since they are deeper than the maximum recursion depth.
+const DepthCappedCounter = "depth_capped"
+
 // subdomainTask handles newly discovered proper subdomain names in the enumeration.
 type subdomainTask struct {
 	enum            *Enumeration
 	cnames          *stringset.Set
 	withinWildcards *stringset.Set
+	capped          *stringset.Set
 	timesChan       chan *timesReq
 	done            chan struct{}
 }
@@ -28,6 +33,7 @@ func newSubdomainTask(e *Enumeration) *subdomainTask {
 		enum:            e,
 		cnames:          stringset.New(),
 		withinWildcards: stringset.New(),
+		capped:          stringset.New(),
 		timesChan:       make(chan *timesReq, 10),
 		done:            make(chan struct{}, 2),
 	}
@@ -41,6 +47,7 @@ func (r *subdomainTask) Stop() {
 	close(r.done)
 	r.cnames.Close()
 	r.withinWildcards.Close()
+	r.capped.Close()
 }
 
 // Process implements the pipeline Task interface.
@@ -67,7 +74,7 @@ func (r *subdomainTask) Process(ctx context.Context, data pipeline.Data, tp pipe
 		}
 	}
 
-	if r.checkForSubdomains(ctx, req, tp) {
+	if r.checkForSubdomains(ctx, req, tp) && !exceedsMaxDepth(req.Name, req.Domain, r.enum.Config.MaxRecursionDepth) {
 		r.enum.sendRequests(&requests.ResolvedRequest{
 			Name:    req.Name,
 			Domain:  req.Domain,
@@ -91,9 +98,19 @@ func (r *subdomainTask) checkForSubdomains(ctx context.Context, req *requests.DN
 	}
 
 	sub := strings.TrimSpace(strings.Join(nlabels[1:], "."))
+	// Names deeper than the maximum are kept in the results, but do not seed further queries
+	if exceedsMaxDepth(sub, req.Domain, r.enum.Config.MaxRecursionDepth) {
+		if !r.capped.Has(sub) {
+			r.capped.Insert(sub)
+			r.enum.Sys.Stats().Count(DepthCappedCounter, 1)
+		}
+		return false
+	}
+
 	times := r.timesForSubdomain(sub)
 	if times == 1 && r.subWithinWildcard(ctx, sub, req.Domain) {
 		r.withinWildcards.Insert(sub)
+		r.enum.Sys.Wildcards().Insert(sub, req.Domain)
 		return false
 	} else if times > 1 && r.withinWildcards.Has(sub) {
 		return false
@@ -121,6 +138,15 @@ func (r *subdomainTask) checkForSubdomains(ctx context.Context, req *requests.DN
 	return true
 }
 
+// exceedsMaxDepth returns true when the name has more labels beyond the root domain than
+// the maximum depth allows. A maximum of zero means the depth is not limited.
+func exceedsMaxDepth(name, domain string, max int) bool {
+	if max <= 0 {
+		return false
+	}
+	return len(strings.Split(name, "."))-len(strings.Split(domain, ".")) > max
+}
+
 func (r *subdomainTask) subWithinWildcard(ctx context.Context, name, domain string) bool {
 	for _, t := range InitialQueryTypes {
 		select {
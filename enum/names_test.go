@@ -0,0 +1,27 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import "testing"
+
+func TestExceedsMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		expected bool
+	}{
+		{"owasp.org", 1, false},
+		{"www.owasp.org", 1, false},
+		{"a.www.owasp.org", 1, true},
+		{"a.www.owasp.org", 2, false},
+		{"a.b.c.d.e.owasp.org", 0, false},
+	}
+
+	for _, test := range tests {
+		if got := exceedsMaxDepth(test.name, "owasp.org", test.max); got != test.expected {
+			t.Errorf("%s with a maximum of %d: got %v, expected %v", test.name, test.max, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+// storeNameServers records the nameservers a data source reported for a domain in scope,
+// the same way as the NS records obtained through DNS resolution.
+func (e *Enumeration) storeNameServers(ctx context.Context, req *requests.WhoisRequest) {
+	domain := strings.Trim(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	if domain == "" || !e.Config.IsDomainInScope(domain) {
+		return
+	}
+
+	for _, ns := range requests.NormalizeNameServers(req.NameServers...) {
+		if err := e.graph.UpsertNS(ctx, domain, ns, req.Source, e.Config.UUID.String()); err != nil {
+			e.Config.Log.Printf("%s failed to insert NS record: %v", e.graph, err)
+			continue
+		}
+		e.flusher.written(ns)
+		// Nameservers within the scope are enumerated like the other names
+		if d := e.Config.WhichDomain(ns); d != "" && ns != d {
+			e.nameSrc.newName(&requests.DNSRequest{
+				Name:   ns,
+				Domain: d,
+				Tag:    req.Tag,
+				Source: req.Source,
+			})
+		}
+	}
+}
+
+// NameServersOf returns the nameservers that the name identified by id delegates to.
+func NameServersOf(ctx context.Context, g *netmap.Graph, id string) []string {
+	edges, err := g.ReadOutEdges(ctx, netmap.Node(id), "ns_record")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, edge := range edges {
+		if ns := g.NodeToID(edge.To); ns != "" {
+			names = append(names, ns)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NameServers returns the nameservers in the events identified by the uuids, or in all the
+// events when none are provided, along with the names that delegate to each of them. The
+// nameservers shared by the most names are first.
+func NameServers(ctx context.Context, g *netmap.Graph, uuids ...string) ([]*requests.NameServer, error) {
+	nodes, err := g.AllNodesOfType(ctx, netmap.TypeFQDN, uuids...)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[string]*requests.NameServer)
+	for _, node := range nodes {
+		name := g.NodeToID(node)
+
+		for _, ns := range NameServersOf(ctx, g, name) {
+			entry, found := lookup[ns]
+			if !found {
+				entry = &requests.NameServer{Name: ns}
+				lookup[ns] = entry
+			}
+			entry.Domains = append(entry.Domains, name)
+		}
+	}
+
+	results := make([]*requests.NameServer, 0, len(lookup))
+	for _, entry := range lookup {
+		sort.Strings(entry.Domains)
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if len(results[i].Domains) != len(results[j].Domains) {
+			return len(results[i].Domains) > len(results[j].Domains)
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
@@ -0,0 +1,49 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/caffix/netmap"
+)
+
+func TestNameServers(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	uuid := "event"
+	for _, rec := range [][2]string{
+		{"owasp.org", "ns1.cloudflare.com"},
+		{"owasp.org", "ns2.cloudflare.com"},
+		{"owasp.net", "ns1.cloudflare.com"},
+	} {
+		if err := g.UpsertNS(ctx, rec[0], rec[1], "DNS", uuid); err != nil {
+			t.Fatalf("Failed to insert the NS record: %v", err)
+		}
+	}
+
+	if ns := NameServersOf(ctx, g, "owasp.org"); fmt.Sprint(ns) != "[ns1.cloudflare.com ns2.cloudflare.com]" {
+		t.Errorf("Unexpected nameservers for the domain: %v", ns)
+	}
+
+	nameservers, err := NameServers(ctx, g, uuid)
+	if err != nil {
+		t.Fatalf("Failed to obtain the nameservers: %v", err)
+	}
+	if len(nameservers) != 2 {
+		t.Fatalf("Expected 2 nameservers, got %d", len(nameservers))
+	}
+	// The nameserver shared by both domains is first
+	if ns := nameservers[0]; ns.Name != "ns1.cloudflare.com" || fmt.Sprint(ns.Domains) != "[owasp.net owasp.org]" {
+		t.Errorf("The shared nameserver was not first: %+v", ns)
+	}
+	if ns := nameservers[1]; ns.Name != "ns2.cloudflare.com" || fmt.Sprint(ns.Domains) != "[owasp.org]" {
+		t.Errorf("Unexpected nameserver: %+v", ns)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"sort"
+
+	"github.com/caffix/netmap"
+)
+
+// OrgPredicate is the node property holding the labels of the data source accounts that reported a name.
+const OrgPredicate = "source_org"
+
+// markOrg stores the label of the data source account that reported the name. Each label is
+// only stored once, so the names reported through several accounts list all of them.
+func (e *Enumeration) markOrg(ctx context.Context, name, org string) {
+	if org == "" {
+		return
+	}
+
+	node, err := e.graph.ReadNode(ctx, name, netmap.TypeFQDN)
+	if err != nil {
+		return
+	}
+
+	for _, o := range Orgs(ctx, e.graph, name) {
+		if o == org {
+			return
+		}
+	}
+	_ = e.graph.UpsertProperty(ctx, node, OrgPredicate, org)
+}
+
+// Orgs returns the sorted labels of the data source accounts that reported the name identified by id.
+func Orgs(ctx context.Context, g *netmap.Graph, id string) []string {
+	node, err := g.ReadNode(ctx, id, netmap.TypeFQDN)
+	if err != nil {
+		return nil
+	}
+
+	props, err := g.ReadProperties(ctx, node, OrgPredicate)
+	if err != nil {
+		return nil
+	}
+
+	var orgs []string
+	seen := make(map[string]struct{})
+	for _, p := range props {
+		o, ok := p.Value.Native().(string)
+		if !ok || o == "" {
+			continue
+		}
+		if _, found := seen[o]; !found {
+			seen[o] = struct{}{}
+			orgs = append(orgs, o)
+		}
+	}
+
+	sort.Strings(orgs)
+	return orgs
+}
@@ -0,0 +1,45 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/caffix/netmap"
+)
+
+func TestOrgs(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	e := &Enumeration{Config: config.NewConfig(), graph: g}
+	if _, err := g.UpsertFQDN(ctx, "www.owasp.org", "Umbrella", "event"); err != nil {
+		t.Fatalf("Failed to insert the name: %v", err)
+	}
+
+	if orgs := Orgs(ctx, g, "www.owasp.org"); len(orgs) != 0 {
+		t.Errorf("Orgs returned labels before any were stored: %v", orgs)
+	}
+
+	// The name is reported through two accounts, and again through the first one
+	for _, org := range []string{"tenant-b", "tenant-a", "", "tenant-b"} {
+		e.markOrg(ctx, "www.owasp.org", org)
+	}
+	if orgs := Orgs(ctx, g, "www.owasp.org"); fmt.Sprint(orgs) != "[tenant-a tenant-b]" {
+		t.Errorf("Unexpected labels for the name: %v", orgs)
+	}
+
+	node, _ := g.ReadNode(ctx, "www.owasp.org", netmap.TypeFQDN)
+	if n, err := g.CountProperties(ctx, node, OrgPredicate); err != nil || n != 2 {
+		t.Errorf("Expected 2 org properties, got %d", n)
+	}
+	if orgs := Orgs(ctx, g, "missing.owasp.org"); orgs != nil {
+		t.Errorf("Orgs returned labels for a name not in the graph: %v", orgs)
+	}
+}
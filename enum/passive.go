@@ -0,0 +1,48 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+const (
+	// PassiveOnlyCounter is the statistics counter of the in-scope names reported by the data
+	// sources that were never confirmed through DNS resolution during the enumeration.
+	PassiveOnlyCounter = "passive_only"
+	// ResolvedCounter is the statistics counter of the in-scope names that resolved.
+	ResolvedCounter = "resolved"
+)
+
+// recordPassive tracks the name reported by a data source until it is resolved.
+func (e *Enumeration) recordPassive(req *requests.DNSRequest) {
+	if !req.Passive || !e.Config.IsDomainInScope(req.Name) || e.resolved.Has(req.Name) {
+		return
+	}
+
+	e.passive.Insert(req.Name)
+}
+
+// recordResolved clears the passive flag of the name that was confirmed through DNS resolution.
+func (e *Enumeration) recordResolved(req *requests.DNSRequest) {
+	req.Passive = false
+	if !e.Config.IsDomainInScope(req.Name) {
+		return
+	}
+
+	e.resolved.Insert(req.Name)
+	e.passive.Remove(req.Name)
+}
+
+// countPassiveNames adds the number of names in each category to the run statistics.
+func (e *Enumeration) countPassiveNames() {
+	e.Sys.Stats().Count(PassiveOnlyCounter, int64(e.passive.Len()))
+	e.Sys.Stats().Count(ResolvedCounter, int64(e.resolved.Len()))
+}
+
+// PassiveOnlyNames returns the names reported by the data sources that have not resolved.
+func (e *Enumeration) PassiveOnlyNames() []string {
+	return e.passive.Slice()
+}
@@ -0,0 +1,53 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/aokimio/Amass/v3/systems"
+)
+
+func TestPassiveNames(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	e := &Enumeration{
+		Config:   cfg,
+		Sys:      &systems.SimpleSystem{Cfg: cfg, Collector: stats.NewCollector()},
+		passive:  stringset.New(),
+		resolved: stringset.New(),
+	}
+
+	www := &requests.DNSRequest{Name: "www.owasp.org", Domain: "owasp.org", Source: "Umbrella", Passive: true}
+	e.recordPassive(www)
+	e.recordPassive(&requests.DNSRequest{Name: "old.owasp.org", Domain: "owasp.org", Source: "Umbrella", Passive: true})
+	e.recordPassive(&requests.DNSRequest{Name: "www.example.com", Domain: "example.com", Source: "Umbrella", Passive: true})
+	e.recordPassive(&requests.DNSRequest{Name: "guess.owasp.org", Domain: "owasp.org", Source: "Brute Forcing"})
+
+	e.recordResolved(www)
+	if www.Passive {
+		t.Errorf("The passive flag was not cleared after the name resolved")
+	}
+	// A name reported again after resolving is not considered passive
+	e.recordPassive(&requests.DNSRequest{Name: "www.owasp.org", Domain: "owasp.org", Source: "NetworksDB", Passive: true})
+	e.recordResolved(&requests.DNSRequest{Name: "api.owasp.org", Domain: "owasp.org", Source: "DNS"})
+
+	if names := e.PassiveOnlyNames(); len(names) != 1 || names[0] != "old.owasp.org" {
+		t.Errorf("Unexpected passive only names: %v", names)
+	}
+
+	e.countPassiveNames()
+	if n := e.Sys.Stats().Counter(PassiveOnlyCounter); n != 1 {
+		t.Errorf("Counted %d passive only names, expected 1", n)
+	}
+	if n := e.Sys.Stats().Counter(ResolvedCounter); n != 2 {
+		t.Errorf("Counted %d resolved names, expected 2", n)
+	}
+}
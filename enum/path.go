@@ -0,0 +1,109 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+// The longest CNAME chain followed while building the path of a name
+const maxPathAliases = 10
+
+// NamePath returns the discovery and resolution path of the name stored in the graph: the data
+// sources that reported it, the CNAME chain it resolves through, the addresses at the end of the
+// chain, and the netblocks and autonomous systems those addresses belong to. Only the
+// attribution from the events identified by the uuids is included, or from all the events
+// when none are provided.
+func NamePath(ctx context.Context, g *netmap.Graph, name string, uuids ...string) (*requests.NamePath, error) {
+	if _, err := g.ReadNode(ctx, name, netmap.TypeFQDN); err != nil {
+		return nil, fmt.Errorf("%s was not found in the graph database", name)
+	}
+
+	visited := make(map[string]struct{})
+	return namePath(ctx, g, name, uuids, visited), nil
+}
+
+func namePath(ctx context.Context, g *netmap.Graph, name string, uuids []string, visited map[string]struct{}) *requests.NamePath {
+	visited[name] = struct{}{}
+
+	path := &requests.NamePath{
+		Name:       name,
+		Sources:    nodeSources(ctx, g, name, uuids),
+		SourceURLs: SourceURLs(ctx, g, name),
+	}
+
+	if edges, err := g.ReadOutEdges(ctx, netmap.Node(name), "cname_record"); err == nil && len(edges) > 0 {
+		target := g.NodeToID(edges[0].To)
+
+		if _, found := visited[target]; !found && target != "" && len(visited) < maxPathAliases {
+			path.CNAME = namePath(ctx, g, target, uuids, visited)
+		}
+		return path
+	}
+
+	if edges, err := g.ReadOutEdges(ctx, netmap.Node(name), "a_record", "aaaa_record"); err == nil {
+		for _, edge := range edges {
+			if addr := g.NodeToID(edge.To); addr != "" {
+				path.Addresses = append(path.Addresses, addrPath(ctx, g, addr, uuids))
+			}
+		}
+	}
+	return path
+}
+
+func addrPath(ctx context.Context, g *netmap.Graph, addr string, uuids []string) *requests.AddrPath {
+	path := &requests.AddrPath{
+		Address: addr,
+		Sources: nodeSources(ctx, g, addr, uuids),
+	}
+
+	edges, err := g.ReadInEdges(ctx, netmap.Node(addr), "contains")
+	if err != nil {
+		return path
+	}
+	// Attribute the address to the most specific netblock containing it
+	bits := -1
+	for _, edge := range edges {
+		cidr := g.NodeToID(edge.From)
+
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if ones, _ := ipnet.Mask.Size(); ones > bits {
+			bits = ones
+			path.Netblock = cidr
+		}
+	}
+	if path.Netblock == "" {
+		return path
+	}
+	path.NetblockSources = nodeSources(ctx, g, path.Netblock, uuids)
+
+	if edges, err := g.ReadInEdges(ctx, netmap.Node(path.Netblock), "prefix"); err == nil && len(edges) > 0 {
+		asn := g.NodeToID(edges[0].From)
+
+		if n, err := strconv.Atoi(asn); err == nil {
+			path.ASN = n
+			path.Description = g.ReadASDescription(ctx, n)
+			path.ASNSources = nodeSources(ctx, g, asn, uuids)
+		}
+	}
+	return path
+}
+
+func nodeSources(ctx context.Context, g *netmap.Graph, id string, uuids []string) []string {
+	srcs, err := g.NodeSources(ctx, netmap.Node(id), uuids...)
+	if err != nil {
+		return nil
+	}
+	return srcs
+}
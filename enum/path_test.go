@@ -0,0 +1,66 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caffix/netmap"
+)
+
+func TestNamePath(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	uuid := "event"
+	if _, err := g.UpsertFQDN(ctx, "www.owasp.org", "Umbrella", uuid); err != nil {
+		t.Fatalf("Failed to insert the name: %v", err)
+	}
+	if err := g.UpsertCNAME(ctx, "www.owasp.org", "owasp.cdn.net", "DNS", uuid); err != nil {
+		t.Fatalf("Failed to insert the CNAME: %v", err)
+	}
+	if err := g.UpsertA(ctx, "owasp.cdn.net", "104.16.1.1", "DNS", uuid); err != nil {
+		t.Fatalf("Failed to insert the A record: %v", err)
+	}
+	if err := g.UpsertInfrastructure(ctx, 13335, "CLOUDFLARENET", "104.16.1.1", "104.16.0.0/12", "RIR", uuid); err != nil {
+		t.Fatalf("Failed to insert the infrastructure: %v", err)
+	}
+	if err := g.UpsertInfrastructure(ctx, 13335, "CLOUDFLARENET", "104.16.1.1", "104.16.0.0/16", "NetworksDB", uuid); err != nil {
+		t.Fatalf("Failed to insert the infrastructure: %v", err)
+	}
+
+	if _, err := NamePath(ctx, g, "missing.owasp.org"); err == nil {
+		t.Errorf("A path was returned for a name that is not in the graph")
+	}
+
+	path, err := NamePath(ctx, g, "www.owasp.org", uuid)
+	if err != nil {
+		t.Fatalf("Failed to build the path: %v", err)
+	}
+	if len(path.Sources) == 0 || path.CNAME == nil || path.CNAME.Name != "owasp.cdn.net" {
+		t.Fatalf("The sources or CNAME were not included: %+v", path)
+	}
+
+	var found bool
+	for _, src := range path.Sources {
+		if src == "Umbrella" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("The data source that reported the name is missing: %v", path.Sources)
+	}
+
+	addrs := path.CNAME.Addresses
+	if len(addrs) != 1 || addrs[0].Address != "104.16.1.1" {
+		t.Fatalf("The address at the end of the CNAME chain is missing: %+v", path.CNAME)
+	}
+	if a := addrs[0]; a.Netblock != "104.16.0.0/16" || a.ASN != 13335 || a.Description != "CLOUDFLARENET" ||
+		len(a.NetblockSources) != 1 || a.NetblockSources[0] != "NetworksDB" {
+		t.Errorf("The address was not attributed to the most specific netblock: %+v", a)
+	}
+}
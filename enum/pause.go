@@ -0,0 +1,64 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+// Pause holds back the requests sent to the data sources until Resume is called. The requests
+// already being handled are completed, and the data sources keep their connections and caches,
+// so the enumeration carries on where it left off. False is returned when already paused.
+func (e *Enumeration) Pause() bool {
+	e.pauseLock.Lock()
+	defer e.pauseLock.Unlock()
+
+	if e.resumed != nil {
+		return false
+	}
+
+	e.resumed = make(chan struct{})
+	e.Config.Log.Print("The enumeration has been paused")
+	return true
+}
+
+// Resume releases the requests held back by Pause. False is returned when not paused.
+func (e *Enumeration) Resume() bool {
+	e.pauseLock.Lock()
+	defer e.pauseLock.Unlock()
+
+	if e.resumed == nil {
+		return false
+	}
+
+	close(e.resumed)
+	e.resumed = nil
+	e.Config.Log.Print("The enumeration has been resumed")
+	return true
+}
+
+// Paused returns true while the requests to the data sources are held back.
+func (e *Enumeration) Paused() bool {
+	e.pauseLock.Lock()
+	defer e.pauseLock.Unlock()
+
+	return e.resumed != nil
+}
+
+// waitWhilePaused blocks until the enumeration is resumed, and returns false
+// when the enumeration ends first.
+func (e *Enumeration) waitWhilePaused() bool {
+	e.pauseLock.Lock()
+	resumed := e.resumed
+	e.pauseLock.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-e.done:
+	case <-e.ctx.Done():
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+func TestPauseResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := &Enumeration{Config: config.NewConfig(), ctx: ctx, done: make(chan struct{})}
+
+	if !e.waitWhilePaused() {
+		t.Errorf("The requests were held back without being paused")
+	}
+	if e.Resume() {
+		t.Errorf("Resume reported a transition without being paused")
+	}
+	if !e.Pause() || e.Pause() || !e.Paused() {
+		t.Fatalf("Pause did not report the transition once")
+	}
+
+	released := make(chan bool, 1)
+	go func() { released <- e.waitWhilePaused() }()
+	select {
+	case <-released:
+		t.Fatalf("The request was released while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !e.Resume() || e.Paused() {
+		t.Fatalf("Resume did not report the transition")
+	}
+	if ok := <-released; !ok {
+		t.Errorf("The request was not released by Resume")
+	}
+
+	// The held requests are released when the enumeration ends
+	e.Pause()
+	go func() { released <- e.waitWhilePaused() }()
+	cancel()
+	if ok := <-released; ok {
+		t.Errorf("The request was released as resumed after the enumeration ended")
+	}
+}
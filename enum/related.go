@@ -0,0 +1,90 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// The most related TLD variants checked for registration at the same time
+const maxRelatedTLDChecks = 10
+
+// expandRelatedTLDs adds to the scope the domains sharing the second-level label of a root domain
+// under the related TLDs, so they are enumerated like the root domains provided. Unless the
+// enumeration is passive, only the variants with NS records are added, since the others are not
+// registered and would spend the quota of the data sources for nothing.
+func (e *Enumeration) expandRelatedTLDs() {
+	if !e.Config.RelatedTLDs.Enabled {
+		return
+	}
+
+	domains := append([]string(nil), e.Config.Domains()...)
+	// A variant shared by several root domains is attributed to the first in alphabetical order
+	sort.Strings(domains)
+
+	candidates := make(map[string]string)
+	for _, domain := range domains {
+		for _, v := range e.Config.RelatedTLDVariants(domain) {
+			if _, found := candidates[v]; !found {
+				candidates[v] = domain
+			}
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	related := make(map[string]string)
+	sem := make(chan struct{}, maxRelatedTLDChecks)
+	for variant, seed := range candidates {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(variant, seed string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if !e.Config.Passive {
+				if _, err := e.fwdQuery(e.ctx, variant, dns.TypeNS); err != nil {
+					return
+				}
+			}
+
+			lock.Lock()
+			related[variant] = seed
+			lock.Unlock()
+		}(variant, seed)
+	}
+	wg.Wait()
+
+	var added []string
+	for variant := range related {
+		added = append(added, variant)
+	}
+	sort.Strings(added)
+
+	for _, variant := range added {
+		e.Config.AddDomain(variant)
+		e.Config.Log.Printf("Related TLDs: %s was added to the scope as a variant of %s", variant, related[variant])
+	}
+	e.relatedLock.Lock()
+	e.related = related
+	e.relatedLock.Unlock()
+}
+
+// RelatedTLDs returns the domains added to the scope by the related TLD expansion, each mapped to
+// the root domain it is a variant of.
+func (e *Enumeration) RelatedTLDs() map[string]string {
+	e.relatedLock.Lock()
+	defer e.relatedLock.Unlock()
+
+	related := make(map[string]string, len(e.related))
+	for variant, seed := range e.related {
+		related[variant] = seed
+	}
+	return related
+}
@@ -0,0 +1,43 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+func TestExpandRelatedTLDs(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Passive = true
+	cfg.AddDomains("example.com", "example.net", "owasp.org")
+	cfg.RelatedTLDs.TLDs = []string{"com", "net", "org"}
+	e := &Enumeration{Config: cfg, ctx: context.Background()}
+
+	// Nothing is added until the expansion is enabled
+	e.expandRelatedTLDs()
+	if len(cfg.Domains()) != 3 || len(e.RelatedTLDs()) != 0 {
+		t.Fatalf("The scope was expanded without the option: %v", cfg.Domains())
+	}
+
+	cfg.RelatedTLDs.Enabled = true
+	e.expandRelatedTLDs()
+	expected := map[string]string{
+		"example.org": "example.com",
+		"owasp.com":   "owasp.org",
+		"owasp.net":   "owasp.org",
+	}
+	if got := e.RelatedTLDs(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected related TLD variants: got %v, expected %v", got, expected)
+	}
+	for variant := range expected {
+		if !cfg.IsDomainInScope("www." + variant) {
+			t.Errorf("The variant %s was not added to the scope", variant)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aokimio/Amass/v3/net/rpki"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+)
+
+// RPKIPredicate is the node property holding the RPKI validation state of a netblock and its origin.
+const RPKIPredicate = "rpki"
+
+type rpkiRoute struct {
+	cidr string
+	asn  int
+}
+
+// rpkiValidator checks the origin of the netblocks discovered against the RPKI in the background,
+// so the checks sent to a validator API do not stall the pipeline.
+type rpkiValidator struct {
+	enum     *Enumeration
+	checker  *rpki.Checker
+	queue    queue.Queue
+	queued   *stringset.Set
+	done     chan struct{}
+	finished chan struct{}
+}
+
+func newRPKIValidator(e *Enumeration, checker *rpki.Checker) *rpkiValidator {
+	v := &rpkiValidator{
+		enum:     e,
+		checker:  checker,
+		queue:    queue.NewQueue(),
+		queued:   stringset.New(),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go v.processRequests()
+	return v
+}
+
+// Stop waits for the routes already queued to be checked, so their state is stored before
+// the findings are written, unless the enumeration is cancelled.
+func (v *rpkiValidator) Stop() {
+	close(v.done)
+	<-v.finished
+	v.queued.Close()
+}
+
+// validate queues the netblock originated by the ASN for a check, once per enumeration.
+func (v *rpkiValidator) validate(cidr string, asn int) {
+	key := cidr + " " + strconv.Itoa(asn)
+	if cidr == "" || asn <= 0 || v.queued.Has(key) {
+		return
+	}
+
+	v.queued.Insert(key)
+	v.queue.Append(&rpkiRoute{cidr: cidr, asn: asn})
+}
+
+func (v *rpkiValidator) processRequests() {
+	defer close(v.finished)
+
+	for {
+		select {
+		case <-v.enum.ctx.Done():
+			return
+		case <-v.done:
+			v.nextRequest()
+			return
+		case <-v.queue.Signal():
+			v.nextRequest()
+		}
+	}
+}
+
+func (v *rpkiValidator) nextRequest() {
+	for {
+		if v.enum.ctx.Err() != nil {
+			return
+		}
+
+		e, ok := v.queue.Next()
+		if !ok {
+			return
+		}
+		if r, ok := e.(*rpkiRoute); ok {
+			v.check(v.enum.ctx, r)
+		}
+	}
+}
+
+func (v *rpkiValidator) check(ctx context.Context, r *rpkiRoute) {
+	state, first := v.checker.Check(ctx, r.cidr, r.asn)
+	if state == "" || !first {
+		return
+	}
+	if state == rpki.Invalid {
+		v.enum.Config.Log.Printf("RPKI validation: %s originated by AS%d is RPKI invalid", r.cidr, r.asn)
+	}
+
+	if node, err := v.enum.graph.UpsertNode(ctx, r.cidr, netmap.TypeNetblock); err == nil {
+		_ = v.enum.graph.UpsertProperty(ctx, node, RPKIPredicate, state)
+		v.enum.flusher.written(r.cidr)
+	}
+}
+
+// NetblockRPKIState returns the RPKI validation state of the netblock, either rpki.Valid, rpki.Invalid
+// or rpki.NotFound, or an empty string when the netblock was not checked. A netblock found invalid
+// by any of the enumerations is reported as invalid, since the route may have been hijacked.
+func NetblockRPKIState(ctx context.Context, g *netmap.Graph, cidr string) string {
+	node, err := g.ReadNode(ctx, cidr, netmap.TypeNetblock)
+	if err != nil {
+		return ""
+	}
+
+	props, err := g.ReadProperties(ctx, node, RPKIPredicate)
+	if err != nil {
+		return ""
+	}
+
+	rank := map[string]int{rpki.NotFound: 1, rpki.Valid: 2, rpki.Invalid: 3}
+	var state string
+	for _, p := range props {
+		if s, ok := p.Value.Native().(string); ok && rank[s] > rank[state] {
+			state = s
+		}
+	}
+	return state
+}
@@ -0,0 +1,87 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+// SeedPredicate is the node property holding the root domains that led the enumeration to the asset.
+const SeedPredicate = "seed"
+
+// requestSeeds returns the root domains that led to the name in the request: the root domains
+// the name falls within, the seeds carried by the request from the names it was reached through,
+// and the seeds already stored for the name. The request is updated to carry all of them.
+func (e *Enumeration) requestSeeds(ctx context.Context, req *requests.DNSRequest) []string {
+	seeds := mergeSeeds(e.Config.SeedDomains(req.Name), req.Seeds)
+	seeds = mergeSeeds(seeds, Seeds(ctx, e.graph, req.Name, netmap.TypeFQDN))
+
+	req.Seeds = seeds
+	return seeds
+}
+
+// markSeeds stores the root domains that led the enumeration to the asset, once each.
+func (e *Enumeration) markSeeds(ctx context.Context, id, ntype string, seeds []string) {
+	if len(seeds) == 0 {
+		return
+	}
+
+	node, err := e.graph.ReadNode(ctx, id, ntype)
+	if err != nil {
+		return
+	}
+
+	stored := Seeds(ctx, e.graph, id, ntype)
+	for _, seed := range seeds {
+		if !containsSeed(stored, seed) {
+			_ = e.graph.UpsertProperty(ctx, node, SeedPredicate, seed)
+		}
+	}
+}
+
+// Seeds returns the root domains that led the enumerations to the asset identified by id, in
+// alphabetical order. Assets reachable from several root domains hold each of them.
+func Seeds(ctx context.Context, g *netmap.Graph, id, ntype string) []string {
+	node, err := g.ReadNode(ctx, id, ntype)
+	if err != nil {
+		return nil
+	}
+
+	props, err := g.ReadProperties(ctx, node, SeedPredicate)
+	if err != nil {
+		return nil
+	}
+
+	var seeds []string
+	for _, p := range props {
+		if s, ok := p.Value.Native().(string); ok && s != "" && !containsSeed(seeds, s) {
+			seeds = append(seeds, s)
+		}
+	}
+	sort.Strings(seeds)
+	return seeds
+}
+
+func mergeSeeds(seeds, more []string) []string {
+	for _, s := range more {
+		if s != "" && !containsSeed(seeds, s) {
+			seeds = append(seeds, s)
+		}
+	}
+	return seeds
+}
+
+func containsSeed(seeds []string, seed string) bool {
+	for _, s := range seeds {
+		if s == seed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+func TestSeeds(t *testing.T) {
+	ctx := context.Background()
+	g := netmap.NewGraph(netmap.NewCayleyGraphMemory())
+	defer g.Close()
+
+	cfg := config.NewConfig()
+	cfg.AddDomains("owasp.org", "example.com")
+	e := &Enumeration{Config: cfg, graph: g}
+
+	uuid := "event"
+	// Both seeds lead to the same CDN name
+	for _, name := range []string{"www.owasp.org", "www.example.com"} {
+		req := &requests.DNSRequest{Name: name, Domain: cfg.WhichDomain(name)}
+		seeds := e.requestSeeds(ctx, req)
+
+		if err := g.UpsertCNAME(ctx, name, "edge.cdn.net", "DNS", uuid); err != nil {
+			t.Fatalf("Failed to insert the CNAME record: %v", err)
+		}
+		e.markSeeds(ctx, name, netmap.TypeFQDN, seeds)
+		e.markSeeds(ctx, "edge.cdn.net", netmap.TypeFQDN, req.Seeds)
+	}
+
+	if seeds := Seeds(ctx, g, "www.owasp.org", netmap.TypeFQDN); fmt.Sprint(seeds) != "[owasp.org]" {
+		t.Errorf("Unexpected seeds for the name in scope: %v", seeds)
+	}
+	if seeds := Seeds(ctx, g, "edge.cdn.net", netmap.TypeFQDN); fmt.Sprint(seeds) != "[example.com owasp.org]" {
+		t.Errorf("The name reachable from both seeds did not record them: %v", seeds)
+	}
+
+	// The seeds stored for the name are carried by later requests for it
+	req := &requests.DNSRequest{Name: "edge.cdn.net", Domain: "cdn.net"}
+	if seeds := e.requestSeeds(ctx, req); len(seeds) != 2 || len(req.Seeds) != 2 {
+		t.Errorf("The request did not carry the stored seeds: %v", req.Seeds)
+	}
+
+	// The seeds are only stored once each
+	e.markSeeds(ctx, "edge.cdn.net", netmap.TypeFQDN, []string{"owasp.org"})
+	node, _ := g.ReadNode(ctx, "edge.cdn.net", netmap.TypeFQDN)
+	if n, err := g.CountProperties(ctx, node, SeedPredicate); err != nil || n != 2 {
+		t.Errorf("Expected 2 seed properties, got %d", n)
+	}
+}
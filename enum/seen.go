@@ -0,0 +1,109 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/caffix/netmap"
+)
+
+// The node properties holding the times an asset was first and last observed.
+const (
+	FirstSeenPredicate = "first_seen"
+	LastSeenPredicate  = "last_seen"
+)
+
+// markSeen stores the times the asset was first and last observed by the enumeration. The
+// timestamps are only written once for each enumeration, since each flush or migration into
+// the graph databases keeps all the values, and AssetTimestamps selects the earliest and latest.
+func (e *Enumeration) markSeen(ctx context.Context, id, ntype string, first, last time.Time) {
+	node, err := e.graph.ReadNode(ctx, id, ntype)
+	if err != nil {
+		return
+	}
+	if n, err := e.graph.CountProperties(ctx, node, LastSeenPredicate); err == nil && n > 0 {
+		return
+	}
+
+	now := time.Now()
+	if first.IsZero() {
+		first = now
+	}
+	if last.IsZero() {
+		last = now
+	}
+
+	_ = e.graph.UpsertProperty(ctx, node, FirstSeenPredicate, first.UTC().Format(time.RFC3339))
+	_ = e.graph.UpsertProperty(ctx, node, LastSeenPredicate, last.UTC().Format(time.RFC3339))
+}
+
+// markInfraSeen records the observation of the address, and the netblock and autonomous system containing it.
+// The netblocks announced by an autonomous system are queued for BGP and RPKI validation when enabled.
+func (e *Enumeration) markInfraSeen(ctx context.Context, addr, prefix string, asn int, first, last time.Time) {
+	e.flusher.touched(addr, prefix, strconv.Itoa(asn))
+	e.markSeen(ctx, addr, netmap.TypeAddr, time.Time{}, time.Time{})
+	e.markSeen(ctx, prefix, netmap.TypeNetblock, time.Time{}, time.Time{})
+	e.markSeen(ctx, strconv.Itoa(asn), netmap.TypeAS, first, last)
+
+	// Reserved and unknown addresses are assigned ASN zero and a prefix that is never announced
+	if e.bgp != nil && asn > 0 {
+		e.bgp.validate(prefix)
+	}
+	if e.rpki != nil && asn > 0 {
+		e.rpki.validate(prefix, asn)
+	}
+}
+
+// AssetTimestamps returns the times the asset identified by id was first and last observed across
+// the enumerations stored in the graph. Assets written before the timestamps were recorded fall back
+// to the date range of the events that discovered them.
+func AssetTimestamps(ctx context.Context, g *netmap.Graph, id, ntype string) (time.Time, time.Time) {
+	var first, last time.Time
+
+	node, err := g.ReadNode(ctx, id, ntype)
+	if err != nil {
+		return first, last
+	}
+
+	if props, err := g.ReadProperties(ctx, node, FirstSeenPredicate, LastSeenPredicate); err == nil {
+		for _, p := range props {
+			s, ok := p.Value.Native().(string)
+			if !ok {
+				continue
+			}
+
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				continue
+			}
+			if p.Predicate == FirstSeenPredicate && (first.IsZero() || t.Before(first)) {
+				first = t
+			} else if p.Predicate == LastSeenPredicate && t.After(last) {
+				last = t
+			}
+		}
+	}
+	if !first.IsZero() && !last.IsZero() {
+		return first, last
+	}
+
+	edges, err := g.ReadInEdges(ctx, node)
+	if err != nil {
+		return first, last
+	}
+	for _, edge := range edges {
+		start, finish := g.EventDateRange(ctx, g.NodeToID(edge.From))
+		if !start.IsZero() && (first.IsZero() || start.Before(first)) {
+			first = start
+		}
+		if finish.After(last) {
+			last = finish
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,49 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/caffix/netmap"
+)
+
+func TestAssetTimestamps(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	e := &Enumeration{
+		Config: cfg,
+		graph:  netmap.NewGraph(netmap.NewCayleyGraphMemory()),
+	}
+	defer e.graph.Close()
+
+	if _, err := e.graph.UpsertFQDN(ctx, "www.owasp.org", "DNS", cfg.UUID.String()); err != nil {
+		t.Fatalf("Failed to insert the name: %v", err)
+	}
+	// Names stored without timestamps use the date range of the events that discovered them
+	if first, last := AssetTimestamps(ctx, e.graph, "www.owasp.org", netmap.TypeFQDN); first.IsZero() || last.IsZero() {
+		t.Errorf("The event date range was not used for a name without timestamps")
+	}
+
+	earlier := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.markSeen(ctx, "www.owasp.org", netmap.TypeFQDN, earlier, earlier)
+	// A second observation by the same enumeration must not add more values
+	e.markSeen(ctx, "www.owasp.org", netmap.TypeFQDN, time.Time{}, time.Time{})
+	if first, last := AssetTimestamps(ctx, e.graph, "www.owasp.org", netmap.TypeFQDN); !first.Equal(earlier) || !last.Equal(earlier) {
+		t.Errorf("Expected the stored timestamps %v, got %v and %v", earlier, first, last)
+	}
+
+	// Simulate a later enumeration rediscovering the name in the same database
+	node, _ := e.graph.ReadNode(ctx, "www.owasp.org", netmap.TypeFQDN)
+	later := earlier.Add(48 * time.Hour)
+	_ = e.graph.UpsertProperty(ctx, node, FirstSeenPredicate, later.Format(time.RFC3339))
+	_ = e.graph.UpsertProperty(ctx, node, LastSeenPredicate, later.Format(time.RFC3339))
+	if first, last := AssetTimestamps(ctx, e.graph, "www.owasp.org", netmap.TypeFQDN); !first.Equal(earlier) || !last.Equal(later) {
+		t.Errorf("Rediscovery should keep first seen at %v and move last seen to %v, got %v and %v", earlier, later, first, last)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+
+	"github.com/caffix/netmap"
+)
+
+// SourceURLPredicate is the node property holding the web pages a name was extracted from.
+const SourceURLPredicate = "source_url"
+
+// markSourceURL stores the web page the name was extracted from, when the data source recorded it.
+func (e *Enumeration) markSourceURL(ctx context.Context, name, u string) {
+	if u == "" {
+		return
+	}
+
+	if node, err := e.graph.ReadNode(ctx, name, netmap.TypeFQDN); err == nil {
+		_ = e.graph.UpsertProperty(ctx, node, SourceURLPredicate, u)
+	}
+}
+
+// SourceURLs returns the web pages that the name identified by id was extracted from.
+func SourceURLs(ctx context.Context, g *netmap.Graph, id string) []string {
+	node, err := g.ReadNode(ctx, id, netmap.TypeFQDN)
+	if err != nil {
+		return nil
+	}
+
+	props, err := g.ReadProperties(ctx, node, SourceURLPredicate)
+	if err != nil {
+		return nil
+	}
+
+	var urls []string
+	seen := make(map[string]struct{})
+	for _, p := range props {
+		u, ok := p.Value.Native().(string)
+		if !ok || u == "" {
+			continue
+		}
+		if _, found := seen[u]; !found {
+			seen[u] = struct{}{}
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
@@ -16,6 +16,7 @@ import (
 	amassnet "github.com/aokimio/Amass/v3/net"
 	amassdns "github.com/aokimio/Amass/v3/net/dns"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
 	"github.com/caffix/pipeline"
 	"github.com/caffix/queue"
 	"github.com/caffix/resolve"
@@ -24,6 +25,9 @@ import (
 	"golang.org/x/net/publicsuffix"
 )
 
+// The maximum number of CNAME records followed from a discovered name
+const maxCNAMEChainLength = 10
+
 // dataManager is the stage that stores all data processed by the pipeline.
 type dataManager struct {
 	enum        *Enumeration
@@ -69,9 +73,14 @@ func (dm *dataManager) Process(ctx context.Context, data pipeline.Data, tp pipel
 		}
 
 		id = v.Name
+		seeds := dm.enum.requestSeeds(ctx, v)
 		if err := dm.dnsRequest(ctx, v, tp); err != nil {
 			dm.enum.Config.Log.Print(err.Error())
 		}
+		dm.enum.markSeen(ctx, v.Name, netmap.TypeFQDN, time.Time{}, time.Time{})
+		dm.enum.markSourceURL(ctx, v.Name, v.SourceURL)
+		dm.enum.markOrg(ctx, v.Name, v.Org)
+		dm.enum.markSeeds(ctx, v.Name, netmap.TypeFQDN, seeds)
 	case *requests.AddrRequest:
 		if v == nil {
 			return nil, nil
@@ -83,6 +92,7 @@ func (dm *dataManager) Process(ctx context.Context, data pipeline.Data, tp pipel
 		}
 	}
 
+	dm.enum.flusher.written(id)
 	if id != "" && dm.filter.TestAndAdd([]byte(id)) {
 		return nil, nil
 	}
@@ -104,6 +114,9 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 		}
 	}
 
+	// Names behind CDNs can resolve to many addresses, which each lead to ASN and netblock queries
+	capped := dm.enum.limitAddresses(req)
+
 	var err error
 	for i, r := range req.Records {
 		select {
@@ -136,6 +149,9 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 			break
 		}
 	}
+	if capped > 0 {
+		dm.enum.markAddrsCapped(ctx, req.Name, capped)
+	}
 	return err
 }
 
@@ -155,13 +171,74 @@ func (dm *dataManager) insertCNAME(ctx context.Context, req *requests.DNSRequest
 		Domain: strings.ToLower(domain),
 		Tag:    requests.DNS,
 		Source: "DNS",
+		Seeds:  req.Seeds,
 	})
 	if err := dm.enum.graph.UpsertCNAME(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert CNAME: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, target, netmap.TypeFQDN, req.Seeds)
+	if dm.enum.Config.FollowCNAMEs {
+		return dm.followCNAMEChain(ctx, req)
+	}
+	return nil
+}
+
+// followCNAMEChain stores the remaining hops of the CNAME chain and submits the hops that are
+// in scope, since an out-of-scope hop would not be investigated any further by the enumeration.
+func (dm *dataManager) followCNAMEChain(ctx context.Context, req *requests.DNSRequest) error {
+	chain := cnameChain(req.Name, req.Records, maxCNAMEChainLength)
+
+	for i := 1; i < len(chain)-1; i++ {
+		from, to := chain[i], chain[i+1]
+
+		if domain := dm.enum.Config.WhichDomain(to); domain != "" {
+			dm.enum.nameSrc.newName(&requests.DNSRequest{
+				Name:   to,
+				Domain: domain,
+				Tag:    requests.DNS,
+				Source: "DNS",
+				Seeds:  req.Seeds,
+			})
+		}
+		if err := dm.enum.graph.UpsertCNAME(ctx, from, to, req.Source, dm.enum.Config.UUID.String()); err != nil {
+			return fmt.Errorf("%s failed to insert CNAME: %v", dm.enum.graph, err)
+		}
+		dm.enum.markSeeds(ctx, to, netmap.TypeFQDN, req.Seeds)
+	}
 	return nil
 }
 
+// cnameChain returns the names reached by following the CNAME records from the provided name,
+// starting with the name itself. The chain stops at a loop or after max hops.
+func cnameChain(name string, records []requests.DNSAnswer, max int) []string {
+	next := make(map[string]string)
+	for _, r := range records {
+		if uint16(r.Type) == dns.TypeCNAME {
+			from := strings.Trim(strings.ToLower(r.Name), ".")
+			if _, found := next[from]; !found {
+				next[from] = strings.Trim(strings.ToLower(r.Data), ".")
+			}
+		}
+	}
+
+	chain := []string{name}
+	seen := map[string]struct{}{name: {}}
+	for hop := 0; hop < max; hop++ {
+		target, found := next[name]
+		if !found || target == "" {
+			break
+		}
+		if _, loop := seen[target]; loop {
+			break
+		}
+
+		seen[target] = struct{}{}
+		chain = append(chain, target)
+		name = target
+	}
+	return chain
+}
+
 func (dm *dataManager) insertA(ctx context.Context, req *requests.DNSRequest, recidx int, tp pipeline.TaskParams) error {
 	addr := strings.TrimSpace(req.Records[recidx].Data)
 	if addr == "" {
@@ -178,6 +255,7 @@ func (dm *dataManager) insertA(ctx context.Context, req *requests.DNSRequest, re
 	if err := dm.enum.graph.UpsertA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert A record: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, addr, netmap.TypeAddr, req.Seeds)
 	return nil
 }
 
@@ -197,6 +275,7 @@ func (dm *dataManager) insertAAAA(ctx context.Context, req *requests.DNSRequest,
 	if err := dm.enum.graph.UpsertAAAA(ctx, req.Name, addr, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert AAAA record: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, addr, netmap.TypeAddr, req.Seeds)
 	return nil
 }
 
@@ -235,11 +314,13 @@ func (dm *dataManager) insertSRV(ctx context.Context, req *requests.DNSRequest,
 			Domain: domain,
 			Tag:    requests.DNS,
 			Source: "DNS",
+			Seeds:  req.Seeds,
 		})
 	}
 	if err := dm.enum.graph.UpsertSRV(ctx, req.Name, service, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert SRV record: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, target, netmap.TypeFQDN, req.Seeds)
 	return nil
 }
 
@@ -259,11 +340,13 @@ func (dm *dataManager) insertNS(ctx context.Context, req *requests.DNSRequest, r
 			Domain: d,
 			Tag:    requests.DNS,
 			Source: "DNS",
+			Seeds:  req.Seeds,
 		})
 	}
 	if err := dm.enum.graph.UpsertNS(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert NS record: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, target, netmap.TypeFQDN, req.Seeds)
 	return nil
 }
 
@@ -283,11 +366,13 @@ func (dm *dataManager) insertMX(ctx context.Context, req *requests.DNSRequest, r
 			Domain: d,
 			Tag:    requests.DNS,
 			Source: "DNS",
+			Seeds:  req.Seeds,
 		})
 	}
 	if err := dm.enum.graph.UpsertMX(ctx, req.Name, target, req.Source, dm.enum.Config.UUID.String()); err != nil {
 		return fmt.Errorf("%s failed to insert MX record: %v", dm.enum.graph, err)
 	}
+	dm.enum.markSeeds(ctx, target, netmap.TypeFQDN, req.Seeds)
 	return nil
 }
 
@@ -347,11 +432,16 @@ func (dm *dataManager) addrRequest(ctx context.Context, req *requests.AddrReques
 	if req == nil || !req.InScope || uuid == "" {
 		return nil
 	}
+	if dm.enum.geo != nil {
+		dm.enum.geo.locate(ctx, req)
+	}
 	if yes, prefix := amassnet.IsReservedAddress(req.Address); yes {
 		var err error
 		if e := dm.enum.graph.UpsertInfrastructure(ctx, 0,
 			amassnet.ReservedCIDRDescription, req.Address, prefix, "RIR", uuid); e != nil {
 			err = e
+		} else {
+			dm.enum.markInfraSeen(ctx, req.Address, prefix, 0, time.Time{}, time.Time{})
 		}
 		return err
 	}
@@ -360,6 +450,8 @@ func (dm *dataManager) addrRequest(ctx context.Context, req *requests.AddrReques
 		if e := dm.enum.graph.UpsertInfrastructure(ctx, r.ASN,
 			r.Description, req.Address, r.Prefix, r.Source, uuid); e != nil {
 			err = e
+		} else {
+			dm.enum.markInfraSeen(ctx, req.Address, r.Prefix, r.ASN, r.FirstSeen, r.LastSeen)
 		}
 		return err
 	}
@@ -394,7 +486,10 @@ func (dm *dataManager) nextInfraInfo() {
 	req := e.(*requests.AddrRequest)
 	uuid := dm.enum.Config.UUID.String()
 	if r := dm.enum.Sys.Cache().AddrSearch(req.Address); r != nil {
-		_ = dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
+		if err := dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid); err == nil {
+			dm.enum.markInfraSeen(ctx, req.Address, r.Prefix, r.ASN, r.FirstSeen, r.LastSeen)
+		}
+		dm.enum.flusher.written()
 		return
 	}
 
@@ -409,7 +504,10 @@ loop:
 
 		time.Sleep(2 * time.Second)
 		if r := dm.enum.Sys.Cache().AddrSearch(req.Address); r != nil {
-			_ = dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid)
+			if err := dm.enum.graph.UpsertInfrastructure(ctx, r.ASN, r.Description, req.Address, r.Prefix, r.Source, uuid); err == nil {
+				dm.enum.markInfraSeen(ctx, req.Address, r.Prefix, r.ASN, r.FirstSeen, r.LastSeen)
+			}
+			dm.enum.flusher.written()
 			return
 		}
 	}
@@ -417,7 +515,10 @@ loop:
 	asn := 0
 	desc := "Unknown"
 	prefix := fakePrefix(req.Address)
-	_ = dm.enum.graph.UpsertInfrastructure(ctx, asn, desc, req.Address, prefix, "RIR", uuid)
+	if err := dm.enum.graph.UpsertInfrastructure(ctx, asn, desc, req.Address, prefix, "RIR", uuid); err == nil {
+		dm.enum.markInfraSeen(ctx, req.Address, prefix, asn, time.Time{}, time.Time{})
+	}
+	dm.enum.flusher.written()
 
 	first, cidr, _ := net.ParseCIDR(prefix)
 	dm.enum.Sys.Cache().Update(&requests.ASNRequest{
@@ -0,0 +1,63 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/miekg/dns"
+)
+
+func TestCNAMEChain(t *testing.T) {
+	cname := func(name, target string) requests.DNSAnswer {
+		return requests.DNSAnswer{Name: name, Type: int(dns.TypeCNAME), Data: target}
+	}
+
+	tests := []struct {
+		name     string
+		records  []requests.DNSAnswer
+		max      int
+		expected []string
+	}{
+		{
+			name: "Out of scope and back",
+			records: []requests.DNSAnswer{
+				cname("www.owasp.org", "owasp.cdn.net."),
+				cname("OWASP.cdn.net.", "edge.owasp.org."),
+				{Name: "edge.owasp.org", Type: int(dns.TypeA), Data: "10.0.0.1"},
+			},
+			max:      maxCNAMEChainLength,
+			expected: []string{"www.owasp.org", "owasp.cdn.net", "edge.owasp.org"},
+		},
+		{
+			name: "Loop",
+			records: []requests.DNSAnswer{
+				cname("www.owasp.org", "a.cdn.net"),
+				cname("a.cdn.net", "b.cdn.net"),
+				cname("b.cdn.net", "www.owasp.org"),
+			},
+			max:      maxCNAMEChainLength,
+			expected: []string{"www.owasp.org", "a.cdn.net", "b.cdn.net"},
+		},
+		{
+			name: "Bounded",
+			records: []requests.DNSAnswer{
+				cname("www.owasp.org", "a.cdn.net"),
+				cname("a.cdn.net", "b.cdn.net"),
+				cname("b.cdn.net", "c.owasp.org"),
+			},
+			max:      2,
+			expected: []string{"www.owasp.org", "a.cdn.net", "b.cdn.net"},
+		},
+	}
+
+	for _, test := range tests {
+		if chain := cnameChain("www.owasp.org", test.records, test.max); !reflect.DeepEqual(chain, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, chain, test.expected)
+		}
+	}
+}
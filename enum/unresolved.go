@@ -0,0 +1,49 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/netmap"
+)
+
+// UnresolvedCounter is the statistics counter of the names provided by the data sources that did
+// not resolve, and were recorded in the graph without being included in the output.
+const UnresolvedCounter = "unresolved_filtered"
+
+// recordUnresolved stores the name that failed to resolve when the only_resolved option is
+// active, so the graph databases keep the findings that the output leaves out. Names generated
+// by brute forcing, alterations and DNS queries are not stored, since most are not expected to exist.
+func (e *Enumeration) recordUnresolved(ctx context.Context, req *requests.DNSRequest) {
+	if !e.Config.OnlyResolved || e.Config.Passive || !e.Config.IsDomainInScope(req.Name) {
+		return
+	}
+
+	switch req.Tag {
+	case requests.ALT, requests.BRUTE, requests.DNS, requests.GUESS, requests.NONE:
+		return
+	}
+	if e.unresolved.Has(req.Name) {
+		return
+	}
+
+	if _, err := e.graph.UpsertFQDN(ctx, req.Name, req.Source, e.Config.UUID.String()); err != nil {
+		e.Config.Log.Print(err.Error())
+		return
+	}
+
+	e.unresolved.Insert(req.Name)
+	e.Sys.Stats().Count(UnresolvedCounter, 1)
+	e.markSeen(ctx, req.Name, netmap.TypeFQDN, time.Time{}, time.Time{})
+	e.flusher.written(req.Name)
+}
+
+// UnresolvedNames returns the names recorded by the enumeration that did not resolve.
+func (e *Enumeration) UnresolvedNames() []string {
+	return e.unresolved.Slice()
+}
@@ -0,0 +1,56 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/netmap"
+)
+
+func TestRecordUnresolved(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	cfg.Log = log.New(ioutil.Discard, "", 0)
+
+	e := &Enumeration{
+		Config:     cfg,
+		Sys:        &systems.SimpleSystem{Cfg: cfg, Collector: stats.NewCollector()},
+		graph:      netmap.NewGraph(netmap.NewCayleyGraphMemory()),
+		unresolved: stringset.New(),
+	}
+	defer e.graph.Close()
+
+	old := &requests.DNSRequest{Name: "old.owasp.org", Domain: "owasp.org", Tag: requests.API, Source: "Umbrella"}
+	e.recordUnresolved(ctx, old)
+	if _, err := e.graph.ReadNode(ctx, old.Name, netmap.TypeFQDN); err == nil {
+		t.Errorf("The name was stored without the only_resolved option")
+	}
+
+	cfg.OnlyResolved = true
+	e.recordUnresolved(ctx, old)
+	e.recordUnresolved(ctx, old)
+	e.recordUnresolved(ctx, &requests.DNSRequest{Name: "guess.owasp.org", Domain: "owasp.org", Tag: requests.BRUTE, Source: "Brute Forcing"})
+	e.recordUnresolved(ctx, &requests.DNSRequest{Name: "www.example.com", Domain: "example.com", Tag: requests.API, Source: "Umbrella"})
+
+	if _, err := e.graph.ReadNode(ctx, old.Name, netmap.TypeFQDN); err != nil {
+		t.Errorf("The unresolved name was not stored in the graph")
+	}
+	if names := e.UnresolvedNames(); len(names) != 1 || names[0] != old.Name {
+		t.Errorf("Unexpected unresolved names: %v", names)
+	}
+	if n := e.Sys.Stats().Counter(UnresolvedCounter); n != 1 {
+		t.Errorf("The unresolved names were counted %d times", n)
+	}
+}
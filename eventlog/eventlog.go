@@ -0,0 +1,234 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package eventlog writes the timeline of the discoveries made during an enumeration as JSON
+// lines, in the order they happened, so the run can be analyzed or replayed afterwards.
+package eventlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// The types of the events in the log.
+const (
+	NameEvent    = "name"
+	AddressEvent = "address"
+	WhoisEvent   = "whois"
+	ASNEvent     = "asn"
+)
+
+// Event is a single discovery in the log. The sequence numbers start at one and increase by one
+// with each event, and the elapsed time is measured on a monotonic clock from the creation of the
+// log, so the order of the events does not depend on adjustments made to the wall clock.
+type Event struct {
+	Seq         uint64    `json:"seq"`
+	Time        time.Time `json:"time"`
+	Elapsed     int64     `json:"elapsed_ns"`
+	Type        string    `json:"type"`
+	Source      string    `json:"source,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Domain      string    `json:"domain,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	ASN         int       `json:"asn,omitempty"`
+	Prefix      string    `json:"prefix,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Netblocks   []string  `json:"netblocks,omitempty"`
+	NewDomains  []string  `json:"new_domains,omitempty"`
+	NameServers []string  `json:"name_servers,omitempty"`
+}
+
+// Log writes the events to an io.Writer. The methods are safe for concurrent use, and safe
+// to call on a nil Log, which discards the events.
+type Log struct {
+	sync.Mutex
+	start  time.Time
+	seq    uint64
+	w      *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer
+	err    error
+}
+
+// NewLog returns a Log that writes the events to w, measuring their times from now.
+// When w is an io.Closer, it is closed along with the Log.
+func NewLog(w io.Writer) *Log {
+	bw := bufio.NewWriter(w)
+	closer, _ := w.(io.Closer)
+
+	return &Log{
+		start:  time.Now(),
+		w:      bw,
+		enc:    json.NewEncoder(bw),
+		closer: closer,
+	}
+}
+
+// Record assigns the next sequence number and timestamp to the event and writes it.
+// The sequence number is assigned while the event is written, so the lines of the log
+// are always in sequence, however many goroutines are recording events.
+func (l *Log) Record(e *Event) {
+	if l == nil || e == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	elapsed := time.Since(l.start)
+	l.seq++
+	e.Seq = l.seq
+	e.Elapsed = int64(elapsed)
+	e.Time = l.start.Add(elapsed).UTC()
+	if err := l.enc.Encode(e); err != nil && l.err == nil {
+		l.err = err
+	}
+}
+
+// RecordOutput writes an event for the request emitted by the named data source. The requests
+// that are not discoveries are ignored.
+func (l *Log) RecordOutput(source string, req interface{}) {
+	if l == nil {
+		return
+	}
+
+	switch v := req.(type) {
+	case *requests.DNSRequest:
+		l.Record(&Event{
+			Type:   NameEvent,
+			Source: source,
+			Tag:    v.Tag,
+			Name:   v.Name,
+			Domain: v.Domain,
+		})
+	case *requests.AddrRequest:
+		l.Record(&Event{
+			Type:    AddressEvent,
+			Source:  source,
+			Tag:     v.Tag,
+			Address: v.Address,
+			Domain:  v.Domain,
+		})
+	case *requests.WhoisRequest:
+		l.Record(&Event{
+			Type:        WhoisEvent,
+			Source:      source,
+			Tag:         v.Tag,
+			Domain:      v.Domain,
+			NewDomains:  v.NewDomains,
+			NameServers: v.NameServers,
+		})
+	case *requests.ASNRequest:
+		l.RecordASN(v)
+	}
+}
+
+// RecordASN writes an event for the ASN information added to the cache.
+func (l *Log) RecordASN(req *requests.ASNRequest) {
+	if l == nil || req == nil {
+		return
+	}
+
+	l.Record(&Event{
+		Type:        ASNEvent,
+		Source:      req.Source,
+		Tag:         req.Tag,
+		Address:     req.Address,
+		ASN:         req.ASN,
+		Prefix:      req.Prefix,
+		Description: req.Description,
+		Netblocks:   req.Netblocks,
+	})
+}
+
+// Flush writes the buffered events and returns the first error encountered while writing the log.
+func (l *Log) Flush() error {
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if err := l.w.Flush(); err != nil && l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
+
+// Close flushes the buffered events and closes the writer of the Log. The events recorded
+// afterwards are discarded.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	err := l.Flush()
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.closer != nil {
+		if cerr := l.closer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		l.closer = nil
+	}
+	l.w.Reset(ioutil.Discard)
+	return err
+}
+
+// Replay reads the events from r and provides them to fn in the order they were recorded. With a
+// speed above zero, Replay waits between the events for the time that separated them during the
+// run, divided by the speed, so a speed of 10 replays the run ten times faster. Replay stops at the
+// first error returned by fn, or once the context is done.
+func Replay(ctx context.Context, r io.Reader, speed float64, fn func(*Event) error) error {
+	dec := json.NewDecoder(r)
+
+	var last int64
+	var seq uint64
+	for {
+		var e Event
+
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if e.Seq <= seq {
+			return errors.New("the events of the log are out of sequence")
+		}
+		seq = e.Seq
+
+		if speed > 0 && e.Elapsed > last {
+			t := time.NewTimer(time.Duration(float64(e.Elapsed-last) / speed))
+
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+		if e.Elapsed > last {
+			last = e.Elapsed
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+}
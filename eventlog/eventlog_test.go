@@ -0,0 +1,149 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestLogSequence(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLog(&buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				l.RecordOutput("crtsh", &requests.DNSRequest{
+					Name:   fmt.Sprintf("host%d-%d.owasp.org", i, j),
+					Domain: "owasp.org",
+					Tag:    requests.CERT,
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := l.Close(); err != nil {
+		t.Fatalf("Failed to close the log: %v", err)
+	}
+
+	var count uint64
+	var last time.Time
+	err := Replay(context.Background(), &buf, 0, func(e *Event) error {
+		count++
+		if e.Seq != count {
+			return fmt.Errorf("event %d has the sequence number %d", count, e.Seq)
+		}
+		if e.Time.Before(last) {
+			return fmt.Errorf("event %d went back in time", e.Seq)
+		}
+		last = e.Time
+		if e.Type != NameEvent || e.Source != "crtsh" || e.Domain != "owasp.org" || e.Tag != requests.CERT {
+			return fmt.Errorf("event %d has unexpected fields: %+v", e.Seq, e)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1000 {
+		t.Errorf("Replayed %d events instead of 1000", count)
+	}
+}
+
+func TestRecordOutput(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLog(&buf)
+
+	l.RecordOutput("Umbrella", &requests.WhoisRequest{
+		Domain:      "owasp.org",
+		NewDomains:  []string{"owasp.net"},
+		NameServers: []string{"ns1.owasp.org"},
+	})
+	l.RecordOutput("AlienVault", &requests.AddrRequest{Address: "192.0.2.1", Domain: "owasp.org"})
+	l.RecordASN(&requests.ASNRequest{ASN: 26808, Prefix: "192.0.2.0/24", Netblocks: []string{"192.0.2.0/24"}, Source: "RADb"})
+	// Requests that are not discoveries are left out of the log
+	l.RecordOutput("RADb", &requests.ZoneXFRRequest{Name: "owasp.org"})
+	_ = l.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("The log has %d events instead of 3: %s", len(lines), buf.String())
+	}
+	for i, expected := range []string{
+		`"type":"whois","source":"Umbrella","domain":"owasp.org","new_domains":["owasp.net"],"name_servers":["ns1.owasp.org"]`,
+		`"type":"address","source":"AlienVault","domain":"owasp.org","address":"192.0.2.1"`,
+		`"type":"asn","source":"RADb","asn":26808,"prefix":"192.0.2.0/24","netblocks":["192.0.2.0/24"]`,
+	} {
+		if !strings.Contains(lines[i], expected) {
+			t.Errorf("Event %d is %s, expected it to contain %s", i+1, lines[i], expected)
+		}
+	}
+
+	var nilLog *Log
+	nilLog.RecordOutput("crtsh", &requests.DNSRequest{Name: "www.owasp.org"})
+	if err := nilLog.Close(); err != nil {
+		t.Errorf("Closing a nil log returned %v", err)
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	input := `{"seq":1,"elapsed_ns":0,"type":"name","name":"a.owasp.org"}
+{"seq":2,"elapsed_ns":200000000,"type":"name","name":"b.owasp.org"}
+`
+	start := time.Now()
+	var names []string
+	err := Replay(context.Background(), strings.NewReader(input), 2, func(e *Event) error {
+		names = append(names, e.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("The replay at twice the speed took %v instead of at least 100ms", elapsed)
+	}
+	if strings.Join(names, ",") != "a.owasp.org,b.owasp.org" {
+		t.Errorf("Replayed the names %v", names)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Replay(ctx, strings.NewReader(input), 1, func(*Event) error { return nil }); err != context.Canceled {
+		t.Errorf("The cancelled replay returned %v", err)
+	}
+
+	outOfOrder := `{"seq":2,"type":"name"}
+{"seq":1,"type":"name"}
+`
+	if err := Replay(context.Background(), strings.NewReader(outOfOrder), 0, func(*Event) error { return nil }); err == nil {
+		t.Errorf("The events out of sequence were replayed without an error")
+	}
+}
+
+func TestASNCacheObserver(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLog(&buf)
+
+	cache := requests.NewASNCache()
+	cache.SetObserver(l.RecordASN)
+	cache.Update(&requests.ASNRequest{ASN: 26808, Prefix: "192.0.2.0/24", Source: "RADb"})
+	_ = l.Flush()
+
+	if !strings.Contains(buf.String(), `"type":"asn","source":"RADb","asn":26808`) {
+		t.Errorf("The cache update was not logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package datasource is an example of a data source implemented outside of the Amass module.
+// Importing the package for side effects, e.g. from a copy of cmd/amass, registers the data source:
+//
+//	import _ "github.com/aokimio/Amass/v3/examples/datasource"
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/datasrcs"
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/systems"
+	"github.com/caffix/service"
+)
+
+func init() {
+	datasrcs.RegisterDataSource(func(sys systems.System) service.Service {
+		return NewExample(sys)
+	})
+}
+
+// Example is the Service that handles access to a hypothetical line-oriented API.
+type Example struct {
+	service.BaseService
+
+	SourceType string
+	sys        systems.System
+}
+
+// NewExample returns the object initialized, but not yet started.
+func NewExample(sys systems.System) *Example {
+	e := &Example{
+		SourceType: requests.API,
+		sys:        sys,
+	}
+
+	go e.requests()
+	e.BaseService = *service.NewBaseService(e, "Example")
+	return e
+}
+
+// Description implements the Service interface.
+func (e *Example) Description() string {
+	return e.SourceType
+}
+
+// OnStart implements the Service interface.
+func (e *Example) OnStart() error {
+	e.SetRateLimit(1)
+	return nil
+}
+
+func (e *Example) requests() {
+	for {
+		select {
+		case <-e.Done():
+			return
+		case in := <-e.Input():
+			switch req := in.(type) {
+			case *requests.DNSRequest:
+				e.CheckRateLimit()
+				e.dnsRequest(context.TODO(), req)
+			}
+		}
+	}
+}
+
+func (e *Example) dnsRequest(ctx context.Context, req *requests.DNSRequest) {
+	if !e.sys.Config().IsDomainInScope(req.Domain) {
+		return
+	}
+
+	u := fmt.Sprintf("https://api.example.com/subdomains/%s", req.Domain)
+	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	if err != nil {
+		e.sys.Config().Log.Printf("%s: %s: %v", e.String(), u, err)
+		return
+	}
+
+	for _, line := range strings.Split(page, "\n") {
+		name := http.CleanName(line)
+		if domain := e.sys.Config().WhichDomain(name); domain != "" {
+			e.Output() <- &requests.DNSRequest{
+				Name:   name,
+				Domain: domain,
+				Tag:    e.SourceType,
+				Source: e.String(),
+			}
+		}
+	}
+}
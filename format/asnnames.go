@@ -0,0 +1,75 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"net"
+	"sort"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// ASNNames returns, for each of the autonomous systems, its netblocks and the names with an
+// address contained in one of them. An address is matched by containment, so a name is listed
+// even when its address was attributed to a more specific netblock announced by another ASN.
+func ASNNames(asns []*requests.ASNRequest, outputs []*requests.Output) []*requests.ASNNames {
+	var results []*requests.ASNNames
+
+	for _, req := range asns {
+		var netblocks []string
+		var ipnets []*net.IPNet
+		for _, cidr := range req.Netblocks {
+			if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+				netblocks = append(netblocks, ipnet.String())
+				ipnets = append(ipnets, ipnet)
+			}
+		}
+		sort.Strings(netblocks)
+
+		result := &requests.ASNNames{
+			ASN:         req.ASN,
+			Description: req.Description,
+			Netblocks:   netblocks,
+		}
+		for _, out := range outputs {
+			if addrs := addrsWithin(out.Addresses, ipnets); len(addrs) > 0 {
+				result.Names = append(result.Names, &requests.NameAddrs{
+					Name:      out.Name,
+					Addresses: addrs,
+				})
+			}
+		}
+
+		sort.Slice(result.Names, func(i, j int) bool {
+			return result.Names[i].Name < result.Names[j].Name
+		})
+		results = append(results, result)
+	}
+	return results
+}
+
+func addrsWithin(addrs []requests.AddressInfo, ipnets []*net.IPNet) []string {
+	var within []string
+
+	seen := make(map[string]struct{})
+	for _, a := range addrs {
+		if a.Address == nil {
+			continue
+		}
+
+		addr := a.Address.String()
+		if _, found := seen[addr]; found {
+			continue
+		}
+		for _, ipnet := range ipnets {
+			if ipnet.Contains(a.Address) {
+				seen[addr] = struct{}{}
+				within = append(within, addr)
+				break
+			}
+		}
+	}
+	return within
+}
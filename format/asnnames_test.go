@@ -0,0 +1,58 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestASNNames(t *testing.T) {
+	output := func(name string, ips ...string) *requests.Output {
+		out := &requests.Output{Name: name}
+		for _, ip := range ips {
+			out.Addresses = append(out.Addresses, requests.AddressInfo{Address: net.ParseIP(ip)})
+		}
+		return out
+	}
+
+	asns := []*requests.ASNRequest{
+		{ASN: 64500, Description: "EXAMPLE-AS", Netblocks: []string{"192.0.2.0/24", "2001:db8::/32", "bad"}},
+		{ASN: 64501, Description: "EMPTY-AS", Netblocks: []string{"198.51.100.0/24"}},
+	}
+	outputs := []*requests.Output{
+		output("www.owasp.org", "192.0.2.10", "203.0.113.1", "192.0.2.10"),
+		output("api.owasp.org", "2001:db8::1"),
+		output("mail.owasp.org", "203.0.113.2"),
+	}
+
+	results := ASNNames(asns, outputs)
+	if len(results) != 2 {
+		t.Fatalf("Expected results for 2 ASNs, got %d", len(results))
+	}
+
+	first := results[0]
+	if first.ASN != 64500 || first.Description != "EXAMPLE-AS" {
+		t.Errorf("Unexpected ASN: %d %s", first.ASN, first.Description)
+	}
+	if got := fmt.Sprint(first.Netblocks); got != "[192.0.2.0/24 2001:db8::/32]" {
+		t.Errorf("Unexpected netblocks: %s", got)
+	}
+
+	var names []string
+	for _, n := range first.Names {
+		names = append(names, fmt.Sprintf("%s %v", n.Name, n.Addresses))
+	}
+	if got := fmt.Sprint(names); got != "[api.owasp.org [2001:db8::1] www.owasp.org [192.0.2.10]]" {
+		t.Errorf("Unexpected names: %s", got)
+	}
+
+	if len(results[1].Names) != 0 {
+		t.Errorf("Expected no names in %d, got %d", results[1].ASN, len(results[1].Names))
+	}
+}
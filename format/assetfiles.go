@@ -0,0 +1,255 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// AssetFiles keeps a file for each discovered name within a directory, such as a git repository
+// holding an inventory. The files are written in a stable format, so the changes between runs
+// show up as small diffs.
+type AssetFiles struct {
+	dir     string
+	assets  map[string]*assetFile
+	updated map[string]struct{}
+}
+
+type assetFile struct {
+	Name      string         `json:"name"`
+	Domain    string         `json:"domain"`
+	Addresses []assetAddress `json:"addresses"`
+	ASNs      []int          `json:"asns"`
+	Sources   []string       `json:"sources"`
+	FirstSeen time.Time      `json:"first_seen"`
+	LastSeen  time.Time      `json:"last_seen"`
+}
+
+type assetAddress struct {
+	IP          string `json:"ip"`
+	CIDR        string `json:"cidr"`
+	ASN         int    `json:"asn"`
+	Description string `json:"desc"`
+}
+
+// NewAssetFiles returns an AssetFiles that keeps the files within the directory provided.
+func NewAssetFiles(dir string) *AssetFiles {
+	return &AssetFiles{
+		dir:     dir,
+		assets:  make(map[string]*assetFile),
+		updated: make(map[string]struct{}),
+	}
+}
+
+// AssetFilePath returns the path of the file for the name, relative to the directory of the
+// inventory. The labels are reversed, so www.example.com is kept in com/example/www.json, and
+// the characters that are not safe within a path are escaped.
+func AssetFilePath(name string) string {
+	var parts []string
+
+	labels := strings.Split(strings.Trim(strings.ToLower(strings.TrimSpace(name)), "."), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if label := assetPathLabel(labels[i]); label != "" {
+			parts = append(parts, label)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	parts[len(parts)-1] += ".json"
+	return filepath.Join(parts...)
+}
+
+func assetPathLabel(label string) string {
+	var b strings.Builder
+
+	for _, c := range []byte(label) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			b.WriteByte(c)
+			continue
+		}
+		// The escaping keeps distinct names from sharing a file
+		fmt.Fprintf(&b, "%%%02x", c)
+	}
+	return b.String()
+}
+
+// Write merges the output into the file of the name. The addresses and sources reported during
+// the run replace those from previous runs, while the time the name was first seen is kept. The
+// file is only written when its content changes.
+func (a *AssetFiles) Write(out *requests.Output) error {
+	rel := AssetFilePath(out.Name)
+	if rel == "" {
+		return fmt.Errorf("the name %q cannot be used as an asset file path", out.Name)
+	}
+	path := filepath.Join(a.dir, rel)
+
+	asset, found := a.assets[rel]
+	if !found {
+		asset = &assetFile{
+			Name:   strings.ToLower(out.Name),
+			Domain: out.Domain,
+		}
+		// The first time the name was seen is carried over from the previous runs
+		if prev := readAssetFile(path); prev != nil && !prev.FirstSeen.IsZero() {
+			asset.FirstSeen = prev.FirstSeen
+		}
+		a.assets[rel] = asset
+	}
+	asset.merge(out)
+
+	data, err := json.MarshalIndent(asset, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if cur, err := ioutil.ReadFile(path); err == nil && bytes.Equal(cur, data) {
+		return nil
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	a.updated[rel] = struct{}{}
+	return nil
+}
+
+func (f *assetFile) merge(out *requests.Output) {
+	if f.Domain == "" {
+		f.Domain = out.Domain
+	}
+
+	addrs := make(map[string]assetAddress)
+	for _, addr := range f.Addresses {
+		addrs[addr.IP] = addr
+	}
+	for _, addr := range out.Addresses {
+		if addr.Address == nil {
+			continue
+		}
+		ip := addr.Address.String()
+		if prev, found := addrs[ip]; found && addr.ASN == 0 && prev.ASN != 0 {
+			continue
+		}
+		addrs[ip] = assetAddress{
+			IP:          ip,
+			CIDR:        addr.CIDRStr,
+			ASN:         addr.ASN,
+			Description: addr.Description,
+		}
+	}
+
+	f.Addresses = f.Addresses[:0]
+	asns := make(map[int]struct{})
+	for _, addr := range addrs {
+		f.Addresses = append(f.Addresses, addr)
+		if addr.ASN != 0 {
+			asns[addr.ASN] = struct{}{}
+		}
+	}
+	sort.Slice(f.Addresses, func(i, j int) bool {
+		return f.Addresses[i].IP < f.Addresses[j].IP
+	})
+
+	f.ASNs = f.ASNs[:0]
+	for asn := range asns {
+		f.ASNs = append(f.ASNs, asn)
+	}
+	sort.Ints(f.ASNs)
+
+	srcs := make(map[string]struct{})
+	for _, src := range append(f.Sources, out.Sources...) {
+		srcs[src] = struct{}{}
+	}
+	f.Sources = f.Sources[:0]
+	for src := range srcs {
+		f.Sources = append(f.Sources, src)
+	}
+	sort.Strings(f.Sources)
+
+	first, last := out.FirstSeen, out.LastSeen
+	if first.IsZero() {
+		first = time.Now()
+	}
+	if last.IsZero() {
+		last = first
+	}
+	// The timestamps are recorded in UTC to the second, so the files do not depend on the host
+	first = first.UTC().Truncate(time.Second)
+	last = last.UTC().Truncate(time.Second)
+	if f.FirstSeen.IsZero() || first.Before(f.FirstSeen) {
+		f.FirstSeen = first
+	}
+	if last.After(f.LastSeen) {
+		f.LastSeen = last
+	}
+}
+
+func readAssetFile(path string) *assetFile {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var f assetFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil
+	}
+	return &f
+}
+
+// writeFileAtomic replaces the file through a rename, so a run that is interrupted does not
+// leave a partial file behind.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".asset-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Names returns the number of names written during the run.
+func (a *AssetFiles) Names() int {
+	return len(a.assets)
+}
+
+// Updated returns the paths of the files created or changed during the run, relative to the
+// directory and in alphabetical order.
+func (a *AssetFiles) Updated() []string {
+	var paths []string
+
+	for rel := range a.updated {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
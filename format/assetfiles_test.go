@@ -0,0 +1,155 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestAssetFilePath(t *testing.T) {
+	for name, expected := range map[string]string{
+		"www.example.com":     filepath.Join("com", "example", "www.json"),
+		"WWW.Example.com.":    filepath.Join("com", "example", "www.json"),
+		"example.com":         filepath.Join("com", "example.json"),
+		"_dmarc.example.com":  filepath.Join("com", "example", "_dmarc.json"),
+		"*.example.com":       filepath.Join("com", "example", "%2a.json"),
+		"a/b..example.com":    filepath.Join("com", "example", "a%2fb.json"),
+		"..":                  "",
+		"a\\b.example.com":    filepath.Join("com", "example", "a%5cb.json"),
+		"münchen.example.com": filepath.Join("com", "example", "m%c3%bcnchen.json"),
+		"xn--mnchen-3ya.de":   filepath.Join("de", "xn--mnchen-3ya.json"),
+		" mail.example.com  ": filepath.Join("com", "example", "mail.json"),
+	} {
+		if got := AssetFilePath(name); got != expected {
+			t.Errorf("AssetFilePath(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestAssetFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	a := NewAssetFiles(dir)
+	for _, out := range []*requests.Output{
+		{
+			Name:   "www.example.com",
+			Domain: "example.com",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("192.0.2.2"), CIDRStr: "192.0.2.0/24", ASN: 64496, Description: "EXAMPLE"},
+			},
+			Sources:   []string{"DNS"},
+			FirstSeen: first,
+			LastSeen:  first,
+		},
+		{
+			Name:   "www.example.com",
+			Domain: "example.com",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("192.0.2.1"), CIDRStr: "192.0.2.0/24", ASN: 64496, Description: "EXAMPLE"},
+			},
+			Sources:   []string{"Crtsh", "DNS"},
+			FirstSeen: first.Add(time.Minute),
+			LastSeen:  first.Add(time.Minute),
+		},
+	} {
+		if err := a.Write(out); err != nil {
+			t.Fatalf("Failed to write the asset file: %v", err)
+		}
+	}
+
+	path := filepath.Join(dir, "com", "example", "www.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("The asset file was not written: %v", err)
+	}
+	expected := `{
+  "name": "www.example.com",
+  "domain": "example.com",
+  "addresses": [
+    {
+      "ip": "192.0.2.1",
+      "cidr": "192.0.2.0/24",
+      "asn": 64496,
+      "desc": "EXAMPLE"
+    },
+    {
+      "ip": "192.0.2.2",
+      "cidr": "192.0.2.0/24",
+      "asn": 64496,
+      "desc": "EXAMPLE"
+    }
+  ],
+  "asns": [
+    64496
+  ],
+  "sources": [
+    "Crtsh",
+    "DNS"
+  ],
+  "first_seen": "2022-03-01T10:00:00Z",
+  "last_seen": "2022-03-01T10:01:00Z"
+}
+`
+	if string(data) != expected {
+		t.Errorf("Unexpected asset file content:\n%s", data)
+	}
+	if updated := a.Updated(); len(updated) != 1 || updated[0] != filepath.Join("com", "example", "www.json") {
+		t.Errorf("Unexpected updated files: %v", updated)
+	}
+
+	// The next run replaces the addresses and keeps the time the name was first seen
+	later := first.Add(24 * time.Hour)
+	a = NewAssetFiles(dir)
+	if err := a.Write(&requests.Output{
+		Name:   "www.example.com",
+		Domain: "example.com",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("198.51.100.7"), CIDRStr: "198.51.100.0/24", ASN: 64497, Description: "OTHER"},
+		},
+		Sources:   []string{"DNS"},
+		FirstSeen: later,
+		LastSeen:  later,
+	}); err != nil {
+		t.Fatalf("Failed to update the asset file: %v", err)
+	}
+
+	data, _ = ioutil.ReadFile(path)
+	content := string(data)
+	if strings.Contains(content, "192.0.2.1") || !strings.Contains(content, "198.51.100.7") ||
+		!strings.Contains(content, `"first_seen": "2022-03-01T10:00:00Z"`) ||
+		!strings.Contains(content, `"last_seen": "2022-03-02T10:00:00Z"`) || strings.Contains(content, "Crtsh") {
+		t.Errorf("The asset file was not updated as expected:\n%s", data)
+	}
+
+	// The file is left alone when nothing changed
+	a = NewAssetFiles(dir)
+	if err := a.Write(&requests.Output{
+		Name:   "www.example.com",
+		Domain: "example.com",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("198.51.100.7"), CIDRStr: "198.51.100.0/24", ASN: 64497, Description: "OTHER"},
+		},
+		Sources:   []string{"DNS"},
+		FirstSeen: later,
+		LastSeen:  later,
+	}); err != nil {
+		t.Fatalf("Failed to write the asset file: %v", err)
+	}
+	if updated := a.Updated(); len(updated) != 0 || a.Names() != 1 {
+		t.Errorf("The unchanged asset file was reported as updated: %v", updated)
+	}
+
+	if err := a.Write(&requests.Output{Name: "..."}); err == nil {
+		t.Errorf("The name without labels was accepted")
+	}
+}
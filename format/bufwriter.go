@@ -0,0 +1,103 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// BufferedWriter batches the writes to an output file, so a large enumeration does not write
+// each line separately. The buffered data is written once the buffer is full, once the flush
+// interval has elapsed, and whenever Flush or Close is called. A record passed to a single Write
+// is never split between two flushes, unless it is larger than the buffer.
+type BufferedWriter struct {
+	sync.Mutex
+	buf       *bufio.Writer
+	immediate bool
+	done      chan struct{}
+	finished  chan struct{}
+}
+
+// NewBufferedWriter returns a BufferedWriter holding up to size bytes before writing to w, and
+// writing the buffered data at least once each interval when the interval is greater than zero.
+// A size of zero or less writes each record immediately, for consumers reading the output as
+// it is produced.
+func NewBufferedWriter(w io.Writer, size int, interval time.Duration) *BufferedWriter {
+	bw := &BufferedWriter{
+		immediate: size <= 0,
+		done:      make(chan struct{}),
+		finished:  make(chan struct{}),
+	}
+	if bw.immediate {
+		bw.buf = bufio.NewWriter(w)
+	} else {
+		bw.buf = bufio.NewWriterSize(w, size)
+	}
+
+	if !bw.immediate && interval > 0 {
+		go bw.periodicFlush(interval)
+	} else {
+		close(bw.finished)
+	}
+	return bw
+}
+
+// Write implements the io.Writer interface and is safe for concurrent use.
+func (bw *BufferedWriter) Write(p []byte) (int, error) {
+	bw.Lock()
+	defer bw.Unlock()
+
+	// Keep the record in one piece by writing out what is buffered first
+	if len(p) > bw.buf.Available() && bw.buf.Buffered() > 0 {
+		if err := bw.buf.Flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := bw.buf.Write(p)
+	if err == nil && bw.immediate {
+		err = bw.buf.Flush()
+	}
+	return n, err
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (bw *BufferedWriter) Flush() error {
+	bw.Lock()
+	defer bw.Unlock()
+
+	return bw.buf.Flush()
+}
+
+// Close stops the periodic flushes and writes the remaining data. The underlying writer is left open.
+func (bw *BufferedWriter) Close() error {
+	select {
+	case <-bw.done:
+	default:
+		close(bw.done)
+	}
+	<-bw.finished
+
+	return bw.Flush()
+}
+
+func (bw *BufferedWriter) periodicFlush(interval time.Duration) {
+	defer close(bw.finished)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-bw.done:
+			return
+		case <-t.C:
+			_ = bw.Flush()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingWriter records each write received, so the batching can be observed.
+type countingWriter struct {
+	sync.Mutex
+	buf    bytes.Buffer
+	writes []string
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.writes = append(c.writes, string(p))
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) state() (string, []string) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.buf.String(), append([]string(nil), c.writes...)
+}
+
+func TestBufferedWriterBatches(t *testing.T) {
+	cw := &countingWriter{}
+	bw := NewBufferedWriter(cw, 32, 0)
+
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(bw, "www%d.owasp.org\n", i)
+	}
+	// Each line is 16 bytes, so the third line does not fit with the first two
+	if _, writes := cw.state(); len(writes) != 2 || writes[0] != "www0.owasp.org\nwww1.owasp.org\n" {
+		t.Errorf("The lines were not written in batches: %q", writes)
+	}
+
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if out, _ := cw.state(); strings.Count(out, "\n") != 5 {
+		t.Errorf("Flush did not write the buffered lines: %q", out)
+	}
+
+	// A record larger than the buffer is written in one piece
+	long := strings.Repeat("a", 40) + "\n"
+	fmt.Fprint(bw, "short\n")
+	fmt.Fprint(bw, long)
+	if err := bw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if out, writes := cw.state(); !strings.HasSuffix(out, "short\n"+long) || writes[len(writes)-1] != long {
+		t.Errorf("The large record was split: %q", writes)
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	cw := &countingWriter{}
+	bw := NewBufferedWriter(cw, 4096, 20*time.Millisecond)
+	defer func() { _ = bw.Close() }()
+
+	fmt.Fprint(bw, "www.owasp.org\n")
+	if out, _ := cw.state(); out != "" {
+		t.Errorf("The line was written before the interval elapsed")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if out, _ := cw.state(); out == "www.owasp.org\n" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("The line was not written once the interval elapsed")
+}
+
+func TestBufferedWriterImmediate(t *testing.T) {
+	cw := &countingWriter{}
+	bw := NewBufferedWriter(cw, 0, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fmt.Fprintf(bw, "www%d.owasp.org\n", i)
+		}(i)
+	}
+	wg.Wait()
+
+	if _, writes := cw.state(); len(writes) != 10 {
+		t.Errorf("The lines were not written immediately: %q", writes)
+	}
+	for _, w := range cw.writes {
+		if !strings.HasPrefix(w, "www") || strings.Count(w, "\n") != 1 {
+			t.Errorf("A line was interleaved with another: %q", w)
+		}
+	}
+	if err := bw.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
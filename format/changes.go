@@ -0,0 +1,97 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// The types of the changes found between two enumerations of a target.
+const (
+	NewName    = "new_name"
+	NewAddress = "new_address"
+	NewASN     = "new_asn"
+)
+
+// Change is a name, address or autonomous system that appeared since an earlier enumeration.
+type Change struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// The discovered name that the address or AS was found through
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Sources     []string `json:"sources"`
+}
+
+// Changes returns the names, addresses and autonomous systems within the newer findings that
+// are missing from the older findings, each attributed to the data sources of the name it was
+// found through. An address or AS reached through several names is reported once, with the
+// first of those names in alphabetical order. The changes are sorted by type and then by value.
+func Changes(older, newer []*requests.Output) []*Change {
+	names := make(map[string]struct{})
+	addrs := make(map[string]struct{})
+	asns := make(map[int]struct{})
+	for _, o := range older {
+		names[o.Name] = struct{}{}
+		for _, a := range o.Addresses {
+			if a.Address != nil {
+				addrs[a.Address.String()] = struct{}{}
+			}
+			if a.ASN > 0 {
+				asns[a.ASN] = struct{}{}
+			}
+		}
+	}
+
+	sorted := append([]*requests.Output(nil), newer...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var changes []*Change
+	add := func(typ, value string, o *requests.Output, desc string) {
+		changes = append(changes, &Change{
+			Type:        typ,
+			Value:       value,
+			Name:        o.Name,
+			Description: desc,
+			Sources:     o.Sources,
+		})
+	}
+	for _, o := range sorted {
+		if _, found := names[o.Name]; !found {
+			names[o.Name] = struct{}{}
+			add(NewName, o.Name, o, "")
+		}
+		for _, a := range o.Addresses {
+			if a.Address != nil {
+				addr := a.Address.String()
+				if _, found := addrs[addr]; !found {
+					addrs[addr] = struct{}{}
+					add(NewAddress, addr, o, "")
+				}
+			}
+			if _, found := asns[a.ASN]; a.ASN > 0 && !found {
+				asns[a.ASN] = struct{}{}
+				add(NewASN, strconv.Itoa(a.ASN), o, a.Description)
+			}
+		}
+	}
+
+	order := map[string]int{NewName: 0, NewAddress: 1, NewASN: 2}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Type != changes[j].Type {
+			return order[changes[i].Type] < order[changes[j].Type]
+		}
+		if changes[i].Type == NewASN {
+			a, _ := strconv.Atoi(changes[i].Value)
+			b, _ := strconv.Atoi(changes[j].Value)
+			return a < b
+		}
+		return changes[i].Value < changes[j].Value
+	})
+	return changes
+}
@@ -0,0 +1,53 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestChanges(t *testing.T) {
+	output := func(name string, sources []string, addrs ...requests.AddressInfo) *requests.Output {
+		return &requests.Output{Name: name, Sources: sources, Addresses: addrs}
+	}
+	addr := func(ip string, asn int, desc string) requests.AddressInfo {
+		return requests.AddressInfo{Address: net.ParseIP(ip), ASN: asn, Description: desc}
+	}
+
+	older := []*requests.Output{
+		output("www.owasp.org", []string{"DNS"}, addr("10.0.0.1", 64500, "Old AS")),
+	}
+	newer := []*requests.Output{
+		output("www.owasp.org", []string{"DNS"}, addr("10.0.0.1", 64500, "Old AS"), addr("10.0.0.2", 64500, "Old AS")),
+		output("vpn.owasp.org", []string{"Umbrella", "crtsh"}, addr("192.0.2.1", 100, "New AS")),
+		output("api.owasp.org", []string{"crtsh"}, addr("192.0.2.1", 100, "New AS")),
+	}
+
+	var got []string
+	for _, c := range Changes(older, newer) {
+		got = append(got, fmt.Sprintf("%s %s %s %q %v", c.Type, c.Value, c.Name, c.Description, c.Sources))
+	}
+	expected := []string{
+		`new_name api.owasp.org api.owasp.org "" [crtsh]`,
+		`new_name vpn.owasp.org vpn.owasp.org "" [Umbrella crtsh]`,
+		`new_address 10.0.0.2 www.owasp.org "" [DNS]`,
+		`new_address 192.0.2.1 api.owasp.org "" [crtsh]`,
+		`new_asn 100 api.owasp.org "New AS" [crtsh]`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Unexpected changes:\n%v\nexpected:\n%v", got, expected)
+	}
+
+	if changes := Changes(newer, newer); len(changes) != 0 {
+		t.Errorf("Changes were found between identical findings: %v", changes)
+	}
+	if changes := Changes(nil, older); len(changes) != 3 {
+		t.Errorf("Expected the name, address and AS of the first findings, got %d changes", len(changes))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"sync"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// AddressClassifier tags the findings whose addresses are within the sinkhole and parking ranges
+// of the configuration, and counts the names tagged with each class. A name is only tagged when
+// all of its addresses are within the ranges, since a name with another address may still be live.
+type AddressClassifier struct {
+	sync.Mutex
+	cfg      *config.SinkholeConfig
+	counts   map[string]int
+	excluded int
+}
+
+// NewAddressClassifier returns a classifier using the ranges provided, or nil when none were configured.
+func NewAddressClassifier(sc *config.SinkholeConfig) *AddressClassifier {
+	if sc == nil {
+		return nil
+	}
+	return &AddressClassifier{
+		cfg:    sc,
+		counts: make(map[string]int),
+	}
+}
+
+// Allow sets the class of the addresses and of the name, and returns false when the name is
+// tagged and the configuration leaves the tagged names out of the output.
+func (ac *AddressClassifier) Allow(out *requests.Output) bool {
+	if ac == nil || len(out.Addresses) == 0 {
+		return true
+	}
+
+	classes := make(map[string]bool)
+	for i, a := range out.Addresses {
+		if a.Address == nil {
+			continue
+		}
+
+		out.Addresses[i].Class = ac.cfg.Classify(a.Address.String())
+		classes[out.Addresses[i].Class] = true
+	}
+	// The sinkholes are reported over the parking services when the addresses are in both
+	switch {
+	case classes[""] || len(classes) == 0:
+		out.Class = ""
+	case classes[config.SinkholeClass]:
+		out.Class = config.SinkholeClass
+	default:
+		out.Class = config.ParkedClass
+	}
+	if out.Class == "" {
+		return true
+	}
+
+	ac.Lock()
+	defer ac.Unlock()
+
+	ac.counts[out.Class]++
+	if ac.cfg.Exclude {
+		ac.excluded++
+		return false
+	}
+	return true
+}
+
+// Counts returns the number of names tagged with each class.
+func (ac *AddressClassifier) Counts() map[string]int {
+	counts := make(map[string]int)
+	if ac == nil {
+		return counts
+	}
+
+	ac.Lock()
+	defer ac.Unlock()
+
+	for class, n := range ac.counts {
+		counts[class] = n
+	}
+	return counts
+}
+
+// Excluded returns the number of tagged names that were left out of the output.
+func (ac *AddressClassifier) Excluded() int {
+	if ac == nil {
+		return 0
+	}
+
+	ac.Lock()
+	defer ac.Unlock()
+
+	return ac.excluded
+}
@@ -0,0 +1,69 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestAddressClassifier(t *testing.T) {
+	output := func(name string, addrs ...string) *requests.Output {
+		o := &requests.Output{Name: name}
+		for _, addr := range addrs {
+			o.Addresses = append(o.Addresses, requests.AddressInfo{Address: net.ParseIP(addr)})
+		}
+		return o
+	}
+
+	var none *AddressClassifier
+	if NewAddressClassifier(nil) != nil || !none.Allow(output("www.owasp.org", "192.0.2.1")) {
+		t.Errorf("The output was classified without any ranges")
+	}
+
+	sc := &config.SinkholeConfig{
+		Sinkholes: []string{"192.0.2.0/24"},
+		Parked:    []string{"203.0.113.0/24"},
+	}
+	ac := NewAddressClassifier(sc)
+	for _, test := range []struct {
+		out      *requests.Output
+		expected string
+	}{
+		{output("sink.owasp.org", "192.0.2.1"), config.SinkholeClass},
+		{output("parked.owasp.org", "203.0.113.5", "203.0.113.6"), config.ParkedClass},
+		{output("both.owasp.org", "203.0.113.5", "192.0.2.1"), config.SinkholeClass},
+		{output("live.owasp.org", "192.0.2.1", "198.51.100.1"), ""},
+		{output("passive.owasp.org"), ""},
+	} {
+		if !ac.Allow(test.out) {
+			t.Errorf("%s: the name was excluded without the exclude option", test.out.Name)
+		}
+		if test.out.Class != test.expected {
+			t.Errorf("%s: got class %q, expected %q", test.out.Name, test.out.Class, test.expected)
+		}
+	}
+
+	live := output("live.owasp.org", "192.0.2.1", "198.51.100.1")
+	ac.Allow(live)
+	if live.Addresses[0].Class != config.SinkholeClass || live.Addresses[1].Class != "" {
+		t.Errorf("The addresses of the live name were not classified: %+v", live.Addresses)
+	}
+	if counts := ac.Counts(); counts[config.SinkholeClass] != 2 || counts[config.ParkedClass] != 1 || ac.Excluded() != 0 {
+		t.Errorf("Unexpected counts: %v, %d excluded", counts, ac.Excluded())
+	}
+
+	sc.Exclude = true
+	ac = NewAddressClassifier(sc)
+	if ac.Allow(output("sink.owasp.org", "192.0.2.1")) || !ac.Allow(output("live.owasp.org", "198.51.100.1")) {
+		t.Errorf("The exclude option did not select the tagged names")
+	}
+	if ac.Excluded() != 1 {
+		t.Errorf("Expected 1 excluded name, got %d", ac.Excluded())
+	}
+}
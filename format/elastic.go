@@ -0,0 +1,422 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	amasshttp "github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/caffix/queue"
+)
+
+const (
+	// The maximum time documents wait in the queue before a partial batch is sent
+	elasticFlushInterval = 5 * time.Second
+	// The number of attempts made for each request while the cluster is reachable
+	elasticMaxAttempts    = 5
+	elasticInitialBackoff = time.Second
+)
+
+// The document types stored in the index.
+const (
+	ElasticTypeFQDN = "fqdn"
+	ElasticTypeAddr = "ipaddress"
+	ElasticTypeASN  = "asn"
+)
+
+// The mapping used when the sink creates the index. Names are keywords for exact matching
+// and aggregations, and the address fields use the ip types to support range queries.
+var elasticMapping = []byte(`{"mappings":{"properties":{` +
+	`"type":{"type":"keyword"},` +
+	`"name":{"type":"keyword"},` +
+	`"domain":{"type":"keyword"},` +
+	`"addresses":{"type":"ip"},` +
+	`"address":{"type":"ip"},` +
+	`"cidr":{"type":"ip_range"},` +
+	`"asn":{"type":"long"},` +
+	`"description":{"type":"text"},` +
+	`"tag":{"type":"keyword"},` +
+	`"sources":{"type":"keyword"},` +
+	`"first_seen":{"type":"date"},` +
+	`"last_seen":{"type":"date"},` +
+	`"@timestamp":{"type":"date"},` +
+	`"enum_uuid":{"type":"keyword"}}}}`)
+
+// ElasticDocument represents a discovered name, address or autonomous system in the index.
+type ElasticDocument struct {
+	ID          string    `json:"-"`
+	Type        string    `json:"type"`
+	Name        string    `json:"name,omitempty"`
+	Domain      string    `json:"domain,omitempty"`
+	Addresses   []string  `json:"addresses,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	CIDR        string    `json:"cidr,omitempty"`
+	ASN         int       `json:"asn,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+	Sources     []string  `json:"sources,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Timestamp   time.Time `json:"@timestamp"`
+	UUID        string    `json:"enum_uuid,omitempty"`
+}
+
+// ElasticDocuments returns the documents that represent the enumeration output. Each address
+// and autonomous system is returned once, the first time it is seen in the set provided.
+func ElasticDocuments(out *requests.Output, uuid string, seen *stringset.Set) []*ElasticDocument {
+	now := time.Now().UTC()
+	first, last := out.FirstSeen, out.LastSeen
+	if first.IsZero() {
+		first = now
+	}
+	if last.IsZero() {
+		last = now
+	}
+
+	fqdn := &ElasticDocument{
+		ID:        ElasticTypeFQDN + ":" + out.Name,
+		Type:      ElasticTypeFQDN,
+		Name:      out.Name,
+		Domain:    out.Domain,
+		Tag:       out.Tag,
+		Sources:   out.Sources,
+		FirstSeen: first,
+		LastSeen:  last,
+		Timestamp: now,
+		UUID:      uuid,
+	}
+	docs := []*ElasticDocument{fqdn}
+
+	for _, a := range out.Addresses {
+		if a.Address == nil {
+			continue
+		}
+
+		addr := a.Address.String()
+		fqdn.Addresses = append(fqdn.Addresses, addr)
+		if id := ElasticTypeAddr + ":" + addr; !seen.Has(id) {
+			seen.Insert(id)
+			docs = append(docs, &ElasticDocument{
+				ID:          id,
+				Type:        ElasticTypeAddr,
+				Address:     addr,
+				CIDR:        a.CIDRStr,
+				ASN:         a.ASN,
+				Description: a.Description,
+				Tag:         out.Tag,
+				Sources:     out.Sources,
+				FirstSeen:   first,
+				LastSeen:    last,
+				Timestamp:   now,
+				UUID:        uuid,
+			})
+		}
+
+		if a.ASN == 0 {
+			continue
+		}
+		if id := ElasticTypeASN + ":" + strconv.Itoa(a.ASN); !seen.Has(id) {
+			seen.Insert(id)
+			docs = append(docs, &ElasticDocument{
+				ID:          id,
+				Type:        ElasticTypeASN,
+				ASN:         a.ASN,
+				Description: a.Description,
+				Tag:         out.Tag,
+				Sources:     out.Sources,
+				FirstSeen:   first,
+				LastSeen:    last,
+				Timestamp:   now,
+				UUID:        uuid,
+			})
+		}
+	}
+	return docs
+}
+
+// ElasticSink bulk indexes the findings into an Elasticsearch or OpenSearch cluster. The
+// documents are queued by Index and sent in batches from a separate goroutine, so a slow
+// or unavailable cluster does not hold up the enumeration.
+type ElasticSink struct {
+	cfg      *config.ElasticsearchConfig
+	uuid     string
+	log      *log.Logger
+	client   *http.Client
+	queue    queue.Queue
+	seen     *stringset.Set
+	backoff  time.Duration
+	done     chan struct{}
+	finished chan struct{}
+	mapped   bool
+	down     bool
+	indexed  int64
+	failed   int64
+}
+
+// NewElasticSink returns an ElasticSink that indexes the findings of the enumeration
+// identified by the UUID. Errors returned by the cluster are written to the logger.
+func NewElasticSink(cfg *config.ElasticsearchConfig, uuid string, logger *log.Logger) *ElasticSink {
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	s := &ElasticSink{
+		cfg:      cfg,
+		uuid:     uuid,
+		log:      logger,
+		client:   amasshttp.DefaultClient,
+		queue:    queue.NewQueue(),
+		seen:     stringset.New(),
+		backoff:  elasticInitialBackoff,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go s.processDocuments()
+	return s
+}
+
+// Index queues the documents representing the output for the next bulk request.
+func (s *ElasticSink) Index(out *requests.Output) {
+	for _, doc := range ElasticDocuments(out, s.uuid, s.seen) {
+		s.queue.Append(doc)
+	}
+}
+
+// Close sends the documents remaining in the queue and returns an error when
+// some of the documents could not be indexed.
+func (s *ElasticSink) Close() error {
+	close(s.done)
+	<-s.finished
+	s.seen.Close()
+
+	if failed := atomic.LoadInt64(&s.failed); failed > 0 {
+		return fmt.Errorf("%d of %d documents could not be indexed into %s", failed,
+			failed+atomic.LoadInt64(&s.indexed), s.cfg.Index)
+	}
+	return nil
+}
+
+// Indexed returns the number of documents accepted by the cluster.
+func (s *ElasticSink) Indexed() int {
+	return int(atomic.LoadInt64(&s.indexed))
+}
+
+func (s *ElasticSink) processDocuments() {
+	defer close(s.finished)
+
+	t := time.NewTicker(elasticFlushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			for !s.queue.Empty() {
+				s.flush()
+			}
+			return
+		case <-t.C:
+			for !s.queue.Empty() {
+				s.flush()
+			}
+		case <-s.queue.Signal():
+			for s.queue.Len() >= s.cfg.BatchSize {
+				s.flush()
+			}
+		}
+	}
+}
+
+// flush sends the next batch of documents in a single bulk request.
+func (s *ElasticSink) flush() {
+	var num int64
+	var body bytes.Buffer
+
+	for num < int64(s.cfg.BatchSize) {
+		e, ok := s.queue.Next()
+		if !ok {
+			break
+		}
+		doc, ok := e.(*ElasticDocument)
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			continue
+		}
+		action, _ := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": s.cfg.Index, "_id": doc.ID},
+		})
+
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(data)
+		body.WriteByte('\n')
+		num++
+	}
+	if num == 0 {
+		return
+	}
+
+	if err := s.ensureIndex(); err != nil {
+		s.log.Printf("Elasticsearch: %v", err)
+		atomic.AddInt64(&s.failed, num)
+		return
+	}
+
+	status, resp, err := s.request(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+	if err == nil && status >= 300 {
+		err = fmt.Errorf("the bulk request returned status %d", status)
+	}
+	if err != nil {
+		s.log.Printf("Elasticsearch: %v", err)
+		atomic.AddInt64(&s.failed, num)
+		return
+	}
+
+	failed := bulkFailures(resp, s.log)
+	atomic.AddInt64(&s.failed, failed)
+	atomic.AddInt64(&s.indexed, num-failed)
+}
+
+// bulkFailures returns the number of documents rejected within a bulk response and logs the first reason.
+func bulkFailures(resp []byte, logger *log.Logger) int64 {
+	var br struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &br); err != nil || !br.Errors {
+		return 0
+	}
+
+	var failed int64
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Status < 300 {
+				continue
+			}
+			if failed == 0 {
+				logger.Printf("Elasticsearch: document rejected: %s: %s", result.Error.Type, result.Error.Reason)
+			}
+			failed++
+		}
+	}
+	return failed
+}
+
+// ensureIndex creates the index with the expected mapping the first time documents are sent.
+func (s *ElasticSink) ensureIndex() error {
+	if s.mapped {
+		return nil
+	}
+
+	status, _, err := s.request(http.MethodHead, "/"+s.cfg.Index, "", nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		status, resp, err := s.request(http.MethodPut, "/"+s.cfg.Index, "application/json", elasticMapping)
+		if err != nil {
+			return err
+		}
+		// Another process may have created the index in the meantime
+		if status >= 300 && !strings.Contains(string(resp), "resource_already_exists_exception") {
+			return fmt.Errorf("failed to create the %s index: status %d: %s", s.cfg.Index, status, string(resp))
+		}
+	} else if status >= 300 {
+		return fmt.Errorf("failed to check for the %s index: status %d", s.cfg.Index, status)
+	}
+
+	s.mapped = true
+	return nil
+}
+
+// request sends the request to the cluster, and retries with an exponential backoff when the
+// connection fails or the cluster is overloaded. Once the retries have been exhausted, later
+// requests are attempted once until the cluster responds again.
+func (s *ElasticSink) request(method, path, ctype string, body []byte) (int, []byte, error) {
+	attempts := elasticMaxAttempts
+	if s.down {
+		attempts = 1
+	}
+
+	var err error
+	delay := s.backoff
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		var status int
+		var resp []byte
+		var header http.Header
+		status, header, resp, err = s.send(method, path, ctype, body)
+		if err == nil && status != http.StatusTooManyRequests && status < 500 {
+			s.down = false
+			return status, resp, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s %s: status %d", method, path, status)
+			if d, ok := amasshttp.RetryAfter(header); ok && d > delay {
+				delay = d
+			}
+		}
+	}
+
+	s.down = true
+	return 0, nil, err
+}
+
+func (s *ElasticSink) send(method, path, ctype string, body []byte) (int, http.Header, []byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, s.cfg.URL+path, r)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	req.Header.Set("User-Agent", amasshttp.UserAgent)
+	if s.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.cfg.APIKey)
+	} else if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, data, err
+}
@@ -0,0 +1,128 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
+)
+
+func TestElasticDocuments(t *testing.T) {
+	seen := stringset.New()
+	defer seen.Close()
+
+	out := &requests.Output{
+		Name:   "www.owasp.org",
+		Domain: "owasp.org",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("104.22.27.77"), CIDRStr: "104.22.16.0/20", ASN: 13335},
+			{Address: net.ParseIP("104.22.26.77"), CIDRStr: "104.22.16.0/20", ASN: 13335},
+		},
+		Tag:     requests.CERT,
+		Sources: []string{"crtsh"},
+	}
+
+	docs := ElasticDocuments(out, "uuid", seen)
+	if len(docs) != 4 {
+		t.Fatalf("Expected one name, two address and one ASN documents, got %d", len(docs))
+	}
+	if docs[0].Type != ElasticTypeFQDN || len(docs[0].Addresses) != 2 || docs[0].FirstSeen.IsZero() {
+		t.Errorf("Unexpected name document: %+v", docs[0])
+	}
+
+	out.Name = "owasp.org"
+	if docs = ElasticDocuments(out, "uuid", seen); len(docs) != 1 {
+		t.Errorf("The addresses and ASN were indexed again: %d documents", len(docs))
+	}
+}
+
+func TestElasticSink(t *testing.T) {
+	var lock sync.Mutex
+	var created, failures, docs int
+	var auth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodHead:
+			if created == 0 {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/findings":
+			body, _ := ioutil.ReadAll(r.Body)
+			if bytes.Contains(body, []byte(`"ip_range"`)) {
+				created++
+			}
+		case r.URL.Path == "/_bulk":
+			auth = r.Header.Get("Authorization")
+			// The first bulk request fails to exercise the retries
+			if failures == 0 {
+				failures++
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				if strings.Contains(scanner.Text(), `"_index":"findings"`) {
+					docs++
+				}
+			}
+			_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	sink := NewElasticSink(&config.ElasticsearchConfig{
+		URL:       srv.URL,
+		Index:     "findings",
+		APIKey:    "key",
+		BatchSize: 2,
+	}, "uuid", nil)
+	sink.backoff = time.Millisecond
+
+	for _, name := range []string{"www.owasp.org", "mail.owasp.org", "owasp.org"} {
+		sink.Index(&requests.Output{Name: name, Domain: "owasp.org", Sources: []string{"DNS"}})
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if created != 1 {
+		t.Errorf("The index was created %d times", created)
+	}
+	if docs != 3 || sink.Indexed() != 3 {
+		t.Errorf("%d documents were received and %d reported as indexed", docs, sink.Indexed())
+	}
+	if auth != "ApiKey key" {
+		t.Errorf("The API key was not provided: %q", auth)
+	}
+}
+
+func TestElasticBulkFailures(t *testing.T) {
+	resp := []byte(`{"errors":true,"items":[{"index":{"status":201}},` +
+		`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad ip"}}}]}`)
+
+	if n := bulkFailures(resp, log.New(ioutil.Discard, "", 0)); n != 1 {
+		t.Errorf("Counted %d rejected documents", n)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"strings"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/dns"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// EncodeOutput writes the DNS names of the output in the encoding selected by the output_encoding
+// option. With the unicode encoding the punycode labels are decoded, and otherwise the names are
+// written in their ASCII form, as they appear in DNS. Only the displayed names are affected, so
+// the output must have passed the scope checks before it is encoded.
+func EncodeOutput(out *requests.Output, encoding string) {
+	conv := dns.ToASCII
+	if strings.EqualFold(encoding, config.OutputEncodingUnicode) {
+		conv = dns.ToUnicode
+	}
+
+	out.Name = conv(out.Name)
+	out.Domain = conv(out.Domain)
+	for i, ns := range out.NameServers {
+		out.NameServers[i] = conv(ns)
+	}
+	for i, seed := range out.Seeds {
+		out.Seeds[i] = conv(seed)
+	}
+}
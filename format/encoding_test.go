@@ -0,0 +1,53 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestEncodeOutput(t *testing.T) {
+	tests := []struct {
+		encoding string
+		name     string
+		domain   string
+		ns       string
+	}{
+		{"", "www.xn--mnchen-3ya.de", "xn--mnchen-3ya.de", "ns1.xn--bcher-kva.example"},
+		{config.OutputEncodingASCII, "www.xn--mnchen-3ya.de", "xn--mnchen-3ya.de", "ns1.xn--bcher-kva.example"},
+		{config.OutputEncodingUnicode, "www.münchen.de", "münchen.de", "ns1.bücher.example"},
+	}
+
+	for _, test := range tests {
+		// The names arrive in both forms, and are written in the one selected
+		for _, name := range []string{"www.xn--mnchen-3ya.de", "www.münchen.de"} {
+			out := &requests.Output{
+				Name:        name,
+				Domain:      "xn--mnchen-3ya.de",
+				NameServers: []string{"ns1.xn--bcher-kva.example"},
+				Seeds:       []string{"xn--mnchen-3ya.de"},
+			}
+
+			EncodeOutput(out, test.encoding)
+			if out.Name != test.name || out.Domain != test.domain {
+				t.Errorf("Encoding %q: %s was written as %s in %s", test.encoding, name, out.Name, out.Domain)
+			}
+			if fmt.Sprint(out.NameServers) != "["+test.ns+"]" || fmt.Sprint(out.Seeds) != "["+test.domain+"]" {
+				t.Errorf("Encoding %q: unexpected nameservers %v and seeds %v", test.encoding, out.NameServers, out.Seeds)
+			}
+		}
+	}
+
+	// The names without internationalized labels are left alone
+	out := &requests.Output{Name: "www.example.com", Domain: "example.com"}
+	EncodeOutput(out, config.OutputEncodingUnicode)
+	if out.Name != "www.example.com" || out.Domain != "example.com" {
+		t.Errorf("The ASCII name was changed to %s in %s", out.Name, out.Domain)
+	}
+}
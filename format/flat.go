@@ -0,0 +1,97 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// The JSON output formats selected with the -json-format flag.
+const (
+	JSONNative = "native"
+	JSONFlat   = "flat"
+)
+
+// FlatOutput is the flattened JSON form of a discovered name. The addresses, netblocks and
+// autonomous systems are inlined as lists of values, so every field holds a value or a list
+// of values and no nested objects need to be handled by the tools reading the output.
+type FlatOutput struct {
+	Name         string    `json:"name"`
+	Domain       string    `json:"domain"`
+	Addresses    []string  `json:"addresses"`
+	CIDRs        []string  `json:"cidrs"`
+	ASNs         []int     `json:"asns"`
+	Descriptions []string  `json:"as_descriptions"`
+	Countries    []string  `json:"countries,omitempty"`
+	NameServers  []string  `json:"nameservers,omitempty"`
+	Seeds        []string  `json:"seeds,omitempty"`
+	Tag          string    `json:"tag"`
+	Sources      []string  `json:"sources"`
+	SourceURLs   []string  `json:"source_urls,omitempty"`
+	Orgs         []string  `json:"orgs,omitempty"`
+	Class        string    `json:"class,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Passive      bool      `json:"passive"`
+}
+
+// NewFlatOutput returns the flattened form of the output. The netblocks, ASNs and countries are
+// listed once each, in the order of the addresses they were found with, and the description
+// of each AS is at the same position as its number.
+func NewFlatOutput(o *requests.Output) *FlatOutput {
+	flat := &FlatOutput{
+		Name:        o.Name,
+		Domain:      o.Domain,
+		Addresses:   []string{},
+		CIDRs:       []string{},
+		ASNs:        []int{},
+		Tag:         o.Tag,
+		Sources:     o.Sources,
+		SourceURLs:  o.SourceURLs,
+		Orgs:        o.Orgs,
+		Class:       o.Class,
+		NameServers: o.NameServers,
+		Seeds:       o.Seeds,
+		FirstSeen:   o.FirstSeen,
+		LastSeen:    o.LastSeen,
+		Passive:     o.Passive,
+	}
+	if flat.Sources == nil {
+		flat.Sources = []string{}
+	}
+
+	asns := make(map[int]struct{})
+	cidrs := make(map[string]struct{})
+	countries := make(map[string]struct{})
+	for _, addr := range o.Addresses {
+		if addr.Address != nil {
+			flat.Addresses = append(flat.Addresses, addr.Address.String())
+		}
+		flat.CIDRs = appendUnique(flat.CIDRs, addr.CIDRStr, cidrs)
+		if _, found := asns[addr.ASN]; !found && addr.ASN != 0 {
+			asns[addr.ASN] = struct{}{}
+			flat.ASNs = append(flat.ASNs, addr.ASN)
+			flat.Descriptions = append(flat.Descriptions, addr.Description)
+		}
+		if addr.Geo != nil {
+			flat.Countries = appendUnique(flat.Countries, addr.Geo.CountryCode, countries)
+		}
+	}
+	if flat.Descriptions == nil {
+		flat.Descriptions = []string{}
+	}
+	return flat
+}
+
+func appendUnique(list []string, val string, seen map[string]struct{}) []string {
+	if _, found := seen[val]; found || val == "" {
+		return list
+	}
+
+	seen[val] = struct{}{}
+	return append(list, val)
+}
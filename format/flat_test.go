@@ -0,0 +1,73 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestNewFlatOutput(t *testing.T) {
+	seen := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	out := &requests.Output{
+		Name:   "www.owasp.org",
+		Domain: "owasp.org",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("104.16.1.1"), CIDRStr: "104.16.0.0/12", ASN: 13335, Description: "CLOUDFLARENET",
+				Geo: &requests.GeoInfo{CountryCode: "US"}},
+			{Address: net.ParseIP("104.16.2.1"), CIDRStr: "104.16.0.0/12", ASN: 13335, Description: "CLOUDFLARENET"},
+			{Address: net.ParseIP("2606:4700::6810:101"), CIDRStr: "2606:4700::/32", ASN: 13335, Description: "CLOUDFLARENET"},
+		},
+		Tag:       requests.API,
+		Sources:   []string{"Umbrella", "DNS"},
+		FirstSeen: seen,
+		LastSeen:  seen,
+	}
+
+	flat := NewFlatOutput(out)
+	if len(flat.Addresses) != 3 || flat.Addresses[2] != "2606:4700::6810:101" {
+		t.Errorf("The addresses were not inlined: %v", flat.Addresses)
+	}
+	if len(flat.CIDRs) != 2 || len(flat.ASNs) != 1 || len(flat.Descriptions) != 1 || flat.Descriptions[0] != "CLOUDFLARENET" {
+		t.Errorf("The netblocks and ASNs were not listed once each: %v, %v, %v", flat.CIDRs, flat.ASNs, flat.Descriptions)
+	}
+	if len(flat.Countries) != 1 || flat.Countries[0] != "US" {
+		t.Errorf("The countries were not inlined: %v", flat.Countries)
+	}
+	if flat.Tag != requests.API || len(flat.Sources) != 2 || !flat.FirstSeen.Equal(seen) || !flat.LastSeen.Equal(seen) {
+		t.Errorf("The tag, sources or timestamps were not kept: %+v", flat)
+	}
+
+	data, err := json.Marshal(flat)
+	if err != nil {
+		t.Fatalf("Failed to encode the flat output: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to decode the flat output: %v", err)
+	}
+	for key, val := range fields {
+		if _, nested := val.(map[string]interface{}); nested {
+			t.Errorf("The %s field holds a nested object", key)
+		}
+		if list, ok := val.([]interface{}); ok {
+			for _, item := range list {
+				if _, nested := item.(map[string]interface{}); nested {
+					t.Errorf("The %s field holds a list of objects", key)
+				}
+			}
+		}
+	}
+
+	// A passive name without addresses still provides empty lists
+	flat = NewFlatOutput(&requests.Output{Name: "dev.owasp.org", Domain: "owasp.org", Passive: true})
+	if flat.Addresses == nil || flat.CIDRs == nil || flat.ASNs == nil || flat.Descriptions == nil || flat.Sources == nil || !flat.Passive {
+		t.Errorf("The lists of the flat output were not initialized: %+v", flat)
+	}
+}
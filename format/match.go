@@ -0,0 +1,54 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"regexp"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// OutputMatcher selects the findings that are written as output, using the patterns provided
+// with the -match and -filter-out flags. The discovered names are tested against the patterns,
+// along with their addresses when Addresses is set.
+type OutputMatcher struct {
+	Match     ParsePatterns
+	FilterOut ParsePatterns
+	Addresses bool
+}
+
+// Empty returns true when no patterns were provided, so all the output is written.
+func (m *OutputMatcher) Empty() bool {
+	return m == nil || (len(m.Match) == 0 && len(m.FilterOut) == 0)
+}
+
+// Allow returns true when the output matches one of the -match patterns, or none were provided,
+// and does not match any of the -filter-out patterns.
+func (m *OutputMatcher) Allow(out *requests.Output) bool {
+	if m.Empty() {
+		return true
+	}
+	if len(m.Match) > 0 && !m.matches(m.Match, out) {
+		return false
+	}
+	return !m.matches(m.FilterOut, out)
+}
+
+func (m *OutputMatcher) matches(patterns []*regexp.Regexp, out *requests.Output) bool {
+	for _, re := range patterns {
+		if re.MatchString(out.Name) {
+			return true
+		}
+		if !m.Addresses {
+			continue
+		}
+		for _, a := range out.Addresses {
+			if a.Address != nil && re.MatchString(a.Address.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestParsePatterns(t *testing.T) {
+	var p ParsePatterns
+
+	for _, expr := range []string{"vpn", `^10\.1\.`} {
+		if err := p.Set(expr); err != nil {
+			t.Errorf("Failed to parse the pattern %s: %v", expr, err)
+		}
+	}
+	if len(p) != 2 || p.String() != `vpn ^10\.1\.` {
+		t.Errorf("The patterns were not kept: %s", p.String())
+	}
+	for _, bad := range []string{"", "vpn(", "a{2,1}"} {
+		if err := p.Set(bad); err == nil {
+			t.Errorf("The invalid pattern %q was accepted", bad)
+		}
+	}
+}
+
+func TestOutputMatcher(t *testing.T) {
+	vpn := &requests.Output{Name: "vpn.owasp.org"}
+	www := &requests.Output{
+		Name:      "www.owasp.org",
+		Addresses: []requests.AddressInfo{{Address: net.ParseIP("10.1.2.3")}},
+	}
+	dev := &requests.Output{Name: "vpn.dev.owasp.org"}
+
+	var m *OutputMatcher
+	if !m.Empty() || !m.Allow(vpn) {
+		t.Errorf("The output was filtered without patterns")
+	}
+
+	m = new(OutputMatcher)
+	_ = m.Match.Set("vpn")
+	_ = m.Match.Set(`^10\.1\.`)
+	_ = m.FilterOut.Set(`\.dev\.`)
+	for _, test := range []struct {
+		out   *requests.Output
+		addrs bool
+		want  bool
+	}{
+		{vpn, false, true},
+		{www, false, false},
+		{www, true, true},
+		{dev, false, false},
+		{dev, true, false},
+	} {
+		m.Addresses = test.addrs
+		if got := m.Allow(test.out); got != test.want {
+			t.Errorf("Allow(%s) with addresses %t returned %t, expected %t", test.out.Name, test.addrs, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/net/bolt"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/caffix/queue"
+)
+
+const (
+	// The maximum time findings wait in the queue before a partial batch is written
+	neo4jFlushInterval = 5 * time.Second
+	neo4jDialTimeout   = 10 * time.Second
+	// The time waited before connecting again after the server could not be reached
+	neo4jRetryInterval = 30 * time.Second
+)
+
+// The constraints and indexes created the first time findings are written. The constraints
+// keep a single node for each asset and back the lookups performed by MERGE.
+var neo4jSchema = []string{
+	"CREATE CONSTRAINT amass_fqdn_name IF NOT EXISTS FOR (n:FQDN) REQUIRE n.name IS UNIQUE",
+	"CREATE CONSTRAINT amass_ipaddress_address IF NOT EXISTS FOR (n:IPAddress) REQUIRE n.address IS UNIQUE",
+	"CREATE CONSTRAINT amass_netblock_cidr IF NOT EXISTS FOR (n:Netblock) REQUIRE n.cidr IS UNIQUE",
+	"CREATE CONSTRAINT amass_as_asn IF NOT EXISTS FOR (n:AS) REQUIRE n.asn IS UNIQUE",
+	"CREATE CONSTRAINT amass_source_name IF NOT EXISTS FOR (n:Source) REQUIRE n.name IS UNIQUE",
+	"CREATE INDEX amass_fqdn_domain IF NOT EXISTS FOR (n:FQDN) ON (n.domain)",
+}
+
+// The queries that write a batch of findings, in order, each taking the rows of one kind.
+var neo4jQueries = []struct {
+	rows  string
+	query string
+}{
+	{"names", "UNWIND $rows AS row MERGE (f:FQDN {name: row.name}) " +
+		"ON CREATE SET f.first_seen = row.first_seen " +
+		"SET f.domain = row.domain, f.tag = row.tag, f.last_seen = row.last_seen, f.enum_uuid = row.uuid"},
+	{"sources", "UNWIND $rows AS row MATCH (f:FQDN {name: row.name}) " +
+		"MERGE (s:Source {name: row.source}) MERGE (s)-[r:DISCOVERED]->(f) SET r.enum_uuid = row.uuid"},
+	{"addrs", "UNWIND $rows AS row MATCH (f:FQDN {name: row.name}) " +
+		"MERGE (a:IPAddress {address: row.address}) SET a.type = row.type MERGE (f)-[:RESOLVES_TO]->(a)"},
+	{"netblocks", "UNWIND $rows AS row MATCH (a:IPAddress {address: row.address}) " +
+		"MERGE (n:Netblock {cidr: row.cidr}) MERGE (a)-[:CONTAINED_BY]->(n)"},
+	{"asns", "UNWIND $rows AS row MATCH (n:Netblock {cidr: row.cidr}) " +
+		"MERGE (as:AS {asn: row.asn}) SET as.description = row.description MERGE (n)-[:ANNOUNCED_BY]->(as)"},
+}
+
+// neo4jRows returns the parameters of the queries that write the findings, keyed by the kind
+// of rows. The graph follows the path name -> address -> netblock -> ASN, and each name is
+// linked to the data sources that discovered it.
+func neo4jRows(outputs []*requests.Output, uuid string) map[string][]map[string]interface{} {
+	rows := make(map[string][]map[string]interface{})
+	add := func(kind string, row map[string]interface{}) {
+		rows[kind] = append(rows[kind], row)
+	}
+
+	now := time.Now().UTC()
+	for _, out := range outputs {
+		first, last := out.FirstSeen, out.LastSeen
+		if first.IsZero() {
+			first = now
+		}
+		if last.IsZero() {
+			last = now
+		}
+
+		add("names", map[string]interface{}{
+			"name":       out.Name,
+			"domain":     out.Domain,
+			"tag":        out.Tag,
+			"first_seen": first.Format(time.RFC3339),
+			"last_seen":  last.Format(time.RFC3339),
+			"uuid":       uuid,
+		})
+		for _, src := range out.Sources {
+			add("sources", map[string]interface{}{"name": out.Name, "source": src, "uuid": uuid})
+		}
+
+		for _, a := range out.Addresses {
+			if a.Address == nil {
+				continue
+			}
+
+			addr := a.Address.String()
+			typ := "IPv4"
+			if a.Address.To4() == nil {
+				typ = "IPv6"
+			}
+			add("addrs", map[string]interface{}{"name": out.Name, "address": addr, "type": typ})
+
+			if a.CIDRStr == "" {
+				continue
+			}
+			add("netblocks", map[string]interface{}{"address": addr, "cidr": a.CIDRStr})
+
+			if a.ASN == 0 {
+				continue
+			}
+			add("asns", map[string]interface{}{"cidr": a.CIDRStr, "asn": int64(a.ASN), "description": a.Description})
+		}
+	}
+	return rows
+}
+
+// Neo4jSink writes the findings and their relationships into a Neo4j database over the Bolt
+// protocol. The findings are queued by Send and written in batches, each within a single
+// transaction, from a separate goroutine, so a slow or unavailable server does not hold up
+// the enumeration.
+type Neo4jSink struct {
+	cfg      *config.Neo4jConfig
+	uuid     string
+	log      *log.Logger
+	queue    queue.Queue
+	conn     *bolt.Conn
+	done     chan struct{}
+	finished chan struct{}
+	retry    time.Time
+	down     bool
+	schema   bool
+	written  int64
+	failed   int64
+}
+
+// NewNeo4jSink returns a Neo4jSink that writes the findings of the enumeration identified by
+// the UUID. Errors returned by the server are written to the logger.
+func NewNeo4jSink(cfg *config.Neo4jConfig, uuid string, logger *log.Logger) *Neo4jSink {
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	s := &Neo4jSink{
+		cfg:      cfg,
+		uuid:     uuid,
+		log:      logger,
+		queue:    queue.NewQueue(),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go s.processOutput()
+	return s
+}
+
+// Send queues the output for the next batch written to the database.
+func (s *Neo4jSink) Send(out *requests.Output) {
+	s.queue.Append(out)
+}
+
+// Close writes the findings remaining in the queue and returns an error when
+// some of the findings could not be written.
+func (s *Neo4jSink) Close() error {
+	close(s.done)
+	<-s.finished
+
+	if failed := atomic.LoadInt64(&s.failed); failed > 0 {
+		return fmt.Errorf("%d of %d findings could not be written to Neo4j at %s", failed,
+			failed+atomic.LoadInt64(&s.written), s.cfg.URI)
+	}
+	return nil
+}
+
+// Written returns the number of findings written to the database.
+func (s *Neo4jSink) Written() int {
+	return int(atomic.LoadInt64(&s.written))
+}
+
+func (s *Neo4jSink) processOutput() {
+	defer close(s.finished)
+	defer func() {
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+	}()
+
+	t := time.NewTicker(neo4jFlushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			for !s.queue.Empty() {
+				s.flush()
+			}
+			return
+		case <-t.C:
+			for !s.queue.Empty() {
+				s.flush()
+			}
+		case <-s.queue.Signal():
+			for s.queue.Len() >= s.cfg.BatchSize {
+				s.flush()
+			}
+		}
+	}
+}
+
+// flush writes the next batch of findings in a single transaction.
+func (s *Neo4jSink) flush() {
+	var outputs []*requests.Output
+
+	for len(outputs) < s.cfg.BatchSize {
+		e, ok := s.queue.Next()
+		if !ok {
+			break
+		}
+		if out, ok := e.(*requests.Output); ok {
+			outputs = append(outputs, out)
+		}
+	}
+	if len(outputs) == 0 {
+		return
+	}
+
+	if err := s.write(outputs); err != nil {
+		atomic.AddInt64(&s.failed, int64(len(outputs)))
+		return
+	}
+	atomic.AddInt64(&s.written, int64(len(outputs)))
+}
+
+func (s *Neo4jSink) write(outputs []*requests.Output) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	var queries []string
+	var params []map[string]interface{}
+	rows := neo4jRows(outputs, s.uuid)
+	for _, q := range neo4jQueries {
+		if r := rows[q.rows]; len(r) > 0 {
+			queries = append(queries, q.query)
+			params = append(params, map[string]interface{}{"rows": r})
+		}
+	}
+
+	if err := s.conn.Transaction(queries, params); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// connect establishes the session and creates the schema, unless the server was found
+// unreachable within the retry interval.
+func (s *Neo4jSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	if s.down && time.Now().Before(s.retry) {
+		return fmt.Errorf("the Neo4j server at %s is unreachable", s.cfg.URI)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), neo4jDialTimeout)
+	defer cancel()
+
+	conn, err := bolt.Dial(ctx, s.cfg.URI, s.cfg.Username, s.cfg.Password, s.cfg.Database)
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+	s.conn = conn
+
+	if !s.schema {
+		for _, stmt := range neo4jSchema {
+			if err := s.conn.Run(stmt, nil); err != nil {
+				s.fail(fmt.Errorf("failed to create the schema: %v", err))
+				return err
+			}
+		}
+		s.schema = true
+	}
+
+	if s.down {
+		s.log.Printf("Neo4j: reconnected to %s", s.cfg.URI)
+		s.down = false
+	}
+	return nil
+}
+
+// fail logs the first failure while the server is unreachable and schedules the next attempt.
+// The failures reported by the server for a query leave the session usable.
+func (s *Neo4jSink) fail(err error) {
+	if _, ok := err.(*bolt.Failure); ok {
+		s.log.Printf("Neo4j: %v", err)
+		return
+	}
+	if !s.down {
+		s.log.Printf("Neo4j: %s is unreachable, findings will not be written until it responds: %v", s.cfg.URI, err)
+	}
+
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	s.down = true
+	s.retry = time.Now().Add(neo4jRetryInterval)
+}
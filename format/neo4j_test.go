@@ -0,0 +1,156 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func neo4jTestOutput(name string) *requests.Output {
+	return &requests.Output{
+		Name:   name,
+		Domain: "owasp.org",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("104.22.27.77"), CIDRStr: "104.22.16.0/20", ASN: 13335, Description: "CLOUDFLARENET"},
+			{Address: net.ParseIP("2606:4700:10::6816:1b4d")},
+		},
+		Tag:     requests.CERT,
+		Sources: []string{"crtsh", "CertSpotter"},
+	}
+}
+
+func TestNeo4jRows(t *testing.T) {
+	rows := neo4jRows([]*requests.Output{neo4jTestOutput("www.owasp.org")}, "uuid")
+
+	expected := map[string]int{"names": 1, "sources": 2, "addrs": 2, "netblocks": 1, "asns": 1}
+	for kind, num := range expected {
+		if len(rows[kind]) != num {
+			t.Errorf("Expected %d %s rows, got %d", num, kind, len(rows[kind]))
+		}
+	}
+
+	if name := rows["names"][0]; name["uuid"] != "uuid" || name["first_seen"] == "" {
+		t.Errorf("Unexpected name row: %v", name)
+	}
+	if addr := rows["addrs"][1]; addr["type"] != "IPv6" {
+		t.Errorf("Unexpected address row: %v", addr)
+	}
+	if asn := rows["asns"][0]; asn["asn"] != int64(13335) || asn["cidr"] != "104.22.16.0/20" {
+		t.Errorf("Unexpected ASN row: %v", asn)
+	}
+}
+
+// neo4jTestServer accepts a single Bolt session, responds with success to each
+// message and returns the messages received when the session ends.
+func neo4jTestServer(t *testing.T) (string, chan [][]byte) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	received := make(chan [][]byte, 1)
+	go func() {
+		defer ln.Close()
+
+		var msgs [][]byte
+		defer func() { received <- msgs }()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		handshake := make([]byte, 20)
+		if _, err := io.ReadFull(conn, handshake); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{0, 0, 4, 4})
+
+		for {
+			var msg bytes.Buffer
+			for {
+				var size uint16
+				if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+					return
+				}
+				if size == 0 {
+					break
+				}
+				if _, err := io.CopyN(&msg, conn, int64(size)); err != nil {
+					return
+				}
+			}
+
+			msgs = append(msgs, msg.Bytes())
+			// A SUCCESS message holding an empty map
+			_, _ = conn.Write([]byte{0, 3, 0xB1, 0x70, 0xA0, 0, 0})
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestNeo4jSink(t *testing.T) {
+	addr, received := neo4jTestServer(t)
+
+	cfg := &config.Neo4jConfig{URI: "bolt://" + addr, Username: "neo4j", Password: "secret", BatchSize: 2}
+	sink := NewNeo4jSink(cfg, "uuid", nil)
+	for _, name := range []string{"www.owasp.org", "owasp.org", "api.owasp.org"} {
+		sink.Send(neo4jTestOutput(name))
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+	if sink.Written() != 3 {
+		t.Errorf("Expected 3 names written, got %d", sink.Written())
+	}
+
+	var schema, merges, commits int
+	for _, msg := range <-received {
+		switch {
+		case len(msg) < 2:
+		case msg[1] == 0x12:
+			commits++
+		case bytes.Contains(msg, []byte("CREATE CONSTRAINT")) || bytes.Contains(msg, []byte("CREATE INDEX")):
+			schema++
+		case bytes.Contains(msg, []byte("MERGE (f:FQDN")):
+			merges++
+		}
+	}
+
+	if schema != len(neo4jSchema) {
+		t.Errorf("Expected the %d schema statements, got %d", len(neo4jSchema), schema)
+	}
+	// The three names are written in two batches, following the schema statements
+	if merges != 2 || commits != len(neo4jSchema)+2 {
+		t.Errorf("Expected two batches, got %d name queries and %d transactions", merges, commits)
+	}
+}
+
+func TestNeo4jSinkUnreachable(t *testing.T) {
+	// Find a port that nothing is listening on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := &config.Neo4jConfig{URI: "bolt://" + addr, BatchSize: 1}
+	sink := NewNeo4jSink(cfg, "uuid", nil)
+	for i := 0; i < 5; i++ {
+		sink.Send(neo4jTestOutput("www.owasp.org"))
+	}
+	if err := sink.Close(); err == nil || sink.Written() != 0 {
+		t.Errorf("The failures to reach the server were not reported")
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aokimio/Amass/v3/config"
 	amassnet "github.com/aokimio/Amass/v3/net"
 )
 
@@ -28,6 +31,25 @@ type ParseCIDRs []*net.IPNet
 // ParseASNs implements the flag.Value interface.
 type ParseASNs []int
 
+// ParseTimeout implements the flag.Value interface. A number without a unit is a number of minutes.
+type ParseTimeout time.Duration
+
+// ParseDumpFormat implements the flag.Value interface. The flag can be provided without a
+// value to select the text format, or with the value "text" or "json".
+type ParseDumpFormat string
+
+// ParseJSONFormat implements the flag.Value interface. The value selects the native or the
+// flat JSON output format.
+type ParseJSONFormat string
+
+// ParseOutputEncoding implements the flag.Value interface. The value selects how the
+// internationalized names are written to the output: ascii or unicode.
+type ParseOutputEncoding string
+
+// ParsePatterns implements the flag.Value interface. Each use of the flag provides one
+// regular expression, which is compiled once as the flag is parsed.
+type ParsePatterns []*regexp.Regexp
+
 func (p *ParseStrings) String() string {
 	if p == nil {
 		return ""
@@ -205,3 +227,123 @@ func (p *ParseASNs) Set(s string) error {
 	}
 	return nil
 }
+
+func (p *ParseTimeout) String() string {
+	if p == nil || *p == 0 {
+		return ""
+	}
+	return time.Duration(*p).String()
+}
+
+// Set implements the flag.Value interface.
+func (p *ParseTimeout) Set(s string) error {
+	s = strings.TrimSpace(s)
+	// Plain numbers remain minutes, as accepted by earlier versions
+	if mins, err := strconv.Atoi(s); err == nil {
+		if mins < 0 {
+			return fmt.Errorf("Timeout parsing failed")
+		}
+		*p = ParseTimeout(time.Duration(mins) * time.Minute)
+		return nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return fmt.Errorf("Timeout parsing failed")
+	}
+	*p = ParseTimeout(d)
+	return nil
+}
+
+func (p *ParseDumpFormat) String() string {
+	if p == nil {
+		return ""
+	}
+	return string(*p)
+}
+
+// IsBoolFlag allows the flag to be provided without a value.
+func (p *ParseDumpFormat) IsBoolFlag() bool { return true }
+
+// Set implements the flag.Value interface.
+func (p *ParseDumpFormat) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "text":
+		*p = "text"
+	case "json":
+		*p = "json"
+	case "false":
+		*p = ""
+	default:
+		return fmt.Errorf("The format must be text or json")
+	}
+	return nil
+}
+
+func (p *ParseJSONFormat) String() string {
+	if p == nil {
+		return ""
+	}
+	return string(*p)
+}
+
+// Set implements the flag.Value interface.
+func (p *ParseJSONFormat) Set(s string) error {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case JSONNative, JSONFlat:
+		*p = ParseJSONFormat(f)
+	default:
+		return fmt.Errorf("The JSON format must be native or flat")
+	}
+	return nil
+}
+
+// Flat returns true when the flat JSON output format was selected.
+func (p *ParseJSONFormat) Flat() bool {
+	return p != nil && *p == JSONFlat
+}
+
+func (p *ParseOutputEncoding) String() string {
+	if p == nil {
+		return ""
+	}
+	return string(*p)
+}
+
+// Set implements the flag.Value interface.
+func (p *ParseOutputEncoding) Set(s string) error {
+	switch e := strings.ToLower(strings.TrimSpace(s)); e {
+	case config.OutputEncodingASCII, config.OutputEncodingUnicode:
+		*p = ParseOutputEncoding(e)
+	default:
+		return fmt.Errorf("The output encoding must be ascii or unicode")
+	}
+	return nil
+}
+
+func (p *ParsePatterns) String() string {
+	if p == nil {
+		return ""
+	}
+
+	var exprs []string
+	for _, re := range *p {
+		exprs = append(exprs, re.String())
+	}
+	return strings.Join(exprs, " ")
+}
+
+// Set implements the flag.Value interface.
+func (p *ParsePatterns) Set(s string) error {
+	if s == "" {
+		return fmt.Errorf("The pattern must not be empty")
+	}
+
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return fmt.Errorf("The pattern %q is not a valid regular expression: %v", s, err)
+	}
+
+	*p = append(*p, re)
+	return nil
+}
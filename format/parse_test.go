@@ -5,7 +5,9 @@
 package format
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"testing"
 )
 
@@ -316,6 +318,54 @@ func TestParseASNs(t *testing.T) {
 	}
 }
 
+func TestParseTimeout(t *testing.T) {
+	cases := []struct {
+		label    string
+		input    string
+		ok       bool
+		expected string
+	}{
+		{
+			label: "Empty",
+			input: "",
+		}, {
+			label:    "Minutes_Without_Unit",
+			input:    "30",
+			ok:       true,
+			expected: "30m0s",
+		}, {
+			label:    "Duration",
+			input:    "1h30m",
+			ok:       true,
+			expected: "1h30m0s",
+		}, {
+			label: "Negative",
+			input: "-5m",
+		}, {
+			label: "Invalid_Unit",
+			input: "10 minutes",
+		},
+	}
+
+	for _, c := range cases {
+		f := func(t *testing.T) {
+			var timeout ParseTimeout
+
+			if err := timeout.Set(c.input); err != nil && c.ok {
+				t.Errorf("Got: %v; Expected: <nil>", err)
+			} else if err == nil && !c.ok {
+				t.Error("Got: <nil>; Expected: some error")
+			} else if err == nil && c.ok {
+				if got := timeout.String(); got != c.expected {
+					t.Errorf("Got: %q; Expected: %q", got, c.expected)
+				}
+			}
+		}
+
+		t.Run(c.label, f)
+	}
+}
+
 func TestParseRange(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -364,3 +414,54 @@ func TestParseRange(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDumpFormat(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	var dump ParseDumpFormat
+	fs.Var(&dump, "config-dump", "")
+	if err := fs.Parse([]string{"-config-dump"}); err != nil || dump != "text" {
+		t.Errorf("Got: %q, %v; Expected: \"text\"", dump, err)
+	}
+	if err := fs.Parse([]string{"-config-dump=JSON"}); err != nil || dump != "json" {
+		t.Errorf("Got: %q, %v; Expected: \"json\"", dump, err)
+	}
+	if err := fs.Parse([]string{"-config-dump=yaml"}); err == nil {
+		t.Error("Got: <nil>; Expected: some error")
+	}
+}
+
+func TestParseJSONFormat(t *testing.T) {
+	var f ParseJSONFormat
+
+	if f.Flat() {
+		t.Errorf("The flat format was selected by default")
+	}
+	if err := f.Set(" Flat "); err != nil || !f.Flat() {
+		t.Errorf("Got: %q, %v; Expected: \"flat\"", f, err)
+	}
+	if err := f.Set("native"); err != nil || f.Flat() {
+		t.Errorf("Got: %q, %v; Expected: \"native\"", f, err)
+	}
+	if err := f.Set("csv"); err == nil {
+		t.Error("Got: <nil>; Expected: some error")
+	}
+}
+
+func TestParseOutputEncoding(t *testing.T) {
+	var e ParseOutputEncoding
+
+	if e.String() != "" {
+		t.Errorf("An encoding was selected by default: %q", e)
+	}
+	if err := e.Set(" Unicode "); err != nil || e.String() != "unicode" {
+		t.Errorf("Got: %q, %v; Expected: \"unicode\"", e, err)
+	}
+	if err := e.Set("ASCII"); err != nil || e.String() != "ascii" {
+		t.Errorf("Got: %q, %v; Expected: \"ascii\"", e, err)
+	}
+	if err := e.Set("utf8"); err == nil {
+		t.Error("Got: <nil>; Expected: some error")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/net/rpki"
 	"github.com/aokimio/Amass/v3/requests"
 	"github.com/fatih/color"
 )
@@ -56,6 +57,10 @@ var (
 type ASNSummaryData struct {
 	Name      string
 	Netblocks map[string]int
+	// The netblocks that the BGP validation did not find in the global BGP table
+	Stale map[string]bool
+	// The RPKI validation states of the netblocks that were checked
+	RPKI map[string]string
 }
 
 // UpdateSummaryData updates the summary maps using the provided requests.Output data.
@@ -72,11 +77,64 @@ func UpdateSummaryData(output *requests.Output, tags map[string]int, asns map[in
 			asns[addr.ASN] = &ASNSummaryData{
 				Name:      addr.Description,
 				Netblocks: make(map[string]int),
+				Stale:     make(map[string]bool),
+				RPKI:      make(map[string]string),
 			}
 			data = asns[addr.ASN]
 		}
 		// Increment how many IPs were in this netblock
 		data.Netblocks[addr.CIDRStr]++
+		if addr.Stale {
+			data.Stale[addr.CIDRStr] = true
+		}
+		if addr.RPKI != "" {
+			data.RPKI[addr.CIDRStr] = addr.RPKI
+		}
+	}
+}
+
+// AggregateSummaryData replaces the netblocks in the summary data with the minimal set of
+// netblocks covering them. The IP address counts are summed across the merged netblocks, and
+// a merged netblock is only marked stale when all the netblocks it covers are stale. The RPKI state
+// is kept when all the netblocks covered share the same state.
+func AggregateSummaryData(asns map[int]*ASNSummaryData) {
+	for _, data := range asns {
+		var cidrs []*net.IPNet
+		for cidr := range data.Netblocks {
+			if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+				cidrs = append(cidrs, ipnet)
+			}
+		}
+
+		netblocks := make(map[string]int)
+		stale := make(map[string]bool)
+		states := make(map[string]string)
+		for _, agg := range amassnet.AggregateCIDRs(cidrs) {
+			var count int
+			allStale := true
+			state, first := "", true
+			for cidr, ips := range data.Netblocks {
+				if _, ipnet, err := net.ParseCIDR(cidr); err == nil && agg.Contains(ipnet.IP) {
+					count += ips
+					allStale = allStale && data.Stale[cidr]
+					if first {
+						state, first = data.RPKI[cidr], false
+					} else if state != data.RPKI[cidr] {
+						state = ""
+					}
+				}
+			}
+			netblocks[agg.String()] = count
+			if allStale {
+				stale[agg.String()] = true
+			}
+			if state != "" {
+				states[agg.String()] = state
+			}
+		}
+		data.Netblocks = netblocks
+		data.Stale = stale
+		data.RPKI = states
 	}
 }
 
@@ -141,11 +199,83 @@ func FprintEnumerationSummary(out io.Writer, total int, tags map[string]int, asn
 
 			countstr = fmt.Sprintf("\t%-4s", countstr)
 			cidrstr = fmt.Sprintf("\t%-18s", cidrstr)
-			fmt.Fprintf(out, "%s%s %s\n", yellow(cidrstr), yellow(countstr), blue("Subdomain Name(s)"))
+			fmt.Fprintf(out, "%s%s %s", yellow(cidrstr), yellow(countstr), blue("Subdomain Name(s)"))
+			if data.Stale[cidr] {
+				r.Fprint(out, " (not announced)")
+			}
+			switch data.RPKI[cidr] {
+			case rpki.Invalid:
+				r.Fprint(out, " (RPKI invalid)")
+			case rpki.Valid:
+				g.Fprint(out, " (RPKI valid)")
+			case rpki.NotFound:
+				y.Fprint(out, " (RPKI not found)")
+			}
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+// FprintNamePath outputs the discovery and resolution path of a name as a tree.
+func FprintNamePath(out io.Writer, path *requests.NamePath) {
+	fmt.Fprintln(out, green(path.Name))
+	fprintNamePathBranch(out, path, "")
+}
+
+func fprintNamePathBranch(out io.Writer, path *requests.NamePath, indent string) {
+	var lines []func(prefix, indent string)
+
+	lines = append(lines, func(prefix, _ string) {
+		fmt.Fprintf(out, "%s%s%s\n", prefix, blue("sources: "), pathSources(path.Sources))
+	})
+	for _, u := range path.SourceURLs {
+		u := u
+		lines = append(lines, func(prefix, _ string) {
+			fmt.Fprintf(out, "%s%s%s\n", prefix, blue("source url: "), u)
+		})
+	}
+	if path.CNAME != nil {
+		lines = append(lines, func(prefix, indent string) {
+			fmt.Fprintf(out, "%s%s%s\n", prefix, blue("CNAME "), green(path.CNAME.Name))
+			fprintNamePathBranch(out, path.CNAME, indent)
+		})
+	}
+	for _, a := range path.Addresses {
+		a := a
+		lines = append(lines, func(prefix, indent string) {
+			fprintAddrPath(out, a, prefix, indent)
+		})
+	}
+
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			line(indent+"└── ", indent+"    ")
+		} else {
+			line(indent+"├── ", indent+"│   ")
 		}
 	}
 }
 
+func fprintAddrPath(out io.Writer, a *requests.AddrPath, prefix, indent string) {
+	fmt.Fprintf(out, "%s%s (%s)\n", prefix, yellow(a.Address), pathSources(a.Sources))
+	if a.Netblock == "" {
+		return
+	}
+
+	fmt.Fprintf(out, "%s└── %s (%s)\n", indent, yellow(a.Netblock), pathSources(a.NetblockSources))
+	if a.ASN != 0 || a.Description != "" {
+		fmt.Fprintf(out, "%s    └── %s%s %s (%s)\n", indent, blue("ASN: "),
+			yellow(strconv.Itoa(a.ASN)), green(a.Description), pathSources(a.ASNSources))
+	}
+}
+
+func pathSources(srcs []string) string {
+	if len(srcs) == 0 {
+		return "unknown"
+	}
+	return strings.Join(srcs, ", ")
+}
+
 // PrintBanner outputs the Amass banner to stderr.
 func PrintBanner() {
 	FprintBanner(color.Error)
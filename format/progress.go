@@ -0,0 +1,250 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+)
+
+// The types of the events written by the ProgressSink.
+const (
+	ProgressDiscovery = "discovery"
+	ProgressSource    = "source"
+	ProgressComplete  = "complete"
+)
+
+const (
+	// The number of events waiting to be written before new events are dropped
+	progressQueueSize    = 1024
+	progressWriteTimeout = 5 * time.Second
+	// The time waited before the consumer is looked for again after it went away
+	progressRetryInterval = 2 * time.Second
+)
+
+// ProgressEvent is a single JSON line written by the ProgressSink.
+type ProgressEvent struct {
+	Type      string   `json:"type"`
+	Time      string   `json:"time"`
+	Name      string   `json:"name,omitempty"`
+	Domain    string   `json:"domain,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Sources   []string `json:"sources,omitempty"`
+	Tag       string   `json:"tag,omitempty"`
+	// The totals of a data source, sent with the source events
+	Source   string `json:"source,omitempty"`
+	Requests int64  `json:"requests,omitempty"`
+	Errors   int64  `json:"errors,omitempty"`
+	Results  int64  `json:"results,omitempty"`
+	// The totals of the enumeration, sent with the complete event
+	Discoveries int64 `json:"discoveries,omitempty"`
+	DurationMS  int64 `json:"duration_ms,omitempty"`
+}
+
+// ProgressSink streams the progress of an enumeration as newline-delimited JSON events to a
+// Unix domain socket or a named pipe, for the frontends that display it. The events are
+// queued and written from a separate goroutine, and are dropped while the queue is full or
+// no consumer is reading, so a consumer that goes away never holds up or ends the enumeration.
+type ProgressSink struct {
+	path        string
+	log         *log.Logger
+	events      chan *ProgressEvent
+	finished    chan struct{}
+	w           io.WriteCloser
+	retry       time.Time
+	down        bool
+	start       time.Time
+	discoveries int64
+	sent        int64
+	dropped     int64
+	// The last totals sent for each data source, so unchanged sources are not repeated
+	last map[string]stats.SourceStats
+}
+
+// NewProgressSink returns a ProgressSink writing to the socket or named pipe at the path.
+// The consumer does not need to be present yet, and its absence is written to the logger.
+// The methods queuing the events are meant to be called from a single goroutine.
+func NewProgressSink(path string, logger *log.Logger) *ProgressSink {
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	s := &ProgressSink{
+		path:     path,
+		log:      logger,
+		events:   make(chan *ProgressEvent, progressQueueSize),
+		finished: make(chan struct{}),
+		start:    time.Now(),
+		last:     make(map[string]stats.SourceStats),
+	}
+
+	go s.processEvents()
+	return s
+}
+
+// Discovery queues the event describing a name found by the enumeration.
+func (s *ProgressSink) Discovery(out *requests.Output) {
+	var addrs []string
+	for _, a := range out.Addresses {
+		if a.Address != nil {
+			addrs = append(addrs, a.Address.String())
+		}
+	}
+
+	s.discoveries++
+	s.send(&ProgressEvent{
+		Type:      ProgressDiscovery,
+		Name:      out.Name,
+		Domain:    out.Domain,
+		Addresses: addrs,
+		Sources:   out.Sources,
+		Tag:       out.Tag,
+	})
+}
+
+// SourceProgress queues an event with the totals of each data source that changed since
+// the previous call.
+func (s *ProgressSink) SourceProgress(sources map[string]stats.SourceStats) {
+	for name, st := range sources {
+		if prev, found := s.last[name]; found && prev.Requests == st.Requests &&
+			prev.Errors == st.Errors && prev.Results == st.Results {
+			continue
+		}
+
+		s.last[name] = st
+		s.send(&ProgressEvent{
+			Type:     ProgressSource,
+			Source:   name,
+			Requests: st.Requests,
+			Errors:   st.Errors,
+			Results:  st.Results,
+		})
+	}
+}
+
+// Close queues the complete event, writes the events remaining in the queue and returns an
+// error when some of the events could not be delivered.
+func (s *ProgressSink) Close() error {
+	s.send(&ProgressEvent{
+		Type:        ProgressComplete,
+		Discoveries: s.discoveries,
+		DurationMS:  time.Since(s.start).Milliseconds(),
+	})
+	close(s.events)
+	<-s.finished
+
+	if dropped := atomic.LoadInt64(&s.dropped); dropped > 0 {
+		return fmt.Errorf("%d of %d progress events could not be written to %s", dropped,
+			dropped+atomic.LoadInt64(&s.sent), s.path)
+	}
+	return nil
+}
+
+// Sent returns the number of events written to the consumer.
+func (s *ProgressSink) Sent() int {
+	return int(atomic.LoadInt64(&s.sent))
+}
+
+func (s *ProgressSink) send(e *ProgressEvent) {
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+
+	select {
+	case s.events <- e:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (s *ProgressSink) processEvents() {
+	defer close(s.finished)
+
+	for e := range s.events {
+		data, err := json.Marshal(e)
+		if err == nil {
+			err = s.write(append(data, '\n'))
+		}
+		if err != nil {
+			atomic.AddInt64(&s.dropped, 1)
+			continue
+		}
+		atomic.AddInt64(&s.sent, 1)
+	}
+
+	if s.w != nil {
+		_ = s.w.Close()
+	}
+}
+
+// write sends the event, connecting first when necessary. After a failure the consumer is
+// only looked for again once the retry interval has elapsed.
+func (s *ProgressSink) write(data []byte) error {
+	if s.w == nil {
+		if s.down && time.Now().Before(s.retry) {
+			return errors.New("no consumer is reading the progress events")
+		}
+		if err := s.connect(); err != nil {
+			s.fail(err)
+			return err
+		}
+		if s.down {
+			s.log.Printf("Progress: a consumer is reading from %s", s.path)
+			s.down = false
+		}
+	}
+
+	if d, ok := s.w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		_ = d.SetWriteDeadline(time.Now().Add(progressWriteTimeout))
+	}
+	// The runtime only raises SIGPIPE for the standard output and error, so a consumer
+	// closing its end is reported here as an error
+	if _, err := s.w.Write(data); err != nil {
+		_ = s.w.Close()
+		s.w = nil
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail logs the first failure while no consumer is reading and schedules the next attempt.
+func (s *ProgressSink) fail(err error) {
+	if !s.down {
+		s.log.Printf("Progress: no consumer is reading from %s, events will be dropped until one connects: %v", s.path, err)
+	}
+
+	s.down = true
+	s.retry = time.Now().Add(progressRetryInterval)
+}
+
+func (s *ProgressSink) connect() error {
+	if fi, err := os.Stat(s.path); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+		// Without the nonblocking flag, opening the pipe would wait for a reader to open it
+		f, err := os.OpenFile(s.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			return err
+		}
+		s.w = f
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", s.path, progressWriteTimeout)
+	if err != nil {
+		return err
+	}
+	s.w = conn
+	return nil
+}
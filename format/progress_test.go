@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
+)
+
+func TestProgressSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen on the socket: %v", err)
+	}
+	defer ln.Close()
+
+	events := make(chan *ProgressEvent, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var e ProgressEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+				events <- &e
+			}
+		}
+		close(events)
+	}()
+
+	sink := NewProgressSink(path, nil)
+	sink.Discovery(&requests.Output{Name: "www.owasp.org", Domain: "owasp.org", Sources: []string{"DNSDB"}})
+	sink.SourceProgress(map[string]stats.SourceStats{"DNSDB": {Requests: 2, Results: 1}})
+	// The totals that did not change are not sent again
+	sink.SourceProgress(map[string]stats.SourceStats{"DNSDB": {Requests: 2, Results: 1}})
+	if err := sink.Close(); err != nil {
+		t.Errorf("The events were not written: %v", err)
+	}
+
+	var got []*ProgressEvent
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(got))
+	}
+	if got[0].Type != ProgressDiscovery || got[0].Name != "www.owasp.org" {
+		t.Errorf("Unexpected discovery event: %+v", got[0])
+	}
+	if got[1].Type != ProgressSource || got[1].Source != "DNSDB" || got[1].Requests != 2 {
+		t.Errorf("Unexpected source event: %+v", got[1])
+	}
+	if got[2].Type != ProgressComplete || got[2].Discoveries != 1 {
+		t.Errorf("Unexpected complete event: %+v", got[2])
+	}
+}
+
+func TestProgressSinkNoConsumer(t *testing.T) {
+	sink := NewProgressSink(filepath.Join(t.TempDir(), "missing.sock"), nil)
+	sink.Discovery(&requests.Output{Name: "www.owasp.org"})
+
+	if err := sink.Close(); err == nil || sink.Sent() != 0 {
+		t.Errorf("The events without a consumer were not reported as dropped")
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/net/dns"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// RDFVocabulary is the namespace of the classes and properties used in the RDF output.
+const RDFVocabulary = "https://owasp.org/www-project-amass/ns#"
+
+// The namespace of the asset IRIs, which are derived from the identity of each asset alone,
+// so the same asset has the same IRI in the output of every run
+const rdfAssetNamespace = "urn:amass:"
+
+var rdfPrefixes = [][2]string{
+	{"amass", RDFVocabulary},
+	{"prov", "http://www.w3.org/ns/prov#"},
+	{"rdf", "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+	{"rdfs", "http://www.w3.org/2000/01/rdf-schema#"},
+	{"xsd", "http://www.w3.org/2001/XMLSchema#"},
+}
+
+// RDFGraph holds the discovered names, IP addresses, netblocks, autonomous systems and data
+// sources as RDF statements, which are written in the Turtle format. Each asset is a resource
+// with a stable IRI, and the data sources that reported a name are attributed to it with the
+// PROV-O wasAttributedTo property.
+type RDFGraph struct {
+	// The objects of each predicate, keyed by the subject
+	subjects map[string]map[string]map[string]struct{}
+}
+
+// NewRDFGraph returns an empty RDFGraph.
+func NewRDFGraph() *RDFGraph {
+	return &RDFGraph{subjects: make(map[string]map[string]map[string]struct{})}
+}
+
+// RDFAssetIRI returns the IRI of the asset with the provided type, such as "fqdn" or "ip",
+// and identifier. Characters that are not allowed in an IRI are percent-encoded.
+func RDFAssetIRI(atype, id string) string {
+	return "<" + rdfAssetNamespace + atype + ":" + url.PathEscape(id) + ">"
+}
+
+// Add inserts the statements describing the provided Output into the graph.
+func (g *RDFGraph) Add(out *requests.Output) {
+	if out == nil || out.Name == "" {
+		return
+	}
+
+	name := g.fqdn(out.Name)
+	if out.Domain != "" && !strings.EqualFold(out.Domain, out.Name) {
+		g.insert(name, "amass:domain", g.fqdn(out.Domain))
+	}
+	if out.Tag != "" {
+		g.insert(name, "amass:tag", rdfLiteral(out.Tag))
+	}
+	if !out.FirstSeen.IsZero() {
+		g.insert(name, "amass:firstSeen", rdfTime(out.FirstSeen))
+	}
+	if !out.LastSeen.IsZero() {
+		g.insert(name, "amass:lastSeen", rdfTime(out.LastSeen))
+	}
+	if out.Passive {
+		g.insert(name, "amass:passive", `"true"^^xsd:boolean`)
+	}
+	if out.Class != "" {
+		g.insert(name, "amass:class", rdfLiteral(out.Class))
+	}
+	for _, ns := range out.NameServers {
+		g.insert(name, "amass:nameServer", g.fqdn(ns))
+	}
+	for _, seed := range out.Seeds {
+		g.insert(name, "amass:seed", g.fqdn(seed))
+	}
+	for _, src := range out.Sources {
+		source := RDFAssetIRI("source", src)
+		g.insert(source, "a", "amass:Source")
+		g.insert(source, "a", "prov:Agent")
+		g.insert(source, "rdfs:label", rdfLiteral(src))
+		g.insert(name, "prov:wasAttributedTo", source)
+	}
+
+	for _, addr := range out.Addresses {
+		if addr.Address == nil {
+			continue
+		}
+
+		ipstr := addr.Address.String()
+		ip := RDFAssetIRI("ip", ipstr)
+		g.insert(ip, "a", "amass:IPAddress")
+		g.insert(ip, "amass:address", rdfLiteral(ipstr))
+		version := "4"
+		if amassnet.IsIPv6(addr.Address) {
+			version = "6"
+		}
+		g.insert(ip, "amass:version", `"`+version+`"^^xsd:integer`)
+		g.insert(name, "amass:resolvesTo", ip)
+
+		if addr.CIDRStr != "" {
+			netblock := RDFAssetIRI("netblock", addr.CIDRStr)
+			g.insert(netblock, "a", "amass:Netblock")
+			g.insert(netblock, "amass:cidr", rdfLiteral(addr.CIDRStr))
+			g.insert(ip, "amass:containedIn", netblock)
+
+			if addr.ASN > 0 {
+				g.insert(g.as(addr.ASN, addr.Description), "amass:announces", netblock)
+			}
+		} else if addr.ASN > 0 {
+			g.insert(ip, "amass:announcedBy", g.as(addr.ASN, addr.Description))
+		}
+	}
+}
+
+// Encode writes the graph to the provided io.Writer in the Turtle format. The subjects,
+// predicates and objects are sorted, so the same findings always produce the same document.
+func (g *RDFGraph) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, p := range rdfPrefixes {
+		fmt.Fprintf(bw, "@prefix %s: <%s> .\n", p[0], p[1])
+	}
+
+	subjects := make([]string, 0, len(g.subjects))
+	for s := range g.subjects {
+		subjects = append(subjects, s)
+	}
+	sort.Strings(subjects)
+
+	for _, s := range subjects {
+		preds := g.subjects[s]
+		// The type of the resource is stated first
+		names := make([]string, 0, len(preds))
+		for p := range preds {
+			if p != "a" {
+				names = append(names, p)
+			}
+		}
+		sort.Strings(names)
+		if _, found := preds["a"]; found {
+			names = append([]string{"a"}, names...)
+		}
+
+		fmt.Fprintf(bw, "\n%s", s)
+		for i, p := range names {
+			objs := make([]string, 0, len(preds[p]))
+			for o := range preds[p] {
+				objs = append(objs, o)
+			}
+			sort.Strings(objs)
+
+			sep := " ;"
+			if i == len(names)-1 {
+				sep = " ."
+			}
+			fmt.Fprintf(bw, "\n    %s %s%s", p, strings.Join(objs, ", "), sep)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func (g *RDFGraph) fqdn(name string) string {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	// The IRI is built from the punycode form, so it does not depend on the output encoding
+	iri := RDFAssetIRI("fqdn", dns.ToASCII(name))
+
+	g.insert(iri, "a", "amass:FQDN")
+	g.insert(iri, "amass:name", rdfLiteral(name))
+	return iri
+}
+
+func (g *RDFGraph) as(asn int, desc string) string {
+	iri := RDFAssetIRI("as", strconv.Itoa(asn))
+
+	g.insert(iri, "a", "amass:AutonomousSystem")
+	g.insert(iri, "amass:asn", `"`+strconv.Itoa(asn)+`"^^xsd:integer`)
+	if desc != "" {
+		g.insert(iri, "amass:description", rdfLiteral(desc))
+	}
+	return iri
+}
+
+func (g *RDFGraph) insert(subject, predicate, object string) {
+	preds, found := g.subjects[subject]
+	if !found {
+		preds = make(map[string]map[string]struct{})
+		g.subjects[subject] = preds
+	}
+
+	objs, found := preds[predicate]
+	if !found {
+		objs = make(map[string]struct{})
+		preds[predicate] = objs
+	}
+	objs[object] = struct{}{}
+}
+
+// rdfLiteral returns the string as a Turtle literal, escaping the characters that the
+// grammar does not allow within quotes.
+func rdfLiteral(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func rdfTime(t time.Time) string {
+	return `"` + t.UTC().Format(time.RFC3339) + `"^^xsd:dateTime`
+}
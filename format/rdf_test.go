@@ -0,0 +1,83 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestRDFGraph(t *testing.T) {
+	seen := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	outputs := []*requests.Output{
+		{
+			Name:   "www.example.com",
+			Domain: "example.com",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("192.0.2.1"), CIDRStr: "192.0.2.0/24", ASN: 64496, Description: "EXAMPLE \"NET\""},
+			},
+			Tag:       requests.API,
+			Sources:   []string{"Umbrella", "DNS"},
+			FirstSeen: seen,
+			LastSeen:  seen.Add(time.Hour),
+		},
+		{
+			Name:      "mail.example.com",
+			Domain:    "example.com",
+			Addresses: []requests.AddressInfo{{Address: net.ParseIP("192.0.2.1"), CIDRStr: "192.0.2.0/24", ASN: 64496}},
+			Tag:       requests.DNS,
+			Sources:   []string{"DNS"},
+			Passive:   true,
+		},
+	}
+
+	encode := func(outs ...*requests.Output) string {
+		g := NewRDFGraph()
+		for _, out := range outs {
+			g.Add(out)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := g.Encode(buf); err != nil {
+			t.Fatalf("Failed to encode the graph: %v", err)
+		}
+		return buf.String()
+	}
+
+	doc := encode(outputs...)
+	for _, expected := range []string{
+		"@prefix amass: <" + RDFVocabulary + "> .\n",
+		"\n<urn:amass:fqdn:www.example.com>\n    a amass:FQDN ;\n    amass:domain <urn:amass:fqdn:example.com> ;\n",
+		"    amass:firstSeen \"2022-03-01T10:00:00Z\"^^xsd:dateTime ;\n",
+		"    amass:resolvesTo <urn:amass:ip:192.0.2.1> ;\n",
+		"    prov:wasAttributedTo <urn:amass:source:DNS>, <urn:amass:source:Umbrella> .\n",
+		"\n<urn:amass:ip:192.0.2.1>\n    a amass:IPAddress ;\n    amass:address \"192.0.2.1\" ;\n    amass:containedIn <urn:amass:netblock:192.0.2.0%2F24> ;\n",
+		"\n<urn:amass:as:64496>\n    a amass:AutonomousSystem ;\n    amass:announces <urn:amass:netblock:192.0.2.0%2F24> ;\n",
+		"    amass:description \"EXAMPLE \\\"NET\\\"\" .\n",
+		"    amass:passive \"true\"^^xsd:boolean ;\n",
+		"\n<urn:amass:source:Umbrella>\n    a amass:Source, prov:Agent ;\n    rdfs:label \"Umbrella\" .\n",
+	} {
+		if !strings.Contains(doc, expected) {
+			t.Errorf("The document is missing %q:\n%s", expected, doc)
+		}
+	}
+	// The shared address, netblock and AS are described once
+	if n := strings.Count(doc, "\n<urn:amass:ip:192.0.2.1>\n"); n != 1 {
+		t.Errorf("The address was described %d times", n)
+	}
+	// The same findings produce the same document, whatever their order
+	if again := encode(outputs[1], outputs[0]); again != doc {
+		t.Errorf("The document changed with the order of the findings:\n%s", again)
+	}
+	// The IRI of a name does not depend on the encoding it is written in
+	if d := encode(&requests.Output{Name: "www.münchen.de"}); !strings.Contains(d, "<urn:amass:fqdn:www.xn--mnchen-3ya.de>") {
+		t.Errorf("The IRI of the internationalized name was not built from punycode:\n%s", d)
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// ReportTimeFormat is the format of the timestamps shown in the HTML report.
+const ReportTimeFormat = "2006-01-02 15:04:05 MST"
+
+const (
+	// The most nodes drawn in the graph of the report, so large enumerations remain readable
+	reportGraphMaxNodes = 500
+	reportGraphSize     = 900
+)
+
+// Report summarizes the findings of enumerations in a self-contained HTML document. It holds
+// sortable tables of the names, addresses, netblocks, ASNs and data sources, and a graph of
+// the names and the infrastructure hosting them, without fetching anything from the network.
+type Report struct {
+	generated time.Time
+	first     time.Time
+	last      time.Time
+	names     []*requests.Output
+	seen      map[string]struct{}
+}
+
+// NewReport returns an empty Report generated at the time provided, covering the enumerations
+// that ran between first and last.
+func NewReport(generated, first, last time.Time) *Report {
+	return &Report{
+		generated: generated,
+		first:     first,
+		last:      last,
+		seen:      make(map[string]struct{}),
+	}
+}
+
+// Add includes the discovered name in the report, once for each name.
+func (r *Report) Add(out *requests.Output) {
+	if out == nil || out.Name == "" {
+		return
+	}
+	if _, found := r.seen[out.Name]; found {
+		return
+	}
+
+	r.seen[out.Name] = struct{}{}
+	r.names = append(r.names, out)
+}
+
+type reportName struct {
+	Name      string
+	Domain    string
+	Addresses string
+	Tag       string
+	Sources   string
+	FirstSeen string
+	LastSeen  string
+	Passive   bool
+}
+
+type reportAddress struct {
+	Address     string
+	Netblock    string
+	ASN         int
+	Description string
+	Names       int
+}
+
+type reportNetblock struct {
+	Netblock    string
+	ASN         int
+	Description string
+	Addresses   int
+	Names       int
+}
+
+type reportASN struct {
+	ASN         int
+	Description string
+	Netblocks   int
+	Addresses   int
+}
+
+type reportSource struct {
+	Source string
+	Names  int
+	Unique int
+}
+
+type reportGraphNode struct {
+	X, Y  float64
+	Class string
+	Label string
+}
+
+type reportGraphEdge struct {
+	X1, Y1, X2, Y2 float64
+}
+
+type reportData struct {
+	Generated  string
+	First      string
+	Last       string
+	Domains    []string
+	Names      []reportName
+	Addresses  []reportAddress
+	Netblocks  []reportNetblock
+	ASNs       []reportASN
+	Sources    []reportSource
+	GraphSize  int
+	GraphNodes []reportGraphNode
+	GraphEdges []reportGraphEdge
+	Truncated  bool
+}
+
+// WriteHTML renders the report as a single HTML file.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r.data())
+}
+
+func (r *Report) data() *reportData {
+	d := &reportData{
+		Generated: reportTime(r.generated),
+		First:     reportTime(r.first),
+		Last:      reportTime(r.last),
+		GraphSize: reportGraphSize,
+	}
+
+	domains := make(map[string]struct{})
+	addrs := make(map[string]*reportAddress)
+	netblocks := make(map[string]*reportNetblock)
+	netblockAddrs := make(map[string]map[string]struct{})
+	netblockNames := make(map[string]map[string]struct{})
+	asns := make(map[int]*reportASN)
+	asnNetblocks := make(map[int]map[string]struct{})
+	asnAddrs := make(map[int]map[string]struct{})
+	sources := make(map[string]*reportSource)
+
+	names := append([]*requests.Output(nil), r.names...)
+	sort.Slice(names, func(i, j int) bool { return names[i].Name < names[j].Name })
+	for _, out := range names {
+		domains[out.Domain] = struct{}{}
+
+		var ips []string
+		for _, a := range out.Addresses {
+			ip := a.Address.String()
+			ips = append(ips, ip)
+
+			addr, found := addrs[ip]
+			if !found {
+				addr = &reportAddress{
+					Address:     ip,
+					Netblock:    a.CIDRStr,
+					ASN:         a.ASN,
+					Description: a.Description,
+				}
+				addrs[ip] = addr
+			}
+			addr.Names++
+
+			if a.CIDRStr != "" {
+				if _, found := netblocks[a.CIDRStr]; !found {
+					netblocks[a.CIDRStr] = &reportNetblock{
+						Netblock:    a.CIDRStr,
+						ASN:         a.ASN,
+						Description: a.Description,
+					}
+					netblockAddrs[a.CIDRStr] = make(map[string]struct{})
+					netblockNames[a.CIDRStr] = make(map[string]struct{})
+				}
+				netblockAddrs[a.CIDRStr][ip] = struct{}{}
+				netblockNames[a.CIDRStr][out.Name] = struct{}{}
+			}
+
+			if a.ASN != 0 {
+				if _, found := asns[a.ASN]; !found {
+					asns[a.ASN] = &reportASN{ASN: a.ASN, Description: a.Description}
+					asnNetblocks[a.ASN] = make(map[string]struct{})
+					asnAddrs[a.ASN] = make(map[string]struct{})
+				}
+				if a.CIDRStr != "" {
+					asnNetblocks[a.ASN][a.CIDRStr] = struct{}{}
+				}
+				asnAddrs[a.ASN][ip] = struct{}{}
+			}
+		}
+
+		for _, src := range out.Sources {
+			s, found := sources[src]
+			if !found {
+				s = &reportSource{Source: src}
+				sources[src] = s
+			}
+			s.Names++
+			if len(out.Sources) == 1 {
+				s.Unique++
+			}
+		}
+
+		d.Names = append(d.Names, reportName{
+			Name:      out.Name,
+			Domain:    out.Domain,
+			Addresses: strings.Join(ips, ", "),
+			Tag:       out.Tag,
+			Sources:   strings.Join(out.Sources, ", "),
+			FirstSeen: reportTime(out.FirstSeen),
+			LastSeen:  reportTime(out.LastSeen),
+			Passive:   out.Passive,
+		})
+	}
+
+	for domain := range domains {
+		d.Domains = append(d.Domains, domain)
+	}
+	sort.Strings(d.Domains)
+	for _, a := range addrs {
+		d.Addresses = append(d.Addresses, *a)
+	}
+	sort.Slice(d.Addresses, func(i, j int) bool { return d.Addresses[i].Address < d.Addresses[j].Address })
+	for cidr, nb := range netblocks {
+		nb.Addresses = len(netblockAddrs[cidr])
+		nb.Names = len(netblockNames[cidr])
+		d.Netblocks = append(d.Netblocks, *nb)
+	}
+	sort.Slice(d.Netblocks, func(i, j int) bool { return d.Netblocks[i].Netblock < d.Netblocks[j].Netblock })
+	for asn, as := range asns {
+		as.Netblocks = len(asnNetblocks[asn])
+		as.Addresses = len(asnAddrs[asn])
+		d.ASNs = append(d.ASNs, *as)
+	}
+	sort.Slice(d.ASNs, func(i, j int) bool { return d.ASNs[i].ASN < d.ASNs[j].ASN })
+	for _, s := range sources {
+		d.Sources = append(d.Sources, *s)
+	}
+	sort.Slice(d.Sources, func(i, j int) bool {
+		if d.Sources[i].Names != d.Sources[j].Names {
+			return d.Sources[i].Names > d.Sources[j].Names
+		}
+		return d.Sources[i].Source < d.Sources[j].Source
+	})
+
+	d.GraphNodes, d.GraphEdges, d.Truncated = reportGraph(d.Domains, names)
+	return d
+}
+
+// reportGraph places the domains, names, addresses and ASNs on concentric rings, from the
+// center outwards, and connects each name to its domain and addresses, and each address to its ASN.
+func reportGraph(domains []string, names []*requests.Output) ([]reportGraphNode, []reportGraphEdge, bool) {
+	rings := [][]string{domains, nil, nil, nil}
+	classes := []string{"domain", "name", "address", "asn"}
+	index := make(map[string][2]int)
+	for i, domain := range domains {
+		index["domain:"+domain] = [2]int{0, i}
+	}
+
+	type link struct{ from, to string }
+	var links []link
+	var truncated bool
+
+	count := len(domains)
+	add := func(ring int, key, label string) bool {
+		if _, found := index[key]; found {
+			return true
+		}
+		if count >= reportGraphMaxNodes {
+			truncated = true
+			return false
+		}
+		index[key] = [2]int{ring, len(rings[ring])}
+		rings[ring] = append(rings[ring], label)
+		count++
+		return true
+	}
+
+	for _, out := range names {
+		from := "domain:" + out.Domain
+		if out.Name != out.Domain {
+			if !add(1, "name:"+out.Name, out.Name) {
+				break
+			}
+			from = "name:" + out.Name
+			links = append(links, link{"domain:" + out.Domain, from})
+		}
+
+		for _, a := range out.Addresses {
+			ip := a.Address.String()
+			if !add(2, "address:"+ip, ip) {
+				break
+			}
+			links = append(links, link{from, "address:" + ip})
+
+			if a.ASN != 0 {
+				asn := strconv.Itoa(a.ASN)
+				if !add(3, "asn:"+asn, "AS"+asn) {
+					break
+				}
+				links = append(links, link{"address:" + ip, "asn:" + asn})
+			}
+		}
+	}
+
+	center := float64(reportGraphSize) / 2
+	step := (center - 40) / float64(len(rings))
+	position := func(key string) (float64, float64) {
+		pos := index[key]
+		n := len(rings[pos[0]])
+		radius := step * float64(pos[0])
+		if pos[0] > 0 || n > 1 {
+			radius += step / 2
+		}
+		angle := 2 * math.Pi * float64(pos[1]) / float64(n)
+		return center + radius*math.Cos(angle), center + radius*math.Sin(angle)
+	}
+
+	var nodes []reportGraphNode
+	for ring, labels := range rings {
+		for i, label := range labels {
+			key := classes[ring] + ":" + label
+			if ring == 3 {
+				key = "asn:" + strings.TrimPrefix(label, "AS")
+			}
+			x, y := position(key)
+			nodes = append(nodes, reportGraphNode{X: x, Y: y, Class: classes[ring], Label: labels[i]})
+		}
+	}
+
+	var edges []reportGraphEdge
+	for _, l := range links {
+		if _, found := index[l.from]; !found {
+			continue
+		}
+		if _, found := index[l.to]; !found {
+			continue
+		}
+		x1, y1 := position(l.from)
+		x2, y2 := position(l.to)
+		edges = append(edges, reportGraphEdge{X1: x1, Y1: y1, X2: x2, Y2: y2})
+	}
+	return nodes, edges, truncated
+}
+
+func reportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(ReportTimeFormat)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"printf2": func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) },
+}).Parse(reportHTML))
+
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>OWASP Amass Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 1.5em; }
+.summary td { padding: 0.2em 1.5em 0.2em 0; }
+table.sortable { border-collapse: collapse; margin-bottom: 2em; font-size: 0.9em; }
+table.sortable th, table.sortable td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+table.sortable th { background: #eee; cursor: pointer; user-select: none; }
+table.sortable th.asc:after { content: " \25B2"; }
+table.sortable th.desc:after { content: " \25BC"; }
+svg line { stroke: #bbb; stroke-width: 0.5; }
+svg circle.domain { fill: #d62728; }
+svg circle.name { fill: #1f77b4; }
+svg circle.address { fill: #2ca02c; }
+svg circle.asn { fill: #ff7f0e; }
+</style>
+</head>
+<body>
+<h1>OWASP Amass Report</h1>
+<div class="meta">Generated {{.Generated}}{{if .First}}, covering the enumerations from {{.First}} to {{.Last}}{{end}}</div>
+
+<h2>Summary</h2>
+<table class="summary">
+<tr><td>Domains</td><td>{{len .Domains}}</td></tr>
+<tr><td>Names</td><td>{{len .Names}}</td></tr>
+<tr><td>Addresses</td><td>{{len .Addresses}}</td></tr>
+<tr><td>Netblocks</td><td>{{len .Netblocks}}</td></tr>
+<tr><td>ASNs</td><td>{{len .ASNs}}</td></tr>
+<tr><td>Data sources</td><td>{{len .Sources}}</td></tr>
+</table>
+
+<h2>Graph</h2>
+{{if .Truncated}}<p>Only part of the findings is drawn, to keep the graph readable.</p>{{end}}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.GraphSize}}" height="{{.GraphSize}}" viewBox="0 0 {{.GraphSize}} {{.GraphSize}}">
+{{range .GraphEdges}}<line x1="{{printf2 .X1}}" y1="{{printf2 .Y1}}" x2="{{printf2 .X2}}" y2="{{printf2 .Y2}}"/>
+{{end}}{{range .GraphNodes}}<circle class="{{.Class}}" cx="{{printf2 .X}}" cy="{{printf2 .Y}}" r="4"><title>{{.Label}}</title></circle>
+{{end}}</svg>
+
+<h2>Data Sources</h2>
+<table class="sortable">
+<thead><tr><th>Source</th><th>Names</th><th>Only Reported by the Source</th></tr></thead>
+<tbody>
+{{range .Sources}}<tr><td>{{.Source}}</td><td>{{.Names}}</td><td>{{.Unique}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Names</h2>
+<table class="sortable">
+<thead><tr><th>Name</th><th>Domain</th><th>Addresses</th><th>Tag</th><th>Sources</th><th>First Seen</th><th>Last Seen</th><th>Resolved</th></tr></thead>
+<tbody>
+{{range .Names}}<tr><td>{{.Name}}</td><td>{{.Domain}}</td><td>{{.Addresses}}</td><td>{{.Tag}}</td><td>{{.Sources}}</td><td>{{.FirstSeen}}</td><td>{{.LastSeen}}</td><td>{{if .Passive}}no{{else}}yes{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Addresses</h2>
+<table class="sortable">
+<thead><tr><th>Address</th><th>Netblock</th><th>ASN</th><th>Description</th><th>Names</th></tr></thead>
+<tbody>
+{{range .Addresses}}<tr><td>{{.Address}}</td><td>{{.Netblock}}</td><td>{{if .ASN}}{{.ASN}}{{end}}</td><td>{{.Description}}</td><td>{{.Names}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Netblocks</h2>
+<table class="sortable">
+<thead><tr><th>Netblock</th><th>ASN</th><th>Description</th><th>Addresses</th><th>Names</th></tr></thead>
+<tbody>
+{{range .Netblocks}}<tr><td>{{.Netblock}}</td><td>{{if .ASN}}{{.ASN}}{{end}}</td><td>{{.Description}}</td><td>{{.Addresses}}</td><td>{{.Names}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>ASNs</h2>
+<table class="sortable">
+<thead><tr><th>ASN</th><th>Description</th><th>Netblocks</th><th>Addresses</th></tr></thead>
+<tbody>
+{{range .ASNs}}<tr><td>{{.ASN}}</td><td>{{.Description}}</td><td>{{.Netblocks}}</td><td>{{.Addresses}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<script>
+document.querySelectorAll("table.sortable th").forEach(function(th) {
+  th.addEventListener("click", function() {
+    var table = th.closest("table"), body = table.tBodies[0], col = th.cellIndex;
+    var asc = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function(h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function(a, b) {
+      var x = a.cells[col].textContent, y = b.cells[col].textContent;
+      var c = x.localeCompare(y, undefined, {numeric: true});
+      return asc ? c : -c;
+    });
+    rows.forEach(function(row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,113 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestReport(t *testing.T) {
+	now := time.Now()
+	rep := NewReport(now, now.Add(-time.Hour), now)
+
+	outputs := []*requests.Output{
+		{
+			Name:   "www.owasp.org",
+			Domain: "owasp.org",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("104.22.27.77"), CIDRStr: "104.22.16.0/20", ASN: 13335, Description: "CLOUDFLARENET"},
+			},
+			Tag:       requests.API,
+			Sources:   []string{"Umbrella", "NetworksDB"},
+			FirstSeen: now.Add(-time.Hour),
+			LastSeen:  now,
+		},
+		{
+			Name:   "owasp.org",
+			Domain: "owasp.org",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("104.22.26.77"), CIDRStr: "104.22.16.0/20", ASN: 13335, Description: "CLOUDFLARENET"},
+			},
+			Tag:     requests.DNS,
+			Sources: []string{"DNS"},
+		},
+		{
+			Name:    "<script>alert(1)</script>.owasp.org",
+			Domain:  "owasp.org",
+			Tag:     requests.SCRAPE,
+			Sources: []string{"Umbrella"},
+			Passive: true,
+		},
+	}
+	for _, out := range outputs {
+		rep.Add(out)
+	}
+	// Names are only included once
+	rep.Add(outputs[0])
+
+	d := rep.data()
+	if len(d.Names) != 3 || len(d.Addresses) != 2 || len(d.Netblocks) != 1 || len(d.ASNs) != 1 {
+		t.Fatalf("Unexpected table sizes: %d names, %d addresses, %d netblocks, %d ASNs",
+			len(d.Names), len(d.Addresses), len(d.Netblocks), len(d.ASNs))
+	}
+	if nb := d.Netblocks[0]; nb.Addresses != 2 || nb.Names != 2 {
+		t.Errorf("The netblock counted %d addresses and %d names", nb.Addresses, nb.Names)
+	}
+	if as := d.ASNs[0]; as.Netblocks != 1 || as.Addresses != 2 {
+		t.Errorf("The ASN counted %d netblocks and %d addresses", as.Netblocks, as.Addresses)
+	}
+	if len(d.Sources) != 3 || d.Sources[0].Source != "Umbrella" || d.Sources[0].Names != 2 || d.Sources[0].Unique != 1 {
+		t.Errorf("Unexpected data source contributions: %+v", d.Sources)
+	}
+	// One domain, two subdomains, two addresses and one ASN
+	if len(d.GraphNodes) != 6 || len(d.GraphEdges) != 6 {
+		t.Errorf("The graph had %d nodes and %d edges", len(d.GraphNodes), len(d.GraphEdges))
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteHTML(&buf); err != nil {
+		t.Fatalf("Failed to write the report: %v", err)
+	}
+
+	page := buf.String()
+	for _, want := range []string{"www.owasp.org", "104.22.16.0/20", "CLOUDFLARENET", "NetworksDB", "<svg", "table class=\"sortable\""} {
+		if !strings.Contains(page, want) {
+			t.Errorf("The report did not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<script>alert(1)</script>") {
+		t.Error("The report did not escape the discovered names")
+	}
+	if strings.Contains(page, "<script src") || strings.Contains(page, "<link") {
+		t.Error("The report loads external resources")
+	}
+}
+
+func TestReportGraphLimit(t *testing.T) {
+	rep := NewReport(time.Now(), time.Time{}, time.Time{})
+
+	for i := 0; i < reportGraphMaxNodes*2; i++ {
+		ip := net.IPv4(10, 0, byte(i/256), byte(i%256))
+		rep.Add(&requests.Output{
+			Name:      ip.String() + ".owasp.org",
+			Domain:    "owasp.org",
+			Addresses: []requests.AddressInfo{{Address: ip}},
+		})
+	}
+
+	d := rep.data()
+	if !d.Truncated || len(d.GraphNodes) > reportGraphMaxNodes {
+		t.Errorf("The graph was not limited: %d nodes", len(d.GraphNodes))
+	}
+	if len(d.Names) != reportGraphMaxNodes*2 {
+		t.Errorf("The tables were limited along with the graph")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// SortOutput orders the findings by name, then by the type of the discovery, and orders the
+// addresses and sources within each finding, so the output of repeated runs can be compared
+// line by line.
+func SortOutput(outputs []*requests.Output) {
+	for _, out := range outputs {
+		sort.Strings(out.Sources)
+		sort.SliceStable(out.Addresses, func(i, j int) bool {
+			a, b := out.Addresses[i].Address, out.Addresses[j].Address
+			// The IPv4 addresses are placed before the IPv6 addresses
+			if a4, b4 := a.To4(), b.To4(); (a4 == nil) != (b4 == nil) {
+				return a4 != nil
+			}
+			return bytes.Compare(a.To16(), b.To16()) < 0
+		})
+	}
+
+	sort.SliceStable(outputs, func(i, j int) bool {
+		if outputs[i].Name != outputs[j].Name {
+			return outputs[i].Name < outputs[j].Name
+		}
+		return outputs[i].Tag < outputs[j].Tag
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestSortOutput(t *testing.T) {
+	addrs := func(ips ...string) []requests.AddressInfo {
+		var infos []requests.AddressInfo
+		for _, ip := range ips {
+			infos = append(infos, requests.AddressInfo{Address: net.ParseIP(ip)})
+		}
+		return infos
+	}
+
+	outputs := []*requests.Output{
+		{Name: "www.owasp.org", Tag: "cert", Sources: []string{"crtsh", "Umbrella"}},
+		{Name: "api.owasp.org", Tag: "dns", Addresses: addrs("2001:db8::1", "192.0.2.10", "192.0.2.9")},
+		{Name: "www.owasp.org", Tag: "api"},
+	}
+	SortOutput(outputs)
+
+	var got []string
+	for _, out := range outputs {
+		var ips []string
+		for _, a := range out.Addresses {
+			ips = append(ips, a.Address.String())
+		}
+		got = append(got, fmt.Sprintf("%s %s %v %v", out.Name, out.Tag, ips, out.Sources))
+	}
+	expected := []string{
+		"api.owasp.org dns [192.0.2.9 192.0.2.10 2001:db8::1] []",
+		"www.owasp.org api [] []",
+		"www.owasp.org cert [] [Umbrella crtsh]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Unexpected order:\n%v\nexpected:\n%v", got, expected)
+	}
+}
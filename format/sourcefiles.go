@@ -0,0 +1,137 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// SourceFiles writes the findings of each data source to its own JSON Lines file, so the
+// contributions of the sources can be audited and compared. Each name is written once per file.
+type SourceFiles struct {
+	prefix string
+	flat   bool
+	files  map[string]*sourceFile
+}
+
+type sourceFile struct {
+	path string
+	ptr  *os.File
+	enc  *json.Encoder
+	seen map[string]struct{}
+}
+
+// NewSourceFiles returns a SourceFiles that creates the files in the directory provided. When the
+// prefix is not empty, it is used in place of the directory, and the file names are appended to it.
+// The flat parameter selects the flat JSON output format.
+func NewSourceFiles(dir, prefix string, flat bool) *SourceFiles {
+	if prefix == "" {
+		prefix = dir + string(filepath.Separator)
+	} else {
+		prefix += "_"
+	}
+
+	return &SourceFiles{
+		prefix: prefix,
+		flat:   flat,
+		files:  make(map[string]*sourceFile),
+	}
+}
+
+// SourceFileName returns the name of the file holding the findings of the data source.
+func SourceFileName(source string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(strings.TrimSpace(source)))
+
+	return "source_" + name + ".jsonl"
+}
+
+// Write adds the output to the file of each data source that reported it. The lines list only
+// the data source of the file they are written to.
+func (s *SourceFiles) Write(out *requests.Output) error {
+	var firstErr error
+
+	for _, src := range out.Sources {
+		f, err := s.file(src)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if _, found := f.seen[out.Name]; found {
+			continue
+		}
+		f.seen[out.Name] = struct{}{}
+
+		o := *out
+		o.Sources = []string{src}
+		if s.flat {
+			err = f.enc.Encode(NewFlatOutput(&o))
+		} else {
+			err = f.enc.Encode(&o)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *SourceFiles) file(source string) (*sourceFile, error) {
+	key := SourceFileName(source)
+	if f, found := s.files[key]; found {
+		return f, nil
+	}
+
+	path := s.prefix + key
+	ptr, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &sourceFile{
+		path: path,
+		ptr:  ptr,
+		enc:  json.NewEncoder(ptr),
+		seen: make(map[string]struct{}),
+	}
+	s.files[key] = f
+	return f, nil
+}
+
+// Paths returns the files written, in alphabetical order.
+func (s *SourceFiles) Paths() []string {
+	var paths []string
+
+	for _, f := range s.files {
+		paths = append(paths, f.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Close flushes and closes all the files.
+func (s *SourceFiles) Close() error {
+	var firstErr error
+
+	for _, f := range s.files {
+		_ = f.ptr.Sync()
+		if err := f.ptr.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
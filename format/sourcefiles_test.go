@@ -0,0 +1,99 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func TestSourceFileName(t *testing.T) {
+	for source, want := range map[string]string{
+		"Umbrella":         "source_umbrella.jsonl",
+		"NetworksDB":       "source_networksdb.jsonl",
+		"Brute Forcing":    "source_brute_forcing.jsonl",
+		"../crt.sh":        "source____crt_sh.jsonl",
+		"Reverse DNS (v6)": "source_reverse_dns__v6_.jsonl",
+	} {
+		if got := SourceFileName(source); got != want {
+			t.Errorf("SourceFileName(%q) returned %s, expected %s", source, got, want)
+		}
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := NewSourceFiles(dir, "", false)
+
+	outputs := []*requests.Output{
+		{Name: "www.owasp.org", Domain: "owasp.org", Sources: []string{"Umbrella", "NetworksDB"}},
+		{Name: "api.owasp.org", Domain: "owasp.org", Sources: []string{"Umbrella"}},
+		// Names are only written once to each file
+		{Name: "www.owasp.org", Domain: "owasp.org", Sources: []string{"Umbrella"}},
+	}
+	for _, out := range outputs {
+		if err := files.Write(out); err != nil {
+			t.Fatalf("Failed to write the output: %v", err)
+		}
+	}
+	if err := files.Close(); err != nil {
+		t.Fatalf("Failed to close the files: %v", err)
+	}
+
+	paths := files.Paths()
+	if len(paths) != 2 || paths[1] != filepath.Join(dir, "source_umbrella.jsonl") {
+		t.Fatalf("Unexpected files written: %v", paths)
+	}
+
+	for path, want := range map[string][]string{
+		filepath.Join(dir, "source_umbrella.jsonl"):   {"www.owasp.org", "api.owasp.org"},
+		filepath.Join(dir, "source_networksdb.jsonl"): {"www.owasp.org"},
+	} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+
+		var names []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var out requests.Output
+			if err := json.Unmarshal(scanner.Bytes(), &out); err != nil {
+				t.Fatalf("%s contained an invalid line: %v", path, err)
+			}
+			if len(out.Sources) != 1 {
+				t.Errorf("%s listed the sources %v", path, out.Sources)
+			}
+			names = append(names, out.Name)
+		}
+		f.Close()
+
+		if len(names) != len(want) || names[0] != want[0] {
+			t.Errorf("%s contained %v, expected %v", path, names, want)
+		}
+	}
+	// The shared output was not modified
+	if len(outputs[0].Sources) != 2 {
+		t.Errorf("The sources of the output were changed: %v", outputs[0].Sources)
+	}
+}
+
+func TestSourceFilesPrefix(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "scan")
+	files := NewSourceFiles("ignored", prefix, true)
+	defer files.Close()
+
+	if err := files.Write(&requests.Output{Name: "www.owasp.org", Sources: []string{"DNS"}}); err != nil {
+		t.Fatalf("Failed to write the output: %v", err)
+	}
+	if _, err := os.Stat(prefix + "_source_dns.jsonl"); err != nil {
+		t.Errorf("The file was not named with the prefix: %v", err)
+	}
+}
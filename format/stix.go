@@ -0,0 +1,185 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
+	"github.com/google/uuid"
+)
+
+// STIXTimeFormat is the timestamp format required by the STIX 2.1 specification.
+const STIXTimeFormat = "2006-01-02T15:04:05.000Z"
+
+const stixSpecVersion = "2.1"
+
+// The namespace defined by STIX 2.1 for generating deterministic identifiers of cyber-observable objects.
+var stixSCONamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// STIXExternalReference attributes a STIX object to the data source that provided the finding.
+type STIXExternalReference struct {
+	SourceName  string `json:"source_name"`
+	Description string `json:"description,omitempty"`
+}
+
+// STIXObject contains the properties used by the STIX objects that represent Amass findings.
+type STIXObject struct {
+	Type               string                  `json:"type"`
+	SpecVersion        string                  `json:"spec_version"`
+	ID                 string                  `json:"id"`
+	Created            string                  `json:"created,omitempty"`
+	Modified           string                  `json:"modified,omitempty"`
+	CreatedByRef       string                  `json:"created_by_ref,omitempty"`
+	Name               string                  `json:"name,omitempty"`
+	IdentityClass      string                  `json:"identity_class,omitempty"`
+	Value              string                  `json:"value,omitempty"`
+	Number             int                     `json:"number,omitempty"`
+	RelationshipType   string                  `json:"relationship_type,omitempty"`
+	SourceRef          string                  `json:"source_ref,omitempty"`
+	TargetRef          string                  `json:"target_ref,omitempty"`
+	FirstObserved      string                  `json:"first_observed,omitempty"`
+	LastObserved       string                  `json:"last_observed,omitempty"`
+	NumberObserved     int                     `json:"number_observed,omitempty"`
+	ObjectRefs         []string                `json:"object_refs,omitempty"`
+	ExternalReferences []STIXExternalReference `json:"external_references,omitempty"`
+}
+
+// STIXBundle is a STIX 2.1 bundle representing the discovered names, IP addresses and
+// autonomous systems. Each requests.Output is represented by an observed-data object that
+// references the cyber-observable objects, and the relationships between the observables
+// are represented by relationship objects.
+type STIXBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []*STIXObject `json:"objects"`
+	created string
+	ids     map[string]struct{}
+}
+
+// NewSTIXBundle returns an empty STIXBundle that includes the identity of the producer.
+func NewSTIXBundle(created time.Time) *STIXBundle {
+	b := &STIXBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.New().String(),
+		created: created.UTC().Format(STIXTimeFormat),
+		ids:     make(map[string]struct{}),
+	}
+
+	b.insert(&STIXObject{
+		Type:          "identity",
+		SpecVersion:   stixSpecVersion,
+		ID:            b.identityID(),
+		Created:       b.created,
+		Modified:      b.created,
+		Name:          "OWASP Amass",
+		IdentityClass: "system",
+	})
+	return b
+}
+
+// Add inserts the objects representing the provided Output into the bundle.
+func (b *STIXBundle) Add(out *requests.Output, first, last time.Time) {
+	if out == nil || out.Name == "" {
+		return
+	}
+
+	name := b.observable(&STIXObject{Type: "domain-name", Value: out.Name}, `{"value":`+strconv.Quote(out.Name)+`}`)
+	refs := []string{name}
+	for _, addr := range out.Addresses {
+		if addr.Address == nil {
+			continue
+		}
+
+		iptype := "ipv4-addr"
+		if amassnet.IsIPv6(addr.Address) {
+			iptype = "ipv6-addr"
+		}
+
+		ipstr := addr.Address.String()
+		ip := b.observable(&STIXObject{Type: iptype, Value: ipstr}, `{"value":`+strconv.Quote(ipstr)+`}`)
+		refs = append(refs, ip)
+		b.relationship("resolves-to", name, ip)
+
+		if addr.ASN > 0 {
+			as := b.observable(&STIXObject{
+				Type:   "autonomous-system",
+				Number: addr.ASN,
+				Name:   addr.Description,
+			}, `{"number":`+strconv.Itoa(addr.ASN)+`}`)
+			refs = append(refs, as)
+			b.relationship("belongs-to", ip, as)
+		}
+	}
+
+	var extrefs []STIXExternalReference
+	for _, src := range out.Sources {
+		extrefs = append(extrefs, STIXExternalReference{
+			SourceName:  src,
+			Description: out.Tag,
+		})
+	}
+
+	b.insert(&STIXObject{
+		Type:               "observed-data",
+		SpecVersion:        stixSpecVersion,
+		ID:                 "observed-data--" + uuid.New().String(),
+		Created:            b.created,
+		Modified:           b.created,
+		CreatedByRef:       b.identityID(),
+		FirstObserved:      first.UTC().Format(STIXTimeFormat),
+		LastObserved:       last.UTC().Format(STIXTimeFormat),
+		NumberObserved:     1,
+		ObjectRefs:         stringset.Deduplicate(refs),
+		ExternalReferences: extrefs,
+	})
+}
+
+// Encode writes the bundle to the provided io.Writer as JSON.
+func (b *STIXBundle) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+func (b *STIXBundle) identityID() string {
+	return "identity--" + uuid.NewSHA1(stixSCONamespace, []byte("OWASP Amass")).String()
+}
+
+func (b *STIXBundle) observable(obj *STIXObject, contributing string) string {
+	obj.SpecVersion = stixSpecVersion
+	obj.ID = obj.Type + "--" + uuid.NewSHA1(stixSCONamespace, []byte(contributing)).String()
+
+	b.insert(obj)
+	return obj.ID
+}
+
+func (b *STIXBundle) relationship(rtype, source, target string) {
+	b.insert(&STIXObject{
+		Type:             "relationship",
+		SpecVersion:      stixSpecVersion,
+		ID:               "relationship--" + uuid.NewSHA1(stixSCONamespace, []byte(source+rtype+target)).String(),
+		Created:          b.created,
+		Modified:         b.created,
+		CreatedByRef:     b.identityID(),
+		RelationshipType: rtype,
+		SourceRef:        source,
+		TargetRef:        target,
+	})
+}
+
+func (b *STIXBundle) insert(obj *STIXObject) {
+	if _, found := b.ids[obj.ID]; found {
+		return
+	}
+
+	b.ids[obj.ID] = struct{}{}
+	b.Objects = append(b.Objects, obj)
+}
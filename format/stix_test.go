@@ -0,0 +1,156 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// Required properties taken from the STIX 2.1 JSON schemas for each object type
+var stixRequired = map[string][]string{
+	"identity":          {"type", "spec_version", "id", "created", "modified", "name"},
+	"observed-data":     {"type", "spec_version", "id", "created", "modified", "first_observed", "last_observed", "number_observed", "object_refs"},
+	"relationship":      {"type", "spec_version", "id", "created", "modified", "relationship_type", "source_ref", "target_ref"},
+	"domain-name":       {"type", "id", "value"},
+	"ipv4-addr":         {"type", "id", "value"},
+	"ipv6-addr":         {"type", "id", "value"},
+	"autonomous-system": {"type", "id", "number"},
+}
+
+var (
+	stixIDRE        = regexp.MustCompile(`^([a-z][a-z0-9-]+[a-z0-9])--[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+	stixTimestampRE = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z$`)
+)
+
+func TestSTIXBundle(t *testing.T) {
+	now := time.Now()
+	b := NewSTIXBundle(now)
+
+	outputs := []*requests.Output{
+		{
+			Name:   "www.owasp.org",
+			Domain: "owasp.org",
+			Addresses: []requests.AddressInfo{
+				{Address: net.ParseIP("104.22.27.77"), CIDRStr: "104.22.16.0/20", ASN: 13335, Description: "CLOUDFLARENET"},
+				{Address: net.ParseIP("2606:4700:10::6816:1b4d"), CIDRStr: "2606:4700:10::/44", ASN: 13335, Description: "CLOUDFLARENET"},
+			},
+			Tag:     requests.API,
+			Sources: []string{"Umbrella", "NetworksDB"},
+		},
+		{
+			Name:      "owasp.org",
+			Domain:    "owasp.org",
+			Addresses: []requests.AddressInfo{{Address: net.ParseIP("104.22.27.77"), ASN: 13335, Description: "CLOUDFLARENET"}},
+			Tag:       requests.DNS,
+			Sources:   []string{"DNS"},
+		},
+	}
+	for _, out := range outputs {
+		b.Add(out, now.Add(-time.Minute), now)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := b.Encode(buf); err != nil {
+		t.Fatalf("Failed to encode the bundle: %v", err)
+	}
+
+	var bundle struct {
+		Type    string                   `json:"type"`
+		ID      string                   `json:"id"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("Failed to decode the bundle: %v", err)
+	}
+	if bundle.Type != "bundle" || !stixIDRE.MatchString(bundle.ID) {
+		t.Errorf("The bundle type or identifier is invalid")
+	}
+
+	ids := make(map[string]string)
+	counts := make(map[string]int)
+	for _, obj := range bundle.Objects {
+		typ, _ := obj["type"].(string)
+		id, _ := obj["id"].(string)
+
+		required, ok := stixRequired[typ]
+		if !ok {
+			t.Errorf("Unexpected object type %s", typ)
+			continue
+		}
+		for _, prop := range required {
+			if _, found := obj[prop]; !found {
+				t.Errorf("The %s object %s is missing the required %s property", typ, id, prop)
+			}
+		}
+		if m := stixIDRE.FindStringSubmatch(id); m == nil || m[1] != typ {
+			t.Errorf("The %s object has an invalid identifier: %s", typ, id)
+		}
+		if v, found := obj["spec_version"]; found && v != "2.1" {
+			t.Errorf("The %s object has an invalid spec_version: %v", typ, v)
+		}
+		for _, prop := range []string{"created", "modified", "first_observed", "last_observed"} {
+			if v, found := obj[prop]; found && !stixTimestampRE.MatchString(v.(string)) {
+				t.Errorf("The %s object has an invalid %s timestamp: %v", typ, prop, v)
+			}
+		}
+		if _, found := ids[id]; found {
+			t.Errorf("The identifier %s was used more than once", id)
+		}
+
+		ids[id] = typ
+		counts[typ]++
+	}
+
+	// Check that all the references are resolved within the bundle
+	for _, obj := range bundle.Objects {
+		var refs []string
+		for _, prop := range []string{"created_by_ref", "source_ref", "target_ref"} {
+			if v, found := obj[prop]; found {
+				refs = append(refs, v.(string))
+			}
+		}
+		if v, found := obj["object_refs"]; found {
+			for _, ref := range v.([]interface{}) {
+				refs = append(refs, ref.(string))
+			}
+		}
+		for _, ref := range refs {
+			if _, found := ids[ref]; !found {
+				t.Errorf("The reference %s was not found in the bundle", ref)
+			}
+		}
+	}
+
+	expected := map[string]int{
+		"identity":          1,
+		"observed-data":     2,
+		"domain-name":       2,
+		"ipv4-addr":         1,
+		"ipv6-addr":         1,
+		"autonomous-system": 1,
+		"relationship":      5,
+	}
+	for typ, num := range expected {
+		if counts[typ] != num {
+			t.Errorf("Expected %d %s objects, got %d", num, typ, counts[typ])
+		}
+	}
+
+	for _, obj := range bundle.Objects {
+		if obj["type"] != "observed-data" {
+			continue
+		}
+		if refs, ok := obj["external_references"].([]interface{}); !ok || len(refs) == 0 {
+			t.Errorf("The observed-data object is missing the source attribution")
+		}
+	}
+}
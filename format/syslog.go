@@ -0,0 +1,281 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+const (
+	// The number of messages waiting to be written before new findings are dropped
+	syslogQueueSize    = 1024
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second
+	// The time waited before connecting again after the server could not be reached
+	syslogRetryInterval = 30 * time.Second
+	// The private enterprise number used in the structured data ID, reserved for documentation by RFC 5612
+	syslogEnterpriseID = "32473"
+)
+
+// The sockets tried, in order, when messages are sent to the local syslog daemon.
+var syslogLocalSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogMessage returns the RFC 5424 message describing the enumeration output. The MSGID is
+// the tag of the data source type, and the structured data carries the name, its addresses
+// and the data sources that discovered it.
+func SyslogMessage(cfg *config.SyslogConfig, out *requests.Output, uuid, hostname string, ts time.Time) string {
+	var addrs []string
+	for _, a := range out.Addresses {
+		if a.Address != nil {
+			addrs = append(addrs, a.Address.String())
+		}
+	}
+
+	msgid := "-"
+	if out.Tag != "" {
+		msgid = out.Tag
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[amass@" + syslogEnterpriseID)
+	for _, param := range [][2]string{
+		{"name", out.Name},
+		{"domain", out.Domain},
+		{"tag", out.Tag},
+		{"sources", strings.Join(out.Sources, ",")},
+		{"addresses", strings.Join(addrs, ",")},
+		{"enum_uuid", uuid},
+	} {
+		if param[1] != "" {
+			sd.WriteString(" " + param[0] + "=\"" + syslogEscape(param[1]) + "\"")
+		}
+	}
+	sd.WriteString("]")
+
+	msg := out.Name
+	if len(addrs) > 0 {
+		msg += " " + strings.Join(addrs, ",")
+	}
+	if len(out.Sources) > 0 {
+		msg = "[" + strings.Join(out.Sources, ", ") + "] " + msg
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s", cfg.Facility*8+cfg.Severity,
+		ts.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, cfg.AppName, os.Getpid(), msgid, sd.String(), msg)
+}
+
+// syslogEscape escapes the characters that RFC 5424 does not allow within structured data values.
+func syslogEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// SyslogSink sends the findings to the local syslog daemon or to a remote collector over UDP,
+// TCP or TLS. The messages are queued by Send and written from a separate goroutine, and are
+// dropped while the queue is full or the server cannot be reached, so the enumeration is never
+// held up by the sink.
+type SyslogSink struct {
+	cfg      *config.SyslogConfig
+	uuid     string
+	hostname string
+	log      *log.Logger
+	msgs     chan string
+	finished chan struct{}
+	conn     net.Conn
+	stream   bool
+	retry    time.Time
+	down     bool
+	sent     int64
+	dropped  int64
+}
+
+// NewSyslogSink returns a SyslogSink that sends the findings of the enumeration identified by
+// the UUID. Failures to reach the server are written to the logger.
+func NewSyslogSink(cfg *config.SyslogConfig, uuid string, logger *log.Logger) *SyslogSink {
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	hostname, _ := os.Hostname()
+	s := &SyslogSink{
+		cfg:      cfg,
+		uuid:     uuid,
+		hostname: hostname,
+		log:      logger,
+		msgs:     make(chan string, syslogQueueSize),
+		finished: make(chan struct{}),
+	}
+
+	go s.processMessages()
+	return s
+}
+
+// Send queues the message describing the output, or drops it when the queue is full.
+func (s *SyslogSink) Send(out *requests.Output) {
+	select {
+	case s.msgs <- SyslogMessage(s.cfg, out, s.uuid, s.hostname, time.Now()):
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Close writes the messages remaining in the queue and returns an error when
+// some of the messages could not be sent.
+func (s *SyslogSink) Close() error {
+	close(s.msgs)
+	<-s.finished
+
+	if dropped := atomic.LoadInt64(&s.dropped); dropped > 0 {
+		return fmt.Errorf("%d of %d syslog messages could not be sent to %s", dropped,
+			dropped+atomic.LoadInt64(&s.sent), s.cfg)
+	}
+	return nil
+}
+
+// Sent returns the number of messages written to the server.
+func (s *SyslogSink) Sent() int {
+	return int(atomic.LoadInt64(&s.sent))
+}
+
+func (s *SyslogSink) processMessages() {
+	defer close(s.finished)
+
+	for msg := range s.msgs {
+		if err := s.write(msg); err != nil {
+			atomic.AddInt64(&s.dropped, 1)
+			continue
+		}
+		atomic.AddInt64(&s.sent, 1)
+	}
+
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// write sends the message, connecting first when necessary. After a failure the connection
+// is only attempted again once the retry interval has elapsed.
+func (s *SyslogSink) write(msg string) error {
+	if s.conn == nil {
+		if s.down && time.Now().Before(s.retry) {
+			return errors.New("the syslog server is unreachable")
+		}
+		if err := s.connect(); err != nil {
+			s.fail(err)
+			return err
+		}
+		if s.down {
+			s.log.Printf("Syslog: reconnected to %s", s.cfg)
+			s.down = false
+		}
+	}
+
+	// Stream transports need the messages to be framed, using octet counting for the remote
+	// collectors (RFC 6587) and a trailing newline for the local daemon
+	data := msg
+	if s.stream && s.cfg.Network == "unix" {
+		data += "\n"
+	} else if s.stream {
+		data = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := s.conn.Write([]byte(data)); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail logs the first failure while the server is unreachable and schedules the next attempt.
+func (s *SyslogSink) fail(err error) {
+	if !s.down {
+		s.log.Printf("Syslog: %s is unreachable, findings will be dropped until it responds: %v", s.cfg, err)
+	}
+
+	s.down = true
+	s.retry = time.Now().Add(syslogRetryInterval)
+}
+
+func (s *SyslogSink) connect() error {
+	d := &net.Dialer{Timeout: syslogDialTimeout}
+
+	var err error
+	switch s.cfg.Network {
+	case "udp":
+		s.conn, err = d.Dial("udp", s.cfg.Address)
+	case "tcp":
+		s.conn, err = d.Dial("tcp", s.cfg.Address)
+		s.stream = true
+	case "tls":
+		var tc *tls.Config
+		tc, err = s.tlsConfig()
+		if err != nil {
+			return err
+		}
+
+		var conn *tls.Conn
+		// Avoid storing a nil *tls.Conn within the interface when the handshake fails
+		if conn, err = tls.DialWithDialer(d, "tcp", s.cfg.Address, tc); err == nil {
+			s.conn = conn
+		}
+		s.stream = true
+	default:
+		paths := syslogLocalSockets
+		if s.cfg.Address != "" {
+			paths = []string{s.cfg.Address}
+		}
+		// The local daemons listen on datagram sockets, and some of them on stream sockets
+		for _, network := range []string{"unixgram", "unix"} {
+			for _, path := range paths {
+				if s.conn, err = d.Dial(network, path); err == nil {
+					s.stream = network == "unix"
+					return nil
+				}
+			}
+		}
+	}
+	return err
+}
+
+func (s *SyslogSink) tlsConfig() (*tls.Config, error) {
+	host, _, _ := net.SplitHostPort(s.cfg.Address)
+	tc := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if s.cfg.CAFile == "" {
+		return tc, nil
+	}
+
+	pem, err := ioutil.ReadFile(s.cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA file: %v", err)
+	}
+
+	tc.RootCAs = x509.NewCertPool()
+	if !tc.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("the CA file %s contains no certificates", s.cfg.CAFile)
+	}
+	return tc, nil
+}
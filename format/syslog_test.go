@@ -0,0 +1,150 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func syslogTestOutput() *requests.Output {
+	return &requests.Output{
+		Name:   "www.owasp.org",
+		Domain: "owasp.org",
+		Addresses: []requests.AddressInfo{
+			{Address: net.ParseIP("104.22.27.77")},
+			{Address: net.ParseIP("104.22.26.77")},
+		},
+		Tag:     requests.CERT,
+		Sources: []string{"crtsh", "Quo\"te]"},
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	cfg := &config.SyslogConfig{Facility: 16, Severity: 6, AppName: "amass"}
+	ts := time.Date(2022, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	msg := SyslogMessage(cfg, syslogTestOutput(), "uuid", "scanner", ts)
+	if !strings.HasPrefix(msg, "<134>1 2022-03-01T12:30:00.000000Z scanner amass ") {
+		t.Errorf("Unexpected message header: %s", msg)
+	}
+	for _, want := range []string{
+		" cert [amass@32473 ",
+		`name="www.owasp.org"`,
+		`addresses="104.22.27.77,104.22.26.77"`,
+		`sources="crtsh,Quo\"te\]"`,
+		`enum_uuid="uuid"]`,
+		"] [crtsh, Quo\"te]] www.owasp.org 104.22.27.77,104.22.26.77",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("The message did not contain %s: %s", want, msg)
+		}
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer pc.Close()
+
+	cfg := &config.SyslogConfig{Network: "udp", Address: pc.LocalAddr().String(), Facility: 1, Severity: 6, AppName: "amass"}
+	sink := NewSyslogSink(cfg, "uuid", nil)
+	sink.Send(syslogTestOutput())
+	if err := sink.Close(); err != nil || sink.Sent() != 1 {
+		t.Fatalf("Failed to send the message: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("The message was not received: %v", err)
+	}
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<14>1 ") || !strings.Contains(msg, "www.owasp.org") {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var msgs []string
+		r := bufio.NewReader(conn)
+		for {
+			// Each message is preceded by its length in octets
+			l, err := r.ReadString(' ')
+			if err != nil {
+				break
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(l))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				break
+			}
+			msgs = append(msgs, string(msg))
+		}
+		received <- msgs
+	}()
+
+	cfg := &config.SyslogConfig{Network: "tcp", Address: ln.Addr().String(), Facility: 1, Severity: 6, AppName: "amass"}
+	sink := NewSyslogSink(cfg, "uuid", nil)
+	sink.Send(syslogTestOutput())
+	sink.Send(syslogTestOutput())
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to send the messages: %v", err)
+	}
+
+	msgs := <-received
+	if len(msgs) != 2 || !strings.HasSuffix(msgs[1], "104.22.26.77") {
+		t.Errorf("The framed messages were not received: %v", msgs)
+	}
+}
+
+func TestSyslogSinkUnreachable(t *testing.T) {
+	// Find a port that nothing is listening on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := &config.SyslogConfig{Network: "tcp", Address: addr, Facility: 1, Severity: 6, AppName: "amass"}
+	sink := NewSyslogSink(cfg, "uuid", nil)
+
+	start := time.Now()
+	for i := 0; i < syslogQueueSize*2; i++ {
+		sink.Send(syslogTestOutput())
+	}
+	if err := sink.Close(); err == nil || sink.Sent() != 0 {
+		t.Errorf("The failures to reach the server were not reported")
+	}
+	if time.Since(start) > syslogDialTimeout {
+		t.Errorf("The unreachable server held up the sink for %v", time.Since(start))
+	}
+}
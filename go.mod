@@ -10,13 +10,13 @@ require (
 	github.com/caffix/queue v0.1.3
 	github.com/caffix/resolve v0.5.4
 	github.com/caffix/service v0.2.3
-	github.com/caffix/stringset v0.1.0
 	github.com/cayleygraph/quad v1.2.4
 	github.com/chromedp/cdproto v0.0.0-20220408044303-8559a4e76b35 // indirect
 	github.com/chromedp/chromedp v0.8.0 // indirect
 	github.com/cjoudrey/gluaurl v0.0.0-20161028222611-31cbb9bef199
 	github.com/cloudflare/cloudflare-go v0.37.0
 	github.com/dghubble/go-twitter v0.0.0-20220413154426-14d8abde2e80
+	github.com/dgraph-io/badger v1.6.2
 	github.com/dgryski/go-farm v0.0.0-20200201041132-a6ae2369ad13 // indirect
 	github.com/fatih/color v1.13.0
 	github.com/fofapro/fofa-go v0.0.0-20200317042037-c0caee09013d
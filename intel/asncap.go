@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package intel
+
+import (
+	"bytes"
+	"net"
+	"sort"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+)
+
+// SkippedNetblock is a netblock of an ASN that was not expanded into addresses, since it
+// did not fit within the max_asn_addrs option.
+type SkippedNetblock struct {
+	ASN       int
+	Netblock  string
+	Addresses int
+}
+
+// SkippedNetblocks returns the netblocks of the ASNs that were left out of the collection.
+func (c *Collection) SkippedNetblocks() []*SkippedNetblock {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.skipped
+}
+
+// capASNNetblocks returns the netblocks of an ASN that fit within the maximum number of
+// addresses, taking the most specific netblocks first, along with the netblocks left out.
+// The IPv6 netblocks are never expanded, so they are returned without being counted.
+func capASNNetblocks(netblocks []*net.IPNet, max int) ([]*net.IPNet, []*net.IPNet) {
+	if max <= 0 {
+		return netblocks, nil
+	}
+
+	sorted := make([]*net.IPNet, len(netblocks))
+	copy(sorted, netblocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := sorted[i].Mask.Size()
+		b, _ := sorted[j].Mask.Size()
+		if a != b {
+			return a > b
+		}
+		return bytes.Compare(sorted[i].IP.To16(), sorted[j].IP.To16()) < 0
+	})
+
+	var total int
+	var kept, skipped []*net.IPNet
+	for _, cidr := range sorted {
+		if amassnet.IsIPv6(cidr.IP.Mask(cidr.Mask)) {
+			kept = append(kept, cidr)
+			continue
+		}
+
+		if n := netblockHosts(cidr); total+n <= max {
+			total += n
+			kept = append(kept, cidr)
+			continue
+		}
+		skipped = append(skipped, cidr)
+	}
+	return kept, skipped
+}
+
+// netblockHosts returns the number of addresses AllHosts expands the IPv4 netblock into.
+func netblockHosts(cidr *net.IPNet) int {
+	ones, bits := cidr.Mask.Size()
+
+	size := 1 << uint(bits-ones)
+	if size > 2 {
+		// The network and broadcast addresses are not expanded
+		size -= 2
+	}
+	return size
+}
@@ -0,0 +1,32 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package intel
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestCapASNNetblocks(t *testing.T) {
+	var netblocks []*net.IPNet
+	for _, cidr := range []string{"192.0.2.0/24", "198.51.100.0/28", "2001:db8::/32", "203.0.113.0/26", "198.51.100.64/28"} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		netblocks = append(netblocks, ipnet)
+	}
+
+	if kept, skipped := capASNNetblocks(netblocks, 0); len(kept) != len(netblocks) || len(skipped) != 0 {
+		t.Errorf("Netblocks were skipped without a maximum")
+	}
+
+	// The /28s and the /26 hold 14 + 14 + 62 addresses, which leaves no room for the /24
+	kept, skipped := capASNNetblocks(netblocks, 100)
+	if got := fmt.Sprint(kept); got != "[2001:db8::/32 198.51.100.0/28 198.51.100.64/28 203.0.113.0/26]" {
+		t.Errorf("Unexpected netblocks kept: %s", got)
+	}
+	if got := fmt.Sprint(skipped); got != "[192.0.2.0/24]" {
+		t.Errorf("Unexpected netblocks skipped: %s", got)
+	}
+}
@@ -16,11 +16,11 @@ import (
 	"github.com/aokimio/Amass/v3/datasrcs"
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/aokimio/Amass/v3/systems"
 	"github.com/caffix/pipeline"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
-	"github.com/caffix/stringset"
 	bf "github.com/tylertreat/BoomFilters"
 	"golang.org/x/net/publicsuffix"
 )
@@ -42,6 +42,7 @@ type Collection struct {
 	doneAlreadyClosed bool
 	filter            *bf.StableBloomFilter
 	timeChan          chan time.Time
+	skipped           []*SkippedNetblock
 }
 
 // NewCollection returns an initialized Collection object that has not been started yet.
@@ -191,39 +192,61 @@ func (c *Collection) asnsToCIDRs() []*net.IPNet {
 	cidrSet := stringset.New()
 	defer cidrSet.Close()
 
+	var missing []int
 	for _, asn := range c.Config.ASNs {
-		req := c.Sys.Cache().ASNSearch(asn)
-
-		if req == nil {
-			systems.PopulateCache(c.ctx, asn, c.Sys)
-			req = c.Sys.Cache().ASNSearch(asn)
-			if req == nil {
-				continue
-			}
+		if c.Sys.Cache().ASNSearch(asn) == nil {
+			missing = append(missing, asn)
 		}
-
-		cidrSet.InsertMany(req.Netblocks...)
 	}
-
-	filter := bf.NewDefaultStableBloomFilter(1000000, 0.01)
-	defer filter.Reset()
+	systems.PopulateCacheForASNs(c.ctx, missing, c.Sys)
 
 	// Do not return CIDRs that are already in the config
 	for _, cidr := range c.Config.CIDRs {
-		filter.Add([]byte(cidr.String()))
+		cidrSet.Insert(cidr.String())
 	}
 
-	for _, netblock := range cidrSet.Slice() {
-		_, ipnet, err := net.ParseCIDR(netblock)
+	for _, asn := range c.Config.ASNs {
+		req := c.Sys.Cache().ASNSearch(asn)
+		if req == nil {
+			continue
+		}
+
+		var netblocks []*net.IPNet
+		for _, netblock := range req.Netblocks {
+			_, ipnet, err := net.ParseCIDR(netblock)
 
-		if err == nil && !filter.Test([]byte(ipnet.String())) {
-			cidrs = append(cidrs, ipnet)
+			if err == nil && !cidrSet.Has(ipnet.String()) {
+				cidrSet.Insert(ipnet.String())
+				netblocks = append(netblocks, ipnet)
+			}
 		}
+
+		kept, skipped := capASNNetblocks(netblocks, c.Config.MaxASNAddrs)
+		cidrs = append(cidrs, kept...)
+		c.skipNetblocks(asn, skipped)
 	}
 
 	return cidrs
 }
 
+// skipNetblocks records the netblocks of the ASN that were left out due to the max_asn_addrs option.
+func (c *Collection) skipNetblocks(asn int, netblocks []*net.IPNet) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, cidr := range netblocks {
+		n := netblockHosts(cidr)
+
+		c.Config.Log.Printf("AS%d: Skipping the %d addresses of %s, which exceed the maximum of %d addresses per ASN",
+			asn, n, cidr.String(), c.Config.MaxASNAddrs)
+		c.skipped = append(c.skipped, &SkippedNetblock{
+			ASN:       asn,
+			Netblock:  cidr.String(),
+			Addresses: n,
+		})
+	}
+}
+
 // ReverseWhois returns domain names that are related to the domains provided
 func (c *Collection) ReverseWhois() error {
 	if err := c.Config.CheckSettings(); err != nil {
@@ -258,6 +281,8 @@ loop:
 		select {
 		case <-c.done:
 			break loop
+		case <-c.Sys.Context().Done():
+			break loop
 		case l := <-c.timeChan:
 			if l.After(last) {
 				last = l
@@ -277,11 +302,17 @@ func (c *Collection) collect(req *requests.WhoisRequest) {
 
 	for _, name := range req.NewDomains {
 		if d, err := publicsuffix.EffectiveTLDPlusOne(name); err == nil && !c.filter.TestAndAdd([]byte(d)) {
+			// The other data sources that already reported the domain are credited as well
+			sources := c.Sys.DomainFilter().Sources(name)
+			if len(sources) == 0 {
+				sources = []string{req.Source}
+			}
+
 			c.Output <- &requests.Output{
 				Name:    d,
 				Domain:  d,
 				Tag:     req.Tag,
-				Sources: []string{req.Source},
+				Sources: sources,
 			}
 		}
 	}
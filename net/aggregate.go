@@ -0,0 +1,112 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package net
+
+import (
+	"math/big"
+	"net"
+	"sort"
+)
+
+type addrRange struct {
+	first *big.Int
+	last  *big.Int
+}
+
+// AggregateCIDRs returns the minimal set of netblocks covering the provided netblocks.
+// Contained and adjacent netblocks are merged, and IPv4 and IPv6 netblocks are aggregated
+// separately. The IPv4 netblocks are returned first, and each family is sorted by address.
+func AggregateCIDRs(cidrs []*net.IPNet) []*net.IPNet {
+	var v4, v6 []*addrRange
+
+	for _, cidr := range cidrs {
+		if cidr == nil {
+			continue
+		}
+
+		ones, bits := cidr.Mask.Size()
+		if ip := cidr.IP.To4(); ip != nil && bits == 32 {
+			v4 = append(v4, netToRange(ip.Mask(cidr.Mask), ones, bits))
+		} else if ip := cidr.IP.To16(); ip != nil && bits == 128 {
+			v6 = append(v6, netToRange(ip.Mask(cidr.Mask), ones, bits))
+		}
+	}
+
+	var results []*net.IPNet
+	for _, r := range mergeRanges(v4) {
+		results = append(results, rangeToCIDRs(r, 32)...)
+	}
+	for _, r := range mergeRanges(v6) {
+		results = append(results, rangeToCIDRs(r, 128)...)
+	}
+	return results
+}
+
+func netToRange(ip net.IP, ones, bits int) *addrRange {
+	first := new(big.Int).SetBytes(ip)
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	last := new(big.Int).Add(first, size)
+
+	return &addrRange{
+		first: first,
+		last:  last.Sub(last, big.NewInt(1)),
+	}
+}
+
+func mergeRanges(ranges []*addrRange) []*addrRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Cmp(ranges[j].first) < 0
+	})
+
+	one := big.NewInt(1)
+	merged := []*addrRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		cur := merged[len(merged)-1]
+		// Check if the range overlaps with or directly follows the current range
+		if next := new(big.Int).Add(cur.last, one); r.first.Cmp(next) <= 0 {
+			if r.last.Cmp(cur.last) > 0 {
+				cur.last = r.last
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func rangeToCIDRs(r *addrRange, bits int) []*net.IPNet {
+	var cidrs []*net.IPNet
+
+	one := big.NewInt(1)
+	first := new(big.Int).Set(r.first)
+	for first.Cmp(r.last) <= 0 {
+		// Find the largest block aligned on the first address
+		host := 0
+		for host < bits && first.Bit(host) == 0 {
+			host++
+		}
+		// Shrink the block until it fits within the range
+		for host > 0 {
+			last := new(big.Int).Lsh(one, uint(host))
+			last.Add(last, first)
+			last.Sub(last, one)
+			if last.Cmp(r.last) <= 0 {
+				break
+			}
+			host--
+		}
+
+		cidrs = append(cidrs, &net.IPNet{
+			IP:   intToIP(first, bits),
+			Mask: net.CIDRMask(bits-host, bits),
+		})
+		first.Add(first, new(big.Int).Lsh(one, uint(host)))
+	}
+	return cidrs
+}
@@ -0,0 +1,77 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package net
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestAggregateCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "contained",
+			input:    []string{"192.168.0.0/16", "192.168.1.0/24", "192.168.200.128/25"},
+			expected: []string{"192.168.0.0/16"},
+		},
+		{
+			name:     "adjacent",
+			input:    []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/23"},
+			expected: []string{"10.0.0.0/22"},
+		},
+		{
+			name:     "overlapping unaligned",
+			input:    []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.1.128/26"},
+			expected: []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.1.128/26"},
+		},
+		{
+			name:     "adjacent but not mergeable",
+			input:    []string{"10.0.1.0/24", "10.0.2.0/24"},
+			expected: []string{"10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:     "disjoint",
+			input:    []string{"8.8.8.0/24", "1.1.1.0/24"},
+			expected: []string{"1.1.1.0/24", "8.8.8.0/24"},
+		},
+		{
+			name:     "duplicates",
+			input:    []string{"72.237.4.0/24", "72.237.4.0/24"},
+			expected: []string{"72.237.4.0/24"},
+		},
+		{
+			name:     "ipv6 adjacent",
+			input:    []string{"2001:db8::/33", "2001:db8:8000::/33", "2001:db8:1::/48"},
+			expected: []string{"2001:db8::/32"},
+		},
+		{
+			name:     "families kept separate",
+			input:    []string{"2001:db8::/32", "0.0.0.0/1", "128.0.0.0/1"},
+			expected: []string{"0.0.0.0/0", "2001:db8::/32"},
+		},
+	}
+
+	for _, test := range tests {
+		var cidrs []*net.IPNet
+		for _, c := range test.input {
+			if _, ipnet, err := net.ParseCIDR(c); err == nil {
+				cidrs = append(cidrs, ipnet)
+			}
+		}
+
+		var got []string
+		for _, ipnet := range AggregateCIDRs(cidrs) {
+			got = append(got, ipnet.String())
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
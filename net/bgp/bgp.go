@@ -0,0 +1,156 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+// The states of a prefix in the global BGP table.
+const (
+	Announced    = "announced"
+	NotAnnounced = "not_announced"
+)
+
+// Validator checks prefixes against the global BGP table.
+type Validator interface {
+	// String returns the name of the validator
+	String() string
+
+	// Announced returns true when the prefix is currently seen in the global BGP table
+	Announced(ctx context.Context, prefix *net.IPNet) (bool, error)
+}
+
+var (
+	validatorsLock sync.Mutex
+	validators     = make(map[string]func(cfg *config.BGPValidationConfig) Validator)
+)
+
+func init() {
+	RegisterValidator("ripestat", func(cfg *config.BGPValidationConfig) Validator { return NewRIPEstat(cfg.URL) })
+}
+
+// RegisterValidator makes the validator available for selection by name
+// in the validator setting of the bgp_validation configuration section.
+func RegisterValidator(name string, f func(cfg *config.BGPValidationConfig) Validator) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+
+	validators[strings.ToLower(name)] = f
+}
+
+// Checker validates the prefixes with the configured validator, checking each prefix once and
+// performing no more than the configured number of checks, at the configured rate.
+type Checker struct {
+	sync.Mutex
+	validator Validator
+	max       int
+	checks    int
+	interval  time.Duration
+	next      time.Time
+	cache     map[string]string
+}
+
+// NewChecker returns the Checker for the validator selected in the configuration, or nil
+// when BGP validation has not been configured.
+func NewChecker(cfg *config.Config) (*Checker, error) {
+	bc := cfg.BGPValidation
+	if bc == nil {
+		return nil, nil
+	}
+
+	validatorsLock.Lock()
+	f, found := validators[bc.Validator]
+	validatorsLock.Unlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown BGP validator: %s", bc.Validator)
+	}
+	return NewCheckerWithValidator(f(bc), bc.MaxChecks, bc.Rate), nil
+}
+
+// NewCheckerWithValidator returns a Checker that performs at most max checks using the
+// validator, and no more than rate checks each second.
+func NewCheckerWithValidator(v Validator, max, rate int) *Checker {
+	if rate <= 0 {
+		rate = config.DefaultBGPChecksPerSecond
+	}
+
+	return &Checker{
+		validator: v,
+		max:       max,
+		interval:  time.Second / time.Duration(rate),
+		cache:     make(map[string]string),
+	}
+}
+
+// Check returns the state of the prefix in the global BGP table and true when this is the first
+// check of the prefix. An empty state is returned when the prefix could not be checked, such as
+// after the limit on the number of checks has been reached.
+func (c *Checker) Check(ctx context.Context, cidr string) (string, bool) {
+	_, prefix, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", false
+	}
+
+	key := prefix.String()
+	c.Lock()
+	if state, found := c.cache[key]; found {
+		c.Unlock()
+		return state, false
+	}
+	if c.max > 0 && c.checks >= c.max {
+		c.Unlock()
+		return "", false
+	}
+	// Claim the prefix and reserve the time of the check, so concurrent callers neither check
+	// the prefix again nor exceed the rate
+	c.cache[key] = ""
+	c.checks++
+	now := time.Now()
+	at := c.next
+	if at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.interval)
+	c.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", false
+		case <-t.C:
+		}
+	}
+
+	var state string
+	if announced, err := c.validator.Announced(ctx, prefix); err == nil && announced {
+		state = Announced
+	} else if err == nil {
+		state = NotAnnounced
+	}
+
+	c.Lock()
+	c.cache[key] = state
+	c.Unlock()
+	return state, true
+}
+
+// Checks returns the number of prefixes sent to the validator.
+func (c *Checker) Checks() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.checks
+}
@@ -0,0 +1,111 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+type testValidator struct {
+	announced map[string]bool
+	calls     int32
+}
+
+func (v *testValidator) String() string { return "test" }
+
+func (v *testValidator) Announced(ctx context.Context, prefix *net.IPNet) (bool, error) {
+	atomic.AddInt32(&v.calls, 1)
+	if prefix.String() == "192.0.2.0/24" {
+		return false, fmt.Errorf("the validator failed")
+	}
+	return v.announced[prefix.String()], nil
+}
+
+func TestChecker(t *testing.T) {
+	v := &testValidator{announced: map[string]bool{"104.16.0.0/13": true}}
+	c := NewCheckerWithValidator(v, 3, 20)
+
+	if state, first := c.Check(context.Background(), "104.16.0.0/13"); state != Announced || !first {
+		t.Errorf("The announced prefix was not reported: %s %v", state, first)
+	}
+	// The prefix is only sent to the validator once, in its canonical form
+	if state, first := c.Check(context.Background(), "104.16.1.0/13"); state != Announced || first {
+		t.Errorf("The cached state was not returned: %s %v", state, first)
+	}
+
+	start := time.Now()
+	if state, _ := c.Check(context.Background(), "198.51.100.0/24"); state != NotAnnounced {
+		t.Errorf("The stale prefix was not reported: %s", state)
+	}
+	if state, _ := c.Check(context.Background(), "192.0.2.0/24"); state != "" {
+		t.Errorf("A state was reported when the validator failed: %s", state)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("The checks were not rate limited: %v", elapsed)
+	}
+
+	if state, first := c.Check(context.Background(), "203.0.113.0/24"); state != "" || first {
+		t.Errorf("The prefix was checked beyond the limit: %s %v", state, first)
+	}
+	if calls := atomic.LoadInt32(&v.calls); calls != 3 || c.Checks() != 3 {
+		t.Errorf("Unexpected number of checks: %d calls, %d checks", calls, c.Checks())
+	}
+	if state, first := c.Check(context.Background(), "not a prefix"); state != "" || first {
+		t.Errorf("The invalid prefix was checked")
+	}
+}
+
+func TestRIPEstat(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/data/prefix-overview/data.json" {
+			nethttp.NotFound(w, r)
+			return
+		}
+
+		switch r.URL.Query().Get("resource") {
+		case "104.16.0.0/13":
+			fmt.Fprint(w, `{"status":"ok","data":{"announced":true,"resource":"104.16.0.0/13"}}`)
+		case "198.51.100.0/24":
+			fmt.Fprint(w, `{"status":"ok","data":{"announced":false,"resource":"198.51.100.0/24"}}`)
+		default:
+			fmt.Fprint(w, `{"status":"error","messages":[["error","invalid resource"]]}`)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.NewConfig()
+	cfg.BGPValidation = &config.BGPValidationConfig{Validator: "ripestat", URL: srv.URL, MaxChecks: 10, Rate: 100}
+	c, err := NewChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the checker: %v", err)
+	}
+
+	for prefix, expected := range map[string]string{
+		"104.16.0.0/13":   Announced,
+		"198.51.100.0/24": NotAnnounced,
+		"192.0.2.0/24":    "",
+	} {
+		if state, _ := c.Check(context.Background(), prefix); state != expected {
+			t.Errorf("Unexpected state for %s: got %q, expected %q", prefix, state, expected)
+		}
+	}
+
+	cfg.BGPValidation.Validator = "unknown"
+	if _, err := NewChecker(cfg); err == nil {
+		t.Errorf("The unknown validator was accepted")
+	}
+	if c, err := NewChecker(config.NewConfig()); c != nil || err != nil {
+		t.Errorf("A checker was returned without the configuration")
+	}
+}
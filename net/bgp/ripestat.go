@@ -0,0 +1,60 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bgp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/aokimio/Amass/v3/net/http"
+)
+
+const ripestatBaseURL = "https://stat.ripe.net"
+
+// RIPEstat checks prefixes using the prefix overview of the RIPEstat Data API, which reports
+// whether the prefix is seen by the RIS route collectors.
+type RIPEstat struct {
+	baseURL string
+}
+
+// NewRIPEstat returns the Validator for the RIPEstat Data API, sending the requests to
+// the base URL when one is provided.
+func NewRIPEstat(baseURL string) *RIPEstat {
+	if baseURL == "" {
+		baseURL = ripestatBaseURL
+	}
+	return &RIPEstat{baseURL: baseURL}
+}
+
+// String implements the Validator interface.
+func (r *RIPEstat) String() string {
+	return "ripestat"
+}
+
+// Announced implements the Validator interface.
+func (r *RIPEstat) Announced(ctx context.Context, prefix *net.IPNet) (bool, error) {
+	u := r.baseURL + "/data/prefix-overview/data.json?sourceapp=amass&resource=" + url.QueryEscape(prefix.String())
+	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Announced *bool `json:"announced"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(page), &resp); err != nil {
+		return false, err
+	}
+	if resp.Status != "ok" || resp.Data.Announced == nil {
+		return false, fmt.Errorf("%s: the response for %s did not include the announcement status", r.String(), prefix)
+	}
+	return *resp.Data.Announced, nil
+}
@@ -0,0 +1,322 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+)
+
+// DefaultPort is the port of the Bolt listener when the URI does not provide one.
+const DefaultPort = "7687"
+
+// The message tags of the Bolt protocol.
+const (
+	msgHello   byte = 0x01
+	msgGoodbye byte = 0x02
+	msgReset   byte = 0x0F
+	msgRun     byte = 0x10
+	msgBegin   byte = 0x11
+	msgCommit  byte = 0x12
+	msgPull    byte = 0x3F
+	msgSuccess byte = 0x70
+	msgRecord  byte = 0x71
+	msgIgnored byte = 0x7E
+	msgFailure byte = 0x7F
+)
+
+const (
+	// The largest chunk of a message, which is limited by its 16 bit size
+	maxChunkSize = 0xFFFF
+	// The longest wait for the server to respond to a request
+	defaultTimeout = 30 * time.Second
+)
+
+// The preamble of the handshake, followed by the versions of the protocol supported, from 4.4
+// back to 4.1. Each proposal holds the range of minor versions below the one provided.
+var handshake = []byte{
+	0x60, 0x60, 0xB0, 0x17,
+	0x00, 0x03, 0x04, 0x04,
+	0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+// Failure is the error returned when the server reports the failure of a request.
+type Failure struct {
+	Code    string
+	Message string
+}
+
+// Error implements the error interface.
+func (f *Failure) Error() string {
+	return f.Code + ": " + f.Message
+}
+
+// Conn is a connection to a Neo4j server speaking version 4 of the Bolt protocol. The queries
+// sent through a Conn are executed one at a time, so it must not be shared between goroutines.
+type Conn struct {
+	conn     net.Conn
+	database string
+	timeout  time.Duration
+	// Set when a failure requires a reset before the connection can be used again
+	failed bool
+}
+
+// Dial connects to the server at the URI and authenticates with the credentials. The bolt
+// and neo4j schemes are supported, along with the +s and +ssc variants that use TLS, where
+// +ssc accepts self-signed certificates. The neo4j scheme connects directly to the server
+// named in the URI, without using the routing table of a cluster. The queries are executed
+// against the database provided, or the default database of the server when empty.
+func Dial(ctx context.Context, uri, username, password, database string) (*Conn, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var tc *tls.Config
+	switch u.Scheme {
+	case "bolt", "neo4j":
+	case "bolt+s", "neo4j+s":
+		tc = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	case "bolt+ssc", "neo4j+ssc":
+		// #nosec G402 -- the scheme asks for self-signed certificates to be accepted
+		tc = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12, InsecureSkipVerify: true}
+	default:
+		return nil, fmt.Errorf("bolt: the %q scheme is not supported", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+
+	conn, err := amassnet.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		conn = tls.Client(conn, tc)
+	}
+
+	c, err := newConn(conn, username, password, database)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newConn performs the handshake and authentication over the established connection.
+func newConn(conn net.Conn, username, password, database string) (*Conn, error) {
+	c := &Conn{conn: conn, database: database, timeout: defaultTimeout}
+
+	if err := c.handshake(); err != nil {
+		return nil, err
+	}
+	if err := c.hello(username, password); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close ends the session and closes the connection.
+func (c *Conn) Close() error {
+	_ = c.send(msgGoodbye)
+	return c.conn.Close()
+}
+
+// Run executes the query with the parameters in a transaction of its own, and discards the
+// records returned.
+func (c *Conn) Run(query string, params map[string]interface{}) error {
+	return c.Transaction([]string{query}, []map[string]interface{}{params})
+}
+
+// Transaction executes the queries, each with the parameters at the same position, within a
+// single transaction that is rolled back when one of the queries fails.
+func (c *Conn) Transaction(queries []string, params []map[string]interface{}) error {
+	if err := c.recover(); err != nil {
+		return err
+	}
+
+	extra := make(map[string]interface{})
+	if c.database != "" {
+		extra["db"] = c.database
+	}
+	if _, err := c.request(msgBegin, extra); err != nil {
+		return err
+	}
+
+	for i, query := range queries {
+		p := make(map[string]interface{})
+		if i < len(params) && params[i] != nil {
+			p = params[i]
+		}
+
+		if _, err := c.request(msgRun, query, p, map[string]interface{}{}); err != nil {
+			return err
+		}
+		if _, err := c.request(msgPull, map[string]interface{}{"n": int64(-1)}); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.request(msgCommit)
+	return err
+}
+
+// recover resets the connection after a failure, which also rolls back the open transaction.
+func (c *Conn) recover() error {
+	if !c.failed {
+		return nil
+	}
+
+	if _, err := c.request(msgReset); err != nil {
+		return err
+	}
+	c.failed = false
+	return nil
+}
+
+func (c *Conn) handshake() error {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer func() { _ = c.conn.SetDeadline(time.Time{}) }()
+
+	if _, err := c.conn.Write(handshake); err != nil {
+		return err
+	}
+
+	version := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, version); err != nil {
+		return err
+	}
+	if version[3] != 4 {
+		return errors.New("bolt: the server does not support version 4 of the protocol")
+	}
+	return nil
+}
+
+func (c *Conn) hello(username, password string) error {
+	auth := map[string]interface{}{"user_agent": "amass"}
+	if username != "" {
+		auth["scheme"] = "basic"
+		auth["principal"] = username
+		auth["credentials"] = password
+	} else {
+		auth["scheme"] = "none"
+	}
+
+	_, err := c.request(msgHello, auth)
+	return err
+}
+
+// request sends the message and returns the metadata of the summary that completes it,
+// skipping the records streamed before the summary.
+func (c *Conn) request(tag byte, fields ...interface{}) (map[string]interface{}, error) {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer func() { _ = c.conn.SetDeadline(time.Time{}) }()
+
+	if err := c.send(tag, fields...); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		var meta map[string]interface{}
+		if len(msg.Fields) > 0 {
+			meta, _ = msg.Fields[0].(map[string]interface{})
+		}
+
+		switch msg.Tag {
+		case msgRecord:
+			continue
+		case msgSuccess:
+			return meta, nil
+		case msgFailure:
+			c.failed = true
+			code, _ := meta["code"].(string)
+			message, _ := meta["message"].(string)
+			return nil, &Failure{Code: code, Message: message}
+		case msgIgnored:
+			c.failed = true
+			return nil, errors.New("bolt: the request was ignored after an earlier failure")
+		default:
+			return nil, fmt.Errorf("bolt: unexpected message 0x%X", msg.Tag)
+		}
+	}
+}
+
+// send writes the message in chunks, followed by the empty chunk that ends it.
+func (c *Conn) send(tag byte, fields ...interface{}) error {
+	var e encoder
+	if err := e.encode(&Structure{Tag: tag, Fields: fields}); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	data := e.buf.Bytes()
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxChunkSize {
+			n = maxChunkSize
+		}
+
+		_ = binary.Write(&out, binary.BigEndian, uint16(n))
+		out.Write(data[:n])
+		data = data[n:]
+	}
+	out.Write([]byte{0, 0})
+
+	_, err := c.conn.Write(out.Bytes())
+	return err
+}
+
+// receive reads the chunks of the next message, skipping the empty chunks sent to keep the
+// connection alive, and decodes the message.
+func (c *Conn) receive() (*Structure, error) {
+	var msg bytes.Buffer
+
+	for {
+		var size uint16
+		if err := binary.Read(c.conn, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			if msg.Len() == 0 {
+				continue
+			}
+			break
+		}
+
+		if _, err := io.CopyN(&msg, c.conn, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := decode(bytes.NewReader(msg.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := v.(*Structure)
+	if !ok {
+		return nil, errors.New("bolt: the message is not a structure")
+	}
+	return s, nil
+}
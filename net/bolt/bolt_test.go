@@ -0,0 +1,143 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bolt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackStream(t *testing.T) {
+	values := []interface{}{
+		nil, true, false, int64(1), int64(-16), int64(-17), int64(200), int64(-40000), int64(1 << 40),
+		1.5, "", "amass", strings.Repeat("x", 300),
+		[]interface{}{int64(1), "two", []interface{}{}},
+		map[string]interface{}{"name": "www.owasp.org", "asn": int64(13335)},
+		&Structure{Tag: 0x4E, Fields: []interface{}{int64(1), []interface{}{"FQDN"}}},
+	}
+
+	for _, v := range values {
+		var e encoder
+		if err := e.encode(v); err != nil {
+			t.Fatalf("Failed to encode %v: %v", v, err)
+		}
+
+		got, err := decode(bytes.NewReader(e.buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Failed to decode %v: %v", v, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("Decoded %#v as %#v", v, got)
+		}
+	}
+
+	var e encoder
+	if err := e.encode([]string{"a", "b"}); err != nil {
+		t.Fatalf("Failed to encode the string slice: %v", err)
+	}
+	if got, _ := decode(bytes.NewReader(e.buf.Bytes())); !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Errorf("Decoded the string slice as %#v", got)
+	}
+	if err := e.encode(struct{}{}); err == nil {
+		t.Errorf("A value of an unsupported type was encoded")
+	}
+}
+
+// fakeServer answers the handshake and the requests of the client, failing the queries that
+// contain FAIL, and returns the messages it received.
+func fakeServer(t *testing.T, conn net.Conn) chan []*Structure {
+	received := make(chan []*Structure, 1)
+
+	go func() {
+		var msgs []*Structure
+		defer func() { received <- msgs }()
+
+		hs := make([]byte, len(handshake))
+		if _, err := io.ReadFull(conn, hs); err != nil || !bytes.Equal(hs[:4], handshake[:4]) {
+			return
+		}
+		_, _ = conn.Write([]byte{0, 0, 4, 4})
+
+		s := &Conn{conn: conn, timeout: defaultTimeout}
+		var failed bool
+		for {
+			msg, err := s.receive()
+			if err != nil {
+				return
+			}
+			msgs = append(msgs, msg)
+
+			switch {
+			case msg.Tag == msgGoodbye:
+				return
+			case msg.Tag == msgReset:
+				failed = false
+				_ = s.send(msgSuccess, map[string]interface{}{})
+			case failed:
+				_ = s.send(msgIgnored)
+			case msg.Tag == msgRun && strings.Contains(msg.Fields[0].(string), "FAIL"):
+				failed = true
+				_ = s.send(msgFailure, map[string]interface{}{
+					"code": "Neo.ClientError.Statement.SyntaxError", "message": "Invalid input"})
+			case msg.Tag == msgPull:
+				_ = s.send(msgRecord, []interface{}{int64(1)})
+				_ = s.send(msgSuccess, map[string]interface{}{})
+			default:
+				_ = s.send(msgSuccess, map[string]interface{}{})
+			}
+		}
+	}()
+	return received
+}
+
+func TestConn(t *testing.T) {
+	client, server := net.Pipe()
+	received := fakeServer(t, server)
+
+	c, err := newConn(client, "neo4j", "secret", "amass")
+	if err != nil {
+		t.Fatalf("Failed to establish the session: %v", err)
+	}
+
+	params := map[string]interface{}{"rows": []map[string]interface{}{{"name": "www.owasp.org"}}}
+	if err := c.Transaction([]string{"UNWIND $rows AS row MERGE (f:FQDN {name: row.name})", "RETURN 1"},
+		[]map[string]interface{}{params}); err != nil {
+		t.Errorf("The transaction failed: %v", err)
+	}
+
+	var f *Failure
+	if err := c.Run("FAIL", nil); !errors.As(err, &f) || f.Code != "Neo.ClientError.Statement.SyntaxError" {
+		t.Errorf("The failure was not reported: %v", err)
+	}
+	// The connection is reset before it is used again
+	if err := c.Run("RETURN 1", nil); err != nil {
+		t.Errorf("The connection was not recovered after the failure: %v", err)
+	}
+	_ = c.Close()
+
+	var tags []byte
+	msgs := <-received
+	for _, msg := range msgs {
+		tags = append(tags, msg.Tag)
+	}
+	expected := []byte{msgHello, msgBegin, msgRun, msgPull, msgRun, msgPull, msgCommit,
+		msgBegin, msgRun, msgReset, msgBegin, msgRun, msgPull, msgCommit, msgGoodbye}
+	if !bytes.Equal(tags, expected) {
+		t.Errorf("Unexpected messages: % X", tags)
+	}
+
+	hello := msgs[0].Fields[0].(map[string]interface{})
+	if hello["scheme"] != "basic" || hello["principal"] != "neo4j" || hello["credentials"] != "secret" {
+		t.Errorf("Unexpected authentication: %v", hello)
+	}
+	if begin := msgs[1].Fields[0].(map[string]interface{}); begin["db"] != "amass" {
+		t.Errorf("The database was not selected: %v", begin)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Structure is a PackStream structure, such as a message or a node returned by the server.
+type Structure struct {
+	Tag    byte
+	Fields []interface{}
+}
+
+// encoder writes values in the PackStream format used by the Bolt protocol.
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) encode(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		e.buf.WriteByte(0xC0)
+	case bool:
+		if val {
+			e.buf.WriteByte(0xC3)
+		} else {
+			e.buf.WriteByte(0xC2)
+		}
+	case int:
+		e.encodeInt(int64(val))
+	case int64:
+		e.encodeInt(val)
+	case float64:
+		e.buf.WriteByte(0xC1)
+		_ = binary.Write(&e.buf, binary.BigEndian, math.Float64bits(val))
+	case string:
+		e.encodeHeader(len(val), 0x80, 0xD0, 0xD1, 0xD2)
+		e.buf.WriteString(val)
+	case []string:
+		e.encodeHeader(len(val), 0x90, 0xD4, 0xD5, 0xD6)
+		for _, s := range val {
+			_ = e.encode(s)
+		}
+	case []interface{}:
+		e.encodeHeader(len(val), 0x90, 0xD4, 0xD5, 0xD6)
+		for _, item := range val {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case []map[string]interface{}:
+		e.encodeHeader(len(val), 0x90, 0xD4, 0xD5, 0xD6)
+		for _, m := range val {
+			if err := e.encode(m); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.encodeHeader(len(val), 0xA0, 0xD8, 0xD9, 0xDA)
+		// The keys are written in order, so the same map is always encoded the same way
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_ = e.encode(k)
+			if err := e.encode(val[k]); err != nil {
+				return err
+			}
+		}
+	case *Structure:
+		if len(val.Fields) > 15 {
+			return errors.New("bolt: structures cannot hold more than 15 fields")
+		}
+		e.buf.WriteByte(0xB0 + byte(len(val.Fields)))
+		e.buf.WriteByte(val.Tag)
+		for _, f := range val.Fields {
+			if err := e.encode(f); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("bolt: values of type %T cannot be sent", v)
+	}
+	return nil
+}
+
+func (e *encoder) encodeInt(i int64) {
+	switch {
+	case i >= -16 && i <= 127:
+		e.buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		e.buf.WriteByte(0xC8)
+		e.buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		e.buf.WriteByte(0xC9)
+		_ = binary.Write(&e.buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		e.buf.WriteByte(0xCA)
+		_ = binary.Write(&e.buf, binary.BigEndian, int32(i))
+	default:
+		e.buf.WriteByte(0xCB)
+		_ = binary.Write(&e.buf, binary.BigEndian, i)
+	}
+}
+
+// encodeHeader writes the marker of a string, list or map holding size items, using the
+// tiny marker when the size fits within its low nibble.
+func (e *encoder) encodeHeader(size int, tiny, m8, m16, m32 byte) {
+	switch {
+	case size < 16:
+		e.buf.WriteByte(tiny + byte(size))
+	case size <= math.MaxUint8:
+		e.buf.WriteByte(m8)
+		e.buf.WriteByte(byte(size))
+	case size <= math.MaxUint16:
+		e.buf.WriteByte(m16)
+		_ = binary.Write(&e.buf, binary.BigEndian, uint16(size))
+	default:
+		e.buf.WriteByte(m32)
+		_ = binary.Write(&e.buf, binary.BigEndian, uint32(size))
+	}
+}
+
+// decode reads the next PackStream value. Integers are returned as int64, lists as
+// []interface{}, maps as map[string]interface{} and structures as *Structure.
+func decode(r *bytes.Reader) (interface{}, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case marker < 0x80:
+		return int64(marker), nil
+	case marker >= 0xF0:
+		return int64(int8(marker)), nil
+	case marker&0xF0 == 0x80:
+		return decodeString(r, int(marker&0x0F))
+	case marker&0xF0 == 0x90:
+		return decodeList(r, int(marker&0x0F))
+	case marker&0xF0 == 0xA0:
+		return decodeMap(r, int(marker&0x0F))
+	case marker&0xF0 == 0xB0:
+		tag, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		fields, err := decodeList(r, int(marker&0x0F))
+		if err != nil {
+			return nil, err
+		}
+		return &Structure{Tag: tag, Fields: fields}, nil
+	}
+
+	switch marker {
+	case 0xC0:
+		return nil, nil
+	case 0xC2:
+		return false, nil
+	case 0xC3:
+		return true, nil
+	case 0xC1:
+		var bits uint64
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xC8:
+		var i int8
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xC9:
+		var i int16
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xCA:
+		var i int32
+		err := binary.Read(r, binary.BigEndian, &i)
+		return int64(i), err
+	case 0xCB:
+		var i int64
+		err := binary.Read(r, binary.BigEndian, &i)
+		return i, err
+	case 0xCC, 0xCD, 0xCE:
+		size, err := decodeSize(r, marker-0xCC)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	case 0xD0, 0xD1, 0xD2:
+		size, err := decodeSize(r, marker-0xD0)
+		if err != nil {
+			return nil, err
+		}
+		return decodeString(r, size)
+	case 0xD4, 0xD5, 0xD6:
+		size, err := decodeSize(r, marker-0xD4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeList(r, size)
+	case 0xD8, 0xD9, 0xDA:
+		size, err := decodeSize(r, marker-0xD8)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(r, size)
+	}
+	return nil, fmt.Errorf("bolt: unknown PackStream marker 0x%X", marker)
+}
+
+// decodeSize reads the 8, 16 or 32 bit size following a marker.
+func decodeSize(r *bytes.Reader, width byte) (int, error) {
+	switch width {
+	case 0:
+		b, err := r.ReadByte()
+		return int(b), err
+	case 1:
+		var size uint16
+		err := binary.Read(r, binary.BigEndian, &size)
+		return int(size), err
+	}
+
+	var size uint32
+	err := binary.Read(r, binary.BigEndian, &size)
+	if err == nil && int64(size) > int64(r.Len()) {
+		return 0, errors.New("bolt: the value is larger than the message")
+	}
+	return int(size), err
+}
+
+func decodeString(r *bytes.Reader, size int) (string, error) {
+	if size > r.Len() {
+		return "", io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, size)
+	_, err := io.ReadFull(r, b)
+	return string(b), err
+}
+
+func decodeList(r *bytes.Reader, size int) ([]interface{}, error) {
+	list := make([]interface{}, 0, size)
+
+	for i := 0; i < size; i++ {
+		v, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func decodeMap(r *bytes.Reader, size int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, size)
+
+	for i := 0; i < size; i++ {
+		k, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, errors.New("bolt: the map key is not a string")
+		}
+
+		v, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
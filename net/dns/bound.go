@@ -0,0 +1,126 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+const (
+	boundTimeout = 5 * time.Second
+	// The most resolvers a query is sent to before SERVFAIL is returned
+	boundAttempts = 3
+)
+
+// BoundProxy is a DNS server on the loopback interface that forwards the queries it receives
+// to traditional resolvers from a selected local IP address. This allows the resolver pools,
+// which open their own sockets, to send their queries from the address. Each query is sent
+// to the next resolver in turn, and to the following ones when a resolver fails to answer.
+type BoundProxy struct {
+	servers []string
+	udp     *mdns.Client
+	tcp     *mdns.Client
+	ludp    *mdns.Server
+	ltcp    *mdns.Server
+	addr    string
+	next    uint32
+}
+
+// NewBoundProxy starts a BoundProxy that sends the queries from the local IP address to the
+// resolvers provided.
+func NewBoundProxy(local net.IP, resolvers ...string) (*BoundProxy, error) {
+	if local == nil {
+		return nil, errors.New("no local address was provided")
+	}
+	if len(resolvers) == 0 {
+		return nil, errors.New("no resolvers were provided")
+	}
+
+	var servers []string
+	for _, r := range resolvers {
+		if _, _, err := net.SplitHostPort(r); err != nil {
+			r = net.JoinHostPort(r, "53")
+		}
+		servers = append(servers, r)
+	}
+
+	p := &BoundProxy{
+		servers: servers,
+		udp: &mdns.Client{
+			Net:     "udp",
+			UDPSize: mdns.DefaultMsgSize,
+			Timeout: boundTimeout,
+			Dialer:  &net.Dialer{LocalAddr: &net.UDPAddr{IP: local}},
+		},
+		tcp: &mdns.Client{
+			Net:     "tcp",
+			Timeout: boundTimeout,
+			Dialer:  &net.Dialer{LocalAddr: &net.TCPAddr{IP: local}},
+		},
+	}
+
+	pc, l, err := listenLoopback()
+	if err != nil {
+		return nil, err
+	}
+
+	p.addr = pc.LocalAddr().String()
+	p.ludp = &mdns.Server{PacketConn: pc, Handler: p}
+	p.ltcp = &mdns.Server{Listener: l, Handler: p}
+	go func() { _ = p.ludp.ActivateAndServe() }()
+	go func() { _ = p.ltcp.ActivateAndServe() }()
+	return p, nil
+}
+
+// Addr returns the address of the loopback server, in the form host:port.
+func (p *BoundProxy) Addr() string {
+	return p.addr
+}
+
+// Stop shuts down the loopback server.
+func (p *BoundProxy) Stop() {
+	_ = p.ludp.Shutdown()
+	_ = p.ltcp.Shutdown()
+}
+
+// ServeDNS implements the miekg/dns Handler interface.
+func (p *BoundProxy) ServeDNS(w mdns.ResponseWriter, req *mdns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), boundAttempts*boundTimeout)
+	defer cancel()
+
+	resp, err := p.Exchange(ctx, req)
+	writeProxied(w, req, resp, err)
+}
+
+// Exchange sends the query to the resolvers from the local address and returns the first response obtained.
+func (p *BoundProxy) Exchange(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+	num := len(p.servers)
+	attempts := boundAttempts
+	if attempts > num {
+		attempts = num
+	}
+
+	var err error
+	start := int(atomic.AddUint32(&p.next, 1)) % num
+	for i := 0; i < attempts; i++ {
+		server := p.servers[(start+i)%num]
+
+		resp, _, e := p.udp.ExchangeContext(ctx, msg, server)
+		if e == nil && resp.Truncated {
+			resp, _, e = p.tcp.ExchangeContext(ctx, msg, server)
+		}
+		if e == nil {
+			return resp, nil
+		}
+		err = e
+	}
+	return nil, err
+}
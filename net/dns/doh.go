@@ -0,0 +1,195 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	amassnet "github.com/aokimio/Amass/v3/net"
+	mdns "github.com/miekg/dns"
+)
+
+const (
+	dohMediaType = "application/dns-message"
+	dohTimeout   = 10 * time.Second
+	// The number of times a free port is sought for both the UDP and TCP listeners
+	loopbackListenAttempts = 5
+)
+
+// DoHProxy is a DNS server on the loopback interface that forwards the queries it receives to
+// DNS-over-HTTPS resolvers. This allows the DoH resolvers to be used anywhere the address of a
+// traditional resolver is accepted. The resolvers are tried in order, starting with the last
+// one that answered, until a response is obtained.
+type DoHProxy struct {
+	urls      []string
+	client    *http.Client
+	udp       *mdns.Server
+	tcp       *mdns.Server
+	addr      string
+	preferred int32
+}
+
+// NewDoHProxy starts a DoHProxy that forwards the queries to the resolvers at the URLs provided.
+// The HTTPS requests honor the proxy environment variables and the local address selected for
+// the network connections.
+func NewDoHProxy(urls ...string) (*DoHProxy, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no DNS-over-HTTPS resolver URLs were provided")
+	}
+
+	p := &DoHProxy{
+		urls: urls,
+		client: &http.Client{
+			Timeout: dohTimeout,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				DialContext:         amassnet.DialContext,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: dohTimeout,
+			},
+		},
+	}
+
+	pc, l, err := listenLoopback()
+	if err != nil {
+		return nil, err
+	}
+
+	p.addr = pc.LocalAddr().String()
+	p.udp = &mdns.Server{PacketConn: pc, Handler: p}
+	p.tcp = &mdns.Server{Listener: l, Handler: p}
+	go func() { _ = p.udp.ActivateAndServe() }()
+	go func() { _ = p.tcp.ActivateAndServe() }()
+	return p, nil
+}
+
+// listenLoopback obtains a UDP and a TCP listener on the same loopback port, so responses
+// truncated over UDP can be requested again over TCP.
+func listenLoopback() (net.PacketConn, net.Listener, error) {
+	var err error
+
+	for i := 0; i < loopbackListenAttempts; i++ {
+		var pc net.PacketConn
+
+		pc, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			continue
+		}
+
+		_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+		l, lerr := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+		if lerr == nil {
+			return pc, l, nil
+		}
+
+		err = lerr
+		_ = pc.Close()
+	}
+	return nil, nil, fmt.Errorf("failed to listen on the loopback interface: %v", err)
+}
+
+// Addr returns the address of the loopback server, in the form host:port.
+func (p *DoHProxy) Addr() string {
+	return p.addr
+}
+
+// Stop shuts down the loopback server.
+func (p *DoHProxy) Stop() {
+	_ = p.udp.Shutdown()
+	_ = p.tcp.Shutdown()
+}
+
+// ServeDNS implements the miekg/dns Handler interface.
+func (p *DoHProxy) ServeDNS(w mdns.ResponseWriter, req *mdns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), dohTimeout)
+	defer cancel()
+
+	resp, err := p.Exchange(ctx, req)
+	writeProxied(w, req, resp, err)
+}
+
+// writeProxied sends the response obtained for a query received by a loopback server, or
+// SERVFAIL when none was obtained. Responses sent over UDP are truncated to fit the client.
+func writeProxied(w mdns.ResponseWriter, req, resp *mdns.Msg, err error) {
+	if err != nil || resp == nil {
+		resp = new(mdns.Msg)
+		resp.SetRcode(req, mdns.RcodeServerFailure)
+	}
+
+	if _, ok := w.LocalAddr().(*net.UDPAddr); ok {
+		size := mdns.MinMsgSize
+		if opt := req.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		resp.Truncate(size)
+	}
+	_ = w.WriteMsg(resp)
+}
+
+// Exchange sends the query to the DNS-over-HTTPS resolvers and returns the first response obtained.
+func (p *DoHProxy) Exchange(ctx context.Context, msg *mdns.Msg) (*mdns.Msg, error) {
+	// The message ID is zero to make the requests cache friendly, as recommended by RFC 8484
+	q := msg.Copy()
+	q.Id = 0
+	wire, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	num := len(p.urls)
+	start := int(atomic.LoadInt32(&p.preferred))
+	for i := 0; i < num; i++ {
+		idx := (start + i) % num
+
+		resp, e := p.post(ctx, p.urls[idx], wire)
+		if e == nil {
+			atomic.StoreInt32(&p.preferred, int32(idx))
+			resp.Id = msg.Id
+			return resp, nil
+		}
+		err = e
+	}
+	return nil, err
+}
+
+func (p *DoHProxy) post(ctx context.Context, u string, wire []byte) (*mdns.Msg, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(wire))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %d: %s", u, resp.StatusCode, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, mdns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(mdns.Msg)
+	if err := m.Unpack(body); err != nil {
+		return nil, fmt.Errorf("%s: failed to unpack the response: %v", u, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,111 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+func TestDoHProxy(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		req := new(mdns.Msg)
+		if r.Header.Get("Content-Type") != dohMediaType || req.Unpack(body) != nil || req.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := new(mdns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &mdns.A{
+			Hdr: mdns.RR_Header{Name: req.Question[0].Name, Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		wire, _ := resp.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(wire)
+	}))
+	defer ts.Close()
+
+	p, err := NewDoHProxy(ts.URL+"/broken", ts.URL+"/dns-query")
+	if err != nil {
+		t.Fatalf("Failed to start the proxy: %v", err)
+	}
+	defer p.Stop()
+	p.client = ts.Client()
+
+	msg := new(mdns.Msg)
+	msg.SetQuestion("www.owasp.org.", mdns.TypeA)
+	resp, _, err := new(mdns.Client).Exchange(msg, p.Addr())
+	if err != nil {
+		t.Fatalf("The query sent to the proxy failed: %v", err)
+	}
+	if resp.Id != msg.Id || resp.Rcode != mdns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Errorf("The proxy returned an unexpected response: %v", resp)
+	}
+	if p.preferred != 1 {
+		t.Errorf("The proxy did not prefer the resolver that answered")
+	}
+
+	p.urls = []string{ts.URL + "/broken"}
+	if resp, _, err := new(mdns.Client).Exchange(msg, p.Addr()); err != nil || resp.Rcode != mdns.RcodeServerFailure {
+		t.Errorf("Expected a server failure when no resolver answered: %v", err)
+	}
+}
+
+func TestBoundProxy(t *testing.T) {
+	local := net.ParseIP("127.0.0.2")
+	if pc, err := net.ListenPacket("udp", "127.0.0.2:0"); err != nil {
+		t.Skipf("The loopback address %s is not available: %v", local, err)
+	} else {
+		_ = pc.Close()
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start the resolver: %v", err)
+	}
+	srcs := make(chan net.IP, 1)
+	server := &mdns.Server{PacketConn: pc, Handler: mdns.HandlerFunc(func(w mdns.ResponseWriter, req *mdns.Msg) {
+		srcs <- w.RemoteAddr().(*net.UDPAddr).IP
+
+		resp := new(mdns.Msg)
+		resp.SetReply(req)
+		_ = w.WriteMsg(resp)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer func() { _ = server.Shutdown() }()
+
+	// The first resolver does not answer, so the query moves on to the next one
+	p, err := NewBoundProxy(local, "127.0.0.1:1", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to start the proxy: %v", err)
+	}
+	defer p.Stop()
+	p.udp.Timeout = 500 * time.Millisecond
+	p.next = 1
+
+	msg := new(mdns.Msg)
+	msg.SetQuestion("www.owasp.org.", mdns.TypeA)
+	resp, _, err := new(mdns.Client).Exchange(msg, p.Addr())
+	if err != nil || resp.Rcode != mdns.RcodeSuccess {
+		t.Fatalf("The query sent to the proxy failed: %v", err)
+	}
+	if src := <-srcs; !src.Equal(local) {
+		t.Errorf("The query was sent from %s instead of %s", src, local)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// The prefix of the labels holding an internationalized name encoded with punycode
+const acePrefix = "xn--"
+
+// ToASCII returns the DNS name in lowercase with each internationalized label encoded as
+// punycode, which is the form used on the wire and throughout the enumeration. A label that
+// cannot be encoded is kept as provided.
+func ToASCII(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if isASCII(name) {
+		return name
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		if l, err := idna.Punycode.ToASCII(label); err == nil {
+			labels[i] = l
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// ToUnicode returns the DNS name with each punycode label decoded into the Unicode characters
+// it represents, for display. A label that is not valid punycode is kept as provided.
+func ToUnicode(name string) string {
+	if !strings.Contains(strings.ToLower(name), acePrefix) {
+		return name
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), acePrefix) {
+			continue
+		}
+		if l, err := idna.Punycode.ToUnicode(strings.ToLower(label)); err == nil && l != "" {
+			labels[i] = l
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import "testing"
+
+func TestToASCII(t *testing.T) {
+	for name, expected := range map[string]string{
+		"www.example.com":       "www.example.com",
+		" Www.München.DE ":      "www.xn--mnchen-3ya.de",
+		"_dmarc.bücher.example": "_dmarc.xn--bcher-kva.example",
+		"www.xn--mnchen-3ya.de": "www.xn--mnchen-3ya.de",
+		"мойдомен.рф":           "xn--d1acklchcc.xn--p1ai",
+	} {
+		if got := ToASCII(name); got != expected {
+			t.Errorf("ToASCII(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	for name, expected := range map[string]string{
+		"www.example.com":         "www.example.com",
+		"www.xn--mnchen-3ya.de":   "www.münchen.de",
+		"WWW.XN--MNCHEN-3YA.DE":   "WWW.münchen.DE",
+		"xn--d1acklchcc.xn--p1ai": "мойдомен.рф",
+		"xn--zz.example.com":      "xn--zz.example.com",
+		"xn--.example.com":        "xn--.example.com",
+	} {
+		if got := ToUnicode(name); got != expected {
+			t.Errorf("ToUnicode(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+	// The names survive the round trip through both forms
+	for _, name := range []string{"www.xn--mnchen-3ya.de", "_dmarc.xn--bcher-kva.example"} {
+		if got := ToASCII(ToUnicode(name)); got != name {
+			t.Errorf("The round trip of %q returned %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package geo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/aokimio/Amass/v3/config"
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// Provider returns the geographic location of addresses.
+type Provider interface {
+	// String returns the name of the provider
+	String() string
+
+	// Local returns true when the lookups are performed without network activity
+	Local() bool
+
+	// Lookup returns the location of the address, or nil when the location is not known
+	Lookup(ctx context.Context, ip net.IP) (*requests.GeoInfo, error)
+}
+
+var (
+	apiProvidersLock sync.Mutex
+	apiProviders     = make(map[string]func(cfg *config.Config) Provider)
+)
+
+func init() {
+	RegisterAPIProvider("ip-api", func(cfg *config.Config) Provider { return NewIPAPI() })
+}
+
+// RegisterAPIProvider makes the online provider available for selection by name
+// in the api setting of the geolocation configuration section.
+func RegisterAPIProvider(name string, f func(cfg *config.Config) Provider) {
+	apiProvidersLock.Lock()
+	defer apiProvidersLock.Unlock()
+
+	apiProviders[strings.ToLower(name)] = f
+}
+
+// Enricher geolocates addresses using the configured providers, and looks up each address once.
+type Enricher struct {
+	sync.Mutex
+	providers []Provider
+	cache     map[string]*requests.GeoInfo
+}
+
+// NewEnricher returns the Enricher for the providers selected in the configuration, or nil
+// when geolocation has not been configured. The local database is always consulted first,
+// and the online provider is not used during passive enumerations.
+func NewEnricher(cfg *config.Config) (*Enricher, error) {
+	var providers []Provider
+
+	if cfg.GeoDatabase != "" {
+		db, err := NewMaxMindDB(cfg.GeoDatabase)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, db)
+	}
+	if cfg.GeoAPI != "" && !cfg.Passive {
+		apiProvidersLock.Lock()
+		f, found := apiProviders[cfg.GeoAPI]
+		apiProvidersLock.Unlock()
+
+		if !found {
+			return nil, fmt.Errorf("unknown geolocation API provider: %s", cfg.GeoAPI)
+		}
+		providers = append(providers, f(cfg))
+	}
+
+	if len(providers) == 0 {
+		return nil, nil
+	}
+	return NewEnricherWithProviders(providers...), nil
+}
+
+// NewEnricherWithProviders returns an Enricher that consults the providers in the order provided.
+func NewEnricherWithProviders(providers ...Provider) *Enricher {
+	return &Enricher{
+		providers: providers,
+		cache:     make(map[string]*requests.GeoInfo),
+	}
+}
+
+// Local returns true when none of the providers perform network activity.
+func (e *Enricher) Local() bool {
+	for _, p := range e.providers {
+		if !p.Local() {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup returns the location of the address and true when this is the first lookup of the
+// address. Later lookups return the cached result and false, so callers can avoid storing
+// the location of an address more than once.
+func (e *Enricher) Lookup(ctx context.Context, addr string) (*requests.GeoInfo, bool) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, false
+	}
+	if reserved, _ := amassnet.IsReservedAddress(addr); reserved {
+		return nil, false
+	}
+
+	key := ip.String()
+	e.Lock()
+	if info, found := e.cache[key]; found {
+		e.Unlock()
+		return info, false
+	}
+	// Claim the address, so concurrent lookups do not query the providers again
+	e.cache[key] = nil
+	e.Unlock()
+
+	var info *requests.GeoInfo
+	for _, p := range e.providers {
+		if i, err := p.Lookup(ctx, ip); err == nil && i != nil {
+			info = i
+			break
+		}
+	}
+
+	e.Lock()
+	e.cache[key] = info
+	e.Unlock()
+	return info, true
+}
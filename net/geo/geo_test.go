@@ -0,0 +1,167 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package geo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+func mmdbTestString(s string) []byte {
+	return append([]byte{byte(mmdbString<<5 | len(s))}, s...)
+}
+
+func mmdbTestDouble(f float64) []byte {
+	b := make([]byte, 9)
+	b[0] = mmdbDouble<<5 | 8
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	return b
+}
+
+func mmdbTestUint16(n uint16) []byte {
+	return []byte{mmdbUint16<<5 | 2, byte(n >> 8), byte(n)}
+}
+
+func mmdbTestMap(pairs ...[]byte) []byte {
+	b := []byte{byte(mmdbMap<<5 | len(pairs)/2)}
+	for _, p := range pairs {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// buildTestMMDB returns an IPv4 database with a single node: 0.0.0.0/1 is located
+// in Mountain View and 128.0.0.0/1 is not found.
+func buildTestMMDB() []byte {
+	// The country code is reached through a pointer to the start of the data section
+	data := mmdbTestString("US")
+	rec := len(data)
+	data = append(data, mmdbTestMap(
+		mmdbTestString("country"), mmdbTestMap(
+			mmdbTestString("iso_code"), []byte{mmdbPointer << 5, 0},
+			mmdbTestString("names"), mmdbTestMap(mmdbTestString("en"), mmdbTestString("United States")),
+		),
+		mmdbTestString("city"), mmdbTestMap(
+			mmdbTestString("names"), mmdbTestMap(mmdbTestString("en"), mmdbTestString("Mountain View")),
+		),
+		mmdbTestString("location"), mmdbTestMap(
+			mmdbTestString("latitude"), mmdbTestDouble(37.386),
+			mmdbTestString("longitude"), mmdbTestDouble(-122.0838),
+		),
+	)...)
+
+	nodeCount := 1
+	left := nodeCount + mmdbDataSectionSeparator + rec
+	tree := []byte{0, 0, byte(left), 0, 0, byte(nodeCount)}
+
+	var buf bytes.Buffer
+	buf.Write(tree)
+	buf.Write(make([]byte, mmdbDataSectionSeparator))
+	buf.Write(data)
+	buf.Write(mmdbMetadataStart)
+	buf.Write(mmdbTestMap(
+		mmdbTestString("node_count"), mmdbTestUint16(uint16(nodeCount)),
+		mmdbTestString("record_size"), mmdbTestUint16(24),
+		mmdbTestString("ip_version"), mmdbTestUint16(4),
+	))
+	return buf.Bytes()
+}
+
+func TestMaxMindDBLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := ioutil.WriteFile(path, buildTestMMDB(), 0600); err != nil {
+		t.Fatalf("Failed to write the test database: %v", err)
+	}
+
+	db, err := NewMaxMindDB(path)
+	if err != nil {
+		t.Fatalf("Failed to open the test database: %v", err)
+	}
+
+	info, err := db.Lookup(context.Background(), net.ParseIP("8.8.8.8"))
+	if err != nil || info == nil {
+		t.Fatalf("Failed to locate the address: %v", err)
+	}
+	if info.CountryCode != "US" || info.Country != "United States" || info.City != "Mountain View" ||
+		info.Latitude != 37.386 || info.Longitude != -122.0838 {
+		t.Errorf("Unexpected location returned: %+v", info)
+	}
+
+	for _, addr := range []string{"200.1.1.1", "2001:db8::1"} {
+		if info, err := db.Lookup(context.Background(), net.ParseIP(addr)); err != nil || info != nil {
+			t.Errorf("%s: expected no location, got %+v: %v", addr, info, err)
+		}
+	}
+
+	if _, err := parseMaxMindDB([]byte("not a database")); err == nil {
+		t.Errorf("Failed to reject data without the metadata section")
+	}
+}
+
+type testProvider struct {
+	local   bool
+	lookups int64
+}
+
+func (p *testProvider) String() string { return "test" }
+
+func (p *testProvider) Local() bool { return p.local }
+
+func (p *testProvider) Lookup(ctx context.Context, ip net.IP) (*requests.GeoInfo, error) {
+	atomic.AddInt64(&p.lookups, 1)
+	return &requests.GeoInfo{CountryCode: "US", Source: p.String()}, nil
+}
+
+func TestEnricherLookup(t *testing.T) {
+	p := &testProvider{local: true}
+	e := NewEnricherWithProviders(p)
+
+	info, first := e.Lookup(context.Background(), "8.8.8.8")
+	if info == nil || !first {
+		t.Fatalf("The first lookup returned %+v, %v", info, first)
+	}
+	if info, first = e.Lookup(context.Background(), "8.8.8.8"); info == nil || first {
+		t.Errorf("The second lookup returned %+v, %v", info, first)
+	}
+	if info, _ = e.Lookup(context.Background(), "192.168.1.1"); info != nil {
+		t.Errorf("Reserved addresses should not be geolocated")
+	}
+	if n := atomic.LoadInt64(&p.lookups); n != 1 {
+		t.Errorf("The provider was queried %d times for the same address", n)
+	}
+}
+
+func TestNewEnricher(t *testing.T) {
+	cfg := config.NewConfig()
+	if e, err := NewEnricher(cfg); err != nil || e != nil {
+		t.Errorf("An enricher was returned without geolocation configured")
+	}
+
+	cfg.GeoAPI = "ip-api"
+	cfg.Passive = true
+	if e, err := NewEnricher(cfg); err != nil || e != nil {
+		t.Errorf("The online provider was selected for a passive enumeration")
+	}
+
+	cfg.Passive = false
+	if e, err := NewEnricher(cfg); err != nil || e == nil || e.Local() {
+		t.Errorf("The online provider was not selected: %v", err)
+	}
+
+	cfg.GeoAPI = "unknown"
+	if _, err := NewEnricher(cfg); err == nil {
+		t.Errorf("An unknown online provider was accepted")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/aokimio/Amass/v3/net/http"
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// The free ip-api.com endpoint allows 45 requests per minute
+const ipapiRequestDelay = 1500 * time.Millisecond
+
+// IPAPI geolocates addresses using the ip-api.com service.
+type IPAPI struct {
+	limiter <-chan time.Time
+}
+
+// NewIPAPI returns the Provider for the ip-api.com service.
+func NewIPAPI() *IPAPI {
+	return &IPAPI{limiter: time.Tick(ipapiRequestDelay)}
+}
+
+// String implements the Provider interface.
+func (i *IPAPI) String() string {
+	return "ip-api"
+}
+
+// Local implements the Provider interface.
+func (i *IPAPI) Local() bool {
+	return false
+}
+
+// Lookup implements the Provider interface.
+func (i *IPAPI) Lookup(ctx context.Context, ip net.IP) (*requests.GeoInfo, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-i.limiter:
+	}
+
+	u := "http://ip-api.com/json/" + ip.String() + "?fields=status,message,countryCode,country,regionName,city,lat,lon"
+	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Status      string  `json:"status"`
+		Message     string  `json:"message"`
+		CountryCode string  `json:"countryCode"`
+		Country     string  `json:"country"`
+		Region      string  `json:"regionName"`
+		City        string  `json:"city"`
+		Latitude    float64 `json:"lat"`
+		Longitude   float64 `json:"lon"`
+	}
+	if err := json.Unmarshal([]byte(page), &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("%s: %s", i.String(), resp.Message)
+	}
+
+	return &requests.GeoInfo{
+		CountryCode: resp.CountryCode,
+		Country:     resp.Country,
+		Region:      resp.Region,
+		City:        resp.City,
+		Latitude:    resp.Latitude,
+		Longitude:   resp.Longitude,
+		Source:      i.String(),
+	}, nil
+}
@@ -0,0 +1,394 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package geo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+
+	"github.com/aokimio/Amass/v3/requests"
+)
+
+// The marker that precedes the metadata section at the end of a MaxMind DB file
+var mmdbMetadataStart = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// The number of zero bytes separating the search tree from the data section
+const mmdbDataSectionSeparator = 16
+
+// The deepest nesting of maps, arrays and pointers accepted by the decoder
+const mmdbMaxDepth = 32
+
+// MaxMindDB geolocates addresses using a MaxMind DB file, such as GeoLite2-City.mmdb,
+// without performing any network activity.
+type MaxMindDB struct {
+	path       string
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// NewMaxMindDB reads the MaxMind DB file at the path and returns the Provider.
+func NewMaxMindDB(path string) (*MaxMindDB, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the MaxMind DB file: %v", err)
+	}
+
+	db, err := parseMaxMindDB(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	db.path = path
+	return db, nil
+}
+
+func parseMaxMindDB(buf []byte) (*MaxMindDB, error) {
+	idx := bytes.LastIndex(buf, mmdbMetadataStart)
+	if idx == -1 {
+		return nil, errors.New("the MaxMind DB metadata was not found")
+	}
+
+	d := &mmdbDecoder{buf: buf[idx+len(mmdbMetadataStart):]}
+	v, _, err := d.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the MaxMind DB metadata: %v", err)
+	}
+	meta, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the MaxMind DB metadata is not a map")
+	}
+
+	db := &MaxMindDB{
+		nodeCount:  mmdbUint(meta["node_count"]),
+		recordSize: mmdbUint(meta["record_size"]),
+		ipVersion:  mmdbUint(meta["ip_version"]),
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size: %d", db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported MaxMind DB IP version: %d", db.ipVersion)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+mmdbDataSectionSeparator > uint(idx) {
+		return nil, errors.New("the MaxMind DB search tree is larger than the file")
+	}
+	db.tree = buf[:treeSize]
+	db.data = buf[treeSize+mmdbDataSectionSeparator : idx]
+
+	// IPv4 addresses are stored under ::/96 in the IPv6 databases
+	if db.ipVersion == 6 {
+		for i := 0; i < 96 && db.ipv4Start < db.nodeCount; i++ {
+			db.ipv4Start = db.readRecord(db.ipv4Start, 0)
+		}
+	}
+	return db, nil
+}
+
+// String implements the Provider interface.
+func (db *MaxMindDB) String() string {
+	return "MaxMind DB"
+}
+
+// Local implements the Provider interface.
+func (db *MaxMindDB) Local() bool {
+	return true
+}
+
+// Lookup implements the Provider interface.
+func (db *MaxMindDB) Lookup(ctx context.Context, ip net.IP) (*requests.GeoInfo, error) {
+	rec, err := db.record(ip)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+
+	info := &requests.GeoInfo{Source: db.String()}
+	country, ok := rec["country"].(map[string]interface{})
+	if !ok {
+		country, _ = rec["registered_country"].(map[string]interface{})
+	}
+	if country != nil {
+		info.CountryCode, _ = country["iso_code"].(string)
+		info.Country = mmdbName(country)
+	}
+	if subs, ok := rec["subdivisions"].([]interface{}); ok && len(subs) > 0 {
+		if sub, ok := subs[0].(map[string]interface{}); ok {
+			info.Region = mmdbName(sub)
+		}
+	}
+	if city, ok := rec["city"].(map[string]interface{}); ok {
+		info.City = mmdbName(city)
+	}
+	if loc, ok := rec["location"].(map[string]interface{}); ok {
+		info.Latitude, _ = loc["latitude"].(float64)
+		info.Longitude, _ = loc["longitude"].(float64)
+	}
+
+	if info.CountryCode == "" && info.City == "" && info.Latitude == 0 && info.Longitude == 0 {
+		return nil, nil
+	}
+	return info, nil
+}
+
+// record returns the data stored in the database for the address, or nil when not found.
+func (db *MaxMindDB) record(ip net.IP) (map[string]interface{}, error) {
+	var node uint
+	addr := ip.To4()
+
+	if addr != nil && db.ipVersion == 6 {
+		node = db.ipv4Start
+	} else if addr == nil {
+		if db.ipVersion == 4 {
+			return nil, nil
+		}
+		if addr = ip.To16(); addr == nil {
+			return nil, fmt.Errorf("invalid IP address: %v", ip)
+		}
+	}
+
+	for i := 0; i < len(addr)*8 && node < db.nodeCount; i++ {
+		bit := (addr[i>>3] >> (7 - uint(i%8))) & 1
+		node = db.readRecord(node, bit)
+	}
+	if node <= db.nodeCount {
+		return nil, nil
+	}
+
+	offset := node - db.nodeCount - mmdbDataSectionSeparator
+	if offset >= uint(len(db.data)) {
+		return nil, errors.New("the MaxMind DB search tree is corrupt")
+	}
+
+	d := &mmdbDecoder{buf: db.data}
+	v, _, err := d.decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	rec, _ := v.(map[string]interface{})
+	return rec, nil
+}
+
+func (db *MaxMindDB) readRecord(node uint, bit byte) uint {
+	b := db.tree
+
+	switch db.recordSize {
+	case 24:
+		off := node*6 + uint(bit)*3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		off := node * 7
+		if bit == 0 {
+			return uint(b[off+3]&0xF0)<<20 | uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+		}
+		return uint(b[off+3]&0x0F)<<24 | uint(b[off+4])<<16 | uint(b[off+5])<<8 | uint(b[off+6])
+	}
+	off := node*8 + uint(bit)*4
+	return uint(binary.BigEndian.Uint32(b[off : off+4]))
+}
+
+// mmdbDecoder decodes the data section format described in the MaxMind DB specification.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+const (
+	mmdbPointer = 1
+	mmdbString  = 2
+	mmdbDouble  = 3
+	mmdbBytes   = 4
+	mmdbUint16  = 5
+	mmdbUint32  = 6
+	mmdbMap     = 7
+	mmdbInt32   = 8
+	mmdbUint64  = 9
+	mmdbUint128 = 10
+	mmdbArray   = 11
+	mmdbBool    = 14
+	mmdbFloat   = 15
+)
+
+func (d *mmdbDecoder) decode(off uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("the MaxMind DB data is nested too deeply")
+	}
+	if off >= uint(len(d.buf)) {
+		return nil, 0, errors.New("unexpected end of the MaxMind DB data")
+	}
+
+	ctrl := d.buf[off]
+	off++
+	typ := uint(ctrl >> 5)
+	if typ == mmdbPointer {
+		ptr, next, err := d.pointer(ctrl, off)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		v, _, err := d.decode(ptr, depth+1)
+		return v, next, err
+	}
+	if typ == 0 {
+		if off >= uint(len(d.buf)) {
+			return nil, 0, errors.New("unexpected end of the MaxMind DB data")
+		}
+		typ = 7 + uint(d.buf[off])
+		off++
+	}
+
+	size, off, err := d.size(ctrl, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	return d.value(typ, size, off, depth)
+}
+
+func (d *mmdbDecoder) value(typ, size, off uint, depth int) (interface{}, uint, error) {
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(off, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("the MaxMind DB map key is not a string")
+			}
+
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			off = next
+		}
+		return m, off, nil
+	case mmdbArray:
+		var a []interface{}
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(off, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			off = next
+		}
+		return a, off, nil
+	case mmdbBool:
+		return size != 0, off, nil
+	}
+
+	if off+size > uint(len(d.buf)) {
+		return nil, 0, errors.New("unexpected end of the MaxMind DB data")
+	}
+	b := d.buf[off : off+size]
+	next := off + size
+
+	switch typ {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid MaxMind DB double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid MaxMind DB float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128, mmdbInt32:
+		if size > 16 {
+			return nil, 0, errors.New("invalid MaxMind DB integer size")
+		}
+
+		var val uint64
+		for _, c := range b {
+			val = val<<8 | uint64(c)
+		}
+		if typ == mmdbInt32 {
+			return int64(int32(uint32(val))), next, nil
+		}
+		return val, next, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind DB data type: %d", typ)
+}
+
+func (d *mmdbDecoder) size(ctrl byte, off uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1F)
+	if size < 29 {
+		return size, off, nil
+	}
+
+	n := size - 28
+	if off+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("unexpected end of the MaxMind DB data")
+	}
+
+	var val uint
+	for _, c := range d.buf[off : off+n] {
+		val = val<<8 | uint(c)
+	}
+	switch size {
+	case 29:
+		val += 29
+	case 30:
+		val += 285
+	default:
+		val += 65821
+	}
+	return val, off + n, nil
+}
+
+func (d *mmdbDecoder) pointer(ctrl byte, off uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if off+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("unexpected end of the MaxMind DB data")
+	}
+
+	var val uint
+	if n < 4 {
+		val = uint(ctrl & 0x7)
+	}
+	for _, c := range d.buf[off : off+n] {
+		val = val<<8 | uint(c)
+	}
+	switch n {
+	case 2:
+		val += 2048
+	case 3:
+		val += 526336
+	}
+	return val, off + n, nil
+}
+
+func mmdbUint(v interface{}) uint {
+	if n, ok := v.(uint64); ok {
+		return uint(n)
+	}
+	return 0
+}
+
+func mmdbName(m map[string]interface{}) string {
+	if names, ok := m["names"].(map[string]interface{}); ok {
+		if name, ok := names["en"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
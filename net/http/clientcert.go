@@ -0,0 +1,89 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type clientCertKey struct{}
+
+// clientCert is a TLS client certificate along with the hosts it is presented to.
+type clientCert struct {
+	cert  *tls.Certificate
+	hosts []string
+}
+
+// The clients presenting each client certificate, so their connections are reused across requests
+var (
+	certClientsLock sync.Mutex
+	certClients     = make(map[*tls.Certificate]*http.Client)
+)
+
+// WithClientCert returns a copy of the parent context that presents the TLS client certificate
+// to the hosts provided, for the requests sent by RequestWebPage. The requests for other hosts
+// are sent without the certificate. The parent is returned when the certificate is nil.
+func WithClientCert(parent context.Context, cert *tls.Certificate, hosts []string) context.Context {
+	if cert == nil || len(hosts) == 0 {
+		return parent
+	}
+	return context.WithValue(parent, clientCertKey{}, &clientCert{cert: cert, hosts: hosts})
+}
+
+// clientFor returns the client that sends the request for the host, which is DefaultClient
+// unless the context carries a client certificate for the host.
+func clientFor(ctx context.Context, host string) *http.Client {
+	cert := clientCertFor(ctx, host)
+	if cert == nil {
+		return DefaultClient
+	}
+
+	certClientsLock.Lock()
+	defer certClientsLock.Unlock()
+
+	if c, found := certClients[cert]; found {
+		return c
+	}
+
+	t, ok := DefaultClient.Transport.(*http.Transport)
+	if !ok {
+		return DefaultClient
+	}
+	// The certificate is kept on a transport of its own, so the pooled connections that
+	// presented it are never used for the requests of other data sources
+	transport := t.Clone()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+
+	c := &http.Client{
+		Timeout:   DefaultClient.Timeout,
+		Transport: transport,
+		Jar:       DefaultClient.Jar,
+	}
+	certClients[cert] = c
+	return c
+}
+
+// clientCertFor returns the client certificate the context presents to the host, or nil when there is none.
+func clientCertFor(ctx context.Context, host string) *tls.Certificate {
+	if cc, ok := ctx.Value(clientCertKey{}).(*clientCert); ok && certHostMatch(host, cc.hosts) {
+		return cc.cert
+	}
+	return nil
+}
+
+func certHostMatch(host string, hosts []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, h := range hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+	}
+	return false
+}
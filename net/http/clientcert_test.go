@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testClientCert(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate the key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "amass"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create the certificate: %v", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientCert(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cert := testClientCert(t)
+	ctx := WithClientCert(context.Background(), cert, []string{"127.0.0.1"})
+	if page, err := RequestWebPage(ctx, ts.URL, nil, nil, nil); err != nil || page != "amass" {
+		t.Errorf("The client certificate was not presented: %q %v", page, err)
+	}
+	if clientFor(ctx, "127.0.0.1") != clientFor(ctx, "127.0.0.1") {
+		t.Errorf("The client presenting the certificate was not reused")
+	}
+
+	// The certificate is not presented to the hosts it was not configured for
+	other := WithClientCert(context.Background(), cert, []string{"mtls.example.com"})
+	if _, err := RequestWebPage(other, ts.URL, nil, nil, nil); err == nil {
+		t.Errorf("The client certificate was presented to another host")
+	}
+	if _, err := RequestWebPage(context.Background(), ts.URL, nil, nil, nil); err == nil {
+		t.Errorf("A client certificate was presented without being configured")
+	}
+	if WithClientCert(context.Background(), nil, []string{"127.0.0.1"}).Value(clientCertKey{}) != nil {
+		t.Errorf("The context carried a nil certificate")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/stats"
+)
+
+// flight is a request on the network that the identical requests made while it is in progress wait for.
+type flight struct {
+	done      chan struct{}
+	cancel    context.CancelFunc
+	waiters   int
+	collector *stats.Collector
+	resp      *Response
+	err       error
+}
+
+var (
+	flightLock sync.Mutex
+	flights    = make(map[string]*flight)
+)
+
+// shareFlight sends the request for the URL using the send function, unless an identical request
+// from the same data source is already in flight, in which case the caller waits for its response.
+// The shared request is only cancelled once every caller waiting on it has given up, and each
+// caller receives the response and error of the request, and records the URL of the page in its
+// own context. The quota, statistics and status observer of the first caller account for the
+// request, along with those of each caller collecting its statistics separately, such as an
+// enumeration running alongside in the same process.
+func shareFlight(ctx context.Context, key, u string, send func(context.Context) (*Response, error)) (*Response, error) {
+	c, _ := stats.FromContext(ctx)
+
+	flightLock.Lock()
+	f, found := flights[key]
+	if !found {
+		fctx, cancel := context.WithCancel(detachedContext{parent: ctx})
+
+		f = &flight{
+			done:      make(chan struct{}),
+			cancel:    cancel,
+			collector: c,
+		}
+		flights[key] = f
+
+		go func() {
+			resp, err := send(fctx)
+
+			flightLock.Lock()
+			if flights[key] == f {
+				delete(flights, key)
+			}
+			f.resp, f.err = resp, err
+			flightLock.Unlock()
+
+			cancel()
+			close(f.done)
+		}()
+	}
+	// The caller is not sent the response once its own quota has been used up
+	separate := found && c != f.collector
+	if separate {
+		if err := stats.TakeQuota(ctx); err != nil {
+			flightLock.Unlock()
+			return nil, err
+		}
+	}
+	f.waiters++
+	flightLock.Unlock()
+
+	select {
+	case <-f.done:
+		resp := f.response()
+		if separate && resp != nil {
+			observeStatus(ctx, resp.StatusCode)
+			stats.RecordStatus(ctx, resp.StatusCode)
+		}
+		if separate {
+			stats.RecordRequest(ctx, f.err)
+		}
+		if f.err == nil {
+			RecordSourceURL(ctx, u)
+		}
+		return resp, f.err
+	case <-ctx.Done():
+	}
+	if separate {
+		stats.RecordRequest(ctx, ctx.Err())
+	}
+
+	flightLock.Lock()
+	f.waiters--
+	if f.waiters == 0 {
+		// Later requests start another flight instead of joining the one being cancelled
+		if flights[key] == f {
+			delete(flights, key)
+		}
+		f.cancel()
+	}
+	flightLock.Unlock()
+	return nil, ctx.Err()
+}
+
+// response returns a copy of the shared response, so the callers cannot modify the headers seen by the others.
+func (f *flight) response() *Response {
+	if f.resp == nil {
+		return nil
+	}
+
+	r := *f.resp
+	r.Header = f.resp.Header.Clone()
+	return &r
+}
+
+// flightKey identifies the requests that are identical, including the data source making
+// them, the headers, the credentials, the client certificate and the largest response
+// accepted, so responses are never shared across API keys or differently configured callers.
+func flightKey(ctx context.Context, method, u string, body []byte, hvals map[string]string, auth *BasicAuth) string {
+	_, src := stats.FromContext(ctx)
+
+	var cert string
+	if parsed, err := url.Parse(u); err == nil {
+		if c := clientCertFor(ctx, parsed.Hostname()); c != nil {
+			cert = fmt.Sprintf("%p", c)
+		}
+	}
+
+	h := sha256.New()
+	for _, s := range []string{src, method, u, string(body), cert, strconv.FormatInt(MaxResponseSize(ctx), 10)} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+
+	keys := make([]string, 0, len(hvals))
+	for k := range hvals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = h.Write([]byte(k + ":" + hvals[k]))
+		_, _ = h.Write([]byte{0})
+	}
+
+	if auth != nil {
+		_, _ = h.Write([]byte(auth.Username + ":" + auth.Password))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// detachedContext keeps the values of the parent context, such as the data source and its
+// statistics, without the deadline and cancellation, which belong to the callers of a flight.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,271 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/stats"
+)
+
+func TestSharedFlight(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		_, _ = w.Write([]byte("shared"))
+	}))
+	defer ts.Close()
+
+	ctx := stats.NewContext(context.Background(), stats.NewCollector(), "NetworksDB")
+	var wg sync.WaitGroup
+	pages := make([]string, 5)
+	for i := range pages {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pages[i], _ = RequestWebPage(ctx, ts.URL+"/asn", nil, nil, nil)
+		}(i)
+	}
+	waitForWaiters(t, 5)
+	// A different body or data source is a distinct request
+	go func() { _, _ = RequestWebPage(ctx, ts.URL+"/asn", strings.NewReader("q=1"), nil, nil) }()
+	other := stats.NewContext(context.Background(), stats.NewCollector(), "RADb")
+	go func() { _, _ = RequestWebPage(other, ts.URL+"/asn", nil, nil, nil) }()
+	waitForFlights(t, 3)
+
+	close(release)
+	wg.Wait()
+	for i, page := range pages {
+		if page != "shared" {
+			t.Errorf("Caller %d received %q instead of the shared response", i, page)
+		}
+	}
+	waitForFlights(t, 0)
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("The server received %d requests instead of 3", n)
+	}
+}
+
+func TestSharedFlightSourceURL(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	next := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/next" {
+			<-next
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		<-release
+		_, _ = w.Write([]byte("shared"))
+	}))
+	defer ts.Close()
+
+	// Two targets of an enumeration, a second enumeration with a quota, and another data source
+	c1, c2 := stats.NewCollector(), stats.NewCollector()
+	c2.SetQuota("NetworksDB", 1)
+	ctxs := []context.Context{
+		WithSourceURL(stats.NewContext(context.Background(), c1, "NetworksDB")),
+		WithSourceURL(stats.NewContext(context.Background(), c1, "NetworksDB")),
+		WithSourceURL(stats.NewContext(context.Background(), c2, "NetworksDB")),
+		WithSourceURL(stats.NewContext(context.Background(), c1, "RADb")),
+	}
+	// A caller accepting smaller responses must not share the response of the others
+	capped := WithSourceURL(WithMaxResponseSize(stats.NewContext(context.Background(), c1, "NetworksDB"), 1))
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(ctxs)+1)
+	for i, ctx := range append(ctxs, capped) {
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			_, errs[i] = RequestWebPage(ctx, ts.URL+"/asn?apikey=secret", nil, nil, nil)
+		}(i, ctx)
+		waitForWaiters(t, i+1)
+	}
+	waitForFlights(t, 3)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("The server received %d requests instead of 3", n)
+	}
+	for i, ctx := range ctxs {
+		if errs[i] != nil {
+			t.Errorf("Caller %d returned the error: %v", i, errs[i])
+		}
+		if u := SourceURL(ctx); u != ts.URL+"/asn?apikey=REDACTED" {
+			t.Errorf("Caller %d recorded the source URL %q", i, u)
+		}
+	}
+	if !errors.Is(errs[len(ctxs)], ErrResponseTooLarge) || SourceURL(capped) != "" {
+		t.Errorf("The caller accepting smaller responses shared the response: %v", errs[len(ctxs)])
+	}
+	// The targets of an enumeration share the accounting, while the other enumeration has its own
+	if n := c1.Source("NetworksDB").Requests; n != 2 {
+		t.Errorf("The first enumeration counted %d requests instead of 2", n)
+	}
+	if n := c2.Source("NetworksDB").Requests; n != 1 || !c2.QuotaReached("NetworksDB") {
+		t.Errorf("The second enumeration counted %d requests without its quota", n)
+	}
+	if codes := c2.StatusCodes("NetworksDB"); len(codes) != 1 || codes[0].Code != http.StatusOK {
+		t.Errorf("The second enumeration recorded the status codes %v", codes)
+	}
+	if n := c1.Source("RADb").Requests; n != 1 {
+		t.Errorf("The other data source counted %d requests instead of 1", n)
+	}
+
+	// The waiter that has used up its quota does not receive the shared response
+	first := make(chan error, 1)
+	go func() {
+		_, err := RequestWebPage(ctxs[0], ts.URL+"/next", nil, nil, nil)
+		first <- err
+	}()
+	waitForWaiters(t, 1)
+	if _, err := RequestWebPage(ctxs[2], ts.URL+"/next", nil, nil, nil); err != stats.ErrQuotaReached {
+		t.Errorf("The waiter without quota returned %v instead of the quota error", err)
+	}
+	close(next)
+	if err := <-first; err != nil {
+		t.Errorf("The first caller returned the error: %v", err)
+	}
+}
+
+func TestSharedFlightError(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := RequestWebPageWithHeaders(context.Background(), ts.URL, nil, nil, nil)
+			if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+				t.Errorf("Caller %d did not receive the response with the error", i)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("Caller %d did not receive the error of the shared request", i)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("The server received %d requests instead of 1", n)
+	}
+}
+
+func TestSharedFlightCancellation(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" {
+			<-release
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer ts.Close()
+
+	// The request continues while a caller is still waiting on it
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := RequestWebPage(first, ts.URL+"/a", nil, nil, nil)
+		firstErr <- err
+	}()
+	waitForFlights(t, 1)
+	second := make(chan string, 1)
+	go func() {
+		page, _ := RequestWebPage(context.Background(), ts.URL+"/a", nil, nil, nil)
+		second <- page
+	}()
+	waitForWaiters(t, 2)
+
+	cancelFirst()
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("The cancelled caller returned %v instead of context.Canceled", err)
+	}
+	close(release)
+	if page := <-second; page != "ok" {
+		t.Errorf("The remaining caller received %q after the other was cancelled", page)
+	}
+
+	// The request is cancelled once every caller has given up
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := RequestWebPage(ctx, ts.URL+"/b", nil, nil, nil)
+		done <- err
+	}()
+	waitForFlights(t, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("The caller returned %v instead of context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Errorf("The request was not cancelled after its only caller gave up")
+	}
+}
+
+func waitForFlights(t *testing.T, n int) {
+	t.Helper()
+
+	for i := 0; i < 500; i++ {
+		flightLock.Lock()
+		l := len(flights)
+		flightLock.Unlock()
+
+		if l == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("The number of requests in flight did not reach %d", n)
+}
+
+func waitForWaiters(t *testing.T, n int) {
+	t.Helper()
+
+	for i := 0; i < 500; i++ {
+		var waiters int
+
+		flightLock.Lock()
+		for _, f := range flights {
+			waiters += f.waiters
+		}
+		flightLock.Unlock()
+
+		if waiters == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("The number of callers waiting did not reach %d", n)
+}
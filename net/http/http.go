@@ -5,6 +5,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -26,7 +27,8 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	amassnet "github.com/aokimio/Amass/v3/net"
 	"github.com/aokimio/Amass/v3/net/dns"
-	"github.com/caffix/stringset"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/geziyor/geziyor"
 	"github.com/geziyor/geziyor/client"
 	bf "github.com/tylertreat/BoomFilters"
@@ -68,7 +70,8 @@ func init() {
 			Proxy:                 http.ProxyFromEnvironment,
 			DialContext:           amassnet.DialContext,
 			MaxIdleConns:          200,
-			MaxConnsPerHost:       50,
+			MaxConnsPerHost:       DefaultMaxConnsPerHost,
+			MaxIdleConnsPerHost:   DefaultMaxConnsPerHost,
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   handshakeTimeout,
 			ExpectContinueTimeout: 5 * time.Second,
@@ -109,16 +112,59 @@ func CheckCookie(urlString string, cookieName string) bool {
 	return found
 }
 
+// Response contains the body of an HTTP response along with the status code and headers.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
 // RequestWebPage returns a string containing the entire response for the provided URL when successful.
+// Identical requests made by a data source while one of them is in flight share its network call.
 func RequestWebPage(ctx context.Context, u string, body io.Reader, hvals map[string]string, auth *BasicAuth) (string, error) {
+	resp, err := RequestWebPageWithHeaders(ctx, u, body, hvals, auth)
+	if resp == nil {
+		return "", err
+	}
+	return resp.Body, err
+}
+
+// RequestWebPageWithHeaders behaves like RequestWebPage, but also returns the status code and
+// headers of the response. The Response is non-nil whenever the server provided a response,
+// including responses with a status code that causes an error to be returned.
+func RequestWebPageWithHeaders(ctx context.Context, u string, body io.Reader, hvals map[string]string, auth *BasicAuth) (*Response, error) {
 	method := "GET"
+	var data []byte
 	if body != nil {
 		method = "POST"
+
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	key := flightKey(ctx, method, u, data, hvals, auth)
+	return shareFlight(ctx, key, u, func(ctx context.Context) (*Response, error) {
+		return sendRequest(ctx, method, u, data, hvals, auth)
+	})
+}
+
+func sendRequest(ctx context.Context, method, u string, data []byte, hvals map[string]string, auth *BasicAuth) (*Response, error) {
+	// Requests are not sent once the data source has used up its quota for the run
+	if err := stats.TakeQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, u, body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Close = true
 
@@ -133,19 +179,67 @@ func RequestWebPage(ctx context.Context, u string, body io.Reader, hvals map[str
 		req.Header.Set(k, v)
 	}
 
-	var in string
-	resp, err := DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := clientFor(ctx, req.URL.Hostname()).Do(req)
+	if err != nil {
+		stats.RecordDuration(ctx, u, time.Since(start))
+		stats.RecordRequest(ctx, err)
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	r := &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	observeStatus(ctx, resp.StatusCode)
+	stats.RecordStatus(ctx, resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		err = fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+	}
+	// Read one byte beyond the limit to learn if the response was truncated
+	limit := MaxResponseSize(ctx)
+	if b, rerr := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1)); rerr == nil {
+		if int64(len(b)) > limit {
+			b = b[:limit]
+			if err == nil {
+				err = fmt.Errorf("%w at %d bytes, raise max_response_size to accept it", ErrResponseTooLarge, limit)
+			}
+		}
+		r.Body = string(b)
+	}
 	if err == nil {
-		defer func() { _ = resp.Body.Close() }()
+		RecordSourceURL(ctx, u)
+	}
+
+	stats.RecordDuration(ctx, u, time.Since(start))
+	stats.RecordRequest(ctx, err)
+	return r, err
+}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			err = fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+// RetryAfter returns the delay requested by the Retry-After header, which can be
+// provided as a number of seconds or an HTTP date. The second return value is false
+// when the header is missing or cannot be parsed.
+func RetryAfter(h http.Header) (time.Duration, bool) {
+	val := strings.TrimSpace(h.Get("Retry-After"))
+	if val == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(val); err == nil {
+		if secs < 0 {
+			return 0, false
 		}
-		if b, err := ioutil.ReadAll(resp.Body); err == nil {
-			in = string(b)
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
 		}
+		return 0, true
 	}
-	return in, err
+	return 0, false
 }
 
 // Crawl will spider the web page at the URL argument looking for DNS names within the scope provided.
@@ -15,8 +15,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/resolve"
-	"github.com/caffix/stringset"
 	"github.com/miekg/dns"
 )
 
@@ -136,6 +136,57 @@ func TestRequestWebPage(t *testing.T) {
 	}
 }
 
+func TestRequestWebPageWithHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, "Slow down")
+	}))
+	defer ts.Close()
+
+	resp, err := RequestWebPageWithHeaders(context.TODO(), ts.URL, nil, nil, nil)
+	if err == nil {
+		t.Errorf("Failed to return an error for the 429 status code")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests || resp.Body != "Slow down" {
+		t.Fatalf("Failed to return the response along with the error")
+	}
+	if d, ok := RetryAfter(resp.Header); !ok || d != 30*time.Second {
+		t.Errorf("Failed to return the Retry-After header value")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		val   string
+		ok    bool
+		delay time.Duration
+	}{
+		{"", false, 0},
+		{"120", true, 2 * time.Minute},
+		{"-5", false, 0},
+		{"soon", false, 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), true, 0},
+	}
+
+	for _, test := range tests {
+		h := make(http.Header)
+		if test.val != "" {
+			h.Set("Retry-After", test.val)
+		}
+
+		if d, ok := RetryAfter(h); ok != test.ok || d != test.delay {
+			t.Errorf("RetryAfter(%q) returned %v, %t, expected %v, %t", test.val, d, ok, test.delay, test.ok)
+		}
+	}
+
+	h := make(http.Header)
+	h.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if d, ok := RetryAfter(h); !ok || d <= 58*time.Minute || d > time.Hour {
+		t.Errorf("RetryAfter returned %v for an HTTP date one hour in the future", d)
+	}
+}
+
 func TestCrawl(t *testing.T) {
 	tests := []struct {
 		name  string
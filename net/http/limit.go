@@ -0,0 +1,75 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// DefaultMaxResponseSize is the largest response body, in bytes, read by RequestWebPage when
+// the context does not set a limit.
+const DefaultMaxResponseSize int64 = 10 << 20
+
+// DefaultMaxConnsPerHost is the number of simultaneous connections DefaultClient opens to a
+// single host when SetMaxConnsPerHost has not selected another limit.
+const DefaultMaxConnsPerHost = 4
+
+// ErrResponseTooLarge is returned along with the truncated body when a response exceeds the limit.
+var ErrResponseTooLarge = errors.New("the response exceeded the maximum size and was truncated")
+
+type maxResponseSizeKey struct{}
+
+type statusObserverKey struct{}
+
+// WithMaxResponseSize returns a copy of the parent context that limits the size of the
+// response bodies read by RequestWebPage to the number of bytes provided.
+func WithMaxResponseSize(parent context.Context, size int64) context.Context {
+	if size <= 0 {
+		return parent
+	}
+	return context.WithValue(parent, maxResponseSizeKey{}, size)
+}
+
+// MaxResponseSize returns the largest response body, in bytes, that will be read using the context.
+func MaxResponseSize(ctx context.Context) int64 {
+	if size, ok := ctx.Value(maxResponseSizeKey{}).(int64); ok && size > 0 {
+		return size
+	}
+	return DefaultMaxResponseSize
+}
+
+// SetMaxConnsPerHost limits the connections DefaultClient opens to each host, including those
+// in use and those being dialed. Requests beyond the limit wait for a connection to become
+// available. A value less than one selects DefaultMaxConnsPerHost. It must be called before
+// requests are sent.
+func SetMaxConnsPerHost(n int) {
+	if n < 1 {
+		n = DefaultMaxConnsPerHost
+	}
+	if t, ok := DefaultClient.Transport.(*http.Transport); ok {
+		limitConnsPerHost(t, n)
+	}
+}
+
+func limitConnsPerHost(t *http.Transport, n int) {
+	t.MaxConnsPerHost = n
+	if t.MaxIdleConnsPerHost < n {
+		t.MaxIdleConnsPerHost = n
+	}
+}
+
+// WithStatusObserver returns a copy of the parent context that provides the status code of
+// each response received by RequestWebPage to the function.
+func WithStatusObserver(parent context.Context, fn func(status int)) context.Context {
+	return context.WithValue(parent, statusObserverKey{}, fn)
+}
+
+func observeStatus(ctx context.Context, status int) {
+	if fn, ok := ctx.Value(statusObserverKey{}).(func(int)); ok && fn != nil {
+		fn(status)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxResponseSize(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	page, err := RequestWebPage(WithMaxResponseSize(context.Background(), 1024), ts.URL, nil, nil, nil)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("The oversized response did not return ErrResponseTooLarge: %v", err)
+	}
+	if len(page) != 1024 {
+		t.Errorf("The response was truncated to %d bytes instead of 1024", len(page))
+	}
+
+	page, err = RequestWebPage(WithMaxResponseSize(context.Background(), int64(len(body))), ts.URL, nil, nil, nil)
+	if err != nil || page != body {
+		t.Errorf("The response at the limit was not returned intact: %v", err)
+	}
+	if size := MaxResponseSize(WithMaxResponseSize(context.Background(), 0)); size != DefaultMaxResponseSize {
+		t.Errorf("A zero limit should select the default, got %d", size)
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	var active, peak int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	// The limit is applied to a copy of the transport, since the connections of the other
+	// tests may still be using the transport of DefaultClient
+	orig := DefaultClient.Transport
+	transport := orig.(*http.Transport).Clone()
+	limitConnsPerHost(transport, 2)
+	DefaultClient.Transport = transport
+	defer func() {
+		DefaultClient.Transport = orig
+		transport.CloseIdleConnections()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		// Distinct URLs keep the requests from sharing a flight
+		go func(n int) {
+			defer wg.Done()
+			_, _ = RequestWebPage(context.Background(), ts.URL+"/?n="+strconv.Itoa(n), nil, nil, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("The host received %d simultaneous requests with a limit of 2", peak)
+	}
+	if peak == 0 {
+		t.Errorf("The requests did not reach the host")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Query parameters with names containing these strings have their values removed from recorded URLs
+var secretParams = []string{"key", "token", "secret", "pass", "auth", "sig"}
+
+type sourceURLKey struct{}
+
+type sourceURL struct {
+	sync.Mutex
+	u string
+}
+
+// WithSourceURL returns a copy of the parent context that records the URL of the last web page
+// successfully requested with it, so the findings extracted from the page can be attributed to it.
+func WithSourceURL(parent context.Context) context.Context {
+	return context.WithValue(parent, sourceURLKey{}, &sourceURL{})
+}
+
+// SourceURL returns the URL of the last web page requested with the context, with the secrets
+// removed, or an empty string when the context does not record the URL.
+func SourceURL(ctx context.Context) string {
+	if s, ok := ctx.Value(sourceURLKey{}).(*sourceURL); ok {
+		s.Lock()
+		defer s.Unlock()
+
+		return s.u
+	}
+	return ""
+}
+
+// RecordSourceURL saves the URL in the context as the last web page requested, which allows
+// responses served from a cache to be attributed to the page.
+func RecordSourceURL(ctx context.Context, u string) {
+	if s, ok := ctx.Value(sourceURLKey{}).(*sourceURL); ok {
+		s.Lock()
+		s.u = RedactURL(u)
+		s.Unlock()
+	}
+}
+
+// RedactURL removes the user information and the values of query parameters that appear to hold
+// API keys or other secrets from the URL.
+func RedactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	parsed.User = nil
+	if parsed.RawQuery != "" {
+		q := parsed.Query()
+		for name := range q {
+			lower := strings.ToLower(name)
+
+			for _, s := range secretParams {
+				if strings.Contains(lower, s) {
+					q.Set(name, "REDACTED")
+					break
+				}
+			}
+		}
+		parsed.RawQuery = q.Encode()
+	}
+	return parsed.String()
+}
@@ -6,6 +6,7 @@ package net
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"math/big"
 	"net"
 	"strconv"
@@ -54,33 +55,49 @@ func init() {
 	}
 }
 
-// DialContext performs the dial using global variables (e.g. LocalAddr).
-func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
-	d := &net.Dialer{DualStack: true}
+// SetLocalAddr selects the local IP address that the network connections are made from.
+// An error is returned when the address is not assigned to this host.
+func SetLocalAddr(addr string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("%s is not a valid IP address", addr)
+	}
 
-	_, p, err := net.SplitHostPort(addr)
+	pc, err := net.ListenPacket("udp", net.JoinHostPort(ip.String(), "0"))
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("the local address %s cannot be assigned: %v", addr, err)
 	}
+	_ = pc.Close()
 
-	port, err := strconv.Atoi(p)
-	if err != nil {
-		return nil, err
+	LocalAddr = &net.IPAddr{IP: ip}
+	return nil
+}
+
+// LocalIP returns the IP address selected by LocalAddr, or nil when no address was selected.
+func LocalIP() net.IP {
+	switch a := LocalAddr.(type) {
+	case *net.IPNet:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
 	}
+	return nil
+}
 
-	if LocalAddr != nil {
-		addr, _, err := net.ParseCIDR(LocalAddr.String())
-
-		if err == nil && strings.HasPrefix(network, "tcp") {
-			d.LocalAddr = &net.TCPAddr{
-				IP:   addr,
-				Port: port,
-			}
-		} else if err == nil && strings.HasPrefix(network, "udp") {
-			d.LocalAddr = &net.UDPAddr{
-				IP:   addr,
-				Port: port,
-			}
+// DialContext performs the dial using global variables (e.g. LocalAddr).
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{DualStack: true}
+
+	// The local port is left for the system to select
+	if ip := LocalIP(); ip != nil {
+		if strings.HasPrefix(network, "tcp") {
+			d.LocalAddr = &net.TCPAddr{IP: ip}
+		} else if strings.HasPrefix(network, "udp") {
+			d.LocalAddr = &net.UDPAddr{IP: ip}
 		}
 	}
 
@@ -118,6 +135,46 @@ func IsReservedAddress(addr string) (bool, string) {
 	return false, ""
 }
 
+// LongestPrefixMatch returns the most specific of the provided CIDRs that contains the IP address,
+// or an empty string when none of them do. Equally specific matches are broken by the lowest
+// string, so the selection does not depend on the order of the CIDRs.
+func LongestPrefixMatch(ip net.IP, cidrs []string) string {
+	if ip == nil {
+		return ""
+	}
+
+	var match string
+	bits := -1
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil || !ipnet.Contains(ip) {
+			continue
+		}
+
+		ones, _ := ipnet.Mask.Size()
+		if ones > bits || (ones == bits && cidr < match) {
+			bits = ones
+			match = cidr
+		}
+	}
+	return match
+}
+
+// CanonicalCIDR returns the CIDR in the form used to compare netblocks: the network address in
+// lowercase followed by the prefix length, without whitespace. An empty string is returned when
+// the CIDR is not valid.
+func CanonicalCIDR(cidr string) string {
+	cidr = strings.ToLower(strings.Join(strings.Fields(cidr), ""))
+
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+	return ipnet.String()
+}
+
 // FirstLast return the first and last IP address of the provided CIDR/netblock.
 func FirstLast(cidr *net.IPNet) (net.IP, net.IP) {
 	firstIP := cidr.IP
@@ -4,6 +4,7 @@
 package net
 
 import (
+	"context"
 	"net"
 	"strconv"
 	"testing"
@@ -49,6 +50,64 @@ func TestIsIPv6(t *testing.T) {
 	}
 }
 
+func TestLongestPrefixMatch(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.2.0/26", "192.168.0.0/16", "2001:db8::/32", "2001:db8:1::/48"}
+
+	tests := []struct {
+		Address  string
+		Expected string
+	}{
+		{"10.1.2.3", "10.1.2.0/26"},
+		{"10.1.2.200", "10.1.2.0/24"},
+		{"10.1.3.1", "10.1.0.0/16"},
+		{"10.200.0.1", "10.0.0.0/8"},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"2001:db8:2::1", "2001:db8::/32"},
+		{"172.16.0.1", ""},
+	}
+
+	for _, test := range tests {
+		if got := LongestPrefixMatch(net.ParseIP(test.Address), cidrs); got != test.Expected {
+			t.Errorf("%s: expected %s, got %s", test.Address, test.Expected, got)
+		}
+		// The selection must not depend on the order of the netblocks
+		reversed := make([]string, len(cidrs))
+		for i, cidr := range cidrs {
+			reversed[len(cidrs)-1-i] = cidr
+		}
+		if got := LongestPrefixMatch(net.ParseIP(test.Address), reversed); got != test.Expected {
+			t.Errorf("%s: expected %s from the reversed netblocks, got %s", test.Address, test.Expected, got)
+		}
+	}
+
+	if got := LongestPrefixMatch(nil, cidrs); got != "" {
+		t.Errorf("A match was returned for a nil address: %s", got)
+	}
+}
+
+func TestCanonicalCIDR(t *testing.T) {
+	tests := []struct {
+		cidr     string
+		expected string
+	}{
+		{cidr: "72.237.4.0/24", expected: "72.237.4.0/24"},
+		{cidr: " 72.237.4.0/24\n", expected: "72.237.4.0/24"},
+		{cidr: "72.237.4.113/24", expected: "72.237.4.0/24"},
+		{cidr: "72.237.4.0 / 24", expected: "72.237.4.0/24"},
+		{cidr: "2001:DB8:0:0::/32", expected: "2001:db8::/32"},
+		{cidr: "2001:0db8:0000::1/48", expected: "2001:db8::/48"},
+		{cidr: "::FFFF:72.237.4.0/120", expected: "72.237.4.0/24"},
+		{cidr: "72.237.4.0", expected: ""},
+		{cidr: "not a cidr", expected: ""},
+	}
+
+	for _, test := range tests {
+		if got := CanonicalCIDR(test.cidr); got != test.expected {
+			t.Errorf("CanonicalCIDR(%q) = %q, expected %q", test.cidr, got, test.expected)
+		}
+	}
+}
+
 func TestFirstLast(t *testing.T) {
 	tests := []struct {
 		CIDR          string
@@ -252,3 +311,39 @@ func TestIPDec(t *testing.T) {
 		}
 	}
 }
+
+func TestSetLocalAddr(t *testing.T) {
+	defer func() { LocalAddr = nil }()
+
+	if err := SetLocalAddr("not an address"); err == nil {
+		t.Errorf("An invalid address was accepted")
+	}
+	if err := SetLocalAddr("192.0.2.123"); err == nil {
+		t.Errorf("An address not assigned to this host was accepted")
+	}
+	if err := SetLocalAddr("127.0.0.1"); err != nil {
+		t.Fatalf("The loopback address was not accepted: %v", err)
+	}
+	if ip := LocalIP(); !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("The local address was not selected: %v", ip)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	// The interface addresses are accepted as well, and the local port is not reused
+	LocalAddr = &net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}
+	conn, err := DialContext(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial from the local address: %v", err)
+	}
+	defer conn.Close()
+
+	if local := conn.LocalAddr().(*net.TCPAddr); !local.IP.Equal(net.ParseIP("127.0.0.1")) ||
+		local.Port == l.Addr().(*net.TCPAddr).Port {
+		t.Errorf("The connection was made from an unexpected address: %v", local)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package rpki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aokimio/Amass/v3/net/http"
+)
+
+const ripestatBaseURL = "https://stat.ripe.net"
+
+// RIPEstat validates routes using the RPKI validation endpoint of the RIPEstat Data API.
+type RIPEstat struct {
+	baseURL string
+}
+
+// NewRIPEstat returns the Validator for the RIPEstat Data API, sending the requests to
+// the base URL when one is provided.
+func NewRIPEstat(baseURL string) *RIPEstat {
+	if baseURL == "" {
+		baseURL = ripestatBaseURL
+	}
+	return &RIPEstat{baseURL: baseURL}
+}
+
+// String implements the Validator interface.
+func (r *RIPEstat) String() string {
+	return "ripestat"
+}
+
+// Validate implements the Validator interface.
+func (r *RIPEstat) Validate(ctx context.Context, prefix *net.IPNet, asn int) (string, error) {
+	u := r.baseURL + "/data/rpki-validation/data.json?sourceapp=amass&resource=AS" +
+		strconv.Itoa(asn) + "&prefix=" + url.QueryEscape(prefix.String())
+	page, err := http.RequestWebPage(ctx, u, nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(page), &resp); err != nil {
+		return "", err
+	}
+	if resp.Status != "ok" {
+		return "", fmt.Errorf("%s: the response for %s did not include the validation status", r.String(), prefix)
+	}
+
+	// The API also reports the invalid_asn and invalid_length reasons for an invalid route
+	switch status := strings.ToLower(resp.Data.Status); {
+	case status == "valid":
+		return Valid, nil
+	case strings.HasPrefix(status, "invalid"):
+		return Invalid, nil
+	case status == "unknown" || status == "not_found" || status == "notfound":
+		return NotFound, nil
+	}
+	return "", fmt.Errorf("%s: unexpected validation status %q for %s", r.String(), resp.Data.Status, prefix)
+}
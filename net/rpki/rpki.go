@@ -0,0 +1,168 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package rpki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+// The RPKI validation states of a route, as defined by RFC 6811.
+const (
+	Valid    = "valid"
+	Invalid  = "invalid"
+	NotFound = "not_found"
+)
+
+// Validator checks the origin of routes against the validated ROA payloads of the RPKI.
+type Validator interface {
+	// String returns the name of the validator
+	String() string
+
+	// Validate returns the validation state of the prefix originated by the autonomous system
+	Validate(ctx context.Context, prefix *net.IPNet, asn int) (string, error)
+}
+
+var (
+	validatorsLock sync.Mutex
+	validators     = make(map[string]func(cfg *config.RPKIConfig) (Validator, error))
+)
+
+func init() {
+	RegisterValidator("ripestat", func(cfg *config.RPKIConfig) (Validator, error) { return NewRIPEstat(cfg.URL), nil })
+	RegisterValidator(config.RPKIFileValidator, func(cfg *config.RPKIConfig) (Validator, error) { return LoadVRPFile(cfg.VRPFile) })
+}
+
+// RegisterValidator makes the validator available for selection by name
+// in the validator setting of the rpki configuration section.
+func RegisterValidator(name string, f func(cfg *config.RPKIConfig) (Validator, error)) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+
+	validators[strings.ToLower(name)] = f
+}
+
+// Checker validates the routes with the configured validator, checking each route once and
+// performing no more than the configured number of checks, at the configured rate.
+type Checker struct {
+	sync.Mutex
+	validator Validator
+	max       int
+	checks    int
+	interval  time.Duration
+	next      time.Time
+	cache     map[string]string
+}
+
+// NewChecker returns the Checker for the validator selected in the configuration, or nil
+// when RPKI validation has not been configured.
+func NewChecker(cfg *config.Config) (*Checker, error) {
+	rc := cfg.RPKI
+	if rc == nil {
+		return nil, nil
+	}
+
+	validatorsLock.Lock()
+	f, found := validators[rc.Validator]
+	validatorsLock.Unlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown RPKI validator: %s", rc.Validator)
+	}
+
+	v, err := f(rc)
+	if err != nil {
+		return nil, fmt.Errorf("RPKI validator %s: %v", rc.Validator, err)
+	}
+	// The local file of VRPs does not need to be protected from a high rate of checks
+	rate := rc.Rate
+	if rc.Validator == config.RPKIFileValidator {
+		rate = 0
+	}
+	return NewCheckerWithValidator(v, rc.MaxChecks, rate), nil
+}
+
+// NewCheckerWithValidator returns a Checker that performs at most max checks using the
+// validator, and no more than rate checks each second. The rate is not limited when zero.
+func NewCheckerWithValidator(v Validator, max, rate int) *Checker {
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Second / time.Duration(rate)
+	}
+
+	return &Checker{
+		validator: v,
+		max:       max,
+		interval:  interval,
+		cache:     make(map[string]string),
+	}
+}
+
+// Check returns the validation state of the prefix originated by the ASN and true when this is the
+// first check of the route. An empty state is returned when the route could not be checked, such as
+// after the limit on the number of checks has been reached.
+func (c *Checker) Check(ctx context.Context, cidr string, asn int) (string, bool) {
+	_, prefix, err := net.ParseCIDR(cidr)
+	if err != nil || asn <= 0 {
+		return "", false
+	}
+
+	key := prefix.String() + " AS" + strconv.Itoa(asn)
+	c.Lock()
+	if state, found := c.cache[key]; found {
+		c.Unlock()
+		return state, false
+	}
+	if c.max > 0 && c.checks >= c.max {
+		c.Unlock()
+		return "", false
+	}
+	// Claim the route and reserve the time of the check, so concurrent callers neither check
+	// the route again nor exceed the rate
+	c.cache[key] = ""
+	c.checks++
+	now := time.Now()
+	at := c.next
+	if at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.interval)
+	c.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", false
+		case <-t.C:
+		}
+	}
+
+	state, err := c.validator.Validate(ctx, prefix, asn)
+	if err != nil {
+		state = ""
+	}
+
+	c.Lock()
+	c.cache[key] = state
+	c.Unlock()
+	return state, true
+}
+
+// Checks returns the number of routes sent to the validator.
+func (c *Checker) Checks() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.checks
+}
@@ -0,0 +1,197 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package rpki
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aokimio/Amass/v3/config"
+)
+
+type testValidator struct {
+	calls int32
+}
+
+func (v *testValidator) String() string { return "test" }
+
+func (v *testValidator) Validate(ctx context.Context, prefix *net.IPNet, asn int) (string, error) {
+	atomic.AddInt32(&v.calls, 1)
+	if prefix.String() == "192.0.2.0/24" {
+		return "", fmt.Errorf("the validator failed")
+	}
+	if asn == 13335 {
+		return Valid, nil
+	}
+	return Invalid, nil
+}
+
+func TestChecker(t *testing.T) {
+	v := &testValidator{}
+	c := NewCheckerWithValidator(v, 4, 20)
+
+	if state, first := c.Check(context.Background(), "104.16.0.0/13", 13335); state != Valid || !first {
+		t.Errorf("The valid route was not reported: %s %v", state, first)
+	}
+	// The route is only sent to the validator once, in its canonical form
+	if state, first := c.Check(context.Background(), "104.16.1.0/13", 13335); state != Valid || first {
+		t.Errorf("The cached state was not returned: %s %v", state, first)
+	}
+
+	start := time.Now()
+	// The same prefix originated by another ASN is a different route
+	if state, first := c.Check(context.Background(), "104.16.0.0/13", 64496); state != Invalid || !first {
+		t.Errorf("The invalid route was not reported: %s %v", state, first)
+	}
+	if state, _ := c.Check(context.Background(), "192.0.2.0/24", 13335); state != "" {
+		t.Errorf("A state was reported when the validator failed: %s", state)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("The checks were not rate limited: %v", elapsed)
+	}
+
+	if state, first := c.Check(context.Background(), "198.51.100.0/24", 13335); state != Valid || !first {
+		t.Errorf("The last route within the limit was not checked: %s %v", state, first)
+	}
+	if state, first := c.Check(context.Background(), "203.0.113.0/24", 13335); state != "" || first {
+		t.Errorf("The route was checked beyond the limit: %s %v", state, first)
+	}
+	if calls := atomic.LoadInt32(&v.calls); calls != 4 || c.Checks() != 4 {
+		t.Errorf("Unexpected number of checks: %d calls, %d checks", calls, c.Checks())
+	}
+	if state, first := c.Check(context.Background(), "104.16.0.0/13", 0); state != "" || first {
+		t.Errorf("The route without an origin was checked")
+	}
+}
+
+func TestVRPSet(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonFile := filepath.Join(dir, "vrps.json")
+	_ = ioutil.WriteFile(jsonFile, []byte(`{"metadata":{"counts":3},"roas":[
+		{"asn":"AS13335","prefix":"104.16.0.0/12","maxLength":20,"ta":"arin"},
+		{"asn":13335,"prefix":"2606:4700::/32","maxLength":48,"ta":"arin"},
+		{"asn":0,"prefix":"192.0.2.0/24","maxLength":24,"ta":"apnic"}]}`), 0600)
+	csvFile := filepath.Join(dir, "vrps.csv")
+	_ = ioutil.WriteFile(csvFile, []byte("ASN,IP Prefix,Max Length,Trust Anchor\n"+
+		"AS13335,104.16.0.0/12,20,arin\nAS13335,2606:4700::/32,48,arin\nAS0,192.0.2.0/24,24,apnic\n"), 0600)
+
+	for _, file := range []string{jsonFile, csvFile} {
+		s, err := LoadVRPFile(file)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", file, err)
+		}
+		if s.Len() != 3 {
+			t.Errorf("Expected 3 VRPs in %s, got %d", file, s.Len())
+		}
+
+		for _, test := range []struct {
+			prefix   string
+			asn      int
+			expected string
+		}{
+			{"104.16.0.0/13", 13335, Valid},
+			{"104.16.0.0/12", 13335, Valid},
+			{"104.16.0.0/24", 13335, Invalid},
+			{"104.16.0.0/13", 64496, Invalid},
+			{"2606:4700:10::/48", 13335, Valid},
+			{"192.0.2.0/24", 64496, Invalid},
+			{"198.51.100.0/24", 13335, NotFound},
+		} {
+			_, prefix, _ := net.ParseCIDR(test.prefix)
+			if state, _ := s.Validate(context.Background(), prefix, test.asn); state != test.expected {
+				t.Errorf("%s: unexpected state for %s AS%d: got %s, expected %s",
+					filepath.Base(file), test.prefix, test.asn, state, test.expected)
+			}
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	_ = ioutil.WriteFile(bad, []byte("AS13335,104.16.0.0/12,8\n"), 0600)
+	if _, err := LoadVRPFile(bad); err == nil {
+		t.Errorf("The maximum length shorter than the prefix was accepted")
+	}
+
+	cfg := config.NewConfig()
+	cfg.RPKI = &config.RPKIConfig{Validator: config.RPKIFileValidator, VRPFile: jsonFile, MaxChecks: 10, Rate: 1}
+	c, err := NewChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the checker: %v", err)
+	}
+	// The checks against the local file are not rate limited
+	start := time.Now()
+	for _, prefix := range []string{"104.16.0.0/13", "104.24.0.0/14", "104.28.0.0/15"} {
+		if state, _ := c.Check(context.Background(), prefix, 13335); state != Valid {
+			t.Errorf("Unexpected state for %s: %s", prefix, state)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("The checks against the file were rate limited: %v", elapsed)
+	}
+
+	cfg.RPKI.VRPFile = filepath.Join(dir, "missing.json")
+	if _, err := NewChecker(cfg); err == nil {
+		t.Errorf("The missing VRP file was accepted")
+	}
+}
+
+func TestRIPEstat(t *testing.T) {
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/data/rpki-validation/data.json" {
+			nethttp.NotFound(w, r)
+			return
+		}
+
+		switch r.URL.Query().Get("resource") + " " + r.URL.Query().Get("prefix") {
+		case "AS13335 104.16.0.0/13":
+			fmt.Fprint(w, `{"status":"ok","data":{"status":"valid","validating_roas":[]}}`)
+		case "AS64496 104.16.0.0/13":
+			fmt.Fprint(w, `{"status":"ok","data":{"status":"invalid_asn","validating_roas":[]}}`)
+		case "AS13335 198.51.100.0/24":
+			fmt.Fprint(w, `{"status":"ok","data":{"status":"unknown","validating_roas":[]}}`)
+		default:
+			fmt.Fprint(w, `{"status":"error","messages":[["error","invalid resource"]]}`)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.NewConfig()
+	cfg.RPKI = &config.RPKIConfig{Validator: "ripestat", URL: srv.URL, MaxChecks: 10, Rate: 100}
+	c, err := NewChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the checker: %v", err)
+	}
+
+	for _, test := range []struct {
+		prefix   string
+		asn      int
+		expected string
+	}{
+		{"104.16.0.0/13", 13335, Valid},
+		{"104.16.0.0/13", 64496, Invalid},
+		{"198.51.100.0/24", 13335, NotFound},
+		{"192.0.2.0/24", 13335, ""},
+	} {
+		if state, _ := c.Check(context.Background(), test.prefix, test.asn); state != test.expected {
+			t.Errorf("Unexpected state for %s AS%d: got %q, expected %q", test.prefix, test.asn, state, test.expected)
+		}
+	}
+
+	cfg.RPKI.Validator = "unknown"
+	if _, err := NewChecker(cfg); err == nil {
+		t.Errorf("The unknown validator was accepted")
+	}
+	if c, err := NewChecker(config.NewConfig()); c != nil || err != nil {
+		t.Errorf("A checker was returned without the configuration")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package rpki
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// VRP is a validated ROA payload, authorizing the ASN to originate the prefix and the more
+// specific prefixes up to the maximum length.
+type VRP struct {
+	ASN       int
+	Prefix    *net.IPNet
+	MaxLength int
+}
+
+// VRPSet validates routes against the VRPs exported by a relying party, such as Routinator
+// or rpki-client, without sending any requests.
+type VRPSet struct {
+	// The VRPs keyed by their prefix in canonical form
+	vrps map[string][]VRP
+	size int
+}
+
+// LoadVRPFile returns the VRPSet read from the file. The JSON export holding a "roas" list,
+// and the CSV export with the ASN, IP Prefix and Max Length columns, are supported.
+func LoadVRPFile(path string) (*VRPSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var vrps []VRP
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		vrps, err = parseVRPJSON(trimmed)
+	} else {
+		vrps, err = parseVRPCSV(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(vrps) == 0 {
+		return nil, fmt.Errorf("%s: the file does not hold any VRPs", path)
+	}
+	return NewVRPSet(vrps), nil
+}
+
+// NewVRPSet returns a VRPSet that validates routes against the VRPs.
+func NewVRPSet(vrps []VRP) *VRPSet {
+	s := &VRPSet{vrps: make(map[string][]VRP)}
+
+	for _, v := range vrps {
+		key := v.Prefix.String()
+		s.vrps[key] = append(s.vrps[key], v)
+		s.size++
+	}
+	return s
+}
+
+// Len returns the number of VRPs in the set.
+func (s *VRPSet) Len() int {
+	return s.size
+}
+
+// String implements the Validator interface.
+func (s *VRPSet) String() string {
+	return "vrp_file"
+}
+
+// Validate implements the Validator interface, following the route origin validation
+// procedure of RFC 6811.
+func (s *VRPSet) Validate(ctx context.Context, prefix *net.IPNet, asn int) (string, error) {
+	ones, bits := prefix.Mask.Size()
+
+	state := NotFound
+	// Each VRP covering the route is keyed by the route address masked to the VRP length
+	for l := 0; l <= ones; l++ {
+		covering := &net.IPNet{IP: prefix.IP.Mask(net.CIDRMask(l, bits)), Mask: net.CIDRMask(l, bits)}
+
+		for _, v := range s.vrps[covering.String()] {
+			// A VRP for AS0 never authorizes an origin
+			if v.ASN != 0 && v.ASN == asn && ones <= v.MaxLength {
+				return Valid, nil
+			}
+			state = Invalid
+		}
+	}
+	return state, nil
+}
+
+func parseVRPJSON(data []byte) ([]VRP, error) {
+	var export struct {
+		ROAs []struct {
+			ASN       json.RawMessage `json:"asn"`
+			Prefix    string          `json:"prefix"`
+			MaxLength int             `json:"maxLength"`
+		} `json:"roas"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	var vrps []VRP
+	for _, r := range export.ROAs {
+		// The ASN is exported as a number or as a string such as "AS13335"
+		v, err := newVRP(strings.Trim(string(r.ASN), `"`), r.Prefix, r.MaxLength)
+		if err != nil {
+			return nil, err
+		}
+		vrps = append(vrps, v)
+	}
+	return vrps, nil
+}
+
+func parseVRPCSV(r io.Reader) ([]VRP, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var vrps []VRP
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 3 {
+			return nil, errors.New("the CSV records must hold the ASN, prefix and maximum length")
+		}
+		// Skip the header
+		if strings.EqualFold(strings.TrimSpace(rec[0]), "ASN") {
+			continue
+		}
+
+		max, err := strconv.Atoi(strings.TrimSpace(rec[2]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid maximum length %q", rec[2])
+		}
+
+		v, err := newVRP(rec[0], rec[1], max)
+		if err != nil {
+			return nil, err
+		}
+		vrps = append(vrps, v)
+	}
+	return vrps, nil
+}
+
+func newVRP(asn, prefix string, max int) (VRP, error) {
+	asn = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(asn)), "AS")
+	num, err := strconv.Atoi(asn)
+	if err != nil || num < 0 {
+		return VRP{}, fmt.Errorf("invalid ASN %q", asn)
+	}
+
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(prefix))
+	if err != nil {
+		return VRP{}, err
+	}
+
+	ones, _ := ipnet.Mask.Size()
+	// The maximum length defaults to the length of the prefix
+	if max == 0 {
+		max = ones
+	}
+	if max < ones {
+		return VRP{}, fmt.Errorf("the maximum length of %s is shorter than the prefix", ipnet)
+	}
+	return VRP{ASN: num, Prefix: ipnet, MaxLength: max}, nil
+}
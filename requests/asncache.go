@@ -7,8 +7,10 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/caffix/stringset"
+	amassnet "github.com/aokimio/Amass/v3/net"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/yl2chen/cidranger"
 )
 
@@ -36,8 +38,9 @@ var reservedCIDRs = []string{
 // ASNCache builds a cache of ASN and netblock information.
 type ASNCache struct {
 	sync.RWMutex
-	cache  map[int]*ASNRequest
-	ranger cidranger.Ranger
+	cache    map[int]*ASNRequest
+	ranger   cidranger.Ranger
+	observer func(*ASNRequest)
 }
 
 type cacheRangerEntry struct {
@@ -68,25 +71,60 @@ func NewASNCache() *ASNCache {
 	}
 }
 
-// Update uses the saves the information in ASNRequest into the ASNCache.
+// Update uses the saves the information in ASNRequest into the ASNCache. When the ASN is already
+// cached, the information is merged into the existing entry: the netblocks are combined, empty
+// fields are filled, and the data source is added to the sources that contributed to the entry.
 func (c *ASNCache) Update(req *ASNRequest) {
+	c.update(req)
+
+	c.RLock()
+	observer := c.observer
+	c.RUnlock()
+
+	if observer != nil {
+		observer(req)
+	}
+}
+
+// SetObserver registers the function that receives each ASNRequest provided to Update, after it
+// has been saved into the cache. Passing nil removes the observer.
+func (c *ASNCache) SetObserver(fn func(*ASNRequest)) {
 	c.Lock()
 	defer c.Unlock()
 
+	c.observer = fn
+}
+
+func (c *ASNCache) update(req *ASNRequest) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if req.FirstSeen.IsZero() {
+		req.FirstSeen = now
+	}
+	if req.LastSeen.IsZero() {
+		req.LastSeen = now
+	}
+
 	as, found := c.cache[req.ASN]
 	if !found {
 		c.cache[req.ASN] = req
-		if len(req.Netblocks) == 0 {
-			req.Netblocks = []string{req.Prefix}
-		}
+		req.Prefix = amassnet.CanonicalCIDR(req.Prefix)
+		req.Netblocks = mergeNetblocks(nil, append([]string{req.Prefix}, req.Netblocks...))
+		req.Sources = mergeSources(nil, append([]string{req.Source}, req.Sources...))
+		req.SplitNetblocks()
 		return
 	}
 
 	// This is additional information for an ASN entry
+	if as.Prefix == "" {
+		as.Prefix = amassnet.CanonicalCIDR(req.Prefix)
+	}
 	if as.CC == "" && req.CC != "" {
 		as.CC = req.CC
 	}
-	if as.Registry == "" && req.Registry != "" {
+	if knownRegistry(req.Registry) && !knownRegistry(as.Registry) {
 		as.Registry = req.Registry
 	}
 	if as.AllocationDate.IsZero() && !req.AllocationDate.IsZero() {
@@ -95,22 +133,75 @@ func (c *ASNCache) Update(req *ASNRequest) {
 	if len(as.Description) < len(req.Description) {
 		as.Description = req.Description
 	}
+	// Rediscovery moves the last seen time forward without resetting the first seen time
+	if req.FirstSeen.Before(as.FirstSeen) {
+		as.FirstSeen = req.FirstSeen
+	}
+	if req.LastSeen.After(as.LastSeen) {
+		as.LastSeen = req.LastSeen
+	}
 
 	// Add new CIDR ranges to cached netblocks
-	for _, cidr := range append([]string{req.Prefix}, req.Netblocks...) {
-		var known bool
+	as.Netblocks = mergeNetblocks(as.Netblocks, append([]string{req.Prefix}, req.Netblocks...))
+	as.SplitNetblocks()
+	as.Sources = mergeSources(as.Sources, append([]string{req.Source}, req.Sources...))
+	// Add the names of networks that were not already known
+	for cidr, name := range req.NetblockNames {
+		if as.NetblockNames == nil {
+			as.NetblockNames = make(map[string]string)
+		}
+		if _, found := as.NetblockNames[cidr]; !found && name != "" {
+			as.NetblockNames[cidr] = name
+		}
+	}
+}
 
-		for _, prefix := range as.Netblocks {
+// mergeNetblocks appends the CIDRs that are not already among the netblocks, in their canonical
+// form, so the same network reported with different notation by two sources is only kept once.
+func mergeNetblocks(netblocks, cidrs []string) []string {
+	for _, cidr := range cidrs {
+		cidr = amassnet.CanonicalCIDR(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		var known bool
+		for _, prefix := range netblocks {
 			if prefix == cidr {
 				known = true
 				break
 			}
 		}
+		if !known {
+			netblocks = append(netblocks, cidr)
+		}
+	}
+	return netblocks
+}
+
+func mergeSources(sources, more []string) []string {
+	for _, src := range more {
+		if src == "" {
+			continue
+		}
 
+		var known bool
+		for _, s := range sources {
+			if s == src {
+				known = true
+				break
+			}
+		}
 		if !known {
-			as.Netblocks = append(as.Netblocks, cidr)
+			sources = append(sources, src)
 		}
 	}
+	return sources
+}
+
+// knownRegistry returns false for the placeholders used when a source could not identify the registry.
+func knownRegistry(registry string) bool {
+	return registry != "" && registry != "N/A"
 }
 
 // DescriptionSearch matches the provided string against description fields in the cache and
@@ -122,7 +213,7 @@ func (c *ASNCache) DescriptionSearch(s string) []*ASNRequest {
 	var matches []*ASNRequest
 	for _, entry := range c.cache {
 		if strings.Contains(entry.Description, s) {
-			matches = append(matches, entry)
+			matches = append(matches, copyASNRequest(entry))
 		}
 	}
 	return matches
@@ -134,7 +225,28 @@ func (c *ASNCache) ASNSearch(asn int) *ASNRequest {
 	c.Lock()
 	defer c.Unlock()
 
-	return c.cache[asn]
+	if entry, found := c.cache[asn]; found {
+		return copyASNRequest(entry)
+	}
+	return nil
+}
+
+// copyASNRequest returns a deep copy of the cache entry, so callers can read it while
+// concurrent updates modify the netblocks held by the cache.
+func copyASNRequest(entry *ASNRequest) *ASNRequest {
+	c := *entry
+
+	c.Netblocks = append([]string(nil), entry.Netblocks...)
+	c.IPv4Netblocks = append([]string(nil), entry.IPv4Netblocks...)
+	c.IPv6Netblocks = append([]string(nil), entry.IPv6Netblocks...)
+	c.Sources = append([]string(nil), entry.Sources...)
+	if entry.NetblockNames != nil {
+		c.NetblockNames = make(map[string]string, len(entry.NetblockNames))
+		for k, v := range entry.NetblockNames {
+			c.NetblockNames[k] = v
+		}
+	}
+	return &c
 }
 
 // AddrSearch returns the cached ASN / netblock info that the addr parameter belongs in,
@@ -175,16 +287,20 @@ func (c *ASNCache) AddrSearch(addr string) *ASNRequest {
 	defer netblocks.Close()
 
 	netblocks.InsertMany(entry.Data.Netblocks...)
-	return &ASNRequest{
+	req := &ASNRequest{
 		Address:     addr,
 		ASN:         entry.Data.ASN,
 		CC:          entry.Data.CC,
 		Prefix:      prefix,
 		Netblocks:   netblocks.Slice(),
 		Description: entry.Data.Description,
+		FirstSeen:   entry.Data.FirstSeen,
+		LastSeen:    entry.Data.LastSeen,
 		Tag:         RIR,
 		Source:      "RIR",
 	}
+	req.SplitNetblocks()
+	return req
 }
 
 func (c *ASNCache) searchRangerData(ip net.IP) *cacheRangerEntry {
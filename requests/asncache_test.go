@@ -4,7 +4,10 @@
 package requests
 
 import (
+	"fmt"
 	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -60,6 +63,164 @@ func TestUpdate(t *testing.T) {
 	if entry := cache.AddrSearch("8.24.68.1"); entry == nil || entry.ASN != 26808 {
 		t.Errorf("Update failed to add the new netblock to the ASN")
 	}
+
+	cache.Update(&ASNRequest{
+		ASN:           26808,
+		Prefix:        "8.24.68.0/23",
+		NetblockNames: map[string]string{"8.24.68.0/23": "UTICA-NET", "72.237.4.0/24": ""},
+		Tag:           RIR,
+		Source:        "RIR",
+	})
+	cache.Update(&ASNRequest{
+		ASN:           26808,
+		Prefix:        "8.24.68.0/23",
+		NetblockNames: map[string]string{"8.24.68.0/23": "OTHER-NET"},
+		Tag:           RIR,
+		Source:        "RIR",
+	})
+
+	if entry := cache.ASNSearch(26808); entry == nil || len(entry.NetblockNames) != 1 || entry.NetblockNames["8.24.68.0/23"] != "UTICA-NET" {
+		t.Errorf("Update failed to add the network names to the ASN")
+	}
+
+	first := cache.ASNSearch(26808).FirstSeen
+	later := first.Add(time.Hour)
+	cache.Update(&ASNRequest{
+		ASN:       26808,
+		Prefix:    "72.237.4.0/24",
+		FirstSeen: later,
+		LastSeen:  later,
+		Tag:       RIR,
+		Source:    "RIR",
+	})
+
+	if entry := cache.AddrSearch("72.237.4.113"); entry == nil || !entry.FirstSeen.Equal(first) || !entry.LastSeen.Equal(later) {
+		t.Errorf("Update failed to move the last seen time forward while keeping the first seen time")
+	}
+}
+
+func TestUpdateMergesSources(t *testing.T) {
+	cache := NewASNCache()
+	allocated := time.Date(2010, 7, 14, 0, 0, 0, 0, time.UTC)
+
+	// NetworksDB provides the netblocks without the allocation date
+	cache.Update(&ASNRequest{
+		ASN:         13335,
+		Description: "CLOUDFLARENET",
+		Netblocks:   []string{"104.16.0.0/12", " 172.64.0.0/13", "2606:4700::/32"},
+		Tag:         API,
+		Source:      "NetworksDB",
+	})
+	// Umbrella provides the prefix, registry and allocation date, with one netblock in another notation
+	cache.Update(&ASNRequest{
+		Address:        "104.16.1.1",
+		ASN:            13335,
+		Prefix:         "104.16.1.0/12",
+		CC:             "US",
+		Registry:       "ARIN",
+		AllocationDate: allocated,
+		Description:    "CLOUDFLARENET - Cloudflare, Inc.",
+		Netblocks:      []string{"162.158.0.0/15"},
+		Tag:            API,
+		Source:         "Umbrella",
+	})
+	// A later partial record does not erase the known fields
+	cache.Update(&ASNRequest{
+		ASN:       13335,
+		Registry:  "N/A",
+		Netblocks: []string{"172.64.0.0/13"},
+		Tag:       API,
+		Source:    "NetworksDB",
+	})
+
+	entry := cache.ASNSearch(13335)
+	if entry == nil {
+		t.Fatalf("ASNSearch returned nil after the updates")
+	}
+	if entry.Prefix != "104.16.0.0/12" || entry.CC != "US" || entry.Registry != "ARIN" || !entry.AllocationDate.Equal(allocated) {
+		t.Errorf("The empty fields were not filled by the second source: %+v", entry)
+	}
+	if entry.Description != "CLOUDFLARENET - Cloudflare, Inc." {
+		t.Errorf("The most complete description was not kept: %s", entry.Description)
+	}
+	want := []string{"104.16.0.0/12", "172.64.0.0/13", "2606:4700::/32", "162.158.0.0/15"}
+	if fmt.Sprint(entry.Netblocks) != fmt.Sprint(want) {
+		t.Errorf("The netblocks were not combined: got %v, expected %v", entry.Netblocks, want)
+	}
+	if fmt.Sprint(entry.Sources) != "[NetworksDB Umbrella]" {
+		t.Errorf("The contributing sources were not recorded: %v", entry.Sources)
+	}
+}
+
+func TestUpdateSplitsNetblocks(t *testing.T) {
+	cache := NewASNCache()
+
+	cache.Update(&ASNRequest{
+		Address:   "8.8.8.8",
+		ASN:       15169,
+		Prefix:    "8.8.8.0/24",
+		Netblocks: []string{"8.8.8.0/24", "2001:4860::/32"},
+		Tag:       RIR,
+		Source:    "RIR",
+	})
+	cache.Update(&ASNRequest{
+		ASN:       15169,
+		Prefix:    "8.8.8.0/24",
+		Netblocks: []string{"8.8.4.0/24", "2404:6800::/32"},
+		Tag:       RIR,
+		Source:    "RIR",
+	})
+
+	entry := cache.ASNSearch(15169)
+	if entry == nil {
+		t.Fatalf("ASNSearch returned nil after the updates")
+	}
+	if len(entry.Netblocks) != 4 || len(entry.IPv4Netblocks) != 2 || len(entry.IPv6Netblocks) != 2 {
+		t.Errorf("The merged netblocks were not split by address family: %+v", entry)
+	}
+	for _, cidr := range entry.IPv6Netblocks {
+		if !strings.Contains(cidr, ":") {
+			t.Errorf("%s was placed with the IPv6 netblocks", cidr)
+		}
+	}
+
+	if req := cache.AddrSearch("8.8.8.8"); req == nil || len(req.IPv4Netblocks) == 0 {
+		t.Errorf("AddrSearch did not return the IPv4 netblocks: %+v", req)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	cache := NewASNCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			cache.Update(&ASNRequest{
+				ASN:       15169,
+				Prefix:    fmt.Sprintf("8.8.%d.0/24", i),
+				Netblocks: []string{fmt.Sprintf("8.8.%d.0/24", i)},
+				Tag:       RIR,
+				Source:    "RIR",
+			})
+			if entry := cache.ASNSearch(15169); entry != nil {
+				_ = len(entry.Netblocks) + len(entry.NetblockNames)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entry := cache.ASNSearch(15169)
+	if entry == nil || len(entry.Netblocks) != 8 {
+		t.Fatalf("The concurrent updates were not all merged: %+v", entry)
+	}
+
+	entry.Netblocks[0] = "10.0.0.0/8"
+	if cache.ASNSearch(15169).Netblocks[0] == "10.0.0.0/8" {
+		t.Errorf("ASNSearch returned the entry held by the cache instead of a copy")
+	}
 }
 
 func TestASNSearch(t *testing.T) {
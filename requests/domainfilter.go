@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainFilter removes the related domains that a data source reports through a WhoisRequest
+// when any source already reported them within the window, so the same domain does not
+// start another round of reverse whois queries. Every data source that reported a domain is
+// recorded, including those whose reports were removed.
+type DomainFilter struct {
+	sync.Mutex
+	window  time.Duration
+	domains map[string]*proposedDomain
+}
+
+type proposedDomain struct {
+	passed  time.Time
+	sources map[string]struct{}
+}
+
+// NewDomainFilter returns a DomainFilter that lets a domain through again once the window has
+// passed since it was last let through. With a window of zero, a domain is only let through once.
+func NewDomainFilter(window time.Duration) *DomainFilter {
+	return &DomainFilter{
+		window:  window,
+		domains: make(map[string]*proposedDomain),
+	}
+}
+
+// Filter records the data source as reporting each of the domains, and returns the domains
+// that were not let through within the window, in the order they were provided.
+func (f *DomainFilter) Filter(source string, domains []string) []string {
+	if f == nil || len(domains) == 0 {
+		return domains
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	now := time.Now()
+	var results []string
+	for _, d := range domains {
+		key := strings.ToLower(strings.TrimSpace(d))
+		if key == "" {
+			continue
+		}
+
+		pd, found := f.domains[key]
+		if !found {
+			pd = &proposedDomain{sources: make(map[string]struct{})}
+			f.domains[key] = pd
+		}
+		if source != "" {
+			pd.sources[source] = struct{}{}
+		}
+
+		if !found || (f.window > 0 && now.Sub(pd.passed) >= f.window) {
+			pd.passed = now
+			results = append(results, d)
+		}
+	}
+	return results
+}
+
+// Sources returns the names of the data sources that reported the domain, in alphabetical order.
+func (f *DomainFilter) Sources(domain string) []string {
+	if f == nil {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	pd, found := f.domains[strings.ToLower(strings.TrimSpace(domain))]
+	if !found {
+		return nil
+	}
+
+	sources := make([]string, 0, len(pd.sources))
+	for src := range pd.sources {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Reset removes all the recorded domains.
+func (f *DomainFilter) Reset() {
+	if f == nil {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.domains = make(map[string]*proposedDomain)
+}
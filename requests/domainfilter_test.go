@@ -0,0 +1,62 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDomainFilter(t *testing.T) {
+	f := NewDomainFilter(0)
+
+	if got := f.Filter("Umbrella", []string{"owasp.net", "owaspfoundation.com"}); fmt.Sprint(got) != "[owasp.net owaspfoundation.com]" {
+		t.Errorf("The first report returned %v", got)
+	}
+	// The domain new to the second source is kept, while the repeat only adds the attribution
+	if got := f.Filter("NetworksDB", []string{"OWASP.net", "owasp.info"}); fmt.Sprint(got) != "[owasp.info]" {
+		t.Errorf("The second report returned %v", got)
+	}
+	if got := f.Filter("Umbrella", []string{"owasp.net"}); len(got) != 0 {
+		t.Errorf("The repeat from the first source returned %v", got)
+	}
+
+	if got := f.Sources("owasp.net"); fmt.Sprint(got) != "[NetworksDB Umbrella]" {
+		t.Errorf("The sources of owasp.net were %v", got)
+	}
+	if got := f.Sources("owasp.info"); fmt.Sprint(got) != "[NetworksDB]" {
+		t.Errorf("The sources of owasp.info were %v", got)
+	}
+
+	f.Reset()
+	if got := f.Filter("Umbrella", []string{"owasp.net"}); len(got) != 1 {
+		t.Errorf("The domain was still filtered after the reset: %v", got)
+	}
+
+	var nilFilter *DomainFilter
+	if got := nilFilter.Filter("Umbrella", []string{"owasp.net"}); len(got) != 1 {
+		t.Errorf("A nil filter removed the domain")
+	}
+}
+
+func TestDomainFilterWindow(t *testing.T) {
+	f := NewDomainFilter(50 * time.Millisecond)
+
+	if got := f.Filter("Umbrella", []string{"owasp.net"}); len(got) != 1 {
+		t.Fatalf("The first report returned %v", got)
+	}
+	if got := f.Filter("NetworksDB", []string{"owasp.net"}); len(got) != 0 {
+		t.Errorf("The report within the window returned %v", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := f.Filter("NetworksDB", []string{"owasp.net"}); len(got) != 1 {
+		t.Errorf("The report after the window returned %v", got)
+	}
+	if got := f.Filter("Umbrella", []string{"owasp.net"}); len(got) != 0 {
+		t.Errorf("The window did not restart once the domain was let through again: %v", got)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"strings"
+	"sync"
+
+	bf "github.com/tylertreat/BoomFilters"
+)
+
+// NameFilter records the names already reported by each data source, so repeated names can be
+// skipped before they are processed again. A Bloom filter answers most lookups, and the set of
+// recorded names is only consulted when the filter reports a probable match, which keeps the
+// false positives of the filter from dropping new names.
+type NameFilter struct {
+	sync.Mutex
+	bloom *bf.BloomFilter
+	names map[string]struct{}
+	max   int
+}
+
+// NewNameFilter returns a NameFilter sized for the number of names provided, which is also the
+// most names recorded, with the expected false-positive rate of the Bloom filter at that size.
+// Nil is returned when the size is zero, and a nil NameFilter does not filter any names.
+func NewNameFilter(size int, fpRate float64) *NameFilter {
+	if size <= 0 {
+		return nil
+	}
+
+	return &NameFilter{
+		bloom: bf.NewBloomFilter(uint(size), fpRate),
+		names: make(map[string]struct{}),
+		max:   size,
+	}
+}
+
+// Has returns true when the name was recorded for the data source.
+func (nf *NameFilter) Has(source, name string) bool {
+	if nf == nil {
+		return false
+	}
+
+	key := nameFilterKey(source, name)
+	nf.Lock()
+	defer nf.Unlock()
+
+	if !nf.bloom.Test(key) {
+		return false
+	}
+	_, found := nf.names[string(key)]
+	return found
+}
+
+// Insert records the name for the data source. Once the filter holds as many names as it was
+// sized for, new names are no longer recorded.
+func (nf *NameFilter) Insert(source, name string) {
+	if nf == nil {
+		return
+	}
+
+	key := nameFilterKey(source, name)
+	nf.Lock()
+	defer nf.Unlock()
+
+	if len(nf.names) >= nf.max {
+		return
+	}
+	nf.bloom.Add(key)
+	nf.names[string(key)] = struct{}{}
+}
+
+// Reset removes all the recorded names.
+func (nf *NameFilter) Reset() {
+	if nf == nil {
+		return
+	}
+
+	nf.Lock()
+	defer nf.Unlock()
+
+	nf.bloom.Reset()
+	nf.names = make(map[string]struct{})
+}
+
+// Len returns the number of names recorded.
+func (nf *NameFilter) Len() int {
+	if nf == nil {
+		return 0
+	}
+
+	nf.Lock()
+	defer nf.Unlock()
+
+	return len(nf.names)
+}
+
+func nameFilterKey(source, name string) []byte {
+	return []byte(source + "|" + strings.ToLower(strings.TrimSpace(name)))
+}
@@ -0,0 +1,61 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestNameFilter(t *testing.T) {
+	nf := NewNameFilter(100, 0.01)
+
+	if nf.Has("Umbrella", "www.owasp.org") {
+		t.Errorf("The name was found before it was inserted")
+	}
+	nf.Insert("Umbrella", "WWW.owasp.org ")
+	if !nf.Has("Umbrella", "www.owasp.org") {
+		t.Errorf("The inserted name was not found")
+	}
+	if nf.Has("DNSDB", "www.owasp.org") {
+		t.Errorf("The name was found for another data source")
+	}
+
+	// The names beyond the size are not recorded
+	for i := 0; i < 200; i++ {
+		nf.Insert("Umbrella", strconv.Itoa(i)+".owasp.org")
+	}
+	if nf.Len() != 100 {
+		t.Errorf("The filter recorded %d names with a size of 100", nf.Len())
+	}
+	if nf.Has("Umbrella", "150.owasp.org") {
+		t.Errorf("A name beyond the size of the filter was reported as recorded")
+	}
+
+	nf.Reset()
+	if nf.Len() != 0 || nf.Has("Umbrella", "www.owasp.org") {
+		t.Errorf("The names were not removed by Reset")
+	}
+
+	var disabled *NameFilter
+	disabled.Insert("Umbrella", "www.owasp.org")
+	if NewNameFilter(0, 0.01) != nil || disabled.Has("Umbrella", "www.owasp.org") {
+		t.Errorf("The disabled filter reported a recorded name")
+	}
+}
+
+func TestNameFilterFalsePositives(t *testing.T) {
+	// A saturated Bloom filter reports many probable matches, which the recorded names reject
+	nf := NewNameFilter(1000, 0.5)
+	for i := 0; i < 1000; i++ {
+		nf.Insert("Umbrella", strconv.Itoa(i)+".owasp.org")
+	}
+
+	for i := 1000; i < 2000; i++ {
+		if nf.Has("Umbrella", strconv.Itoa(i)+".owasp.org") {
+			t.Fatalf("A new name was reported as recorded")
+		}
+	}
+}
@@ -6,6 +6,7 @@ package requests
 
 import (
 	"net"
+	"regexp"
 	"strings"
 	"time"
 
@@ -59,16 +60,28 @@ type DNSRequest struct {
 	Records []DNSAnswer
 	Tag     string
 	Source  string
+	// The web page the data source extracted the name from, when recorded
+	SourceURL string
+	// The label of the account the data source was queried with, when several have been configured
+	Org string
+	// Set when the name was reported by a data source and has not been resolved yet
+	Passive bool
+	// The root domains provided for the enumeration that led to the name
+	Seeds []string
 }
 
 // Clone implements pipeline Data.
 func (d *DNSRequest) Clone() pipeline.Data {
 	return &DNSRequest{
-		Name:    d.Name,
-		Domain:  d.Domain,
-		Records: append([]DNSAnswer(nil), d.Records...),
-		Tag:     d.Tag,
-		Source:  d.Source,
+		Name:      d.Name,
+		Domain:    d.Domain,
+		Records:   append([]DNSAnswer(nil), d.Records...),
+		Tag:       d.Tag,
+		Source:    d.Source,
+		SourceURL: d.SourceURL,
+		Org:       d.Org,
+		Passive:   d.Passive,
+		Seeds:     append([]string(nil), d.Seeds...),
 	}
 }
 
@@ -198,6 +211,7 @@ type AddrRequest struct {
 	Domain  string
 	Tag     string
 	Source  string
+	Geo     *GeoInfo
 }
 
 // Clone implements pipeline Data.
@@ -208,6 +222,7 @@ func (a *AddrRequest) Clone() pipeline.Data {
 		Domain:  a.Domain,
 		Tag:     a.Tag,
 		Source:  a.Source,
+		Geo:     a.Geo,
 	}
 }
 
@@ -237,8 +252,15 @@ type ASNRequest struct {
 	AllocationDate time.Time
 	Description    string
 	Netblocks      []string
+	IPv4Netblocks  []string          // The IPv4 netblocks within Netblocks
+	IPv6Netblocks  []string          // The IPv6 netblocks within Netblocks
+	NetblockNames  map[string]string // Optional network names keyed by CIDR
+	FirstSeen      time.Time
+	LastSeen       time.Time
 	Tag            string
 	Source         string
+	// The data sources that contributed to the entry, once merged into the ASNCache
+	Sources []string
 }
 
 // Clone implements pipeline Data.
@@ -252,14 +274,39 @@ func (a *ASNRequest) Clone() pipeline.Data {
 		AllocationDate: a.AllocationDate,
 		Description:    a.Description,
 		Netblocks:      a.Netblocks,
+		IPv4Netblocks:  a.IPv4Netblocks,
+		IPv6Netblocks:  a.IPv6Netblocks,
+		NetblockNames:  a.NetblockNames,
+		FirstSeen:      a.FirstSeen,
+		LastSeen:       a.LastSeen,
 		Tag:            a.Tag,
 		Source:         a.Source,
+		Sources:        a.Sources,
 	}
 }
 
 // MarkAsProcessed implements pipeline Data.
 func (a *ASNRequest) MarkAsProcessed() {}
 
+// SplitNetblocks separates the netblocks by address family into the IPv4Netblocks and
+// IPv6Netblocks fields. The Netblocks field is not modified, and invalid CIDRs are skipped.
+func (a *ASNRequest) SplitNetblocks() {
+	a.IPv4Netblocks, a.IPv6Netblocks = nil, nil
+
+	for _, cidr := range a.Netblocks {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			a.IPv4Netblocks = append(a.IPv4Netblocks, cidr)
+		} else {
+			a.IPv6Netblocks = append(a.IPv6Netblocks, cidr)
+		}
+	}
+}
+
 // Valid performs input validation of the receiver.
 func (a *ASNRequest) Valid() bool {
 	if ip := net.ParseIP(a.Address); ip == nil {
@@ -282,8 +329,60 @@ type WhoisRequest struct {
 	Company    string
 	Email      string
 	NewDomains []string
-	Tag        string
-	Source     string
+	// The nameservers of the domain reported by the data source
+	NameServers []string
+	// The domains the data source found hosted in each netblock, keyed by CIDR
+	Netblocks map[string][]string
+	Tag       string
+	Source    string
+}
+
+var nameServerRE = regexp.MustCompile("^" + amassdns.AnySubdomainRegexString() + "$")
+
+// NormalizeNameServers returns the valid nameserver hostnames provided in lowercase and without
+// the trailing dot, dropping the duplicates while keeping the order they were first seen in.
+func NormalizeNameServers(names ...string) []string {
+	var results []string
+
+	seen := make(map[string]struct{})
+	for _, name := range names {
+		name = strings.Trim(strings.ToLower(strings.TrimSpace(name)), ".")
+		if !nameServerRE.MatchString(name) {
+			continue
+		}
+		if _, found := seen[name]; !found {
+			seen[name] = struct{}{}
+			results = append(results, name)
+		}
+	}
+	return results
+}
+
+// NameServer is a nameserver discovered during the enumerations, along with the
+// names that delegate to it.
+type NameServer struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains"`
+}
+
+// NetblockDomains is a netblock discovered through whois data, along with the domains hosted in it.
+type NetblockDomains struct {
+	Netblock string   `json:"netblock"`
+	Domains  []string `json:"domains"`
+}
+
+// ASNNames is an autonomous system, along with its netblocks and the names with addresses in them.
+type ASNNames struct {
+	ASN         int          `json:"asn"`
+	Description string       `json:"description"`
+	Netblocks   []string     `json:"netblocks"`
+	Names       []*NameAddrs `json:"names"`
+}
+
+// NameAddrs is a discovered name, along with the addresses it resolved to.
+type NameAddrs struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
 }
 
 // Output contains all the output data for an enumerated DNS name.
@@ -293,16 +392,38 @@ type Output struct {
 	Addresses []AddressInfo `json:"addresses"`
 	Tag       string        `json:"tag"`
 	Sources   []string      `json:"sources"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	// The web pages the name was extracted from, when recorded by the enumeration
+	SourceURLs []string `json:"source_urls,omitempty"`
+	// The labels of the data source accounts that reported the name, when several have been configured
+	Orgs []string `json:"orgs,omitempty"`
+	// Set when the name was only observed by the data sources and not confirmed through DNS resolution
+	Passive bool `json:"passive"`
+	// The nameservers the name delegates to
+	NameServers []string `json:"nameservers,omitempty"`
+	// The root domains provided for the enumerations that led to the name
+	Seeds []string `json:"seeds,omitempty"`
+	// The sinkhole or parked class shared by all the addresses of the name, when they have one
+	Class string `json:"class,omitempty"`
 }
 
 // Clone implements pipeline Data.
 func (o *Output) Clone() pipeline.Data {
 	return &Output{
-		Name:      o.Name,
-		Domain:    o.Domain,
-		Addresses: append([]AddressInfo(nil), o.Addresses...),
-		Tag:       o.Tag,
-		Sources:   append([]string(nil), o.Sources...),
+		Name:        o.Name,
+		Domain:      o.Domain,
+		Addresses:   append([]AddressInfo(nil), o.Addresses...),
+		Tag:         o.Tag,
+		Sources:     append([]string(nil), o.Sources...),
+		FirstSeen:   o.FirstSeen,
+		LastSeen:    o.LastSeen,
+		SourceURLs:  append([]string(nil), o.SourceURLs...),
+		Orgs:        append([]string(nil), o.Orgs...),
+		Passive:     o.Passive,
+		NameServers: append([]string(nil), o.NameServers...),
+		Seeds:       append([]string(nil), o.Seeds...),
+		Class:       o.Class,
 	}
 }
 
@@ -339,6 +460,46 @@ type AddressInfo struct {
 	CIDRStr     string     `json:"cidr"`
 	ASN         int        `json:"asn"`
 	Description string     `json:"desc"`
+	Geo         *GeoInfo   `json:"geo,omitempty"`
+	// Set when the BGP validation did not find the netblock in the global BGP table
+	Stale bool `json:"stale,omitempty"`
+	// The RPKI validation state of the netblock and its origin ASN, when it was checked
+	RPKI string `json:"rpki,omitempty"`
+	// The sinkhole or parked class of the range holding the address, when it is within one
+	Class string `json:"class,omitempty"`
+}
+
+// GeoInfo stores the geographic location of an address.
+type GeoInfo struct {
+	CountryCode string  `json:"cc,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	Region      string  `json:"region,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Latitude    float64 `json:"lat"`
+	Longitude   float64 `json:"lon"`
+	Source      string  `json:"source,omitempty"`
+}
+
+// NamePath describes why a name is in the results: the data sources that reported it, the
+// pages it was extracted from, and the chain of aliases and addresses it resolves to.
+type NamePath struct {
+	Name       string      `json:"name"`
+	Sources    []string    `json:"sources"`
+	SourceURLs []string    `json:"source_urls,omitempty"`
+	CNAME      *NamePath   `json:"cname,omitempty"`
+	Addresses  []*AddrPath `json:"addresses,omitempty"`
+}
+
+// AddrPath describes an address a name resolved to, and the netblock and autonomous system
+// the address was attributed to, along with the data sources that provided them.
+type AddrPath struct {
+	Address         string   `json:"ip"`
+	Sources         []string `json:"sources"`
+	Netblock        string   `json:"cidr,omitempty"`
+	NetblockSources []string `json:"cidr_sources,omitempty"`
+	ASN             int      `json:"asn,omitempty"`
+	Description     string   `json:"desc,omitempty"`
+	ASNSources      []string `json:"asn_sources,omitempty"`
 }
 
 // TrustedTag returns true when the tag parameter is of a type that should be trusted even
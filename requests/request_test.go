@@ -500,3 +500,11 @@ func TestASNRequestValid(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeNameServers(t *testing.T) {
+	got := NormalizeNameServers("NS1.Example.com.", " ns2.example.com", "ns1.example.com", "localhost", "bad name.com", "")
+
+	if len(got) != 2 || got[0] != "ns1.example.com" || got[1] != "ns2.example.com" {
+		t.Errorf("Unexpected nameservers: %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"strings"
+	"sync"
+)
+
+// WildcardCache records the subdomains that have been found to be within DNS wildcards.
+type WildcardCache struct {
+	sync.RWMutex
+	subs map[string]string
+}
+
+// NewWildcardCache returns an empty WildcardCache.
+func NewWildcardCache() *WildcardCache {
+	return &WildcardCache{subs: make(map[string]string)}
+}
+
+// Insert records that names directly below the sub parameter resolve using a DNS wildcard
+// belonging to the domain parameter. A wildcard at the root domain name is not recorded,
+// since it would cause all names within the domain to be considered wildcard matches.
+func (wc *WildcardCache) Insert(sub, domain string) {
+	sub = strings.Trim(strings.ToLower(strings.TrimSpace(sub)), ".")
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if wc == nil || sub == "" || sub == domain {
+		return
+	}
+
+	wc.Lock()
+	defer wc.Unlock()
+
+	wc.subs[sub] = domain
+}
+
+// Detected returns true when the name parameter falls within a subdomain that has been
+// recorded as being within a DNS wildcard.
+func (wc *WildcardCache) Detected(name string) bool {
+	if wc == nil {
+		return false
+	}
+
+	name = strings.Trim(strings.ToLower(strings.TrimSpace(name)), ".")
+	labels := strings.Split(name, ".")
+
+	wc.RLock()
+	defer wc.RUnlock()
+
+	for i := 1; i < len(labels); i++ {
+		if _, found := wc.subs[strings.Join(labels[i:], ".")]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// Subdomains returns the recorded wildcard subdomains that belong to the domain parameter.
+func (wc *WildcardCache) Subdomains(domain string) []string {
+	if wc == nil {
+		return nil
+	}
+
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	wc.RLock()
+	defer wc.RUnlock()
+
+	var subs []string
+	for sub, d := range wc.subs {
+		if d == domain {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
@@ -0,0 +1,52 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import "testing"
+
+func TestWildcardCache(t *testing.T) {
+	wc := NewWildcardCache()
+	// The synthetic zone resolves every name below dev.example.com and *.cdn.example.com
+	wc.Insert("dev.example.com", "example.com")
+	wc.Insert("CDN.Example.com.", "example.com")
+	wc.Insert("", "example.com")
+	wc.Insert("example.com", "example.com")
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"random123.dev.example.com", true},
+		{"a.b.dev.example.com", true},
+		{"xyz.cdn.example.com", true},
+		{"XYZ.CDN.EXAMPLE.COM", true},
+		{"dev.example.com", false},
+		{"www.example.com", false},
+		{"example.com", false},
+		{"dev.example.org", false},
+		{"notdev.example.com", false},
+	}
+
+	for _, test := range tests {
+		if got := wc.Detected(test.name); got != test.expected {
+			t.Errorf("Detected(%s) returned %t, expected %t", test.name, got, test.expected)
+		}
+	}
+
+	if subs := wc.Subdomains("example.com"); len(subs) != 2 {
+		t.Errorf("Subdomains returned %d subdomains, expected 2", len(subs))
+	}
+	if subs := wc.Subdomains("example.org"); len(subs) != 0 {
+		t.Errorf("Subdomains returned subdomains for a domain without wildcards")
+	}
+
+	var nilcache *WildcardCache
+	if nilcache.Detected("random123.dev.example.com") {
+		t.Errorf("Detected returned true for a nil cache")
+	}
+	if subs := nilcache.Subdomains("example.com"); len(subs) != 0 {
+		t.Errorf("Subdomains returned subdomains for a nil cache")
+	}
+}
@@ -69,7 +69,24 @@ func GetIP2ASNData() ([]*IP2ASN, error) {
 }
 
 func GetDefaultScripts() ([]string, error) {
+	files, err := GetDefaultScriptFiles()
+
 	var scripts []string
+	for _, f := range files {
+		scripts = append(scripts, f.Content)
+	}
+	return scripts, err
+}
+
+// ScriptFile is the content of a data source script along with the path it was read from.
+type ScriptFile struct {
+	Path    string
+	Content string
+}
+
+// GetDefaultScriptFiles returns the data source scripts embedded in the binary.
+func GetDefaultScriptFiles() ([]ScriptFile, error) {
+	var scripts []ScriptFile
 
 	ferr := fs.WalkDir(resourceFS, "scripts", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -85,7 +102,7 @@ func GetDefaultScripts() ([]string, error) {
 			return err
 		}
 
-		scripts = append(scripts, string(data))
+		scripts = append(scripts, ScriptFile{Path: path, Content: string(data)})
 		return nil
 	})
 
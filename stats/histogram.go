@@ -0,0 +1,212 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the buckets in the request duration histograms.
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// The most slow requests kept for looking up the URL of an exemplar
+const maxSlowRequests = 100
+
+// OpenMetricsContentType is the media type of the text written by WriteOpenMetrics.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Exemplar is a request that took at least the slow request threshold, identified by a trace id.
+type Exemplar struct {
+	Source  string    `json:"source"`
+	TraceID string    `json:"trace_id"`
+	URL     string    `json:"url"`
+	Seconds float64   `json:"seconds"`
+	Time    time.Time `json:"time"`
+}
+
+// histogram counts the durations of the requests sent by a data source. The last bucket holds
+// the durations beyond the largest bound, and each bucket keeps its most recent exemplar.
+type histogram struct {
+	counts    []int64
+	exemplars []*Exemplar
+	sum       float64
+	count     int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{
+		counts:    make([]int64, len(DurationBuckets)+1),
+		exemplars: make([]*Exemplar, len(DurationBuckets)+1),
+	}
+}
+
+// RecordDuration adds the duration of the request for the URL to the histogram of the data source in the context.
+func RecordDuration(ctx context.Context, u string, d time.Duration) {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		c.Duration(src, u, d)
+	}
+}
+
+// EnableExemplars attaches an exemplar, holding the data source and a trace id, to the samples
+// of the requests that took at least the slow duration. Zero disables the exemplars.
+func (c *Collector) EnableExemplars(slow time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.slow = slow
+}
+
+// Duration adds the duration of a request for the URL made by the named data source.
+func (c *Collector) Duration(source, u string, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	h, found := c.durations[source]
+	if !found {
+		h = newHistogram()
+		c.durations[source] = h
+	}
+
+	secs := d.Seconds()
+	idx := sort.SearchFloat64s(DurationBuckets, secs)
+	h.counts[idx]++
+	h.sum += secs
+	h.count++
+	// The trace ids are only generated for the slow requests, so the samples stay cheap otherwise
+	if c.slow <= 0 || d < c.slow {
+		return
+	}
+
+	e := &Exemplar{
+		Source:  source,
+		TraceID: newTraceID(),
+		URL:     u,
+		Seconds: secs,
+		Time:    time.Now(),
+	}
+	h.exemplars[idx] = e
+	c.slowRequests = append(c.slowRequests, e)
+	if len(c.slowRequests) > maxSlowRequests {
+		c.slowRequests = c.slowRequests[len(c.slowRequests)-maxSlowRequests:]
+	}
+}
+
+// SlowRequests returns the most recent requests that took at least the slow request threshold.
+func (c *Collector) SlowRequests() []*Exemplar {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return append([]*Exemplar(nil), c.slowRequests...)
+}
+
+// ServeHTTP implements the http.Handler interface and writes the metrics in the OpenMetrics format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", OpenMetricsContentType)
+	_ = c.WriteOpenMetrics(w)
+}
+
+// WriteOpenMetrics writes the request counters and the request duration histograms of the data
+// sources to the provided io.Writer in the OpenMetrics text format, along with the exemplars.
+func (c *Collector) WriteOpenMetrics(w io.Writer) error {
+	if c == nil {
+		_, err := io.WriteString(w, "# EOF\n")
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	var b strings.Builder
+	names := make([]string, 0, len(c.sources))
+	for name := range c.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("# TYPE amass_source_requests counter\n")
+	b.WriteString("# HELP amass_source_requests Requests sent by the data sources.\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "amass_source_requests_total{source=\"%s\"} %d\n", escapeLabel(name), c.sources[name].Requests)
+	}
+	b.WriteString("# TYPE amass_source_request_errors counter\n")
+	b.WriteString("# HELP amass_source_request_errors Requests of the data sources that failed.\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "amass_source_request_errors_total{source=\"%s\"} %d\n", escapeLabel(name), c.sources[name].Errors)
+	}
+
+	names = names[:0]
+	for name := range c.durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const metric = "amass_http_request_duration_seconds"
+	b.WriteString("# TYPE " + metric + " histogram\n")
+	b.WriteString("# UNIT " + metric + " seconds\n")
+	b.WriteString("# HELP " + metric + " Duration of the HTTP requests sent by the data sources.\n")
+	for _, name := range names {
+		h := c.durations[name]
+		src := escapeLabel(name)
+
+		var cumulative int64
+		for i, count := range h.counts {
+			le := "+Inf"
+			if i < len(DurationBuckets) {
+				le = strconv.FormatFloat(DurationBuckets[i], 'g', -1, 64)
+			}
+
+			cumulative += count
+			fmt.Fprintf(&b, "%s_bucket{source=\"%s\",le=\"%s\"} %d", metric, src, le, cumulative)
+			if e := h.exemplars[i]; e != nil {
+				fmt.Fprintf(&b, " # {source=\"%s\",trace_id=\"%s\"} %s %s", src, e.TraceID,
+					strconv.FormatFloat(e.Seconds, 'g', -1, 64), formatTimestamp(e.Time))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s_count{source=\"%s\"} %d\n", metric, src, h.count)
+		fmt.Fprintf(&b, "%s_sum{source=\"%s\"} %s\n", metric, src, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	}
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeLabel(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(val)
+}
+
+func formatTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+func newTraceID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,79 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationHistogram(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c, "NetworksDB")
+
+	RecordRequest(ctx, nil)
+	RecordDuration(ctx, "https://networksdb.io/api/key", 30*time.Millisecond)
+	RecordDuration(ctx, "https://networksdb.io/api/ip-info", 3*time.Second)
+	// Exemplars are not attached until they have been enabled
+	if len(c.SlowRequests()) != 0 {
+		t.Errorf("Slow requests were recorded without the exemplars enabled")
+	}
+
+	c.EnableExemplars(2 * time.Second)
+	RecordDuration(ctx, "https://networksdb.io/api/asn-networks", 45*time.Second)
+	RecordDuration(ctx, "https://networksdb.io/api/key", time.Second)
+
+	slow := c.SlowRequests()
+	if len(slow) != 1 || slow[0].Source != "NetworksDB" || slow[0].URL != "https://networksdb.io/api/asn-networks" {
+		t.Fatalf("Unexpected slow requests: %+v", slow)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(slow[0].TraceID) {
+		t.Errorf("The trace id %q is not 32 hexadecimal digits", slow[0].TraceID)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteOpenMetrics(&buf); err != nil {
+		t.Fatalf("Failed to write the metrics: %v", err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		`amass_source_requests_total{source="NetworksDB"} 1`,
+		`amass_http_request_duration_seconds_bucket{source="NetworksDB",le="0.05"} 1`,
+		`amass_http_request_duration_seconds_bucket{source="NetworksDB",le="1"} 2`,
+		`amass_http_request_duration_seconds_bucket{source="NetworksDB",le="5"} 3`,
+		`amass_http_request_duration_seconds_bucket{source="NetworksDB",le="+Inf"} 4 # {source="NetworksDB",trace_id="` + slow[0].TraceID + `"} 45 `,
+		`amass_http_request_duration_seconds_count{source="NetworksDB"} 4`,
+		`amass_http_request_duration_seconds_sum{source="NetworksDB"} 49.03`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("The metrics are missing %q:\n%s", line, out)
+		}
+	}
+	if !strings.HasSuffix(out, "# EOF\n") {
+		t.Errorf("The metrics do not end with the EOF marker")
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != OpenMetricsContentType {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+}
+
+func TestDurationWithoutCollector(t *testing.T) {
+	var c *Collector
+
+	c.EnableExemplars(time.Second)
+	c.Duration("NetworksDB", "https://networksdb.io", time.Minute)
+	RecordDuration(context.Background(), "https://networksdb.io", time.Minute)
+	if c.SlowRequests() != nil {
+		t.Errorf("A nil Collector returned slow requests")
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// SourceStats contains the metrics collected for a single data source.
+type SourceStats struct {
+	Requests        int64 `json:"requests"`
+	Errors          int64 `json:"errors"`
+	RateLimitWaits  int64 `json:"rate_limit_waits"`
+	RateLimitWaitMS int64 `json:"rate_limit_wait_ms"`
+	Results         int64 `json:"results"`
+	Quota           int64 `json:"quota,omitempty"`
+	// Pages where the scrape regular expressions did not match, and whether the source was disabled as a result
+	ExtractionFailures int64 `json:"extraction_failures,omitempty"`
+	Broken             bool  `json:"broken,omitempty"`
+	// The most results waiting in the output buffer, the time spent waiting for room in it,
+	// and the results dropped because it was full
+	OutputDepthMax  int64 `json:"output_depth_max,omitempty"`
+	OutputBlockedMS int64 `json:"output_blocked_ms,omitempty"`
+	OutputDropped   int64 `json:"output_dropped,omitempty"`
+	issued          int64
+	failedInRow     int64
+}
+
+// sourceScore holds the decayed counts of the outcomes behind the score of a data source,
+// and when they were last decayed.
+type sourceScore struct {
+	successes float64
+	failures  float64
+	decayed   time.Time
+}
+
+// PhaseStats contains the metrics collected for a phase of the enumeration.
+type PhaseStats struct {
+	Calls      int64 `json:"calls"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// Collector gathers the run statistics for the data sources and enumeration phases.
+// The methods are safe to call on a nil Collector, which discards the metrics.
+type Collector struct {
+	sync.Mutex
+	start           time.Time
+	sources         map[string]*SourceStats
+	phases          map[string]*PhaseStats
+	counters        map[string]int64
+	extractionLimit int64
+	// The request duration histograms of the data sources, and the requests slower than the threshold
+	durations    map[string]*histogram
+	scores       map[string]*sourceScore
+	statuses     map[string]map[int]int64
+	slow         time.Duration
+	slowRequests []*Exemplar
+}
+
+type ctxKey int
+
+const (
+	collectorKey ctxKey = iota
+	sourceKey
+)
+
+// Rate limiter checks shorter than this are not counted as waits
+const minRateLimitWait = time.Millisecond
+
+// The time it takes for an outcome to count half as much toward the score of a data source,
+// so the score follows changes in the behavior of the source during the run
+const scoreHalfLife = time.Minute
+
+// ErrQuotaReached is returned once a data source has issued all the requests allowed by its quota.
+var ErrQuotaReached = errors.New("the request quota for the data source has been reached")
+
+// ErrNoCollector is returned when the metrics of a nil Collector are written.
+var ErrNoCollector = errors.New("no metrics were collected")
+
+// NewCollector returns a Collector with the start time set to now.
+func NewCollector() *Collector {
+	return &Collector{
+		start:     time.Now(),
+		sources:   make(map[string]*SourceStats),
+		phases:    make(map[string]*PhaseStats),
+		counters:  make(map[string]int64),
+		durations: make(map[string]*histogram),
+		scores:    make(map[string]*sourceScore),
+		statuses:  make(map[string]map[int]int64),
+	}
+}
+
+// NewContext returns a copy of the parent context that carries the Collector and the name
+// of the data source, so that metrics can be attributed when the context is used.
+func NewContext(parent context.Context, c *Collector, source string) context.Context {
+	ctx := context.WithValue(parent, collectorKey, c)
+	return context.WithValue(ctx, sourceKey, source)
+}
+
+// FromContext returns the Collector and data source name carried by the context.
+func FromContext(ctx context.Context) (*Collector, string) {
+	if ctx == nil {
+		return nil, ""
+	}
+
+	c, _ := ctx.Value(collectorKey).(*Collector)
+	src, _ := ctx.Value(sourceKey).(string)
+	return c, src
+}
+
+// RecordRequest counts a request made on behalf of the data source in the context.
+func RecordRequest(ctx context.Context, err error) {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		c.Request(src, err)
+	}
+}
+
+// TakeQuota claims one request from the quota of the data source in the context, and returns
+// ErrQuotaReached when the quota has been used up. Contexts without a data source are not limited.
+func TakeQuota(ctx context.Context) error {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		return c.TakeQuota(src)
+	}
+	return nil
+}
+
+// RecordExtraction tracks whether the scrape regular expressions of the data source in the
+// context matched the page, and returns true when the failure marked the source as broken.
+func RecordExtraction(ctx context.Context, ok bool) bool {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		return c.Extraction(src, ok)
+	}
+	return false
+}
+
+// RecordRateLimitWait adds the time spent waiting on the rate limiter to the data source in the context.
+func RecordRateLimitWait(ctx context.Context, d time.Duration) {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		c.RateLimitWait(src, d)
+	}
+}
+
+// RecordResult counts a result emitted by the data source in the context.
+func RecordResult(ctx context.Context) {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		c.Result(src)
+	}
+}
+
+// Request counts a request made by the named data source.
+func (c *Collector) Request(source string, err error) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.source(source)
+	s.Requests++
+	if err != nil {
+		s.Errors++
+	}
+	c.outcome(source, err == nil)
+}
+
+// SetQuota limits the number of requests the named data source can issue. Zero removes the limit.
+func (c *Collector) SetQuota(source string, max int64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.source(source).Quota = max
+}
+
+// TakeQuota claims one request from the quota of the named data source, and returns
+// ErrQuotaReached when the quota has been used up.
+func (c *Collector) TakeQuota(source string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.source(source)
+	if s.Quota > 0 && s.issued >= s.Quota {
+		return ErrQuotaReached
+	}
+	s.issued++
+	return nil
+}
+
+// QuotaReached returns true when the named data source has issued all the requests allowed by its quota.
+func (c *Collector) QuotaReached(source string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, found := c.sources[source]
+	return found && s.Quota > 0 && s.issued >= s.Quota
+}
+
+// SetExtractionLimit sets the number of consecutive extraction failures that mark a data source
+// as broken. Zero disables the detection.
+func (c *Collector) SetExtractionLimit(limit int64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.extractionLimit = limit
+}
+
+// Extraction tracks whether the scrape regular expressions of the named data source matched
+// the page. The return value is true only for the failure that marked the source as broken.
+func (c *Collector) Extraction(source string, ok bool) bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.source(source)
+	if ok {
+		s.failedInRow = 0
+		return false
+	}
+
+	s.ExtractionFailures++
+	s.failedInRow++
+	if c.extractionLimit > 0 && s.failedInRow >= c.extractionLimit && !s.Broken {
+		s.Broken = true
+		return true
+	}
+	return false
+}
+
+// Broken returns true when the named data source failed to extract data from too many consecutive pages.
+func (c *Collector) Broken(source string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, found := c.sources[source]
+	return found && s.Broken
+}
+
+// RateLimitWait adds the time the named data source spent waiting on the rate limiter.
+func (c *Collector) RateLimitWait(source string, d time.Duration) {
+	if c == nil || d < minRateLimitWait {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.source(source)
+	s.RateLimitWaits++
+	s.RateLimitWaitMS += d.Milliseconds()
+}
+
+// Result counts a result emitted by the named data source.
+func (c *Collector) Result(source string) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.source(source).Results++
+	c.outcome(source, true)
+}
+
+// OutputDepth records the number of results waiting in the output buffer of the named data source.
+func (c *Collector) OutputDepth(source string, depth int) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if s := c.source(source); int64(depth) > s.OutputDepthMax {
+		s.OutputDepthMax = int64(depth)
+	}
+}
+
+// OutputBlocked adds the time the named data source waited for room in its output buffer.
+func (c *Collector) OutputBlocked(source string, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.source(source).OutputBlockedMS += d.Milliseconds()
+}
+
+// OutputDropped counts a result of the named data source dropped because its output buffer was full.
+func (c *Collector) OutputDropped(source string) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.source(source).OutputDropped++
+}
+
+// Phase adds the duration of a single execution of the named phase.
+func (c *Collector) Phase(name string, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	p, found := c.phases[name]
+	if !found {
+		p = new(PhaseStats)
+		c.phases[name] = p
+	}
+	p.Calls++
+	p.DurationMS += d.Milliseconds()
+}
+
+// Count adds n to the named counter of enumeration events.
+func (c *Collector) Count(name string, n int64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.counters[name] += n
+}
+
+// Counter returns the current value of the named counter.
+func (c *Collector) Counter(name string) int64 {
+	if c == nil {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.counters[name]
+}
+
+// Source returns a copy of the metrics collected for the named data source.
+func (c *Collector) Source(name string) SourceStats {
+	if c == nil {
+		return SourceStats{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if s, found := c.sources[name]; found {
+		return *s
+	}
+	return SourceStats{}
+}
+
+// Sources returns a copy of the metrics collected for each data source, keyed by name.
+func (c *Collector) Sources() map[string]SourceStats {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	sources := make(map[string]SourceStats, len(c.sources))
+	for name, s := range c.sources {
+		sources[name] = *s
+	}
+	return sources
+}
+
+// Score returns how productive the named data source has been recently, from zero to one. Successful
+// requests and emitted results raise the score, while failed requests lower it. The outcomes count
+// less as they age, so a source recovers from a bad spell, and a source without outcomes scores 0.5.
+func (c *Collector) Score(source string) float64 {
+	if c == nil {
+		return 0.5
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if s, found := c.scores[source]; found {
+		return s.score(time.Now())
+	}
+	return 0.5
+}
+
+// Scores returns the score of each data source with outcomes, keyed by name.
+func (c *Collector) Scores() map[string]float64 {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.currentScores()
+}
+
+func (c *Collector) currentScores() map[string]float64 {
+	now := time.Now()
+	scores := make(map[string]float64, len(c.scores))
+	for name, s := range c.scores {
+		scores[name] = s.score(now)
+	}
+	return scores
+}
+
+// WriteJSON writes the collected metrics to the provided io.Writer.
+func (c *Collector) WriteJSON(w io.Writer) error {
+	if c == nil {
+		return ErrNoCollector
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	end := time.Now()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Start      string                      `json:"start"`
+		End        string                      `json:"end"`
+		DurationMS int64                       `json:"duration_ms"`
+		Sources    map[string]*SourceStats     `json:"sources"`
+		Phases     map[string]*PhaseStats      `json:"phases"`
+		Counters   map[string]int64            `json:"counters,omitempty"`
+		Scores     map[string]float64          `json:"scores,omitempty"`
+		Statuses   map[string]map[string]int64 `json:"status_codes,omitempty"`
+		Slow       []*Exemplar                 `json:"slow_requests,omitempty"`
+	}{
+		Start:      c.start.Format(time.RFC3339),
+		End:        end.Format(time.RFC3339),
+		DurationMS: end.Sub(c.start).Milliseconds(),
+		Sources:    c.sources,
+		Phases:     c.phases,
+		Counters:   c.counters,
+		Scores:     c.currentScores(),
+		Statuses:   c.statusCodesJSON(),
+		Slow:       c.slowRequests,
+	})
+}
+
+func (c *Collector) source(name string) *SourceStats {
+	s, found := c.sources[name]
+	if !found {
+		s = new(SourceStats)
+		c.sources[name] = s
+	}
+	return s
+}
+
+// outcome adds a success or failure of the named data source to the decayed counts behind its score.
+func (c *Collector) outcome(source string, success bool) {
+	s, found := c.scores[source]
+	if !found {
+		s = new(sourceScore)
+		c.scores[source] = s
+	}
+
+	s.decay(time.Now())
+
+	if success {
+		s.successes++
+	} else {
+		s.failures++
+	}
+}
+
+func (s *sourceScore) decay(now time.Time) {
+	if !s.decayed.IsZero() {
+		if elapsed := now.Sub(s.decayed); elapsed > 0 {
+			f := math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+			s.successes *= f
+			s.failures *= f
+		}
+	}
+	s.decayed = now
+}
+
+// score returns the share of the recent outcomes that were successes, starting from an even
+// chance, so a few outcomes cannot push the score to either end.
+func (s *sourceScore) score(now time.Time) float64 {
+	s.decay(now)
+	return (s.successes + 1) / (s.successes + s.failures + 2)
+}
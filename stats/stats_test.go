@@ -0,0 +1,183 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c, "Umbrella")
+
+	RecordRequest(ctx, nil)
+	RecordRequest(ctx, errors.New("connection refused"))
+	RecordRateLimitWait(ctx, 2*time.Second)
+	RecordRateLimitWait(ctx, time.Microsecond)
+	RecordResult(ctx)
+	RecordResult(ctx)
+	RecordResult(ctx)
+	// Requests made without a data source in the context are not attributed
+	RecordRequest(context.Background(), nil)
+
+	expected := SourceStats{
+		Requests:        2,
+		Errors:          1,
+		RateLimitWaits:  1,
+		RateLimitWaitMS: 2000,
+		Results:         3,
+	}
+	if got := c.Source("Umbrella"); got != expected {
+		t.Errorf("Source returned %+v, expected %+v", got, expected)
+	}
+
+	c.Phase("dns", 100*time.Millisecond)
+	c.Phase("dns", 50*time.Millisecond)
+	c.Count("depth_capped", 2)
+	c.Count("depth_capped", 1)
+	if n := c.Counter("depth_capped"); n != 3 {
+		t.Errorf("Counter returned %d, expected 3", n)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := c.WriteJSON(buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var out struct {
+		Sources  map[string]SourceStats `json:"sources"`
+		Phases   map[string]PhaseStats  `json:"phases"`
+		Counters map[string]int64       `json:"counters"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Failed to decode the statistics: %v", err)
+	}
+	if len(out.Sources) != 1 || out.Sources["Umbrella"] != expected {
+		t.Errorf("The JSON output contained unexpected source metrics: %+v", out.Sources)
+	}
+	if p := out.Phases["dns"]; p.Calls != 2 || p.DurationMS != 150 {
+		t.Errorf("The JSON output contained unexpected phase metrics: %+v", p)
+	}
+	if out.Counters["depth_capped"] != 3 {
+		t.Errorf("The JSON output contained unexpected counters: %+v", out.Counters)
+	}
+
+	var nilcollector *Collector
+	nilcollector.Request("Umbrella", nil)
+	nilcollector.Phase("dns", time.Second)
+	nilcollector.Count("depth_capped", 1)
+}
+
+func TestNilCollector(t *testing.T) {
+	var c *Collector
+
+	if s := c.Source("Umbrella"); s != (SourceStats{}) {
+		t.Errorf("A nil collector returned the metrics %+v", s)
+	}
+	if err := c.WriteJSON(new(bytes.Buffer)); err != ErrNoCollector {
+		t.Errorf("WriteJSON on a nil collector returned %v", err)
+	}
+
+	b := new(bytes.Buffer)
+	if err := c.WriteOpenMetrics(b); err != nil || b.String() != "# EOF\n" {
+		t.Errorf("WriteOpenMetrics on a nil collector wrote %q: %v", b.String(), err)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c, "Umbrella")
+
+	c.SetQuota("Umbrella", 2)
+	for i := 0; i < 2; i++ {
+		if err := TakeQuota(ctx); err != nil {
+			t.Errorf("Request %d was rejected before the quota was reached", i+1)
+		}
+	}
+	if !c.QuotaReached("Umbrella") {
+		t.Errorf("The quota was not reported as reached")
+	}
+	if err := TakeQuota(ctx); err != ErrQuotaReached {
+		t.Errorf("The request was allowed after the quota was reached")
+	}
+	if err := TakeQuota(NewContext(context.Background(), c, "NetworksDB")); err != nil || c.QuotaReached("NetworksDB") {
+		t.Errorf("A data source without a quota was limited")
+	}
+
+	var nilcollector *Collector
+	if err := nilcollector.TakeQuota("Umbrella"); err != nil || nilcollector.QuotaReached("Umbrella") {
+		t.Errorf("A nil Collector enforced a quota")
+	}
+}
+
+func TestExtraction(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c, "NetworksDB")
+
+	c.SetExtractionLimit(3)
+	RecordExtraction(ctx, false)
+	RecordExtraction(ctx, false)
+	// A successful extraction resets the consecutive failures
+	RecordExtraction(ctx, true)
+	if RecordExtraction(ctx, false) || RecordExtraction(ctx, false) || c.Broken("NetworksDB") {
+		t.Errorf("The source was marked broken before the consecutive failures reached the limit")
+	}
+	if !RecordExtraction(ctx, false) || !c.Broken("NetworksDB") {
+		t.Errorf("The source was not marked broken at the limit")
+	}
+	if RecordExtraction(ctx, false) {
+		t.Errorf("The source was reported as broken more than once")
+	}
+	if s := c.Source("NetworksDB"); s.ExtractionFailures != 6 || !s.Broken {
+		t.Errorf("Unexpected extraction metrics: %+v", s)
+	}
+
+	c.SetExtractionLimit(0)
+	for i := 0; i < 10; i++ {
+		c.Extraction("Umbrella", false)
+	}
+	if c.Broken("Umbrella") {
+		t.Errorf("The source was marked broken with the detection disabled")
+	}
+
+	var nilcollector *Collector
+	if nilcollector.Extraction("NetworksDB", false) || nilcollector.Broken("NetworksDB") {
+		t.Errorf("A nil Collector marked a source broken")
+	}
+}
+
+func TestScore(t *testing.T) {
+	c := NewCollector()
+	if s := c.Score("crtsh"); s != 0.5 {
+		t.Errorf("The source without outcomes did not score 0.5: %v", s)
+	}
+
+	for i := 0; i < 8; i++ {
+		c.Request("crtsh", nil)
+		c.Request("dnsdb", errors.New("timeout"))
+	}
+	c.Result("crtsh")
+	good, bad := c.Score("crtsh"), c.Score("dnsdb")
+	if good <= 0.5 || bad >= 0.5 || good >= 1 || bad <= 0 {
+		t.Errorf("Unexpected scores: crtsh %v, dnsdb %v", good, bad)
+	}
+	if s := c.Scores()["dnsdb"]; s-bad > 0.01 {
+		t.Errorf("The score was not included in the scores: %v", s)
+	}
+
+	// The failures fade as they age, so the source recovers once it delivers
+	c.Lock()
+	c.scores["dnsdb"].decayed = time.Now().Add(-10 * scoreHalfLife)
+	c.Unlock()
+	c.Request("dnsdb", nil)
+	if s := c.Score("dnsdb"); s <= 0.5 {
+		t.Errorf("The source did not recover from the aged failures: %v", s)
+	}
+}
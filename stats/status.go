@@ -0,0 +1,109 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StatusCount is the number of responses a data source received with an HTTP status code.
+type StatusCount struct {
+	Code  int
+	Count int64
+}
+
+// RecordStatus counts a response with the HTTP status code received by the data source in the context.
+func RecordStatus(ctx context.Context, code int) {
+	if c, src := FromContext(ctx); c != nil && src != "" {
+		c.Status(src, code)
+	}
+}
+
+// Status counts a response with the HTTP status code received by the named data source.
+func (c *Collector) Status(source string, code int) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	codes, found := c.statuses[source]
+	if !found {
+		codes = make(map[int]int64)
+		c.statuses[source] = codes
+	}
+	codes[code]++
+}
+
+// StatusCodes returns the HTTP status codes received by the named data source, starting with
+// the most frequent, and in numerical order when the counts are equal.
+func (c *Collector) StatusCodes(source string) []StatusCount {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	var counts []StatusCount
+	for code, n := range c.statuses[source] {
+		counts = append(counts, StatusCount{Code: code, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Code < counts[j].Code
+	})
+	return counts
+}
+
+// StatusSources returns the names of the data sources that received HTTP responses, in alphabetical order.
+func (c *Collector) StatusSources() []string {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	var names []string
+	for name := range c.statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatStatusCodes renders the counts as a list, such as "200×120, 429×3, 403×1".
+func FormatStatusCodes(counts []StatusCount) string {
+	list := make([]string, 0, len(counts))
+	for _, sc := range counts {
+		list = append(list, fmt.Sprintf("%d×%d", sc.Code, sc.Count))
+	}
+	return strings.Join(list, ", ")
+}
+
+// statusCodesJSON returns the status code counts of each data source, with the codes as strings
+// since JSON object keys cannot be numbers.
+func (c *Collector) statusCodesJSON() map[string]map[string]int64 {
+	if len(c.statuses) == 0 {
+		return nil
+	}
+
+	sources := make(map[string]map[string]int64, len(c.statuses))
+	for name, codes := range c.statuses {
+		m := make(map[string]int64, len(codes))
+		for code, n := range codes {
+			m[fmt.Sprint(code)] = n
+		}
+		sources[name] = m
+	}
+	return sources
+}
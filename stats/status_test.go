@@ -0,0 +1,59 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStatusCodes(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c, "NetworksDB")
+
+	for i := 0; i < 5; i++ {
+		RecordStatus(ctx, 200)
+	}
+	RecordStatus(ctx, 403)
+	RecordStatus(ctx, 429)
+	RecordStatus(ctx, 429)
+	c.Status("Umbrella", 401)
+	// Responses received without a data source in the context are not attributed
+	RecordStatus(context.Background(), 500)
+
+	expected := []StatusCount{{200, 5}, {429, 2}, {403, 1}}
+	if got := c.StatusCodes("NetworksDB"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("StatusCodes returned %v, expected %v", got, expected)
+	}
+	if got := FormatStatusCodes(expected); got != "200×5, 429×2, 403×1" {
+		t.Errorf("FormatStatusCodes returned %q", got)
+	}
+	if got := c.StatusSources(); !reflect.DeepEqual(got, []string{"NetworksDB", "Umbrella"}) {
+		t.Errorf("StatusSources returned %v", got)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := c.WriteJSON(buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var out struct {
+		Statuses map[string]map[string]int64 `json:"status_codes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Failed to decode the statistics: %v", err)
+	}
+	if out.Statuses["NetworksDB"]["429"] != 2 || out.Statuses["Umbrella"]["401"] != 1 || len(out.Statuses) != 2 {
+		t.Errorf("The JSON output contained unexpected status codes: %v", out.Statuses)
+	}
+
+	var nilcollector *Collector
+	nilcollector.Status("NetworksDB", 200)
+	if nilcollector.StatusCodes("NetworksDB") != nil {
+		t.Errorf("The nil collector returned status codes")
+	}
+}
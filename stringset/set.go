@@ -0,0 +1,318 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package stringset provides a case-insensitive set of strings. A Set is held in memory until
+// the number of elements grows past the spill threshold, and is then moved into a key-value
+// store on disk shared by the spilled sets, so the large sets built while enumerating the
+// netblocks of big autonomous systems do not exhaust the available memory.
+package stringset
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// The number of elements copied from one Set to another at a time
+const batchSize = 1000
+
+var spillThreshold int64
+
+// SetSpillThreshold sets the number of elements a Set can hold in memory before it is moved
+// to disk. The sets that have already spilled remain on disk. A threshold of zero, which is
+// the default, keeps every Set in memory.
+func SetSpillThreshold(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&spillThreshold, int64(n))
+}
+
+// SpillThreshold returns the number of elements a Set can hold in memory before it is moved to disk.
+func SpillThreshold() int {
+	return int(atomic.LoadInt64(&spillThreshold))
+}
+
+// Set is a case-insensitive set of strings that is safe for concurrent use.
+type Set struct {
+	sync.Mutex
+	elements map[string]struct{}
+	store    *diskStore
+}
+
+// Deduplicate utilizes the Set type to generate a unique list of strings from the input slice.
+func Deduplicate(input []string) []string {
+	ss := New(input...)
+	defer ss.Close()
+
+	return ss.Slice()
+}
+
+// New returns a Set containing the values provided in the arguments.
+func New(initial ...string) *Set {
+	s := &Set{elements: make(map[string]struct{}, 50)}
+
+	s.InsertMany(initial...)
+	return s
+}
+
+// Close releases the elements of the Set and removes them from disk.
+func (s *Set) Close() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.elements = make(map[string]struct{})
+	if s.store != nil {
+		s.store.close()
+		s.store = nil
+	}
+}
+
+// Spilled returns true if the elements of the Set have been moved to disk.
+func (s *Set) Spilled() bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.store != nil
+}
+
+// Has returns true if the receiver Set already contains the element string argument.
+func (s *Set) Has(element string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.has(strings.ToLower(element))
+}
+
+// Insert adds the element string argument to the receiver Set.
+func (s *Set) Insert(element string) {
+	s.InsertMany(element)
+}
+
+// InsertMany adds all the elements strings into the receiver Set.
+func (s *Set) InsertMany(elements ...string) {
+	if len(elements) == 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.insert(lower(elements))
+}
+
+// Remove will delete the element string from the receiver Set.
+func (s *Set) Remove(element string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.remove([]string{strings.ToLower(element)})
+}
+
+// Slice returns a string slice that contains all the elements in the Set. Range avoids
+// loading the elements of a large spilled Set into memory at once.
+func (s *Set) Slice() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.slice()
+}
+
+// Range calls fn for each element in the Set until fn returns false. The elements of a spilled
+// Set are read from disk one at a time. The Set is locked while fn runs, so fn must not call
+// the methods of the Set.
+func (s *Set) Range(fn func(element string) bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.each(fn)
+}
+
+// Union adds all the elements from the other Set argument into the receiver Set.
+func (s *Set) Union(other *Set) {
+	if s == other {
+		return
+	}
+
+	defer lockPair(s, other)()
+	other.batches(s.insert)
+}
+
+// Len returns the number of elements in the receiver Set.
+func (s *Set) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.len()
+}
+
+// Subtract removes all elements in the other Set argument from the receiver Set.
+func (s *Set) Subtract(other *Set) {
+	if s == other {
+		s.Close()
+		return
+	}
+
+	defer lockPair(s, other)()
+	other.batches(s.remove)
+}
+
+// Intersect causes the receiver Set to only contain elements also found in the
+// other Set argument.
+func (s *Set) Intersect(other *Set) {
+	if s == other {
+		return
+	}
+
+	defer lockPair(s, other)()
+
+	var missing []string
+	s.each(func(e string) bool {
+		if !other.has(e) {
+			missing = append(missing, e)
+		}
+		return true
+	})
+	s.remove(missing)
+}
+
+// lockPair locks both sets in the order of their addresses, so two goroutines combining the
+// same sets in opposite directions cannot deadlock, and returns the function unlocking them.
+func lockPair(a, b *Set) func() {
+	if uintptr(unsafe.Pointer(b)) < uintptr(unsafe.Pointer(a)) {
+		a, b = b, a
+	}
+
+	a.Lock()
+	b.Lock()
+	return func() {
+		b.Unlock()
+		a.Unlock()
+	}
+}
+
+// String implements the flag.Value interface.
+func (s *Set) String() string {
+	return strings.Join(s.Slice(), ",")
+}
+
+// Set implements the flag.Value interface.
+func (s *Set) Set(input string) error {
+	if input == "" {
+		return fmt.Errorf("String parsing failed")
+	}
+
+	items := strings.Split(input, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	s.InsertMany(items...)
+	return nil
+}
+
+func (s *Set) has(element string) bool {
+	if s.store != nil {
+		return s.store.has(element)
+	}
+
+	_, found := s.elements[element]
+	return found
+}
+
+func (s *Set) insert(elements []string) {
+	if s.store != nil {
+		s.store.insert(elements)
+		return
+	}
+
+	for _, e := range elements {
+		s.elements[e] = struct{}{}
+	}
+	if max := SpillThreshold(); max > 0 && len(s.elements) > max {
+		s.spill()
+	}
+}
+
+func (s *Set) remove(elements []string) {
+	if s.store != nil {
+		s.store.remove(elements)
+		return
+	}
+
+	for _, e := range elements {
+		delete(s.elements, e)
+	}
+}
+
+func (s *Set) slice() []string {
+	elements := make([]string, 0, s.len())
+
+	s.each(func(e string) bool {
+		elements = append(elements, e)
+		return true
+	})
+	return elements
+}
+
+func (s *Set) len() int {
+	if s.store != nil {
+		return s.store.count
+	}
+	return len(s.elements)
+}
+
+func (s *Set) each(fn func(string) bool) {
+	if s.store != nil {
+		s.store.each(fn)
+		return
+	}
+
+	for e := range s.elements {
+		if !fn(e) {
+			return
+		}
+	}
+}
+
+// batches passes the elements of the Set to fn in batches, so a spilled Set is never loaded
+// into memory at once.
+func (s *Set) batches(fn func([]string)) {
+	batch := make([]string, 0, batchSize)
+
+	s.each(func(e string) bool {
+		if batch = append(batch, e); len(batch) == batchSize {
+			fn(batch)
+			batch = batch[:0]
+		}
+		return true
+	})
+	if len(batch) > 0 {
+		fn(batch)
+	}
+}
+
+// spill moves the elements held in memory to a store of their own on disk. The Set stays in
+// memory when the store cannot be created.
+func (s *Set) spill() {
+	store, err := newDiskStore()
+	if err != nil {
+		logf("stringset: failed to move a set to disk: %v", err)
+		return
+	}
+
+	store.insert(s.slice())
+	s.store = store
+	s.elements = make(map[string]struct{})
+}
+
+func lower(elements []string) []string {
+	l := make([]string, len(elements))
+	for i, e := range elements {
+		l[i] = strings.ToLower(e)
+	}
+	return l
+}
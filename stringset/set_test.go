@@ -0,0 +1,241 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stringset
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withSpillThreshold(t testing.TB, n int) {
+	prev := SpillThreshold()
+	SetSpillThreshold(n)
+	t.Cleanup(func() { SetSpillThreshold(prev) })
+}
+
+func TestDeduplicate(t *testing.T) {
+	set := Deduplicate([]string{"dup", "DUP", "dup", "test1", "test2", "test3"})
+	sort.Strings(set)
+
+	if got := strings.Join(set, ","); got != "dup,test1,test2,test3" {
+		t.Errorf("Returned %s instead of the unique elements", got)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	for _, threshold := range []int{0, 2} {
+		t.Run(fmt.Sprintf("threshold=%d", threshold), func(t *testing.T) {
+			withSpillThreshold(t, threshold)
+
+			s := New("a", "B", "c")
+			defer s.Close()
+			if spilled := s.Spilled(); spilled != (threshold > 0) {
+				t.Errorf("Spilled returned %t with a threshold of %d", spilled, threshold)
+			}
+
+			s.InsertMany("b", "D", "d")
+			if s.Len() != 4 {
+				t.Errorf("Len returned %d instead of 4", s.Len())
+			}
+			if !s.Has("A") || !s.Has("d") || s.Has("e") {
+				t.Errorf("Has returned the wrong results for %v", s.Slice())
+			}
+
+			s.Remove("C")
+			s.Remove("missing")
+			if s.Len() != 3 || s.Has("c") {
+				t.Errorf("Remove failed to delete the element: %v", s.Slice())
+			}
+
+			other := New("a", "x")
+			defer other.Close()
+			s.Union(other)
+			if s.Len() != 4 || !s.Has("x") {
+				t.Errorf("Union failed to add the elements: %v", s.Slice())
+			}
+
+			in := New("A", "b", "x", "z")
+			defer in.Close()
+			s.Intersect(in)
+			elements := s.Slice()
+			sort.Strings(elements)
+			if got := strings.Join(elements, ","); got != "a,b,x" {
+				t.Errorf("Intersect left %s instead of a,b,x", got)
+			}
+
+			sub := New("b", "y")
+			defer sub.Close()
+			s.Subtract(sub)
+			elements = s.Slice()
+			sort.Strings(elements)
+			if got := strings.Join(elements, ","); got != "a,x" || s.Len() != 2 {
+				t.Errorf("Subtract left %s instead of a,x", got)
+			}
+		})
+	}
+}
+
+func TestSetSpill(t *testing.T) {
+	withSpillThreshold(t, 10)
+
+	s := New()
+	for i := 0; i < 10; i++ {
+		s.Insert(fmt.Sprintf("host%d.owasp.org", i))
+	}
+	if s.Spilled() {
+		t.Errorf("The set spilled before passing the threshold")
+	}
+
+	s.Insert("HOST10.owasp.org")
+	if !s.Spilled() {
+		t.Fatalf("The set did not spill after passing the threshold")
+	}
+	if s.Len() != 11 || !s.Has("host10.owasp.org") || !s.Has("host0.owasp.org") {
+		t.Errorf("The elements were not moved to disk: %v", s.Slice())
+	}
+
+	dir := shared.path
+	s.Close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Close did not remove the store at %s", dir)
+	}
+	if s.Len() != 0 {
+		t.Errorf("The set was not empty after Close")
+	}
+}
+
+func TestSetSharedStore(t *testing.T) {
+	withSpillThreshold(t, 2)
+
+	a := New("a", "b", "c", "")
+	defer a.Close()
+	b := New("c", "d", "e")
+	if !a.Spilled() || !b.Spilled() || a.store.db != b.store.db {
+		t.Fatalf("The spilled sets do not share the store")
+	}
+	if !a.Has("") || b.Has("") || a.Len() != 4 {
+		t.Errorf("The empty string was not stored in the spilled set")
+	}
+
+	var elements []string
+	b.Range(func(e string) bool {
+		elements = append(elements, e)
+		return true
+	})
+	sort.Strings(elements)
+	if got := strings.Join(elements, ","); got != "c,d,e" {
+		t.Errorf("Range returned %s from the set sharing the store", got)
+	}
+
+	dir := shared.path
+	closed := *b.store
+	b.Close()
+	if !a.Has("a") || a.Has("d") || a.Len() != 4 {
+		t.Errorf("Closing a set changed the other set sharing the store")
+	}
+	var left int
+	closed.each(func(string) bool {
+		left++
+		return true
+	})
+	if left != 0 {
+		t.Errorf("Closing a set left %d of its elements in the shared store", left)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("The store was removed while a set was still using it")
+	}
+}
+
+func TestSetRangeStops(t *testing.T) {
+	s := New("a", "b", "c")
+	defer s.Close()
+
+	var n int
+	s.Range(func(e string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Range continued after fn returned false: %d calls", n)
+	}
+}
+
+func TestSetIntersectBothWays(t *testing.T) {
+	a := New("a", "b", "c")
+	defer a.Close()
+	b := New("b", "c", "d")
+	defer b.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			a.Intersect(b)
+		}
+		close(done)
+	}()
+	for i := 0; i < 1000; i++ {
+		b.Intersect(a)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Intersecting the sets in opposite directions deadlocked")
+	}
+	if a.Len() != 2 || b.Len() != 2 || !a.Has("b") || !b.Has("c") {
+		t.Errorf("Intersect left %v and %v", a.Slice(), b.Slice())
+	}
+}
+
+func TestSetFlagValue(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	if err := s.Set(""); err == nil {
+		t.Errorf("Set accepted an empty string")
+	}
+	if err := s.Set("owasp.org, Example.com"); err != nil || s.Len() != 2 || !s.Has("example.com") {
+		t.Errorf("Set failed to parse the list: %v", s.Slice())
+	}
+}
+
+// BenchmarkNetblocks measures the heap in use after collecting the netblocks announced by a
+// very large autonomous system, with the set held in memory and spilled to disk:
+//
+//	go test -run=NONE -bench=Netblocks ./stringset/
+func BenchmarkNetblocks(b *testing.B) {
+	const netblocks = 500000
+
+	for _, threshold := range []int{0, 10000} {
+		b.Run(fmt.Sprintf("threshold=%d", threshold), func(b *testing.B) {
+			withSpillThreshold(b, threshold)
+			b.ReportAllocs()
+
+			var heap uint64
+			for i := 0; i < b.N; i++ {
+				s := New()
+				for j := 0; j < netblocks; j++ {
+					s.Insert(fmt.Sprintf("%d.%d.%d.0/24", 10+j>>16, (j>>8)&0xff, j&0xff))
+				}
+
+				var m runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&m)
+				heap += m.HeapInuse
+
+				if s.Len() != netblocks {
+					b.Fatalf("The set held %d netblocks instead of %d", s.Len(), netblocks)
+				}
+				s.Close()
+			}
+			b.ReportMetric(float64(heap)/float64(b.N)/(1<<20), "heap-MB")
+		})
+	}
+}
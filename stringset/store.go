@@ -0,0 +1,270 @@
+// Copyright © by Jeff Foley 2017-2022. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package stringset
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
+)
+
+// shared is the badger database, within a temporary directory, that holds the elements of every
+// spilled Set. It is opened when the first Set spills, and removed once the last one is closed.
+var shared struct {
+	sync.Mutex
+	db     *badger.DB
+	path   string
+	stores int
+	next   uint64
+}
+
+var (
+	loggerLock sync.Mutex
+	logger     = log.New(ioutil.Discard, "", 0)
+)
+
+// SetLogger sets the logger receiving the failures to write the spilled sets to disk, which
+// cause elements to be lost. The failures are discarded when no logger has been set.
+func SetLogger(l *log.Logger) {
+	if l == nil {
+		l = log.New(ioutil.Discard, "", 0)
+	}
+
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+
+	logger = l
+}
+
+func logf(format string, v ...interface{}) {
+	loggerLock.Lock()
+	l := logger
+	loggerLock.Unlock()
+
+	l.Printf(format, v...)
+}
+
+// diskStore holds the elements of a spilled Set in the shared database, under a key prefix of its own.
+type diskStore struct {
+	db     *badger.DB
+	prefix []byte
+	count  int
+}
+
+var present = []byte{1}
+
+func newDiskStore() (*diskStore, error) {
+	shared.Lock()
+	defer shared.Unlock()
+
+	if shared.db == nil {
+		db, path, err := openSharedDB()
+		if err != nil {
+			return nil, err
+		}
+		shared.db = db
+		shared.path = path
+	}
+
+	shared.stores++
+	shared.next++
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, shared.next)
+	return &diskStore{db: shared.db, prefix: prefix}, nil
+}
+
+func openSharedDB() (*badger.DB, string, error) {
+	path, err := ioutil.TempDir("", "amass-stringset")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The tables are read from the files instead of being loaded into memory, and the
+	// memtables are kept small, since the store exists to limit the memory in use
+	opts := badger.DefaultOptions(path).
+		WithLogger(nil).
+		WithEventLogging(false).
+		WithSyncWrites(false).
+		WithTableLoadingMode(options.FileIO).
+		WithValueLogLoadingMode(options.FileIO).
+		WithMaxTableSize(8 << 20).
+		WithNumMemtables(2).
+		WithNumLevelZeroTables(2).
+		WithNumLevelZeroTablesStall(4).
+		WithValueLogFileSize(64 << 20)
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		_ = os.RemoveAll(path)
+		return nil, "", err
+	}
+	return db, path, nil
+}
+
+// close deletes the elements of the store, and removes the shared database when no other
+// store is using it.
+func (d *diskStore) close() {
+	// The store still counts as using the database while its keys are deleted, so the
+	// database is only removed by the store that is the last one when it decrements the count
+	shared.Lock()
+	if shared.stores == 1 {
+		closeSharedDB()
+		shared.Unlock()
+		return
+	}
+	shared.Unlock()
+
+	// DropPrefix would block the writes of the other stores, so the keys are deleted instead
+	wb := d.db.NewWriteBatch()
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(d.iteratorOptions())
+		defer it.Close()
+
+		for it.Seek(d.prefix); it.ValidForPrefix(d.prefix); it.Next() {
+			if err := wb.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = wb.Flush()
+	} else {
+		wb.Cancel()
+	}
+	if err != nil {
+		logf("stringset: failed to delete a closed set from disk: %v", err)
+	}
+
+	shared.Lock()
+	defer shared.Unlock()
+
+	if shared.stores == 1 {
+		closeSharedDB()
+		return
+	}
+	shared.stores--
+}
+
+// closeSharedDB closes and removes the shared database. The shared lock must be held.
+func closeSharedDB() {
+	shared.stores = 0
+	if shared.db != nil {
+		_ = shared.db.Close()
+		_ = os.RemoveAll(shared.path)
+	}
+	shared.db = nil
+	shared.path = ""
+}
+
+// key returns the key of the element, which is never empty thanks to the prefix of the store.
+func (d *diskStore) key(element string) []byte {
+	key := make([]byte, len(d.prefix)+len(element))
+	copy(key, d.prefix)
+	copy(key[len(d.prefix):], element)
+	return key
+}
+
+func (d *diskStore) iteratorOptions() badger.IteratorOptions {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = d.prefix
+	return opts
+}
+
+func (d *diskStore) has(element string) bool {
+	var found bool
+
+	_ = d.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(d.key(element))
+		found = err == nil
+		return nil
+	})
+	return found
+}
+
+func (d *diskStore) insert(elements []string) {
+	d.update(elements, func(txn *badger.Txn, key []byte) (int, error) {
+		if _, err := txn.Get(key); err == nil {
+			return 0, nil
+		} else if err != badger.ErrKeyNotFound {
+			return 0, err
+		}
+		return 1, txn.Set(key, present)
+	})
+}
+
+func (d *diskStore) remove(elements []string) {
+	d.update(elements, func(txn *badger.Txn, key []byte) (int, error) {
+		if _, err := txn.Get(key); err == badger.ErrKeyNotFound {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		return -1, txn.Delete(key)
+	})
+}
+
+// update applies the change to each element, committing the transaction and starting
+// another each time it grows too large, and keeps count of the elements in the store.
+// The elements that could not be changed are written to the log.
+func (d *diskStore) update(elements []string, change func(*badger.Txn, []byte) (int, error)) {
+	txn := d.db.NewTransaction(true)
+	var delta, pending, failed int
+	var last error
+
+	commit := func() {
+		if err := txn.Commit(); err != nil {
+			failed += pending
+			last = err
+		} else {
+			d.count += delta
+		}
+		delta, pending = 0, 0
+	}
+
+	for _, e := range elements {
+		key := d.key(e)
+		n, err := change(txn, key)
+
+		if err == badger.ErrTxnTooBig {
+			commit()
+			txn = d.db.NewTransaction(true)
+			n, err = change(txn, key)
+		}
+		if err != nil {
+			failed++
+			last = err
+			continue
+		}
+		delta += n
+		pending++
+	}
+	commit()
+
+	if failed > 0 {
+		logf("stringset: failed to update %d elements of a set on disk: %v", failed, last)
+	}
+}
+
+// each calls fn with the elements of the store, read from disk one at a time, until fn returns false.
+func (d *diskStore) each(fn func(string) bool) {
+	_ = d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(d.iteratorOptions())
+		defer it.Close()
+
+		for it.Seek(d.prefix); it.ValidForPrefix(d.prefix); it.Next() {
+			if !fn(string(it.Item().Key()[len(d.prefix):])) {
+				break
+			}
+		}
+		return nil
+	})
+}
@@ -5,6 +5,7 @@
 package systems
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -15,22 +16,42 @@ import (
 	"time"
 
 	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/eventlog"
 	"github.com/aokimio/Amass/v3/limits"
 	amassnet "github.com/aokimio/Amass/v3/net"
+	amassdns "github.com/aokimio/Amass/v3/net/dns"
+	"github.com/aokimio/Amass/v3/net/http"
 	"github.com/aokimio/Amass/v3/requests"
 	"github.com/aokimio/Amass/v3/resources"
+	"github.com/aokimio/Amass/v3/stats"
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/netmap"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
 )
 
+// The longest random gap between starting two data sources when the start order is randomized
+const maxSourceStartGap = 100 * time.Millisecond
+
 // LocalSystem implements a System to be executed within a single process.
 type LocalSystem struct {
 	Cfg               *config.Config
 	pool              *resolve.Resolvers
 	trusted           *resolve.Resolvers
+	doh               *amassdns.DoHProxy
+	bound             *boundResolvers
 	graphs            []*netmap.Graph
 	cache             *requests.ASNCache
+	wildcards         *requests.WildcardCache
+	names             *requests.NameFilter
+	domains           *requests.DomainFilter
+	stats             *stats.Collector
+	events            *eventlog.Log
+	ctx               context.Context
+	cancel            context.CancelFunc
+	reqLock           sync.Mutex
+	reqctx            context.Context
+	reqcancel         context.CancelFunc
 	done              chan struct{}
 	doneAlreadyClosed bool
 	addSource         chan service.Service
@@ -42,15 +63,45 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 	if err := cfg.CheckSettings(); err != nil {
 		return nil, err
 	}
+	// Keep the data sources from opening many connections to the same host at once
+	http.SetMaxConnsPerHost(cfg.MaxConnsPerHost)
+	// The large sets built while expanding ASNs are moved to disk past the threshold
+	stringset.SetSpillThreshold(cfg.SpillThreshold)
+	stringset.SetLogger(cfg.Log)
+	// The connections and queries are sent from the local address once it is known to be assigned
+	if cfg.LocalAddress != "" {
+		if err := amassnet.SetLocalAddr(cfg.LocalAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	// The remote resolver lists are merged with the resolvers configured
+	if err := cfg.LoadResolverLists(context.Background()); err != nil {
+		return nil, err
+	}
 
 	var set bool
 	if cfg.MaxDNSQueries == 0 {
 		set = true
 	}
 
+	var doh *amassdns.DoHProxy
+	if len(cfg.DoHResolvers) > 0 {
+		var err error
+		// The DNS-over-HTTPS resolvers are reached through a server on the loopback interface
+		if doh, err = amassdns.NewDoHProxy(cfg.DoHResolvers...); err != nil {
+			return nil, err
+		}
+	}
+
+	bound := &boundResolvers{local: amassnet.LocalIP()}
 	max := int(float64(limits.GetFileLimit()) * 0.7)
-	trusted, num := trustedResolvers(cfg, max)
+	trusted, num := trustedResolvers(cfg, doh, bound, max)
 	if trusted == nil {
+		if doh != nil {
+			doh.Stop()
+		}
+		bound.stop()
 		return nil, errors.New("the system was unable to build the pool of trusted resolvers")
 	}
 	max -= num
@@ -58,8 +109,13 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 		cfg.MaxDNSQueries += num * cfg.TrustedQPS
 	}
 
-	pool, num := untrustedResolvers(cfg, max)
+	pool, num := untrustedResolvers(cfg, doh, bound, max)
 	if pool == nil {
+		trusted.Stop()
+		if doh != nil {
+			doh.Stop()
+		}
+		bound.stop()
 		return nil, errors.New("the system was unable to build the pool of untrusted resolvers")
 	}
 	if set {
@@ -72,12 +128,26 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 		Cfg:        cfg,
 		pool:       pool,
 		trusted:    trusted,
+		doh:        doh,
+		bound:      bound,
 		cache:      requests.NewASNCache(),
+		wildcards:  requests.NewWildcardCache(),
+		names:      requests.NewNameFilter(cfg.NameFilterSize, cfg.NameFilterRate()),
+		domains:    requests.NewDomainFilter(cfg.NewDomainWindow),
+		stats:      stats.NewCollector(),
 		done:       make(chan struct{}, 2),
 		addSource:  make(chan service.Service),
 		allSources: make(chan chan []service.Service, 10),
 	}
+	// The clock for the maximum runtime starts with the system
+	if cfg.Timeout > 0 {
+		sys.ctx, sys.cancel = context.WithTimeout(context.Background(), cfg.Timeout)
+	} else {
+		sys.ctx, sys.cancel = context.WithCancel(context.Background())
+	}
 
+	// Scrape data sources are disabled after failing to extract data from this many pages in a row
+	sys.stats.SetExtractionLimit(int64(cfg.ScrapeFailureLimit))
 	// Load the ASN information into the cache
 	if err := sys.loadCacheData(); err != nil {
 		_ = sys.Shutdown()
@@ -118,6 +188,84 @@ func (l *LocalSystem) Cache() *requests.ASNCache {
 	return l.cache
 }
 
+// Wildcards implements the System interface.
+func (l *LocalSystem) Wildcards() *requests.WildcardCache {
+	return l.wildcards
+}
+
+// NameFilter implements the System interface.
+func (l *LocalSystem) NameFilter() *requests.NameFilter {
+	return l.names
+}
+
+// DomainFilter implements the System interface.
+func (l *LocalSystem) DomainFilter() *requests.DomainFilter {
+	return l.domains
+}
+
+// Stats implements the System interface.
+func (l *LocalSystem) Stats() *stats.Collector {
+	return l.stats
+}
+
+// EventLog implements the System interface.
+func (l *LocalSystem) EventLog() *eventlog.Log {
+	return l.events
+}
+
+// SetEventLog selects the log that receives the discovery events, including the ASN information
+// added to the cache. It must be called before the enumeration is started.
+func (l *LocalSystem) SetEventLog(log *eventlog.Log) {
+	l.events = log
+	if log == nil {
+		l.cache.SetObserver(nil)
+		return
+	}
+	l.cache.SetObserver(log.RecordASN)
+}
+
+// Context implements the System interface.
+func (l *LocalSystem) Context() context.Context {
+	return l.ctx
+}
+
+// RequestContext implements the System interface.
+func (l *LocalSystem) RequestContext() context.Context {
+	l.reqLock.Lock()
+	defer l.reqLock.Unlock()
+
+	if l.reqctx == nil {
+		return l.ctx
+	}
+	return l.reqctx
+}
+
+// SetRequestContext implements the System interface. The requests handled within the
+// replaced context are cancelled.
+func (l *LocalSystem) SetRequestContext(ctx context.Context) {
+	l.reqLock.Lock()
+	defer l.reqLock.Unlock()
+
+	if l.reqcancel != nil {
+		l.reqcancel()
+	}
+	l.reqctx, l.reqcancel = nil, nil
+	if ctx == nil {
+		return
+	}
+
+	rctx, cancel := context.WithCancel(ctx)
+	// The requests still end with the runtime of the System
+	go func() {
+		select {
+		case <-l.ctx.Done():
+			cancel()
+		case <-rctx.Done():
+		}
+	}()
+	l.reqctx, l.reqcancel = rctx, cancel
+}
+
 // AddSource implements the System interface.
 func (l *LocalSystem) AddSource(src service.Service) error {
 	l.addSource <- src
@@ -129,6 +277,9 @@ func (l *LocalSystem) AddAndStart(srv service.Service) error {
 	err := srv.Start()
 
 	if err == nil {
+		if dsc := l.Cfg.GetDataSourceConfig(srv.String()); dsc != nil && dsc.Quota > 0 {
+			l.stats.SetQuota(srv.String(), int64(dsc.Quota))
+		}
 		return l.AddSource(srv)
 	}
 	return err
@@ -147,12 +298,26 @@ func (l *LocalSystem) SetDataSources(sources []service.Service) error {
 	f := func(src service.Service, ch chan error) { ch <- l.AddAndStart(src) }
 
 	ch := make(chan error, len(sources))
-	// Add all the data sources that successfully start to the list
-	for _, src := range sources {
-		go f(src, ch)
+	timeout := 30 * time.Second
+	if l.Cfg.RandomizeSources {
+		// Start the data sources in a random order, spread out over a short period
+		var delay time.Duration
+		rng := l.Cfg.NewRand()
+		for _, i := range rng.Perm(len(sources)) {
+			delay += time.Duration(rng.Int63n(int64(maxSourceStartGap)))
+			time.AfterFunc(delay, func(src service.Service) func() {
+				return func() { f(src, ch) }
+			}(sources[i]))
+		}
+		timeout += delay
+	} else {
+		// Add all the data sources that successfully start to the list
+		for _, src := range sources {
+			go f(src, ch)
+		}
 	}
 
-	t := time.NewTimer(30 * time.Second)
+	t := time.NewTimer(timeout)
 	defer t.Stop()
 
 	var err error
@@ -179,6 +344,8 @@ func (l *LocalSystem) Shutdown() error {
 		return nil
 	}
 	l.doneAlreadyClosed = true
+	// In-flight data source work is abandoned
+	l.cancel()
 
 	var wg sync.WaitGroup
 	for _, src := range l.DataSources() {
@@ -198,6 +365,10 @@ func (l *LocalSystem) Shutdown() error {
 
 	l.pool.Stop()
 	l.trusted.Stop()
+	if l.doh != nil {
+		l.doh.Stop()
+	}
+	l.bound.stop()
 	l.cache = nil
 	return nil
 }
@@ -295,38 +466,60 @@ func (l *LocalSystem) loadCacheData() error {
 	return nil
 }
 
-func trustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func trustedResolvers(cfg *config.Config, doh *amassdns.DoHProxy, bound *boundResolvers, max int) (*resolve.Resolvers, int) {
 	var num int
 	pool := resolve.NewResolvers()
 
-	if len(cfg.TrustedResolvers) > 0 {
+	if doh != nil && len(cfg.TrustedResolvers) == 0 {
+		num = 1
+		qps := cfg.TrustedQPS * len(cfg.DoHResolvers)
+		_ = pool.AddResolvers(qps, doh.Addr())
+		pool.SetDetectionResolver(qps, doh.Addr())
+	} else if len(cfg.TrustedResolvers) > 0 {
 		num = len(cfg.TrustedResolvers)
-		_ = pool.AddResolvers(cfg.TrustedQPS, cfg.TrustedResolvers...)
+		if _, err := bound.addResolvers(pool, cfg.TrustedQPS, cfg.TrustedResolvers...); err != nil {
+			cfg.Log.Printf("%v", err)
+			return nil, 0
+		}
 	} else {
 		num = len(config.DefaultBaselineResolvers)
-		_ = pool.AddResolvers(cfg.TrustedQPS, config.DefaultBaselineResolvers...)
-		pool.SetDetectionResolver(cfg.TrustedQPS, "8.8.8.8")
+		addr, err := bound.addResolvers(pool, cfg.TrustedQPS, config.DefaultBaselineResolvers...)
+		if err != nil {
+			cfg.Log.Printf("%v", err)
+			return nil, 0
+		}
+		if addr == "" {
+			addr = "8.8.8.8"
+		}
+		pool.SetDetectionResolver(cfg.TrustedQPS, addr)
 	}
 
 	pool.SetLogger(cfg.Log)
 	return pool, num
 }
 
-func untrustedResolvers(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func untrustedResolvers(cfg *config.Config, doh *amassdns.DoHProxy, bound *boundResolvers, max int) (*resolve.Resolvers, int) {
 	if max <= 0 {
 		return nil, 0
 	}
+	// Queries are kept off the public resolvers when only DNS-over-HTTPS resolvers were selected
+	if doh != nil && len(cfg.Resolvers) == 0 {
+		pool := resolve.NewResolvers()
+		pool.SetLogger(cfg.Log)
+		_ = pool.AddResolvers(cfg.ResolversQPS*len(cfg.DoHResolvers), doh.Addr())
+		return pool, 1
+	}
 	if len(cfg.Resolvers) == 0 {
-		if pool, num := publicResolverSetup(cfg, max); num > 0 {
+		if pool, num := publicResolverSetup(cfg, bound, max); num > 0 {
 			return pool, num
 		}
 		// Failed to use the public DNS resolvers database
 		cfg.Resolvers = config.DefaultBaselineResolvers
 	}
-	return customResolverSetup(cfg, max)
+	return customResolverSetup(cfg, bound, max)
 }
 
-func customResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func customResolverSetup(cfg *config.Config, bound *boundResolvers, max int) (*resolve.Resolvers, int) {
 	num := len(cfg.Resolvers)
 	if num > max {
 		num = max
@@ -335,7 +528,12 @@ func customResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int)
 
 	pool := resolve.NewResolvers()
 	pool.SetLogger(cfg.Log)
-	_ = pool.AddResolvers(cfg.ResolversQPS, cfg.Resolvers...)
+	if addr, err := bound.addResolvers(pool, cfg.ResolversQPS, cfg.Resolvers...); err != nil {
+		cfg.Log.Printf("%v", err)
+		return nil, 0
+	} else if addr != "" {
+		return pool, num
+	}
 	pool.SetThresholdOptions(&resolve.ThresholdOptions{
 		ThresholdValue:      200,
 		CountTimeouts:       true,
@@ -345,7 +543,7 @@ func customResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int)
 	return pool, num
 }
 
-func publicResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int) {
+func publicResolverSetup(cfg *config.Config, bound *boundResolvers, max int) (*resolve.Resolvers, int) {
 	addrs := config.PublicResolvers
 	num := len(config.PublicResolvers)
 
@@ -367,7 +565,12 @@ func publicResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int)
 
 	r := resolve.NewResolvers()
 	r.SetLogger(cfg.Log)
-	_ = r.AddResolvers(cfg.ResolversQPS, addrs...)
+	if addr, err := bound.addResolvers(r, cfg.ResolversQPS, addrs...); err != nil {
+		cfg.Log.Printf("%v", err)
+		return nil, 0
+	} else if addr != "" {
+		return r, len(addrs)
+	}
 	r.SetThresholdOptions(&resolve.ThresholdOptions{
 		ThresholdValue:      100,
 		CountTimeouts:       true,
@@ -379,6 +582,44 @@ func publicResolverSetup(cfg *config.Config, max int) (*resolve.Resolvers, int)
 	return r, len(addrs)
 }
 
+// boundResolvers sends the queries of the resolver pools from the local address selected
+// for the network connections, through forwarders on the loopback interface.
+type boundResolvers struct {
+	sync.Mutex
+	local   net.IP
+	proxies []*amassdns.BoundProxy
+}
+
+// addResolvers adds the resolvers to the pool. When a local address was selected, they are
+// reached through a single forwarder, whose address is returned, that shares their rate limits.
+func (b *boundResolvers) addResolvers(pool *resolve.Resolvers, qps int, addrs ...string) (string, error) {
+	if b.local == nil || len(addrs) == 0 {
+		_ = pool.AddResolvers(qps, addrs...)
+		return "", nil
+	}
+
+	p, err := amassdns.NewBoundProxy(b.local, addrs...)
+	if err != nil {
+		return "", err
+	}
+
+	b.Lock()
+	b.proxies = append(b.proxies, p)
+	b.Unlock()
+	_ = pool.AddResolvers(qps*len(addrs), p.Addr())
+	return p.Addr(), nil
+}
+
+func (b *boundResolvers) stop() {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, p := range b.proxies {
+		p.Stop()
+	}
+	b.proxies = nil
+}
+
 func checkAddresses(addrs []string) []string {
 	ips := []string{}
 
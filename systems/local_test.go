@@ -1,8 +1,10 @@
 package systems
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCheckAddresses(t *testing.T) {
@@ -99,3 +101,42 @@ func TestRunSubnetChecks(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestContext(t *testing.T) {
+	l := &LocalSystem{}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	defer l.cancel()
+
+	if l.RequestContext() != l.ctx {
+		t.Fatal("The System context was not used for requests before a target was set")
+	}
+
+	target, cancel := context.WithCancel(context.Background())
+	l.SetRequestContext(target)
+	reqctx := l.RequestContext()
+	cancel()
+	select {
+	case <-reqctx.Done():
+	case <-time.After(time.Second):
+		t.Error("The request context was not done when the target ended")
+	}
+
+	l.SetRequestContext(context.Background())
+	reqctx = l.RequestContext()
+	l.SetRequestContext(nil)
+	if reqctx.Err() == nil {
+		t.Error("The request context was not done when it was replaced")
+	}
+	if l.RequestContext() != l.ctx {
+		t.Error("The System context was not restored for requests")
+	}
+
+	l.SetRequestContext(context.Background())
+	reqctx = l.RequestContext()
+	l.cancel()
+	select {
+	case <-reqctx.Done():
+	case <-time.After(time.Second):
+		t.Error("The request context was not done when the System was shut down")
+	}
+}
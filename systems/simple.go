@@ -5,22 +5,32 @@
 package systems
 
 import (
+	"context"
 	"runtime"
 
 	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/eventlog"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/caffix/netmap"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
 )
 
 type SimpleSystem struct {
-	Cfg      *config.Config
-	Pool     *resolve.Resolvers
-	Trusted  *resolve.Resolvers
-	Graph    *netmap.Graph
-	ASNCache *requests.ASNCache
-	Service  service.Service
+	Cfg           *config.Config
+	Pool          *resolve.Resolvers
+	Trusted       *resolve.Resolvers
+	Graph         *netmap.Graph
+	ASNCache      *requests.ASNCache
+	WildcardCache *requests.WildcardCache
+	Filter        *requests.NameFilter
+	Domains       *requests.DomainFilter
+	Collector     *stats.Collector
+	Events        *eventlog.Log
+	Service       service.Service
+	Ctx           context.Context
+	ReqCtx        context.Context
 }
 
 // Config implements the System interface.
@@ -35,6 +45,40 @@ func (ss *SimpleSystem) TrustedResolvers() *resolve.Resolvers { return ss.Truste
 // Cache implements the System interface.
 func (ss *SimpleSystem) Cache() *requests.ASNCache { return ss.ASNCache }
 
+// Wildcards implements the System interface.
+func (ss *SimpleSystem) Wildcards() *requests.WildcardCache { return ss.WildcardCache }
+
+// NameFilter implements the System interface.
+func (ss *SimpleSystem) NameFilter() *requests.NameFilter { return ss.Filter }
+
+// DomainFilter implements the System interface.
+func (ss *SimpleSystem) DomainFilter() *requests.DomainFilter { return ss.Domains }
+
+// Stats implements the System interface.
+func (ss *SimpleSystem) Stats() *stats.Collector { return ss.Collector }
+
+// EventLog implements the System interface.
+func (ss *SimpleSystem) EventLog() *eventlog.Log { return ss.Events }
+
+// Context implements the System interface.
+func (ss *SimpleSystem) Context() context.Context {
+	if ss.Ctx == nil {
+		return context.Background()
+	}
+	return ss.Ctx
+}
+
+// RequestContext implements the System interface.
+func (ss *SimpleSystem) RequestContext() context.Context {
+	if ss.ReqCtx == nil {
+		return ss.Context()
+	}
+	return ss.ReqCtx
+}
+
+// SetRequestContext implements the System interface.
+func (ss *SimpleSystem) SetRequestContext(ctx context.Context) { ss.ReqCtx = ctx }
+
 // AddSource implements the System interface.
 func (ss *SimpleSystem) AddSource(src service.Service) error { ss.Service = src; return nil }
 
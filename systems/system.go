@@ -6,10 +6,13 @@ package systems
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/aokimio/Amass/v3/config"
+	"github.com/aokimio/Amass/v3/eventlog"
 	"github.com/aokimio/Amass/v3/requests"
+	"github.com/aokimio/Amass/v3/stats"
 	"github.com/caffix/netmap"
 	"github.com/caffix/resolve"
 	"github.com/caffix/service"
@@ -29,6 +32,33 @@ type System interface {
 	// Returns the cache populated by the system
 	Cache() *requests.ASNCache
 
+	// Returns the subdomains found to be within DNS wildcards
+	Wildcards() *requests.WildcardCache
+
+	// Returns the names already reported by each data source
+	NameFilter() *requests.NameFilter
+
+	// Returns the related domains already reported by the data sources through reverse whois
+	DomainFilter() *requests.DomainFilter
+
+	// Returns the collector of run statistics
+	Stats() *stats.Collector
+
+	// Returns the log of the discovery events, which is nil unless one was requested
+	EventLog() *eventlog.Log
+
+	// Returns the context bounding the work performed for the System, which is done once
+	// the configured timeout expires or the System is shut down
+	Context() context.Context
+
+	// Returns the context that the data sources handle each request within, which is also
+	// done when the work on the current target ends
+	RequestContext() context.Context
+
+	// SetRequestContext bounds the requests handled by the data sources with the provided
+	// context, until it is replaced. A nil context restores the context of the System
+	SetRequestContext(ctx context.Context)
+
 	// AddSource appends the provided data source to the slice of sources managed by the System
 	AddSource(srv service.Service) error
 
@@ -67,3 +97,28 @@ func PopulateCache(ctx context.Context, asn int, sys System) {
 		}
 	}
 }
+
+// PopulateCacheForASNs updates the provided System cache with information for each of the ASNs,
+// expanding as many ASNs at the same time as the configuration allows. The data sources continue
+// to apply their rate limits across the concurrent requests.
+func PopulateCacheForASNs(ctx context.Context, asns []int, sys System) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sys.Config().NumASNWorkers())
+
+	for _, asn := range asns {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(asn int) {
+			defer func() { <-sem; wg.Done() }()
+
+			PopulateCache(ctx, asn, sys)
+		}(asn)
+	}
+	wg.Wait()
+}
@@ -14,7 +14,7 @@ digraph "{{ .Name }}" {
 	size = "7.5,10"; ranksep="2.5 equally"; ratio=auto;
 
 {{ range .Nodes }}
-        node [label="{{ .Label }}",color="{{ .Color }}",type="{{ .Type }}",source="{{ .Source }}"]; n{{ .ID }};
+        node [label="{{ .Label }}",color="{{ .Color }}",style="{{ .Style }}",type="{{ .Type }}",source="{{ .Source }}"]; n{{ .ID }};
 {{ end }}
 
 {{ range .Edges }}
@@ -33,10 +33,29 @@ type dotNode struct {
 	ID     string
 	Label  string
 	Color  string
+	Style  string
 	Type   string
 	Source string
 }
 
+// The sources that observe the assets directly, instead of reporting what third parties have seen.
+var dotDirectSources = map[string]struct{}{
+	"DNS":          {},
+	"Reverse DNS":  {},
+	"NSEC Walk":    {},
+	"DNS Zone XFR": {},
+	"Active Crawl": {},
+	"Active Cert":  {},
+}
+
+// dotNodeStyle draws the nodes discovered by the data sources dashed, and those observed directly as solid.
+func dotNodeStyle(source string) string {
+	if _, found := dotDirectSources[source]; found {
+		return "solid"
+	}
+	return "dashed"
+}
+
 type dotGraph struct {
 	Name  string
 	Nodes []dotNode
@@ -63,6 +82,7 @@ func WriteDOTData(output io.Writer, nodes []Node, edges []Edge) error {
 			ID:     strconv.Itoa(idx + 1),
 			Label:  node.Label,
 			Color:  colors[node.Type],
+			Style:  dotNodeStyle(node.Source),
 			Type:   node.Type,
 			Source: node.Source,
 		})
@@ -22,9 +22,9 @@ digraph "OWASP Amass Network Mapping" {
 	size = "7.5,10"; ranksep="2.5 equally"; ratio=auto;
 
 
-        node [label="owasp.org",color="red",type="domain",source="DNS"]; n1;
+        node [label="owasp.org",color="red",style="solid",type="domain",source="DNS"]; n1;
 
-        node [label="205.251.199.98",color="orange",type="address",source="DNS"]; n2;
+        node [label="205.251.199.98",color="orange",style="solid",type="address",source="DNS"]; n2;
 
 
 
@@ -32,3 +32,9 @@ digraph "OWASP Amass Network Mapping" {
 
 }
 `
+
+func TestDOTNodeStyle(t *testing.T) {
+	assert.Equal(t, "solid", dotNodeStyle("DNS"))
+	assert.Equal(t, "solid", dotNodeStyle("Active Cert"))
+	assert.Equal(t, "dashed", dotNodeStyle("Crtsh"))
+}
@@ -7,8 +7,8 @@ import (
 	"context"
 	"strings"
 
+	"github.com/aokimio/Amass/v3/stringset"
 	"github.com/caffix/netmap"
-	"github.com/caffix/stringset"
 	"github.com/cayleygraph/quad"
 )
 